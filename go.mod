@@ -8,4 +8,5 @@ require (
 	github.com/shopspring/decimal v1.3.1
 	github.com/xeonx/geom v0.0.0-20151223130215-76a21efc1ce4 // indirect
 	github.com/xeonx/proj4 v0.0.0-20151223112312-c52078bad901
+	gopkg.in/yaml.v3 v3.0.1
 )
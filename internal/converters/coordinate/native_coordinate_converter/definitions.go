@@ -0,0 +1,113 @@
+package native_coordinate_converter
+
+// Ellipsoids, datums, prime meridians and units that can be referenced by name in the proj4 definitions, with the
+// same values of the tables of proj4
+
+type ellipsoidDefinition struct {
+	major string
+	minor string
+}
+
+var ellipsoids = map[string]ellipsoidDefinition{
+	"MERIT":     {"a=6378137.0", "rf=298.257"},
+	"SGS85":     {"a=6378136.0", "rf=298.257"},
+	"GRS80":     {"a=6378137.0", "rf=298.257222101"},
+	"IAU76":     {"a=6378140.0", "rf=298.257"},
+	"airy":      {"a=6377563.396", "b=6356256.910"},
+	"APL4.9":    {"a=6378137.0", "rf=298.25"},
+	"NWL9D":     {"a=6378145.0", "rf=298.25"},
+	"mod_airy":  {"a=6377340.189", "b=6356034.446"},
+	"andrae":    {"a=6377104.43", "rf=300.0"},
+	"aust_SA":   {"a=6378160.0", "rf=298.25"},
+	"GRS67":     {"a=6378160.0", "rf=298.2471674270"},
+	"bessel":    {"a=6377397.155", "rf=299.1528128"},
+	"bess_nam":  {"a=6377483.865", "rf=299.1528128"},
+	"clrk66":    {"a=6378206.4", "b=6356583.8"},
+	"clrk80":    {"a=6378249.145", "rf=293.4663"},
+	"clrk80ign": {"a=6378249.2", "rf=293.4660212936269"},
+	"CPM":       {"a=6375738.7", "rf=334.29"},
+	"delmbr":    {"a=6376428.", "rf=311.5"},
+	"engelis":   {"a=6378136.05", "rf=298.2566"},
+	"evrst30":   {"a=6377276.345", "rf=300.8017"},
+	"evrst48":   {"a=6377304.063", "rf=300.8017"},
+	"evrst56":   {"a=6377301.243", "rf=300.8017"},
+	"evrst69":   {"a=6377295.664", "rf=300.8017"},
+	"evrstSS":   {"a=6377298.556", "rf=300.8017"},
+	"fschr60":   {"a=6378166.", "rf=298.3"},
+	"fschr60m":  {"a=6378155.", "rf=298.3"},
+	"fschr68":   {"a=6378150.", "rf=298.3"},
+	"helmert":   {"a=6378200.", "rf=298.3"},
+	"hough":     {"a=6378270.0", "rf=297."},
+	"intl":      {"a=6378388.0", "rf=297."},
+	"krass":     {"a=6378245.0", "rf=298.3"},
+	"kaula":     {"a=6378163.", "rf=298.24"},
+	"lerch":     {"a=6378139.", "rf=298.257"},
+	"mprts":     {"a=6397300.", "rf=191."},
+	"new_intl":  {"a=6378157.5", "b=6356772.2"},
+	"plessis":   {"a=6376523.", "b=6355863."},
+	"SEasia":    {"a=6378155.0", "b=6356773.3205"},
+	"walbeck":   {"a=6376896.0", "b=6355834.8467"},
+	"WGS60":     {"a=6378165.0", "rf=298.3"},
+	"WGS66":     {"a=6378145.0", "rf=298.25"},
+	"WGS72":     {"a=6378135.0", "rf=298.26"},
+	"WGS84":     {"a=6378137.0", "rf=298.257223563"},
+	"sphere":    {"a=6370997.0", "b=6370997.0"},
+}
+
+type datumDefinition struct {
+	definition string
+	ellipsoid  string
+}
+
+var datums = map[string]datumDefinition{
+	"WGS84":         {"towgs84=0,0,0", "WGS84"},
+	"GGRS87":        {"towgs84=-199.87,74.79,246.62", "GRS80"},
+	"NAD83":         {"towgs84=0,0,0", "GRS80"},
+	"NAD27":         {"nadgrids=@conus,@alaska,@ntv2_0.gsb,@ntv1_can.dat", "clrk66"},
+	"potsdam":       {"towgs84=598.1,73.7,418.2,0.202,0.045,-2.455,6.7", "bessel"},
+	"carthage":      {"towgs84=-263.0,6.0,431.0", "clrk80ign"},
+	"hermannskogel": {"towgs84=577.326,90.129,463.919,5.137,1.474,5.297,2.4232", "bessel"},
+	"ire65":         {"towgs84=482.530,-130.596,564.557,-1.042,-0.214,-0.631,8.15", "mod_airy"},
+	"nzgd49":        {"towgs84=59.47,-5.04,187.44,0.47,-0.1,1.024,-4.5993", "intl"},
+	"OSGB36":        {"towgs84=446.448,-125.157,542.060,0.1502,0.2470,0.8421,-20.4894", "airy"},
+}
+
+var primeMeridians = map[string]string{
+	"greenwich": "0dE",
+	"lisbon":    "9d07'54.862\"W",
+	"paris":     "2d20'14.025\"E",
+	"bogota":    "74d04'51.3\"W",
+	"madrid":    "3d41'16.58\"W",
+	"rome":      "12d27'8.4\"E",
+	"bern":      "7d26'22.5\"E",
+	"jakarta":   "106d48'27.79\"E",
+	"ferro":     "17d40'W",
+	"brussels":  "4d22'4.71\"E",
+	"stockholm": "18d3'29.8\"E",
+	"athens":    "23d42'58.815\"E",
+	"oslo":      "10d43'22.5\"E",
+}
+
+var units = map[string]string{
+	"km":     "1000.",
+	"m":      "1.",
+	"dm":     "1/10",
+	"cm":     "1/100",
+	"mm":     "1/1000",
+	"kmi":    "1852.0",
+	"in":     "0.0254",
+	"ft":     "0.3048",
+	"yd":     "0.9144",
+	"mi":     "1609.344",
+	"fath":   "1.8288",
+	"ch":     "20.1168",
+	"link":   "0.201168",
+	"us-in":  "1./39.37",
+	"us-ft":  "0.304800609601219",
+	"us-yd":  "0.914401828803658",
+	"us-ch":  "20.11684023368047",
+	"us-mi":  "1609.347218694437",
+	"ind-yd": "0.91439523",
+	"ind-ft": "0.30479841",
+	"ind-ch": "20.11669506",
+}
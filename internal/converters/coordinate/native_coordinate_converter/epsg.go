@@ -0,0 +1,9 @@
+package native_coordinate_converter
+
+// Represents a EPSG reference system and stores the relevant projection object for caching reasons
+type epsgProjection struct {
+	EpsgCode    int
+	Description string
+	Proj4       string
+	Projection  *projection
+}
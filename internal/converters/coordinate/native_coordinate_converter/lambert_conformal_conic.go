@@ -0,0 +1,121 @@
+package native_coordinate_converter
+
+import (
+	"errors"
+	"math"
+)
+
+// Lambert Conformal Conic projection with one standard parallel, +lat_1, or two, +lat_1 and +lat_2
+type lambertConformalConic struct {
+	e       float64
+	k0      float64
+	n       float64
+	rho0    float64
+	c       float64
+	ellipse bool
+}
+
+func newLambertConformalConic(p *projection, parameters proj4Parameters) (*lambertConformalConic, error) {
+	phi1, err := parameters.angle("lat_1")
+	if err != nil {
+		return nil, err
+	}
+	phi2 := phi1
+	if parameters.has("lat_2") {
+		if phi2, err = parameters.angle("lat_2"); err != nil {
+			return nil, err
+		}
+	} else if !parameters.has("lat_0") {
+		p.phi0 = phi1
+	}
+	if math.Abs(phi1+phi2) < 1e-10 {
+		return nil, errors.New("standard parallels cannot be opposite")
+	}
+
+	l := &lambertConformalConic{
+		e:       p.e,
+		k0:      p.k0,
+		ellipse: p.es != 0,
+	}
+
+	sinphi := math.Sin(phi1)
+	cosphi := math.Cos(phi1)
+	l.n = sinphi
+	secant := math.Abs(phi1-phi2) >= 1e-10
+	if l.ellipse {
+		m1 := msfn(sinphi, cosphi, p.es)
+		ml1 := tsfn(phi1, sinphi, p.e)
+		if secant {
+			sinphi = math.Sin(phi2)
+			l.n = math.Log(m1 / msfn(sinphi, math.Cos(phi2), p.es))
+			l.n /= math.Log(ml1 / tsfn(phi2, sinphi, p.e))
+		}
+		l.c = m1 * math.Pow(ml1, -l.n) / l.n
+		if math.Abs(math.Abs(p.phi0)-halfPi) < 1e-10 {
+			l.rho0 = 0
+		} else {
+			l.rho0 = l.c * math.Pow(tsfn(p.phi0, math.Sin(p.phi0), p.e), l.n)
+		}
+	} else {
+		if secant {
+			l.n = math.Log(cosphi/math.Cos(phi2)) /
+				math.Log(math.Tan(math.Pi/4+.5*phi2)/math.Tan(math.Pi/4+.5*phi1))
+		}
+		l.c = cosphi * math.Pow(math.Tan(math.Pi/4+.5*phi1), l.n) / l.n
+		if math.Abs(math.Abs(p.phi0)-halfPi) < 1e-10 {
+			l.rho0 = 0
+		} else {
+			l.rho0 = l.c * math.Pow(math.Tan(math.Pi/4+.5*p.phi0), -l.n)
+		}
+	}
+
+	return l, nil
+}
+
+func (l *lambertConformalConic) forward(lam float64, phi float64) (float64, float64, error) {
+	var rho float64
+	if math.Abs(math.Abs(phi)-halfPi) < 1e-10 {
+		if phi*l.n <= 0 {
+			return 0, 0, errors.New("point at the pole opposite to the cone apex")
+		}
+		rho = 0
+	} else if l.ellipse {
+		rho = l.c * math.Pow(tsfn(phi, math.Sin(phi), l.e), l.n)
+	} else {
+		rho = l.c * math.Pow(math.Tan(math.Pi/4+.5*phi), -l.n)
+	}
+
+	lam *= l.n
+	return l.k0 * (rho * math.Sin(lam)), l.k0 * (l.rho0 - rho*math.Cos(lam)), nil
+}
+
+func (l *lambertConformalConic) inverse(x float64, y float64) (float64, float64, error) {
+	x /= l.k0
+	y /= l.k0
+	y = l.rho0 - y
+	rho := math.Hypot(x, y)
+	if rho == 0 {
+		if l.n > 0 {
+			return 0, halfPi, nil
+		}
+		return 0, -halfPi, nil
+	}
+
+	if l.n < 0 {
+		rho = -rho
+		x = -x
+		y = -y
+	}
+
+	var phi float64
+	if l.ellipse {
+		var err error
+		if phi, err = phi2(math.Pow(rho/l.c, 1/l.n), l.e); err != nil {
+			return 0, 0, err
+		}
+	} else {
+		phi = 2*math.Atan(math.Pow(l.c/rho, 1/l.n)) - halfPi
+	}
+
+	return math.Atan2(x, y) / l.n, phi, nil
+}
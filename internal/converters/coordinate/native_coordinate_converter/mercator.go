@@ -0,0 +1,59 @@
+package native_coordinate_converter
+
+import (
+	"errors"
+	"math"
+)
+
+// Mercator projection, on the ellipsoid as the World Mercator EPSG:3395 or on the sphere as the Web Mercator
+// EPSG:3857. The scale factor is given by +k or by the true scale latitude +lat_ts
+type mercator struct {
+	e  float64
+	k0 float64
+}
+
+func newMercator(p *projection, parameters proj4Parameters) (*mercator, error) {
+	if parameters.has("lat_ts") {
+		phits, err := parameters.angle("lat_ts")
+		if err != nil {
+			return nil, err
+		}
+		phits = math.Abs(phits)
+		if phits >= halfPi {
+			return nil, errors.New("true scale latitude must be lower than 90 degrees")
+		}
+		if p.es != 0 {
+			p.k0 = msfn(math.Sin(phits), math.Cos(phits), p.es)
+		} else {
+			p.k0 = math.Cos(phits)
+		}
+	}
+
+	return &mercator{
+		e:  p.e,
+		k0: p.k0,
+	}, nil
+}
+
+func (m *mercator) forward(lam float64, phi float64) (float64, float64, error) {
+	if math.Abs(math.Abs(phi)-halfPi) <= 1e-10 {
+		return 0, 0, errors.New("poles cannot be projected")
+	}
+
+	if m.e == 0 {
+		return m.k0 * lam, m.k0 * math.Log(math.Tan(math.Pi/4+.5*phi)), nil
+	}
+	return m.k0 * lam, -m.k0 * math.Log(tsfn(phi, math.Sin(phi), m.e)), nil
+}
+
+func (m *mercator) inverse(x float64, y float64) (float64, float64, error) {
+	if m.e == 0 {
+		return x / m.k0, halfPi - 2*math.Atan(math.Exp(-y/m.k0)), nil
+	}
+
+	phi, err := phi2(math.Exp(-y/m.k0), m.e)
+	if err != nil {
+		return 0, 0, err
+	}
+	return x / m.k0, phi, nil
+}
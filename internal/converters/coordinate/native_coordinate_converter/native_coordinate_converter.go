@@ -0,0 +1,223 @@
+package native_coordinate_converter
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ecopia-map/cesium_tiler/internal/converters"
+	"github.com/ecopia-map/cesium_tiler/internal/geometry"
+	"github.com/ecopia-map/cesium_tiler/tools"
+	"github.com/golang/glog"
+)
+
+// Converts coordinates with a pure Go port of the proj4 4.9 projections: Transverse Mercator and UTM, Lambert
+// Conformal Conic, Mercator, geographic and geocentric systems, with translation and Helmert datum shifts. Conversions
+// don't go through cgo and can run concurrently, the projections are parsed from the EPSG database once, under lock,
+// and are read only afterwards. EPSG codes whose definition needs a projection or a grid shift which is not supported
+// return an error
+type nativeCoordinateConverter struct {
+	EpsgDatabase map[int]*epsgProjection
+	sync.RWMutex
+}
+
+// Returns the native converter of the EPSG codes of the projection database in the assets folder. An error is returned
+// if the database cannot be read
+func NewNativeCoordinateConverter() (converters.CoordinateConverter, error) {
+	rootFolder, err := tools.GetRootFolder()
+	if err != nil {
+		return nil, err
+	}
+	file := path.Join(rootFolder, "assets", "epsg_projections.txt")
+
+	epsgDatabase, err := loadEPSGProjectionDatabase(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return &nativeCoordinateConverter{
+		EpsgDatabase: epsgDatabase,
+	}, nil
+}
+
+func loadEPSGProjectionDatabase(databasePath string) (map[int]*epsgProjection, error) {
+	file, err := os.Open(databasePath)
+	if err != nil {
+		return nil, fmt.Errorf("error loading the epsg projection file: %v", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	var epsgDatabase = make(map[int]*epsgProjection)
+
+	scanner := bufio.NewScanner(file)
+
+	for scanner.Scan() {
+		record := scanner.Text()
+		code, projection, err := parseEPSGProjectionDatabaseRecord(record)
+		if err != nil {
+			return nil, fmt.Errorf("error while parsing the epsg projection file [%s]: %v", databasePath, err)
+		}
+		epsgDatabase[code] = projection
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading the epsg projection file [%s]: %v", databasePath, err)
+	}
+
+	return epsgDatabase, nil
+}
+
+func parseEPSGProjectionDatabaseRecord(databaseRecord string) (int, *epsgProjection, error) {
+	tokens := strings.Split(databaseRecord, "\t")
+	if len(tokens) < 3 {
+		return 0, nil, fmt.Errorf("invalid record [%s]", databaseRecord)
+	}
+	code, err := strconv.Atoi(strings.Replace(tokens[0], "EPSG:", "", -1))
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid epsg code [%s]", tokens[0])
+	}
+
+	return code, &epsgProjection{
+		EpsgCode:    code,
+		Description: tokens[1],
+		Proj4:       tokens[2],
+	}, nil
+}
+
+// Converts the given coordinate from the given source Srid to the given target srid.
+func (cc *nativeCoordinateConverter) ConvertCoordinateSrid(sourceSrid int, targetSrid int, coord geometry.Coordinate) (geometry.Coordinate, error) {
+	if sourceSrid == targetSrid {
+		return coord, nil
+	}
+
+	src, err := cc.initProjection(sourceSrid)
+	if err != nil {
+		glog.Infoln(err)
+		return coord, err
+	}
+
+	dst, err := cc.initProjection(targetSrid)
+	if err != nil {
+		glog.Infoln(err)
+		return coord, err
+	}
+
+	return executeConversion(coord, src, dst)
+}
+
+// Converts in place the given coordinates from the given source Srid to the given target srid. The projections are
+// looked up once, then the coordinates are converted one by one as there is no per call overhead to spare
+func (cc *nativeCoordinateConverter) ConvertCoordinatesSrid(sourceSrid int, targetSrid int, coords []geometry.Coordinate) error {
+	if sourceSrid == targetSrid || len(coords) == 0 {
+		return nil
+	}
+
+	src, err := cc.initProjection(sourceSrid)
+	if err != nil {
+		glog.Infoln(err)
+		return err
+	}
+
+	dst, err := cc.initProjection(targetSrid)
+	if err != nil {
+		glog.Infoln(err)
+		return err
+	}
+
+	for i, coord := range coords {
+		if coords[i], err = executeConversion(coord, src, dst); err != nil {
+			return fmt.Errorf("unable to convert coordinate X:[%f] Y:[%f] Z:[%f]: %v", coord.X, coord.Y, coord.Z, err)
+		}
+	}
+	return nil
+}
+
+// Converts the generic bounding box bounds values from the given input srid to a EPSG:4326 srid (in radians)
+// and returns a float64 array containing xMin, yMin, xMax, yMax, zMin, zMax. Z values are left unchanged
+func (cc *nativeCoordinateConverter) Convert2DBoundingboxToWGS84Region(bbox *geometry.BoundingBox, srid int) (*geometry.BoundingBox, error) {
+	w84lc, err := cc.ConvertCoordinateSrid(srid, 4326, geometry.Coordinate{X: bbox.Xmin, Y: bbox.Ymin, Z: 0})
+	if err != nil {
+		return nil, err
+	}
+	w84uc, err := cc.ConvertCoordinateSrid(srid, 4326, geometry.Coordinate{X: bbox.Xmax, Y: bbox.Ymax, Z: 0})
+	if err != nil {
+		return nil, err
+	}
+
+	return geometry.NewBoundingBox(w84lc.X*toRadians, w84lc.Y*toRadians, w84uc.X*toRadians, w84uc.Y*toRadians, bbox.Zmin, bbox.Zmax), nil
+}
+
+// Converts the input coordinate from the given srid to EPSG:4978 srid, the WGS84 geocentric coordinates. As the
+// geographic and geocentric WGS84 systems share the datum the coordinate is converted in a single step
+func (cc *nativeCoordinateConverter) ConvertToWGS84Cartesian(coord geometry.Coordinate, sourceSrid int) (geometry.Coordinate, error) {
+	return cc.ConvertCoordinateSrid(sourceSrid, 4978, coord)
+}
+
+// Converts in place the given coordinates from the given srid to EPSG:4978 srid, the WGS84 geocentric coordinates
+func (cc *nativeCoordinateConverter) ConvertToWGS84CartesianBatch(coords []geometry.Coordinate, sourceSrid int) error {
+	return cc.ConvertCoordinatesSrid(sourceSrid, 4978, coords)
+}
+
+// Releases the parsed projections
+func (cc *nativeCoordinateConverter) Cleanup() {
+	cc.Lock()
+	defer cc.Unlock()
+
+	for _, val := range cc.EpsgDatabase {
+		val.Projection = nil
+	}
+}
+
+func executeConversion(coord geometry.Coordinate, src *projection, dst *projection) (geometry.Coordinate, error) {
+	x, y := coord.X, coord.Y
+	if src.isLatLong {
+		x *= toRadians
+		y *= toRadians
+	}
+
+	x, y, z, err := transform(src, dst, x, y, coord.Z)
+	if err != nil {
+		return coord, err
+	}
+
+	if dst.isLatLong {
+		x *= toDeg
+		y *= toDeg
+	}
+
+	return geometry.Coordinate{X: x, Y: y, Z: z}, nil
+}
+
+// Returns the projection corresponding to the given EPSG code, storing it in the relevant EpsgDatabase entry for caching
+func (cc *nativeCoordinateConverter) initProjection(code int) (*projection, error) {
+	cc.RLock()
+	val, ok := cc.EpsgDatabase[code]
+	var projection *projection
+	if ok {
+		projection = val.Projection
+	}
+	cc.RUnlock()
+
+	if !ok {
+		return nil, errors.New("epsg code not found")
+	} else if projection != nil {
+		return projection, nil
+	}
+
+	cc.Lock()
+	defer cc.Unlock()
+
+	if val.Projection == nil {
+		parsed, err := newProjection(val.Proj4)
+		if err != nil {
+			return nil, fmt.Errorf("unable to init projection of epsg code %d: %v", code, err)
+		}
+		val.Projection = parsed
+	}
+	return val.Projection, nil
+}
@@ -0,0 +1,160 @@
+package native_coordinate_converter
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Parameters of a proj4 definition in the order they are given. As in proj4 the first occurrence of a parameter is
+// the one used, so that the parameters expanded from +datum and +ellps don't override the ones given explicitly
+type proj4Parameters []proj4Parameter
+
+type proj4Parameter struct {
+	name  string
+	value string
+}
+
+func parseProj4Parameters(definition string) proj4Parameters {
+	var parameters proj4Parameters
+	for _, token := range strings.Fields(definition) {
+		token = strings.TrimPrefix(token, "+")
+		if token == "" {
+			continue
+		}
+		tokens := strings.SplitN(token, "=", 2)
+		parameter := proj4Parameter{name: tokens[0]}
+		if len(tokens) == 2 {
+			parameter.value = tokens[1]
+		}
+		parameters = append(parameters, parameter)
+	}
+
+	return parameters
+}
+
+func (p *proj4Parameters) add(name string, value string) {
+	*p = append(*p, proj4Parameter{name: name, value: value})
+}
+
+// Returns true if the parameter is given
+func (p proj4Parameters) has(name string) bool {
+	_, ok := p.get(name)
+	return ok
+}
+
+// Returns the value of the first occurrence of the parameter
+func (p proj4Parameters) get(name string) (string, bool) {
+	for _, parameter := range p {
+		if parameter.name == name {
+			return parameter.value, true
+		}
+	}
+	return "", false
+}
+
+// Returns true if the flag parameter is given, either without value or with a value starting by t or T
+func (p proj4Parameters) flag(name string) bool {
+	value, ok := p.get(name)
+	if !ok {
+		return false
+	}
+	return value == "" || value[0] == 't' || value[0] == 'T'
+}
+
+// Returns the numeric value of the parameter, 0 if it is not given
+func (p proj4Parameters) float(name string) (float64, error) {
+	value, ok := p.get(name)
+	if !ok {
+		return 0, nil
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value [%s] of parameter +%s", value, name)
+	}
+	return number, nil
+}
+
+// Returns the value in radians of the angle parameter, given either in decimal degrees or in the DMS notation of
+// proj4, 0 if it is not given
+func (p proj4Parameters) angle(name string) (float64, error) {
+	value, ok := p.get(name)
+	if !ok {
+		return 0, nil
+	}
+
+	degrees, err := parseDMS(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value [%s] of parameter +%s", value, name)
+	}
+	return degrees * toRadians, nil
+}
+
+// Parses an angle in degrees given either as a decimal number or in the DMS notation of proj4, such as 9d07'54.862"W
+func parseDMS(value string) (float64, error) {
+	if value == "" {
+		return 0, errors.New("empty angle")
+	}
+	if degrees, err := strconv.ParseFloat(value, 64); err == nil {
+		return degrees, nil
+	}
+
+	sign := 1.0
+	switch value[len(value)-1] {
+	case 'W', 'w', 'S', 's':
+		sign = -1
+		value = value[:len(value)-1]
+	case 'E', 'e', 'N', 'n':
+		value = value[:len(value)-1]
+	}
+	if strings.HasPrefix(value, "-") {
+		sign = -sign
+		value = value[1:]
+	} else {
+		value = strings.TrimPrefix(value, "+")
+	}
+
+	degrees := 0.0
+	divisor := 1.0
+	for _, separator := range []string{"d", "'", "\""} {
+		index := strings.Index(value, separator)
+		if index < 0 {
+			continue
+		}
+		number, err := strconv.ParseFloat(value[:index], 64)
+		if err != nil {
+			return 0, err
+		}
+		degrees += number / divisor
+		divisor *= 60
+		value = value[index+1:]
+	}
+	if value != "" || divisor == 1 {
+		return 0, errors.New("invalid angle")
+	}
+
+	return sign * degrees, nil
+}
+
+// Parses a unit conversion factor, given either as a number or as a fraction such as 1/1000
+func parseUnitFactor(value string) (float64, error) {
+	tokens := strings.SplitN(value, "/", 2)
+	factor, err := strconv.ParseFloat(tokens[0], 64)
+	if err != nil {
+		return 0, err
+	}
+	if len(tokens) == 2 {
+		divisor, err := strconv.ParseFloat(tokens[1], 64)
+		if err != nil {
+			return 0, err
+		}
+		factor /= divisor
+	}
+	if factor <= 0 || math.IsInf(factor, 0) {
+		return 0, errors.New("invalid unit factor")
+	}
+	return factor, nil
+}
@@ -0,0 +1,392 @@
+package native_coordinate_converter
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const toRadians = math.Pi / 180
+const toDeg = 180 / math.Pi
+const halfPi = math.Pi / 2
+
+// Semi-major axis and squared eccentricity of the WGS84 ellipsoid, the reference of the +towgs84 datum shifts
+const wgs84SemiMajorAxis = 6378137.0
+const wgs84SquaredEccentricity = 0.0066943799901413165
+
+// Conversion of the +towgs84 rotations from arc seconds to radians
+const secondsToRadians = 4.84813681109535993589914102357e-6
+
+type datumType int
+
+const (
+	// No datum is given, coordinates are not shifted when the datum changes
+	datumUnknown datumType = iota
+
+	// Datum shifted to WGS84 by a translation
+	datum3Param
+
+	// Datum shifted to WGS84 by a Helmert transformation of 3 translations, 3 rotations and a scale factor
+	datum7Param
+
+	// Datum shifted to WGS84 by a grid, only the null grid, which leaves the coordinates unchanged, is supported
+	datumGridShift
+
+	// WGS84 datum or one equivalent to it, as NAD83
+	datumWGS84
+)
+
+// Projects geodetic coordinates, in radians with the longitude relative to the central meridian, to the coordinates
+// of an ellipsoid with unit semi-major axis and back, as the projection functions of proj4
+type projector interface {
+	forward(lam float64, phi float64) (float64, float64, error)
+	inverse(x float64, y float64) (float64, float64, error)
+}
+
+// Coordinate reference system parsed from a proj4 definition, it reproduces the computations of proj4 4.9 for the
+// projections supported
+type projection struct {
+	isLatLong bool
+	isGeocent bool
+
+	// semi-major axis, squared eccentricity and eccentricity of the ellipsoid
+	a  float64
+	ra float64
+	es float64
+	e  float64
+
+	lam0 float64
+	phi0 float64
+	x0   float64
+	y0   float64
+	k0   float64
+
+	toMeter  float64
+	frMeter  float64
+	vtoMeter float64
+	vfrMeter float64
+
+	fromGreenwich float64
+	over          bool
+
+	datumType   datumType
+	datumParams [7]float64
+	nadgrids    string
+
+	projector projector
+}
+
+// Parses the given proj4 definition, returning an error if it uses a projection or an option which is not supported
+func newProjection(definition string) (*projection, error) {
+	parameters := parseProj4Parameters(definition)
+	for _, unsupported := range []string{"axis", "geoc", "lon_wrap", "geoidgrids", "catalog", "init", "R_A", "R_V", "R_a", "R_g", "R_h", "R_lat_a", "R_lat_g"} {
+		if parameters.has(unsupported) {
+			return nil, fmt.Errorf("parameter +%s is not supported", unsupported)
+		}
+	}
+
+	name, ok := parameters.get("proj")
+	if !ok {
+		return nil, errors.New("projection not given")
+	}
+
+	p := &projection{}
+	if err := p.setDatum(&parameters); err != nil {
+		return nil, err
+	}
+	if err := p.setEllipsoid(&parameters); err != nil {
+		return nil, err
+	}
+	if err := p.setParameters(parameters); err != nil {
+		return nil, err
+	}
+
+	var err error
+	switch name {
+	case "longlat", "latlong", "lonlat", "latlon":
+		p.isLatLong = true
+		p.x0 = 0
+		p.y0 = 0
+	case "geocent":
+		p.isGeocent = true
+		p.x0 = 0
+		p.y0 = 0
+	case "tmerc":
+		p.projector = newTransverseMercator(p)
+	case "utm":
+		p.projector, err = newUniversalTransverseMercator(p, parameters)
+	case "lcc":
+		p.projector, err = newLambertConformalConic(p, parameters)
+	case "merc":
+		p.projector, err = newMercator(p, parameters)
+	default:
+		return nil, fmt.Errorf("projection %s is not supported", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// Sets the datum, expanding the +datum parameter in the +ellps and +towgs84 or +nadgrids ones as proj4 does
+func (p *projection) setDatum(parameters *proj4Parameters) error {
+	p.datumType = datumUnknown
+
+	if name, ok := parameters.get("datum"); ok {
+		datum, ok := datums[name]
+		if !ok {
+			return fmt.Errorf("unknown datum %s", name)
+		}
+		parameters.add("ellps", datum.ellipsoid)
+		tokens := strings.SplitN(datum.definition, "=", 2)
+		parameters.add(tokens[0], tokens[1])
+	}
+
+	if nadgrids, ok := parameters.get("nadgrids"); ok {
+		if nadgrids != "@null" {
+			return fmt.Errorf("grid shift files [%s] are not supported", nadgrids)
+		}
+		p.datumType = datumGridShift
+		p.nadgrids = nadgrids
+	} else if towgs84, ok := parameters.get("towgs84"); ok {
+		for i, value := range strings.Split(towgs84, ",") {
+			if i >= len(p.datumParams) {
+				break
+			}
+			number, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return fmt.Errorf("invalid value [%s] of parameter +towgs84", towgs84)
+			}
+			p.datumParams[i] = number
+		}
+
+		if p.datumParams[3] != 0 || p.datumParams[4] != 0 || p.datumParams[5] != 0 || p.datumParams[6] != 0 {
+			p.datumType = datum7Param
+			p.datumParams[3] *= secondsToRadians
+			p.datumParams[4] *= secondsToRadians
+			p.datumParams[5] *= secondsToRadians
+			p.datumParams[6] = p.datumParams[6]/1000000.0 + 1
+		} else {
+			p.datumType = datum3Param
+		}
+	}
+
+	return nil
+}
+
+// Sets the ellipsoid from the +R, +a, +es, +e, +rf, +f and +b parameters, the +ellps parameter gives the defaults
+// of the +a and +rf or +b ones
+func (p *projection) setEllipsoid(parameters *proj4Parameters) error {
+	var err error
+	b := 0.0
+
+	if parameters.has("R") {
+		if p.a, err = parameters.float("R"); err != nil {
+			return err
+		}
+	} else {
+		extended := append(proj4Parameters{}, *parameters...)
+		if name, ok := extended.get("ellps"); ok {
+			ellipsoid, ok := ellipsoids[name]
+			if !ok {
+				return fmt.Errorf("unknown ellipsoid %s", name)
+			}
+			for _, definition := range []string{ellipsoid.major, ellipsoid.minor} {
+				tokens := strings.SplitN(definition, "=", 2)
+				extended.add(tokens[0], tokens[1])
+			}
+		}
+
+		if p.a, err = extended.float("a"); err != nil {
+			return err
+		}
+		if extended.has("es") {
+			if p.es, err = extended.float("es"); err != nil {
+				return err
+			}
+		} else if extended.has("e") {
+			e, err := extended.float("e")
+			if err != nil {
+				return err
+			}
+			p.es = e * e
+		} else if extended.has("rf") {
+			rf, err := extended.float("rf")
+			if err != nil {
+				return err
+			}
+			if rf == 0 {
+				return errors.New("reciprocal flattening cannot be 0")
+			}
+			p.es = 1 / rf
+			p.es = p.es * (2 - p.es)
+		} else if extended.has("f") {
+			f, err := extended.float("f")
+			if err != nil {
+				return err
+			}
+			p.es = f * (2 - f)
+		} else if extended.has("b") {
+			if b, err = extended.float("b"); err != nil {
+				return err
+			}
+			p.es = 1 - (b*b)/(p.a*p.a)
+		}
+	}
+
+	if p.es < 0 {
+		return errors.New("squared eccentricity cannot be negative")
+	}
+	if p.a <= 0 {
+		return errors.New("semi-major axis must be greater than 0")
+	}
+	if p.es >= 1 {
+		return errors.New("squared eccentricity must be lower than 1")
+	}
+	p.e = math.Sqrt(p.es)
+	p.ra = 1 / p.a
+
+	// a WGS84 or GRS80 ellipsoid without shift is the WGS84 datum
+	if p.datumType == datum3Param && p.datumParams[0] == 0 && p.datumParams[1] == 0 && p.datumParams[2] == 0 &&
+		p.a == wgs84SemiMajorAxis && math.Abs(p.es-0.006694379990) < 0.000000000050 {
+		p.datumType = datumWGS84
+	}
+
+	return nil
+}
+
+// Sets the parameters shared by all the projections: origin, false easting and northing, scale factor, units and
+// prime meridian
+func (p *projection) setParameters(parameters proj4Parameters) error {
+	var err error
+
+	p.over = parameters.flag("over")
+	if p.lam0, err = parameters.angle("lon_0"); err != nil {
+		return err
+	}
+	if p.phi0, err = parameters.angle("lat_0"); err != nil {
+		return err
+	}
+	if p.x0, err = parameters.float("x_0"); err != nil {
+		return err
+	}
+	if p.y0, err = parameters.float("y_0"); err != nil {
+		return err
+	}
+
+	p.k0 = 1
+	if parameters.has("k_0") {
+		p.k0, err = parameters.float("k_0")
+	} else if parameters.has("k") {
+		p.k0, err = parameters.float("k")
+	}
+	if err != nil {
+		return err
+	}
+	if p.k0 <= 0 {
+		return errors.New("scale factor must be greater than 0")
+	}
+
+	if p.toMeter, err = parseUnits(parameters, "units", "to_meter", 1); err != nil {
+		return err
+	}
+	p.frMeter = 1 / p.toMeter
+	if p.vtoMeter, err = parseUnits(parameters, "vunits", "vto_meter", p.toMeter); err != nil {
+		return err
+	}
+	p.vfrMeter = 1 / p.vtoMeter
+
+	if name, ok := parameters.get("pm"); ok {
+		value, ok := primeMeridians[name]
+		if !ok {
+			value = name
+		}
+		degrees, err := parseDMS(value)
+		if err != nil {
+			return fmt.Errorf("unknown prime meridian %s", name)
+		}
+		p.fromGreenwich = degrees * toRadians
+	}
+
+	return nil
+}
+
+// Returns the factor converting the units given either by name or by factor to meters
+func parseUnits(parameters proj4Parameters, unitsName string, factorName string, defaultFactor float64) (float64, error) {
+	value, ok := "", false
+	if name, isNamed := parameters.get(unitsName); isNamed {
+		if value, ok = units[name]; !ok {
+			return 0, fmt.Errorf("unknown units %s", name)
+		}
+	} else {
+		value, ok = parameters.get(factorName)
+	}
+	if !ok {
+		return defaultFactor, nil
+	}
+
+	factor, err := parseUnitFactor(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value [%s] of parameter +%s", value, factorName)
+	}
+	return factor, nil
+}
+
+// Projects the given geodetic coordinates in radians, returning the coordinates in the units of the projection
+func (p *projection) forward(lam float64, phi float64) (float64, float64, error) {
+	t := math.Abs(phi) - halfPi
+	if t > 1e-12 || math.Abs(lam) > 10 {
+		return 0, 0, errors.New("latitude or longitude out of range")
+	}
+	if math.Abs(t) <= 1e-12 {
+		if phi < 0 {
+			phi = -halfPi
+		} else {
+			phi = halfPi
+		}
+	}
+
+	lam -= p.lam0
+	if !p.over {
+		lam = adjustLongitude(lam)
+	}
+
+	x, y, err := p.projector.forward(lam, phi)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return p.frMeter * (p.a*x + p.x0), p.frMeter * (p.a*y + p.y0), nil
+}
+
+// Returns the geodetic coordinates in radians of the given projected coordinates
+func (p *projection) inverse(x float64, y float64) (float64, float64, error) {
+	x = (x*p.toMeter - p.x0) * p.ra
+	y = (y*p.toMeter - p.y0) * p.ra
+
+	lam, phi, err := p.projector.inverse(x, y)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	lam += p.lam0
+	if !p.over {
+		lam = adjustLongitude(lam)
+	}
+
+	return lam, phi, nil
+}
+
+// Reduces the longitude to the range -pi, pi
+func adjustLongitude(lon float64) float64 {
+	if math.Abs(lon) <= 3.14159265359 {
+		return lon
+	}
+	lon += math.Pi
+	lon -= 2 * math.Pi * math.Floor(lon/(2*math.Pi))
+	lon -= math.Pi
+	return lon
+}
@@ -0,0 +1,70 @@
+package native_coordinate_converter
+
+import (
+	"errors"
+	"math"
+)
+
+// Functions shared by the projections, ported from proj4
+
+// Coefficients of the meridian distance series for the given squared eccentricity
+func meridianDistanceCoefficients(es float64) [5]float64 {
+	var en [5]float64
+	en[0] = 1 - es*(.25+es*(.046875+es*(.01953125+es*.01068115234375)))
+	en[1] = es * (.75 - es*(.046875+es*(.01953125+es*.01068115234375)))
+	t := es * es
+	en[2] = t * (.46875 - es*(.01302083333333333333+es*.00712076822916666666))
+	t *= es
+	en[3] = t * (.36458333333333333333 - es*.00569661458333333333)
+	en[4] = t * es * .3076171875
+	return en
+}
+
+// Meridian distance on the ellipsoid with unit semi-major axis from the equator to the given latitude
+func meridianDistance(phi float64, sinphi float64, cosphi float64, en [5]float64) float64 {
+	cosphi *= sinphi
+	sinphi *= sinphi
+	return en[0]*phi - cosphi*(en[1]+sinphi*(en[2]+sinphi*(en[3]+sinphi*en[4])))
+}
+
+// Latitude at the given meridian distance, found iteratively
+func inverseMeridianDistance(arg float64, es float64, en [5]float64) (float64, error) {
+	k := 1 / (1 - es)
+	phi := arg
+	for i := 10; i > 0; i-- {
+		s := math.Sin(phi)
+		t := 1 - es*s*s
+		t = (meridianDistance(phi, s, math.Cos(phi), en) - arg) * (t * math.Sqrt(t)) * k
+		phi -= t
+		if math.Abs(t) < 1e-11 {
+			return phi, nil
+		}
+	}
+	return phi, errors.New("latitude of the meridian distance did not converge")
+}
+
+// Isometric latitude function t of the conformal projections
+func tsfn(phi float64, sinphi float64, e float64) float64 {
+	sinphi *= e
+	return math.Tan(.5*(halfPi-phi)) / math.Pow((1-sinphi)/(1+sinphi), .5*e)
+}
+
+// Latitude of the given value of the t function, found iteratively
+func phi2(ts float64, e float64) (float64, error) {
+	eccnth := .5 * e
+	phi := halfPi - 2*math.Atan(ts)
+	for i := 15; i > 0; i-- {
+		con := e * math.Sin(phi)
+		dphi := halfPi - 2*math.Atan(ts*math.Pow((1-con)/(1+con), eccnth)) - phi
+		phi += dphi
+		if math.Abs(dphi) <= 1.0e-10 {
+			return phi, nil
+		}
+	}
+	return phi, errors.New("latitude of the conformal projection did not converge")
+}
+
+// Ratio between the radius of the parallel and the semi-major axis
+func msfn(sinphi float64, cosphi float64, es float64) float64 {
+	return cosphi / math.Sqrt(1-es*sinphi*sinphi)
+}
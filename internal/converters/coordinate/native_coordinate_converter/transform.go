@@ -0,0 +1,233 @@
+package native_coordinate_converter
+
+import (
+	"errors"
+	"math"
+)
+
+// Transforms the given coordinate from the source to the target projection following the steps of proj4: the source
+// coordinates are converted to geodetic ones, shifted to the target datum and projected. Geodetic coordinates are in
+// radians, Z is the ellipsoidal height
+func transform(src *projection, dst *projection, x float64, y float64, z float64) (float64, float64, float64, error) {
+	var err error
+
+	if src.vtoMeter != 1 {
+		z *= src.vtoMeter
+	}
+
+	if src.isGeocent {
+		if src.toMeter != 1 {
+			x *= src.toMeter
+			y *= src.toMeter
+		}
+		x, y, z = geocentricToGeodetic(src.a, src.es, x, y, z)
+	} else if !src.isLatLong {
+		if x, y, err = src.inverse(x, y); err != nil {
+			return 0, 0, 0, err
+		}
+	}
+
+	x += src.fromGreenwich
+
+	if x, y, z, err = datumTransform(src, dst, x, y, z); err != nil {
+		return 0, 0, 0, err
+	}
+
+	x -= dst.fromGreenwich
+
+	if dst.isGeocent {
+		if x, y, z, err = geodeticToGeocentric(dst.a, dst.es, x, y, z); err != nil {
+			return 0, 0, 0, err
+		}
+		if dst.frMeter != 1 {
+			x *= dst.frMeter
+			y *= dst.frMeter
+		}
+	} else if !dst.isLatLong {
+		if x, y, err = dst.forward(x, y); err != nil {
+			return 0, 0, 0, err
+		}
+	}
+
+	if dst.vtoMeter != 1 {
+		z *= dst.vfrMeter
+	}
+
+	return x, y, z, nil
+}
+
+// Returns true if the coordinates don't change between the datums of the two projections
+func isSameDatum(src *projection, dst *projection) bool {
+	if src.datumType != dst.datumType {
+		return false
+	}
+	// the tolerance of the squared eccentricity makes GRS80 and WGS84 identical
+	if src.a != dst.a || math.Abs(src.es-dst.es) > 0.000000000050 {
+		return false
+	}
+
+	switch src.datumType {
+	case datum3Param:
+		return src.datumParams[0] == dst.datumParams[0] &&
+			src.datumParams[1] == dst.datumParams[1] &&
+			src.datumParams[2] == dst.datumParams[2]
+	case datum7Param:
+		return src.datumParams == dst.datumParams
+	case datumGridShift:
+		return src.nadgrids == dst.nadgrids
+	}
+	return true
+}
+
+// Shifts the geodetic coordinates from the datum of the source projection to the one of the target projection,
+// through geocentric coordinates and WGS84 if the ellipsoids or the datums differ
+func datumTransform(src *projection, dst *projection, lam float64, phi float64, h float64) (float64, float64, float64, error) {
+	if src.datumType == datumUnknown || dst.datumType == datumUnknown || isSameDatum(src, dst) {
+		return lam, phi, h, nil
+	}
+
+	srcA, srcEs := src.a, src.es
+	dstA, dstEs := dst.a, dst.es
+	// the null grid leaves the coordinates unchanged and refers them to the WGS84 ellipsoid
+	if src.datumType == datumGridShift {
+		srcA, srcEs = wgs84SemiMajorAxis, wgs84SquaredEccentricity
+	}
+	if dst.datumType == datumGridShift {
+		dstA, dstEs = wgs84SemiMajorAxis, wgs84SquaredEccentricity
+	}
+
+	if srcEs == dstEs && srcA == dstA && !src.hasShift() && !dst.hasShift() {
+		return lam, phi, h, nil
+	}
+
+	x, y, z, err := geodeticToGeocentric(srcA, srcEs, lam, phi, h)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	if src.hasShift() {
+		x, y, z = src.geocentricToWGS84(x, y, z)
+	}
+	if dst.hasShift() {
+		x, y, z = dst.geocentricFromWGS84(x, y, z)
+	}
+	lam, phi, h = geocentricToGeodetic(dstA, dstEs, x, y, z)
+
+	return lam, phi, h, nil
+}
+
+// Returns true if the datum is shifted to WGS84 by a translation or by a Helmert transformation
+func (p *projection) hasShift() bool {
+	return p.datumType == datum3Param || p.datumType == datum7Param
+}
+
+// Applies the datum shift of the projection to geocentric coordinates, returning WGS84 geocentric coordinates
+func (p *projection) geocentricToWGS84(x float64, y float64, z float64) (float64, float64, float64) {
+	dx, dy, dz := p.datumParams[0], p.datumParams[1], p.datumParams[2]
+	if p.datumType == datum3Param {
+		return x + dx, y + dy, z + dz
+	}
+
+	rx, ry, rz, m := p.datumParams[3], p.datumParams[4], p.datumParams[5], p.datumParams[6]
+	return m*(x-rz*y+ry*z) + dx,
+		m*(rz*x+y-rx*z) + dy,
+		m*(-ry*x+rx*y+z) + dz
+}
+
+// Applies the inverse of the datum shift of the projection to WGS84 geocentric coordinates
+func (p *projection) geocentricFromWGS84(x float64, y float64, z float64) (float64, float64, float64) {
+	dx, dy, dz := p.datumParams[0], p.datumParams[1], p.datumParams[2]
+	if p.datumType == datum3Param {
+		return x - dx, y - dy, z - dz
+	}
+
+	rx, ry, rz, m := p.datumParams[3], p.datumParams[4], p.datumParams[5], p.datumParams[6]
+	x = (x - dx) / m
+	y = (y - dy) / m
+	z = (z - dz) / m
+	return x + rz*y - ry*z,
+		-rz*x + y + rx*z,
+		ry*x - rx*y + z
+}
+
+// Converts geodetic coordinates in radians and ellipsoidal height to geocentric coordinates on the ellipsoid with the
+// given semi-major axis and squared eccentricity
+func geodeticToGeocentric(a float64, es float64, lam float64, phi float64, h float64) (float64, float64, float64, error) {
+	e2 := geocentricSquaredEccentricity(a, es)
+
+	if phi < -halfPi && phi > -1.001*halfPi {
+		phi = -halfPi
+	} else if phi > halfPi && phi < 1.001*halfPi {
+		phi = halfPi
+	} else if phi < -halfPi || phi > halfPi {
+		return 0, 0, 0, errors.New("latitude out of range")
+	}
+
+	if lam > math.Pi {
+		lam -= 2 * math.Pi
+	}
+	sinLat := math.Sin(phi)
+	cosLat := math.Cos(phi)
+	rn := a / math.Sqrt(1.0-e2*sinLat*sinLat)
+
+	return (rn + h) * cosLat * math.Cos(lam),
+		(rn + h) * cosLat * math.Sin(lam),
+		(rn*(1-e2) + h) * sinLat,
+		nil
+}
+
+// Converts geocentric coordinates to geodetic coordinates in radians and ellipsoidal height, with the iterative
+// method of proj4
+func geocentricToGeodetic(a float64, es float64, x float64, y float64, z float64) (float64, float64, float64) {
+	const precision = 1.e-12
+	const maxIterations = 30
+
+	e2 := geocentricSquaredEccentricity(a, es)
+	p := math.Sqrt(x*x + y*y)
+	rr := math.Sqrt(x*x + y*y + z*z)
+
+	var lam, phi, h float64
+	if p/a < precision {
+		lam = 0
+		// the center of the earth
+		if rr/a < precision {
+			return lam, halfPi, -a * math.Sqrt(1-es)
+		}
+	} else {
+		lam = math.Atan2(y, x)
+	}
+
+	ct := z / rr
+	st := p / rr
+	rx := 1.0 / math.Sqrt(1.0-e2*(2.0-e2)*st*st)
+	cphi0 := st * (1.0 - e2) * rx
+	sphi0 := ct * rx
+
+	var cphi, sphi float64
+	for iteration := 1; ; iteration++ {
+		rn := a / math.Sqrt(1.0-e2*sphi0*sphi0)
+		h = p*cphi0 + z*sphi0 - rn*(1.0-e2*sphi0*sphi0)
+
+		rk := e2 * rn / (rn + h)
+		rx = 1.0 / math.Sqrt(1.0-rk*(2.0-rk)*st*st)
+		cphi = st * (1.0 - rk) * rx
+		sphi = ct * rx
+		sdphi := sphi*cphi0 - cphi*sphi0
+		cphi0 = cphi
+		sphi0 = sphi
+		if sdphi*sdphi <= precision*precision || iteration >= maxIterations {
+			break
+		}
+	}
+	phi = math.Atan(sphi / math.Abs(cphi))
+
+	return lam, phi, h
+}
+
+// Squared eccentricity computed from the semi-axes, as proj4 does for the geocentric conversions
+func geocentricSquaredEccentricity(a float64, es float64) float64 {
+	b := a
+	if es != 0 {
+		b = a * math.Sqrt(1-es)
+	}
+	return (a*a - b*b) / (a * a)
+}
@@ -0,0 +1,198 @@
+package native_coordinate_converter
+
+import (
+	"errors"
+	"math"
+)
+
+const (
+	fc1 = 1.
+	fc2 = .5
+	fc3 = .16666666666666666666
+	fc4 = .08333333333333333333
+	fc5 = .05
+	fc6 = .03333333333333333333
+	fc7 = .02380952380952380952
+	fc8 = .01785714285714285714
+)
+
+// Transverse Mercator projection with the series of proj4, on the ellipsoid or on the sphere
+type transverseMercator struct {
+	es   float64
+	esp  float64
+	k0   float64
+	phi0 float64
+	ml0  float64
+	en   [5]float64
+}
+
+func newTransverseMercator(p *projection) *transverseMercator {
+	t := &transverseMercator{
+		es:   p.es,
+		k0:   p.k0,
+		phi0: p.phi0,
+	}
+	if p.es != 0 {
+		t.en = meridianDistanceCoefficients(p.es)
+		t.ml0 = meridianDistance(p.phi0, math.Sin(p.phi0), math.Cos(p.phi0), t.en)
+		t.esp = p.es / (1 - p.es)
+	}
+	return t
+}
+
+// Universal Transverse Mercator projection, a Transverse Mercator projection whose central meridian is given by the
+// +zone parameter, or is the one nearest to +lon_0, and whose false northing is 10000000 m if +south is given
+func newUniversalTransverseMercator(p *projection, parameters proj4Parameters) (*transverseMercator, error) {
+	if p.es == 0 {
+		return nil, errors.New("utm projection requires an ellipsoid")
+	}
+
+	p.y0 = 0
+	if parameters.flag("south") {
+		p.y0 = 10000000
+	}
+	p.x0 = 500000
+
+	var zone int
+	if value, ok := parameters.get("zone"); ok {
+		number, err := parameters.float("zone")
+		if err != nil {
+			return nil, err
+		}
+		zone = int(number)
+		if zone <= 0 || zone > 60 {
+			return nil, errors.New("invalid utm zone " + value)
+		}
+		zone--
+	} else {
+		zone = int(math.Floor((adjustLongitude(p.lam0) + math.Pi) * 30 / math.Pi))
+		if zone < 0 {
+			zone = 0
+		} else if zone >= 60 {
+			zone = 59
+		}
+	}
+
+	p.lam0 = (float64(zone)+.5)*math.Pi/30 - math.Pi
+	p.k0 = 0.9996
+	p.phi0 = 0
+
+	return newTransverseMercator(p), nil
+}
+
+func (t *transverseMercator) forward(lam float64, phi float64) (float64, float64, error) {
+	// results are meaningless farther than 90 degrees from the central meridian
+	if lam < -halfPi || lam > halfPi {
+		return 0, 0, errors.New("longitude farther than 90 degrees from the central meridian")
+	}
+
+	if t.es == 0 {
+		return t.sphericalForward(lam, phi)
+	}
+
+	sinphi := math.Sin(phi)
+	cosphi := math.Cos(phi)
+	tn := 0.0
+	if math.Abs(cosphi) > 1e-10 {
+		tn = sinphi / cosphi
+	}
+	tn *= tn
+	al := cosphi * lam
+	als := al * al
+	al /= math.Sqrt(1 - t.es*sinphi*sinphi)
+	n := t.esp * cosphi * cosphi
+
+	x := t.k0 * al * (fc1 +
+		fc3*als*(1-tn+n+
+			fc5*als*(5+tn*(tn-18)+n*(14-58*tn)+
+				fc7*als*(61+tn*(tn*(179-tn)-479)))))
+	y := t.k0 * (meridianDistance(phi, sinphi, cosphi, t.en) - t.ml0 +
+		sinphi*al*lam*fc2*(1+
+			fc4*als*(5-tn+n*(9+4*n)+
+				fc6*als*(61+tn*(tn-58)+n*(270-330*tn)+
+					fc8*als*(1385+tn*(tn*(543-tn)-3111))))))
+
+	return x, y, nil
+}
+
+func (t *transverseMercator) sphericalForward(lam float64, phi float64) (float64, float64, error) {
+	cosphi := math.Cos(phi)
+	b := cosphi * math.Sin(lam)
+	if math.Abs(math.Abs(b)-1) <= 1e-10 {
+		return 0, 0, errors.New("point at 90 degrees from the central meridian")
+	}
+
+	x := .5 * t.k0 * math.Log((1+b)/(1-b))
+	y := cosphi * math.Cos(lam) / math.Sqrt(1-b*b)
+	if b = math.Abs(y); b >= 1 {
+		if b-1 > 1e-10 {
+			return 0, 0, errors.New("point out of the projection domain")
+		}
+		y = 0
+	} else {
+		y = math.Acos(y)
+	}
+	if phi < 0 {
+		y = -y
+	}
+	y = t.k0 * (y - t.phi0)
+
+	return x, y, nil
+}
+
+func (t *transverseMercator) inverse(x float64, y float64) (float64, float64, error) {
+	if t.es == 0 {
+		return t.sphericalInverse(x, y)
+	}
+
+	phi, err := inverseMeridianDistance(t.ml0+y/t.k0, t.es, t.en)
+	if err != nil {
+		return 0, 0, err
+	}
+	if math.Abs(phi) >= halfPi {
+		if y < 0 {
+			return 0, -halfPi, nil
+		}
+		return 0, halfPi, nil
+	}
+
+	sinphi := math.Sin(phi)
+	cosphi := math.Cos(phi)
+	tn := 0.0
+	if math.Abs(cosphi) > 1e-10 {
+		tn = sinphi / cosphi
+	}
+	n := t.esp * cosphi * cosphi
+	con := 1 - t.es*sinphi*sinphi
+	d := x * math.Sqrt(con) / t.k0
+	con *= tn
+	tn *= tn
+	ds := d * d
+
+	phi -= (con * ds / (1 - t.es)) * fc2 * (1 -
+		ds*fc4*(5+tn*(3-9*n)+n*(1-4*n)-
+			ds*fc6*(61+tn*(90-252*n+45*tn)+46*n-
+				ds*fc8*(1385+tn*(3633+tn*(4095+1574*tn))))))
+	lam := d * (fc1 -
+		ds*fc3*(1+2*tn+n-
+			ds*fc5*(5+tn*(28+24*tn+8*n)+6*n-
+				ds*fc7*(61+tn*(662+tn*(1320+720*tn)))))) / cosphi
+
+	return lam, phi, nil
+}
+
+func (t *transverseMercator) sphericalInverse(x float64, y float64) (float64, float64, error) {
+	h := math.Exp(x / t.k0)
+	g := .5 * (h - 1/h)
+	h = math.Cos(t.phi0 + y/t.k0)
+	phi := math.Asin(math.Sqrt((1 - h*h) / (1 + g*g)))
+	if y < 0 {
+		phi = -phi
+	}
+	lam := 0.0
+	if g != 0 || h != 0 {
+		lam = math.Atan2(g, h)
+	}
+
+	return lam, phi, nil
+}
@@ -1,11 +1,8 @@
 package proj4_coordinate_converter
 
-import "github.com/xeonx/proj4"
-
-// Represents a EPSG reference system and stores the relevant projection object for caching reasons
+// Represents a EPSG reference system, whose projection objects are initialized by each set of projections
 type epsgProjection struct {
 	EpsgCode    int
 	Description string
 	Proj4       string
-	Projection  *proj.Proj
 }
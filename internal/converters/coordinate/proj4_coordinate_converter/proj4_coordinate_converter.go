@@ -3,10 +3,13 @@ package proj4_coordinate_converter
 import (
 	"bufio"
 	"errors"
+	"fmt"
 	"math"
+	"os"
 	"path"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/ecopia-map/cesium_tiler/internal/converters"
 	"github.com/ecopia-map/cesium_tiler/internal/geometry"
@@ -18,12 +21,30 @@ import (
 const toRadians = math.Pi / 180
 const toDeg = 180 / math.Pi
 
+// Definition of EPSG:4326, the projection the grid shifts are applied towards when they are loaded
+const wgs84Definition = "+proj=longlat +datum=WGS84 +no_defs"
+
+// Converts coordinates with the proj4 library. The converter is shared by all the goroutines of the tiler. As proj4
+// projection objects can't be used by several threads at once, each conversion takes a set of projections from a
+// pool and gives it back when done, so that concurrent conversions use different projection objects. The lock only
+// guards the pool, the conversions run in parallel
 type proj4CoordinateConverter struct {
 	EpsgDatabase map[int]*epsgProjection
+	projections  []projectionSet
+	initLock     sync.Mutex
+	sync.Mutex
 }
 
-func NewProj4CoordinateConverter() converters.CoordinateConverter {
-	exPath := tools.GetRootFolder()
+// Projections initialized for the EPSG codes converted by a goroutine, by EPSG code
+type projectionSet map[int]*proj.Proj
+
+// Returns the proj4 converter of the EPSG codes of the projection database in the assets folder. An error is returned
+// if the database cannot be read
+func NewProj4CoordinateConverter() (converters.CoordinateConverter, error) {
+	exPath, err := tools.GetRootFolder()
+	if err != nil {
+		return nil, err
+	}
 
 	// Set path for retrieving projection assets data
 	proj.SetFinder([]string{path.Join(exPath, "assets", "share")})
@@ -31,13 +52,21 @@ func NewProj4CoordinateConverter() converters.CoordinateConverter {
 	// Initialization of EPSG Proj4 database
 	file := path.Join(exPath, "assets", "epsg_projections.txt")
 
-	return &proj4CoordinateConverter{
-		EpsgDatabase: *loadEPSGProjectionDatabase(file),
+	epsgDatabase, err := loadEPSGProjectionDatabase(file)
+	if err != nil {
+		return nil, err
 	}
+
+	return &proj4CoordinateConverter{
+		EpsgDatabase: epsgDatabase,
+	}, nil
 }
 
-func loadEPSGProjectionDatabase(databasePath string) *map[int]*epsgProjection {
-	file := tools.OpenFileOrFail(databasePath)
+func loadEPSGProjectionDatabase(databasePath string) (map[int]*epsgProjection, error) {
+	file, err := os.Open(databasePath)
+	if err != nil {
+		return nil, fmt.Errorf("error loading the epsg projection file: %v", err)
+	}
 	defer func() { _ = file.Close() }()
 
 	var epsgDatabase = make(map[int]*epsgProjection)
@@ -46,22 +75,28 @@ func loadEPSGProjectionDatabase(databasePath string) *map[int]*epsgProjection {
 
 	for scanner.Scan() {
 		record := scanner.Text()
-		code, projection := parseEPSGProjectionDatabaseRecord(record)
+		code, projection, err := parseEPSGProjectionDatabaseRecord(record)
+		if err != nil {
+			return nil, fmt.Errorf("error while parsing the epsg projection file [%s]: %v", databasePath, err)
+		}
 		epsgDatabase[code] = projection
 	}
 
 	if err := scanner.Err(); err != nil {
-		glog.Fatal(err)
+		return nil, fmt.Errorf("error reading the epsg projection file [%s]: %v", databasePath, err)
 	}
 
-	return &epsgDatabase
+	return epsgDatabase, nil
 }
 
-func parseEPSGProjectionDatabaseRecord(databaseRecord string) (int, *epsgProjection) {
+func parseEPSGProjectionDatabaseRecord(databaseRecord string) (int, *epsgProjection, error) {
 	tokens := strings.Split(databaseRecord, "\t")
+	if len(tokens) < 3 {
+		return 0, nil, fmt.Errorf("invalid record [%s]", databaseRecord)
+	}
 	code, err := strconv.Atoi(strings.Replace(tokens[0], "EPSG:", "", -1))
 	if err != nil {
-		glog.Fatal("error while parsing the epsg projection file", err)
+		return 0, nil, fmt.Errorf("invalid epsg code [%s]", tokens[0])
 	}
 	desc := tokens[1]
 	proj4 := tokens[2]
@@ -70,7 +105,7 @@ func parseEPSGProjectionDatabaseRecord(databaseRecord string) (int, *epsgProject
 		EpsgCode:    code,
 		Description: desc,
 		Proj4:       proj4,
-	}
+	}, nil
 }
 
 // Converts the given coordinate from the given source Srid to the given target srid.
@@ -79,13 +114,16 @@ func (cc *proj4CoordinateConverter) ConvertCoordinateSrid(sourceSrid int, target
 		return coord, nil
 	}
 
-	src, err := cc.initProjection(sourceSrid)
+	projections := cc.acquireProjections()
+	defer cc.releaseProjections(projections)
+
+	src, err := cc.initProjection(projections, sourceSrid)
 	if err != nil {
 		glog.Infoln(err)
 		return coord, err
 	}
 
-	dst, err := cc.initProjection(targetSrid)
+	dst, err := cc.initProjection(projections, targetSrid)
 	if err != nil {
 		glog.Infoln(err)
 		return coord, err
@@ -96,6 +134,31 @@ func (cc *proj4CoordinateConverter) ConvertCoordinateSrid(sourceSrid int, target
 	return *converted, result
 }
 
+// Converts in place the given coordinates from the given source Srid to the given target srid with a single
+// transformation, taking a set of projections once for all of them
+func (cc *proj4CoordinateConverter) ConvertCoordinatesSrid(sourceSrid int, targetSrid int, coords []geometry.Coordinate) error {
+	if sourceSrid == targetSrid || len(coords) == 0 {
+		return nil
+	}
+
+	projections := cc.acquireProjections()
+	defer cc.releaseProjections(projections)
+
+	src, err := cc.initProjection(projections, sourceSrid)
+	if err != nil {
+		glog.Infoln(err)
+		return err
+	}
+
+	dst, err := cc.initProjection(projections, targetSrid)
+	if err != nil {
+		glog.Infoln(err)
+		return err
+	}
+
+	return executeBatchConversion(coords, src, dst)
+}
+
 // Converts the generic bounding box bounds values from the given input srid to a EPSG:4326 srid (in radians)
 // and returns a float64 array containing xMin, yMin, xMax, yMax, zMin, zMax. Z values are left unchanged
 func (cc *proj4CoordinateConverter) Convert2DBoundingboxToWGS84Region(bbox *geometry.BoundingBox, srid int) (*geometry.BoundingBox, error) {
@@ -139,13 +202,62 @@ func (cc *proj4CoordinateConverter) ConvertToWGS84Cartesian(coord geometry.Coord
 	return res2, err
 }
 
-// Releases all projection objects from memory
+// Converts in place the given coordinates from the given srid to EPSG:4978, going through EPSG:4326 as
+// ConvertToWGS84Cartesian does
+func (cc *proj4CoordinateConverter) ConvertToWGS84CartesianBatch(coords []geometry.Coordinate, sourceSrid int) error {
+	if sourceSrid == 4978 || len(coords) == 0 {
+		return nil
+	}
+
+	projectionSet := cc.acquireProjections()
+	defer cc.releaseProjections(projectionSet)
+
+	var projections []*proj.Proj
+	for _, code := range []int{sourceSrid, 4326, 4329, 4978} {
+		projection, err := cc.initProjection(projectionSet, code)
+		if err != nil {
+			glog.Infoln(err)
+			return err
+		}
+		projections = append(projections, projection)
+	}
+
+	return executeBatchConversion(coords, projections...)
+}
+
+// Releases all projection objects from memory. The converter must not be converting coordinates, the sets of
+// projections are all back in the pool
 func (cc *proj4CoordinateConverter) Cleanup() {
-	for _, val := range cc.EpsgDatabase {
-		if val.Projection != nil {
-			val.Projection.Close()
+	cc.Lock()
+	defer cc.Unlock()
+
+	for _, projections := range cc.projections {
+		for _, projection := range projections {
+			projection.Close()
 		}
 	}
+	cc.projections = nil
+}
+
+// Takes a set of projections from the pool, or a new empty one if all of them are in use
+func (cc *proj4CoordinateConverter) acquireProjections() projectionSet {
+	cc.Lock()
+	defer cc.Unlock()
+
+	if n := len(cc.projections); n > 0 {
+		projections := cc.projections[n-1]
+		cc.projections = cc.projections[:n-1]
+		return projections
+	}
+	return projectionSet{}
+}
+
+// Gives back to the pool a set of projections taken with acquireProjections
+func (cc *proj4CoordinateConverter) releaseProjections(projections projectionSet) {
+	cc.Lock()
+	defer cc.Unlock()
+
+	cc.projections = append(cc.projections, projections)
 }
 
 func executeConversion(coord *geometry.Coordinate, sourceProj *proj.Proj, destinationProj *proj.Proj) (*geometry.Coordinate, error) {
@@ -162,6 +274,41 @@ func executeConversion(coord *geometry.Coordinate, sourceProj *proj.Proj, destin
 	return &converted, err
 }
 
+// Converts in place the given coordinates through the given projections, with a transformation of all the coordinates
+// from each projection to the following one. As proj4 doesn't fail when only some of the points cannot be converted,
+// but sets their coordinates to HUGE_VAL, they are checked after the transformations
+func executeBatchConversion(coords []geometry.Coordinate, projections ...*proj.Proj) error {
+	source, destination := projections[0], projections[len(projections)-1]
+
+	x := make([]float64, len(coords))
+	y := make([]float64, len(coords))
+	z := make([]float64, len(coords))
+	for i, coord := range coords {
+		x[i] = *getCoordinateInRadiansFromSridFormat(coord.X, source)
+		y[i] = *getCoordinateInRadiansFromSridFormat(coord.Y, source)
+		z[i] = coord.Z
+	}
+
+	for i := 1; i < len(projections); i++ {
+		if err := proj.TransformRaw(projections[i-1], projections[i], x, y, z); err != nil {
+			return err
+		}
+	}
+
+	for i := range coords {
+		if math.IsInf(x[i], 0) || math.IsInf(y[i], 0) {
+			return fmt.Errorf("unable to convert coordinate X:[%f] Y:[%f] Z:[%f]", coords[i].X, coords[i].Y, coords[i].Z)
+		}
+		coords[i] = geometry.Coordinate{
+			X: getCoordinateFromRadiansToSridFormat(x[i], destination),
+			Y: getCoordinateFromRadiansToSridFormat(y[i], destination),
+			Z: z[i],
+		}
+	}
+
+	return nil
+}
+
 // From a input Coordinate object and associated Proj object, return a set of arrays to be used for coordinate coversion
 func getCoordinateArraysForConversion(coord *geometry.Coordinate, srid *proj.Proj) ([]float64, []float64, []float64) {
 	var x, y, z []float64
@@ -206,17 +353,47 @@ func getCoordinateFromRadiansToSridFormat(coord float64, srid *proj.Proj) float6
 	return angle
 }
 
-// Returns the projection corresponding to the given EPSG code, storing it in the relevant EpsgDatabase entry for caching
-func (cc *proj4CoordinateConverter) initProjection(code int) (*proj.Proj, error) {
+// Returns the projection of the given set corresponding to the given EPSG code, initializing it and storing it in the
+// set for caching if missing
+func (cc *proj4CoordinateConverter) initProjection(projections projectionSet, code int) (*proj.Proj, error) {
+	if projection, ok := projections[code]; ok {
+		return projection, nil
+	}
+
 	val, ok := cc.EpsgDatabase[code]
 	if !ok {
 		return &proj.Proj{}, errors.New("epsg code not found")
-	} else if val.Projection == nil {
-		projection, err := proj.InitPlus(val.Proj4)
+	}
+
+	projection, err := cc.newProjection(val.Proj4)
+	if err != nil {
+		return &proj.Proj{}, errors.New("unable to init projection")
+	}
+	projections[code] = projection
+	return projection, nil
+}
+
+// Initializes the projection of the given definition. Proj4 is built without mutexes, so the initialization, which
+// reads the shared init and grid caches, is serialized. The grid shift files are listed on the first transformation,
+// which is done here for the same reason, the following transformations of the projection only read them
+func (cc *proj4CoordinateConverter) newProjection(definition string) (*proj.Proj, error) {
+	cc.initLock.Lock()
+	defer cc.initLock.Unlock()
+
+	projection, err := proj.InitPlus(definition)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.Contains(definition, "+nadgrids=") {
+		wgs84, err := proj.InitPlus(wgs84Definition)
 		if err != nil {
-			return &proj.Proj{}, errors.New("unable to init projection")
+			projection.Close()
+			return nil, err
 		}
-		val.Projection = projection
+		defer wgs84.Close()
+		_ = proj.TransformRaw(projection, wgs84, []float64{0}, []float64{0}, []float64{0})
 	}
-	return val.Projection, nil
+
+	return projection, nil
 }
@@ -4,9 +4,17 @@ import (
 	"github.com/ecopia-map/cesium_tiler/internal/geometry"
 )
 
+// Number of coordinates the readers and the consumers convert with each call of the batch methods
+const CoordinateBatchSize = 4096
+
 type CoordinateConverter interface {
 	ConvertCoordinateSrid(sourceSrid int, targetSrid int, coord geometry.Coordinate) (geometry.Coordinate, error)
+	// Converts in place the given coordinates from the source srid to the target srid. An error is returned if any of
+	// them cannot be converted, in which case the content of the slice is undefined
+	ConvertCoordinatesSrid(sourceSrid int, targetSrid int, coords []geometry.Coordinate) error
 	Convert2DBoundingboxToWGS84Region(bbox *geometry.BoundingBox, srid int) (*geometry.BoundingBox, error)
 	ConvertToWGS84Cartesian(coord geometry.Coordinate, sourceSrid int) (geometry.Coordinate, error)
+	// Converts in place the given coordinates from the given srid to EPSG:4978, as ConvertCoordinatesSrid does
+	ConvertToWGS84CartesianBatch(coords []geometry.Coordinate, sourceSrid int) error
 	Cleanup()
 }
@@ -3,7 +3,6 @@ package geoid_elevation_corrector
 import (
 	"github.com/ecopia-map/cesium_tiler/internal/converters"
 	"github.com/ecopia-map/cesium_tiler/internal/converters/geoid_offset"
-	"github.com/golang/glog"
 )
 
 type GeoidElevationCorrector struct {
@@ -23,10 +22,20 @@ func NewGeoidElevationCorrector(srid int, ellipsoidToGeoidOffsetCalculator conve
 	}
 }
 
-func (c *GeoidElevationCorrector) CorrectElevation(lon, lat, z float64) float64 {
+// Instances a geoid elevation corrector computing the offset of every point, which is cached in cells of the given
+// size in degrees. The coordinates given to the corrector are in EPSG:4326, so that the cell size does not depend on
+// the srid of the input points
+func NewBufferedGeoidElevationCorrector(cellSize float64, ellipsoidToGeoidOffsetCalculator converters.EllipsoidToGeoidOffsetCalculator) converters.ElevationCorrector {
+	return &GeoidElevationCorrector{
+		srid:             4326,
+		offsetCalculator: geoid_offset.NewEllipsoidToGeoidBufferedCalculator(cellSize, ellipsoidToGeoidOffsetCalculator),
+	}
+}
+
+func (c *GeoidElevationCorrector) CorrectElevation(lon, lat, z float64) (float64, error) {
 	zfix, err := c.offsetCalculator.GetEllipsoidToGeoidOffset(lon, lat, c.srid)
 	if err != nil {
-		glog.Fatal(err)
+		return 0, err
 	}
-	return zfix + z
+	return zfix + z, nil
 }
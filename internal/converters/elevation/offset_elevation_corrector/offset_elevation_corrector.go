@@ -12,6 +12,6 @@ func NewOffsetElevationCorrector(offset float64) converters.ElevationCorrector {
 	}
 }
 
-func (c *OffsetElevationCorrector) CorrectElevation(lon, lat, z float64) float64 {
-	return z + c.Offset
+func (c *OffsetElevationCorrector) CorrectElevation(lon, lat, z float64) (float64, error) {
+	return z + c.Offset, nil
 }
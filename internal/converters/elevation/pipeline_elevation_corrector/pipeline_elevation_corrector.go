@@ -14,10 +14,14 @@ func NewPipelineElevationCorrector(elevationCorrectors []converters.ElevationCor
 	}
 }
 
-func (c *PipelineElevationCorrector) CorrectElevation(lon, lat, z float64) float64 {
+func (c *PipelineElevationCorrector) CorrectElevation(lon, lat, z float64) (float64, error) {
+	var err error
 	for _, elevationCorrector := range c.Correctors {
-		z = elevationCorrector.CorrectElevation(lon, lat, z)
+		z, err = elevationCorrector.CorrectElevation(lon, lat, z)
+		if err != nil {
+			return 0, err
+		}
 	}
 
-	return z
+	return z, nil
 }
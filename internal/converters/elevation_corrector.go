@@ -1,5 +1,5 @@
 package converters
 
 type ElevationCorrector interface {
-	CorrectElevation(lon, lat, z float64) float64
+	CorrectElevation(lon, lat, z float64) (float64, error)
 }
@@ -31,9 +31,8 @@ func (bc *EllipsoidToGeoidBufferedCalculator) GetEllipsoidToGeoidOffset(lon, lat
 	yMap, yMapPresent := bc.GeoidHeightMap.Load(x)
 
 	if !yMapPresent {
-		var temp sync.Map
-		bc.GeoidHeightMap.Store(x, &temp)
-		yMap = &temp
+		// the calculator is shared by the goroutines of the tiler, keep the map stored first
+		yMap, _ = bc.GeoidHeightMap.LoadOrStore(x, &sync.Map{})
 	}
 
 	yVal, yValPresent := yMap.(*sync.Map).Load(y)
@@ -22,7 +22,7 @@ func (spc *EllipsoidToGeoidSinglePointCalculator) GetEllipsoidToGeoidOffset(lon,
 	if spc.cachedOffset == nil {
 		offset, err := spc.getEllipsoidToGeoidOffset(lon, lat, srid)
 		if err != nil {
-			return 0, nil
+			return 0, err
 		}
 		spc.cachedOffset = &offset
 	}
@@ -2,6 +2,7 @@ package gh_offset_calculator
 
 import (
 	"bufio"
+	"fmt"
 	"math"
 	"os"
 	"path"
@@ -9,7 +10,6 @@ import (
 	"strings"
 
 	"github.com/ecopia-map/cesium_tiler/tools"
-	"github.com/golang/glog"
 )
 
 const sqrt03 = 1.7320508075688772935274463415059
@@ -33,11 +33,11 @@ type egm struct {
 }
 
 // Inits a new earth gravitational model according to the default parameters
-func newDefaultEarthGravitationalModel() *egm {
+func newDefaultEarthGravitationalModel() (*egm, error) {
 	return newEarthGraviationalModel(defaultOrder, true)
 }
 
-func newEarthGraviationalModel(nmax int, wgs84 bool) *egm {
+func newEarthGraviationalModel(nmax int, wgs84 bool) (*egm, error) {
 	model := egm{
 		nmax:  nmax,
 		wgs84: wgs84,
@@ -65,15 +65,17 @@ func newEarthGraviationalModel(nmax int, wgs84 bool) *egm {
 	model.snmGeopCoef = make([]float64, geopCoefLength)
 	model.as = make([]float64, nmax+1)
 
-	exPath := tools.GetRootFolder()
+	exPath, err := tools.GetRootFolder()
+	if err != nil {
+		return nil, err
+	}
 
 	// Loading Earth Gravitational Model data
-	err := model.load(path.Join(exPath, "assets", "egm180.nor"))
-	if err != nil {
-		glog.Fatal("error loading gravitational model data", err)
+	if err := model.load(path.Join(exPath, "assets", "egm180.nor")); err != nil {
+		return nil, fmt.Errorf("error loading gravitational model data: %v", err)
 	}
 
-	return &model
+	return &model, nil
 }
 
 func locatingArray(n int) int {
@@ -83,7 +85,7 @@ func locatingArray(n int) int {
 func (egm *egm) load(filename string) error {
 	file, err := os.Open(filename)
 	if err != nil {
-		glog.Fatal(err)
+		return err
 	}
 	defer file.Close()
 	scanner := bufio.NewScanner(file)
@@ -99,6 +101,9 @@ func (egm *egm) load(filename string) error {
 			}
 		}
 		tokens = tokens[:i]
+		if len(tokens) < 4 {
+			return fmt.Errorf("invalid record [%s]", line)
+		}
 		n, err := strconv.Atoi(tokens[0])
 		if err != nil {
 			return err
@@ -123,7 +128,7 @@ func (egm *egm) load(filename string) error {
 	}
 
 	if err := scanner.Err(); err != nil {
-		glog.Fatal(err)
+		return err
 	}
 	egm.initialize()
 	return nil
@@ -11,13 +11,18 @@ type EllipsoidToGeoidGHOffsetCalculator struct {
 	coordinateConverter converters.CoordinateConverter
 }
 
-func NewEllipsoidToGeoidGHOffsetCalculator(coordinateConverter converters.CoordinateConverter) converters.EllipsoidToGeoidOffsetCalculator {
-	var gravitationalModel = newDefaultEarthGravitationalModel()
+// Returns the calculator of the geoid height of the default earth gravitational model. An error is returned if the
+// model cannot be loaded
+func NewEllipsoidToGeoidGHOffsetCalculator(coordinateConverter converters.CoordinateConverter) (converters.EllipsoidToGeoidOffsetCalculator, error) {
+	gravitationalModel, err := newDefaultEarthGravitationalModel()
+	if err != nil {
+		return nil, err
+	}
 
 	return &EllipsoidToGeoidGHOffsetCalculator{
 		gravitationalModel:  gravitationalModel,
 		coordinateConverter: coordinateConverter,
-	}
+	}, nil
 }
 
 func (ghc *EllipsoidToGeoidGHOffsetCalculator) GetEllipsoidToGeoidOffset(lat, lon float64, sourceSrid int) (float64, error) {
@@ -0,0 +1,62 @@
+package grid_offset_calculator
+
+import (
+	"math"
+	"sync"
+
+	"github.com/ecopia-map/cesium_tiler/internal/converters"
+	"github.com/ecopia-map/cesium_tiler/internal/geometry"
+)
+
+// Computes the geoid height of each point interpolating a geoid height grid, as the EGM96 and EGM2008 grids
+// distributed by PROJ and VDatum or the NOAA GEOID grids. The grid is entirely loaded in memory
+type EllipsoidToGeoidGridOffsetCalculator struct {
+	grid                *geoidGrid
+	interpolation       Interpolation
+	coordinateConverter converters.CoordinateConverter
+}
+
+// Grids already loaded by path. Grids are only read after loading, so they are shared by the calculators as the merge
+// commands instance a calculator for every folder
+var (
+	loadedGrids     = map[string]*geoidGrid{}
+	loadedGridsLock sync.Mutex
+)
+
+// Loads the geoid grid stored in the given .gtx, .bin or GeoTIFF file. The grid must be in EPSG:4326 coordinates
+func NewEllipsoidToGeoidGridOffsetCalculator(gridPath string, interpolation Interpolation, coordinateConverter converters.CoordinateConverter) (converters.EllipsoidToGeoidOffsetCalculator, error) {
+	grid, err := loadGeoidGrid(gridPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &EllipsoidToGeoidGridOffsetCalculator{
+		grid:                grid,
+		interpolation:       interpolation,
+		coordinateConverter: coordinateConverter,
+	}, nil
+}
+
+func (gc *EllipsoidToGeoidGridOffsetCalculator) GetEllipsoidToGeoidOffset(lat, lon float64, sourceSrid int) (float64, error) {
+	coordinateInEPSG4326, err := gc.coordinateConverter.ConvertCoordinateSrid(sourceSrid, 4326, geometry.Coordinate{X: lon, Y: lat, Z: math.NaN()})
+	if err != nil {
+		return 0, err
+	}
+
+	return gc.grid.height(coordinateInEPSG4326.Y, coordinateInEPSG4326.X, gc.interpolation)
+}
+
+func loadGeoidGrid(gridPath string) (*geoidGrid, error) {
+	loadedGridsLock.Lock()
+	defer loadedGridsLock.Unlock()
+
+	if grid, ok := loadedGrids[gridPath]; ok {
+		return grid, nil
+	}
+	grid, err := readGeoidGrid(gridPath)
+	if err != nil {
+		return nil, err
+	}
+	loadedGrids[gridPath] = grid
+	return grid, nil
+}
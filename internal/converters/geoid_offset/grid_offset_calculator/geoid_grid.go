@@ -0,0 +1,176 @@
+package grid_offset_calculator
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"strings"
+)
+
+// Interpolation method of the geoid heights between the nodes of the grid
+type Interpolation int
+
+const (
+	// Weighted mean of the 4 nodes surrounding the point
+	Bilinear Interpolation = iota
+
+	// Quadratic interpolation on the 3x3 nodes centered on the node nearest to the point, as done by the NOAA
+	// INTG program. It follows the curvature of the geoid on coarse grids
+	Biquadratic
+)
+
+// Geoid heights sampled on a regular longitude/latitude grid in EPSG:4326. Row 0 is the southernmost row of the grid
+// and column 0 the westernmost column, heights are in meters above the ellipsoid
+type geoidGrid struct {
+	south     float64 // latitude in degrees of the first row
+	west      float64 // longitude in degrees of the first column
+	latStep   float64 // spacing in degrees between two rows
+	lonStep   float64 // spacing in degrees between two columns
+	rows      int
+	cols      int
+	heights   []float32 // heights of the nodes, row after row
+	noData    float32
+	hasNoData bool
+	wraps     bool // true if the grid covers all the longitudes and the last column is followed by the first one
+}
+
+// Reads the geoid grid stored in the given file, the format is chosen according to the extension of the file:
+// .gtx for the VDatum grids, .bin for the NOAA grids and .tif or .tiff for single band GeoTIFF grids
+func readGeoidGrid(gridPath string) (*geoidGrid, error) {
+	var grid *geoidGrid
+	var err error
+	switch strings.ToLower(filepath.Ext(gridPath)) {
+	case ".gtx":
+		grid, err = readGtxGrid(gridPath)
+	case ".bin":
+		grid, err = readNoaaBinGrid(gridPath)
+	case ".tif", ".tiff":
+		grid, err = readGeoTiffGrid(gridPath)
+	default:
+		return nil, fmt.Errorf("unsupported geoid grid format [%s], expected .gtx, .bin, .tif or .tiff", gridPath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading geoid grid [%s]: %v", gridPath, err)
+	}
+	return grid, nil
+}
+
+// Instances a grid with the given layout, checking that it holds the given heights
+func newGeoidGrid(south, west, latStep, lonStep float64, rows, cols int, heights []float32) (*geoidGrid, error) {
+	if rows < 2 || cols < 2 {
+		return nil, fmt.Errorf("grid should have at least 2 rows and 2 columns, got %dx%d", rows, cols)
+	}
+	if latStep <= 0 || lonStep <= 0 {
+		return nil, fmt.Errorf("grid spacing should be positive, got %f %f", latStep, lonStep)
+	}
+	if len(heights) != rows*cols {
+		return nil, fmt.Errorf("grid should have %d heights, got %d", rows*cols, len(heights))
+	}
+	return &geoidGrid{
+		south:   south,
+		west:    west,
+		latStep: latStep,
+		lonStep: lonStep,
+		rows:    rows,
+		cols:    cols,
+		heights: heights,
+		wraps:   math.Abs(float64(cols)*lonStep-360) < lonStep/2,
+	}, nil
+}
+
+// Sets the value marking the nodes without height
+func (g *geoidGrid) setNoData(noData float32) {
+	g.noData = noData
+	g.hasNoData = true
+}
+
+// Returns the geoid height interpolated at the given latitude and longitude in degrees
+func (g *geoidGrid) height(lat, lon float64, interpolation Interpolation) (float64, error) {
+	// small tolerance on the edges of the grid, whose bounds are rounded in the file headers
+	const epsilon = 1e-9
+
+	row := (lat - g.south) / g.latStep
+	col := math.Mod(math.Mod(lon-g.west, 360)+360, 360) / g.lonStep
+	if row < -epsilon || row > float64(g.rows-1)+epsilon || (!g.wraps && col > float64(g.cols-1)+epsilon) {
+		return 0, fmt.Errorf("point lat:[%f] lon:[%f] is outside of the geoid grid", lat, lon)
+	}
+	row = math.Max(0, math.Min(row, float64(g.rows-1)))
+	if !g.wraps {
+		col = math.Min(col, float64(g.cols-1))
+	}
+
+	if interpolation == Biquadratic && g.rows >= 3 && (g.wraps || g.cols >= 3) {
+		return g.biquadratic(row, col)
+	}
+	return g.bilinear(row, col)
+}
+
+// Interpolates the height at the given fractional row and column from the 4 surrounding nodes
+func (g *geoidGrid) bilinear(row, col float64) (float64, error) {
+	r0 := int(math.Min(math.Floor(row), float64(g.rows-2)))
+	c0 := int(math.Floor(col))
+	if !g.wraps {
+		c0 = int(math.Min(float64(c0), float64(g.cols-2)))
+	}
+	dr := row - float64(r0)
+	dc := col - float64(c0)
+
+	var values [2][2]float64
+	for i := 0; i < 2; i++ {
+		for j := 0; j < 2; j++ {
+			value, err := g.node(r0+i, c0+j)
+			if err != nil {
+				return 0, err
+			}
+			values[i][j] = value
+		}
+	}
+
+	south := values[0][0]*(1-dc) + values[0][1]*dc
+	north := values[1][0]*(1-dc) + values[1][1]*dc
+	return south*(1-dr) + north*dr, nil
+}
+
+// Interpolates the height at the given fractional row and column with quadratic polynomials through the 3x3 nodes
+// centered on the nearest node
+func (g *geoidGrid) biquadratic(row, col float64) (float64, error) {
+	r0 := int(math.Max(1, math.Min(math.Round(row), float64(g.rows-2))))
+	c0 := int(math.Round(col))
+	if !g.wraps {
+		c0 = int(math.Max(1, math.Min(float64(c0), float64(g.cols-2))))
+	}
+	rowWeights := quadraticWeights(row - float64(r0))
+	colWeights := quadraticWeights(col - float64(c0))
+
+	height := 0.0
+	for i := -1; i <= 1; i++ {
+		rowHeight := 0.0
+		for j := -1; j <= 1; j++ {
+			value, err := g.node(r0+i, c0+j)
+			if err != nil {
+				return 0, err
+			}
+			rowHeight += colWeights[j+1] * value
+		}
+		height += rowWeights[i+1] * rowHeight
+	}
+	return height, nil
+}
+
+// Lagrange weights of the nodes at -1, 0 and 1 for the given offset from the central node
+func quadraticWeights(d float64) [3]float64 {
+	return [3]float64{d * (d - 1) / 2, 1 - d*d, d * (d + 1) / 2}
+}
+
+// Returns the height of the given node, the column wraps around if the grid covers all the longitudes
+func (g *geoidGrid) node(row, col int) (float64, error) {
+	if g.wraps {
+		col = (col%g.cols + g.cols) % g.cols
+	}
+	value := g.heights[row*g.cols+col]
+	if math.IsNaN(float64(value)) || (g.hasNoData && value == g.noData) {
+		return 0, fmt.Errorf("geoid grid has no height at lat:[%f] lon:[%f]",
+			g.south+float64(row)*g.latStep, g.west+float64(col)*g.lonStep)
+	}
+	return float64(value), nil
+}
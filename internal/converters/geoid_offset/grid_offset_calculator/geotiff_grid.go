@@ -0,0 +1,421 @@
+package grid_offset_calculator
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Tags of the TIFF and GeoTIFF specifications read from the grid files
+const (
+	tiffTagImageWidth       = 256
+	tiffTagImageLength      = 257
+	tiffTagBitsPerSample    = 258
+	tiffTagCompression      = 259
+	tiffTagStripOffsets     = 273
+	tiffTagSamplesPerPixel  = 277
+	tiffTagRowsPerStrip     = 278
+	tiffTagStripByteCounts  = 279
+	tiffTagPredictor        = 317
+	tiffTagTileWidth        = 322
+	tiffTagTileLength       = 323
+	tiffTagTileOffsets      = 324
+	tiffTagTileByteCounts   = 325
+	tiffTagSampleFormat     = 339
+	tiffTagModelPixelScale  = 33550
+	tiffTagModelTiepoint    = 33922
+	tiffTagGeoKeyDirectory  = 34735
+	tiffTagGdalMetadata     = 42112
+	tiffTagGdalNoData       = 42113
+	geoKeyModelType         = 1024
+	geoKeyRasterType        = 1025
+	geoModelTypeGeographic  = 2
+	geoRasterPixelIsPoint   = 2
+	tiffCompressionNone     = 1
+	tiffCompressionDeflate  = 8
+	tiffCompressionDeflate2 = 32946
+	tiffPredictorNone       = 1
+	tiffPredictorHorizontal = 2
+	tiffPredictorFloat      = 3
+	tiffSampleFormatUint    = 1
+	tiffSampleFormatInt     = 2
+	tiffSampleFormatFloat   = 3
+)
+
+// scale and offset of the band values in the GDAL metadata, as <Item name="SCALE" sample="0" role="scale">0.001</Item>
+var gdalMetadataItemRegexp = regexp.MustCompile(`<Item name="(SCALE|OFFSET)"[^>]*>([^<]*)</Item>`)
+
+// size in bytes of the values of the TIFF field types, 0 for the unknown types
+var tiffFieldTypeSizes = [17]int{0, 1, 1, 2, 4, 8, 1, 1, 2, 4, 8, 4, 8, 4, 0, 0, 8}
+
+// Fields of the first image of a TIFF file
+type tiffImage struct {
+	content []byte
+	order   binary.ByteOrder
+	fields  map[uint16]tiffField
+}
+
+type tiffField struct {
+	fieldType uint16
+	count     int
+	data      []byte
+}
+
+// Reads a single band GeoTIFF grid in geographic coordinates. Uncompressed and deflate compressed strips and tiles of
+// integer or float samples are supported
+func readGeoTiffGrid(gridPath string) (*geoidGrid, error) {
+	content, err := ioutil.ReadFile(gridPath)
+	if err != nil {
+		return nil, err
+	}
+	image, err := readTiffImage(content)
+	if err != nil {
+		return nil, err
+	}
+
+	width := image.intValue(tiffTagImageWidth, 0)
+	height := image.intValue(tiffTagImageLength, 0)
+	if samples := image.intValue(tiffTagSamplesPerPixel, 1); samples != 1 {
+		return nil, fmt.Errorf("geotiff grid should have a single band, got %d", samples)
+	}
+
+	raster, err := image.readRaster(width, height)
+	if err != nil {
+		return nil, err
+	}
+
+	south, west, latStep, lonStep, err := image.georeference(height)
+	if err != nil {
+		return nil, err
+	}
+
+	// tiff rows start from the north, grid rows from the south
+	heights := make([]float32, width*height)
+	for row := 0; row < height; row++ {
+		copy(heights[row*width:(row+1)*width], raster[(height-1-row)*width:(height-row)*width])
+	}
+
+	// the no data value is compared with the values stored in the file, before scaling them
+	noData, hasNoData := image.gdalNoData()
+	scale, offset := image.gdalScaleOffset()
+	for i, value := range heights {
+		if hasNoData && value == noData {
+			heights[i] = float32(math.NaN())
+		} else if scale != 1 || offset != 0 {
+			heights[i] = float32(float64(value)*scale + offset)
+		}
+	}
+
+	return newGeoidGrid(south, west, latStep, lonStep, height, width, heights)
+}
+
+// Parses the header and the first image file directory of a TIFF file
+func readTiffImage(content []byte) (*tiffImage, error) {
+	if len(content) < 8 {
+		return nil, errors.New("tiff header truncated")
+	}
+
+	image := &tiffImage{content: content, fields: map[uint16]tiffField{}}
+	switch string(content[0:2]) {
+	case "II":
+		image.order = binary.LittleEndian
+	case "MM":
+		image.order = binary.BigEndian
+	default:
+		return nil, errors.New("not a tiff file")
+	}
+	if version := image.order.Uint16(content[2:4]); version != 42 {
+		return nil, fmt.Errorf("unsupported tiff version %d, BigTIFF files are not supported", version)
+	}
+
+	offset := int(image.order.Uint32(content[4:8]))
+	if offset+2 > len(content) {
+		return nil, errors.New("tiff image file directory truncated")
+	}
+	numEntries := int(image.order.Uint16(content[offset : offset+2]))
+	if offset+2+numEntries*12 > len(content) {
+		return nil, errors.New("tiff image file directory truncated")
+	}
+
+	for i := 0; i < numEntries; i++ {
+		entry := content[offset+2+i*12 : offset+14+i*12]
+		field := tiffField{
+			fieldType: image.order.Uint16(entry[2:4]),
+			count:     int(image.order.Uint32(entry[4:8])),
+		}
+		if int(field.fieldType) >= len(tiffFieldTypeSizes) || tiffFieldTypeSizes[field.fieldType] == 0 {
+			continue
+		}
+
+		// values that don't fit in the entry are stored at the given offset
+		size := field.count * tiffFieldTypeSizes[field.fieldType]
+		field.data = entry[8:12]
+		if size > 4 {
+			dataOffset := int(image.order.Uint32(entry[8:12]))
+			if dataOffset+size > len(content) {
+				return nil, fmt.Errorf("tiff tag %d truncated", image.order.Uint16(entry[0:2]))
+			}
+			field.data = content[dataOffset : dataOffset+size]
+		}
+		image.fields[image.order.Uint16(entry[0:2])] = field
+	}
+
+	return image, nil
+}
+
+// Returns the integer values of the given tag
+func (t *tiffImage) ints(tag uint16) []int {
+	field, ok := t.fields[tag]
+	if !ok {
+		return nil
+	}
+	values := make([]int, field.count)
+	for i := range values {
+		switch field.fieldType {
+		case 1, 7:
+			values[i] = int(field.data[i])
+		case 3:
+			values[i] = int(t.order.Uint16(field.data[i*2:]))
+		case 4:
+			values[i] = int(t.order.Uint32(field.data[i*4:]))
+		case 16:
+			values[i] = int(t.order.Uint64(field.data[i*8:]))
+		}
+	}
+	return values
+}
+
+// Returns the first integer value of the given tag, or the given default value if the tag is not set
+func (t *tiffImage) intValue(tag uint16, defaultValue int) int {
+	if values := t.ints(tag); len(values) > 0 {
+		return values[0]
+	}
+	return defaultValue
+}
+
+// Returns the double values of the given tag
+func (t *tiffImage) float64s(tag uint16) []float64 {
+	field, ok := t.fields[tag]
+	if !ok || field.fieldType != 12 {
+		return nil
+	}
+	values := make([]float64, field.count)
+	for i := range values {
+		values[i] = math.Float64frombits(t.order.Uint64(field.data[i*8:]))
+	}
+	return values
+}
+
+// Returns the no data value written by GDAL as text, if any
+func (t *tiffImage) gdalNoData() (float32, bool) {
+	field, ok := t.fields[tiffTagGdalNoData]
+	if !ok || field.fieldType != 2 {
+		return 0, false
+	}
+	value, err := strconv.ParseFloat(strings.Trim(string(field.data[:field.count]), " \x00"), 64)
+	if err != nil || math.IsNaN(value) {
+		return 0, false
+	}
+	return float32(value), true
+}
+
+// Returns the scale and offset of the band values written by GDAL, 1 and 0 if they are not set
+func (t *tiffImage) gdalScaleOffset() (float64, float64) {
+	scale, offset := 1.0, 0.0
+	field, ok := t.fields[tiffTagGdalMetadata]
+	if !ok || field.fieldType != 2 {
+		return scale, offset
+	}
+	for _, match := range gdalMetadataItemRegexp.FindAllStringSubmatch(string(field.data[:field.count]), -1) {
+		value, err := strconv.ParseFloat(strings.TrimSpace(match[2]), 64)
+		if err != nil {
+			continue
+		}
+		if match[1] == "SCALE" {
+			scale = value
+		} else {
+			offset = value
+		}
+	}
+	return scale, offset
+}
+
+// Returns the value of the given GeoTIFF key, or the given default value if the key is not set
+func (t *tiffImage) geoKey(key int, defaultValue int) int {
+	directory := t.ints(tiffTagGeoKeyDirectory)
+	for i := 4; i+3 < len(directory); i += 4 {
+		// only the keys stored in the directory itself are needed
+		if directory[i] == key && directory[i+1] == 0 {
+			return directory[i+3]
+		}
+	}
+	return defaultValue
+}
+
+// Returns the latitude and longitude of the south west node of the grid and the spacing of its nodes
+func (t *tiffImage) georeference(height int) (float64, float64, float64, float64, error) {
+	if modelType := t.geoKey(geoKeyModelType, geoModelTypeGeographic); modelType != geoModelTypeGeographic {
+		return 0, 0, 0, 0, errors.New("geotiff grid should be in geographic coordinates")
+	}
+
+	scale := t.float64s(tiffTagModelPixelScale)
+	tiepoint := t.float64s(tiffTagModelTiepoint)
+	if len(scale) < 2 || len(tiepoint) < 6 {
+		return 0, 0, 0, 0, errors.New("geotiff grid should have pixel scale and tiepoint tags")
+	}
+
+	// the tiepoint refers to the corner of the pixels unless they are points, nodes are at the center of the pixels
+	half := 0.5
+	if t.geoKey(geoKeyRasterType, 1) == geoRasterPixelIsPoint {
+		half = 0
+	}
+	lonStep, latStep := scale[0], scale[1]
+	west := tiepoint[3] + (half-tiepoint[0])*lonStep
+	north := tiepoint[4] - (half-tiepoint[1])*latStep
+	return north - float64(height-1)*latStep, west, latStep, lonStep, nil
+}
+
+// Decodes the samples of the image as float32, row after row starting from the north
+func (t *tiffImage) readRaster(width, height int) ([]float32, error) {
+	sampleSize := t.intValue(tiffTagBitsPerSample, 1) / 8
+	sampleFormat := t.intValue(tiffTagSampleFormat, tiffSampleFormatUint)
+	switch {
+	case sampleFormat == tiffSampleFormatFloat && (sampleSize == 4 || sampleSize == 8):
+	case (sampleFormat == tiffSampleFormatUint || sampleFormat == tiffSampleFormatInt) && (sampleSize == 2 || sampleSize == 4):
+	default:
+		return nil, fmt.Errorf("unsupported geotiff sample format %d of %d bytes", sampleFormat, sampleSize)
+	}
+
+	compression := t.intValue(tiffTagCompression, tiffCompressionNone)
+	if compression != tiffCompressionNone && compression != tiffCompressionDeflate && compression != tiffCompressionDeflate2 {
+		return nil, fmt.Errorf("unsupported geotiff compression %d, only uncompressed and deflate grids are supported", compression)
+	}
+	predictor := t.intValue(tiffTagPredictor, tiffPredictorNone)
+	if predictor != tiffPredictorNone && predictor != tiffPredictorHorizontal && predictor != tiffPredictorFloat {
+		return nil, fmt.Errorf("unsupported geotiff predictor %d", predictor)
+	}
+
+	// images are stored in tiles or in strips, which are tiles as wide as the image
+	blockWidth, blockHeight := t.intValue(tiffTagTileWidth, 0), t.intValue(tiffTagTileLength, 0)
+	offsets, byteCounts := t.ints(tiffTagTileOffsets), t.ints(tiffTagTileByteCounts)
+	if blockWidth == 0 {
+		blockWidth, blockHeight = width, t.intValue(tiffTagRowsPerStrip, height)
+		offsets, byteCounts = t.ints(tiffTagStripOffsets), t.ints(tiffTagStripByteCounts)
+	}
+	if width <= 0 || height <= 0 || blockWidth <= 0 || blockHeight <= 0 {
+		return nil, fmt.Errorf("invalid geotiff image size %dx%d", width, height)
+	}
+	blocksAcross := (width + blockWidth - 1) / blockWidth
+	blocksDown := (height + blockHeight - 1) / blockHeight
+	if len(offsets) < blocksAcross*blocksDown || len(byteCounts) < len(offsets) {
+		return nil, errors.New("geotiff block offsets truncated")
+	}
+
+	raster := make([]float32, width*height)
+	for blockRow := 0; blockRow < blocksDown; blockRow++ {
+		for blockCol := 0; blockCol < blocksAcross; blockCol++ {
+			index := blockRow*blocksAcross + blockCol
+			if offsets[index]+byteCounts[index] > len(t.content) {
+				return nil, errors.New("geotiff block truncated")
+			}
+			block, err := decompressTiffBlock(t.content[offsets[index]:offsets[index]+byteCounts[index]], compression)
+			if err != nil {
+				return nil, err
+			}
+
+			for y := 0; y < blockHeight; y++ {
+				row := blockRow*blockHeight + y
+				if row >= height {
+					break
+				}
+				// the last strip holds only the remaining rows
+				rowBytes := blockWidth * sampleSize
+				if (y+1)*rowBytes > len(block) {
+					return nil, errors.New("geotiff block data truncated")
+				}
+				values := t.decodeRow(block[y*rowBytes:(y+1)*rowBytes], blockWidth, sampleSize, sampleFormat, predictor)
+				for x := 0; x < blockWidth; x++ {
+					col := blockCol*blockWidth + x
+					if col >= width {
+						break
+					}
+					raster[row*width+col] = values[x]
+				}
+			}
+		}
+	}
+	return raster, nil
+}
+
+// Decodes a row of samples, reverting the predictor applied when the file was written
+func (t *tiffImage) decodeRow(row []byte, width, sampleSize, sampleFormat, predictor int) []float32 {
+	values := make([]float32, width)
+
+	if predictor == tiffPredictorFloat {
+		// bytes are differenced along the row, then the samples are rebuilt from the planes of their bytes, which
+		// start from the most significant one
+		for i := 1; i < len(row); i++ {
+			row[i] += row[i-1]
+		}
+		sample := make([]byte, sampleSize)
+		for x := 0; x < width; x++ {
+			for b := 0; b < sampleSize; b++ {
+				sample[b] = row[b*width+x]
+			}
+			values[x] = decodeTiffSample(sample, binary.BigEndian, sampleSize, sampleFormat)
+		}
+		return values
+	}
+
+	if predictor == tiffPredictorHorizontal {
+		// integer samples are differenced along the row, wrapping around their size
+		for x := 1; x < width; x++ {
+			previous, current := row[(x-1)*sampleSize:x*sampleSize], row[x*sampleSize:(x+1)*sampleSize]
+			if sampleSize == 2 {
+				t.order.PutUint16(current, t.order.Uint16(previous)+t.order.Uint16(current))
+			} else {
+				t.order.PutUint32(current, t.order.Uint32(previous)+t.order.Uint32(current))
+			}
+		}
+	}
+
+	for x := 0; x < width; x++ {
+		values[x] = decodeTiffSample(row[x*sampleSize:(x+1)*sampleSize], t.order, sampleSize, sampleFormat)
+	}
+	return values
+}
+
+func decodeTiffSample(b []byte, order binary.ByteOrder, sampleSize, sampleFormat int) float32 {
+	switch {
+	case sampleFormat == tiffSampleFormatFloat && sampleSize == 4:
+		return math.Float32frombits(order.Uint32(b))
+	case sampleFormat == tiffSampleFormatFloat:
+		return float32(math.Float64frombits(order.Uint64(b)))
+	case sampleSize == 2 && sampleFormat == tiffSampleFormatInt:
+		return float32(int16(order.Uint16(b)))
+	case sampleSize == 2:
+		return float32(order.Uint16(b))
+	case sampleFormat == tiffSampleFormatInt:
+		return float32(int32(order.Uint32(b)))
+	}
+	return float32(order.Uint32(b))
+}
+
+func decompressTiffBlock(data []byte, compression int) ([]byte, error) {
+	if compression == tiffCompressionNone {
+		return append([]byte(nil), data...), nil
+	}
+	reader, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = reader.Close() }()
+	return ioutil.ReadAll(reader)
+}
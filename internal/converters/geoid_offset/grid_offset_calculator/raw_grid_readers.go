@@ -0,0 +1,108 @@
+package grid_offset_calculator
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"math"
+)
+
+const (
+	gtxHeaderSize     = 40
+	noaaBinHeaderSize = 44
+
+	// value of the nodes without height in the VDatum grids
+	gtxNoData = -88.8888
+
+	// kind of the NOAA grid values, 1 for float32
+	noaaBinFloatKind = 1
+)
+
+// Reads a VDatum .gtx grid. The big endian header holds the latitude and longitude of the south west node, the
+// spacing and the number of rows and columns, followed by the float32 heights starting from the south west node
+func readGtxGrid(gridPath string) (*geoidGrid, error) {
+	content, err := ioutil.ReadFile(gridPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(content) < gtxHeaderSize {
+		return nil, fmt.Errorf("gtx header truncated")
+	}
+
+	order := binary.BigEndian
+	rows := int(int32(order.Uint32(content[32:36])))
+	cols := int(int32(order.Uint32(content[36:40])))
+	heights, err := decodeFloat32Heights(content[gtxHeaderSize:], rows, cols, order)
+	if err != nil {
+		return nil, err
+	}
+
+	grid, err := newGeoidGrid(
+		readFloat64(content[0:8], order),
+		readFloat64(content[8:16], order),
+		readFloat64(content[16:24], order),
+		readFloat64(content[24:32], order),
+		rows, cols, heights,
+	)
+	if err != nil {
+		return nil, err
+	}
+	grid.setNoData(gtxNoData)
+	return grid, nil
+}
+
+// Reads a NOAA .bin grid, as GEOID12B or GEOID18. The header holds the latitude and longitude of the south west node,
+// the spacing, the number of rows and columns and the kind of the values, followed by the float32 heights starting
+// from the south west node. The byte order of the file is detected from the kind of the values
+func readNoaaBinGrid(gridPath string) (*geoidGrid, error) {
+	content, err := ioutil.ReadFile(gridPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(content) < noaaBinHeaderSize {
+		return nil, fmt.Errorf("bin header truncated")
+	}
+
+	var order binary.ByteOrder = binary.LittleEndian
+	if order.Uint32(content[40:44]) != noaaBinFloatKind {
+		order = binary.BigEndian
+		if order.Uint32(content[40:44]) != noaaBinFloatKind {
+			return nil, fmt.Errorf("unsupported bin grid kind, only float32 values are supported")
+		}
+	}
+
+	rows := int(int32(order.Uint32(content[32:36])))
+	cols := int(int32(order.Uint32(content[36:40])))
+	heights, err := decodeFloat32Heights(content[noaaBinHeaderSize:], rows, cols, order)
+	if err != nil {
+		return nil, err
+	}
+
+	return newGeoidGrid(
+		readFloat64(content[0:8], order),
+		readFloat64(content[8:16], order),
+		readFloat64(content[16:24], order),
+		readFloat64(content[24:32], order),
+		rows, cols, heights,
+	)
+}
+
+// Decodes the float32 heights of a grid with the given number of rows and columns
+func decodeFloat32Heights(content []byte, rows, cols int, order binary.ByteOrder) ([]float32, error) {
+	if rows <= 0 || cols <= 0 {
+		return nil, fmt.Errorf("invalid grid size %dx%d", rows, cols)
+	}
+	if len(content) < rows*cols*4 {
+		return nil, fmt.Errorf("grid data truncated, expected %d bytes, got %d", rows*cols*4, len(content))
+	}
+
+	heights := make([]float32, rows*cols)
+	for i := range heights {
+		heights[i] = math.Float32frombits(order.Uint32(content[i*4 : i*4+4]))
+	}
+	return heights, nil
+}
+
+func readFloat64(b []byte, order binary.ByteOrder) float64 {
+	return math.Float64frombits(order.Uint64(b))
+}
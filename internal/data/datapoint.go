@@ -9,19 +9,28 @@ type Point struct {
 	R              uint8
 	G              uint8
 	B              uint8
-	Intensity      uint8
+	Intensity      uint16
 	Classification uint8
 
 	// extend in las_file
 	PointExtend *PointExtend
 }
 
+// Attributes of the las point record that are not needed to build the tree, they are only written in the batch table
 type PointExtend struct {
-	LasPointIndex int
+	LasPointIndex   int
+	GpsTime         float64 // GPS time, 0 for point formats without it
+	ScanAngle       float32 // scan angle in degrees
+	PointSourceID   uint16  // id of the flight line the point comes from
+	ReturnNumber    uint8
+	NumberOfReturns uint8
+	UserData        uint8
+	Nir             uint16    // near infrared channel, 0 for point formats without it
+	ExtraBytes      []float64 // values of the extra bytes attributes written in the batch table, in the order they are requested
 }
 
 // Builds a new Point from the given coordinates, colors, intensity and classification values
-func NewPoint(X, Y, Z float64, R, G, B, Intensity, Classification uint8, pointExtend *PointExtend) *Point {
+func NewPoint(X, Y, Z float64, R, G, B uint8, Intensity uint16, Classification uint8, pointExtend *PointExtend) *Point {
 	return &Point{
 		X:              X,
 		Y:              Y,
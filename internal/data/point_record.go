@@ -0,0 +1,101 @@
+package data
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// Size in bytes of the fixed part of the binary record of a Point: X, Y, Z as float64, R, G, B as uint8, Intensity
+// as uint16, Classification as uint8, the index of the point in the las file as int64, followed by the point extend
+// attributes: GPS time as float64, scan angle as float32, point source id as uint16, return number, number of
+// returns and user data as uint8, near infrared as uint16 and the number of extra bytes values as uint8. The extra
+// bytes values follow as float64
+const PointRecordSize = 3*8 + 3 + 2 + 1 + 8 + 8 + 4 + 2 + 3 + 2 + 1
+
+// Max number of extra bytes values of a Point that can be stored in its binary record
+const MaxPointRecordExtraBytes = 255
+
+// Returns the size in bytes of the binary record of the given Point
+func PointRecordLength(point *Point) int {
+	if point.PointExtend == nil {
+		return PointRecordSize
+	}
+	numExtraBytes := len(point.PointExtend.ExtraBytes)
+	if numExtraBytes > MaxPointRecordExtraBytes {
+		numExtraBytes = MaxPointRecordExtraBytes
+	}
+	return PointRecordSize + 8*numExtraBytes
+}
+
+// Returns the size in bytes of the extra bytes values following the fixed part of the binary record stored in the
+// given buffer, which must be at least PointRecordSize long
+func PointRecordExtraBytesLength(buffer []byte) int {
+	return 8 * int(buffer[PointRecordSize-1])
+}
+
+// Writes the binary record of the given Point into the given buffer, which must be at least PointRecordLength long.
+// Extra bytes values beyond MaxPointRecordExtraBytes are not written
+func EncodePointRecord(buffer []byte, point *Point) {
+	binary.LittleEndian.PutUint64(buffer[0:8], math.Float64bits(point.X))
+	binary.LittleEndian.PutUint64(buffer[8:16], math.Float64bits(point.Y))
+	binary.LittleEndian.PutUint64(buffer[16:24], math.Float64bits(point.Z))
+	buffer[24] = point.R
+	buffer[25] = point.G
+	buffer[26] = point.B
+	binary.LittleEndian.PutUint16(buffer[27:29], point.Intensity)
+	buffer[29] = point.Classification
+
+	pointExtend := point.PointExtend
+	if pointExtend == nil {
+		pointExtend = &PointExtend{LasPointIndex: -1}
+	}
+	binary.LittleEndian.PutUint64(buffer[30:38], uint64(int64(pointExtend.LasPointIndex)))
+	binary.LittleEndian.PutUint64(buffer[38:46], math.Float64bits(pointExtend.GpsTime))
+	binary.LittleEndian.PutUint32(buffer[46:50], math.Float32bits(pointExtend.ScanAngle))
+	binary.LittleEndian.PutUint16(buffer[50:52], pointExtend.PointSourceID)
+	buffer[52] = pointExtend.ReturnNumber
+	buffer[53] = pointExtend.NumberOfReturns
+	buffer[54] = pointExtend.UserData
+	binary.LittleEndian.PutUint16(buffer[55:57], pointExtend.Nir)
+
+	extraBytes := pointExtend.ExtraBytes
+	if len(extraBytes) > MaxPointRecordExtraBytes {
+		extraBytes = extraBytes[:MaxPointRecordExtraBytes]
+	}
+	buffer[57] = uint8(len(extraBytes))
+	for i, value := range extraBytes {
+		binary.LittleEndian.PutUint64(buffer[PointRecordSize+i*8:PointRecordSize+i*8+8], math.Float64bits(value))
+	}
+}
+
+// Builds a new Point from the binary record stored in the given buffer, which must hold the whole record
+func DecodePointRecord(buffer []byte) *Point {
+	var pointExtend *PointExtend
+	if lasPointIndex := int64(binary.LittleEndian.Uint64(buffer[30:38])); lasPointIndex >= 0 {
+		pointExtend = &PointExtend{
+			LasPointIndex:   int(lasPointIndex),
+			GpsTime:         math.Float64frombits(binary.LittleEndian.Uint64(buffer[38:46])),
+			ScanAngle:       math.Float32frombits(binary.LittleEndian.Uint32(buffer[46:50])),
+			PointSourceID:   binary.LittleEndian.Uint16(buffer[50:52]),
+			ReturnNumber:    buffer[52],
+			NumberOfReturns: buffer[53],
+			UserData:        buffer[54],
+			Nir:             binary.LittleEndian.Uint16(buffer[55:57]),
+		}
+		if numExtraBytes := int(buffer[57]); numExtraBytes > 0 {
+			pointExtend.ExtraBytes = make([]float64, numExtraBytes)
+			for i := range pointExtend.ExtraBytes {
+				pointExtend.ExtraBytes[i] = math.Float64frombits(binary.LittleEndian.Uint64(buffer[PointRecordSize+i*8 : PointRecordSize+i*8+8]))
+			}
+		}
+	}
+
+	return NewPoint(
+		math.Float64frombits(binary.LittleEndian.Uint64(buffer[0:8])),
+		math.Float64frombits(binary.LittleEndian.Uint64(buffer[8:16])),
+		math.Float64frombits(binary.LittleEndian.Uint64(buffer[16:24])),
+		buffer[24], buffer[25], buffer[26],
+		binary.LittleEndian.Uint16(buffer[27:29]), buffer[29],
+		pointExtend,
+	)
+}
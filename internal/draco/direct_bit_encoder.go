@@ -0,0 +1,63 @@
+package draco
+
+// Stores bits without any entropy coding, mirrors draco::DirectBitEncoder. Bits are packed in 32 bit words
+// starting from the most significant bit
+type directBitEncoder struct {
+	bits         []uint32
+	localBits    uint32
+	numLocalBits uint32
+}
+
+func newDirectBitEncoder() *directBitEncoder {
+	return &directBitEncoder{
+		bits: make([]uint32, 0),
+	}
+}
+
+func (e *directBitEncoder) encodeBit(bit bool) {
+	if bit {
+		e.localBits |= 1 << (31 - e.numLocalBits)
+	}
+	e.numLocalBits++
+	if e.numLocalBits == 32 {
+		e.bits = append(e.bits, e.localBits)
+		e.numLocalBits = 0
+		e.localBits = 0
+	}
+}
+
+// Encodes the nbits least significant bits of value, most significant bit first
+func (e *directBitEncoder) encodeLeastSignificantBits32(nbits uint32, value uint32) {
+	remaining := 32 - e.numLocalBits
+
+	// drop the bits that should not be encoded
+	value = value << (32 - nbits)
+	if nbits <= remaining {
+		value = value >> e.numLocalBits
+		e.localBits |= value
+		e.numLocalBits += nbits
+		if e.numLocalBits == 32 {
+			e.bits = append(e.bits, e.localBits)
+			e.localBits = 0
+			e.numLocalBits = 0
+		}
+	} else {
+		value = value >> (32 - nbits)
+		e.numLocalBits = nbits - remaining
+		e.localBits |= value >> e.numLocalBits
+		e.bits = append(e.bits, e.localBits)
+		e.localBits = value << (32 - e.numLocalBits)
+	}
+}
+
+// Writes the size in bytes of the encoded words followed by the words
+func (e *directBitEncoder) endEncoding(buffer *encoderBuffer) {
+	e.bits = append(e.bits, e.localBits)
+	buffer.encodeUint32(uint32(len(e.bits) * 4))
+	for _, word := range e.bits {
+		buffer.encodeUint32(word)
+	}
+	e.bits = e.bits[:0]
+	e.localBits = 0
+	e.numLocalBits = 0
+}
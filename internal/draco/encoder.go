@@ -0,0 +1,196 @@
+package draco
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Point cloud encoding method, values match draco::PointCloudEncodingMethod
+type EncodingMethod uint8
+
+const (
+	// Attributes are encoded one after the other in the original point order. Fast but larger output
+	SequentialEncoding EncodingMethod = 0
+
+	// All the attributes are encoded together by recursively splitting the points with a kd-tree. Points are
+	// reordered and the output is considerably smaller, this is the method used by the draco_encoder tool for point clouds
+	KdTreeEncoding EncodingMethod = 1
+)
+
+const (
+	// Default number of quantization bits of float attributes, same as the draco_encoder -qp default
+	DefaultQuantizationBits = 11
+
+	MaxQuantizationBits = 30
+)
+
+// Draco bitstream header values for point clouds
+const (
+	bitstreamVersionMajor = 2
+	bitstreamVersionMinor = 3
+	encoderTypePointCloud = 0
+)
+
+func (m EncodingMethod) String() string {
+	if m == SequentialEncoding {
+		return "sequential"
+	} else if m == KdTreeEncoding {
+		return "kd-tree"
+	}
+	return ""
+}
+
+type EncoderOptions struct {
+	Method           EncodingMethod // Encoding method of the point cloud
+	QuantizationBits int            // Number of bits used to quantize float attributes
+}
+
+// Encodes the given point cloud into a Draco bitstream (version 2.3), which can be decoded by any Draco decoder
+// and embedded in 3DTILES_draco_point_compression or KHR_draco_mesh_compression extensions
+func EncodePointCloud(pc *PointCloud, opts EncoderOptions) ([]byte, error) {
+	if opts.QuantizationBits < 1 || opts.QuantizationBits > MaxQuantizationBits {
+		return nil, fmt.Errorf("quantization bits should be between 1 and %d", MaxQuantizationBits)
+	}
+	if pc.numPoints == 0 {
+		return nil, errors.New("point cloud has no points")
+	}
+	if len(pc.attributes) == 0 {
+		return nil, errors.New("point cloud has no attributes")
+	}
+
+	buffer := &encoderBuffer{}
+	encodeHeader(buffer, opts.Method)
+
+	// geometry data
+	buffer.encodeInt32(int32(pc.numPoints))
+
+	// all the attributes are handled by a single attributes encoder
+	buffer.encodeUint8(1)
+	encodeAttributesEncoderData(buffer, pc)
+
+	// float attributes are quantized to integers before being encoded
+	portableValues := make([][]uint32, len(pc.attributes))
+	transforms := make([]*quantizationTransform, 0)
+	for i, att := range pc.attributes {
+		if att.dataType == dataTypeFloat32 {
+			transform := computeQuantizationTransform(att.floatValues, att.numComponents, opts.QuantizationBits)
+			portableValues[i] = transform.quantize(att.floatValues)
+			transforms = append(transforms, transform)
+		} else {
+			portableValues[i] = att.integerValues()
+		}
+	}
+
+	switch opts.Method {
+	case SequentialEncoding:
+		encodeSequentialAttributes(buffer, pc, portableValues)
+	case KdTreeEncoding:
+		encodeKdTreeAttributes(buffer, pc, portableValues)
+	default:
+		return nil, fmt.Errorf("unsupported draco encoding method %d", opts.Method)
+	}
+
+	// data needed to transform the portable values back to the original ones
+	for _, transform := range transforms {
+		transform.encodeParameters(buffer)
+	}
+
+	return buffer.data, nil
+}
+
+func encodeHeader(buffer *encoderBuffer, method EncodingMethod) {
+	buffer.encodeBytes([]byte("DRACO"))
+	buffer.encodeUint8(bitstreamVersionMajor)
+	buffer.encodeUint8(bitstreamVersionMinor)
+	buffer.encodeUint8(encoderTypePointCloud)
+	buffer.encodeUint8(uint8(method))
+	// flags, no metadata
+	buffer.encodeUint16(0)
+}
+
+// Writes the descriptors of the attributes handled by the attributes encoder
+func encodeAttributesEncoderData(buffer *encoderBuffer, pc *PointCloud) {
+	buffer.encodeVarint(uint64(len(pc.attributes)))
+	for _, att := range pc.attributes {
+		buffer.encodeUint8(uint8(att.attributeType))
+		buffer.encodeUint8(uint8(att.dataType))
+		buffer.encodeUint8(uint8(att.numComponents))
+		if att.normalized {
+			buffer.encodeUint8(1)
+		} else {
+			buffer.encodeUint8(0)
+		}
+		buffer.encodeVarint(uint64(att.uniqueId))
+	}
+}
+
+// Quantization of float attributes, mirrors draco::AttributeQuantizationTransform
+type quantizationTransform struct {
+	numComponents    int
+	quantizationBits int
+	minValues        []float32
+	valueRange       float32
+}
+
+func computeQuantizationTransform(values []float32, numComponents int, quantizationBits int) *quantizationTransform {
+	minValues := make([]float32, numComponents)
+	maxValues := make([]float32, numComponents)
+	for c := 0; c < numComponents; c++ {
+		minValues[c] = math.MaxFloat32
+		maxValues[c] = -math.MaxFloat32
+	}
+	for i, v := range values {
+		c := i % numComponents
+		if v < minValues[c] {
+			minValues[c] = v
+		}
+		if v > maxValues[c] {
+			maxValues[c] = v
+		}
+	}
+
+	// a single range is used for all the components
+	var valueRange float32
+	for c := 0; c < numComponents; c++ {
+		if dif := maxValues[c] - minValues[c]; dif > valueRange {
+			valueRange = dif
+		}
+	}
+	// all values are the same, use unit range so that they are all quantized to the same value
+	if valueRange == 0 {
+		valueRange = 1
+	}
+
+	return &quantizationTransform{
+		numComponents:    numComponents,
+		quantizationBits: quantizationBits,
+		minValues:        minValues,
+		valueRange:       valueRange,
+	}
+}
+
+func (t *quantizationTransform) quantize(values []float32) []uint32 {
+	maxQuantizedValue := uint32(1)<<uint(t.quantizationBits) - 1
+	inverseDelta := float32(maxQuantizedValue) / t.valueRange
+
+	quantized := make([]uint32, len(values))
+	for i, v := range values {
+		q := math.Floor(float64((v-t.minValues[i%t.numComponents])*inverseDelta) + 0.5)
+		if q < 0 {
+			q = 0
+		} else if q > float64(maxQuantizedValue) {
+			q = float64(maxQuantizedValue)
+		}
+		quantized[i] = uint32(q)
+	}
+	return quantized
+}
+
+func (t *quantizationTransform) encodeParameters(buffer *encoderBuffer) {
+	for _, v := range t.minValues {
+		buffer.encodeFloat32(v)
+	}
+	buffer.encodeFloat32(t.valueRange)
+	buffer.encodeUint8(uint8(t.quantizationBits))
+}
@@ -0,0 +1,56 @@
+package draco
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// Little endian output buffer mirroring draco::EncoderBuffer
+type encoderBuffer struct {
+	data []byte
+}
+
+func (b *encoderBuffer) encodeUint8(v uint8) {
+	b.data = append(b.data, v)
+}
+
+func (b *encoderBuffer) encodeInt8(v int8) {
+	b.data = append(b.data, uint8(v))
+}
+
+func (b *encoderBuffer) encodeUint16(v uint16) {
+	b.data = append(b.data, byte(v), byte(v>>8))
+}
+
+func (b *encoderBuffer) encodeUint32(v uint32) {
+	b.data = append(b.data, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+}
+
+func (b *encoderBuffer) encodeInt32(v int32) {
+	b.encodeUint32(uint32(v))
+}
+
+func (b *encoderBuffer) encodeFloat32(v float32) {
+	b.encodeUint32(math.Float32bits(v))
+}
+
+// Encodes an unsigned value using 7 bits per byte, the most significant bit flags the presence of a following byte
+func (b *encoderBuffer) encodeVarint(v uint64) {
+	var varint [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(varint[:], v)
+	b.data = append(b.data, varint[:n]...)
+}
+
+func (b *encoderBuffer) encodeBytes(data []byte) {
+	b.data = append(b.data, data...)
+}
+
+// Returns the index of the most significant bit of a non zero value
+func mostSignificantBit(v uint32) int {
+	msb := 0
+	for v > 1 {
+		v >>= 1
+		msb++
+	}
+	return msb
+}
@@ -0,0 +1,199 @@
+package draco
+
+// Compression level of the kd-tree encoder. At level 0 all the bit streams are stored without entropy coding
+// and the splitting axis is chosen in round robin fashion
+const kdTreeCompressionLevel = 0
+
+// Encodes all the attributes together as points of a multidimensional integer space, mirrors
+// draco::KdTreeAttributesEncoder and draco::DynamicIntegerPointsKdTreeEncoder
+func encodeKdTreeAttributes(buffer *encoderBuffer, pc *PointCloud, portableValues [][]uint32) {
+	buffer.encodeUint8(kdTreeCompressionLevel)
+
+	// every point is the concatenation of the values of all its attributes
+	dimension := 0
+	for _, att := range pc.attributes {
+		dimension += att.numComponents
+	}
+	points := make([]uint32, pc.numPoints*dimension)
+	offset := 0
+	for i, att := range pc.attributes {
+		for p := 0; p < pc.numPoints; p++ {
+			copy(points[p*dimension+offset:], portableValues[i][p*att.numComponents:(p+1)*att.numComponents])
+		}
+		offset += att.numComponents
+	}
+
+	// number of bits needed to represent the largest value
+	var bitLength uint32
+	for _, v := range points {
+		if v > 0 {
+			if msb := uint32(mostSignificantBit(v)) + 1; msb > bitLength {
+				bitLength = msb
+			}
+		}
+	}
+
+	encoder := newKdTreeEncoder(uint32(dimension), bitLength, points)
+	encoder.encodePoints(buffer)
+}
+
+type kdTreeEncodingStatus struct {
+	begin    int
+	end      int
+	lastAxis uint32
+	stackPos int
+}
+
+type kdTreeEncoder struct {
+	dimension  uint32
+	bitLength  uint32
+	points     []uint32
+	order      []int
+	baseStack  [][]uint32
+	levelStack [][]uint32
+
+	numbersEncoder       *directBitEncoder
+	remainingBitsEncoder *directBitEncoder
+	axisEncoder          *directBitEncoder
+	halfEncoder          *directBitEncoder
+}
+
+func newKdTreeEncoder(dimension uint32, bitLength uint32, points []uint32) *kdTreeEncoder {
+	numPoints := len(points) / int(dimension)
+	order := make([]int, numPoints)
+	for i := range order {
+		order[i] = i
+	}
+
+	// every split increases the level of one axis, the stack can't be deeper than the total number of levels
+	stackSize := int(bitLength*dimension) + 2
+	baseStack := make([][]uint32, stackSize)
+	levelStack := make([][]uint32, stackSize)
+	for i := 0; i < stackSize; i++ {
+		baseStack[i] = make([]uint32, dimension)
+		levelStack[i] = make([]uint32, dimension)
+	}
+
+	return &kdTreeEncoder{
+		dimension:            dimension,
+		bitLength:            bitLength,
+		points:               points,
+		order:                order,
+		baseStack:            baseStack,
+		levelStack:           levelStack,
+		numbersEncoder:       newDirectBitEncoder(),
+		remainingBitsEncoder: newDirectBitEncoder(),
+		axisEncoder:          newDirectBitEncoder(),
+		halfEncoder:          newDirectBitEncoder(),
+	}
+}
+
+func (e *kdTreeEncoder) point(i int) []uint32 {
+	index := e.order[i] * int(e.dimension)
+	return e.points[index : index+int(e.dimension)]
+}
+
+func (e *kdTreeEncoder) nextAxis(axis uint32) uint32 {
+	axis++
+	if axis == e.dimension {
+		return 0
+	}
+	return axis
+}
+
+func (e *kdTreeEncoder) encodePoints(buffer *encoderBuffer) {
+	buffer.encodeUint32(e.bitLength)
+	buffer.encodeUint32(uint32(len(e.order)))
+	if len(e.order) == 0 {
+		return
+	}
+
+	e.encodeInternal()
+
+	e.numbersEncoder.endEncoding(buffer)
+	e.remainingBitsEncoder.endEncoding(buffer)
+	e.axisEncoder.endEncoding(buffer)
+	e.halfEncoder.endEncoding(buffer)
+}
+
+// Recursively splits the points in halves along one axis at a time, encoding only the number of points
+// falling in each half. Once a cell holds at most two points their remaining bits are stored directly
+func (e *kdTreeEncoder) encodeInternal() {
+	stack := []kdTreeEncodingStatus{{begin: 0, end: len(e.order), lastAxis: 0, stackPos: 0}}
+
+	for len(stack) > 0 {
+		status := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		oldBase := e.baseStack[status.stackPos]
+		levels := e.levelStack[status.stackPos]
+
+		axis := e.nextAxis(status.lastAxis)
+		level := levels[axis]
+		numRemainingPoints := uint32(status.end - status.begin)
+
+		// all axes are subdivided to the end, the points are all equal to the base
+		if e.bitLength-level == 0 {
+			continue
+		}
+
+		if numRemainingPoints <= 2 {
+			for i := status.begin; i < status.end; i++ {
+				p := e.point(i)
+				currentAxis := axis
+				for j := uint32(0); j < e.dimension; j++ {
+					numRemainingBits := e.bitLength - levels[currentAxis]
+					if numRemainingBits > 0 {
+						e.remainingBitsEncoder.encodeLeastSignificantBits32(numRemainingBits, p[currentAxis])
+					}
+					currentAxis = e.nextAxis(currentAxis)
+				}
+			}
+			continue
+		}
+
+		numRemainingBits := e.bitLength - level
+		modifier := uint32(1) << (numRemainingBits - 1)
+		newBase := e.baseStack[status.stackPos+1]
+		copy(newBase, oldBase)
+		newBase[axis] += modifier
+
+		split := e.partition(status.begin, status.end, axis, newBase[axis])
+
+		// number of points in the first and second half, encoded as the difference from the half of the points
+		requiredBits := uint32(mostSignificantBit(numRemainingPoints))
+		firstHalf := uint32(split - status.begin)
+		secondHalf := uint32(status.end - split)
+		left := firstHalf < secondHalf
+		if firstHalf != secondHalf {
+			e.halfEncoder.encodeBit(left)
+		}
+		if left {
+			e.numbersEncoder.encodeLeastSignificantBits32(requiredBits, numRemainingPoints/2-firstHalf)
+		} else {
+			e.numbersEncoder.encodeLeastSignificantBits32(requiredBits, numRemainingPoints/2-secondHalf)
+		}
+
+		levels[axis]++
+		copy(e.levelStack[status.stackPos+1], levels)
+		if split != status.begin {
+			stack = append(stack, kdTreeEncodingStatus{begin: status.begin, end: split, lastAxis: axis, stackPos: status.stackPos})
+		}
+		if split != status.end {
+			stack = append(stack, kdTreeEncodingStatus{begin: split, end: status.end, lastAxis: axis, stackPos: status.stackPos + 1})
+		}
+	}
+}
+
+// Reorders the points in [begin, end) so that the ones whose value along the axis is lower than the given
+// value come first. Returns the index of the first point of the second group
+func (e *kdTreeEncoder) partition(begin int, end int, axis uint32, value uint32) int {
+	split := begin
+	for i := begin; i < end; i++ {
+		if e.points[e.order[i]*int(e.dimension)+int(axis)] < value {
+			e.order[i], e.order[split] = e.order[split], e.order[i]
+			split++
+		}
+	}
+	return split
+}
@@ -0,0 +1,129 @@
+package draco
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Semantic of an attribute, values match draco::GeometryAttribute::Type
+type AttributeType uint8
+
+const (
+	AttributePosition AttributeType = 0
+	AttributeNormal   AttributeType = 1
+	AttributeColor    AttributeType = 2
+	AttributeTexCoord AttributeType = 3
+	AttributeGeneric  AttributeType = 4
+)
+
+// Data type of the attribute values, values match draco::DataType. Only the types needed by the tiler are supported
+type dataType uint8
+
+const (
+	dataTypeUint8   dataType = 2
+	dataTypeUint16  dataType = 4
+	dataTypeFloat32 dataType = 9
+)
+
+type attribute struct {
+	attributeType AttributeType
+	dataType      dataType
+	numComponents int
+	normalized    bool
+	uniqueId      int
+	floatValues   []float32
+	uint8Values   []uint8
+	uint16Values  []uint16
+}
+
+// In memory representation of a point cloud to be encoded. Every attribute stores one value per point
+// and attributes are identified by their unique id, which is the order in which they are added
+type PointCloud struct {
+	numPoints  int
+	attributes []*attribute
+}
+
+func NewPointCloud(numPoints int) *PointCloud {
+	return &PointCloud{
+		numPoints:  numPoints,
+		attributes: make([]*attribute, 0),
+	}
+}
+
+func (pc *PointCloud) NumPoints() int {
+	return pc.numPoints
+}
+
+// Adds a float32 attribute, values are quantized at encoding time. Returns the unique id of the attribute
+func (pc *PointCloud) AddFloat32Attribute(attributeType AttributeType, numComponents int, values []float32) (int, error) {
+	if err := pc.checkAttributeSize(numComponents, len(values)); err != nil {
+		return -1, err
+	}
+	return pc.addAttribute(&attribute{
+		attributeType: attributeType,
+		dataType:      dataTypeFloat32,
+		numComponents: numComponents,
+		floatValues:   values,
+	}), nil
+}
+
+// Adds an uint8 attribute, values are encoded losslessly. Returns the unique id of the attribute
+func (pc *PointCloud) AddUint8Attribute(attributeType AttributeType, numComponents int, normalized bool, values []uint8) (int, error) {
+	if err := pc.checkAttributeSize(numComponents, len(values)); err != nil {
+		return -1, err
+	}
+	return pc.addAttribute(&attribute{
+		attributeType: attributeType,
+		dataType:      dataTypeUint8,
+		numComponents: numComponents,
+		normalized:    normalized,
+		uint8Values:   values,
+	}), nil
+}
+
+// Adds an uint16 attribute, values are encoded losslessly. Returns the unique id of the attribute
+func (pc *PointCloud) AddUint16Attribute(attributeType AttributeType, numComponents int, normalized bool, values []uint16) (int, error) {
+	if err := pc.checkAttributeSize(numComponents, len(values)); err != nil {
+		return -1, err
+	}
+	return pc.addAttribute(&attribute{
+		attributeType: attributeType,
+		dataType:      dataTypeUint16,
+		numComponents: numComponents,
+		normalized:    normalized,
+		uint16Values:  values,
+	}), nil
+}
+
+func (pc *PointCloud) addAttribute(att *attribute) int {
+	att.uniqueId = len(pc.attributes)
+	pc.attributes = append(pc.attributes, att)
+	return att.uniqueId
+}
+
+func (pc *PointCloud) checkAttributeSize(numComponents int, numValues int) error {
+	if numComponents <= 0 {
+		return errors.New("attribute must have at least one component")
+	}
+	if numValues != numComponents*pc.numPoints {
+		return fmt.Errorf("attribute has %d values, expected %d", numValues, numComponents*pc.numPoints)
+	}
+	return nil
+}
+
+// Returns the attribute values as unsigned integers, as required by the integer attribute encoders
+func (att *attribute) integerValues() []uint32 {
+	if att.dataType == dataTypeUint16 {
+		values := make([]uint32, len(att.uint16Values))
+		for i, v := range att.uint16Values {
+			values[i] = uint32(v)
+		}
+		return values
+	}
+
+	values := make([]uint32, len(att.uint8Values))
+	for i, v := range att.uint8Values {
+		values[i] = uint32(v)
+	}
+	return values
+}
@@ -0,0 +1,234 @@
+package draco
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	// Symbols are coded with a single rANS probability table
+	symbolCodingRaw = 1
+
+	// Maximum bit length of the number of unique symbols supported by the rANS coder
+	maxRawEncodingBitLength = 18
+
+	// Maximum symbol value coded with the rANS coder, larger symbols would require huge probability tables
+	maxRawEncodingSymbol = 1 << 24
+
+	ransIoBase = 256
+)
+
+// Encodes the symbols with a rANS coder, mirrors draco::EncodeRawSymbols. Returns false if the symbols
+// cannot be entropy coded
+func encodeSymbols(buffer *encoderBuffer, symbols []uint32) bool {
+	var maxValue uint32
+	for _, s := range symbols {
+		if s > maxValue {
+			maxValue = s
+		}
+	}
+	if maxValue >= maxRawEncodingSymbol {
+		return false
+	}
+	frequencies := make([]uint64, uint64(maxValue)+1)
+	numUniqueSymbols := 0
+	for _, s := range symbols {
+		if frequencies[s] == 0 {
+			numUniqueSymbols++
+		}
+		frequencies[s]++
+	}
+
+	uniqueSymbolsBitLength := mostSignificantBit(uint32(numUniqueSymbols)) + 1
+	if uniqueSymbolsBitLength > maxRawEncodingBitLength {
+		return false
+	}
+
+	encoder := newRAnsSymbolEncoder(uniqueSymbolsBitLength)
+	if !encoder.createProbabilityTable(frequencies) {
+		return false
+	}
+
+	buffer.encodeUint8(symbolCodingRaw)
+	buffer.encodeUint8(uint8(uniqueSymbolsBitLength))
+	encoder.encodeTable(buffer)
+
+	// rANS works as a stack, symbols are encoded in reverse order so that they are decoded in the original order
+	for i := len(symbols) - 1; i >= 0; i-- {
+		encoder.encodeSymbol(symbols[i])
+	}
+	data := encoder.endEncoding()
+	buffer.encodeVarint(uint64(len(data)))
+	buffer.encodeBytes(data)
+
+	return true
+}
+
+type ransSymbol struct {
+	prob    uint32
+	cumProb uint32
+}
+
+type rAnsSymbolEncoder struct {
+	precision        uint32
+	lRansBase        uint32
+	probabilityTable []ransSymbol
+	state            uint32
+	data             []byte
+}
+
+func newRAnsSymbolEncoder(uniqueSymbolsBitLength int) *rAnsSymbolEncoder {
+	// precision of the rANS coder, clamped between 12 and 20 bits
+	precisionBits := (3 * uniqueSymbolsBitLength) / 2
+	if precisionBits < 12 {
+		precisionBits = 12
+	} else if precisionBits > 20 {
+		precisionBits = 20
+	}
+	precision := uint32(1) << uint(precisionBits)
+
+	return &rAnsSymbolEncoder{
+		precision: precision,
+		lRansBase: precision * 4,
+		state:     precision * 4,
+		data:      make([]byte, 0),
+	}
+}
+
+// Rescales the symbol frequencies to probabilities summing up to the precision of the coder,
+// every symbol that appears at least once gets a non zero probability
+func (e *rAnsSymbolEncoder) createProbabilityTable(frequencies []uint64) bool {
+	var totalFreq uint64
+	numSymbols := 0
+	for i, freq := range frequencies {
+		totalFreq += freq
+		if freq > 0 {
+			numSymbols = i + 1
+		}
+	}
+
+	e.probabilityTable = make([]ransSymbol, numSymbols)
+	var totalProb uint32
+	for i := 0; i < numSymbols; i++ {
+		prob := uint32(math.Floor(float64(frequencies[i])/float64(totalFreq)*float64(e.precision) + 0.5))
+		if prob == 0 && frequencies[i] > 0 {
+			prob = 1
+		}
+		e.probabilityTable[i].prob = prob
+		totalProb += prob
+	}
+
+	if totalProb != e.precision {
+		sortedSymbols := make([]int, numSymbols)
+		for i := range sortedSymbols {
+			sortedSymbols[i] = i
+		}
+		sort.SliceStable(sortedSymbols, func(i, j int) bool {
+			return e.probabilityTable[sortedSymbols[i]].prob > e.probabilityTable[sortedSymbols[j]].prob
+		})
+
+		if totalProb < e.precision {
+			// the missing precision is assigned to the most frequent symbol
+			e.probabilityTable[sortedSymbols[0]].prob += e.precision - totalProb
+		} else {
+			// the exceeding precision is removed from the most frequent symbols, keeping every probability above zero
+			for totalProb > e.precision {
+				progress := false
+				for _, s := range sortedSymbols {
+					if totalProb == e.precision {
+						break
+					}
+					prob := e.probabilityTable[s].prob
+					if prob <= 1 {
+						break
+					}
+					fix := uint32(uint64(prob) * uint64(totalProb-e.precision) / uint64(totalProb))
+					if fix == 0 {
+						fix = 1
+					}
+					if fix > totalProb-e.precision {
+						fix = totalProb - e.precision
+					}
+					if fix >= prob {
+						fix = prob - 1
+					}
+					e.probabilityTable[s].prob -= fix
+					totalProb -= fix
+					progress = true
+				}
+				if !progress {
+					return false
+				}
+			}
+		}
+	}
+
+	var cumProb uint32
+	for i := range e.probabilityTable {
+		e.probabilityTable[i].cumProb = cumProb
+		cumProb += e.probabilityTable[i].prob
+	}
+	return cumProb == e.precision
+}
+
+// Writes the probability table. Every probability is stored in 1 to 3 bytes, the first two bits of the first
+// byte storing the number of extra bytes. Runs of zero probabilities are stored as a single byte with token 3
+func (e *rAnsSymbolEncoder) encodeTable(buffer *encoderBuffer) {
+	numSymbols := len(e.probabilityTable)
+	buffer.encodeVarint(uint64(numSymbols))
+	for i := 0; i < numSymbols; i++ {
+		prob := e.probabilityTable[i].prob
+		if prob == 0 {
+			// the last symbol always has a non zero probability
+			offset := 0
+			for ; offset < (1<<6)-1; offset++ {
+				if e.probabilityTable[i+offset+1].prob > 0 {
+					break
+				}
+			}
+			buffer.encodeUint8(uint8(offset<<2) | 3)
+			i += offset
+			continue
+		}
+
+		numExtraBytes := 0
+		if prob >= 1<<6 {
+			numExtraBytes++
+			if prob >= 1<<14 {
+				numExtraBytes++
+			}
+		}
+		buffer.encodeUint8(uint8(prob<<2) | uint8(numExtraBytes))
+		for b := 0; b < numExtraBytes; b++ {
+			buffer.encodeUint8(uint8(prob >> uint(8*(b+1)-2)))
+		}
+	}
+}
+
+func (e *rAnsSymbolEncoder) encodeSymbol(symbol uint32) {
+	sym := e.probabilityTable[symbol]
+	// renormalization, the state is kept in [lRansBase, lRansBase * ransIoBase)
+	for e.state >= e.lRansBase/e.precision*ransIoBase*sym.prob {
+		e.data = append(e.data, byte(e.state%ransIoBase))
+		e.state /= ransIoBase
+	}
+	e.state = (e.state/sym.prob)*e.precision + e.state%sym.prob + sym.cumProb
+}
+
+// Flushes the final state of the coder, the first two bits of the last byte store the number of bytes used
+func (e *rAnsSymbolEncoder) endEncoding() []byte {
+	state := e.state - e.lRansBase
+	if state < 1<<6 {
+		e.data = append(e.data, byte(state))
+	} else if state < 1<<14 {
+		v := (0x01 << 14) + state
+		e.data = append(e.data, byte(v), byte(v>>8))
+	} else if state < 1<<22 {
+		v := (0x02 << 22) + state
+		e.data = append(e.data, byte(v), byte(v>>8), byte(v>>16))
+	} else {
+		v := (0x03 << 30) + state
+		e.data = append(e.data, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+	}
+	return e.data
+}
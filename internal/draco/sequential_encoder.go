@@ -0,0 +1,61 @@
+package draco
+
+// Identifiers of the sequential attribute decoders, values match draco::SequentialAttributeEncoderType
+const (
+	sequentialAttributeEncoderInteger      = 1
+	sequentialAttributeEncoderQuantization = 2
+)
+
+// Prediction scheme of the integer values, values match draco::PredictionSchemeMethod
+const predictionNone = -2
+
+// Encodes the portable values of all the attributes one after the other, following the original point order
+func encodeSequentialAttributes(buffer *encoderBuffer, pc *PointCloud, portableValues [][]uint32) {
+	// type of the decoder to use for each attribute
+	for _, att := range pc.attributes {
+		if att.dataType == dataTypeFloat32 {
+			buffer.encodeUint8(sequentialAttributeEncoderQuantization)
+		} else {
+			buffer.encodeUint8(sequentialAttributeEncoderInteger)
+		}
+	}
+
+	for i := range pc.attributes {
+		buffer.encodeInt8(predictionNone)
+
+		// values are signed integers in the bitstream, converted to symbols as 2*v for positive numbers
+		symbols := make([]uint32, len(portableValues[i]))
+		for j, v := range portableValues[i] {
+			symbols[j] = v << 1
+		}
+		encodeIntegerValues(buffer, symbols)
+	}
+}
+
+// Encodes the symbols with the rANS entropy coder. When the coder can't be used or when its probability table
+// outweighs the gain, as for small sparse inputs, the symbols are stored using the minimum number of bytes per value
+func encodeIntegerValues(buffer *encoderBuffer, symbols []uint32) {
+	var maskedValue uint32
+	for _, v := range symbols {
+		maskedValue |= v
+	}
+	numBytes := 1
+	if maskedValue != 0 {
+		numBytes = 1 + mostSignificantBit(maskedValue)/8
+	}
+
+	compressed := &encoderBuffer{}
+	if encodeSymbols(compressed, symbols) && len(compressed.data) < 1+numBytes*len(symbols) {
+		buffer.encodeUint8(1)
+		buffer.encodeBytes(compressed.data)
+		return
+	}
+
+	buffer.encodeUint8(0)
+	buffer.encodeUint8(uint8(numBytes))
+	for _, v := range symbols {
+		for b := 0; b < numBytes; b++ {
+			buffer.encodeUint8(uint8(v >> uint(8*b)))
+		}
+	}
+}
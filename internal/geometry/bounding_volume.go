@@ -0,0 +1,233 @@
+package geometry
+
+import (
+	"math"
+)
+
+// WGS84 ellipsoid parameters
+const (
+	wgs84SemiMajorAxis        = 6378137.0
+	wgs84Flattening           = 1 / 298.257223563
+	wgs84FirstEccentricitySqr = wgs84Flattening * (2 - wgs84Flattening)
+)
+
+// Minimum half size in meters of an oriented bounding box, so that the box of flat or single point data is not degenerate
+const minHalfAxisLength = 0.01
+
+// Box oriented in ECEF (EPSG:4978) coordinates, as the 3D Tiles boundingVolume.box
+type OrientedBoundingBox struct {
+	Center   Coordinate
+	HalfAxes [3]Coordinate // x, y and z half axes, each one as long as half the size of the box along it
+}
+
+// Sphere in ECEF (EPSG:4978) coordinates, as the 3D Tiles boundingVolume.sphere
+type BoundingSphere struct {
+	Center Coordinate
+	Radius float64
+}
+
+// Returns the box as the 12 elements array of the 3D Tiles boundingVolume.box
+func (b *OrientedBoundingBox) GetAsArray() []float64 {
+	return []float64{
+		b.Center.X, b.Center.Y, b.Center.Z,
+		b.HalfAxes[0].X, b.HalfAxes[0].Y, b.HalfAxes[0].Z,
+		b.HalfAxes[1].X, b.HalfAxes[1].Y, b.HalfAxes[1].Z,
+		b.HalfAxes[2].X, b.HalfAxes[2].Y, b.HalfAxes[2].Z,
+	}
+}
+
+// Returns the 8 corners of the box
+func (b *OrientedBoundingBox) GetCorners() []Coordinate {
+	corners := make([]Coordinate, 0, 8)
+	for i := 0; i < 8; i++ {
+		corner := b.Center
+		for axis := 0; axis < 3; axis++ {
+			sign := float64(1)
+			if i>>uint(axis)&1 == 0 {
+				sign = -1
+			}
+			corner = addScaled(corner, b.HalfAxes[axis], sign)
+		}
+		corners = append(corners, corner)
+	}
+	return corners
+}
+
+// Returns true if the given point is inside the box, enlarged by the given tolerance in meters
+func (b *OrientedBoundingBox) Contains(point Coordinate, tolerance float64) bool {
+	d := sub(point, b.Center)
+	for _, halfAxis := range b.HalfAxes {
+		length := norm(halfAxis)
+		if length == 0 {
+			continue
+		}
+		if math.Abs(dot(d, halfAxis))/length > length+tolerance {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the sphere as the 4 elements array of the 3D Tiles boundingVolume.sphere
+func (s *BoundingSphere) GetAsArray() []float64 {
+	return []float64{s.Center.X, s.Center.Y, s.Center.Z, s.Radius}
+}
+
+// Returns true if the given point is inside the sphere, enlarged by the given tolerance in meters
+func (s *BoundingSphere) Contains(point Coordinate, tolerance float64) bool {
+	return norm(sub(point, s.Center)) <= s.Radius+tolerance
+}
+
+// Returns the smallest box containing the given ECEF points whose z axis is the ellipsoid normal at their centroid.
+// The box is rotated around the normal along the principal direction of the points, so that data not aligned with
+// the meridians gets a tight box too. Returns nil if there are no points
+func NewOrientedBoundingBoxFromPoints(points []Coordinate) *OrientedBoundingBox {
+	if len(points) == 0 {
+		return nil
+	}
+
+	var origin Coordinate
+	for _, point := range points {
+		origin = addScaled(origin, point, 1/float64(len(points)))
+	}
+	lon, lat, _ := EcefToGeodetic(origin)
+	east, north, up := enuAxes(lon, lat)
+
+	// principal direction of the points projected on the tangent plane
+	var sumEE, sumNN, sumEN, sumE, sumN float64
+	for _, point := range points {
+		d := sub(point, origin)
+		e, n := dot(d, east), dot(d, north)
+		sumE += e
+		sumN += n
+		sumEE += e * e
+		sumNN += n * n
+		sumEN += e * n
+	}
+	count := float64(len(points))
+	covEE := sumEE/count - (sumE/count)*(sumE/count)
+	covNN := sumNN/count - (sumN/count)*(sumN/count)
+	covEN := sumEN/count - (sumE/count)*(sumN/count)
+	angle := 0.5 * math.Atan2(2*covEN, covEE-covNN)
+
+	axes := [3]Coordinate{
+		addScaled(scale(east, math.Cos(angle)), north, math.Sin(angle)),
+		addScaled(scale(east, -math.Sin(angle)), north, math.Cos(angle)),
+		up,
+	}
+
+	var min, max [3]float64
+	for axis := range axes {
+		min[axis] = math.Inf(1)
+		max[axis] = math.Inf(-1)
+	}
+	for _, point := range points {
+		d := sub(point, origin)
+		for axis := range axes {
+			v := dot(d, axes[axis])
+			min[axis] = math.Min(min[axis], v)
+			max[axis] = math.Max(max[axis], v)
+		}
+	}
+
+	box := &OrientedBoundingBox{Center: origin}
+	for axis := range axes {
+		box.Center = addScaled(box.Center, axes[axis], (min[axis]+max[axis])/2)
+		box.HalfAxes[axis] = scale(axes[axis], math.Max((max[axis]-min[axis])/2, minHalfAxisLength))
+	}
+
+	return box
+}
+
+// Returns the sphere centered in the given ECEF point containing the given ECEF points
+func NewBoundingSphereFromPoints(center Coordinate, points []Coordinate) *BoundingSphere {
+	radius := float64(0)
+	for _, point := range points {
+		radius = math.Max(radius, norm(sub(point, center)))
+	}
+	return &BoundingSphere{
+		Center: center,
+		Radius: radius,
+	}
+}
+
+// Returns the smallest sphere centered in the center of the first one containing both the given spheres
+func (s *BoundingSphere) Enclose(other *BoundingSphere) *BoundingSphere {
+	return &BoundingSphere{
+		Center: s.Center,
+		Radius: math.Max(s.Radius, norm(sub(other.Center, s.Center))+other.Radius),
+	}
+}
+
+// Converts the given WGS84 longitude and latitude, in radians, and ellipsoidal height to ECEF (EPSG:4978) coordinates
+func GeodeticToEcef(lon float64, lat float64, height float64) Coordinate {
+	sinLat, cosLat := math.Sin(lat), math.Cos(lat)
+	n := wgs84SemiMajorAxis / math.Sqrt(1-wgs84FirstEccentricitySqr*sinLat*sinLat)
+	return Coordinate{
+		X: (n + height) * cosLat * math.Cos(lon),
+		Y: (n + height) * cosLat * math.Sin(lon),
+		Z: (n*(1-wgs84FirstEccentricitySqr) + height) * sinLat,
+	}
+}
+
+// Converts the given ECEF (EPSG:4978) coordinates to WGS84 longitude and latitude, in radians, and ellipsoidal height
+func EcefToGeodetic(coord Coordinate) (float64, float64, float64) {
+	lon := math.Atan2(coord.Y, coord.X)
+	p := math.Hypot(coord.X, coord.Y)
+
+	// iterative solution, converges to sub millimeter precision in a few steps for terrestrial heights
+	lat := math.Atan2(coord.Z, p*(1-wgs84FirstEccentricitySqr))
+	height := float64(0)
+	for i := 0; i < 5; i++ {
+		sinLat := math.Sin(lat)
+		n := wgs84SemiMajorAxis / math.Sqrt(1-wgs84FirstEccentricitySqr*sinLat*sinLat)
+		height = p/math.Cos(lat) - n
+		lat = math.Atan2(coord.Z, p*(1-wgs84FirstEccentricitySqr*n/(n+height)))
+	}
+
+	return lon, lat, height
+}
+
+// Returns the 8 corners in ECEF coordinates of the given region, expressed as west, south, east, north in radians
+// and min and max heights
+func GetRegionCorners(region []float64) []Coordinate {
+	corners := make([]Coordinate, 0, 8)
+	for _, lon := range []float64{region[0], region[2]} {
+		for _, lat := range []float64{region[1], region[3]} {
+			for _, height := range []float64{region[4], region[5]} {
+				corners = append(corners, GeodeticToEcef(lon, lat, height))
+			}
+		}
+	}
+	return corners
+}
+
+// Returns the east, north and up unit vectors of the local tangent plane at the given longitude and latitude in radians
+func enuAxes(lon float64, lat float64) (Coordinate, Coordinate, Coordinate) {
+	sinLon, cosLon := math.Sin(lon), math.Cos(lon)
+	sinLat, cosLat := math.Sin(lat), math.Cos(lat)
+	east := Coordinate{X: -sinLon, Y: cosLon, Z: 0}
+	north := Coordinate{X: -sinLat * cosLon, Y: -sinLat * sinLon, Z: cosLat}
+	up := Coordinate{X: cosLat * cosLon, Y: cosLat * sinLon, Z: sinLat}
+	return east, north, up
+}
+
+func sub(a Coordinate, b Coordinate) Coordinate {
+	return Coordinate{X: a.X - b.X, Y: a.Y - b.Y, Z: a.Z - b.Z}
+}
+
+func scale(a Coordinate, s float64) Coordinate {
+	return Coordinate{X: a.X * s, Y: a.Y * s, Z: a.Z * s}
+}
+
+func addScaled(a Coordinate, b Coordinate, s float64) Coordinate {
+	return Coordinate{X: a.X + b.X*s, Y: a.Y + b.Y*s, Z: a.Z + b.Z*s}
+}
+
+func dot(a Coordinate, b Coordinate) float64 {
+	return a.X*b.X + a.Y*b.Y + a.Z*b.Z
+}
+
+func norm(a Coordinate) float64 {
+	return math.Sqrt(dot(a, a))
+}
@@ -0,0 +1,227 @@
+package io
+
+import (
+	"encoding/binary"
+	"math"
+	"sort"
+
+	"github.com/ecopia-map/cesium_tiler/internal/data"
+	"github.com/ecopia-map/cesium_tiler/internal/tiler"
+)
+
+// Types used to store a batch attribute in the pnts batch table and in the glb vertex attributes
+type batchAttributeType struct {
+	pntsComponentType     string // componentType of the pnts batch table property
+	pntsComponentSize     int    // size in bytes of a pnts value
+	gltfComponentType     int    // componentType of the glb accessor
+	gltfComponentSize     int    // size in bytes of a glb value
+	metadataComponentType string // componentType of the EXT_structural_metadata class property
+}
+
+var (
+	uint8BatchAttributeType = batchAttributeType{
+		pntsComponentType:     "UNSIGNED_BYTE",
+		pntsComponentSize:     1,
+		gltfComponentType:     gltfComponentTypeUnsignedByte,
+		gltfComponentSize:     1,
+		metadataComponentType: "UINT8",
+	}
+	uint16BatchAttributeType = batchAttributeType{
+		pntsComponentType:     "UNSIGNED_SHORT",
+		pntsComponentSize:     2,
+		gltfComponentType:     gltfComponentTypeUnsignedShort,
+		gltfComponentSize:     2,
+		metadataComponentType: "UINT16",
+	}
+	float32BatchAttributeType = batchAttributeType{
+		pntsComponentType:     "FLOAT",
+		pntsComponentSize:     4,
+		gltfComponentType:     gltfComponentTypeFloat,
+		gltfComponentSize:     4,
+		metadataComponentType: "FLOAT32",
+	}
+	// glTF has no double vertex attributes, glb values are stored as float32 relative to the tile minimum, which is
+	// set as offset of the metadata property
+	float64BatchAttributeType = batchAttributeType{
+		pntsComponentType:     "DOUBLE",
+		pntsComponentSize:     8,
+		gltfComponentType:     gltfComponentTypeFloat,
+		gltfComponentSize:     4,
+		metadataComponentType: "FLOAT32",
+	}
+)
+
+var batchAttributeTypes = map[tiler.BatchAttribute]batchAttributeType{
+	tiler.BatchAttributeIntensity:       uint16BatchAttributeType,
+	tiler.BatchAttributeClassification:  uint8BatchAttributeType,
+	tiler.BatchAttributeGpsTime:         float64BatchAttributeType,
+	tiler.BatchAttributeReturnNumber:    uint8BatchAttributeType,
+	tiler.BatchAttributeNumberOfReturns: uint8BatchAttributeType,
+	tiler.BatchAttributePointSourceId:   uint16BatchAttributeType,
+	tiler.BatchAttributeScanAngle:       float32BatchAttributeType,
+	tiler.BatchAttributeUserData:        uint8BatchAttributeType,
+	tiler.BatchAttributeNir:             uint16BatchAttributeType,
+}
+
+// Extra bytes attributes have no fixed type, they are stored as doubles which can hold the scaled value of any of them
+var extraBytesBatchAttributeType = float64BatchAttributeType
+
+// Values of a batch attribute for the points of a tile
+type batchAttributeValues struct {
+	attribute       tiler.BatchAttribute
+	attributeType   batchAttributeType
+	extraBytesIndex int // index of the value in the extra bytes of the point extend, -1 for the other attributes
+	values          []float64
+}
+
+// Instances the values of the given attributes for the given number of points. Extra bytes attributes read the
+// extra bytes values of the points in the order they are listed
+func newBatchAttributeValuesList(attributes []tiler.BatchAttribute, numPoints int) []*batchAttributeValues {
+	list := make([]*batchAttributeValues, len(attributes))
+	extraBytesIndex := 0
+	for i, attribute := range attributes {
+		list[i] = &batchAttributeValues{
+			attribute:       attribute,
+			attributeType:   batchAttributeTypes[attribute],
+			extraBytesIndex: -1,
+			values:          make([]float64, numPoints),
+		}
+		if _, ok := attribute.ExtraBytesName(); ok {
+			list[i].attributeType = extraBytesBatchAttributeType
+			list[i].extraBytesIndex = extraBytesIndex
+			extraBytesIndex++
+		}
+	}
+	return list
+}
+
+// Returns the value of the attribute of the given point
+func (a *batchAttributeValues) pointValue(point *data.Point) float64 {
+	if a.extraBytesIndex >= 0 {
+		if point.PointExtend == nil || a.extraBytesIndex >= len(point.PointExtend.ExtraBytes) {
+			return 0
+		}
+		return point.PointExtend.ExtraBytes[a.extraBytesIndex]
+	}
+	return getBatchAttributeValue(a.attribute, point)
+}
+
+// Returns the value of the given attribute of the point, attributes of the las record are 0 if the point has no extend
+func getBatchAttributeValue(attribute tiler.BatchAttribute, point *data.Point) float64 {
+	switch attribute {
+	case tiler.BatchAttributeIntensity:
+		return float64(point.Intensity)
+	case tiler.BatchAttributeClassification:
+		return float64(point.Classification)
+	}
+
+	pointExtend := point.PointExtend
+	if pointExtend == nil {
+		return 0
+	}
+	switch attribute {
+	case tiler.BatchAttributeGpsTime:
+		return pointExtend.GpsTime
+	case tiler.BatchAttributeReturnNumber:
+		return float64(pointExtend.ReturnNumber)
+	case tiler.BatchAttributeNumberOfReturns:
+		return float64(pointExtend.NumberOfReturns)
+	case tiler.BatchAttributePointSourceId:
+		return float64(pointExtend.PointSourceID)
+	case tiler.BatchAttributeScanAngle:
+		return float64(pointExtend.ScanAngle)
+	case tiler.BatchAttributeUserData:
+		return float64(pointExtend.UserData)
+	case tiler.BatchAttributeNir:
+		return float64(pointExtend.Nir)
+	}
+	return 0
+}
+
+// Returns the minimum of the values, which is subtracted from float64 values stored as float32
+func (a *batchAttributeValues) min() float64 {
+	min := math.MaxFloat64
+	for _, v := range a.values {
+		min = math.Min(min, v)
+	}
+	return min
+}
+
+// Returns the pnts binary representation of the values
+func (a *batchAttributeValues) pntsBytes() []byte {
+	return encodeBatchAttributeValues(a.values, a.attributeType.pntsComponentSize, 0)
+}
+
+// Returns the glb binary representation of the values, relative to the glb offset
+func (a *batchAttributeValues) gltfBytes() []byte {
+	return encodeBatchAttributeValues(a.values, a.attributeType.gltfComponentSize, a.gltfOffset())
+}
+
+// Returns the offset subtracted from the glb values, the minimum for float64 values stored as float32 and 0 otherwise
+func (a *batchAttributeValues) gltfOffset() float64 {
+	if a.attributeType.pntsComponentSize > a.attributeType.gltfComponentSize && len(a.values) > 0 {
+		return a.min()
+	}
+	return 0
+}
+
+// Returns the values as float32, relative to the glb offset
+func (a *batchAttributeValues) float32Values() []float32 {
+	offset := a.gltfOffset()
+	out := make([]float32, len(a.values))
+	for i, v := range a.values {
+		out[i] = float32(v - offset)
+	}
+	return out
+}
+
+func (a *batchAttributeValues) uint16Values() []uint16 {
+	out := make([]uint16, len(a.values))
+	for i, v := range a.values {
+		out[i] = uint16(v)
+	}
+	return out
+}
+
+func (a *batchAttributeValues) uint8Values() []uint8 {
+	out := make([]uint8, len(a.values))
+	for i, v := range a.values {
+		out[i] = uint8(v)
+	}
+	return out
+}
+
+// Encodes the values little endian with the given component size: 1 and 2 bytes are unsigned integers, 4 and 8 bytes
+// are floats. The offset is subtracted from the values before encoding them
+func encodeBatchAttributeValues(values []float64, componentSize int, offset float64) []byte {
+	out := make([]byte, len(values)*componentSize)
+	for i, v := range values {
+		element := out[i*componentSize : (i+1)*componentSize]
+		switch componentSize {
+		case 1:
+			element[0] = uint8(v)
+		case 2:
+			binary.LittleEndian.PutUint16(element, uint16(v))
+		case 4:
+			binary.LittleEndian.PutUint32(element, math.Float32bits(float32(v-offset)))
+		case 8:
+			binary.LittleEndian.PutUint64(element, math.Float64bits(v-offset))
+		}
+	}
+	return out
+}
+
+// Returns the attributes sorted by decreasing pnts component size, so that every property of the batch table binary
+// body is aligned to its component size
+func sortBatchAttributesForPnts(attributes []*batchAttributeValues) []*batchAttributeValues {
+	sorted := append([]*batchAttributeValues(nil), attributes...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].attributeType.pntsComponentSize > sorted[j].attributeType.pntsComponentSize
+	})
+	return sorted
+}
+
+// Name of the glb vertex attribute of the given batch attribute, application specific attributes start with '_'
+func gltfBatchAttributeName(attribute tiler.BatchAttribute) string {
+	return "_" + attribute.PropertyName()
+}
@@ -0,0 +1,133 @@
+package io
+
+import (
+	"errors"
+	"math"
+
+	"github.com/ecopia-map/cesium_tiler/internal/converters"
+	"github.com/ecopia-map/cesium_tiler/internal/geometry"
+	"github.com/ecopia-map/cesium_tiler/internal/octree"
+	"github.com/ecopia-map/cesium_tiler/internal/tiler"
+)
+
+// Returns the bounding volume of the given type of the tile of the given node. Regions are computed from the node
+// bounding box, boxes and spheres from the points of the node and of its descendants, plus the parent points
+// included in the tile content with the REPLACE refine mode
+func newNodeBoundingVolume(
+	node octree.INode,
+	converter converters.CoordinateConverter,
+	volumeType tiler.BoundingVolumeType,
+	refineMode tiler.RefineMode,
+) (BoundingVolume, error) {
+	switch volumeType {
+	case tiler.BoundingVolumeBox, tiler.BoundingVolumeSphere:
+		volume, err := node.GetTightBoundingVolume(converter, refineMode == tiler.RefineModeReplace)
+		if err != nil {
+			return BoundingVolume{}, err
+		}
+		if volumeType == tiler.BoundingVolumeBox {
+			return BoundingVolume{Box: volume.Box.GetAsArray()}, nil
+		}
+		return BoundingVolume{Sphere: volume.Sphere.GetAsArray()}, nil
+	default:
+		reg, err := node.GetBoundingBoxRegion(converter)
+		if err != nil {
+			return BoundingVolume{}, err
+		}
+		if reg == nil {
+			return BoundingVolume{}, errors.New("unable to convert the node bounding box to a region")
+		}
+		return BoundingVolume{Region: reg.GetAsArray()}, nil
+	}
+}
+
+// Returns the points in ECEF coordinates whose convex hull contains the bounding volume: the corners of a region or
+// of a box, the corners of the axis aligned cube circumscribed to a sphere
+func (bv *BoundingVolume) GetCorners() []geometry.Coordinate {
+	if len(bv.Box) == 12 {
+		box := geometry.OrientedBoundingBox{
+			Center: geometry.Coordinate{X: bv.Box[0], Y: bv.Box[1], Z: bv.Box[2]},
+			HalfAxes: [3]geometry.Coordinate{
+				{X: bv.Box[3], Y: bv.Box[4], Z: bv.Box[5]},
+				{X: bv.Box[6], Y: bv.Box[7], Z: bv.Box[8]},
+				{X: bv.Box[9], Y: bv.Box[10], Z: bv.Box[11]},
+			},
+		}
+		return box.GetCorners()
+	}
+	if len(bv.Sphere) == 4 {
+		r := bv.Sphere[3]
+		box := geometry.OrientedBoundingBox{
+			Center: geometry.Coordinate{X: bv.Sphere[0], Y: bv.Sphere[1], Z: bv.Sphere[2]},
+			HalfAxes: [3]geometry.Coordinate{
+				{X: r}, {Y: r}, {Z: r},
+			},
+		}
+		return box.GetCorners()
+	}
+	if len(bv.Region) == 6 {
+		return geometry.GetRegionCorners(bv.Region)
+	}
+	return nil
+}
+
+// Returns a bounding volume of the same type of the given one enclosing it and all the other given volumes
+func EncloseBoundingVolumes(volume BoundingVolume, others []BoundingVolume) BoundingVolume {
+	switch {
+	case volume.Box != nil:
+		corners := volume.GetCorners()
+		for _, other := range others {
+			corners = append(corners, other.GetCorners()...)
+		}
+		return BoundingVolume{Box: geometry.NewOrientedBoundingBoxFromPoints(corners).GetAsArray()}
+
+	case volume.Sphere != nil:
+		sphere := &geometry.BoundingSphere{
+			Center: geometry.Coordinate{X: volume.Sphere[0], Y: volume.Sphere[1], Z: volume.Sphere[2]},
+			Radius: volume.Sphere[3],
+		}
+		for _, other := range others {
+			if len(other.Sphere) == 4 {
+				sphere = sphere.Enclose(&geometry.BoundingSphere{
+					Center: geometry.Coordinate{X: other.Sphere[0], Y: other.Sphere[1], Z: other.Sphere[2]},
+					Radius: other.Sphere[3],
+				})
+			} else {
+				sphere = sphere.Enclose(geometry.NewBoundingSphereFromPoints(sphere.Center, other.GetCorners()))
+			}
+		}
+		return BoundingVolume{Sphere: sphere.GetAsArray()}
+
+	default:
+		region := append([]float64{}, volume.Region...)
+		for _, other := range others {
+			otherRegion := other.Region
+			if otherRegion == nil {
+				otherRegion = getCornersRegion(other.GetCorners())
+			}
+
+			region[0] = math.Min(otherRegion[0], region[0])
+			region[1] = math.Min(otherRegion[1], region[1])
+			region[2] = math.Max(otherRegion[2], region[2])
+			region[3] = math.Max(otherRegion[3], region[3])
+			region[4] = math.Min(otherRegion[4], region[4])
+			region[5] = math.Max(otherRegion[5], region[5])
+		}
+		return BoundingVolume{Region: region}
+	}
+}
+
+// Returns the region bounding the given ECEF points
+func getCornersRegion(corners []geometry.Coordinate) []float64 {
+	region := []float64{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1), math.Inf(1), math.Inf(-1)}
+	for _, corner := range corners {
+		lon, lat, height := geometry.EcefToGeodetic(corner)
+		region[0] = math.Min(lon, region[0])
+		region[1] = math.Min(lat, region[1])
+		region[2] = math.Max(lon, region[2])
+		region[3] = math.Max(lat, region[3])
+		region[4] = math.Min(height, region[4])
+		region[5] = math.Max(height, region[5])
+	}
+	return region
+}
@@ -1,7 +1,10 @@
 package io
 
-import "sync"
+import (
+	"context"
+	"sync"
+)
 
 type Consumer interface {
-	Consume(workchan chan *WorkUnit, errchan chan error, waitGroup *sync.WaitGroup)
+	Consume(ctx context.Context, workchan chan *WorkUnit, errchan chan error, waitGroup *sync.WaitGroup)
 }
@@ -0,0 +1,137 @@
+package io
+
+// Subset of the glTF 2.0 json schema needed to write point cloud tiles
+
+const (
+	gltfModePoints = 0
+
+	gltfComponentTypeUnsignedByte  = 5121
+	gltfComponentTypeUnsignedShort = 5123
+	gltfComponentTypeFloat         = 5126
+
+	gltfTargetArrayBuffer = 34962
+
+	gltfChunkTypeJson = 0x4E4F534A
+	gltfChunkTypeBin  = 0x004E4942
+)
+
+const (
+	extMeshoptCompression   = "EXT_meshopt_compression"
+	extStructuralMetadata   = "EXT_structural_metadata"
+	khrDracoMeshCompression = "KHR_draco_mesh_compression"
+	gltfMetadataClassName   = "point"
+)
+
+type GltfAsset struct {
+	Version   string `json:"version"`
+	Generator string `json:"generator,omitempty"`
+}
+
+type GltfScene struct {
+	Nodes []int `json:"nodes"`
+}
+
+type GltfNode struct {
+	Mesh        int       `json:"mesh"`
+	Translation []float64 `json:"translation,omitempty"`
+}
+
+type GltfMesh struct {
+	Primitives []GltfPrimitive `json:"primitives"`
+}
+
+type GltfPrimitive struct {
+	Attributes map[string]int         `json:"attributes"`
+	Mode       int                    `json:"mode"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+type GltfAccessor struct {
+	BufferView    *int      `json:"bufferView,omitempty"`
+	ByteOffset    int       `json:"byteOffset,omitempty"`
+	ComponentType int       `json:"componentType"`
+	Normalized    bool      `json:"normalized,omitempty"`
+	Count         int       `json:"count"`
+	Type          string    `json:"type"`
+	Min           []float64 `json:"min,omitempty"`
+	Max           []float64 `json:"max,omitempty"`
+}
+
+type GltfBufferView struct {
+	Buffer     int                    `json:"buffer"`
+	ByteOffset int                    `json:"byteOffset,omitempty"`
+	ByteLength int                    `json:"byteLength"`
+	ByteStride int                    `json:"byteStride,omitempty"`
+	Target     int                    `json:"target,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+type GltfBuffer struct {
+	ByteLength int                    `json:"byteLength"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+type Gltf struct {
+	Asset              GltfAsset              `json:"asset"`
+	ExtensionsUsed     []string               `json:"extensionsUsed,omitempty"`
+	ExtensionsRequired []string               `json:"extensionsRequired,omitempty"`
+	Extensions         map[string]interface{} `json:"extensions,omitempty"`
+	Scene              int                    `json:"scene"`
+	Scenes             []GltfScene            `json:"scenes"`
+	Nodes              []GltfNode             `json:"nodes"`
+	Meshes             []GltfMesh             `json:"meshes"`
+	Accessors          []GltfAccessor         `json:"accessors"`
+	BufferViews        []GltfBufferView       `json:"bufferViews"`
+	Buffers            []GltfBuffer           `json:"buffers"`
+}
+
+// EXT_meshopt_compression bufferView extension
+type GltfMeshoptBufferView struct {
+	Buffer     int    `json:"buffer"`
+	ByteOffset int    `json:"byteOffset,omitempty"`
+	ByteLength int    `json:"byteLength"`
+	ByteStride int    `json:"byteStride"`
+	Count      int    `json:"count"`
+	Mode       string `json:"mode"`
+}
+
+// KHR_draco_mesh_compression primitive extension
+type GltfDracoPrimitive struct {
+	BufferView int            `json:"bufferView"`
+	Attributes map[string]int `json:"attributes"`
+}
+
+// EXT_structural_metadata root extension
+type GltfStructuralMetadata struct {
+	Schema             GltfMetadataSchema              `json:"schema"`
+	PropertyAttributes []GltfMetadataPropertyAttribute `json:"propertyAttributes"`
+}
+
+type GltfMetadataSchema struct {
+	Id      string                       `json:"id"`
+	Classes map[string]GltfMetadataClass `json:"classes"`
+}
+
+type GltfMetadataClass struct {
+	Properties map[string]GltfMetadataClassProperty `json:"properties"`
+}
+
+type GltfMetadataClassProperty struct {
+	Type          string `json:"type"`
+	ComponentType string `json:"componentType"`
+}
+
+type GltfMetadataPropertyAttribute struct {
+	Class      string                                       `json:"class"`
+	Properties map[string]GltfMetadataPropertyAttributeProp `json:"properties"`
+}
+
+type GltfMetadataPropertyAttributeProp struct {
+	Attribute string   `json:"attribute"`
+	Offset    *float64 `json:"offset,omitempty"`
+}
+
+// EXT_structural_metadata primitive extension
+type GltfPrimitiveStructuralMetadata struct {
+	PropertyAttributes []int `json:"propertyAttributes"`
+}
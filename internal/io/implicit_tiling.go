@@ -0,0 +1,415 @@
+package io
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path"
+	"strconv"
+	"sync"
+
+	"github.com/ecopia-map/cesium_tiler/internal/converters"
+	"github.com/ecopia-map/cesium_tiler/internal/octree"
+	"github.com/ecopia-map/cesium_tiler/internal/tiler"
+	"github.com/ecopia-map/cesium_tiler/tools"
+)
+
+// Implicit tiling writes the whole GridTree as a 3D Tiles 1.1 implicit octree: a single tileset.json with an
+// implicitTiling root, binary .subtree files with the tile and content availability and one content file per tile
+// located by its {level}/{x}/{y}/{z} coordinates.
+//
+// The implicit subdivision of a region halves longitude, latitude and height, while the GridTree halves the EPSG:3395
+// bounding box of the nodes, whose y axis is not linear in latitude. The exact region and geometric error of every
+// tile are therefore stored in the subtrees as tile metadata with the TILE_BOUNDING_REGION and TILE_GEOMETRIC_ERROR
+// semantics, which take precedence over the implicit ones. With box bounding volumes the tight box of every tile is
+// stored with the TILE_BOUNDING_BOX semantic instead.
+
+const (
+	implicitSubtreesFolder   = "subtrees"
+	implicitTileMetadataName = "tile"
+)
+
+// A tree node placed at its coordinates in the implicit octree
+type ImplicitTile struct {
+	Node     octree.INode
+	Level    int
+	X        int
+	Y        int
+	Z        int
+	children [8]*ImplicitTile
+}
+
+// Builds the implicit octree for the given tree root. The children merged by MergeSmallChildren are split back
+// into their original octants so that every tile matches its octree coordinates
+func NewImplicitTileTree(root octree.INode) *ImplicitTile {
+	return newImplicitTile(root, 0, 0, 0, 0)
+}
+
+func newImplicitTile(node octree.INode, level, x, y, z int) *ImplicitTile {
+	tile := &ImplicitTile{
+		Node:  node,
+		Level: level,
+		X:     x,
+		Y:     y,
+		Z:     z,
+	}
+
+	for i, child := range node.GetOctreeChildren() {
+		if child != nil {
+			tile.children[i] = newImplicitTile(child, level+1, 2*x+(i&1), 2*y+(i>>1)&1, 2*z+(i>>2)&1)
+		}
+	}
+
+	return tile
+}
+
+// Returns the number of levels of the implicit octree below and including this tile
+func (t *ImplicitTile) Depth() int {
+	depth := 0
+	for _, child := range t.children {
+		if child != nil {
+			if childDepth := child.Depth(); childDepth > depth {
+				depth = childDepth
+			}
+		}
+	}
+	return depth + 1
+}
+
+// Returns true if the tile has a content file
+func (t *ImplicitTile) HasContent() bool {
+	return t.Node.NumberOfPoints() > 0
+}
+
+// Returns the folder of the tile content relative to the tileset root
+func (t *ImplicitTile) ContentPath() string {
+	return path.Join(strconv.Itoa(t.Level), strconv.Itoa(t.X), strconv.Itoa(t.Y), strconv.Itoa(t.Z))
+}
+
+type ImplicitProducer struct {
+	basePath string
+	options  *tiler.TilerOptions
+}
+
+func NewImplicitProducer(basepath string, subfolder string, options *tiler.TilerOptions) *ImplicitProducer {
+	return &ImplicitProducer{
+		basePath: path.Join(basepath, subfolder),
+		options:  options,
+	}
+}
+
+// Parses the implicit tiles and submits WorkUnits the the provided workchannel. Should be called only on the root tile.
+// Closes the channel when all work is submitted or the context is canceled.
+func (p *ImplicitProducer) Produce(ctx context.Context, work chan *WorkUnit, wg *sync.WaitGroup, tile *ImplicitTile) {
+	p.produce(ctx, tile, work)
+	close(work)
+	wg.Done()
+}
+
+// Submits the WorkUnits of the given tile and its descendants. Returns false if the context has been canceled
+func (p *ImplicitProducer) produce(ctx context.Context, tile *ImplicitTile, work chan *WorkUnit) bool {
+	if tile.HasContent() {
+		workUnit := &WorkUnit{
+			Node:     tile.Node,
+			BasePath: path.Join(p.basePath, tile.ContentPath()),
+			Opts:     p.options,
+			Implicit: true,
+		}
+		if !submitWork(ctx, work, workUnit) {
+			return false
+		}
+	}
+
+	for _, child := range tile.children {
+		if child != nil && !p.produce(ctx, child, work) {
+			return false
+		}
+	}
+
+	return true
+}
+
+type ImplicitTilesetWriter struct {
+	coordinateConverter converters.CoordinateConverter
+	refineMode          tiler.RefineMode
+	outputFormat        tiler.OutputFormat
+	subtreeLevels       int
+	boundingVolume      tiler.BoundingVolumeType
+	bytesWritten        int64 // bytes of the tileset.json and subtree files written
+}
+
+// Instances a new writer of implicit tilesets. The bounding volumes can either be regions or boxes, as implicit tiling
+// does not support spheres
+func NewImplicitTilesetWriter(coordinateConverter converters.CoordinateConverter, refineMode tiler.RefineMode, outputFormat tiler.OutputFormat, subtreeLevels int, boundingVolume tiler.BoundingVolumeType) *ImplicitTilesetWriter {
+	return &ImplicitTilesetWriter{
+		coordinateConverter: coordinateConverter,
+		refineMode:          refineMode,
+		outputFormat:        outputFormat,
+		subtreeLevels:       subtreeLevels,
+		boundingVolume:      boundingVolume,
+	}
+}
+
+// Writes the tileset.json file and all the subtree files of the given implicit octree in the given folder
+func (w *ImplicitTilesetWriter) Write(basePath string, root *ImplicitTile) error {
+	// Create base folder if it does not exist
+	err := tools.CreateDirectoryIfDoesNotExist(basePath)
+	if err != nil {
+		return err
+	}
+
+	jsonData, err := w.generateTilesetJson(root)
+	if err != nil {
+		return err
+	}
+
+	err = ioutil.WriteFile(path.Join(basePath, "tileset.json"), jsonData, 0666)
+	if err != nil {
+		return err
+	}
+	w.bytesWritten += int64(len(jsonData))
+
+	return w.writeSubtree(basePath, root)
+}
+
+// Returns the number of bytes of the files written by the writer
+func (w *ImplicitTilesetWriter) BytesWritten() int64 {
+	return w.bytesWritten
+}
+
+func (w *ImplicitTilesetWriter) generateTilesetJson(root *ImplicitTile) ([]byte, error) {
+	boundingVolume, err := newNodeBoundingVolume(root.Node, w.coordinateConverter, w.boundingVolume, w.refineMode)
+	if err != nil {
+		return nil, err
+	}
+
+	tileset := Tileset{
+		Asset:          Asset{Version: "1.1"},
+		Schema:         w.generateSchema(),
+		GeometricError: root.Node.ComputeGeometricError(),
+		Root: Root{
+			Content:        Content{"{level}/{x}/{y}/{z}/" + w.outputFormat.ContentFileName()},
+			BoundingVolume: boundingVolume,
+			GeometricError: root.Node.ComputeGeometricError(),
+			Refine:         w.refineMode.String(),
+			ImplicitTiling: &ImplicitTiling{
+				SubdivisionScheme: "OCTREE",
+				SubtreeLevels:     w.subtreeLevels,
+				AvailableLevels:   root.Depth(),
+				Subtrees:          Subtrees{implicitSubtreesFolder + "/{level}/{x}/{y}/{z}.subtree"},
+			},
+		},
+	}
+
+	// Outputting a formatted json file
+	return json.MarshalIndent(tileset, "", "\t")
+}
+
+func (w *ImplicitTilesetWriter) generateSchema() *Schema {
+	boundingVolumeProperty := SchemaClassProperty{Type: "SCALAR", ComponentType: "FLOAT64", Array: true, Count: 6, Semantic: "TILE_BOUNDING_REGION"}
+	if w.boundingVolume == tiler.BoundingVolumeBox {
+		boundingVolumeProperty = SchemaClassProperty{Type: "SCALAR", ComponentType: "FLOAT64", Array: true, Count: 12, Semantic: "TILE_BOUNDING_BOX"}
+	}
+
+	return &Schema{
+		Id: "cesium_tiler",
+		Classes: map[string]SchemaClass{
+			implicitTileMetadataName: {
+				Properties: map[string]SchemaClassProperty{
+					w.boundingVolumePropertyName(): boundingVolumeProperty,
+					"geometricError":               {Type: "SCALAR", ComponentType: "FLOAT64", Semantic: "TILE_GEOMETRIC_ERROR"},
+				},
+			},
+		},
+	}
+}
+
+// Returns the name of the tile metadata property storing the bounding volume of the tiles
+func (w *ImplicitTilesetWriter) boundingVolumePropertyName() string {
+	if w.boundingVolume == tiler.BoundingVolumeBox {
+		return "boundingBox"
+	}
+	return "boundingRegion"
+}
+
+// Writes the subtree rooted at the given tile and recursively all its child subtrees
+func (w *ImplicitTilesetWriter) writeSubtree(basePath string, subtreeRoot *ImplicitTile) error {
+	numTiles := (int(math.Pow(8, float64(w.subtreeLevels))) - 1) / 7
+	numChildSubtrees := int(math.Pow(8, float64(w.subtreeLevels)))
+
+	tileAvailability := make([]byte, (numTiles+7)/8)
+	contentAvailability := make([]byte, (numTiles+7)/8)
+	childSubtreeAvailability := make([]byte, (numChildSubtrees+7)/8)
+	tiles := make([]*ImplicitTile, numTiles)
+	childSubtrees := make([]*ImplicitTile, 0)
+
+	var visit func(tile *ImplicitTile, level, x, y, z int)
+	visit = func(tile *ImplicitTile, level, x, y, z int) {
+		if level == w.subtreeLevels {
+			setBit(childSubtreeAvailability, mortonIndex(x, y, z))
+			childSubtrees = append(childSubtrees, tile)
+			return
+		}
+
+		index := (int(math.Pow(8, float64(level)))-1)/7 + mortonIndex(x, y, z)
+		tiles[index] = tile
+		setBit(tileAvailability, index)
+		if tile.HasContent() {
+			setBit(contentAvailability, index)
+		}
+
+		for i, child := range tile.children {
+			if child != nil {
+				visit(child, level+1, 2*x+(i&1), 2*y+(i>>1)&1, 2*z+(i>>2)&1)
+			}
+		}
+	}
+	visit(subtreeRoot, 0, 0, 0, 0)
+
+	subtree, binChunk, err := w.generateSubtree(tiles, tileAvailability, contentAvailability, childSubtreeAvailability)
+	if err != nil {
+		return err
+	}
+
+	outputByte, err := generateSubtreeByteArray(subtree, binChunk)
+	if err != nil {
+		return err
+	}
+
+	subtreeFolder := path.Join(basePath, implicitSubtreesFolder, strconv.Itoa(subtreeRoot.Level), strconv.Itoa(subtreeRoot.X), strconv.Itoa(subtreeRoot.Y))
+	err = tools.CreateDirectoryIfDoesNotExist(subtreeFolder)
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(path.Join(subtreeFolder, fmt.Sprintf("%d.subtree", subtreeRoot.Z)), outputByte, 0666)
+	if err != nil {
+		return err
+	}
+	w.bytesWritten += int64(len(outputByte))
+
+	for _, child := range childSubtrees {
+		if err := w.writeSubtree(basePath, child); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (w *ImplicitTilesetWriter) generateSubtree(tiles []*ImplicitTile, tileAvailability, contentAvailability, childSubtreeAvailability []byte) (*Subtree, []byte, error) {
+	subtree := Subtree{}
+	binChunk := make([]byte, 0)
+
+	// every buffer view is aligned to 8 bytes
+	addBufferView := func(data []byte) int {
+		subtree.BufferViews = append(subtree.BufferViews, SubtreeBufferView{
+			Buffer:     0,
+			ByteOffset: len(binChunk),
+			ByteLength: len(data),
+		})
+		binChunk = padByteArray(append(binChunk, data...), 8, 0)
+		return len(subtree.BufferViews) - 1
+	}
+	addAvailability := func(bitstream []byte) SubtreeAvailability {
+		count := countBits(bitstream)
+		if count == 0 {
+			constant := 0
+			return SubtreeAvailability{Constant: &constant}
+		}
+		bufferView := addBufferView(bitstream)
+		return SubtreeAvailability{Bitstream: &bufferView, AvailableCount: &count}
+	}
+
+	subtree.TileAvailability = addAvailability(tileAvailability)
+	subtree.ContentAvailability = []SubtreeAvailability{addAvailability(contentAvailability)}
+	subtree.ChildSubtreeAvailability = addAvailability(childSubtreeAvailability)
+
+	// tile metadata values are stored for the available tiles following the availability order
+	boundingVolumes := make([]byte, 0)
+	geometricErrors := make([]byte, 0)
+	count := 0
+	for _, tile := range tiles {
+		if tile == nil {
+			continue
+		}
+		boundingVolume, err := newNodeBoundingVolume(tile.Node, w.coordinateConverter, w.boundingVolume, w.refineMode)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, v := range append(boundingVolume.Region, boundingVolume.Box...) {
+			boundingVolumes = appendFloat64(boundingVolumes, v)
+		}
+		geometricErrors = appendFloat64(geometricErrors, tile.Node.ComputeGeometricError())
+		count++
+	}
+
+	tileMetadata := 0
+	subtree.PropertyTables = []SubtreePropertyTable{
+		{
+			Class: implicitTileMetadataName,
+			Count: count,
+			Properties: map[string]SubtreePropertyTableProperty{
+				w.boundingVolumePropertyName(): {Values: addBufferView(boundingVolumes)},
+				"geometricError":               {Values: addBufferView(geometricErrors)},
+			},
+		},
+	}
+	subtree.TileMetadata = &tileMetadata
+	subtree.Buffers = []SubtreeBuffer{{ByteLength: len(binChunk)}}
+
+	return &subtree, binChunk, nil
+}
+
+// Generates the subtree binary container: 24 bytes header, json chunk padded with spaces and binary chunk padded with zeros
+func generateSubtreeByteArray(subtree *Subtree, binChunk []byte) ([]byte, error) {
+	jsonChunk, err := json.Marshal(subtree)
+	if err != nil {
+		return nil, err
+	}
+	jsonChunk = padByteArray(jsonChunk, 8, ' ')
+	binChunk = padByteArray(binChunk, 8, 0)
+
+	outputByte := make([]byte, 24, 24+len(jsonChunk)+len(binChunk))
+	copy(outputByte[0:4], "subt")                                           // magic
+	binary.LittleEndian.PutUint32(outputByte[4:8], 1)                       // version number
+	binary.LittleEndian.PutUint64(outputByte[8:16], uint64(len(jsonChunk))) // json byte length
+	binary.LittleEndian.PutUint64(outputByte[16:24], uint64(len(binChunk))) // binary byte length
+	outputByte = append(outputByte, jsonChunk...)
+	outputByte = append(outputByte, binChunk...)
+
+	return outputByte, nil
+}
+
+// Returns the morton index of the given local coordinates, interleaving the x, y and z bits
+func mortonIndex(x, y, z int) int {
+	index := 0
+	for i := uint(0); i < 21; i++ {
+		index |= (x >> i & 1) << (3 * i)
+		index |= (y >> i & 1) << (3*i + 1)
+		index |= (z >> i & 1) << (3*i + 2)
+	}
+	return index
+}
+
+func setBit(bitstream []byte, index int) {
+	bitstream[index/8] |= 1 << uint(index%8)
+}
+
+func countBits(bitstream []byte) int {
+	count := 0
+	for _, b := range bitstream {
+		for ; b != 0; b &= b - 1 {
+			count++
+		}
+	}
+	return count
+}
+
+func appendFloat64(data []byte, v float64) []byte {
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, math.Float64bits(v))
+	return append(data, b...)
+}
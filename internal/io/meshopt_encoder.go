@@ -0,0 +1,175 @@
+package io
+
+// Encoder for the meshoptimizer vertex buffer codec (version 0) used by the ATTRIBUTES mode of EXT_meshopt_compression.
+// Vertices are processed in blocks; for each byte of the vertex the deltas from the previous vertex are zigzag encoded
+// and stored in groups of 16 values using 0, 2, 4 or 8 bits per value.
+
+const (
+	meshoptVertexHeader         = 0xa0
+	meshoptVertexBlockSizeBytes = 8192
+	meshoptVertexBlockMaxSize   = 256
+	meshoptByteGroupSize        = 16
+	meshoptTailMaxSize          = 32
+)
+
+// Encodes vertexCount vertices of vertexSize bytes each. vertexSize must be a multiple of 4 and not greater than 256
+func encodeMeshoptVertexBuffer(vertices []byte, vertexCount int, vertexSize int) []byte {
+	data := make([]byte, 0, len(vertices)/2+meshoptTailMaxSize+1)
+	data = append(data, meshoptVertexHeader)
+
+	firstVertex := make([]byte, vertexSize)
+	if vertexCount > 0 {
+		copy(firstVertex, vertices[:vertexSize])
+	}
+	lastVertex := make([]byte, vertexSize)
+	copy(lastVertex, firstVertex)
+
+	vertexBlockSize := getMeshoptVertexBlockSize(vertexSize)
+	for vertexOffset := 0; vertexOffset < vertexCount; {
+		blockSize := vertexBlockSize
+		if vertexOffset+blockSize > vertexCount {
+			blockSize = vertexCount - vertexOffset
+		}
+		data = encodeMeshoptVertexBlock(data, vertices[vertexOffset*vertexSize:], blockSize, vertexSize, lastVertex)
+		vertexOffset += blockSize
+	}
+
+	// the first vertex is written at the end of the stream padded to 32 bytes
+	if vertexSize < meshoptTailMaxSize {
+		data = append(data, make([]byte, meshoptTailMaxSize-vertexSize)...)
+	}
+	data = append(data, firstVertex...)
+
+	return data
+}
+
+func getMeshoptVertexBlockSize(vertexSize int) int {
+	result := meshoptVertexBlockSizeBytes / vertexSize
+	result &^= meshoptByteGroupSize - 1
+	if result > meshoptVertexBlockMaxSize {
+		return meshoptVertexBlockMaxSize
+	}
+	return result
+}
+
+func encodeMeshoptVertexBlock(data []byte, vertices []byte, vertexCount int, vertexSize int, lastVertex []byte) []byte {
+	var buffer [meshoptVertexBlockMaxSize]byte
+	bufferSize := (vertexCount + meshoptByteGroupSize - 1) &^ (meshoptByteGroupSize - 1)
+
+	for k := 0; k < vertexSize; k++ {
+		p := lastVertex[k]
+		for i := 0; i < vertexCount; i++ {
+			v := vertices[i*vertexSize+k]
+			buffer[i] = zigzag8(v - p)
+			p = v
+		}
+		for i := vertexCount; i < bufferSize; i++ {
+			buffer[i] = 0
+		}
+		data = encodeMeshoptBytes(data, buffer[:bufferSize])
+	}
+
+	copy(lastVertex, vertices[(vertexCount-1)*vertexSize:vertexCount*vertexSize])
+
+	return data
+}
+
+func zigzag8(v byte) byte {
+	return byte(int8(v)>>7) ^ (v << 1)
+}
+
+func encodeMeshoptBytes(data []byte, buffer []byte) []byte {
+	// 2 header bits for each group, rounded to a full byte
+	headerOffset := len(data)
+	headerSize := (len(buffer)/meshoptByteGroupSize + 3) / 4
+	data = append(data, make([]byte, headerSize)...)
+
+	for i := 0; i < len(buffer); i += meshoptByteGroupSize {
+		group := buffer[i : i+meshoptByteGroupSize]
+
+		bestBits := 8
+		bestSize := measureMeshoptBytesGroup(group, 8)
+		for bits := 1; bits < 8; bits *= 2 {
+			size := measureMeshoptBytesGroup(group, bits)
+			if size < bestSize {
+				bestBits = bits
+				bestSize = size
+			}
+		}
+
+		var bitsLog2 byte
+		switch bestBits {
+		case 1:
+			bitsLog2 = 0
+		case 2:
+			bitsLog2 = 1
+		case 4:
+			bitsLog2 = 2
+		default:
+			bitsLog2 = 3
+		}
+
+		groupIndex := i / meshoptByteGroupSize
+		data[headerOffset+groupIndex/4] |= bitsLog2 << uint((groupIndex%4)*2)
+		data = encodeMeshoptBytesGroup(data, group, bestBits)
+	}
+
+	return data
+}
+
+// Returns the number of bytes needed to encode the group with the given number of bits
+func measureMeshoptBytesGroup(group []byte, bits int) int {
+	if bits == 1 {
+		for _, v := range group {
+			if v != 0 {
+				// a non-zero group cannot be encoded with 0 bits, return a size larger than the 8 bits encoding
+				return meshoptByteGroupSize + 1
+			}
+		}
+		return 0
+	}
+	if bits == 8 {
+		return meshoptByteGroupSize
+	}
+
+	result := meshoptByteGroupSize * bits / 8
+	sentinel := byte(1<<uint(bits)) - 1
+	for _, v := range group {
+		if v >= sentinel {
+			result++
+		}
+	}
+	return result
+}
+
+func encodeMeshoptBytesGroup(data []byte, group []byte, bits int) []byte {
+	if bits == 1 {
+		return data
+	}
+	if bits == 8 {
+		return append(data, group...)
+	}
+
+	// fixed portion: bits for each value, out of range values are replaced by a sentinel
+	// and stored as full bytes after the fixed portion
+	valuesPerByte := 8 / bits
+	sentinel := byte(1<<uint(bits)) - 1
+	for i := 0; i < meshoptByteGroupSize; i += valuesPerByte {
+		var b byte
+		for k := 0; k < valuesPerByte; k++ {
+			enc := group[i+k]
+			if enc >= sentinel {
+				enc = sentinel
+			}
+			b = b<<uint(bits) | enc
+		}
+		data = append(data, b)
+	}
+	for _, v := range group {
+		if v >= sentinel {
+			data = append(data, v)
+		}
+	}
+
+	return data
+}
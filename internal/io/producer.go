@@ -1,10 +1,23 @@
 package io
 
 import (
-	"github.com/ecopia-map/cesium_tiler/internal/octree"
+	"context"
 	"sync"
+
+	"github.com/ecopia-map/cesium_tiler/internal/octree"
 )
 
 type Producer interface {
-	Produce(work chan *WorkUnit, wg *sync.WaitGroup, node octree.INode)
-}
\ No newline at end of file
+	Produce(ctx context.Context, work chan *WorkUnit, wg *sync.WaitGroup, node octree.INode)
+}
+
+// Submits the given WorkUnit to the work channel. Returns false if the context is canceled before the WorkUnit
+// could be submitted
+func submitWork(ctx context.Context, work chan *WorkUnit, workUnit *WorkUnit) bool {
+	select {
+	case work <- workUnit:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -2,6 +2,7 @@ package io
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -10,16 +11,16 @@ import (
 	"os"
 	"os/exec"
 	"path"
-	"sort"
 	"strconv"
 	"strings"
 	"sync"
 
 	"github.com/ecopia-map/cesium_tiler/internal/converters"
-	"github.com/ecopia-map/cesium_tiler/internal/data"
+	"github.com/ecopia-map/cesium_tiler/internal/draco"
 	"github.com/ecopia-map/cesium_tiler/internal/geometry"
-	"github.com/ecopia-map/cesium_tiler/internal/octree/grid_tree"
+	"github.com/ecopia-map/cesium_tiler/internal/octree"
 	"github.com/ecopia-map/cesium_tiler/internal/ply"
+	"github.com/ecopia-map/cesium_tiler/internal/progress"
 	"github.com/ecopia-map/cesium_tiler/internal/tiler"
 	"github.com/ecopia-map/cesium_tiler/tools"
 	"github.com/golang/glog"
@@ -30,30 +31,56 @@ type StandardConsumer struct {
 	refineMode          tiler.RefineMode
 	draco               bool
 	dracoEncoderPath    string
+	dracoOptions        draco.EncoderOptions
+	outputFormat        tiler.OutputFormat
+	meshopt             bool
+	boundingVolume      tiler.BoundingVolumeType
+	batchAttributes     []tiler.BatchAttribute
+	progress            *progress.Task // stage receiving the written tiles, nil if not tracked
+	bytesWritten        int64          // bytes of the files written for the current work unit
 }
 
-func NewStandardConsumer(coordinateConverter converters.CoordinateConverter, refineMode tiler.RefineMode, draco bool, dracoEncoderPath string) *StandardConsumer {
+func NewStandardConsumer(coordinateConverter converters.CoordinateConverter, refineMode tiler.RefineMode, useDraco bool, dracoEncoderPath string, dracoMethod tiler.DracoMethod, dracoQuantizationBits int, outputFormat tiler.OutputFormat, meshopt bool, boundingVolume tiler.BoundingVolumeType, batchAttributes []tiler.BatchAttribute) *StandardConsumer {
+	dracoOptions := draco.EncoderOptions{
+		Method:           draco.KdTreeEncoding,
+		QuantizationBits: dracoQuantizationBits,
+	}
+	if dracoMethod == tiler.DracoMethodSequential {
+		dracoOptions.Method = draco.SequentialEncoding
+	}
+
 	return &StandardConsumer{
 		coordinateConverter: coordinateConverter,
 		refineMode:          refineMode,
-		draco:               draco,
+		draco:               useDraco,
 		dracoEncoderPath:    dracoEncoderPath,
+		dracoOptions:        dracoOptions,
+		outputFormat:        outputFormat,
+		meshopt:             meshopt,
+		boundingVolume:      boundingVolume,
+		batchAttributes:     batchAttributes,
 	}
 }
 
+// Sets the stage receiving the level, the points and the bytes of the tiles written by the consumer
+func (c *StandardConsumer) SetProgress(task *progress.Task) {
+	c.progress = task
+}
+
 // struct used to store data in an intermediate format
 type intermediateData struct {
-	coords          []float64
-	colors          []uint8
-	intensities     []uint8
-	classifications []uint8
-	numPoints       int
+	coords     []float64
+	colors     []uint8
+	attributes []*batchAttributeValues
+	numPoints  int
 }
 
-// Continually consumes WorkUnits submitted to a work channel producing corresponding content.pnts files and tileset.json files
-// continues working until work channel is closed or if an error is raised. In this last case submits the error to an error
-// channel before quitting
-func (c *StandardConsumer) Consume(workchan chan *WorkUnit, errchan chan error, waitGroup *sync.WaitGroup) {
+// Continually consumes WorkUnits submitted to a work channel producing corresponding content.pnts (or content.glb) files and tileset.json files
+// continues working until work channel is closed. If an error is raised it is submitted to the error channel, which
+// must be able to buffer an error per consumer, and the following WorkUnits are discarded, as they are once the
+// context is canceled, so that the producer is never blocked
+func (c *StandardConsumer) Consume(ctx context.Context, workchan chan *WorkUnit, errchan chan error, waitGroup *sync.WaitGroup) {
+	failed := false
 	for {
 		// get work from channel
 		work, ok := <-workchan
@@ -63,13 +90,19 @@ func (c *StandardConsumer) Consume(workchan chan *WorkUnit, errchan chan error,
 		}
 
 		// do work
-		err := c.doWork(work)
+		var err error
+		if !failed && ctx.Err() == nil {
+			err = c.doWork(work)
+		}
+		if work.Pending != nil {
+			work.Pending.Done()
+		}
 
-		// if there were errors during work send in error channel and quit
+		// if there were errors during work send in error channel and discard the remaining work
 		if err != nil {
 			errchan <- err
 			fmt.Println("exception in c worker")
-			break
+			failed = true
 		}
 	}
 
@@ -77,10 +110,25 @@ func (c *StandardConsumer) Consume(workchan chan *WorkUnit, errchan chan error,
 	waitGroup.Done()
 }
 
-// Takes a workunit and writes the corresponding content.pnts and tileset.json files
+// Takes a workunit and writes the corresponding content.pnts (or content.glb) and tileset.json files
 func (c *StandardConsumer) doWork(workUnit *WorkUnit) error {
-	// writes the content.pnts file
-	if c.draco {
+	c.bytesWritten = 0
+
+	// boxes and spheres are computed from the points, the node one is cached for the tileset of its parent
+	// as the points of the node may be released once it is written
+	if c.boundingVolume == tiler.BoundingVolumeBox || c.boundingVolume == tiler.BoundingVolumeSphere {
+		if _, err := newNodeBoundingVolume(workUnit.Node, c.coordinateConverter, c.boundingVolume, c.refineMode); err != nil {
+			return err
+		}
+	}
+
+	// writes the content.pnts or content.glb file
+	if c.outputFormat == tiler.OutputFormatGlb {
+		err := c.writeBinaryGlbFile(*workUnit)
+		if err != nil {
+			return err
+		}
+	} else if c.draco {
 		err := c.writeBinaryPntsFileWithDraco(*workUnit)
 		if err != nil {
 			return err
@@ -92,25 +140,45 @@ func (c *StandardConsumer) doWork(workUnit *WorkUnit) error {
 		}
 	}
 
-	if !workUnit.Node.IsLeaf() || workUnit.Node.IsRoot() {
+	if !workUnit.Implicit && (!workUnit.Node.IsLeaf() || workUnit.Node.IsRoot()) {
 		// if the node has children also writes the tileset.json file
 		err := c.writeTilesetJsonFile(*workUnit)
 		if err != nil {
 			return err
 		}
 	}
+
+	c.progress.AddTile(nodeLevel(workUnit.Node), int64(workUnit.Node.NumberOfPoints()), c.bytesWritten)
+	return nil
+}
+
+// Writes a file of the current work unit, counting its bytes
+func (c *StandardConsumer) writeFile(filePath string, content []byte, perm os.FileMode) error {
+	if err := ioutil.WriteFile(filePath, content, perm); err != nil {
+		return err
+	}
+	c.bytesWritten += int64(len(content))
 	return nil
 }
 
+// Returns the depth of the node in its tree, the root node being at level 0
+func nodeLevel(node octree.INode) int {
+	level := 0
+	for parent := node.GetParent(); parent != nil; parent = parent.GetParent() {
+		level++
+	}
+	return level
+}
+
 func (c *StandardConsumer) invokeDracoEncoder(
-	programLocation, plyInputFileLocation, outputFileLocation string, compressionLevel int,
+	programLocation, plyInputFileLocation, outputFileLocation string, compressionLevel int, quantizationBits int,
 ) error {
 	//startTime := time.Now()
 	cmdParams := []string{
 		"-point_cloud",
 		"-i", plyInputFileLocation,
 		"-o", outputFileLocation,
-		"-qp", strconv.Itoa(11),
+		"-qp", strconv.Itoa(quantizationBits),
 		"-cl", strconv.Itoa(compressionLevel),
 	}
 
@@ -150,12 +218,93 @@ func (c *StandardConsumer) writeBinaryPntsFileWithDraco(workUnit WorkUnit) error
 	// Normalizing coordinates relative to average
 	c.subtractXYZFromIntermediateDataCoords(intermediatePointData, averageXYZ)
 
+	// the points may be reordered by draco, so the batch attributes are compressed together with them
+	dracoContent, err := c.generateDracoContent(parentFolder, intermediatePointData)
+	if err != nil {
+		return err
+	}
+
+	// Feature table
+	featureTableStr := c.generateFeatureTableJsonContentWithDraco(
+		averageXYZ[0], averageXYZ[1], averageXYZ[2], intermediatePointData.numPoints, 0, len(dracoContent),
+	)
+	featureTableLen := len(featureTableStr)
+
+	// Batch table, it has no binary body as its properties are stored in the draco buffer
+	batchTableStr := ""
+	if len(intermediatePointData.attributes) > 0 {
+		batchTableStr = c.generateBatchTableJsonContentWithDraco(intermediatePointData.attributes, 28+featureTableLen+len(dracoContent), 0)
+	}
+
+	outputByte := c.generatePntsByteArrayWithDraco([]byte(featureTableStr), featureTableLen, []byte(batchTableStr), len(batchTableStr), dracoContent, len(dracoContent))
+
+	//fmt.Println("generate from generatePntsByteArrayWithDraco")
+
+	// Write binary content to file
+	pntsFilePath := path.Join(parentFolder, "content.pnts")
+	err = c.writeFile(pntsFilePath, outputByte, 0777)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Compresses xyz, color and batch attributes of the given points in-process, or xyz and color only if an external
+// draco encoder is configured
+func (c *StandardConsumer) generateDracoContent(parentFolder string, intermediatePointData *intermediateData) ([]byte, error) {
+	if c.dracoEncoderPath == "" {
+		return c.encodeDracoPointCloud(intermediatePointData, true)
+	}
+	return c.generateDracoContentWithEncoder(parentFolder, intermediatePointData)
+}
+
+// Compresses the given points with the in-process draco encoder. Positions and colors get the attribute ids 0 and 1,
+// if withPointAttributes is set the batch attributes are also compressed with the following ids, float ones are
+// quantized as the positions
+func (c *StandardConsumer) encodeDracoPointCloud(intermediatePointData *intermediateData, withPointAttributes bool) ([]byte, error) {
+	pointCloud := draco.NewPointCloud(intermediatePointData.numPoints)
+
+	positions := make([]float32, len(intermediatePointData.coords))
+	for i, coord := range intermediatePointData.coords {
+		positions[i] = float32(coord)
+	}
+	if _, err := pointCloud.AddFloat32Attribute(draco.AttributePosition, 3, positions); err != nil {
+		return nil, err
+	}
+	if _, err := pointCloud.AddUint8Attribute(draco.AttributeColor, 3, true, intermediatePointData.colors); err != nil {
+		return nil, err
+	}
+
+	if withPointAttributes {
+		for _, attribute := range intermediatePointData.attributes {
+			var err error
+			switch attribute.attributeType.gltfComponentType {
+			case gltfComponentTypeUnsignedByte:
+				_, err = pointCloud.AddUint8Attribute(draco.AttributeGeneric, 1, false, attribute.uint8Values())
+			case gltfComponentTypeUnsignedShort:
+				_, err = pointCloud.AddUint16Attribute(draco.AttributeGeneric, 1, false, attribute.uint16Values())
+			default:
+				_, err = pointCloud.AddFloat32Attribute(draco.AttributeGeneric, 1, attribute.float32Values())
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return draco.EncodePointCloud(pointCloud, c.dracoOptions)
+}
+
+// Compresses xyz and color of the given points invoking the external draco encoder
+func (c *StandardConsumer) generateDracoContentWithEncoder(parentFolder string, intermediatePointData *intermediateData) ([]byte, error) {
 	// write ply file
 	plyFileName := "content.ply"
 	plyFilePath := path.Join(parentFolder, plyFileName)
 	if err := c.writePlyFile(plyFilePath, intermediatePointData); err != nil {
 		log.Println("Wrote PLY failed.", err.Error())
-		return err
+		return nil, err
 	}
 
 	// generate Draco Encoder binary
@@ -165,32 +314,15 @@ func (c *StandardConsumer) writeBinaryPntsFileWithDraco(workUnit WorkUnit) error
 	outputFileName := "content.drc"
 	drcFilePath := path.Join(parentFolder, outputFileName)
 	compressionLevel := 7
-	if err := c.invokeDracoEncoder(programLocation, plyInputFileLocation, drcFilePath, compressionLevel); err != nil {
+	if err := c.invokeDracoEncoder(programLocation, plyInputFileLocation, drcFilePath, compressionLevel, c.dracoOptions.QuantizationBits); err != nil {
 		log.Println("invokeDracoEncoder failed.", err.Error())
-		return err
+		return nil, err
 	}
 
 	dracoContent, err := ioutil.ReadFile(drcFilePath)
 	if err != nil {
 		fmt.Printf("file error:%s\n", err.Error())
-	}
-	//fmt.Println("ReadFile success")
-
-	// Feature table
-	featureTableStr := c.generateFeatureTableJsonContentWithDraco(
-		averageXYZ[0], averageXYZ[1], averageXYZ[2], intermediatePointData.numPoints, 0, len(dracoContent),
-	)
-	featureTableLen := len(featureTableStr)
-	outputByte := c.generatePntsByteArrayWithDraco([]byte(featureTableStr), featureTableLen, []byte{}, 0, dracoContent, len(dracoContent))
-
-	//fmt.Println("generate from generatePntsByteArrayWithDraco")
-
-	// Write binary content to file
-	pntsFilePath := path.Join(parentFolder, "content.pnts")
-	err = ioutil.WriteFile(pntsFilePath, outputByte, 0777)
-
-	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Delete temporary ply file
@@ -202,7 +334,7 @@ func (c *StandardConsumer) writeBinaryPntsFileWithDraco(workUnit WorkUnit) error
 		log.Println("delete temporary drc file failed.", err.Error())
 	}
 
-	return nil
+	return dracoContent, nil
 }
 
 func (c *StandardConsumer) writePlyFile(filePath string, intermediatePointData *intermediateData) error {
@@ -251,15 +383,16 @@ func (c *StandardConsumer) writeBinaryPntsFile(workUnit WorkUnit) error {
 	// Feature table
 	featureTableBytes, featureTableLen := c.generateFeatureTable(averageXYZ[0], averageXYZ[1], averageXYZ[2], intermediatePointData.numPoints)
 
-	// Batch table
-	batchTableBytes, batchTableLen := c.generateBatchTable(intermediatePointData.numPoints)
+	// Batch table, its binary body follows the feature table and must be aligned to 8 bytes
+	batchTableStart := 28 + featureTableLen + len(positionBytes) + len(intermediatePointData.colors)
+	batchTableBytes, batchTableLen, batchTableBinary := c.generateBatchTable(intermediatePointData, batchTableStart)
 
 	// Appending binary content to slice
-	outputByte := c.generatePntsByteArray(intermediatePointData, positionBytes, featureTableBytes, featureTableLen, batchTableBytes, batchTableLen)
+	outputByte := c.generatePntsByteArray(intermediatePointData, positionBytes, featureTableBytes, featureTableLen, batchTableBytes, batchTableLen, batchTableBinary)
 
 	// Write binary content to file
 	pntsFilePath := path.Join(parentFolder, "content.pnts")
-	err = ioutil.WriteFile(pntsFilePath, outputByte, 0777)
+	err = c.writeFile(pntsFilePath, outputByte, 0777)
 
 	if err != nil {
 		return err
@@ -268,78 +401,64 @@ func (c *StandardConsumer) writeBinaryPntsFile(workUnit WorkUnit) error {
 	return nil
 }
 
-func (c *StandardConsumer) generateIntermediateDataForPnts(node *grid_tree.GridNode) (*intermediateData, error) {
+func (c *StandardConsumer) generateIntermediateDataForPnts(node octree.INode) (*intermediateData, error) {
 	points := node.GetPoints()
 
 	if c.refineMode == tiler.RefineModeReplace {
-		points = appendParentPoints(node, points)
+		points = node.AppendParentPoints(points)
 	}
 
 	numPoints := len(points)
 	intermediateData := intermediateData{
-		coords:          make([]float64, numPoints*3),
-		colors:          make([]uint8, numPoints*3),
-		intensities:     make([]uint8, numPoints),
-		classifications: make([]uint8, numPoints),
-		numPoints:       numPoints,
-	}
-
-	// Decomposing tile data properties in separate sublists for coords, colors, intensities and classifications
-	for i := 0; i < len(points); i++ {
-		point := points[i]
-		if point == nil {
-			fmt.Println("a")
+		coords:     make([]float64, numPoints*3),
+		colors:     make([]uint8, numPoints*3),
+		attributes: newBatchAttributeValuesList(c.batchAttributes, numPoints),
+		numPoints:  numPoints,
+	}
+
+	// Decomposing tile data properties in separate sublists for coords, colors and batch attributes, the coordinates
+	// are converted in batches
+	coords := make([]geometry.Coordinate, 0, converters.CoordinateBatchSize)
+	for start := 0; start < numPoints; start += converters.CoordinateBatchSize {
+		end := start + converters.CoordinateBatchSize
+		if end > numPoints {
+			end = numPoints
 		}
-		srcCoord := geometry.Coordinate{
-			X: point.X,
-			Y: point.Y,
-			Z: point.Z,
+
+		coords = coords[:0]
+		for _, point := range points[start:end] {
+			coords = append(coords, geometry.Coordinate{
+				X: point.X,
+				Y: point.Y,
+				Z: point.Z,
+			})
 		}
 
 		// ConvertCoordinateSrid coords according to cesium CRS
-		outCrd, err := c.coordinateConverter.ConvertToWGS84Cartesian(srcCoord, node.GetInternalSrid())
-		if err != nil {
+		if err := c.coordinateConverter.ConvertToWGS84CartesianBatch(coords, node.GetInternalSrid()); err != nil {
 			glog.Infoln(err)
 			return nil, err
 		}
 
-		intermediateData.coords[i*3] = outCrd.X
-		intermediateData.coords[i*3+1] = outCrd.Y
-		intermediateData.coords[i*3+2] = outCrd.Z
+		for j, outCrd := range coords {
+			i := start + j
+			point := points[i]
 
-		intermediateData.colors[i*3] = point.R
-		intermediateData.colors[i*3+1] = point.G
-		intermediateData.colors[i*3+2] = point.B
+			intermediateData.coords[i*3] = outCrd.X
+			intermediateData.coords[i*3+1] = outCrd.Y
+			intermediateData.coords[i*3+2] = outCrd.Z
 
-		intermediateData.intensities[i] = point.Intensity
-		intermediateData.classifications[i] = point.Classification
-	}
-
-	return &intermediateData, nil
-}
+			intermediateData.colors[i*3] = point.R
+			intermediateData.colors[i*3+1] = point.G
+			intermediateData.colors[i*3+2] = point.B
 
-func appendParentPoints(node *grid_tree.GridNode, points []*data.Point) []*data.Point {
-	parent := node.GetParent()
-	boundingBox := node.GetBoundingBox()
-	isContained := func(point *data.Point) bool {
-		if point.X >= boundingBox.Xmin && point.X <= boundingBox.Xmax &&
-			point.Y >= boundingBox.Ymin && point.Y <= boundingBox.Ymax &&
-			point.Z >= boundingBox.Zmin && point.Z <= boundingBox.Zmax {
-			return true
-		}
-		return false
-	}
-
-	for parent != nil {
-		for _, point := range parent.GetPoints() {
-			if isContained(point) {
-				points = append(points, point)
+			for _, attribute := range intermediateData.attributes {
+				attribute.values[i] = attribute.pointValue(point)
 			}
 		}
-		parent = parent.GetParent()
 	}
 
-	return points
+	return &intermediateData, nil
 }
 
 func (c *StandardConsumer) generateFeatureTable(avgX float64, avgY float64, avgZ float64, numPoints int) ([]byte, int) {
@@ -348,28 +467,41 @@ func (c *StandardConsumer) generateFeatureTable(avgX float64, avgY float64, avgZ
 	return []byte(featureTableStr), featureTableLen
 }
 
-func (c *StandardConsumer) generateBatchTable(numPoints int) ([]byte, int) {
-	batchTableStr := c.generateBatchTableJsonContent(numPoints, 0)
+// Generates the batch table json and binary body of the batch attributes, the json starts at the given byte offset
+// of the pnts file. No batch table is written if there are no batch attributes
+func (c *StandardConsumer) generateBatchTable(intermediateData *intermediateData, start int) ([]byte, int, []byte) {
+	if len(intermediateData.attributes) == 0 {
+		return []byte{}, 0, []byte{}
+	}
+
+	attributes := sortBatchAttributesForPnts(intermediateData.attributes)
+	byteOffsets := make([]int, len(attributes))
+	batchTableBinary := make([]byte, 0)
+	for i, attribute := range attributes {
+		byteOffsets[i] = len(batchTableBinary)
+		batchTableBinary = append(batchTableBinary, attribute.pntsBytes()...)
+	}
+
+	batchTableStr := c.generateBatchTableJsonContent(attributes, byteOffsets, start, 0)
 	batchTableLen := len(batchTableStr)
-	return []byte(batchTableStr), batchTableLen
+	return []byte(batchTableStr), batchTableLen, batchTableBinary
 }
 
-func (c *StandardConsumer) generatePntsByteArray(intermediateData *intermediateData, positionBytes []byte, featureTableBytes []byte, featureTableLen int, batchTableBytes []byte, batchTableLen int) []byte {
+func (c *StandardConsumer) generatePntsByteArray(intermediateData *intermediateData, positionBytes []byte, featureTableBytes []byte, featureTableLen int, batchTableBytes []byte, batchTableLen int, batchTableBinary []byte) []byte {
 	outputByte := make([]byte, 0)
 	outputByte = append(outputByte, []byte("pnts")...)                 // magic
 	outputByte = append(outputByte, tools.ConvertIntToByteArray(1)...) // version number
-	byteLength := 28 + featureTableLen + len(positionBytes) + len(intermediateData.colors)
+	byteLength := 28 + featureTableLen + len(positionBytes) + len(intermediateData.colors) + batchTableLen + len(batchTableBinary)
 	outputByte = append(outputByte, tools.ConvertIntToByteArray(byteLength)...)
-	outputByte = append(outputByte, tools.ConvertIntToByteArray(featureTableLen)...)                                                         // feature table length
-	outputByte = append(outputByte, tools.ConvertIntToByteArray(len(positionBytes)+len(intermediateData.colors))...)                         // feature table binary length
-	outputByte = append(outputByte, tools.ConvertIntToByteArray(batchTableLen)...)                                                           // batch table length
-	outputByte = append(outputByte, tools.ConvertIntToByteArray(len(intermediateData.intensities)+len(intermediateData.classifications))...) // batch table binary length
-	outputByte = append(outputByte, featureTableBytes...)                                                                                    // feature table
-	outputByte = append(outputByte, positionBytes...)                                                                                        // positions array
-	outputByte = append(outputByte, intermediateData.colors...)                                                                              // colors array
-	outputByte = append(outputByte, batchTableBytes...)                                                                                      // batch table
-	outputByte = append(outputByte, intermediateData.intensities...)                                                                         // intensities array
-	outputByte = append(outputByte, intermediateData.classifications...)
+	outputByte = append(outputByte, tools.ConvertIntToByteArray(featureTableLen)...)                                 // feature table length
+	outputByte = append(outputByte, tools.ConvertIntToByteArray(len(positionBytes)+len(intermediateData.colors))...) // feature table binary length
+	outputByte = append(outputByte, tools.ConvertIntToByteArray(batchTableLen)...)                                   // batch table length
+	outputByte = append(outputByte, tools.ConvertIntToByteArray(len(batchTableBinary))...)                           // batch table binary length
+	outputByte = append(outputByte, featureTableBytes...)                                                            // feature table
+	outputByte = append(outputByte, positionBytes...)                                                                // positions array
+	outputByte = append(outputByte, intermediateData.colors...)                                                      // colors array
+	outputByte = append(outputByte, batchTableBytes...)                                                              // batch table
+	outputByte = append(outputByte, batchTableBinary...)                                                             // batch attributes arrays
 
 	return outputByte
 }
@@ -380,15 +512,15 @@ func (c *StandardConsumer) generatePntsByteArrayWithDraco(
 	outputByte := make([]byte, 0)
 	outputByte = append(outputByte, []byte("pnts")...)                 // magic
 	outputByte = append(outputByte, tools.ConvertIntToByteArray(1)...) // version number
-	byteLength := 28 + featureTableLen + dracoByteLength
+	byteLength := 28 + featureTableLen + dracoByteLength + batchTableLen
 	outputByte = append(outputByte, tools.ConvertIntToByteArray(byteLength)...)
 	outputByte = append(outputByte, tools.ConvertIntToByteArray(featureTableLen)...) // feature table length
 	outputByte = append(outputByte, tools.ConvertIntToByteArray(dracoByteLength)...) // feature table binary length
 	outputByte = append(outputByte, tools.ConvertIntToByteArray(batchTableLen)...)   // batch table length
 	outputByte = append(outputByte, tools.ConvertIntToByteArray(0)...)               // batch table binary length
 	outputByte = append(outputByte, featureTableBytes...)                            // feature table
-	outputByte = append(outputByte, batchTableBytes...)                              // batch table
 	outputByte = append(outputByte, dracoBytes...)                                   // 3DTILES_draco_point_compression
+	outputByte = append(outputByte, batchTableBytes...)                              // batch table
 
 	return outputByte
 }
@@ -448,16 +580,52 @@ func (c *StandardConsumer) generateFeatureTableJsonContent(x, y, z float64, poin
 	return sb
 }
 
-// Generates the json representation of the batch table
-func (c *StandardConsumer) generateBatchTableJsonContent(pointNumber, spaceNumber int) string {
-	sb := ""
-	sb += "{\"INTENSITY\":" + "{\"byteOffset\":" + "0" + ", \"componentType\":\"UNSIGNED_BYTE\", \"type\":\"SCALAR\"},"
-	sb += "\"CLASSIFICATION\":" + "{\"byteOffset\":" + strconv.Itoa(pointNumber) + ", \"componentType\":\"UNSIGNED_BYTE\", \"type\":\"SCALAR\"}}"
+// Generates the json representation of the batch table, padded so that the binary body following it, and so every
+// property, is aligned to 8 bytes given the byte offset at which the json starts
+func (c *StandardConsumer) generateBatchTableJsonContent(attributes []*batchAttributeValues, byteOffsets []int, start int, spaceNumber int) string {
+	sb := "{"
+	for i, attribute := range attributes {
+		if i > 0 {
+			sb += ","
+		}
+		sb += "\"" + attribute.attribute.PropertyName() + "\":" + "{\"byteOffset\":" + strconv.Itoa(byteOffsets[i]) + ", \"componentType\":\"" + attribute.attributeType.pntsComponentType + "\", \"type\":\"SCALAR\"}"
+	}
+	sb += "}"
 	sb += strings.Repeat(" ", spaceNumber)
 	headerByteLength := len([]byte(sb))
-	paddingSize := headerByteLength % 4
+	paddingSize := (start + headerByteLength) % 8
 	if paddingSize != 0 {
-		return c.generateBatchTableJsonContent(pointNumber, 4-paddingSize)
+		return c.generateBatchTableJsonContent(attributes, byteOffsets, start, 8-paddingSize)
+	}
+	return sb
+}
+
+// Generates the json representation of the batch table of a draco compressed pnts tile. The properties are stored in
+// the draco buffer with the attribute ids following the ones of positions and colors, their componentType is the one
+// they are compressed with. The json is padded to 8 bytes given the byte offset at which it starts
+func (c *StandardConsumer) generateBatchTableJsonContentWithDraco(attributes []*batchAttributeValues, start int, spaceNumber int) string {
+	properties := ""
+	dracoProperties := ""
+	for i, attribute := range attributes {
+		if i > 0 {
+			properties += ","
+			dracoProperties += ","
+		}
+		componentType := "FLOAT"
+		switch attribute.attributeType.gltfComponentType {
+		case gltfComponentTypeUnsignedByte:
+			componentType = "UNSIGNED_BYTE"
+		case gltfComponentTypeUnsignedShort:
+			componentType = "UNSIGNED_SHORT"
+		}
+		properties += "\"" + attribute.attribute.PropertyName() + "\":" + "{\"byteOffset\":0, \"componentType\":\"" + componentType + "\", \"type\":\"SCALAR\"}"
+		dracoProperties += "\"" + attribute.attribute.PropertyName() + "\":" + strconv.Itoa(i+2)
+	}
+	sb := "{" + properties + ",\"extensions\":{\"3DTILES_draco_point_compression\":{\"properties\":{" + dracoProperties + "}}}}"
+	sb += strings.Repeat(" ", spaceNumber)
+	paddingSize := (start + len([]byte(sb))) % 8
+	if paddingSize != 0 {
+		return c.generateBatchTableJsonContentWithDraco(attributes, start, 8-paddingSize)
 	}
 	return sb
 }
@@ -481,7 +649,7 @@ func (c *StandardConsumer) writeTilesetJsonFile(workUnit WorkUnit) error {
 	}
 
 	// Writes the tileset.json binary content to the given file
-	err = ioutil.WriteFile(file, jsonData, 0666)
+	err = c.writeFile(file, jsonData, 0666)
 	if err != nil {
 		return err
 	}
@@ -490,7 +658,7 @@ func (c *StandardConsumer) writeTilesetJsonFile(workUnit WorkUnit) error {
 }
 
 // Generates the tileset.json content for the given tree node
-func (c *StandardConsumer) generateTilesetJson(node *grid_tree.GridNode) ([]byte, error) {
+func (c *StandardConsumer) generateTilesetJson(node octree.INode) ([]byte, error) {
 	if !node.IsLeaf() || node.IsRoot() {
 		root, err := c.generateTilesetRoot(node)
 		if err != nil {
@@ -511,9 +679,8 @@ func (c *StandardConsumer) generateTilesetJson(node *grid_tree.GridNode) ([]byte
 	return nil, errors.New("this node is a leaf, cannot create a tileset json for it")
 }
 
-func (c *StandardConsumer) generateTilesetRoot(node *grid_tree.GridNode) (*Root, error) {
-	reg, err := node.GetBoundingBoxRegion(c.coordinateConverter)
-
+func (c *StandardConsumer) generateTilesetRoot(node octree.INode) (*Root, error) {
+	boundingVolume, err := newNodeBoundingVolume(node, c.coordinateConverter, c.boundingVolume, c.refineMode)
 	if err != nil {
 		return nil, err
 	}
@@ -524,8 +691,8 @@ func (c *StandardConsumer) generateTilesetRoot(node *grid_tree.GridNode) (*Root,
 	}
 
 	root := Root{
-		Content:        Content{"content.pnts"},
-		BoundingVolume: BoundingVolume{reg.GetAsArray()},
+		Content:        Content{c.outputFormat.ContentFileName()},
+		BoundingVolume: boundingVolume,
 		GeometricError: node.ComputeGeometricError(),
 		Refine:         c.refineMode.String(),
 		Children:       children,
@@ -534,16 +701,16 @@ func (c *StandardConsumer) generateTilesetRoot(node *grid_tree.GridNode) (*Root,
 	return &root, nil
 }
 
-func (c *StandardConsumer) generateTileset(node *grid_tree.GridNode, root *Root) *Tileset {
+func (c *StandardConsumer) generateTileset(node octree.INode, root *Root) *Tileset {
 	tileset := Tileset{}
-	tileset.Asset = Asset{Version: "1.0"}
+	tileset.Asset = Asset{Version: c.outputFormat.TilesetVersion()}
 	tileset.GeometricError = node.ComputeGeometricError()
 	tileset.Root = *root
 
 	return &tileset
 }
 
-func (c *StandardConsumer) generateTilesetChildren(node *grid_tree.GridNode) ([]Child, error) {
+func (c *StandardConsumer) generateTilesetChildren(node octree.INode) ([]Child, error) {
 	var children []Child
 	for i, child := range node.GetChildren() {
 		if c.nodeContainsPoints(child) {
@@ -557,36 +724,27 @@ func (c *StandardConsumer) generateTilesetChildren(node *grid_tree.GridNode) ([]
 	return children, nil
 }
 
-func (c *StandardConsumer) nodeContainsPoints(node *grid_tree.GridNode) bool {
+func (c *StandardConsumer) nodeContainsPoints(node octree.INode) bool {
 	return node != nil && node.TotalNumberOfPoints() > 0
 }
 
-func (c *StandardConsumer) generateTilesetChild(child *grid_tree.GridNode, childIndex int, parent *grid_tree.GridNode) (*Child, error) {
+func (c *StandardConsumer) generateTilesetChild(child octree.INode, childIndex int, parent octree.INode) (*Child, error) {
 	childJson := Child{}
 	filename := "tileset.json"
 	if child.IsLeaf() {
-		filename = "content.pnts"
+		filename = c.outputFormat.ContentFileName()
 	}
 
-	childrenPath := parent.GetChildrenPath()
-	childPath := childrenPath[childIndex]
-	// sort "74520" to "02457" for merge_children case
-	if len(childPath) > 1 {
-		childList := []byte(childPath)
-		sort.Slice(childList, func(i, j int) bool { return childList[i] < childList[j] })
-		childPath = string(childList)
-	}
+	childPath := sortedChildPath(parent.GetChildrenPath()[childIndex])
 
 	childJson.Content = Content{
 		Url: childPath + "/" + filename,
 	}
-	reg, err := child.GetBoundingBoxRegion(c.coordinateConverter)
+	boundingVolume, err := newNodeBoundingVolume(child, c.coordinateConverter, c.boundingVolume, c.refineMode)
 	if err != nil {
 		return nil, err
 	}
-	childJson.BoundingVolume = BoundingVolume{
-		Region: reg.GetAsArray(),
-	}
+	childJson.BoundingVolume = boundingVolume
 	childJson.GeometricError = child.ComputeGeometricError()
 	childJson.Refine = c.refineMode.String()
 	return &childJson, nil
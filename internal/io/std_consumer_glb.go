@@ -0,0 +1,324 @@
+package io
+
+import (
+	"encoding/json"
+	"math"
+	"path"
+
+	"github.com/ecopia-map/cesium_tiler/tools"
+)
+
+// Writes a content.glb binary file from the given WorkUnit. Points are stored as a single POINTS primitive
+// whose positions are expressed relative to the tile center, which is set as translation of the glTF node.
+// Batch attributes are stored as vertex attributes named after them, e.g. _INTENSITY, and described through
+// EXT_structural_metadata property attributes.
+func (c *StandardConsumer) writeBinaryGlbFile(workUnit WorkUnit) error {
+	parentFolder := workUnit.BasePath
+	node := workUnit.Node
+
+	// Create base folder if it does not exist
+	err := tools.CreateDirectoryIfDoesNotExist(parentFolder)
+	if err != nil {
+		return err
+	}
+
+	intermediatePointData, err := c.generateIntermediateDataForPnts(node)
+	if err != nil {
+		return err
+	}
+
+	// Evaluating average X, Y, Z to express coords relative to tile center
+	averageXYZ := c.computeAverageXYZ(intermediatePointData)
+
+	// Normalizing coordinates relative to average
+	c.subtractXYZFromIntermediateDataCoords(intermediatePointData, averageXYZ)
+
+	// glTF is y-up, 3D Tiles rotates the content from y-up to z-up at load time
+	c.convertIntermediateDataCoordsToYUp(intermediatePointData)
+	center := []float64{averageXYZ[0], averageXYZ[2], -averageXYZ[1]}
+
+	var gltf *Gltf
+	var binChunk []byte
+	if c.draco {
+		// the external encoder only handles positions and colors, glb content is always compressed in-process
+		dracoContent, err := c.encodeDracoPointCloud(intermediatePointData, true)
+		if err != nil {
+			return err
+		}
+		gltf, binChunk = c.generateGltfWithDraco(intermediatePointData, center, dracoContent)
+	} else {
+		gltf, binChunk = c.generateGltf(intermediatePointData, center)
+	}
+
+	outputByte, err := c.generateGlbByteArray(gltf, binChunk)
+	if err != nil {
+		return err
+	}
+
+	// Write binary content to file
+	glbFilePath := path.Join(parentFolder, "content.glb")
+	err = c.writeFile(glbFilePath, outputByte, 0777)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *StandardConsumer) convertIntermediateDataCoordsToYUp(intermediatePointData *intermediateData) {
+	for i := 0; i < intermediatePointData.numPoints; i++ {
+		y := intermediatePointData.coords[i*3+1]
+		intermediatePointData.coords[i*3+1] = intermediatePointData.coords[i*3+2]
+		intermediatePointData.coords[i*3+2] = -y
+	}
+}
+
+// Generates the glTF json and binary chunk for uncompressed or meshopt compressed content
+func (c *StandardConsumer) generateGltf(intermediatePointData *intermediateData, center []float64) (*Gltf, []byte) {
+	numPoints := intermediatePointData.numPoints
+	gltf := c.generateGltfSkeleton(center, intermediatePointData.attributes)
+
+	type vertexAttribute struct {
+		name          string
+		data          []byte
+		stride        int
+		componentType int
+		normalized    bool
+		accessorType  string
+	}
+
+	// every vertex attribute element must be aligned to 4 bytes, single byte and two bytes attributes are padded
+	attributes := []vertexAttribute{
+		{"POSITION", tools.ConvertTruncateFloat64ToFloat32ByteArray(intermediatePointData.coords), 12, gltfComponentTypeFloat, false, "VEC3"},
+		{"COLOR_0", padVertexAttribute(intermediatePointData.colors, 3, 4), 4, gltfComponentTypeUnsignedByte, true, "VEC3"},
+	}
+	for _, batchAttribute := range intermediatePointData.attributes {
+		attributes = append(attributes, vertexAttribute{
+			name:          gltfBatchAttributeName(batchAttribute.attribute),
+			data:          padVertexAttribute(batchAttribute.gltfBytes(), batchAttribute.attributeType.gltfComponentSize, 4),
+			stride:        4,
+			componentType: batchAttribute.attributeType.gltfComponentType,
+			accessorType:  "SCALAR",
+		})
+	}
+
+	binChunk := make([]byte, 0)
+	fallbackLength := 0
+	for _, attribute := range attributes {
+		bufferView := GltfBufferView{
+			ByteLength: len(attribute.data),
+			ByteStride: attribute.stride,
+			Target:     gltfTargetArrayBuffer,
+		}
+
+		if c.meshopt {
+			// the uncompressed data lives in a fallback buffer which is never loaded
+			compressed := encodeMeshoptVertexBuffer(attribute.data, numPoints, attribute.stride)
+			bufferView.Buffer = 1
+			bufferView.ByteOffset = fallbackLength
+			bufferView.Extensions = map[string]interface{}{
+				extMeshoptCompression: GltfMeshoptBufferView{
+					Buffer:     0,
+					ByteOffset: len(binChunk),
+					ByteLength: len(compressed),
+					ByteStride: attribute.stride,
+					Count:      numPoints,
+					Mode:       "ATTRIBUTES",
+				},
+			}
+			binChunk = append(binChunk, compressed...)
+			fallbackLength += len(attribute.data)
+		} else {
+			bufferView.ByteOffset = len(binChunk)
+			binChunk = append(binChunk, attribute.data...)
+		}
+		binChunk = padByteArray(binChunk, 4, 0)
+
+		bufferViewIndex := len(gltf.BufferViews)
+		gltf.BufferViews = append(gltf.BufferViews, bufferView)
+
+		accessor := GltfAccessor{
+			BufferView:    &bufferViewIndex,
+			ComponentType: attribute.componentType,
+			Normalized:    attribute.normalized,
+			Count:         numPoints,
+			Type:          attribute.accessorType,
+		}
+		if attribute.name == "POSITION" {
+			accessor.Min, accessor.Max = computeFloat32MinMax(intermediatePointData.coords)
+		}
+		gltf.Meshes[0].Primitives[0].Attributes[attribute.name] = len(gltf.Accessors)
+		gltf.Accessors = append(gltf.Accessors, accessor)
+	}
+
+	gltf.Buffers = append(gltf.Buffers, GltfBuffer{ByteLength: len(binChunk)})
+	if c.meshopt {
+		gltf.Buffers = append(gltf.Buffers, GltfBuffer{
+			ByteLength: fallbackLength,
+			Extensions: map[string]interface{}{
+				extMeshoptCompression: map[string]bool{"fallback": true},
+			},
+		})
+		gltf.ExtensionsUsed = append(gltf.ExtensionsUsed, extMeshoptCompression)
+		gltf.ExtensionsRequired = append(gltf.ExtensionsRequired, extMeshoptCompression)
+	}
+
+	return gltf, binChunk
+}
+
+// Generates the glTF json and binary chunk for draco compressed content. All the vertex attributes are stored
+// in the KHR_draco_mesh_compression buffer view since the draco encoder may reorder the points
+func (c *StandardConsumer) generateGltfWithDraco(intermediatePointData *intermediateData, center []float64, dracoContent []byte) (*Gltf, []byte) {
+	numPoints := intermediatePointData.numPoints
+	gltf := c.generateGltfSkeleton(center, intermediatePointData.attributes)
+	primitive := &gltf.Meshes[0].Primitives[0]
+
+	binChunk := padByteArray(dracoContent, 4, 0)
+	gltf.BufferViews = append(gltf.BufferViews, GltfBufferView{ByteLength: len(dracoContent)})
+
+	positionMin, positionMax := computeFloat32MinMax(intermediatePointData.coords)
+	type namedAccessor struct {
+		name     string
+		accessor GltfAccessor
+	}
+	accessors := []namedAccessor{
+		{"POSITION", GltfAccessor{ComponentType: gltfComponentTypeFloat, Count: numPoints, Type: "VEC3", Min: positionMin, Max: positionMax}},
+		{"COLOR_0", GltfAccessor{ComponentType: gltfComponentTypeUnsignedByte, Normalized: true, Count: numPoints, Type: "VEC3"}},
+	}
+	for _, batchAttribute := range intermediatePointData.attributes {
+		accessors = append(accessors, namedAccessor{
+			gltfBatchAttributeName(batchAttribute.attribute),
+			GltfAccessor{ComponentType: batchAttribute.attributeType.gltfComponentType, Count: numPoints, Type: "SCALAR"},
+		})
+	}
+
+	// draco attribute ids follow the order in which the attributes are added to the point cloud
+	dracoAttributes := map[string]int{}
+	for i, attribute := range accessors {
+		primitive.Attributes[attribute.name] = len(gltf.Accessors)
+		gltf.Accessors = append(gltf.Accessors, attribute.accessor)
+		dracoAttributes[attribute.name] = i
+	}
+	primitive.Extensions[khrDracoMeshCompression] = GltfDracoPrimitive{
+		BufferView: 0,
+		Attributes: dracoAttributes,
+	}
+
+	gltf.Buffers = append(gltf.Buffers, GltfBuffer{ByteLength: len(binChunk)})
+	gltf.ExtensionsUsed = append(gltf.ExtensionsUsed, khrDracoMeshCompression)
+	gltf.ExtensionsRequired = append(gltf.ExtensionsRequired, khrDracoMeshCompression)
+
+	return gltf, binChunk
+}
+
+// Generates a glTF with a single node and a single POINTS primitive, together with the
+// EXT_structural_metadata schema describing the given batch attributes. Values stored relative to the tile minimum
+// get it as offset of their property attribute
+func (c *StandardConsumer) generateGltfSkeleton(center []float64, attributes []*batchAttributeValues) *Gltf {
+	gltf := &Gltf{
+		Asset:  GltfAsset{Version: "2.0", Generator: "cesium_tiler"},
+		Scene:  0,
+		Scenes: []GltfScene{{Nodes: []int{0}}},
+		Nodes:  []GltfNode{{Mesh: 0, Translation: center}},
+		Meshes: []GltfMesh{
+			{
+				Primitives: []GltfPrimitive{
+					{
+						Attributes: map[string]int{},
+						Mode:       gltfModePoints,
+						Extensions: map[string]interface{}{},
+					},
+				},
+			},
+		},
+		Accessors:   []GltfAccessor{},
+		BufferViews: []GltfBufferView{},
+		Buffers:     []GltfBuffer{},
+	}
+
+	if len(attributes) == 0 {
+		return gltf
+	}
+
+	classProperties := map[string]GltfMetadataClassProperty{}
+	attributeProperties := map[string]GltfMetadataPropertyAttributeProp{}
+	for _, attribute := range attributes {
+		name := attribute.attribute.PropertyName()
+		classProperties[name] = GltfMetadataClassProperty{Type: "SCALAR", ComponentType: attribute.attributeType.metadataComponentType}
+		property := GltfMetadataPropertyAttributeProp{Attribute: gltfBatchAttributeName(attribute.attribute)}
+		if offset := attribute.gltfOffset(); offset != 0 {
+			property.Offset = &offset
+		}
+		attributeProperties[name] = property
+	}
+
+	metadata := GltfStructuralMetadata{
+		Schema: GltfMetadataSchema{
+			Id: "cesium_tiler",
+			Classes: map[string]GltfMetadataClass{
+				gltfMetadataClassName: {Properties: classProperties},
+			},
+		},
+		PropertyAttributes: []GltfMetadataPropertyAttribute{
+			{Class: gltfMetadataClassName, Properties: attributeProperties},
+		},
+	}
+	gltf.ExtensionsUsed = []string{extStructuralMetadata}
+	gltf.Extensions = map[string]interface{}{extStructuralMetadata: metadata}
+	gltf.Meshes[0].Primitives[0].Extensions[extStructuralMetadata] = GltfPrimitiveStructuralMetadata{PropertyAttributes: []int{0}}
+
+	return gltf
+}
+
+// Generates the glb container: 12 bytes header, json chunk padded with spaces and binary chunk padded with zeros
+func (c *StandardConsumer) generateGlbByteArray(gltf *Gltf, binChunk []byte) ([]byte, error) {
+	jsonChunk, err := json.Marshal(gltf)
+	if err != nil {
+		return nil, err
+	}
+	jsonChunk = padByteArray(jsonChunk, 4, ' ')
+	binChunk = padByteArray(binChunk, 4, 0)
+
+	byteLength := 12 + 8 + len(jsonChunk) + 8 + len(binChunk)
+	outputByte := make([]byte, 0, byteLength)
+	outputByte = append(outputByte, []byte("glTF")...)                          // magic
+	outputByte = append(outputByte, tools.ConvertIntToByteArray(2)...)          // version number
+	outputByte = append(outputByte, tools.ConvertIntToByteArray(byteLength)...) // total length
+	outputByte = append(outputByte, tools.ConvertIntToByteArray(len(jsonChunk))...)
+	outputByte = append(outputByte, tools.ConvertIntToByteArray(gltfChunkTypeJson)...)
+	outputByte = append(outputByte, jsonChunk...)
+	outputByte = append(outputByte, tools.ConvertIntToByteArray(len(binChunk))...)
+	outputByte = append(outputByte, tools.ConvertIntToByteArray(gltfChunkTypeBin)...)
+	outputByte = append(outputByte, binChunk...)
+
+	return outputByte, nil
+}
+
+// Copies elements of componentCount bytes into elements of stride bytes, filling the remaining bytes with zeros
+func padVertexAttribute(data []byte, componentCount int, stride int) []byte {
+	numElements := len(data) / componentCount
+	out := make([]byte, numElements*stride)
+	for i := 0; i < numElements; i++ {
+		copy(out[i*stride:], data[i*componentCount:(i+1)*componentCount])
+	}
+	return out
+}
+
+func padByteArray(data []byte, alignment int, padding byte) []byte {
+	for len(data)%alignment != 0 {
+		data = append(data, padding)
+	}
+	return data
+}
+
+// Returns min and max of the given xyz coordinates once truncated to float32
+func computeFloat32MinMax(coords []float64) ([]float64, []float64) {
+	min := []float64{math.MaxFloat64, math.MaxFloat64, math.MaxFloat64}
+	max := []float64{-math.MaxFloat64, -math.MaxFloat64, -math.MaxFloat64}
+	for i := 0; i < len(coords); i++ {
+		v := float64(float32(coords[i]))
+		min[i%3] = math.Min(min[i%3], v)
+		max[i%3] = math.Max(max[i%3], v)
+	}
+	return min, max
+}
@@ -1,11 +1,13 @@
 package io
 
 import (
+	"context"
+	"errors"
 	"path"
 	"sort"
 	"sync"
 
-	"github.com/ecopia-map/cesium_tiler/internal/octree/grid_tree"
+	"github.com/ecopia-map/cesium_tiler/internal/octree"
 	"github.com/ecopia-map/cesium_tiler/internal/tiler"
 )
 
@@ -22,38 +24,115 @@ func NewStandardProducer(basepath string, subfolder string, options *tiler.Tiler
 }
 
 // Parses a tree node and submits WorkUnits the the provided workchannel. Should be called only on the tree root node.
-// Closes the channel when all work is submitted.
-func (p *StandardProducer) Produce(work chan *WorkUnit, wg *sync.WaitGroup, node *grid_tree.GridNode) {
-	p.produce(p.basePath, node, work, wg)
+// Closes the channel when all work is submitted or the context is canceled.
+func (p *StandardProducer) Produce(ctx context.Context, work chan *WorkUnit, wg *sync.WaitGroup, node octree.INode) {
+	p.produce(ctx, p.basePath, node, work, wg)
 	close(work)
 	wg.Done()
 }
 
-// Parses a tree node and submits WorkUnits the the provided workchannel.
-func (p *StandardProducer) produce(basePath string, node *grid_tree.GridNode, work chan *WorkUnit, wg *sync.WaitGroup) {
+// Parses a tree node and submits WorkUnits the the provided workchannel. Returns false if the context has been
+// canceled and no more work has to be submitted.
+func (p *StandardProducer) produce(ctx context.Context, basePath string, node octree.INode, work chan *WorkUnit, wg *sync.WaitGroup) bool {
 	// if node contains points (it should always be the case), then submit work
 	if node.NumberOfPoints() > 0 {
-		work <- &WorkUnit{
+		workUnit := &WorkUnit{
 			Node:     node,
 			BasePath: basePath,
 			Opts:     p.options,
 		}
+		if !submitWork(ctx, work, workUnit) {
+			return false
+		}
 	}
 
 	// iterate all non nil children and recursively submit all work units
 	for i, child := range node.GetChildren() {
 		if child != nil && child.IsChildrenInitialized() {
-			childrenPath := node.GetChildrenPath()
-			childPath := childrenPath[i]
-			// sort "74520" to "02457" for merge_children case
-			if len(childPath) > 1 {
-				childList := []byte(childPath)
-				sort.Slice(childList, func(i, j int) bool { return childList[i] < childList[j] })
-				childPath = string(childList)
+			childPath := sortedChildPath(node.GetChildrenPath()[i])
+			if !p.produce(ctx, path.Join(basePath, childPath), child, work, wg) {
+				return false
 			}
-			p.produce(path.Join(basePath, childPath), child, work, wg)
 		}
 	}
+
+	return true
+}
+
+// sort "74520" to "02457" for merge_children case
+func sortedChildPath(childPath string) string {
+	if len(childPath) > 1 {
+		childList := []byte(childPath)
+		sort.Slice(childList, func(i, j int) bool { return childList[i] < childList[j] })
+		childPath = string(childList)
+	}
+	return childPath
+}
+
+// Submits WorkUnits for nodes handed over while the tree is being built, as done by the out of core build
+type StandardStreamProducer struct {
+	basePath string
+	options  *tiler.TilerOptions
+}
+
+func NewStandardStreamProducer(basepath string, subfolder string, options *tiler.TilerOptions) *StandardStreamProducer {
+	return &StandardStreamProducer{
+		basePath: path.Join(basepath, subfolder),
+		options:  options,
+	}
+}
+
+// Submits a WorkUnit for each of the given nodes to the provided workchannel and waits until all of them have been
+// processed, so that the caller can release the nodes afterwards. Nodes must still be attached to their parents.
+// Stops submitting work and returns the context error if the context is canceled.
+func (p *StandardStreamProducer) Produce(ctx context.Context, work chan *WorkUnit, nodes []octree.INode) error {
+	var pending sync.WaitGroup
+	defer pending.Wait()
+
+	for _, node := range nodes {
+		if node.NumberOfPoints() == 0 {
+			continue
+		}
+
+		nodePath, err := nodePath(node)
+		if err != nil {
+			return err
+		}
+
+		pending.Add(1)
+		workUnit := &WorkUnit{
+			Node:     node,
+			BasePath: path.Join(p.basePath, nodePath),
+			Opts:     p.options,
+			Pending:  &pending,
+		}
+		if !submitWork(ctx, work, workUnit) {
+			pending.Done()
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+// Returns the path of the given node relative to the root node folder
+func nodePath(node octree.INode) (string, error) {
+	parent := node.GetParent()
+	if parent == nil {
+		return "", nil
+	}
+
+	for i, child := range parent.GetChildren() {
+		if child == node {
+			parentPath, err := nodePath(parent)
+			if err != nil {
+				return "", err
+			}
+			return path.Join(parentPath, sortedChildPath(parent.GetChildrenPath()[i])), nil
+		}
+	}
+
+	return "", errors.New("node not found among the children of its parent")
 }
 
 type StandardMergeProducer struct {
@@ -69,22 +148,22 @@ func NewStandardMergeProducer(basepath string, subfolder string, options *tiler.
 }
 
 // Parses a tree node and submits WorkUnits the the provided workchannel. Should be called only on the tree root node.
-// Closes the channel when all work is submitted.
-func (p *StandardMergeProducer) Produce(work chan *WorkUnit, wg *sync.WaitGroup, node *grid_tree.GridNode) {
-	p.produce(p.basePath, node, work, wg)
+// Closes the channel when all work is submitted or the context is canceled.
+func (p *StandardMergeProducer) Produce(ctx context.Context, work chan *WorkUnit, wg *sync.WaitGroup, node octree.INode) {
+	p.produce(ctx, p.basePath, node, work, wg)
 	close(work)
 	wg.Done()
 }
 
 // Parses a tree node and submits WorkUnits the the provided workchannel.
-func (p *StandardMergeProducer) produce(basePath string, node *grid_tree.GridNode, work chan *WorkUnit, wg *sync.WaitGroup) {
+func (p *StandardMergeProducer) produce(ctx context.Context, basePath string, node octree.INode, work chan *WorkUnit, wg *sync.WaitGroup) {
 	// if node contains points (it should always be the case), then submit work
 	if node.NumberOfPoints() > 0 {
-		work <- &WorkUnit{
+		submitWork(ctx, work, &WorkUnit{
 			Node:     node,
 			BasePath: basePath,
 			Opts:     p.options,
-		}
+		})
 	}
 
 }
@@ -0,0 +1,39 @@
+package io
+
+// Subset of the 3D Tiles 1.1 subtree json schema
+
+type SubtreeBuffer struct {
+	ByteLength int `json:"byteLength"`
+}
+
+type SubtreeBufferView struct {
+	Buffer     int `json:"buffer"`
+	ByteOffset int `json:"byteOffset"`
+	ByteLength int `json:"byteLength"`
+}
+
+type SubtreeAvailability struct {
+	Bitstream      *int `json:"bitstream,omitempty"`
+	AvailableCount *int `json:"availableCount,omitempty"`
+	Constant       *int `json:"constant,omitempty"`
+}
+
+type SubtreePropertyTable struct {
+	Class      string                                  `json:"class"`
+	Count      int                                     `json:"count"`
+	Properties map[string]SubtreePropertyTableProperty `json:"properties"`
+}
+
+type SubtreePropertyTableProperty struct {
+	Values int `json:"values"`
+}
+
+type Subtree struct {
+	Buffers                  []SubtreeBuffer        `json:"buffers,omitempty"`
+	BufferViews              []SubtreeBufferView    `json:"bufferViews,omitempty"`
+	PropertyTables           []SubtreePropertyTable `json:"propertyTables,omitempty"`
+	TileAvailability         SubtreeAvailability    `json:"tileAvailability"`
+	ContentAvailability      []SubtreeAvailability  `json:"contentAvailability"`
+	ChildSubtreeAvailability SubtreeAvailability    `json:"childSubtreeAvailability"`
+	TileMetadata             *int                   `json:"tileMetadata,omitempty"`
+}
@@ -8,8 +8,11 @@ type Content struct {
 	Url string `json:"uri"`
 }
 
+// Bounding volume of a tile, only one among region, box and sphere is set
 type BoundingVolume struct {
-	Region []float64 `json:"region"`
+	Region []float64 `json:"region,omitempty"`
+	Box    []float64 `json:"box,omitempty"`
+	Sphere []float64 `json:"sphere,omitempty"`
 }
 
 type Child struct {
@@ -20,15 +23,45 @@ type Child struct {
 }
 
 type Root struct {
-	Children       []Child        `json:"children"`
-	Content        Content        `json:"content"`
-	BoundingVolume BoundingVolume `json:"boundingVolume"`
-	GeometricError float64        `json:"geometricError"`
-	Refine         string         `json:"refine"`
+	Children       []Child         `json:"children,omitempty"`
+	Content        Content         `json:"content"`
+	BoundingVolume BoundingVolume  `json:"boundingVolume"`
+	GeometricError float64         `json:"geometricError"`
+	Refine         string          `json:"refine"`
+	ImplicitTiling *ImplicitTiling `json:"implicitTiling,omitempty"`
 }
 
 type Tileset struct {
 	Asset          Asset   `json:"asset"`
+	Schema         *Schema `json:"schema,omitempty"`
 	GeometricError float64 `json:"geometricError"`
 	Root           Root    `json:"root"`
 }
+
+type ImplicitTiling struct {
+	SubdivisionScheme string   `json:"subdivisionScheme"`
+	SubtreeLevels     int      `json:"subtreeLevels"`
+	AvailableLevels   int      `json:"availableLevels"`
+	Subtrees          Subtrees `json:"subtrees"`
+}
+
+type Subtrees struct {
+	Url string `json:"uri"`
+}
+
+type Schema struct {
+	Id      string                 `json:"id"`
+	Classes map[string]SchemaClass `json:"classes"`
+}
+
+type SchemaClass struct {
+	Properties map[string]SchemaClassProperty `json:"properties"`
+}
+
+type SchemaClassProperty struct {
+	Type          string `json:"type"`
+	ComponentType string `json:"componentType,omitempty"`
+	Array         bool   `json:"array,omitempty"`
+	Count         int    `json:"count,omitempty"`
+	Semantic      string `json:"semantic,omitempty"`
+}
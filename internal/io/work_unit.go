@@ -1,13 +1,17 @@
 package io
 
 import (
-	"github.com/ecopia-map/cesium_tiler/internal/octree/grid_tree"
+	"sync"
+
+	"github.com/ecopia-map/cesium_tiler/internal/octree"
 	"github.com/ecopia-map/cesium_tiler/internal/tiler"
 )
 
 // Contains the minimal data needed to produce a single 3d tile, i.e. a binary content.pnts file and a tileset.json file
 type WorkUnit struct {
-	Node     *grid_tree.GridNode
+	Node     octree.INode
 	Opts     *tiler.TilerOptions
 	BasePath string
+	Implicit bool            // if true the tile belongs to an implicit tileset and no tileset.json is written for it
+	Pending  *sync.WaitGroup // if not nil it is signalled once the work unit has been processed
 }
@@ -5,19 +5,29 @@ import (
 	"sync"
 
 	"github.com/ecopia-map/cesium_tiler/internal/data"
+	"github.com/ecopia-map/cesium_tiler/internal/tiler"
 )
 
-// Data structure that accepts points and stores just the one closest to its center, or if the side is too small,
-// all the points. It assumes that coordinates are expressed in a metric cartesian system.
+// Data structure that accepts points and stores just one of them, chosen by the sampling strategy of the tree, or if
+// the side is too small, all the points. It assumes that coordinates are expressed in a metric cartesian system.
 type gridCell struct {
-	index              gridIndex     // unique spatial index of the cell
-	size               float64       // length of the side of the cell (cubic cell)
-	points             []*data.Point // points stored in the cell
-	sizeThreshold      float64       // if size is below sizeThreshold store all points in the cell instead of just the one closest to the center
-	distanceFromCenter float64       // distance from center of current point at index 0
+	index              gridIndex              // unique spatial index of the cell
+	size               float64                // length of the side of the cell (cubic cell)
+	points             []*data.Point          // points stored in the cell
+	sizeThreshold      float64                // if size is below sizeThreshold store all points in the cell instead of just the sampled one
+	sampling           tiler.SamplingStrategy // strategy choosing the point stored by the cell
+	distanceFromCenter float64                // distance from center of current point at index 0
+	numSampledPoints   int64                  // number of points submitted to the cell while sampling
+	sum                *gridCellSum           // sums of the points submitted to the cell, only for the centroid sampling
+	randomState        uint64                 // state of the random generator of the random sampling
 	sync.RWMutex
 }
 
+// Sums of the coordinates, colors and intensities of the points submitted to a cell
+type gridCellSum struct {
+	x, y, z, r, g, b, intensity float64
+}
+
 // returns the spatial index component associated to a given dimension (e.g. X or Y or Z) coordinate value
 func getDimensionIndex(dimensionValue float64, size float64) int {
 	return int(math.Floor(dimensionValue / size))
@@ -33,19 +43,31 @@ func (gc *gridCell) getCellCenter() (float64, float64, float64) {
 // submits a point to the cell, eventually returning a pointer to the point pushed out.
 func (gc *gridCell) pushPoint(point *data.Point, isFollowSizeThreshold bool) *data.Point {
 	gc.Lock()
-	if gc.points == nil {
-		gc.storeFirstPoint(point)
+	if isFollowSizeThreshold && gc.isSizeBelowThreshold() {
+		gc.points = append(gc.points, point)
 		gc.Unlock()
 		return nil
 	}
 
-	if isFollowSizeThreshold && gc.isSizeBelowThreshold() {
-		gc.points = append(gc.points, point)
+	gc.addToSum(point)
+	gc.numSampledPoints++
+	if gc.points == nil {
+		gc.storeFirstPoint(point)
 		gc.Unlock()
 		return nil
 	}
 
-	retPoint := gc.storeClosestPointAndReturnFarthestOne(point)
+	var retPoint *data.Point
+	switch gc.sampling {
+	case tiler.SamplingRandom:
+		retPoint = gc.storeRandomPointAndReturnOtherOne(point)
+	case tiler.SamplingHighest:
+		retPoint = gc.storePointIf(point, point.Z > gc.points[0].Z)
+	case tiler.SamplingLowest:
+		retPoint = gc.storePointIf(point, point.Z < gc.points[0].Z)
+	default:
+		retPoint = gc.storeClosestPointAndReturnFarthestOne(point)
+	}
 	gc.Unlock()
 
 	return retPoint
@@ -62,6 +84,75 @@ func (gc *gridCell) storeFirstPoint(point *data.Point) {
 	gc.distanceFromCenter = gc.getDistanceFromCenter(point)
 }
 
+// stores the input point in place of the current one if the condition holds, returning the rejected one
+func (gc *gridCell) storePointIf(point *data.Point, condition bool) *data.Point {
+	if condition {
+		oldPoint := gc.points[0]
+		gc.points[0] = point
+		return oldPoint
+	}
+
+	return point
+}
+
+// stores the input point with probability 1/n, n being the number of points submitted to the cell so far, so that
+// each of them is equally likely to be the one stored at the end (reservoir sampling). Returns the rejected point
+func (gc *gridCell) storeRandomPointAndReturnOtherOne(point *data.Point) *data.Point {
+	return gc.storePointIf(point, gc.nextRandom()%uint64(gc.numSampledPoints) == 0)
+}
+
+// returns the next value of the xorshift generator of the cell, seeded from the cell index so that the sampling does
+// not depend on a shared generator
+func (gc *gridCell) nextRandom() uint64 {
+	if gc.randomState == 0 {
+		gc.randomState = uint64(gc.index.x)*0x9E3779B97F4A7C15 ^ uint64(gc.index.y)*0xC2B2AE3D27D4EB4F ^ uint64(gc.index.z)*0x165667B19E3779F9 | 1
+	}
+	gc.randomState ^= gc.randomState << 13
+	gc.randomState ^= gc.randomState >> 7
+	gc.randomState ^= gc.randomState << 17
+	return gc.randomState
+}
+
+// adds the input point to the sums of the cell when the centroid sampling is used
+func (gc *gridCell) addToSum(point *data.Point) {
+	if gc.sampling != tiler.SamplingCentroid {
+		return
+	}
+	if gc.sum == nil {
+		gc.sum = &gridCellSum{}
+	}
+	gc.sum.x += point.X
+	gc.sum.y += point.Y
+	gc.sum.z += point.Z
+	gc.sum.r += float64(point.R)
+	gc.sum.g += float64(point.G)
+	gc.sum.b += float64(point.B)
+	gc.sum.intensity += float64(point.Intensity)
+}
+
+// returns the points stored in the cell. With the centroid sampling the sampled point is replaced by a copy moved to
+// the centroid of all the points submitted to the cell, with their mean color and intensity
+func (gc *gridCell) getPoints() []*data.Point {
+	if gc.sum == nil || gc.numSampledPoints <= 1 || len(gc.points) == 0 {
+		return gc.points
+	}
+
+	n := float64(gc.numSampledPoints)
+	sampled := *gc.points[0]
+	sampled.X = gc.sum.x / n
+	sampled.Y = gc.sum.y / n
+	sampled.Z = gc.sum.z / n
+	sampled.R = uint8(math.Round(gc.sum.r / n))
+	sampled.G = uint8(math.Round(gc.sum.g / n))
+	sampled.B = uint8(math.Round(gc.sum.b / n))
+	sampled.Intensity = uint16(math.Round(gc.sum.intensity / n))
+
+	points := make([]*data.Point, len(gc.points))
+	copy(points, gc.points)
+	points[0] = &sampled
+	return points
+}
+
 // takes the input point and compares its distance from the center to the one in the points array,
 // storing in the array only the one closest to the center and returning the other, rejected and farthest from the center, one
 func (gc *gridCell) storeClosestPointAndReturnFarthestOne(point *data.Point) *data.Point {
@@ -77,6 +168,20 @@ func (gc *gridCell) storeClosestPointAndReturnFarthestOne(point *data.Point) *da
 	return point
 }
 
+// checks if any point stored in the cell has a squared distance from the input point lower than the given one
+func (gc *gridCell) hasPointCloserThan(point *data.Point, distanceSquared float64) bool {
+	gc.RLock()
+	defer gc.RUnlock()
+
+	for _, stored := range gc.points {
+		dx, dy, dz := stored.X-point.X, stored.Y-point.Y, stored.Z-point.Z
+		if dx*dx+dy*dy+dz*dz < distanceSquared {
+			return true
+		}
+	}
+	return false
+}
+
 // computes the cartesian distance of a point from the cell center
 func (gc *gridCell) getDistanceFromCenter(point *data.Point) float64 {
 	xc, yc, zc := gc.getCellCenter()
@@ -11,6 +11,9 @@ import (
 	"github.com/ecopia-map/cesium_tiler/internal/converters"
 	"github.com/ecopia-map/cesium_tiler/internal/data"
 	"github.com/ecopia-map/cesium_tiler/internal/geometry"
+	"github.com/ecopia-map/cesium_tiler/internal/octree"
+	"github.com/ecopia-map/cesium_tiler/internal/point_loader"
+	"github.com/ecopia-map/cesium_tiler/internal/tiler"
 	"github.com/golang/glog"
 )
 
@@ -34,7 +37,11 @@ type GridNode struct {
 	numberOfPoints        int32
 	leaf                  int32
 	isChildrenInitialized bool
+	spillBuckets          *[8]*point_loader.SpillLoader
 	extend                *GridNodeExtend
+	tightBoundingVolume   *octree.NodeBoundingVolume
+	volumeLock            sync.Mutex
+	samplingLock          sync.Mutex // serializes the points kept by the min-distance sampling
 
 	sync.RWMutex
 }
@@ -148,8 +155,8 @@ func (n *GridNode) GetCellSize() float64 {
 	return n.cellSize
 }
 
-func (n *GridNode) GetChildren() [8]*GridNode {
-	return n.children
+func (n *GridNode) GetChildren() [8]octree.INode {
+	return toINodes(n.children)
 }
 
 func (n *GridNode) GetChildrenPath() [8]string {
@@ -246,7 +253,7 @@ func getOctantFromElement(element *data.Point, bbox *geometry.BoundingBox) uint8
 func (n *GridNode) BuildPoints() {
 	var points []*data.Point
 	for _, cell := range n.cells {
-		points = append(points, cell.points...)
+		points = append(points, cell.getPoints()...)
 	}
 	n.points = points
 	n.cells = make(map[gridIndex]*gridCell)
@@ -258,7 +265,10 @@ func (n *GridNode) BuildPoints() {
 	}
 }
 
-func (n *GridNode) GetParent() *GridNode {
+func (n *GridNode) GetParent() octree.INode {
+	if n.parent == nil {
+		return nil
+	}
 	return n.parent
 }
 
@@ -313,6 +323,7 @@ func (n *GridNode) initializeGridCell(index *gridIndex) *gridCell {
 			index:         *index,
 			size:          n.cellSize,
 			sizeThreshold: n.minCellSize,
+			sampling:      n.getSampling(),
 			points:        nil,
 		}
 		n.cells[*index] = cell
@@ -333,15 +344,68 @@ func (n *GridNode) pushPointToCell(point *data.Point, isFollowSizeThreshold bool
 	// if !isFollowSizeThreshold || n.cellSize < n.minCellSize/2 {
 	// 	glog.Infoln(*point)
 	// }
-	return n.getPointGridCell(point).pushPoint(point, isFollowSizeThreshold)
+	cell := n.getPointGridCell(point)
+	if n.getSampling() == tiler.SamplingMinDistance && !(isFollowSizeThreshold && cell.isSizeBelowThreshold()) {
+		return n.pushPointAtMinDistance(cell, point)
+	}
+	return cell.pushPoint(point, isFollowSizeThreshold)
+}
+
+// stores the point in its cell only if no point stored by the node is closer than the cell size, otherwise returns it
+// to be pushed to the children. As the cell size is the min distance only the cells around the point are searched
+func (n *GridNode) pushPointAtMinDistance(cell *gridCell, point *data.Point) *data.Point {
+	// two close points submitted concurrently must not be both stored
+	n.samplingLock.Lock()
+	defer n.samplingLock.Unlock()
+
+	minDistanceSquared := n.cellSize * n.cellSize
+	for x := cell.index.x - 1; x <= cell.index.x+1; x++ {
+		for y := cell.index.y - 1; y <= cell.index.y+1; y++ {
+			for z := cell.index.z - 1; z <= cell.index.z+1; z++ {
+				n.RLock()
+				neighbour := n.cells[gridIndex{x, y, z}]
+				n.RUnlock()
+				if neighbour != nil && neighbour.hasPointCloserThan(point, minDistanceSquared) {
+					return point
+				}
+			}
+		}
+	}
+
+	cell.Lock()
+	cell.points = append(cell.points, point)
+	cell.Unlock()
+
+	return nil
+}
+
+// returns the sampling strategy of the tree of the node, the closest point to the cell center if the node has no tree
+func (n *GridNode) getSampling() tiler.SamplingStrategy {
+	if n.extend == nil || n.extend.tree == nil {
+		return tiler.SamplingClosest
+	}
+	return n.extend.tree.sampling
 }
 
-// add a point to the node children and clears the leaf flag from this node
+// add a point to the node children and clears the leaf flag from this node.
+// If the node spills its children points to disk the point is appended to the bucket of the child instead
 func (n *GridNode) addPointToChildren(point *data.Point, isFollowSizeThreshold bool) {
-	n.children[getOctantFromElement(point, n.boundingBox)].AddDataPoint(point, isFollowSizeThreshold)
+	octant := getOctantFromElement(point, n.boundingBox)
+	if n.spillBuckets != nil {
+		n.spillBuckets[octant].AddPoint(point)
+	} else {
+		n.children[octant].AddDataPoint(point, isFollowSizeThreshold)
+	}
 	n.clearLeafFlag()
 }
 
+// Drops the references to the points of the node so that they can be garbage collected.
+// The point counters are kept as they are still needed to describe the node in the tileset of its parent
+func (n *GridNode) ReleasePoints() {
+	n.points = nil
+	n.cells = nil
+}
+
 // sets the leaf flag to 0 atomically
 func (n *GridNode) clearLeafFlag() {
 	atomic.StoreInt32(&n.leaf, 0)
@@ -437,11 +501,21 @@ func (n *GridNode) MergeSmallChildren(minPointsNum int64) error {
 			continue
 		}
 		if err := n.children[i].MergeSmallChildren(minPointsNum); err != nil {
-			glog.Fatal(err)
+			return err
 		}
 
 	}
 
+	return n.mergeChildren(minPointsNum)
+}
+
+// Merges the small leaf children of the node together or into the node itself.
+// The children of the children must already be merged
+func (n *GridNode) mergeChildren(minPointsNum int64) error {
+	if n.IsLeaf() {
+		return nil
+	}
+
 	// merge children
 	wrapChildren := make([]*GridWrapNode, 0)
 	branchChildrenCount := 0
@@ -572,12 +646,10 @@ func (n *GridNode) SplitBigNode(maxPointsNum int32) error {
 
 		if child.IsLeaf() {
 			if err := n.children[i].SplitBigLeafNode(maxPointsNum); err != nil {
-				glog.Fatal(err)
 				return err
 			}
 		} else {
 			if err := n.children[i].SplitBigBranchNode(maxPointsNum); err != nil {
-				glog.Fatal(err)
 				return err
 			}
 		}
@@ -607,7 +679,6 @@ func (n *GridNode) SplitBigBranchNode(maxPointsNum int32) error {
 			continue
 		}
 		if err := n.children[i].SplitBigNode(maxPointsNum); err != nil {
-			glog.Fatal(err)
 			return err
 		}
 
@@ -663,7 +734,6 @@ func (n *GridNode) SplitBigLeafNode(maxPointsNum int32) error {
 			continue
 		}
 		if err := n.children[i].SplitBigLeafNode(maxPointsNum); err != nil {
-			glog.Fatal(err)
 			return err
 		}
 	}
@@ -671,6 +741,74 @@ func (n *GridNode) SplitBigLeafNode(maxPointsNum int32) error {
 	return nil
 }
 
+// Returns the children of the node placed at their octree position. A child produced by MergeSmallChildren
+// stores the points of several sibling octants, listed by its children path; such a child is split back into
+// one leaf node per merged octant so that every returned node covers exactly the bounding box of its octant.
+// Empty octants are returned as nil.
+func (n *GridNode) GetOctreeChildren() [8]octree.INode {
+	var octreeChildren [8]*GridNode
+
+	for i, child := range n.children {
+		if child == nil || child.TotalNumberOfPoints() == 0 {
+			continue
+		}
+
+		childPath := n.childrenPath[i]
+		if len(childPath) < 2 {
+			octreeChildren[i] = child
+			continue
+		}
+
+		merged := make(map[uint8]bool)
+		for _, c := range childPath {
+			merged[uint8(c-'0')] = true
+		}
+
+		for _, point := range child.points {
+			octant := getOctantFromElement(point, n.boundingBox)
+			if !merged[octant] {
+				// the point lies outside the merged octants, keep it in the octant of the merged node
+				octant = uint8(i)
+			}
+
+			if octreeChildren[octant] == nil {
+				octreeChildren[octant] = NewGridNode(
+					fmt.Sprintf("%s-%d", n.nodeNID, octant),
+					n.extend.tree,
+					n,
+					getOctantBoundingBox(&octant, n.boundingBox),
+					child.cellSize,
+					child.minCellSize,
+					false,
+				)
+			}
+			octreeChildren[octant].AddDataPointForce(point)
+		}
+	}
+
+	return toINodes(octreeChildren)
+}
+
+// Returns the given nodes as a list of INode
+func ToINodeList(nodes []*GridNode) []octree.INode {
+	iNodes := make([]octree.INode, len(nodes))
+	for i, node := range nodes {
+		iNodes[i] = node
+	}
+	return iNodes
+}
+
+// Returns the given nodes as INode, the missing nodes as nil interfaces
+func toINodes(nodes [8]*GridNode) [8]octree.INode {
+	var iNodes [8]octree.INode
+	for i, node := range nodes {
+		if node != nil {
+			iNodes[i] = node
+		}
+	}
+	return iNodes
+}
+
 // Returns a bounding box from the given box and the given octant index
 func getOctantBoundingBox(octant *uint8, bbox *geometry.BoundingBox) *geometry.BoundingBox {
 	return geometry.NewBoundingBoxFromParent(bbox, octant)
@@ -0,0 +1,34 @@
+package grid_tree
+
+import (
+	"github.com/ecopia-map/cesium_tiler/internal/converters"
+	"github.com/ecopia-map/cesium_tiler/internal/data"
+	"github.com/ecopia-map/cesium_tiler/internal/octree"
+)
+
+// Returns the tight bounding box and sphere of the points of the node and of its descendants. If includeParentPoints
+// is true the points of the ancestors falling in the node bounding box, which are part of the node content with the
+// REPLACE refine mode, are enclosed too.
+// The volumes are computed once and cached. As the volumes of the children are needed to compute the one of the node,
+// the volume of a node must be computed before its points are released
+func (n *GridNode) GetTightBoundingVolume(converter converters.CoordinateConverter, includeParentPoints bool) (*octree.NodeBoundingVolume, error) {
+	n.volumeLock.Lock()
+	defer n.volumeLock.Unlock()
+
+	if n.tightBoundingVolume != nil {
+		return n.tightBoundingVolume, nil
+	}
+
+	volume, err := octree.ComputeTightBoundingVolume(n, converter, includeParentPoints)
+	if err != nil {
+		return nil, err
+	}
+	n.tightBoundingVolume = volume
+
+	return n.tightBoundingVolume, nil
+}
+
+// Appends to the given points the points of the ancestors of the node falling in the node bounding box
+func (n *GridNode) AppendParentPoints(points []*data.Point) []*data.Point {
+	return octree.AppendParentPoints(n, points)
+}
@@ -9,7 +9,9 @@ import (
 	"github.com/ecopia-map/cesium_tiler/internal/converters"
 	"github.com/ecopia-map/cesium_tiler/internal/data"
 	"github.com/ecopia-map/cesium_tiler/internal/geometry"
+	"github.com/ecopia-map/cesium_tiler/internal/octree"
 	"github.com/ecopia-map/cesium_tiler/internal/point_loader"
+	"github.com/ecopia-map/cesium_tiler/internal/tiler"
 	"github.com/ecopia-map/cesium_tiler/tools"
 	"github.com/golang/glog"
 )
@@ -24,6 +26,7 @@ type GridTree struct {
 	built               bool
 	maxCellSize         float64
 	minCellSize         float64
+	sampling            tiler.SamplingStrategy
 	coordinateConverter converters.CoordinateConverter
 	elevationCorrector  converters.ElevationCorrector
 	point_loader.Loader
@@ -31,6 +34,12 @@ type GridTree struct {
 
 	// Extend
 	extend *GridTreeExtend
+
+	// set when the tree is built out of core, nil otherwise
+	outOfCore *GridTreeOutOfCore
+
+	// number of goroutines loading points into the tree, 0 uses one per CPU
+	numWorkers int
 }
 
 type GridTreeExtend struct {
@@ -46,11 +55,13 @@ func NewGridTree(
 	elevationCorrector converters.ElevationCorrector,
 	maxCellSize float64,
 	minCellSize float64,
+	sampling tiler.SamplingStrategy,
 ) *GridTree {
 	return &GridTree{
 		built:               false,
 		maxCellSize:         maxCellSize,
 		minCellSize:         minCellSize,
+		sampling:            sampling,
 		Loader:              point_loader.NewSequentialLoader(),
 		coordinateConverter: coordinateConverter,
 		elevationCorrector:  elevationCorrector,
@@ -71,7 +82,7 @@ func (tree *GridTree) Build() error {
 	tree.init()
 
 	var wg sync.WaitGroup
-	tree.launchParallelPointLoaders(&wg)
+	tree.launchParallelPointLoaders(&wg, tree.Loader, tree.rootNode)
 	wg.Wait()
 
 	tree.Loader.ClearLoader()
@@ -82,7 +93,15 @@ func (tree *GridTree) Build() error {
 	return nil
 }
 
-func (tree *GridTree) GetRootNode() *GridNode {
+func (tree *GridTree) GetRootNode() octree.INode {
+	if tree.rootNode == nil {
+		return nil
+	}
+	return tree.rootNode
+}
+
+// Returns the root node with the methods specific to the grid algorithm, as needed to merge and split the nodes
+func (tree *GridTree) GetRootGridNode() *GridNode {
 	return tree.rootNode
 }
 
@@ -95,28 +114,69 @@ func (tree *GridTree) Clear() bool {
 	return true
 }
 
+// Converts the given point to the internal srid and adds it to the tree. An error is returned if the point cannot be
+// converted
 func (tree *GridTree) AddPoint(
 	coordinate *geometry.Coordinate,
 	r uint8, g uint8, b uint8,
-	intensity uint8, classification uint8, srid int,
+	intensity uint16, classification uint8, srid int,
 	pointExtend *data.PointExtend,
-) {
-	tree.Loader.AddPoint(tree.getPointFromRawData(coordinate, r, g, b, intensity, classification, srid, pointExtend))
+) error {
+	point, err := tree.getPointFromRawData(coordinate, r, g, b, intensity, classification, srid, pointExtend)
+	if err != nil {
+		return err
+	}
+
+	tree.Loader.AddPoint(point)
+	return nil
+}
+
+// Converts the given points to the internal srid, with two batch conversions for all of them, and adds them to the tree.
+// An error is returned if any point cannot be converted
+func (tree *GridTree) AddPoints(points []*data.Point, srid int) error {
+	coords := make([]geometry.Coordinate, len(points))
+	for i, point := range points {
+		coords[i] = geometry.Coordinate{X: point.X, Y: point.Y, Z: point.Z}
+	}
+
+	if err := tree.coordinateConverter.ConvertCoordinatesSrid(srid, 4326, coords); err != nil {
+		return fmt.Errorf("%v. srid:[%d]", err, srid)
+	}
+
+	for i, point := range points {
+		z, err := tree.elevationCorrector.CorrectElevation(coords[i].X, coords[i].Y, coords[i].Z)
+		if err != nil {
+			return fmt.Errorf("%v. srid:[%d]", err, srid)
+		}
+		coords[i] = geometry.Coordinate{X: point.X, Y: point.Y, Z: z}
+	}
+
+	if err := tree.coordinateConverter.ConvertCoordinatesSrid(srid, internalCoordinateEpsgCode, coords); err != nil {
+		return fmt.Errorf("%v. srid:[%d]", err, srid)
+	}
+
+	for i, point := range points {
+		point.X, point.Y, point.Z = coords[i].X, coords[i].Y, coords[i].Z
+		tree.Loader.AddPoint(point)
+	}
+	return nil
 }
 
 func (tree *GridTree) getPointFromRawData(
 	coordinate *geometry.Coordinate,
 	r uint8, g uint8, b uint8,
-	intensity uint8, classification uint8, srid int,
+	intensity uint16, classification uint8, srid int,
 	pointExtend *data.PointExtend,
-) *data.Point {
+) (*data.Point, error) {
 	wgs84coords, err := tree.coordinateConverter.ConvertCoordinateSrid(srid, 4326, *coordinate)
 	if err != nil {
-		glog.Infoln(err)
-		glog.Fatal(err)
+		return nil, fmt.Errorf("%v. srid:[%d] coordinate:[%s]", err, srid, tools.FmtJSONString(coordinate))
 	}
 
-	z := tree.elevationCorrector.CorrectElevation(wgs84coords.X, wgs84coords.Y, wgs84coords.Z)
+	z, err := tree.elevationCorrector.CorrectElevation(wgs84coords.X, wgs84coords.Y, wgs84coords.Z)
+	if err != nil {
+		return nil, fmt.Errorf("%v. srid:[%d] coordinate:[%s]", err, srid, tools.FmtJSONString(coordinate))
+	}
 
 	worldMercatorCoords, err := tree.coordinateConverter.ConvertCoordinateSrid(
 		srid,
@@ -129,9 +189,7 @@ func (tree *GridTree) getPointFromRawData(
 	)
 
 	if err != nil {
-		glog.Infoln(err, fmt.Sprintf("srid:[%d] coordinate:[%s]", srid, tools.FmtJSONString(coordinate)))
-		glog.Infoln(err, fmt.Sprintf("coordinate X:[%f] Y:[%f] Z:[%f]", coordinate.X, coordinate.Y, z))
-		glog.Fatal(err)
+		return nil, fmt.Errorf("%v. srid:[%d] coordinate X:[%f] Y:[%f] Z:[%f]", err, srid, coordinate.X, coordinate.Y, z)
 	}
 
 	return data.NewPoint(
@@ -140,7 +198,7 @@ func (tree *GridTree) getPointFromRawData(
 		worldMercatorCoords.Z,
 		r, g, b, intensity, classification,
 		pointExtend,
-	)
+	), nil
 }
 
 func (tree *GridTree) GetTreeExtend() *GridTreeExtend {
@@ -193,20 +251,28 @@ func (tree *GridTree) clear() {
 	tree.Loader.ClearLoader()
 }
 
-func (tree *GridTree) launchParallelPointLoaders(waitGroup *sync.WaitGroup) {
-	N := runtime.NumCPU()
+// Sets the number of goroutines loading points into the tree, 0 uses one per CPU
+func (tree *GridTree) SetNumWorkers(numWorkers int) {
+	tree.numWorkers = numWorkers
+}
+
+func (tree *GridTree) launchParallelPointLoaders(waitGroup *sync.WaitGroup, loader point_loader.Loader, node *GridNode) {
+	N := tree.numWorkers
+	if N <= 0 {
+		N = runtime.NumCPU()
+	}
 
 	for i := 0; i < N; i++ {
 		waitGroup.Add(1)
-		go tree.launchPointLoader(waitGroup)
+		go tree.launchPointLoader(waitGroup, loader, node)
 	}
 }
 
-func (tree *GridTree) launchPointLoader(waitGroup *sync.WaitGroup) {
+func (tree *GridTree) launchPointLoader(waitGroup *sync.WaitGroup, loader point_loader.Loader, node *GridNode) {
 	for {
-		val, shouldContinue := tree.Loader.GetNext()
+		val, shouldContinue := loader.GetNext()
 		if val != nil {
-			tree.rootNode.AddDataPoint(val, true)
+			node.AddDataPoint(val, true)
 		}
 		if !shouldContinue {
 			break
@@ -224,30 +290,16 @@ func (tree *GridTree) UpdateExtendChunkEdge(chunkEdgeX, chunkEdgeY, chunkEdgeZ f
 
 func (tree *GridTree) MergeSmallNode(minPointsNum int32) error {
 	if !tree.built {
-		err := errors.New("octree does not built")
-		glog.Fatal(err)
-		return err
+		return errors.New("octree does not built")
 	}
 
-	if err := tree.rootNode.MergeSmallChildren(int64(minPointsNum)); err != nil {
-		glog.Fatal(err)
-		return err
-	}
-
-	return nil
+	return tree.rootNode.MergeSmallChildren(int64(minPointsNum))
 }
 
 func (tree *GridTree) SplitBigNode(maxPointsNum int32) error {
 	if !tree.built {
-		err := errors.New("octree does not built")
-		glog.Fatal(err)
-		return err
+		return errors.New("octree does not built")
 	}
 
-	if err := tree.rootNode.SplitBigNode(maxPointsNum); err != nil {
-		glog.Fatal(err)
-		return err
-	}
-
-	return nil
+	return tree.rootNode.SplitBigNode(maxPointsNum)
 }
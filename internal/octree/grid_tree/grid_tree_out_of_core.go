@@ -0,0 +1,233 @@
+package grid_tree
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sync"
+
+	"github.com/ecopia-map/cesium_tiler/internal/point_loader"
+	"github.com/golang/glog"
+)
+
+// Rough estimate of the memory in bytes taken by a point stored in the tree, including the grid cell overhead
+const pointMemoryFootprint = 160
+
+// Settings of a tree built out of core
+type GridTreeOutOfCore struct {
+	spillFolder       string
+	maxResidentPoints int64
+	loader            *point_loader.SpillLoader
+}
+
+// Switches the tree to the out of core build. Points added to the tree are written to the given spill folder
+// instead of being kept in memory, the memory budget is expressed in bytes
+func (tree *GridTree) EnableOutOfCore(spillFolder string, memoryBudget int64) error {
+	if tree.built {
+		return errors.New("octree already built")
+	}
+
+	loader, err := point_loader.NewSpillLoader(path.Join(spillFolder, "points.bin"))
+	if err != nil {
+		return err
+	}
+
+	maxResidentPoints := memoryBudget / pointMemoryFootprint
+	if maxResidentPoints < 1 {
+		maxResidentPoints = 1
+	}
+
+	tree.Loader = loader
+	tree.outOfCore = &GridTreeOutOfCore{
+		spillFolder:       spillFolder,
+		maxResidentPoints: maxResidentPoints,
+		loader:            loader,
+	}
+
+	glog.Infof("out-of-core build enabled. spillFolder:[%s] maxResidentPoints:[%d]", spillFolder, maxResidentPoints)
+
+	return nil
+}
+
+func (tree *GridTree) IsOutOfCore() bool {
+	return tree.outOfCore != nil
+}
+
+// Builds the hierarchical tree structure keeping in memory only the points allowed by the memory budget.
+// Nodes holding more points than the budget store their own points and spill the ones pushed out to a bucket file
+// per child, which is then built on its own. Big nodes are split and small ones merged as done by SplitBigNode and
+// MergeSmallNode. As soon as the subtree of a node is complete its nodes are passed to the export function and
+// their points released, the root node is exported last and keeps its points.
+func (tree *GridTree) BuildOutOfCore(maxPointsNum int32, minPointsNum int32, export func(nodes []*GridNode) error) error {
+	if tree.built {
+		return errors.New("octree already built")
+	}
+	if tree.outOfCore == nil {
+		return errors.New("out-of-core build not enabled")
+	}
+
+	tree.init()
+
+	if err := tree.buildNodeOutOfCore(tree.rootNode, tree.outOfCore.loader, maxPointsNum, minPointsNum, export); err != nil {
+		return err
+	}
+	tree.Loader.ClearLoader()
+	tree.built = true
+
+	return export([]*GridNode{tree.rootNode})
+}
+
+// Loads the points of the given loader into the node. On return all the descendants of the node are complete,
+// exported and released while the node keeps its points, as it can still be merged with its siblings
+func (tree *GridTree) buildNodeOutOfCore(
+	node *GridNode,
+	loader *point_loader.SpillLoader,
+	maxPointsNum int32,
+	minPointsNum int32,
+	export func(nodes []*GridNode) error,
+) error {
+	loader.InitializeLoader()
+
+	if loader.NumberOfPoints() <= tree.outOfCore.maxResidentPoints {
+		// the whole subtree fits in memory
+		tree.loadPoints(node, loader)
+		err := loader.Err()
+		loader.ClearLoader()
+		if err != nil {
+			return err
+		}
+		node.BuildPoints()
+
+		return tree.finalizeSubtree(node, maxPointsNum, minPointsNum, export)
+	}
+
+	glog.Infof("spill node. nodeNID:[%s] numberOfPoints:[%d]", node.nodeNID, loader.NumberOfPoints())
+
+	// the node keeps the points retained by its cells, the ones pushed out are written to the children buckets.
+	// The buckets are cleared by the builds of the children, the ones left are released on any return
+	var buckets [8]*point_loader.SpillLoader
+	defer func() {
+		for _, bucket := range buckets {
+			if bucket != nil {
+				bucket.ClearLoader()
+			}
+		}
+	}()
+	for i := range buckets {
+		bucket, err := point_loader.NewSpillLoader(path.Join(tree.outOfCore.spillFolder, fmt.Sprintf("%s-%d.bin", node.nodeNID, i)))
+		if err != nil {
+			return err
+		}
+		buckets[i] = bucket
+	}
+
+	node.spillBuckets = &buckets
+	tree.loadPoints(node, loader)
+	err := loader.Err()
+	loader.ClearLoader()
+	node.spillBuckets = nil
+	for _, bucket := range buckets {
+		if err == nil {
+			err = bucket.Err()
+		}
+	}
+	if err != nil {
+		return err
+	}
+	node.BuildPoints()
+
+	if node.IsLeaf() {
+		// no point was pushed out, the node is complete
+		return tree.finalizeSubtree(node, maxPointsNum, minPointsNum, export)
+	}
+
+	for i, bucket := range buckets {
+		if bucket.NumberOfPoints() == 0 {
+			bucket.ClearLoader()
+			continue
+		}
+		if err := tree.buildNodeOutOfCore(node.children[i], bucket, maxPointsNum, minPointsNum, export); err != nil {
+			return err
+		}
+	}
+
+	// the children are complete once merged together
+	if err := node.mergeChildren(int64(minPointsNum)); err != nil {
+		return err
+	}
+
+	var children []*GridNode
+	for _, child := range node.children {
+		if child != nil && child.IsChildrenInitialized() && child.NumberOfPoints() > 0 {
+			children = append(children, child)
+		}
+	}
+
+	return exportAndReleaseNodes(children, export)
+}
+
+// Splits the big nodes and merges the small ones of a subtree held in memory, then exports all the nodes of the
+// subtree but its root
+func (tree *GridTree) finalizeSubtree(node *GridNode, maxPointsNum int32, minPointsNum int32, export func(nodes []*GridNode) error) error {
+	var err error
+	if node.IsRoot() {
+		err = node.SplitBigNode(maxPointsNum)
+	} else if node.IsLeaf() {
+		err = node.SplitBigLeafNode(maxPointsNum)
+	} else {
+		err = node.SplitBigBranchNode(maxPointsNum)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := node.MergeSmallChildren(int64(minPointsNum)); err != nil {
+		return err
+	}
+
+	var descendants []*GridNode
+	for _, child := range node.children {
+		if child != nil && child.IsChildrenInitialized() {
+			descendants = appendSubtreeNodes(descendants, child)
+		}
+	}
+
+	return exportAndReleaseNodes(descendants, export)
+}
+
+// Loads all the points of the loader into the given node using a goroutine per CPU
+func (tree *GridTree) loadPoints(node *GridNode, loader point_loader.Loader) {
+	var wg sync.WaitGroup
+	tree.launchParallelPointLoaders(&wg, loader, node)
+	wg.Wait()
+}
+
+// Appends the given node, if it contains points, and all its descendants to the list, following the same order
+// used when the whole tree is exported
+func appendSubtreeNodes(nodes []*GridNode, node *GridNode) []*GridNode {
+	if node.NumberOfPoints() > 0 {
+		nodes = append(nodes, node)
+	}
+	for _, child := range node.children {
+		if child != nil && child.IsChildrenInitialized() {
+			nodes = appendSubtreeNodes(nodes, child)
+		}
+	}
+	return nodes
+}
+
+func exportAndReleaseNodes(nodes []*GridNode, export func(nodes []*GridNode) error) error {
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	if err := export(nodes); err != nil {
+		return err
+	}
+
+	for _, node := range nodes {
+		node.ReleasePoints()
+	}
+
+	return nil
+}
@@ -0,0 +1,87 @@
+package octree
+
+import (
+	"errors"
+
+	"github.com/ecopia-map/cesium_tiler/internal/converters"
+	"github.com/ecopia-map/cesium_tiler/internal/data"
+	"github.com/ecopia-map/cesium_tiler/internal/geometry"
+)
+
+// Tight bounding volumes, in ECEF coordinates, of the points of a node and of all its descendants
+type NodeBoundingVolume struct {
+	Box    *geometry.OrientedBoundingBox
+	Sphere *geometry.BoundingSphere
+}
+
+// Computes the tight bounding box and sphere of the points of the node and of its descendants, whose volumes are
+// obtained from their GetTightBoundingVolume method so that the nodes can cache them. If includeParentPoints is true
+// the points of the ancestors falling in the node bounding box, which are part of the node content with the REPLACE
+// refine mode, are enclosed too
+func ComputeTightBoundingVolume(node INode, converter converters.CoordinateConverter, includeParentPoints bool) (*NodeBoundingVolume, error) {
+	points := node.GetPoints()
+	if includeParentPoints {
+		points = node.AppendParentPoints(points)
+	}
+
+	ecefPoints := make([]geometry.Coordinate, 0, len(points))
+	for _, point := range points {
+		ecefPoint, err := converter.ConvertToWGS84Cartesian(geometry.Coordinate{X: point.X, Y: point.Y, Z: point.Z}, node.GetInternalSrid())
+		if err != nil {
+			return nil, err
+		}
+		ecefPoints = append(ecefPoints, ecefPoint)
+	}
+	ownPoints := len(ecefPoints)
+
+	var childVolumes []*NodeBoundingVolume
+	for _, child := range node.GetChildren() {
+		if child == nil || child.TotalNumberOfPoints() == 0 {
+			continue
+		}
+		childVolume, err := child.GetTightBoundingVolume(converter, includeParentPoints)
+		if err != nil {
+			return nil, err
+		}
+		childVolumes = append(childVolumes, childVolume)
+		ecefPoints = append(ecefPoints, childVolume.Box.GetCorners()...)
+	}
+
+	if len(ecefPoints) == 0 {
+		return nil, errors.New("cannot compute the bounding volume of a node without points")
+	}
+
+	box := geometry.NewOrientedBoundingBoxFromPoints(ecefPoints)
+	sphere := geometry.NewBoundingSphereFromPoints(box.Center, ecefPoints[:ownPoints])
+	for _, childVolume := range childVolumes {
+		sphere = sphere.Enclose(childVolume.Sphere)
+	}
+
+	return &NodeBoundingVolume{
+		Box:    box,
+		Sphere: sphere,
+	}, nil
+}
+
+// Appends to the given points the points of the ancestors of the node falling in the node bounding box
+func AppendParentPoints(node INode, points []*data.Point) []*data.Point {
+	boundingBox := node.GetBoundingBox()
+	isContained := func(point *data.Point) bool {
+		if point.X >= boundingBox.Xmin && point.X <= boundingBox.Xmax &&
+			point.Y >= boundingBox.Ymin && point.Y <= boundingBox.Ymax &&
+			point.Z >= boundingBox.Zmin && point.Z <= boundingBox.Zmax {
+			return true
+		}
+		return false
+	}
+
+	for parent := node.GetParent(); parent != nil; parent = parent.GetParent() {
+		for _, point := range parent.GetPoints() {
+			if isContained(point) {
+				points = append(points, point)
+			}
+		}
+	}
+
+	return points
+}
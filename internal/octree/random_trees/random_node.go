@@ -3,23 +3,25 @@ package random_trees
 import "C"
 import (
 	"math"
+	"strconv"
 	"sync"
 	"sync/atomic"
 
 	"github.com/ecopia-map/cesium_tiler/internal/converters"
-	"github.com/ecopia-map/cesium_tiler/internal/converters/coordinate/proj4_coordinate_converter"
 	"github.com/ecopia-map/cesium_tiler/internal/data"
 	"github.com/ecopia-map/cesium_tiler/internal/geometry"
 	"github.com/ecopia-map/cesium_tiler/internal/octree"
 	"github.com/ecopia-map/cesium_tiler/internal/tiler"
 )
 
+const toRadians = math.Pi / 180
+
 // Models a node of the octree, which can either be a leaf (a node without children nodes) or not. Each Node can contain
 // up to eight children nodes
 type RandomNode struct {
-	parent              octree.INode
+	parent              *RandomNode
 	boundingBox         *geometry.BoundingBox
-	children            [8]octree.INode
+	children            [8]*RandomNode
 	points              []*data.Point
 	internalSrid        int
 	totalNumberOfPoints int64
@@ -27,11 +29,13 @@ type RandomNode struct {
 	tilerOptions        *tiler.TilerOptions
 	leaf                bool
 	initialized         bool
+	tightBoundingVolume *octree.NodeBoundingVolume
+	volumeLock          sync.Mutex
 	sync.RWMutex
 }
 
 // Instantiates a new RandomNode
-func NewRandomNode(boundingBox *geometry.BoundingBox, opts *tiler.TilerOptions, parent octree.INode) octree.INode {
+func NewRandomNode(boundingBox *geometry.BoundingBox, opts *tiler.TilerOptions, parent *RandomNode) *RandomNode {
 	node := RandomNode{
 		parent:              parent,
 		boundingBox:         boundingBox,
@@ -48,33 +52,34 @@ func NewRandomNode(boundingBox *geometry.BoundingBox, opts *tiler.TilerOptions,
 
 // Adds a Point to the RandomNode eventually propagating it to the RandomNode relevant children
 func (n *RandomNode) AddDataPoint(element *data.Point) {
-	if atomic.LoadInt32(&n.numberOfPoints) == 0 {
-		n.Lock()
+	// the check of the number of points and the append must happen under the same lock, otherwise concurrent
+	// goroutines could store more than MaxNumPointsPerNode points
+	n.Lock()
+	if !n.initialized {
 		for i := uint8(0); i < 8; i++ {
-			if n.children[i] == nil {
-				n.children[i] = NewRandomNode(getOctantBoundingBox(&i, n.boundingBox), n.tilerOptions, n)
-			}
+			n.children[i] = NewRandomNode(getOctantBoundingBox(&i, n.boundingBox), n.tilerOptions, n)
 		}
 		n.initialized = true
-		n.Unlock()
 	}
-	if atomic.LoadInt32(&n.numberOfPoints) < n.tilerOptions.MaxNumPointsPerNode {
-		n.Lock()
+	isStored := n.numberOfPoints < n.tilerOptions.MaxNumPointsPerNode
+	if isStored {
 		n.points = append(n.points, element)
 		atomic.AddInt32(&n.numberOfPoints, 1)
-		n.Unlock()
 	} else {
+		n.leaf = false
+	}
+	n.Unlock()
+
+	if !isStored {
 		n.children[getOctantFromElement(element, n.boundingBox)].AddDataPoint(element)
-		if n.leaf {
-			n.Lock()
-			n.leaf = false
-			n.Unlock()
-		}
 	}
 	atomic.AddInt64(&n.totalNumberOfPoints, 1)
 }
 
 func (n *RandomNode) GetParent() octree.INode {
+	if n.parent == nil {
+		return nil
+	}
 	return n.parent
 }
 
@@ -97,7 +102,33 @@ func (n *RandomNode) GetBoundingBox() *geometry.BoundingBox {
 }
 
 func (n *RandomNode) GetChildren() [8]octree.INode {
-	return n.children
+	var children [8]octree.INode
+	for i, child := range n.children {
+		if child != nil {
+			children[i] = child
+		}
+	}
+	return children
+}
+
+// Returns the octant index of each child, as the children of a RandomNode are never merged
+func (n *RandomNode) GetChildrenPath() [8]string {
+	var childrenPath [8]string
+	for i := range childrenPath {
+		childrenPath[i] = strconv.Itoa(i)
+	}
+	return childrenPath
+}
+
+// Returns the children of the node containing points, the empty octants are returned as nil
+func (n *RandomNode) GetOctreeChildren() [8]octree.INode {
+	var octreeChildren [8]octree.INode
+	for i, child := range n.children {
+		if child != nil && child.TotalNumberOfPoints() > 0 {
+			octreeChildren[i] = child
+		}
+	}
+	return octreeChildren
 }
 
 func (n *RandomNode) GetPoints() []*data.Point {
@@ -120,10 +151,34 @@ func (n *RandomNode) IsRoot() bool {
 	return n.parent == nil
 }
 
-func (n *RandomNode) IsInitialized() bool {
+func (n *RandomNode) IsChildrenInitialized() bool {
 	return n.initialized
 }
 
+// Appends to the given points the points of the ancestors of the node falling in the node bounding box
+func (n *RandomNode) AppendParentPoints(points []*data.Point) []*data.Point {
+	return octree.AppendParentPoints(n, points)
+}
+
+// Returns the tight bounding box and sphere of the points of the node and of its descendants, computed once and
+// cached
+func (n *RandomNode) GetTightBoundingVolume(converter converters.CoordinateConverter, includeParentPoints bool) (*octree.NodeBoundingVolume, error) {
+	n.volumeLock.Lock()
+	defer n.volumeLock.Unlock()
+
+	if n.tightBoundingVolume != nil {
+		return n.tightBoundingVolume, nil
+	}
+
+	volume, err := octree.ComputeTightBoundingVolume(n, converter, includeParentPoints)
+	if err != nil {
+		return nil, err
+	}
+	n.tightBoundingVolume = volume
+
+	return n.tightBoundingVolume, nil
+}
+
 // Returns the index of the octant that contains the given Point within this boundingBox
 func getOctantFromElement(element *data.Point, bbox *geometry.BoundingBox) uint8 {
 	var result uint8 = 0
@@ -153,14 +208,12 @@ func (n *RandomNode) ComputeGeometricError() float64 {
 	return n.estimateErrorAsDensityDifference()
 }
 
+// The bounding box of the node is expressed in the EPSG:4326 internal srid, its corners are only converted to radians
 func (n *RandomNode) estimateErrorAsBoundingBoxDiagonal() float64 {
-	regionBox, _ := proj4_coordinate_converter.NewProj4CoordinateConverter().Convert2DBoundingboxToWGS84Region(n.boundingBox, n.GetInternalSrid())
-	region := regionBox.GetAsArray()
-	var latA = region[1]
-	var latB = region[3]
-	var lngA = region[0]
-	var lngB = region[2]
-	latA = region[1]
+	var latA = n.boundingBox.Ymin * toRadians
+	var latB = n.boundingBox.Ymax * toRadians
+	var lngA = n.boundingBox.Xmin * toRadians
+	var lngB = n.boundingBox.Xmax * toRadians
 	return 6371000 * math.Acos(math.Cos(latA)*math.Cos(latB)*math.Cos(lngB-lngA)+math.Sin(latA)*math.Sin(latB))
 }
 
@@ -174,7 +227,7 @@ func (n *RandomNode) estimateErrorAsDensityDifference() float64 {
 				totalRenderedPoints++
 			}
 		}
-		parent = parent.(*RandomNode).parent
+		parent = parent.GetParent()
 	}
 	densityWithAllPoints := math.Pow(volume/float64(totalRenderedPoints+n.TotalNumberOfPoints()-int64(n.NumberOfPoints())), 0.333)
 	densityWithOnlyThisTile := math.Pow(volume/float64(totalRenderedPoints), 0.333)
@@ -2,7 +2,7 @@ package random_trees
 
 import (
 	"errors"
-	"log"
+	"fmt"
 	"runtime"
 	"sync"
 
@@ -12,17 +12,21 @@ import (
 	"github.com/ecopia-map/cesium_tiler/internal/octree"
 	"github.com/ecopia-map/cesium_tiler/internal/point_loader"
 	"github.com/ecopia-map/cesium_tiler/internal/tiler"
+	"github.com/ecopia-map/cesium_tiler/tools"
 )
 
 // Represents an RandomTree of points and contains all information needed
 // to propagate points in the tree
 type RandomTree struct {
-	rootNode            octree.INode
+	rootNode            *RandomNode
 	built               bool
 	opts                *tiler.TilerOptions
 	coordinateConverter converters.CoordinateConverter
 	elevationCorrector  converters.ElevationCorrector
 	point_loader.Loader
+
+	// number of goroutines loading points into the tree, 0 uses one per CPU
+	numWorkers int
 }
 
 // Builds an empty RandomTree initializing its properties to the correct defaults
@@ -83,8 +87,16 @@ func (t *RandomTree) clear() {
 	t.Loader.ClearLoader()
 }
 
+// Sets the number of goroutines loading points into the tree, 0 uses one per CPU
+func (t *RandomTree) SetNumWorkers(numWorkers int) {
+	t.numWorkers = numWorkers
+}
+
 func (t *RandomTree) launchParallelPointLoaders(waitGroup *sync.WaitGroup) {
-	N := runtime.NumCPU()
+	N := t.numWorkers
+	if N <= 0 {
+		N = runtime.NumCPU()
+	}
 
 	for i := 0; i < N; i++ {
 		waitGroup.Add(1)
@@ -106,6 +118,9 @@ func (t *RandomTree) launchPointLoader(waitGroup *sync.WaitGroup) {
 }
 
 func (t *RandomTree) GetRootNode() octree.INode {
+	if t.rootNode == nil {
+		return nil
+	}
 	return t.rootNode
 }
 
@@ -113,15 +128,51 @@ func (t *RandomTree) IsBuilt() bool {
 	return t.built
 }
 
-func (t *RandomTree) AddPoint(coordinate *geometry.Coordinate, r uint8, g uint8, b uint8, intensity uint8, classification uint8, srid int, pointExtend *data.PointExtend) {
-	t.Loader.AddPoint(t.getPointFromRawData(coordinate, r, g, b, intensity, classification, srid, pointExtend))
+// Converts the given point to the internal srid and adds it to the tree. An error is returned if the point cannot be
+// converted
+func (t *RandomTree) AddPoint(coordinate *geometry.Coordinate, r uint8, g uint8, b uint8, intensity uint16, classification uint8, srid int, pointExtend *data.PointExtend) error {
+	point, err := t.getPointFromRawData(coordinate, r, g, b, intensity, classification, srid, pointExtend)
+	if err != nil {
+		return err
+	}
+
+	t.Loader.AddPoint(point)
+	return nil
 }
 
-func (t *RandomTree) getPointFromRawData(coordinate *geometry.Coordinate, r uint8, g uint8, b uint8, intensity uint8, classification uint8, srid int, pointExtend *data.PointExtend) *data.Point {
+// Converts the given points to the internal srid, with a batch conversion for all of them, and adds them to the tree.
+// An error is returned if any point cannot be converted
+func (t *RandomTree) AddPoints(points []*data.Point, srid int) error {
+	coords := make([]geometry.Coordinate, len(points))
+	for i, point := range points {
+		coords[i] = geometry.Coordinate{X: point.X, Y: point.Y, Z: point.Z}
+	}
+
+	if err := t.coordinateConverter.ConvertCoordinatesSrid(srid, 4326, coords); err != nil {
+		return fmt.Errorf("%v. srid:[%d]", err, srid)
+	}
+
+	for i, point := range points {
+		z, err := t.elevationCorrector.CorrectElevation(coords[i].X, coords[i].Y, coords[i].Z)
+		if err != nil {
+			return fmt.Errorf("%v. srid:[%d]", err, srid)
+		}
+		point.X, point.Y, point.Z = coords[i].X, coords[i].Y, z
+		t.Loader.AddPoint(point)
+	}
+	return nil
+}
+
+func (t *RandomTree) getPointFromRawData(coordinate *geometry.Coordinate, r uint8, g uint8, b uint8, intensity uint16, classification uint8, srid int, pointExtend *data.PointExtend) (*data.Point, error) {
 	tr, err := t.coordinateConverter.ConvertCoordinateSrid(srid, 4326, *coordinate)
 	if err != nil {
-		glog.Fatal(err)
+		return nil, fmt.Errorf("%v. srid:[%d] coordinate:[%s]", err, srid, tools.FmtJSONString(coordinate))
+	}
+
+	z, err := t.elevationCorrector.CorrectElevation(tr.X, tr.Y, tr.Z)
+	if err != nil {
+		return nil, fmt.Errorf("%v. srid:[%d] coordinate:[%s]", err, srid, tools.FmtJSONString(coordinate))
 	}
 
-	return data.NewPoint(tr.X, tr.Y, t.elevationCorrector.CorrectElevation(tr.X, tr.Y, tr.Z), r, g, b, intensity, classification, pointExtend)
+	return data.NewPoint(tr.X, tr.Y, z, r, g, b, intensity, classification, pointExtend), nil
 }
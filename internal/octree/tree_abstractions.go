@@ -11,17 +11,30 @@ type ITree interface {
 	GetRootNode() INode
 	IsBuilt() bool
 	Clear() bool
-	// Adds a Point to the Tree
-	AddPoint(coordinate *geometry.Coordinate, r uint8, g uint8, b uint8, intensity uint8, classification uint8, srid int, pointExtend *data.PointExtend)
+	// Adds a Point to the Tree, returns an error if the point cannot be converted to the internal srid of the tree
+	AddPoint(coordinate *geometry.Coordinate, r uint8, g uint8, b uint8, intensity uint16, classification uint8, srid int, pointExtend *data.PointExtend) error
+	// Adds to the Tree the given points, whose coordinates are in the given srid and are converted in place to the
+	// internal srid of the tree with the batch methods of the coordinate converter. Returns an error if any point cannot
+	// be converted
+	AddPoints(points []*data.Point, srid int) error
+	// Sets the number of goroutines loading points into the tree, 0 uses one per CPU
+	SetNumWorkers(numWorkers int)
 }
 
 type INode interface {
-	AddDataPoint(element *data.Point)
 	GetInternalSrid() int
 	IsRoot() bool
 	GetBoundingBoxRegion(converter converters.CoordinateConverter) (*geometry.BoundingBox, error)
 	GetChildren() [8]INode
+	// Returns the names of the folders of the children, which differ from the octant index if a child stores the
+	// points of several octants
+	GetChildrenPath() [8]string
+	// Returns the children placed at their octant, each of them covering exactly the bounding box of its octant
+	GetOctreeChildren() [8]INode
 	GetPoints() []*data.Point
+	// Appends to the given points the points of the ancestors of the node falling in the node bounding box
+	AppendParentPoints(points []*data.Point) []*data.Point
+	GetTightBoundingVolume(converter converters.CoordinateConverter, includeParentPoints bool) (*NodeBoundingVolume, error)
 	TotalNumberOfPoints() int64
 	NumberOfPoints() int32
 	IsLeaf() bool
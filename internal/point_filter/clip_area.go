@@ -0,0 +1,333 @@
+package point_filter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/ecopia-map/cesium_tiler/internal/converters"
+	"github.com/ecopia-map/cesium_tiler/internal/geometry"
+)
+
+// Area made of polygons, each one made of an outer ring and of optional holes. Rings are lists of x, y vertices
+type ClipArea struct {
+	Polygons [][][][2]float64
+
+	bounds [][4]float64 // xmin, ymin, xmax, ymax of each polygon
+}
+
+// Reads the clip area from the given GeoJSON or WKT file. GeoJSON Polygon and MultiPolygon geometries are read,
+// also when wrapped in features, feature collections or geometry collections. WKT POLYGON and MULTIPOLYGON are read
+func LoadClipArea(filePath string) (*ClipArea, error) {
+	content, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	text := strings.TrimSpace(string(content))
+	var area *ClipArea
+	if strings.HasPrefix(text, "{") {
+		area, err = ParseGeoJsonClipArea(content)
+	} else {
+		area, err = ParseWktClipArea(text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("clip polygon [%s]: %v", filePath, err)
+	}
+
+	return area, nil
+}
+
+// Parses the polygons of the given GeoJSON object
+func ParseGeoJsonClipArea(content []byte) (*ClipArea, error) {
+	var object geoJsonObject
+	if err := json.Unmarshal(content, &object); err != nil {
+		return nil, err
+	}
+
+	area := &ClipArea{}
+	if err := area.addGeoJsonObject(&object); err != nil {
+		return nil, err
+	}
+
+	return area.validate()
+}
+
+// Parses the polygons of the given WKT POLYGON or MULTIPOLYGON
+func ParseWktClipArea(text string) (*ClipArea, error) {
+	text = strings.TrimSpace(text)
+	// EWKT srid prefix, the srid of the clip area is given by the options
+	if strings.HasPrefix(strings.ToUpper(text), "SRID=") {
+		if i := strings.Index(text, ";"); i >= 0 {
+			text = text[i+1:]
+		}
+	}
+
+	open := strings.Index(text, "(")
+	if open < 0 {
+		return nil, errors.New("invalid WKT, missing coordinates")
+	}
+	keyword := strings.ToUpper(strings.Fields(text[:open] + " ")[0])
+
+	parser := &wktParser{text: text, pos: open}
+	node, err := parser.parseNode()
+	if err != nil {
+		return nil, err
+	}
+	parser.skipSpaces()
+	if parser.pos != len(parser.text) {
+		return nil, fmt.Errorf("invalid WKT, unexpected text at position %d", parser.pos)
+	}
+
+	area := &ClipArea{}
+	switch keyword {
+	case "POLYGON":
+		polygon, err := node.polygon()
+		if err != nil {
+			return nil, err
+		}
+		area.Polygons = append(area.Polygons, polygon)
+	case "MULTIPOLYGON":
+		for _, child := range node.children {
+			polygon, err := child.polygon()
+			if err != nil {
+				return nil, err
+			}
+			area.Polygons = append(area.Polygons, polygon)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported WKT geometry [%s], expected POLYGON or MULTIPOLYGON", keyword)
+	}
+
+	return area.validate()
+}
+
+// Converts the vertices of the area from the source srid to the target one
+func (a *ClipArea) Reproject(sourceSrid int, targetSrid int, converter converters.CoordinateConverter) error {
+	if sourceSrid != targetSrid {
+		for _, polygon := range a.Polygons {
+			for _, ring := range polygon {
+				for i, vertex := range ring {
+					converted, err := converter.ConvertCoordinateSrid(sourceSrid, targetSrid, geometry.Coordinate{X: vertex[0], Y: vertex[1]})
+					if err != nil {
+						return fmt.Errorf("unable to convert the clip area from srid %d to srid %d: %v", sourceSrid, targetSrid, err)
+					}
+					ring[i] = [2]float64{converted.X, converted.Y}
+				}
+			}
+		}
+	}
+	a.computeBounds()
+	return nil
+}
+
+// Returns true if the given point is inside one of the polygons of the area. Holes are handled with the even-odd rule
+func (a *ClipArea) Contains(x float64, y float64) bool {
+	for i, polygon := range a.Polygons {
+		// bounds are computed when the area is parsed or reprojected, the area is never modified while it is used
+		if i < len(a.bounds) {
+			bounds := a.bounds[i]
+			if x < bounds[0] || y < bounds[1] || x > bounds[2] || y > bounds[3] {
+				continue
+			}
+		}
+
+		inside := false
+		for _, ring := range polygon {
+			for j, k := 0, len(ring)-1; j < len(ring); k, j = j, j+1 {
+				xj, yj, xk, yk := ring[j][0], ring[j][1], ring[k][0], ring[k][1]
+				if (yj > y) != (yk > y) && x < (xk-xj)*(y-yj)/(yk-yj)+xj {
+					inside = !inside
+				}
+			}
+		}
+		if inside {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (a *ClipArea) computeBounds() {
+	a.bounds = make([][4]float64, len(a.Polygons))
+	for i, polygon := range a.Polygons {
+		bounds := [4]float64{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+		// the outer ring contains the holes
+		for _, vertex := range polygon[0] {
+			bounds[0] = math.Min(bounds[0], vertex[0])
+			bounds[1] = math.Min(bounds[1], vertex[1])
+			bounds[2] = math.Max(bounds[2], vertex[0])
+			bounds[3] = math.Max(bounds[3], vertex[1])
+		}
+		a.bounds[i] = bounds
+	}
+}
+
+func (a *ClipArea) validate() (*ClipArea, error) {
+	if len(a.Polygons) == 0 {
+		return nil, errors.New("no polygon found")
+	}
+	for _, polygon := range a.Polygons {
+		if len(polygon) == 0 {
+			return nil, errors.New("polygon without rings")
+		}
+		for _, ring := range polygon {
+			if len(ring) < 3 {
+				return nil, fmt.Errorf("polygon ring must have at least 3 vertices, found %d", len(ring))
+			}
+		}
+	}
+	a.computeBounds()
+	return a, nil
+}
+
+// Subset of the GeoJSON objects holding polygons
+type geoJsonObject struct {
+	Type        string           `json:"type"`
+	Coordinates json.RawMessage  `json:"coordinates"`
+	Geometry    *geoJsonObject   `json:"geometry"`
+	Geometries  []*geoJsonObject `json:"geometries"`
+	Features    []*geoJsonObject `json:"features"`
+}
+
+func (a *ClipArea) addGeoJsonObject(object *geoJsonObject) error {
+	switch object.Type {
+	case "FeatureCollection":
+		for _, feature := range object.Features {
+			if err := a.addGeoJsonObject(feature); err != nil {
+				return err
+			}
+		}
+	case "Feature":
+		if object.Geometry != nil {
+			return a.addGeoJsonObject(object.Geometry)
+		}
+	case "GeometryCollection":
+		for _, geometry := range object.Geometries {
+			if err := a.addGeoJsonObject(geometry); err != nil {
+				return err
+			}
+		}
+	case "Polygon":
+		var polygon [][][]float64
+		if err := json.Unmarshal(object.Coordinates, &polygon); err != nil {
+			return err
+		}
+		return a.addGeoJsonPolygon(polygon)
+	case "MultiPolygon":
+		var polygons [][][][]float64
+		if err := json.Unmarshal(object.Coordinates, &polygons); err != nil {
+			return err
+		}
+		for _, polygon := range polygons {
+			if err := a.addGeoJsonPolygon(polygon); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported GeoJSON type [%s], expected Polygon or MultiPolygon", object.Type)
+	}
+	return nil
+}
+
+func (a *ClipArea) addGeoJsonPolygon(polygon [][][]float64) error {
+	rings := make([][][2]float64, 0, len(polygon))
+	for _, positions := range polygon {
+		ring := make([][2]float64, 0, len(positions))
+		for _, position := range positions {
+			if len(position) < 2 {
+				return errors.New("GeoJSON position must have at least 2 values")
+			}
+			ring = append(ring, [2]float64{position[0], position[1]})
+		}
+		rings = append(rings, ring)
+	}
+	a.Polygons = append(a.Polygons, rings)
+	return nil
+}
+
+// Node of a WKT coordinate list: either a coordinate or a parenthesized list of nodes
+type wktNode struct {
+	coordinate []float64
+	children   []*wktNode
+}
+
+func (n *wktNode) polygon() ([][][2]float64, error) {
+	rings := make([][][2]float64, 0, len(n.children))
+	for _, ringNode := range n.children {
+		ring := make([][2]float64, 0, len(ringNode.children))
+		for _, vertex := range ringNode.children {
+			if len(vertex.coordinate) < 2 {
+				return nil, errors.New("invalid WKT polygon, expected a list of rings of coordinates")
+			}
+			ring = append(ring, [2]float64{vertex.coordinate[0], vertex.coordinate[1]})
+		}
+		rings = append(rings, ring)
+	}
+	return rings, nil
+}
+
+type wktParser struct {
+	text string
+	pos  int
+}
+
+func (p *wktParser) skipSpaces() {
+	for p.pos < len(p.text) && strings.ContainsRune(" \t\r\n", rune(p.text[p.pos])) {
+		p.pos++
+	}
+}
+
+// Parses a parenthesized list or a coordinate starting at the current position
+func (p *wktParser) parseNode() (*wktNode, error) {
+	p.skipSpaces()
+	if p.pos >= len(p.text) {
+		return nil, errors.New("invalid WKT, unexpected end of text")
+	}
+
+	if p.text[p.pos] != '(' {
+		end := p.pos
+		for end < len(p.text) && !strings.ContainsRune(",()", rune(p.text[end])) {
+			end++
+		}
+		var coordinate []float64
+		for _, field := range strings.Fields(p.text[p.pos:end]) {
+			value, err := strconv.ParseFloat(field, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid WKT coordinate [%s]", field)
+			}
+			coordinate = append(coordinate, value)
+		}
+		p.pos = end
+		return &wktNode{coordinate: coordinate}, nil
+	}
+
+	p.pos++
+	node := &wktNode{}
+	for {
+		child, err := p.parseNode()
+		if err != nil {
+			return nil, err
+		}
+		node.children = append(node.children, child)
+
+		p.skipSpaces()
+		if p.pos >= len(p.text) {
+			return nil, errors.New("invalid WKT, unbalanced parentheses")
+		}
+		switch p.text[p.pos] {
+		case ',':
+			p.pos++
+		case ')':
+			p.pos++
+			return node, nil
+		default:
+			return nil, fmt.Errorf("invalid WKT, unexpected character at position %d", p.pos)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package point_filter
+
+import (
+	"context"
+	"math"
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/ecopia-map/cesium_tiler/internal/tiler"
+)
+
+// Number of points processed by a goroutine between two checks of the context
+const outlierCancelCheckPoints = 10000
+
+// Returns the squared distances from the point of the given index to its neighbours within the search radius of the
+// filter, excluding the point itself
+type NeighbourSearch func(index int) []float64
+
+// Finds the noise points, such as birds or multipath returns, which the grid sampler would otherwise keep in the
+// coarse tiles. A point is a statistical outlier if the mean distance to its nearest neighbours exceeds the mean of
+// all the points by more than the given number of standard deviations, and a radius outlier if it has too few
+// neighbours within the given radius. Safe for concurrent use as it is never modified after its creation
+type OutlierFilter struct {
+	sorNeighbors int
+	sorSigma     float64
+	sorRadius    float64
+	rorNeighbors int
+	rorRadius    float64
+}
+
+// Creates the filter described by the given options. Returns nil if both the statistical and the radius outlier
+// removal are disabled, so that no time is spent searching the neighbours
+func NewOutlierFilter(opts *tiler.TilerOutlierOptions) *OutlierFilter {
+	if !IsOutlierFilterEnabled(opts) {
+		return nil
+	}
+
+	return &OutlierFilter{
+		sorNeighbors: opts.SorNeighbors,
+		sorSigma:     opts.SorSigma,
+		sorRadius:    opts.SorRadius,
+		rorNeighbors: opts.RorNeighbors,
+		rorRadius:    opts.RorRadius,
+	}
+}
+
+// Returns true if the options remove any outlier
+func IsOutlierFilterEnabled(opts *tiler.TilerOutlierOptions) bool {
+	return opts.SorNeighbors > 0 || opts.RorNeighbors > 0
+}
+
+// Returns the radius the neighbours of the points must be searched within
+func (f *OutlierFilter) SearchRadius() float64 {
+	radius := 0.0
+	if f.sorNeighbors > 0 {
+		radius = f.sorRadius
+	}
+	if f.rorNeighbors > 0 && f.rorRadius > radius {
+		radius = f.rorRadius
+	}
+	return radius
+}
+
+// Returns the flags of the outliers among the given number of points, whose neighbours are found with the given
+// search. The points are split among the given number of goroutines, 0 uses one per CPU
+func (f *OutlierFilter) FindOutliers(ctx context.Context, numPoints int, numWorkers int, search NeighbourSearch) ([]bool, error) {
+	outliers := make([]bool, numPoints)
+	var meanDistances []float64
+	if f.sorNeighbors > 0 {
+		meanDistances = make([]float64, numPoints)
+	}
+
+	err := forEachPointBlock(ctx, numPoints, numWorkers, func(index int) {
+		squaredDistances := search(index)
+		if f.rorNeighbors > 0 && f.isRadiusOutlier(squaredDistances) {
+			outliers[index] = true
+		}
+		if f.sorNeighbors > 0 {
+			meanDistances[index] = f.meanNeighbourDistance(squaredDistances)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if f.sorNeighbors > 0 {
+		threshold := f.statisticalThreshold(meanDistances)
+		for i, meanDistance := range meanDistances {
+			if meanDistance > threshold {
+				outliers[i] = true
+			}
+		}
+	}
+
+	return outliers, nil
+}
+
+// Returns true if less than rorNeighbors neighbours are within rorRadius
+func (f *OutlierFilter) isRadiusOutlier(squaredDistances []float64) bool {
+	radiusSquared := f.rorRadius * f.rorRadius
+	numNeighbors := 0
+	for _, squaredDistance := range squaredDistances {
+		if squaredDistance <= radiusSquared {
+			numNeighbors++
+			if numNeighbors >= f.rorNeighbors {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Returns the mean distance of the sorNeighbors nearest neighbours. The neighbours missing within the search radius
+// are counted at sorRadius, as they are at least that far
+func (f *OutlierFilter) meanNeighbourDistance(squaredDistances []float64) float64 {
+	radiusSquared := f.sorRadius * f.sorRadius
+	nearest := make([]float64, 0, len(squaredDistances))
+	for _, squaredDistance := range squaredDistances {
+		if squaredDistance <= radiusSquared {
+			nearest = append(nearest, squaredDistance)
+		}
+	}
+	sort.Float64s(nearest)
+	if len(nearest) > f.sorNeighbors {
+		nearest = nearest[:f.sorNeighbors]
+	}
+
+	sum := float64(f.sorNeighbors-len(nearest)) * f.sorRadius
+	for _, squaredDistance := range nearest {
+		sum += math.Sqrt(squaredDistance)
+	}
+	return sum / float64(f.sorNeighbors)
+}
+
+// Returns the mean of the given mean neighbour distances plus sorSigma times their standard deviation
+func (f *OutlierFilter) statisticalThreshold(meanDistances []float64) float64 {
+	if len(meanDistances) == 0 {
+		return 0
+	}
+
+	var sum float64
+	for _, meanDistance := range meanDistances {
+		sum += meanDistance
+	}
+	mean := sum / float64(len(meanDistances))
+
+	var squaredDeviations float64
+	for _, meanDistance := range meanDistances {
+		squaredDeviations += (meanDistance - mean) * (meanDistance - mean)
+	}
+	stdDev := math.Sqrt(squaredDeviations / float64(len(meanDistances)))
+
+	return mean + f.sorSigma*stdDev
+}
+
+// Calls the given function for every point index, splitting the points into a block per goroutine. The goroutines
+// stop with the context error if the context is canceled
+func forEachPointBlock(ctx context.Context, numPoints int, numWorkers int, process func(index int)) error {
+	if numWorkers <= 0 {
+		numWorkers = runtime.NumCPU()
+	}
+	blockSize := (numPoints + numWorkers - 1) / numWorkers
+	if blockSize == 0 {
+		blockSize = 1
+	}
+
+	var waitGroup sync.WaitGroup
+	for start := 0; start < numPoints; start += blockSize {
+		end := start + blockSize
+		if end > numPoints {
+			end = numPoints
+		}
+		waitGroup.Add(1)
+		go func(start int, end int) {
+			defer waitGroup.Done()
+			for i := start; i < end; i++ {
+				if (i-start)%outlierCancelCheckPoints == 0 && ctx.Err() != nil {
+					return
+				}
+				process(i)
+			}
+		}(start, end)
+	}
+	waitGroup.Wait()
+
+	return ctx.Err()
+}
@@ -0,0 +1,180 @@
+package point_filter
+
+import (
+	"fmt"
+
+	"github.com/ecopia-map/cesium_tiler/internal/converters"
+	"github.com/ecopia-map/cesium_tiler/internal/geometry"
+	"github.com/ecopia-map/cesium_tiler/internal/tiler"
+)
+
+// Number of segments each edge of the clip box is split into when the box is reprojected, so that the edges follow
+// the curvature they have in the srid of the input points
+const clipBoxEdgeSegments = 32
+
+// Attributes of a las point record checked by the filter. Coordinates are in the srid of the input points
+type PointAttributes struct {
+	X               float64
+	Y               float64
+	Z               float64
+	Classification  uint8
+	ReturnNumber    uint8
+	NumberOfReturns uint8
+	Withheld        bool
+	Synthetic       bool
+}
+
+// Decides which points of the las files are loaded in the tree. Safe for concurrent use as it is never modified
+// after its creation
+type PointFilter struct {
+	includeClasses [256]bool
+	hasInclude     bool
+	excludeClasses [256]bool
+	returns        tiler.ReturnFilter
+	withheld       tiler.FlagFilter
+	synthetic      tiler.FlagFilter
+	zMin           *float64
+	zMax           *float64
+	clipBox        *geometry.BoundingBox // set if the clip box does not need to be reprojected
+	clipAreas      []*ClipArea           // areas, in the srid of the input points, the points must fall in
+}
+
+// Creates the filter described by the given options for points in the given srid. The clip box and polygon are
+// converted from their srid to the one of the points with the given converter.
+// Returns nil if the options keep all the points, so that no time is spent filtering
+func NewPointFilter(opts *tiler.TilerFilterOptions, srid int, converter converters.CoordinateConverter) (*PointFilter, error) {
+	if !IsFilterEnabled(opts) {
+		return nil, nil
+	}
+
+	filter := &PointFilter{
+		hasInclude: len(opts.IncludeClasses) > 0,
+		returns:    opts.Returns,
+		withheld:   opts.Withheld,
+		synthetic:  opts.Synthetic,
+		zMin:       opts.ZMin,
+		zMax:       opts.ZMax,
+	}
+	for _, class := range opts.IncludeClasses {
+		filter.includeClasses[class] = true
+	}
+	for _, class := range opts.ExcludeClasses {
+		filter.excludeClasses[class] = true
+	}
+
+	clipSrid := opts.ClipSrid
+	if clipSrid == 0 {
+		clipSrid = srid
+	}
+
+	// the points must fall in both the box and the polygon when they are given together
+	var areas []*ClipArea
+	if len(opts.ClipBox) > 0 {
+		if len(opts.ClipBox) != 4 {
+			return nil, fmt.Errorf("clip box must have 4 values, found %d", len(opts.ClipBox))
+		}
+		xMin, yMin, xMax, yMax := opts.ClipBox[0], opts.ClipBox[1], opts.ClipBox[2], opts.ClipBox[3]
+		if xMin > xMax || yMin > yMax {
+			return nil, fmt.Errorf("clip box min values must be lower than max values")
+		}
+		if clipSrid == srid {
+			filter.clipBox = geometry.NewBoundingBox(xMin, xMax, yMin, yMax, 0, 0)
+		} else {
+			areas = append(areas, newBoxClipArea(xMin, yMin, xMax, yMax))
+		}
+	}
+
+	if opts.ClipPolygon != "" {
+		area, err := LoadClipArea(opts.ClipPolygon)
+		if err != nil {
+			return nil, err
+		}
+		areas = append(areas, area)
+	}
+
+	for _, area := range areas {
+		if err := area.Reproject(clipSrid, srid, converter); err != nil {
+			return nil, err
+		}
+		filter.clipAreas = append(filter.clipAreas, area)
+	}
+
+	return filter, nil
+}
+
+// Returns true if the given options discard any point
+func IsFilterEnabled(opts *tiler.TilerFilterOptions) bool {
+	return len(opts.IncludeClasses) > 0 || len(opts.ExcludeClasses) > 0 ||
+		(opts.Returns != "" && opts.Returns != tiler.ReturnFilterAll) ||
+		(opts.Withheld != "" && opts.Withheld != tiler.FlagFilterKeep) ||
+		(opts.Synthetic != "" && opts.Synthetic != tiler.FlagFilterKeep) ||
+		opts.ZMin != nil || opts.ZMax != nil ||
+		len(opts.ClipBox) > 0 || opts.ClipPolygon != ""
+}
+
+// Returns true if the given point passes all the filters
+func (f *PointFilter) Accept(p *PointAttributes) bool {
+	if f.hasInclude && !f.includeClasses[p.Classification] {
+		return false
+	}
+	if f.excludeClasses[p.Classification] {
+		return false
+	}
+
+	switch f.returns {
+	case tiler.ReturnFilterFirst:
+		if p.ReturnNumber > 1 {
+			return false
+		}
+	case tiler.ReturnFilterLast:
+		if p.ReturnNumber < p.NumberOfReturns {
+			return false
+		}
+	}
+
+	if !acceptFlag(f.withheld, p.Withheld) || !acceptFlag(f.synthetic, p.Synthetic) {
+		return false
+	}
+
+	if (f.zMin != nil && p.Z < *f.zMin) || (f.zMax != nil && p.Z > *f.zMax) {
+		return false
+	}
+
+	if f.clipBox != nil && (p.X < f.clipBox.Xmin || p.X > f.clipBox.Xmax || p.Y < f.clipBox.Ymin || p.Y > f.clipBox.Ymax) {
+		return false
+	}
+
+	for _, area := range f.clipAreas {
+		if !area.Contains(p.X, p.Y) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func acceptFlag(filter tiler.FlagFilter, isSet bool) bool {
+	switch filter {
+	case tiler.FlagFilterDrop:
+		return !isSet
+	case tiler.FlagFilterOnly:
+		return isSet
+	}
+	return true
+}
+
+// Returns the clip area of the given box, with the edges split so that they can be reprojected
+func newBoxClipArea(xMin float64, yMin float64, xMax float64, yMax float64) *ClipArea {
+	corners := [][2]float64{{xMin, yMin}, {xMax, yMin}, {xMax, yMax}, {xMin, yMax}, {xMin, yMin}}
+	ring := make([][2]float64, 0, 4*clipBoxEdgeSegments+1)
+	for i := 0; i < 4; i++ {
+		start, end := corners[i], corners[i+1]
+		for s := 0; s < clipBoxEdgeSegments; s++ {
+			t := float64(s) / clipBoxEdgeSegments
+			ring = append(ring, [2]float64{start[0] + (end[0]-start[0])*t, start[1] + (end[1]-start[1])*t})
+		}
+	}
+	ring = append(ring, ring[0])
+
+	return &ClipArea{Polygons: [][][][2]float64{{ring}}}
+}
@@ -39,15 +39,16 @@ func (eb *RandomBoxLoader) AddPoint(e *data.Point) {
 	geoKey := computeGeoKey(e)
 	eb.Lock()
 	eb.recomputeBoundsFromElement(e)
-	if bucket := eb.Buckets[geoKey]; bucket == nil {
-		eb.Buckets[geoKey] = newSafeElementList()
-		eb.Unlock()
-	} else {
-		eb.Unlock()
-		bucket.Lock()
-		bucket.Elements = append(bucket.Elements, e)
-		bucket.Unlock()
+	bucket := eb.Buckets[geoKey]
+	if bucket == nil {
+		bucket = newSafeElementList()
+		eb.Buckets[geoKey] = bucket
 	}
+	eb.Unlock()
+
+	bucket.Lock()
+	bucket.Elements = append(bucket.Elements, e)
+	bucket.Unlock()
 }
 
 func (eb *RandomBoxLoader) GetNext() (*data.Point, bool) {
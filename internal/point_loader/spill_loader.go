@@ -0,0 +1,174 @@
+package point_loader
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"os"
+	"sync"
+
+	"github.com/ecopia-map/cesium_tiler/internal/data"
+	"github.com/golang/glog"
+)
+
+// Size of the buffers used to read and write the spill file
+const spillBufferSize = 1 << 16
+
+// Stores points in a binary file on disk and returns them in order. Used to build trees larger than the available
+// memory, only the points being read or written are kept in memory. The first i/o error stops the loader, which then
+// ignores the points added and returns no more points, and is reported by Err
+type SpillLoader struct {
+	sync.Mutex
+	filePath                           string
+	file                               *os.File
+	writer                             *bufio.Writer
+	reader                             *bufio.Reader
+	record                             []byte
+	numberOfPoints                     int64
+	remainingPoints                    int64
+	err                                error
+	minX, maxX, minY, maxY, minZ, maxZ float64
+}
+
+// Instances a new SpillLoader storing its points in the given file, which is created or truncated
+func NewSpillLoader(filePath string) (*SpillLoader, error) {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SpillLoader{
+		filePath: filePath,
+		file:     file,
+		writer:   bufio.NewWriterSize(file, spillBufferSize),
+		record:   make([]byte, data.PointRecordSize),
+		minX:     math.MaxFloat64,
+		minY:     math.MaxFloat64,
+		minZ:     math.MaxFloat64,
+		maxX:     -1 * math.MaxFloat64,
+		maxY:     -1 * math.MaxFloat64,
+		maxZ:     -1 * math.MaxFloat64,
+	}, nil
+}
+
+func (eb *SpillLoader) AddPoint(e *data.Point) {
+	eb.Lock()
+	defer eb.Unlock()
+
+	if eb.err != nil || eb.writer == nil {
+		return
+	}
+
+	record := eb.recordBuffer(data.PointRecordLength(e))
+	data.EncodePointRecord(record, e)
+	if _, err := eb.writer.Write(record); err != nil {
+		eb.err = err
+		return
+	}
+	eb.numberOfPoints++
+	eb.recomputeBoundsFromElement(e)
+}
+
+func (eb *SpillLoader) GetNext() (*data.Point, bool) {
+	eb.Lock()
+	defer eb.Unlock()
+
+	if eb.err != nil || eb.reader == nil || eb.remainingPoints == 0 {
+		return nil, false
+	}
+
+	if _, err := io.ReadFull(eb.reader, eb.record[:data.PointRecordSize]); err != nil {
+		eb.err = err
+		return nil, false
+	}
+	// the extra bytes values of the point follow the fixed part of the record
+	record := eb.recordBuffer(data.PointRecordSize + data.PointRecordExtraBytesLength(eb.record))
+	if _, err := io.ReadFull(eb.reader, record[data.PointRecordSize:]); err != nil {
+		eb.err = err
+		return nil, false
+	}
+	eb.remainingPoints--
+
+	return data.DecodePointRecord(record), eb.remainingPoints > 0
+}
+
+// Returns the record buffer resized to the given length, keeping its content
+func (eb *SpillLoader) recordBuffer(length int) []byte {
+	if cap(eb.record) < length {
+		record := make([]byte, length)
+		copy(record, eb.record)
+		eb.record = record
+	}
+	return eb.record[:length]
+}
+
+// Flushes the points written so far and rewinds the file so that they can be retrieved with GetNext
+func (eb *SpillLoader) InitializeLoader() {
+	eb.Lock()
+	defer eb.Unlock()
+
+	if eb.err != nil || eb.file == nil {
+		return
+	}
+
+	if eb.writer != nil {
+		if err := eb.writer.Flush(); err != nil {
+			eb.err = err
+			return
+		}
+		eb.writer = nil
+	}
+
+	if _, err := eb.file.Seek(0, io.SeekStart); err != nil {
+		eb.err = err
+		return
+	}
+	eb.reader = bufio.NewReaderSize(eb.file, spillBufferSize)
+	eb.remainingPoints = eb.numberOfPoints
+}
+
+// Closes and removes the spill file
+func (eb *SpillLoader) ClearLoader() {
+	eb.Lock()
+	defer eb.Unlock()
+
+	if eb.file == nil {
+		return
+	}
+
+	_ = eb.file.Close()
+	if err := os.Remove(eb.filePath); err != nil && !os.IsNotExist(err) {
+		glog.Infoln(err)
+	}
+	eb.file = nil
+	eb.writer = nil
+	eb.reader = nil
+	eb.remainingPoints = 0
+}
+
+// Returns the first i/o error raised while writing or reading the spill file, nil if there was none
+func (eb *SpillLoader) Err() error {
+	eb.Lock()
+	defer eb.Unlock()
+
+	return eb.err
+}
+
+// Returns the number of points stored in the spill file
+func (eb *SpillLoader) NumberOfPoints() int64 {
+	return eb.numberOfPoints
+}
+
+// Updates the data cloud bounds as per loaded elements and given additional element
+func (eb *SpillLoader) recomputeBoundsFromElement(element *data.Point) {
+	eb.minX = math.Min(element.X, eb.minX)
+	eb.minY = math.Min(element.Y, eb.minY)
+	eb.minZ = math.Min(element.Z, eb.minZ)
+	eb.maxX = math.Max(element.X, eb.maxX)
+	eb.maxY = math.Max(element.Y, eb.maxY)
+	eb.maxZ = math.Max(element.Z, eb.maxZ)
+}
+
+func (eb *SpillLoader) GetBounds() []float64 {
+	return []float64{eb.minX, eb.maxX, eb.minY, eb.maxY, eb.minZ, eb.maxZ}
+}
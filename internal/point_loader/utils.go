@@ -49,7 +49,7 @@ func computeGeoKey(e *data.Point) geoKey {
 	// 6th decimal for lat lng, 1st decimal for meters
 	return geoKey{
 		X: int(math.Floor(e.X / 10e-6)),
-		Y: int(math.Floor(e.Y / 1 * 10e-6)),
+		Y: int(math.Floor(e.Y / 10e-6)),
 		Z: int(math.Floor(e.Z / 10e-1)),
 	}
 }
@@ -0,0 +1,141 @@
+package progress
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// Stage of the pipeline processing a file
+type Stage string
+
+const (
+	StageRead      Stage = "read"       // points are read from the las file and added to the tree
+	StageBuild     Stage = "build"      // the tree hierarchy is built
+	StageSplit     Stage = "split"      // nodes with too many points are split
+	StageMerge     Stage = "merge"      // nodes with too few points, or the chunk las files, are merged
+	StageExport    Stage = "export"     // tiles and tileset.json files are written
+	StageLasExport Stage = "las-export" // the points of the root node are written to content.las
+)
+
+type EventType string
+
+const (
+	EventStart    EventType = "start"
+	EventProgress EventType = "progress"
+	EventFinish   EventType = "finish"
+)
+
+// Progress of a stage of the processing of a file
+type Event struct {
+	Time           time.Time `json:"time"`
+	Type           EventType `json:"event"`
+	File           string    `json:"file"`
+	Stage          Stage     `json:"stage"`
+	Done           int64     `json:"done"`            // number of points processed by the stage
+	Total          int64     `json:"total"`           // number of points to process, 0 if not known in advance
+	ElapsedSeconds float64   `json:"elapsed_seconds"` // time elapsed since the start of the stage
+	Error          string    `json:"error,omitempty"` // error stopping the stage, only set for finish events
+}
+
+// Returns the percentage of the points processed by the stage, -1 if the total is not known
+func (e Event) Percent() int {
+	if e.Total <= 0 {
+		return -1
+	}
+	return int(100 * e.Done / e.Total)
+}
+
+// Receives the progress of the stages of a run. Stages of different files may be reported concurrently
+type Reporter interface {
+	Report(event Event)
+}
+
+// Logs the start and the end of the stages, and their progress every 10%
+type LogReporter struct{}
+
+func NewLogReporter() *LogReporter {
+	return &LogReporter{}
+}
+
+func (r *LogReporter) Report(event Event) {
+	switch event.Type {
+	case EventStart:
+		glog.Infof("stage [%s] of [%s] started", event.Stage, event.File)
+	case EventProgress:
+		if percent := event.Percent(); percent%10 == 0 {
+			glog.Infof("stage [%s] of [%s] progress: %d%%", event.Stage, event.File, percent)
+		}
+	case EventFinish:
+		if event.Error != "" {
+			glog.Infof("stage [%s] of [%s] failed after %.1fs: %s", event.Stage, event.File, event.ElapsedSeconds, event.Error)
+		} else {
+			glog.Infof("stage [%s] of [%s] finished in %.1fs", event.Stage, event.File, event.ElapsedSeconds)
+		}
+	}
+}
+
+// Width in characters of the progress bar
+const barWidth = 30
+
+// Draws a progress bar of the last updated stage on a terminal line, which is kept once the stage finishes
+type BarReporter struct {
+	writer io.Writer
+	lock   sync.Mutex
+}
+
+func NewBarReporter(writer io.Writer) *BarReporter {
+	return &BarReporter{writer: writer}
+}
+
+func (r *BarReporter) Report(event Event) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	line := fmt.Sprintf("%-10s %-30s", event.Stage, filepath.Base(event.File))
+	if percent := event.Percent(); percent >= 0 {
+		filled := barWidth * percent / 100
+		if filled > barWidth {
+			filled = barWidth
+		}
+		line += fmt.Sprintf(" [%s%s] %3d%% %d/%d", strings.Repeat("=", filled), strings.Repeat(" ", barWidth-filled), percent, event.Done, event.Total)
+	} else if event.Done > 0 {
+		line += fmt.Sprintf(" %d", event.Done)
+	}
+	line += fmt.Sprintf(" %s", time.Duration(event.ElapsedSeconds*float64(time.Second)).Round(time.Second))
+
+	// the line is cleared as the previous one may be longer
+	switch {
+	case event.Type == EventFinish && event.Error != "":
+		_, _ = fmt.Fprintf(r.writer, "\r\033[K%s failed: %s\n", line, event.Error)
+	case event.Type == EventFinish:
+		_, _ = fmt.Fprintf(r.writer, "\r\033[K%s done\n", line)
+	default:
+		_, _ = fmt.Fprintf(r.writer, "\r\033[K%s", line)
+	}
+}
+
+// Writes the events as a stream of json objects, one per line
+type JsonReporter struct {
+	encoder *json.Encoder
+	lock    sync.Mutex
+}
+
+func NewJsonReporter(writer io.Writer) *JsonReporter {
+	return &JsonReporter{encoder: json.NewEncoder(writer)}
+}
+
+func (r *JsonReporter) Report(event Event) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if err := r.encoder.Encode(event); err != nil {
+		glog.Infof("unable to write progress event: %v", err)
+	}
+}
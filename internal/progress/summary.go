@@ -0,0 +1,205 @@
+package progress
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Statistics of a run, written as summary.json in the output folder. Safe for concurrent use
+type Summary struct {
+	lock sync.Mutex
+
+	command        string
+	input          string
+	output         string
+	start          time.Time
+	end            time.Time
+	err            error
+	files          int
+	filesSkipped   int
+	pointsRead     int64
+	pointsFiltered int64
+	outliers       map[uint8]int64
+	bytesWritten   int64
+	levels         map[int]*LevelSummary
+	stages         map[Stage]*StageSummary
+}
+
+// Points and nodes written at a level of the tree, the root node being at level 0
+type LevelSummary struct {
+	Level  int   `json:"level"`
+	Nodes  int64 `json:"nodes"`
+	Points int64 `json:"points"`
+}
+
+// Time spent in a stage, summed over the files processed. Files processed concurrently may make it longer than the run
+type StageSummary struct {
+	Stage   Stage   `json:"stage"`
+	Count   int     `json:"count"`
+	Seconds float64 `json:"seconds"`
+}
+
+type summaryJson struct {
+	Command        string          `json:"command"`
+	Input          string          `json:"input"`
+	Output         string          `json:"output"`
+	StartTime      time.Time       `json:"start_time"`
+	EndTime        time.Time       `json:"end_time"`
+	ElapsedSeconds float64         `json:"elapsed_seconds"`
+	Error          string          `json:"error,omitempty"`
+	Files          int             `json:"files"`
+	FilesSkipped   int             `json:"files_skipped"`
+	PointsRead     int64           `json:"points_read"`
+	PointsFiltered int64           `json:"points_filtered"`
+	PointsOutliers int64           `json:"points_outliers"`
+	OutliersClass  map[uint8]int64 `json:"outliers_by_class"`
+	PointsWritten  int64           `json:"points_written"`
+	Nodes          int64           `json:"nodes"`
+	BytesWritten   int64           `json:"bytes_written"`
+	Levels         []*LevelSummary `json:"levels"`
+	Stages         []*StageSummary `json:"stages"`
+}
+
+// Order in which the stages are listed in the summary
+var stageOrder = map[Stage]int{StageRead: 0, StageBuild: 1, StageSplit: 2, StageMerge: 3, StageExport: 4, StageLasExport: 5}
+
+// Returns the summary of a run of the given command starting now
+func NewSummary(command string, input string, output string) *Summary {
+	return &Summary{
+		command:  command,
+		input:    input,
+		output:   output,
+		start:    time.Now(),
+		outliers: make(map[uint8]int64),
+		levels:   make(map[int]*LevelSummary),
+		stages:   make(map[Stage]*StageSummary),
+	}
+}
+
+// Records a processed input file with the number of points read from it and the ones discarded by the filters
+func (s *Summary) AddFile(pointsRead int64, pointsFiltered int64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.files++
+	s.pointsRead += pointsRead
+	s.pointsFiltered += pointsFiltered
+}
+
+// Records the points of an input file removed as outliers, by classification
+func (s *Summary) AddOutliers(outliersByClass map[uint8]int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for classification, numPoints := range outliersByClass {
+		s.outliers[classification] += int64(numPoints)
+	}
+}
+
+// Records an input file skipped as its output is already complete
+func (s *Summary) AddSkippedFile() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.filesSkipped++
+}
+
+// Records a node written at the given level with its points and the bytes of its files
+func (s *Summary) AddNode(level int, points int64, bytes int64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	levelSummary, ok := s.levels[level]
+	if !ok {
+		levelSummary = &LevelSummary{Level: level}
+		s.levels[level] = levelSummary
+	}
+	levelSummary.Nodes++
+	levelSummary.Points += points
+	s.bytesWritten += bytes
+}
+
+// Records bytes written in files not belonging to a node, as the content.las and the subtree files
+func (s *Summary) AddBytes(bytes int64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.bytesWritten += bytes
+}
+
+func (s *Summary) addStage(stage Stage, elapsed time.Duration) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	stageSummary, ok := s.stages[stage]
+	if !ok {
+		stageSummary = &StageSummary{Stage: stage}
+		s.stages[stage] = stageSummary
+	}
+	stageSummary.Count++
+	stageSummary.Seconds += elapsed.Seconds()
+}
+
+// Records the end of the run with its error, if any
+func (s *Summary) Finish(err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.end = time.Now()
+	s.err = err
+}
+
+func (s *Summary) MarshalJSON() ([]byte, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	end := s.end
+	if end.IsZero() {
+		end = time.Now()
+	}
+	output := summaryJson{
+		Command:        s.command,
+		Input:          s.input,
+		Output:         s.output,
+		StartTime:      s.start,
+		EndTime:        end,
+		ElapsedSeconds: end.Sub(s.start).Seconds(),
+		Files:          s.files,
+		FilesSkipped:   s.filesSkipped,
+		PointsRead:     s.pointsRead,
+		PointsFiltered: s.pointsFiltered,
+		OutliersClass:  make(map[uint8]int64),
+		BytesWritten:   s.bytesWritten,
+		Levels:         []*LevelSummary{},
+		Stages:         []*StageSummary{},
+	}
+	if s.err != nil {
+		output.Error = s.err.Error()
+	}
+
+	for classification, numPoints := range s.outliers {
+		output.OutliersClass[classification] = numPoints
+		output.PointsOutliers += numPoints
+	}
+
+	for _, levelSummary := range s.levels {
+		output.Levels = append(output.Levels, levelSummary)
+		output.Nodes += levelSummary.Nodes
+		output.PointsWritten += levelSummary.Points
+	}
+	sort.Slice(output.Levels, func(i, j int) bool { return output.Levels[i].Level < output.Levels[j].Level })
+
+	for _, stageSummary := range s.stages {
+		output.Stages = append(output.Stages, stageSummary)
+	}
+	sort.Slice(output.Stages, func(i, j int) bool {
+		return stageOrder[output.Stages[i].Stage] < stageOrder[output.Stages[j].Stage]
+	})
+
+	return json.Marshal(output)
+}
+
+// Writes the summary as json to the given file
+func (s *Summary) Write(filePath string) error {
+	jsonData, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filePath, jsonData, 0666)
+}
@@ -0,0 +1,120 @@
+package progress
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Starts the tasks of the stages of a run, reporting their progress and recording their statistics in the summary
+type Tracker struct {
+	reporter Reporter
+	summary  *Summary
+}
+
+func NewTracker(reporter Reporter, summary *Summary) *Tracker {
+	return &Tracker{reporter: reporter, summary: summary}
+}
+
+// Returns the summary of the run
+func (t *Tracker) Summary() *Summary {
+	return t.summary
+}
+
+// Starts the given stage of the processing of a file with the number of points to process, 0 if not known. A nil
+// tracker returns a nil task
+func (t *Tracker) Start(file string, stage Stage, total int64) *Task {
+	if t == nil {
+		return nil
+	}
+	task := &Task{
+		tracker: t,
+		file:    file,
+		stage:   stage,
+		total:   total,
+		start:   time.Now(),
+	}
+	task.report(EventStart, 0, "")
+	return task
+}
+
+// Stage of the processing of a file. A nil task does nothing, so that the pipeline can run without tracker.
+// The points processed can be added concurrently
+type Task struct {
+	tracker     *Tracker
+	file        string
+	stage       Stage
+	total       int64
+	done        int64
+	lastPercent int64
+	start       time.Time
+}
+
+// Adds the given number of processed points, reporting the progress when the percentage changes, or every
+// progressPointsStep points when the total is not known
+func (t *Task) Add(numPoints int64) {
+	if t == nil || numPoints == 0 {
+		return
+	}
+	done := atomic.AddInt64(&t.done, numPoints)
+
+	var step int64
+	if t.total > 0 {
+		step = 100 * done / t.total
+	} else {
+		step = done / progressPointsStep
+	}
+	if last := atomic.LoadInt64(&t.lastPercent); step > last && atomic.CompareAndSwapInt64(&t.lastPercent, last, step) {
+		t.report(EventProgress, done, "")
+	}
+}
+
+// Number of points between two progress events of tasks with unknown total
+const progressPointsStep = 1000000
+
+// Records a tile written at the given level of the tree with its points and the bytes of its files, and adds its
+// points to the processed ones
+func (t *Task) AddTile(level int, numPoints int64, bytes int64) {
+	if t == nil {
+		return
+	}
+	t.tracker.summary.AddNode(level, numPoints, bytes)
+	t.Add(numPoints)
+}
+
+// Records bytes written by the stage in files not belonging to a tile
+func (t *Task) AddBytes(bytes int64) {
+	if t == nil {
+		return
+	}
+	t.tracker.summary.AddBytes(bytes)
+}
+
+// Ends the stage with the error stopping it, if any, and records its duration in the summary
+func (t *Task) Finish(err error) {
+	if t == nil {
+		return
+	}
+	errorMessage := ""
+	if err != nil {
+		errorMessage = err.Error()
+	}
+	t.report(EventFinish, atomic.LoadInt64(&t.done), errorMessage)
+	t.tracker.summary.addStage(t.stage, time.Since(t.start))
+}
+
+func (t *Task) report(eventType EventType, done int64, errorMessage string) {
+	if t.tracker.reporter == nil {
+		return
+	}
+	now := time.Now()
+	t.tracker.reporter.Report(Event{
+		Time:           now,
+		Type:           eventType,
+		File:           t.file,
+		Stage:          t.stage,
+		Done:           done,
+		Total:          t.total,
+		ElapsedSeconds: now.Sub(t.start).Seconds(),
+		Error:          errorMessage,
+	})
+}
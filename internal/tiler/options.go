@@ -1,23 +1,64 @@
 package tiler
 
-import "strings"
+import (
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"strings"
+
+	"github.com/ecopia-map/cesium_tiler/internal/progress"
+)
 
 type Algorithm string
 type RefineMode string
+type OutputFormat string
+type DracoMethod string
+type BoundingVolumeType string
+type ReturnFilter string
+type FlagFilter string
+type BatchAttribute string
+type GeoidInterpolation string
+type SamplingStrategy string
+type ProgressMode string
+type CoordinateConverterType string
 
 const (
+	// Samples the points with a grid whose cells halve in size at every level of the tree, keeping a point per cell.
+	Grid Algorithm = "GRID"
 
 	// Uniform random pick among all loaded elements. points will tend to be selected in areas with higher density.
-	Grid Algorithm = "GRID"
+	Random Algorithm = "RANDOM"
 
 	// Uniform pick in small boxes of points randomly ordered. Point will tend to be more evenly spaced at lower zoom levels.
 	// points are grouped in buckets of 1e-6 deg of latitude and longitude. Boxes are randomly sorted and the next data
 	// is selected at random from the first box. Next data is taken at random from the following box. When boxes have all been visited
 	// the selection will begin again from the first one. If one box becomes empty is removed and replaced with the last one in the set.
-	Random    Algorithm = "RANDOM"
 	RandomBox Algorithm = "RANDOMBOX"
 )
 
+func (e Algorithm) String() string {
+	if e == Grid {
+		return "GRID"
+	} else if e == Random {
+		return "RANDOM"
+	} else if e == RandomBox {
+		return "RANDOMBOX"
+	}
+	return ""
+}
+
+func ParseAlgorithm(value string) Algorithm {
+	normalizedValue := strings.Trim(strings.ToUpper(value), " ")
+	if normalizedValue == "GRID" {
+		return Grid
+	} else if normalizedValue == "RANDOM" {
+		return Random
+	} else if normalizedValue == "RANDOMBOX" {
+		return RandomBox
+	}
+	return ""
+}
+
 const (
 	RefineModeAdd     RefineMode = "ADD"
 	RefineModeReplace RefineMode = "REPLACE"
@@ -42,43 +83,594 @@ func ParseRefineMode(value string) RefineMode {
 	return ""
 }
 
-// Contains the options needed for the tiling algorithm
+const (
+	// 3D Tiles 1.0 point cloud content (content.pnts)
+	OutputFormatPnts OutputFormat = "PNTS"
+
+	// 3D Tiles 1.1 glTF binary content with POINTS primitives (content.glb)
+	OutputFormatGlb OutputFormat = "GLB"
+)
+
+func (e OutputFormat) String() string {
+	if e == OutputFormatPnts {
+		return "PNTS"
+	} else if e == OutputFormatGlb {
+		return "GLB"
+	}
+	return ""
+}
+
+// Returns the file name of the tile content written for the given output format
+func (e OutputFormat) ContentFileName() string {
+	if e == OutputFormatGlb {
+		return "content.glb"
+	}
+	return "content.pnts"
+}
+
+// Returns the 3D Tiles version written in the tileset.json asset for the given output format
+func (e OutputFormat) TilesetVersion() string {
+	if e == OutputFormatGlb {
+		return "1.1"
+	}
+	return "1.0"
+}
+
+func ParseOutputFormat(value string) OutputFormat {
+	normalizedValue := strings.Trim(strings.ToUpper(value), " ")
+	if normalizedValue == "PNTS" {
+		return OutputFormatPnts
+	} else if normalizedValue == "GLB" {
+		return OutputFormatGlb
+	}
+	return ""
+}
+
+const (
+	// Draco sequential encoding, attributes are compressed one after the other keeping the point order
+	DracoMethodSequential DracoMethod = "SEQUENTIAL"
+
+	// Draco kd-tree encoding, points are reordered and compressed with a kd-tree. Smaller output
+	DracoMethodKdTree DracoMethod = "KD-TREE"
+)
+
+func (e DracoMethod) String() string {
+	if e == DracoMethodSequential {
+		return "SEQUENTIAL"
+	} else if e == DracoMethodKdTree {
+		return "KD-TREE"
+	}
+	return ""
+}
+
+func ParseDracoMethod(value string) DracoMethod {
+	normalizedValue := strings.Trim(strings.ToUpper(value), " ")
+	if normalizedValue == "SEQUENTIAL" {
+		return DracoMethodSequential
+	} else if normalizedValue == "KD-TREE" {
+		return DracoMethodKdTree
+	}
+	return ""
+}
+
+const (
+	// Longitude, latitude and height bounds of the tile, computed from the corners of its bounding box
+	BoundingVolumeRegion BoundingVolumeType = "REGION"
+
+	// Box oriented along the points of the tile, in ECEF coordinates
+	BoundingVolumeBox BoundingVolumeType = "BOX"
+
+	// Sphere enclosing the points of the tile, in ECEF coordinates
+	BoundingVolumeSphere BoundingVolumeType = "SPHERE"
+)
+
+func (e BoundingVolumeType) String() string {
+	if e == BoundingVolumeRegion {
+		return "REGION"
+	} else if e == BoundingVolumeBox {
+		return "BOX"
+	} else if e == BoundingVolumeSphere {
+		return "SPHERE"
+	}
+	return ""
+}
+
+func ParseBoundingVolumeType(value string) BoundingVolumeType {
+	normalizedValue := strings.Trim(strings.ToUpper(value), " ")
+	if normalizedValue == "REGION" {
+		return BoundingVolumeRegion
+	} else if normalizedValue == "BOX" {
+		return BoundingVolumeBox
+	} else if normalizedValue == "SPHERE" {
+		return BoundingVolumeSphere
+	}
+	return ""
+}
+
+const (
+	// All the returns are kept
+	ReturnFilterAll ReturnFilter = "ALL"
+
+	// Only the first return of each pulse is kept
+	ReturnFilterFirst ReturnFilter = "FIRST"
+
+	// Only the last return of each pulse is kept
+	ReturnFilterLast ReturnFilter = "LAST"
+)
+
+func (e ReturnFilter) String() string {
+	if e == ReturnFilterAll {
+		return "ALL"
+	} else if e == ReturnFilterFirst {
+		return "FIRST"
+	} else if e == ReturnFilterLast {
+		return "LAST"
+	}
+	return ""
+}
+
+func ParseReturnFilter(value string) ReturnFilter {
+	normalizedValue := strings.Trim(strings.ToUpper(value), " ")
+	if normalizedValue == "ALL" {
+		return ReturnFilterAll
+	} else if normalizedValue == "FIRST" {
+		return ReturnFilterFirst
+	} else if normalizedValue == "LAST" {
+		return ReturnFilterLast
+	}
+	return ""
+}
+
+const (
+	// Points are kept whatever the value of the flag
+	FlagFilterKeep FlagFilter = "KEEP"
+
+	// Points having the flag set are discarded
+	FlagFilterDrop FlagFilter = "DROP"
+
+	// Only the points having the flag set are kept
+	FlagFilterOnly FlagFilter = "ONLY"
+)
+
+func (e FlagFilter) String() string {
+	if e == FlagFilterKeep {
+		return "KEEP"
+	} else if e == FlagFilterDrop {
+		return "DROP"
+	} else if e == FlagFilterOnly {
+		return "ONLY"
+	}
+	return ""
+}
+
+func ParseFlagFilter(value string) FlagFilter {
+	normalizedValue := strings.Trim(strings.ToUpper(value), " ")
+	if normalizedValue == "KEEP" {
+		return FlagFilterKeep
+	} else if normalizedValue == "DROP" {
+		return FlagFilterDrop
+	} else if normalizedValue == "ONLY" {
+		return FlagFilterOnly
+	}
+	return ""
+}
+
+const (
+	// Geoid heights are interpolated linearly between the 4 nodes surrounding the point
+	GeoidInterpolationBilinear GeoidInterpolation = "BILINEAR"
+
+	// Geoid heights are interpolated with quadratic polynomials through the 3x3 nodes nearest to the point
+	GeoidInterpolationBiquadratic GeoidInterpolation = "BIQUADRATIC"
+)
+
+func (e GeoidInterpolation) String() string {
+	if e == GeoidInterpolationBilinear {
+		return "BILINEAR"
+	} else if e == GeoidInterpolationBiquadratic {
+		return "BIQUADRATIC"
+	}
+	return ""
+}
+
+func ParseGeoidInterpolation(value string) GeoidInterpolation {
+	normalizedValue := strings.Trim(strings.ToUpper(value), " ")
+	if normalizedValue == "BILINEAR" {
+		return GeoidInterpolationBilinear
+	} else if normalizedValue == "BIQUADRATIC" {
+		return GeoidInterpolationBiquadratic
+	}
+	return ""
+}
+
+const (
+	// Each grid cell keeps the point closest to its center
+	SamplingClosest SamplingStrategy = "CLOSEST"
+
+	// Each grid cell keeps a point moved to the centroid of the points falling in the cell, with their mean color and
+	// intensity
+	SamplingCentroid SamplingStrategy = "CENTROID"
+
+	// Each grid cell keeps a point picked at random among the ones falling in the cell
+	SamplingRandom SamplingStrategy = "RANDOM"
+
+	// Each grid cell keeps the point with the highest Z, e.g. to show the canopy at the coarse levels
+	SamplingHighest SamplingStrategy = "HIGHEST"
+
+	// Each grid cell keeps the point with the lowest Z, e.g. to show the ground at the coarse levels
+	SamplingLowest SamplingStrategy = "LOWEST"
+
+	// Points are kept only if they are farther than the cell size from the points already kept by the node, in the
+	// spirit of Poisson disk sampling. It gives the most even spacing but the node keeps fewer points
+	SamplingMinDistance SamplingStrategy = "MIN-DISTANCE"
+)
+
+func (e SamplingStrategy) String() string {
+	if e == SamplingClosest {
+		return "CLOSEST"
+	} else if e == SamplingCentroid {
+		return "CENTROID"
+	} else if e == SamplingRandom {
+		return "RANDOM"
+	} else if e == SamplingHighest {
+		return "HIGHEST"
+	} else if e == SamplingLowest {
+		return "LOWEST"
+	} else if e == SamplingMinDistance {
+		return "MIN-DISTANCE"
+	}
+	return ""
+}
+
+func ParseSamplingStrategy(value string) SamplingStrategy {
+	normalizedValue := strings.Trim(strings.ToUpper(value), " ")
+	if normalizedValue == "CLOSEST" {
+		return SamplingClosest
+	} else if normalizedValue == "CENTROID" {
+		return SamplingCentroid
+	} else if normalizedValue == "RANDOM" {
+		return SamplingRandom
+	} else if normalizedValue == "HIGHEST" {
+		return SamplingHighest
+	} else if normalizedValue == "LOWEST" {
+		return SamplingLowest
+	} else if normalizedValue == "MIN-DISTANCE" {
+		return SamplingMinDistance
+	}
+	return ""
+}
+
+const (
+	// Stages and their progress are written to the log
+	ProgressModeLog ProgressMode = "LOG"
+
+	// A progress bar of the running stage is drawn on the terminal
+	ProgressModeBar ProgressMode = "BAR"
+
+	// Stages and their progress are written as a stream of json events, one per line
+	ProgressModeJson ProgressMode = "JSON"
+)
+
+func (e ProgressMode) String() string {
+	if e == ProgressModeLog {
+		return "LOG"
+	} else if e == ProgressModeBar {
+		return "BAR"
+	} else if e == ProgressModeJson {
+		return "JSON"
+	}
+	return ""
+}
+
+func ParseProgressMode(value string) ProgressMode {
+	normalizedValue := strings.Trim(strings.ToUpper(value), " ")
+	if normalizedValue == "LOG" {
+		return ProgressModeLog
+	} else if normalizedValue == "BAR" {
+		return ProgressModeBar
+	} else if normalizedValue == "JSON" {
+		return ProgressModeJson
+	}
+	return ""
+}
+
+const (
+	// Coordinates are converted with the proj4 C library through cgo
+	CoordinateConverterProj4 CoordinateConverterType = "PROJ4"
+
+	// Coordinates are converted with the pure Go port of the proj4 projections, which supports Transverse Mercator,
+	// UTM, Lambert Conformal Conic, Mercator, geographic and geocentric systems with translation and Helmert datum shifts
+	CoordinateConverterNative CoordinateConverterType = "NATIVE"
+)
+
+func (e CoordinateConverterType) String() string {
+	if e == CoordinateConverterProj4 {
+		return "PROJ4"
+	} else if e == CoordinateConverterNative {
+		return "NATIVE"
+	}
+	return ""
+}
+
+func ParseCoordinateConverterType(value string) CoordinateConverterType {
+	normalizedValue := strings.Trim(strings.ToUpper(value), " ")
+	if normalizedValue == "PROJ4" {
+		return CoordinateConverterProj4
+	} else if normalizedValue == "NATIVE" {
+		return CoordinateConverterNative
+	}
+	return ""
+}
+
+// Point attributes that can be written in the batch table of pnts tiles or as vertex attributes of glb tiles. The
+// values are the names of the batch table and metadata properties
+const (
+	BatchAttributeIntensity       BatchAttribute = "INTENSITY"
+	BatchAttributeClassification  BatchAttribute = "CLASSIFICATION"
+	BatchAttributeGpsTime         BatchAttribute = "GPS_TIME"
+	BatchAttributeReturnNumber    BatchAttribute = "RETURN_NUMBER"
+	BatchAttributeNumberOfReturns BatchAttribute = "NUMBER_OF_RETURNS"
+	BatchAttributePointSourceId   BatchAttribute = "POINT_SOURCE_ID"
+	BatchAttributeScanAngle       BatchAttribute = "SCAN_ANGLE"
+	BatchAttributeUserData        BatchAttribute = "USER_DATA"
+	BatchAttributeNir             BatchAttribute = "NIR"
+)
+
+// Prefix of the batch attributes read from the extra bytes of the las point records, followed by the name of the
+// extra bytes attribute
+const batchAttributeExtraBytesPrefix = "EXTRA:"
+
+var batchAttributes = []BatchAttribute{
+	BatchAttributeIntensity,
+	BatchAttributeClassification,
+	BatchAttributeGpsTime,
+	BatchAttributeReturnNumber,
+	BatchAttributeNumberOfReturns,
+	BatchAttributePointSourceId,
+	BatchAttributeScanAngle,
+	BatchAttributeUserData,
+	BatchAttributeNir,
+}
+
+func (e BatchAttribute) String() string {
+	if name, ok := e.ExtraBytesName(); ok {
+		return "extra:" + name
+	}
+	for _, attribute := range batchAttributes {
+		if e == attribute {
+			return strings.ReplaceAll(strings.ToLower(string(e)), "_", "-")
+		}
+	}
+	return ""
+}
+
+// Returns the batch attribute reading the extra bytes attribute with the given name
+func ExtraBytesBatchAttribute(name string) BatchAttribute {
+	return BatchAttribute(batchAttributeExtraBytesPrefix + name)
+}
+
+// Returns the name of the extra bytes attribute read by the batch attribute, false if it is not an extra bytes one
+func (e BatchAttribute) ExtraBytesName() (string, bool) {
+	if !strings.HasPrefix(string(e), batchAttributeExtraBytesPrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(string(e), batchAttributeExtraBytesPrefix), true
+}
+
+// Returns the name of the batch table and metadata property of the attribute. Extra bytes names are turned into
+// identifiers, replacing the characters other than letters, digits and underscores
+func (e BatchAttribute) PropertyName() string {
+	name, ok := e.ExtraBytesName()
+	if !ok {
+		return string(e)
+	}
+	return "EXTRA_" + strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// Parses a batch attribute given either as gps-time or as GPS_TIME. Extra bytes attributes are given as
+// extra:<name>, where the name is case sensitive
+func ParseBatchAttribute(value string) BatchAttribute {
+	trimmedValue := strings.Trim(value, " ")
+	if strings.HasPrefix(strings.ToUpper(trimmedValue), batchAttributeExtraBytesPrefix) {
+		name := strings.Trim(trimmedValue[len(batchAttributeExtraBytesPrefix):], " ")
+		if name == "" {
+			return ""
+		}
+		return ExtraBytesBatchAttribute(name)
+	}
+
+	normalizedValue := strings.ReplaceAll(strings.ToUpper(trimmedValue), "-", "_")
+	for _, attribute := range batchAttributes {
+		if normalizedValue == string(attribute) {
+			return attribute
+		}
+	}
+	return ""
+}
+
+// Returns the names of the extra bytes attributes among the given batch attributes, in the same order
+func ExtraBytesNames(attributes []BatchAttribute) []string {
+	names := make([]string, 0)
+	for _, attribute := range attributes {
+		if name, ok := attribute.ExtraBytesName(); ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Parses a comma separated list of batch attributes, invalid attributes are returned as empty values. An empty
+// list means that no attribute is written
+func ParseBatchAttributeList(value string) []BatchAttribute {
+	attributes := make([]BatchAttribute, 0)
+	if strings.Trim(value, " ") == "" {
+		return attributes
+	}
+	for _, item := range strings.Split(value, ",") {
+		attributes = append(attributes, ParseBatchAttribute(item))
+	}
+	return attributes
+}
+
+// Contains the options needed for the tiling algorithm. The json names are the keys of the configuration files
 type TilerOptions struct {
-	Input                  string     // Input LAS file/folder
-	Srid                   int        // EPSG code for SRID of input LAS points
-	EightBitColors         bool       // if true assume that LAS uses 8bit color depth
-	ZOffset                float64    // Z Offset in meters to apply to points during conversion
-	MinNumPointsPerNode    int32      // Minimum allowed number of points per node for GridTree Algorithms
-	MaxNumPointsPerNode    int32      // Maximum allowed number of points per node for Random and RandomBox Algorithms
-	EnableGeoidZCorrection bool       // Enables the conversion from geoid to ellipsoid height
-	FolderProcessing       bool       // Enables the processing of all LAS files in folder
-	Recursive              bool       // Recursive lookup of LAS files in subfolders
-	Algorithm              Algorithm  // Algorithm to use
-	CellMaxSize            float64    // Max cell size for grid algorithm
-	CellMinSize            float64    // Min cell size for grid algorithm
-	RefineMode             RefineMode // Refine mode to use to generate the tileset
-	Draco                  bool       // if true use Draco algorithm to compress xyz and color
-	DracoEncoderPath       string     // draco_endocer path
-
-	Command            string
-	TilerIndexOptions  *TilerIndexOptions
-	TilerMergeOptions  *TilerMergeOptions
-	TilerVerifyOptions *TilerVerifyOptions
+	Input                  string                  `json:"input"`                   // Input LAS file/folder
+	Srid                   int                     `json:"srid"`                    // EPSG code for SRID of input LAS points
+	EightBitColors         bool                    `json:"eight_bit_colors"`        // if true assume that LAS uses 8bit color depth
+	ZOffset                float64                 `json:"z_offset"`                // Z Offset in meters to apply to points during conversion
+	MinNumPointsPerNode    int32                   `json:"min_num_points_per_node"` // Minimum allowed number of points per node for GridTree Algorithms
+	MaxNumPointsPerNode    int32                   `json:"max_num_points_per_node"` // Maximum allowed number of points per node for Random and RandomBox Algorithms
+	EnableGeoidZCorrection bool                    `json:"geoid"`                   // Enables the conversion from geoid to ellipsoid height
+	GeoidGrid              string                  `json:"geoid_grid"`              // optional geoid height grid file (.gtx, .bin or GeoTIFF), if empty the EGM180 model is used
+	GeoidInterpolation     GeoidInterpolation      `json:"geoid_interpolation"`     // Interpolation of the geoid grid heights, either bilinear or biquadratic
+	FolderProcessing       bool                    `json:"folder"`                  // Enables the processing of all LAS files in folder
+	Recursive              bool                    `json:"recursive"`               // Recursive lookup of LAS files in subfolders
+	Algorithm              Algorithm               `json:"algorithm"`               // Algorithm to use
+	CellMaxSize            float64                 `json:"grid_max_size"`           // Max cell size for grid algorithm
+	CellMinSize            float64                 `json:"grid_min_size"`           // Min cell size for grid algorithm
+	RefineMode             RefineMode              `json:"refine_mode"`             // Refine mode to use to generate the tileset
+	Sampling               SamplingStrategy        `json:"sampling"`                // Strategy choosing the points kept by the grid cells of the coarse levels
+	Draco                  bool                    `json:"draco"`                   // if true use Draco algorithm to compress xyz, color and batch attributes
+	DracoEncoderPath       string                  `json:"draco_encoder_path"`      // optional external draco_encoder path, if empty draco compression is done in-process
+	DracoMethod            DracoMethod             `json:"draco_method"`            // Draco encoding method, either sequential or kd-tree
+	DracoQuantizationBits  int                     `json:"draco_quantization_bits"` // Number of bits used by Draco to quantize positions
+	OutputFormat           OutputFormat            `json:"output_format"`           // Format of the tile content, either pnts or glb
+	Meshopt                bool                    `json:"meshopt"`                 // if true compress glb vertex attributes with EXT_meshopt_compression
+	BoundingVolume         BoundingVolumeType      `json:"bounding_volume"`         // Type of the tile bounding volumes, either region, box or sphere
+	BatchAttributes        []BatchAttribute        `json:"batch_attributes"`        // Point attributes written in the batch table of pnts tiles or as vertex attributes of glb tiles
+	Progress               ProgressMode            `json:"progress"`                // How the progress of the stages is reported, either log, bar or json
+	ProgressFile           string                  `json:"progress_file"`           // File receiving the json progress events, if empty they are written to the standard output
+	WorkDir                string                  `json:"workdir"`                 // Folder of the temporary files of the merge, if empty the system temporary folder is used
+	CoordinateConverter    CoordinateConverterType `json:"coordinate_converter"`    // Library converting the coordinates, either proj4 or native
+
+	// Receives the progress events instead of the reporter selected by Progress, used by the library callers
+	ProgressReporter progress.Reporter `json:"-"`
+
+	Command            string              `json:"-"`
+	TilerIndexOptions  *TilerIndexOptions  `json:"index,omitempty"`
+	TilerMergeOptions  *TilerMergeOptions  `json:"merge,omitempty"`
+	TilerUpdateOptions *TilerUpdateOptions `json:"update,omitempty"`
+	TilerVerifyOptions *TilerVerifyOptions `json:"verify,omitempty"`
+	TilerServeOptions  *TilerServeOptions  `json:"serve,omitempty"`
+
+	TilerVerifyTilesetOptions *TilerVerifyTilesetOptions `json:"verify_tileset,omitempty"`
 }
 
 type TilerIndexOptions struct {
-	Output                         string // Output Cesium Tileset folder
-	UseEdgeCalculateGeometricError bool
+	Output                         string `json:"output"` // Output Cesium Tileset folder
+	UseEdgeCalculateGeometricError bool   `json:"use_edge_calculate"`
+	ImplicitTiling                 bool   `json:"implicit"`       // if true write the tree as a 3D Tiles 1.1 implicit octree
+	SubtreeLevels                  int    `json:"subtree_levels"` // Number of levels stored in each subtree file of the implicit octree
+	MemoryBudget                   int    `json:"memory_budget"`  // Max memory in MB used to hold points while building the tree, 0 keeps all the points in memory
+	Resume                         bool   `json:"resume"`         // if true skip the files whose chunk tileset is recorded as complete in the output manifest
+	Jobs                           int    `json:"jobs"`           // Number of las files processed concurrently
+	Workers                        int    `json:"workers"`        // Number of goroutines shared by the concurrent files, 0 uses one per CPU
+
+	Filter   TilerFilterOptions  `json:"filter"`   // Filters applied to the points of the las files before building the tree
+	Outliers TilerOutlierOptions `json:"outliers"` // Removal of the noise points of the las files before building the tree
+}
+
+// Splits the workers and the memory budget among the given number of concurrent jobs, returning the workers and the
+// memory budget in MB of each job. Every job gets at least one worker, and at least 1 MB if a budget is set
+func (opts *TilerIndexOptions) JobResources(numJobs int) (int, int) {
+	numWorkers := opts.Workers
+	if numWorkers < 1 {
+		numWorkers = runtime.NumCPU()
+	}
+	numWorkers /= numJobs
+	if numWorkers < 1 {
+		numWorkers = 1
+	}
+
+	memoryBudget := opts.MemoryBudget / numJobs
+	if opts.MemoryBudget > 0 && memoryBudget < 1 {
+		memoryBudget = 1
+	}
+
+	return numWorkers, memoryBudget
+}
+
+// List of point classifications, written as a list of numbers in json rather than as the base64 string of a []uint8
+type ClassList []uint8
+
+func (c ClassList) MarshalJSON() ([]byte, error) {
+	values := make([]int, len(c))
+	for i, class := range c {
+		values[i] = int(class)
+	}
+	return json.Marshal(values)
+}
+
+func (c *ClassList) UnmarshalJSON(data []byte) error {
+	var values []uint8
+	var numbers []int
+	if err := json.Unmarshal(data, &numbers); err != nil {
+		return err
+	}
+	for _, number := range numbers {
+		if number < 0 || number > 255 {
+			return fmt.Errorf("invalid classification [%d], must be between 0 and 255", number)
+		}
+		values = append(values, uint8(number))
+	}
+	*c = values
+	return nil
+}
+
+// Filters applied to the points while they are read from the las files
+type TilerFilterOptions struct {
+	IncludeClasses ClassList    `json:"classes"`         // if not empty only the points of these classifications are kept
+	ExcludeClasses ClassList    `json:"exclude_classes"` // points of these classifications are discarded
+	Returns        ReturnFilter `json:"returns"`         // Returns to keep, either all, first or last
+	Withheld       FlagFilter   `json:"withheld"`        // How points flagged as withheld are handled, either keep, drop or only
+	Synthetic      FlagFilter   `json:"synthetic"`       // How points flagged as synthetic are handled, either keep, drop or only
+	ZMin           *float64     `json:"z_min"`           // if set the points with Z lower than ZMin are discarded
+	ZMax           *float64     `json:"z_max"`           // if set the points with Z greater than ZMax are discarded
+	ClipBox        []float64    `json:"clip_box"`        // if set only the points inside the xmin, ymin, xmax, ymax box are kept
+	ClipPolygon    string       `json:"clip_polygon"`    // if set only the points inside the polygons of this GeoJSON or WKT file are kept
+	ClipSrid       int          `json:"clip_srid"`       // EPSG code of the clip box and polygon coordinates, 0 uses the srid of the input points
+}
+
+// Options of the removal of the noise points, such as birds or multipath returns, before building the tree. Distances
+// are in the units of the input coordinates
+type TilerOutlierOptions struct {
+	SorNeighbors int     `json:"sor_neighbors"` // Number of nearest neighbours of the statistical outlier removal, 0 disables it
+	SorSigma     float64 `json:"sor_sigma"`     // Points whose mean neighbour distance exceeds the mean of all the points by more than this many standard deviations are removed
+	SorRadius    float64 `json:"sor_radius"`    // Distance the nearest neighbours are searched within, the missing ones are counted at this distance
+	RorNeighbors int     `json:"ror_neighbors"` // Min number of neighbours within RorRadius of the radius outlier removal, 0 disables it
+	RorRadius    float64 `json:"ror_radius"`    // Radius of the radius outlier removal
 }
 
 type TilerMergeOptions struct {
-	Output string // Output Cesium Tileset folder
+	Output string `json:"output"` // Output Cesium Tileset folder
+}
+
+// Options of the update command, which indexes again the changed las files of a merged tileset with the index options
+// and merges again the folders containing them with these options
+type TilerUpdateOptions struct {
+	MergeGridMaxSize         float64 `json:"merge_grid_max_size"`           // Max cell size of the grid algorithm for the deepest merged folders, doubled at every level
+	MergeMinNumPointsPerNode int32   `json:"merge_min_num_points_per_node"` // Minimum number of points per node of the merged folders
+	MergeMaxNumPointsPerNode int32   `json:"merge_max_num_points_per_node"` // Maximum number of points per node of the merged folders
+	DryRun                   bool    `json:"dry_run"`                       // if true only list the changed las files and the folders to merge again
+}
+
+type TilerServeOptions struct {
+	Address string `json:"address"` // Address the HTTP server listens on
+	Viewer  bool   `json:"viewer"`  // if true serve a Cesium viewer page loading the tileset at the root path
+}
+
+type TilerVerifyTilesetOptions struct {
+	Report string `json:"report"` // Path of the json report file, if empty the report is written to the standard output
 }
 
 type TilerVerifyOptions struct {
-	Output      string // Output Cesium Tileset folder
-	OffsetBegin int64
-	OffsetEnd   int64
+	Output      string `json:"output"` // Output Cesium Tileset folder
+	OffsetBegin int64  `json:"offset_begin"`
+	OffsetEnd   int64  `json:"offset_end"`
 }
 
 func (opt *TilerOptions) Copy() *TilerOptions {
@@ -91,12 +683,28 @@ func (opt *TilerOptions) Copy() *TilerOptions {
 		MinNumPointsPerNode:    opt.MinNumPointsPerNode,
 		MaxNumPointsPerNode:    opt.MaxNumPointsPerNode,
 		EnableGeoidZCorrection: opt.EnableGeoidZCorrection,
+		GeoidGrid:              opt.GeoidGrid,
+		GeoidInterpolation:     opt.GeoidInterpolation,
 		FolderProcessing:       opt.FolderProcessing,
 		Recursive:              opt.Recursive,
 		Algorithm:              opt.Algorithm,
 		CellMaxSize:            opt.CellMaxSize,
 		CellMinSize:            opt.CellMinSize,
 		RefineMode:             opt.RefineMode,
+		Sampling:               opt.Sampling,
+		Draco:                  opt.Draco,
+		DracoEncoderPath:       opt.DracoEncoderPath,
+		DracoMethod:            opt.DracoMethod,
+		DracoQuantizationBits:  opt.DracoQuantizationBits,
+		OutputFormat:           opt.OutputFormat,
+		Meshopt:                opt.Meshopt,
+		BoundingVolume:         opt.BoundingVolume,
+		BatchAttributes:        append([]BatchAttribute(nil), opt.BatchAttributes...),
+		Progress:               opt.Progress,
+		ProgressFile:           opt.ProgressFile,
+		WorkDir:                opt.WorkDir,
+		CoordinateConverter:    opt.CoordinateConverter,
+		ProgressReporter:       opt.ProgressReporter,
 		Command:                opt.Command,
 		TilerIndexOptions:      nil,
 		TilerMergeOptions:      nil,
@@ -112,10 +720,25 @@ func (opt *TilerOptions) Copy() *TilerOptions {
 		newOpt.TilerMergeOptions = &mergeOpt
 	}
 
+	if opt.TilerUpdateOptions != nil {
+		updateOpt := *opt.TilerUpdateOptions
+		newOpt.TilerUpdateOptions = &updateOpt
+	}
+
 	if opt.TilerVerifyOptions != nil {
 		mergeOpt := *opt.TilerMergeOptions
 		newOpt.TilerMergeOptions = &mergeOpt
 	}
 
+	if opt.TilerServeOptions != nil {
+		serveOpt := *opt.TilerServeOptions
+		newOpt.TilerServeOptions = &serveOpt
+	}
+
+	if opt.TilerVerifyTilesetOptions != nil {
+		verifyTilesetOpt := *opt.TilerVerifyTilesetOptions
+		newOpt.TilerVerifyTilesetOptions = &verifyTilesetOpt
+	}
+
 	return newOpt
 }
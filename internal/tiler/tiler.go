@@ -1,5 +1,7 @@
 package tiler
 
+import "context"
+
 type ITiler interface {
-	RunTiler(opts *TilerOptions) error
+	RunTiler(ctx context.Context, opts *TilerOptions) error
 }
@@ -22,6 +22,8 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
@@ -71,7 +73,7 @@ func main() {
 
 	args := flag.Args()
 	if len(args) == 0 {
-		glog.Fatal("Please specify a subcommand [index|merge].")
+		glog.Fatal("Please specify a subcommand [index|merge|serve].")
 	}
 	cmd, args := args[0], args[1:]
 
@@ -86,8 +88,16 @@ func main() {
 		mainCommandVerifyLas(args, cmd)
 	case tools.CommandVerifyLasMerge:
 		mainCommandVerifyLas(args, cmd)
+	case tools.CommandVerifyTileset:
+		mainCommandVerifyTileset(args)
+	case tools.CommandServe:
+		mainCommandServe(args)
+	case tools.CommandUpdate:
+		mainCommandUpdate(args)
+	case tools.CommandConfig:
+		mainCommandConfig(args)
 	default:
-		glog.Fatalf("Unrecognized command [%q]. Command must be one of [index|merge]", cmd)
+		glog.Fatalf("Unrecognized command [%q]. Command must be one of [index|merge|serve]", cmd)
 	}
 
 }
@@ -110,10 +120,61 @@ func mainCommandIndex(args []string) {
 		return
 	}
 
+	opts := getOptionsForCommandIndex(&flags)
+
+	// Starts the tiler
+	// defer timeTrack(time.Now(), "tiler")
+	algorithmManager, err := std_algorithm_manager.NewAlgorithmManager(opts)
+	if err != nil {
+		glog.Fatal("Error while tiling: ", err)
+	}
+	err = pkg.NewTiler(tools.NewStandardFileFinder(), algorithmManager).RunTiler(context.Background(), opts)
+
+	if err != nil {
+		glog.Fatal("Error while tiling: ", err)
+	} else {
+		glog.Infoln("Conversion Completed")
+	}
+}
+
+// Builds the options of the index command from the flags and the configuration file, then validates them and
+// detects the srid of the input files
+func getOptionsForCommandIndex(flags *tools.FlagsForCommandIndex) *tiler.TilerOptions {
 	glog.Infoln("flags", tools.FmtJSONString(flags))
 
+	opts := getIndexOptions(flags)
+
+	setKeys := applyConfigFile(&opts, flags.FlagCommand, *flags.Config)
+	pkg.DropDracoEncoderBatchAttributes(&opts, setKeys["batch_attributes"])
+
+	// Validate TilerOptions
+	if msg, res := validateOptionsForCommandIndex(&opts, flags); !res {
+		glog.Fatal("Error parsing input parameters: " + msg)
+	}
+
+	// Detect the srid of the input files, checking it against the one eventually given
+	lasFiles, err := tools.NewStandardFileFinder().GetLasFilesToProcess(&opts)
+	if err != nil {
+		glog.Fatal("Error listing input files: ", err)
+	}
+	srid, err := pkg.DetectSrid(lasFiles, opts.Srid, setKeys["srid"])
+	if err != nil {
+		glog.Fatal("Error detecting input srid: ", err)
+	}
+	opts.Srid = srid
+
+	return &opts
+}
+
+// Puts the flags of the index command inside a TilerOptions struct
+func getIndexOptions(flags *tools.FlagsForCommandIndex) tiler.TilerOptions {
 	tilerFlags := flags.TilerFlags
 
+	filterOptions, err := getFilterOptions(flags)
+	if err != nil {
+		glog.Fatal("Error parsing input parameters: ", err)
+	}
+
 	// Put args inside a TilerOptions struct
 	opts := tiler.TilerOptions{
 		Input:                  *tilerFlags.Input,
@@ -123,61 +184,148 @@ func mainCommandIndex(args []string) {
 		MaxNumPointsPerNode:    int32(*tilerFlags.MaxNumPoints),
 		MinNumPointsPerNode:    int32(*tilerFlags.MinNumPoints),
 		EnableGeoidZCorrection: *tilerFlags.ZGeoidCorrection,
+		GeoidGrid:              *tilerFlags.GeoidGrid,
+		GeoidInterpolation:     tiler.ParseGeoidInterpolation(*tilerFlags.GeoidInterpolation),
 		FolderProcessing:       *tilerFlags.FolderProcessing,
 		Recursive:              *tilerFlags.RecursiveFolderProcessing,
-		Algorithm:              tiler.Algorithm(strings.ToUpper(*tilerFlags.Algorithm)),
+		Algorithm:              tiler.ParseAlgorithm(*tilerFlags.Algorithm),
 		CellMinSize:            *tilerFlags.GridCellMinSize,
 		CellMaxSize:            *tilerFlags.GridCellMaxSize,
 		RefineMode:             tiler.ParseRefineMode(*tilerFlags.RefineMode),
+		Sampling:               tiler.ParseSamplingStrategy(*tilerFlags.Sampling),
+		CoordinateConverter:    tiler.ParseCoordinateConverterType(*tilerFlags.CoordinateConverter),
 		Draco:                  *tilerFlags.Draco,
 		DracoEncoderPath:       *tilerFlags.DracoEncoderPath,
+		DracoMethod:            tiler.ParseDracoMethod(*tilerFlags.DracoMethod),
+		DracoQuantizationBits:  *tilerFlags.DracoQuantizationBits,
+		OutputFormat:           tiler.ParseOutputFormat(*tilerFlags.OutputFormat),
+		Meshopt:                *tilerFlags.Meshopt,
+		BoundingVolume:         tiler.ParseBoundingVolumeType(*tilerFlags.BoundingVolume),
+		BatchAttributes:        tiler.ParseBatchAttributeList(*tilerFlags.BatchAttributes),
+		Progress:               tiler.ParseProgressMode(*tilerFlags.Progress),
+		ProgressFile:           *tilerFlags.ProgressFile,
 
 		Command: tools.CommandIndex,
 		TilerIndexOptions: &tiler.TilerIndexOptions{
 			Output:                         *flags.Output,
 			UseEdgeCalculateGeometricError: *flags.UseEdgeCalculateGeometricError,
+			ImplicitTiling:                 *flags.ImplicitTiling,
+			SubtreeLevels:                  *flags.SubtreeLevels,
+			MemoryBudget:                   *flags.MemoryBudget,
+			Resume:                         *flags.Resume,
+			Jobs:                           *flags.Jobs,
+			Workers:                        *flags.Workers,
+			Filter:                         filterOptions,
+			Outliers: tiler.TilerOutlierOptions{
+				SorNeighbors: *flags.SorNeighbors,
+				SorSigma:     *flags.SorSigma,
+				SorRadius:    *flags.SorRadius,
+				RorNeighbors: *flags.RorNeighbors,
+				RorRadius:    *flags.RorRadius,
+			},
 		},
 	}
 
-	// Validate TilerOptions
-	if msg, res := validateOptionsForCommandIndex(&opts, &flags); !res {
-		glog.Fatal("Error parsing input parameters: " + msg)
+	return opts
+}
+
+// Validates the input options provided to the command line tool checking
+// that input and output folders/files exist
+func validateOptionsForCommandIndex(opts *tiler.TilerOptions, flags *tools.FlagsForCommandIndex) (string, bool) {
+	if err := pkg.ValidateIndexOptions(opts); err != nil {
+		return err.Error(), false
 	}
 
-	// Starts the tiler
-	// defer timeTrack(time.Now(), "tiler")
-	err := pkg.NewTiler(tools.NewStandardFileFinder(), std_algorithm_manager.NewAlgorithmManager(&opts)).RunTiler(&opts)
+	return "", true
+}
 
+// Converts the point filter flags of the index command into the filter options
+func getFilterOptions(flags *tools.FlagsForCommandIndex) (tiler.TilerFilterOptions, error) {
+	filterOptions := tiler.TilerFilterOptions{
+		Returns:     tiler.ParseReturnFilter(*flags.FilterReturns),
+		Withheld:    tiler.ParseFlagFilter(*flags.FilterWithheld),
+		Synthetic:   tiler.ParseFlagFilter(*flags.FilterSynthetic),
+		ClipPolygon: *flags.ClipPolygon,
+		ClipSrid:    *flags.ClipSrid,
+	}
+
+	var err error
+	if filterOptions.IncludeClasses, err = tools.ParseClassificationList(*flags.FilterClasses); err != nil {
+		return filterOptions, err
+	}
+	if filterOptions.ExcludeClasses, err = tools.ParseClassificationList(*flags.FilterExcludeClasses); err != nil {
+		return filterOptions, err
+	}
+
+	zRange, err := tools.ParseFloatList(*flags.FilterZRange, 2, true)
 	if err != nil {
-		glog.Fatal("Error while tiling: ", err)
-	} else {
-		glog.Infoln("Conversion Completed")
+		return filterOptions, fmt.Errorf("filter-z-range: %v", err)
+	}
+	if zRange != nil {
+		filterOptions.ZMin, filterOptions.ZMax = zRange[0], zRange[1]
+	}
+
+	clipBox, err := tools.ParseFloatList(*flags.ClipBox, 4, false)
+	if err != nil {
+		return filterOptions, fmt.Errorf("clip-box: %v", err)
 	}
+	for _, value := range clipBox {
+		filterOptions.ClipBox = append(filterOptions.ClipBox, *value)
+	}
+
+	return filterOptions, nil
 }
 
-// Validates the input options provided to the command line tool checking
-// that input and output folders/files exist
-func validateOptionsForCommandIndex(opts *tiler.TilerOptions, flags *tools.FlagsForCommandIndex) (string, bool) {
-	if _, err := os.Stat(opts.Input); os.IsNotExist(err) {
-		return "Input file/folder not found", false
+func mainCommandUpdate(args []string) {
+	flags := tools.ParseFlagsForCommandUpdate(args)
+
+	// Prints the command line flag description
+	if *flags.Help {
+		showHelpForSubCommand(flags.FlagCommand)
+		return
 	}
-	if _, err := os.Stat(*flags.Output); os.IsNotExist(err) {
-		return "Output folder not found", false
+
+	if *flags.Version {
+		printVersion()
+		return
 	}
 
-	if opts.CellMinSize > opts.CellMaxSize {
-		return "grid-max-size parameter cannot be lower than grid-min-size parameter", false
+	opts := getOptionsForCommandUpdate(&flags)
+
+	if err := pkg.NewTilerUpdate(tools.NewStandardFileFinder()).RunTiler(context.Background(), opts); err != nil {
+		glog.Fatal("Error while updating: ", err)
+	} else {
+		glog.Infoln("Update Completed")
 	}
+}
 
-	if opts.RefineMode == "" {
-		return "refine-mode should be either ADD or REPLACE", false
+// Builds the options of the update command from the flags and the configuration file and validates them. The srid is
+// left to 0 when not given, so that it is detected from the changed files
+func getOptionsForCommandUpdate(flags *tools.FlagsForCommandUpdate) *tiler.TilerOptions {
+	glog.Infoln("flags", tools.FmtJSONString(flags))
+
+	opts := getIndexOptions(&flags.FlagsForCommandIndex)
+	opts.Command = tools.CommandUpdate
+	opts.WorkDir = *flags.WorkDir
+	opts.TilerUpdateOptions = &tiler.TilerUpdateOptions{
+		MergeGridMaxSize:         *flags.MergeGridMaxSize,
+		MergeMinNumPointsPerNode: int32(*flags.MergeMinNumPointsPerNode),
+		MergeMaxNumPointsPerNode: int32(*flags.MergeMaxNumPointsPerNode),
+		DryRun:                   *flags.DryRun,
 	}
 
-	if opts.Draco && opts.DracoEncoderPath == "" {
-		return "draco-encoder-path must be set", false
+	setKeys := applyConfigFile(&opts, flags.FlagCommand, *flags.Config)
+	if !setKeys["srid"] {
+		opts.Srid = 0
 	}
+	pkg.DropDracoEncoderBatchAttributes(&opts, setKeys["batch_attributes"])
 
-	return "", true
+	// Validate TilerOptions
+	if err := pkg.ValidateUpdateOptions(&opts); err != nil {
+		glog.Fatal("Error parsing input parameters: " + err.Error())
+	}
+
+	return &opts
 }
 
 func mainCommandMerge(args []string, cmd string) {
@@ -194,6 +342,28 @@ func mainCommandMerge(args []string, cmd string) {
 		return
 	}
 
+	opts := getOptionsForCommandMerge(&flags, cmd)
+
+	// Starts the tiler
+	// defer timeTrack(time.Now(), "tiler")
+	fileFinder := tools.NewStandardFileFinder()
+	algorithmManager, err := std_algorithm_manager.NewAlgorithmManager(opts)
+	if err != nil {
+		glog.Fatal("Error while tiling: ", err)
+	}
+	err = pkg.NewTilerMerge(fileFinder, algorithmManager).RunTiler(context.Background(), opts)
+
+	if err != nil {
+		glog.Fatal("Error while tiling: ", err)
+	} else {
+		glog.Infoln("Conversion Completed")
+	}
+
+}
+
+// Builds the options of the merge commands from the flags and the configuration file, then validates them and
+// detects the srid of the chunk las files
+func getOptionsForCommandMerge(flags *tools.FlagsForCommandMerge, cmd string) *tiler.TilerOptions {
 	glog.Infoln("flags", tools.FmtJSONString(flags))
 
 	tilerFlags := flags.TilerFlags
@@ -208,46 +378,59 @@ func mainCommandMerge(args []string, cmd string) {
 		MaxNumPointsPerNode:    int32(*tilerFlags.MaxNumPoints),
 		MinNumPointsPerNode:    int32(*tilerFlags.MinNumPoints),
 		EnableGeoidZCorrection: *tilerFlags.ZGeoidCorrection,
+		GeoidGrid:              *tilerFlags.GeoidGrid,
+		GeoidInterpolation:     tiler.ParseGeoidInterpolation(*tilerFlags.GeoidInterpolation),
 		FolderProcessing:       *tilerFlags.FolderProcessing,
 		Recursive:              *tilerFlags.RecursiveFolderProcessing,
-		Algorithm:              tiler.Algorithm(strings.ToUpper(*tilerFlags.Algorithm)),
+		Algorithm:              tiler.ParseAlgorithm(*tilerFlags.Algorithm),
 		CellMinSize:            *tilerFlags.GridCellMinSize,
 		CellMaxSize:            *tilerFlags.GridCellMaxSize,
 		RefineMode:             tiler.ParseRefineMode(*tilerFlags.RefineMode),
+		Sampling:               tiler.ParseSamplingStrategy(*tilerFlags.Sampling),
+		CoordinateConverter:    tiler.ParseCoordinateConverterType(*tilerFlags.CoordinateConverter),
 		Draco:                  *tilerFlags.Draco,
 		DracoEncoderPath:       *tilerFlags.DracoEncoderPath,
+		DracoMethod:            tiler.ParseDracoMethod(*tilerFlags.DracoMethod),
+		DracoQuantizationBits:  *tilerFlags.DracoQuantizationBits,
+		OutputFormat:           tiler.ParseOutputFormat(*tilerFlags.OutputFormat),
+		Meshopt:                *tilerFlags.Meshopt,
+		BoundingVolume:         tiler.ParseBoundingVolumeType(*tilerFlags.BoundingVolume),
+		BatchAttributes:        tiler.ParseBatchAttributeList(*tilerFlags.BatchAttributes),
+		Progress:               tiler.ParseProgressMode(*tilerFlags.Progress),
+		ProgressFile:           *tilerFlags.ProgressFile,
+		WorkDir:                *tilerFlags.WorkDir,
 
 		TilerMergeOptions: &tiler.TilerMergeOptions{
 			Output: "",
 		},
 	}
 
+	setKeys := applyConfigFile(&opts, flags.FlagCommand, *tilerFlags.Config)
+	pkg.DropDracoEncoderBatchAttributes(&opts, setKeys["batch_attributes"])
+
 	// Validate TilerOptions
-	if msg, res := validateOptionsForCommandMerge(&opts, &flags); !res {
+	if msg, res := validateOptionsForCommandMerge(&opts, flags); !res {
 		glog.Fatal("Error parsing input parameters: " + msg)
 	}
 
-	// Starts the tiler
-	// defer timeTrack(time.Now(), "tiler")
+	// Detect the srid of the chunk las files, checking it against the one eventually given
 	fileFinder := tools.NewStandardFileFinder()
-	algorithmManager := std_algorithm_manager.NewAlgorithmManager(&opts)
-	err := pkg.NewTilerMerge(fileFinder, algorithmManager).RunTiler(&opts)
-
+	lasFiles, err := fileFinder.GetLasFilesToMerge(&opts)
 	if err != nil {
-		glog.Fatal("Error while tiling: ", err)
-	} else {
-		glog.Infoln("Conversion Completed")
+		glog.Fatal("Error listing input files: ", err)
+	}
+	srid, err := pkg.DetectSrid(lasFiles, opts.Srid, setKeys["srid"])
+	if err != nil {
+		glog.Fatal("Error detecting input srid: ", err)
 	}
+	opts.Srid = srid
 
+	return &opts
 }
 
 func validateOptionsForCommandMerge(opts *tiler.TilerOptions, flags *tools.FlagsForCommandMerge) (string, bool) {
-	if _, err := os.Stat(opts.Input); os.IsNotExist(err) {
-		return "Input file/folder not found", false
-	}
-
-	if opts.RefineMode == "" {
-		return "refine-mode should be either ADD or REPLACE", false
+	if err := pkg.ValidateMergeOptions(opts); err != nil {
+		return err.Error(), false
 	}
 
 	return "", true
@@ -267,6 +450,27 @@ func mainCommandVerifyLas(args []string, cmd string) {
 		return
 	}
 
+	opts := getOptionsForCommandVerify(&flags, cmd)
+
+	// Starts the tiler
+	// defer timeTrack(time.Now(), "tiler")
+	fileFinder := tools.NewStandardFileFinder()
+	algorithmManager, err := std_algorithm_manager.NewAlgorithmManager(opts)
+	if err != nil {
+		glog.Fatal("Error while tiling: ", err)
+	}
+	err = pkg.NewTilerVerify(fileFinder, algorithmManager).RunTiler(context.Background(), opts)
+
+	if err != nil {
+		glog.Fatal("Error while tiling: ", err)
+	} else {
+		glog.Infoln("Conversion Completed")
+	}
+
+}
+
+// Builds the options of the verify commands from the flags and the configuration file and validates them
+func getOptionsForCommandVerify(flags *tools.FlagsForCommandVerify, cmd string) *tiler.TilerOptions {
 	glog.Infoln("flags", tools.FmtJSONString(flags))
 
 	tilerFlags := flags.TilerFlags
@@ -281,12 +485,17 @@ func mainCommandVerifyLas(args []string, cmd string) {
 		MaxNumPointsPerNode:    int32(*tilerFlags.MaxNumPoints),
 		MinNumPointsPerNode:    int32(*tilerFlags.MinNumPoints),
 		EnableGeoidZCorrection: *tilerFlags.ZGeoidCorrection,
+		GeoidGrid:              *tilerFlags.GeoidGrid,
+		GeoidInterpolation:     tiler.ParseGeoidInterpolation(*tilerFlags.GeoidInterpolation),
 		FolderProcessing:       *tilerFlags.FolderProcessing,
 		Recursive:              *tilerFlags.RecursiveFolderProcessing,
-		Algorithm:              tiler.Algorithm(strings.ToUpper(*tilerFlags.Algorithm)),
+		Algorithm:              tiler.ParseAlgorithm(*tilerFlags.Algorithm),
 		CellMinSize:            *tilerFlags.GridCellMinSize,
 		CellMaxSize:            *tilerFlags.GridCellMaxSize,
 		RefineMode:             tiler.ParseRefineMode(*tilerFlags.RefineMode),
+		Sampling:               tiler.ParseSamplingStrategy(*tilerFlags.Sampling),
+		CoordinateConverter:    tiler.ParseCoordinateConverterType(*tilerFlags.CoordinateConverter),
+		WorkDir:                *tilerFlags.WorkDir,
 		TilerVerifyOptions: &tiler.TilerVerifyOptions{
 			Output:      "",
 			OffsetBegin: 0,
@@ -294,23 +503,14 @@ func mainCommandVerifyLas(args []string, cmd string) {
 		},
 	}
 
+	applyConfigFile(&opts, flags.FlagCommand, *tilerFlags.Config)
+
 	// Validate TilerOptions
-	if msg, res := validateOptionsForCommandVerify(&opts, &flags); !res {
+	if msg, res := validateOptionsForCommandVerify(&opts, flags); !res {
 		glog.Fatal("Error parsing input parameters: " + msg)
 	}
 
-	// Starts the tiler
-	// defer timeTrack(time.Now(), "tiler")
-	fileFinder := tools.NewStandardFileFinder()
-	algorithmManager := std_algorithm_manager.NewAlgorithmManager(&opts)
-	err := pkg.NewTilerVerify(fileFinder, algorithmManager).RunTiler(&opts)
-
-	if err != nil {
-		glog.Fatal("Error while tiling: ", err)
-	} else {
-		glog.Infoln("Conversion Completed")
-	}
-
+	return &opts
 }
 
 func validateOptionsForCommandVerify(opts *tiler.TilerOptions, flags *tools.FlagsForCommandVerify) (string, bool) {
@@ -322,9 +522,186 @@ func validateOptionsForCommandVerify(opts *tiler.TilerOptions, flags *tools.Flag
 		return "refine-mode should be either ADD or REPLACE", false
 	}
 
+	if opts.Sampling == "" {
+		return "sampling should be either closest, centroid, random, highest, lowest or min-distance", false
+	}
+
+	if opts.CoordinateConverter == "" {
+		return "coordinate-converter should be either proj4 or native", false
+	}
+
+	if err := pkg.ValidateGeoidOptions(opts); err != nil {
+		return err.Error(), false
+	}
+
+	if err := pkg.ValidateWorkDir(opts); err != nil {
+		return err.Error(), false
+	}
+
 	return "", true
 }
 
+func mainCommandVerifyTileset(args []string) {
+	flags := tools.ParseFlagsForCommandVerifyTileset(args)
+
+	// Prints the command line flag description
+	if *flags.Help {
+		showHelpForSubCommand(flags.FlagCommand)
+		return
+	}
+
+	if *flags.Version {
+		printVersion()
+		return
+	}
+
+	opts := getOptionsForCommandVerifyTileset(&flags)
+
+	if err := pkg.NewTilerVerifyTileset().RunTiler(context.Background(), opts); err != nil {
+		glog.Fatal("Error while verifying: ", err)
+	} else {
+		glog.Infoln("Verification Completed")
+	}
+}
+
+// Builds the options of the verify-tileset command from the flags and the configuration file and validates them
+func getOptionsForCommandVerifyTileset(flags *tools.FlagsForCommandVerifyTileset) *tiler.TilerOptions {
+	glog.Infoln("flags", tools.FmtJSONString(flags))
+
+	opts := tiler.TilerOptions{
+		Command: tools.CommandVerifyTileset,
+		Input:   *flags.Input,
+		TilerVerifyTilesetOptions: &tiler.TilerVerifyTilesetOptions{
+			Report: *flags.Report,
+		},
+	}
+
+	applyConfigFile(&opts, flags.FlagCommand, *flags.Config)
+
+	if _, err := os.Stat(opts.Input); os.IsNotExist(err) {
+		glog.Fatal("Error parsing input parameters: Input file/folder not found")
+	}
+
+	return &opts
+}
+
+func mainCommandServe(args []string) {
+	flags := tools.ParseFlagsForCommandServe(args)
+
+	// Prints the command line flag description
+	if *flags.Help {
+		showHelpForSubCommand(flags.FlagCommand)
+		return
+	}
+
+	if *flags.Version {
+		printVersion()
+		return
+	}
+
+	opts := getOptionsForCommandServe(&flags)
+
+	if err := pkg.NewTilerServe().RunTiler(context.Background(), opts); err != nil {
+		glog.Fatal("Error while serving: ", err)
+	}
+}
+
+// Builds the options of the serve command from the flags and the configuration file and validates them
+func getOptionsForCommandServe(flags *tools.FlagsForCommandServe) *tiler.TilerOptions {
+	glog.Infoln("flags", tools.FmtJSONString(flags))
+
+	opts := tiler.TilerOptions{
+		Command: tools.CommandServe,
+		Input:   *flags.Input,
+		TilerServeOptions: &tiler.TilerServeOptions{
+			Address: *flags.Address,
+			Viewer:  *flags.Viewer,
+		},
+	}
+
+	applyConfigFile(&opts, flags.FlagCommand, *flags.Config)
+
+	// Validate TilerOptions
+	if msg, res := validateOptionsForCommandServe(&opts); !res {
+		glog.Fatal("Error parsing input parameters: " + msg)
+	}
+
+	return &opts
+}
+
+func validateOptionsForCommandServe(opts *tiler.TilerOptions) (string, bool) {
+	if info, err := os.Stat(opts.Input); os.IsNotExist(err) {
+		return "Input folder not found", false
+	} else if err == nil && !info.IsDir() {
+		return "Input must be a folder", false
+	}
+
+	if opts.TilerServeOptions.Address == "" {
+		return "address cannot be empty", false
+	}
+
+	return "", true
+}
+
+// Applies the configuration file eventually given to the options, the flags set on the command line override its
+// values. Returns the configuration keys set either by the flags or by the file
+func applyConfigFile(opts *tiler.TilerOptions, flagCommand *flag.FlagSet, configPath string) map[string]bool {
+	flagKeys := tools.GetFlagConfigKeys(flagCommand)
+	if configPath == "" {
+		return flagKeys
+	}
+
+	configKeys, err := tools.ApplyConfigFile(opts, configPath, flagKeys)
+	if err != nil {
+		glog.Fatal("Error parsing configuration file: ", err)
+	}
+	glog.Infof("applied configuration file [%s]", configPath)
+
+	for key := range configKeys {
+		flagKeys[key] = true
+	}
+	return flagKeys
+}
+
+// Prints the effective options of the given command as a json configuration file, which can be given back to the
+// command with the config flag to reproduce the run
+func mainCommandConfig(args []string) {
+	if len(args) < 2 || args[0] != "dump" {
+		glog.Fatal("Please specify the command whose configuration is printed: config dump <command> [flags]")
+	}
+	cmd, args := args[1], args[2:]
+
+	var opts *tiler.TilerOptions
+	switch cmd {
+	case tools.CommandIndex:
+		flags := tools.ParseFlagsForCommandIndex(args)
+		opts = getOptionsForCommandIndex(&flags)
+	case tools.CommandUpdate:
+		flags := tools.ParseFlagsForCommandUpdate(args)
+		opts = getOptionsForCommandUpdate(&flags)
+	case tools.CommandMergeChildren, tools.CommandMergeTree:
+		flags := tools.ParseFlagsForCommandMerge(args)
+		opts = getOptionsForCommandMerge(&flags, cmd)
+	case tools.CommandVerifyLas, tools.CommandVerifyLasMerge:
+		flags := tools.ParseFlagsForCommandVerify(args)
+		opts = getOptionsForCommandVerify(&flags, cmd)
+	case tools.CommandVerifyTileset:
+		flags := tools.ParseFlagsForCommandVerifyTileset(args)
+		opts = getOptionsForCommandVerifyTileset(&flags)
+	case tools.CommandServe:
+		flags := tools.ParseFlagsForCommandServe(args)
+		opts = getOptionsForCommandServe(&flags)
+	default:
+		glog.Fatalf("Unrecognized command [%q]. Command must be one of [index|update|merge-tree|merge-children|verify-las|verify-las-merge|verify-tileset|serve]", cmd)
+	}
+
+	data, err := json.MarshalIndent(opts, "", "  ")
+	if err != nil {
+		glog.Fatal("Error printing configuration: ", err)
+	}
+	fmt.Println(string(data))
+}
+
 func timeTrack(start time.Time, name string) {
 	elapsed := time.Since(start)
 	glog.Infoln(fmt.Sprintf("%s took %s", name, elapsed))
@@ -341,7 +718,7 @@ func showHelp() {
 	printVersion()
 	fmt.Println("***")
 	fmt.Println("")
-	fmt.Println("Usage: ./cesium_tiler < index | merge-tree | merge-children | verify-las | verify-las-merge >")
+	fmt.Println("Usage: ./cesium_tiler < index | update | merge-tree | merge-children | verify-las | verify-las-merge | verify-tileset | serve | config dump <command> >")
 	fmt.Println("")
 	fmt.Println("Command line flags: ")
 	flag.CommandLine.SetOutput(os.Stdout)
@@ -2,11 +2,11 @@ package algorithm_manager
 
 import (
 	"github.com/ecopia-map/cesium_tiler/internal/converters"
-	"github.com/ecopia-map/cesium_tiler/internal/octree/grid_tree"
+	"github.com/ecopia-map/cesium_tiler/internal/octree"
 )
 
 type AlgorithmManager interface {
 	GetElevationCorrectionAlgorithm() converters.ElevationCorrector
-	GetTreeAlgorithm() *grid_tree.GridTree
+	GetTreeAlgorithm() octree.ITree
 	GetCoordinateConverterAlgorithm() converters.CoordinateConverter
 }
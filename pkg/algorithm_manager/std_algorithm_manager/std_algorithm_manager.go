@@ -1,29 +1,56 @@
 package std_algorithm_manager
 
 import (
+	"fmt"
+
 	"github.com/ecopia-map/cesium_tiler/internal/converters"
+	"github.com/ecopia-map/cesium_tiler/internal/converters/coordinate/native_coordinate_converter"
 	"github.com/ecopia-map/cesium_tiler/internal/converters/coordinate/proj4_coordinate_converter"
 	"github.com/ecopia-map/cesium_tiler/internal/converters/elevation/geoid_elevation_corrector"
 	"github.com/ecopia-map/cesium_tiler/internal/converters/elevation/offset_elevation_corrector"
 	"github.com/ecopia-map/cesium_tiler/internal/converters/elevation/pipeline_elevation_corrector"
 	"github.com/ecopia-map/cesium_tiler/internal/converters/geoid_offset/gh_offset_calculator"
+	"github.com/ecopia-map/cesium_tiler/internal/converters/geoid_offset/grid_offset_calculator"
+	"github.com/ecopia-map/cesium_tiler/internal/octree"
 	"github.com/ecopia-map/cesium_tiler/internal/octree/grid_tree"
+	"github.com/ecopia-map/cesium_tiler/internal/octree/random_trees"
 	"github.com/ecopia-map/cesium_tiler/internal/tiler"
 	"github.com/ecopia-map/cesium_tiler/pkg/algorithm_manager"
 	"github.com/golang/glog"
 )
 
+// Size in degrees of the cells sharing the same geoid grid height, about 50m. The error it introduces is a few
+// millimeters as the geoid varies slowly, while it keeps the cache small for surveys of tens of kilometers
+const geoidGridCacheCellSize = 0.0005
+
 type StandardAlgorithmManager struct {
 	options             *tiler.TilerOptions
 	coordinateConverter converters.CoordinateConverter
 	elevationCorrector  converters.ElevationCorrector
 }
 
-func NewAlgorithmManager(opts *tiler.TilerOptions) algorithm_manager.AlgorithmManager {
-	coordinateConverter := proj4_coordinate_converter.NewProj4CoordinateConverter()
-	ellipsoidToGeoidOffsetCalculator := gh_offset_calculator.NewEllipsoidToGeoidGHOffsetCalculator(coordinateConverter)
-	elevationCorrectionAlgorithm := evaluateElevationCorrectionAlgorithm(
+// Instances the algorithms selected by the given options. An error is returned if the tree algorithm is unknown or if
+// the coordinate converter, the gravitational model or the geoid grid cannot be loaded
+func NewAlgorithmManager(opts *tiler.TilerOptions) (algorithm_manager.AlgorithmManager, error) {
+	if !isTreeAlgorithmSupported(opts.Algorithm) {
+		return nil, fmt.Errorf("unrecognized algorithm [%s], it should be either grid, random or randombox", string(opts.Algorithm))
+	}
+
+	coordinateConverter, err := evaluateCoordinateConverterAlgorithm(opts)
+	if err != nil {
+		return nil, err
+	}
+	ellipsoidToGeoidOffsetCalculator, err := gh_offset_calculator.NewEllipsoidToGeoidGHOffsetCalculator(coordinateConverter)
+	if err != nil {
+		coordinateConverter.Cleanup()
+		return nil, err
+	}
+	elevationCorrectionAlgorithm, err := evaluateElevationCorrectionAlgorithm(
 		opts, ellipsoidToGeoidOffsetCalculator, coordinateConverter)
+	if err != nil {
+		coordinateConverter.Cleanup()
+		return nil, err
+	}
 
 	algorithmManager := &StandardAlgorithmManager{
 		options:             opts,
@@ -31,14 +58,14 @@ func NewAlgorithmManager(opts *tiler.TilerOptions) algorithm_manager.AlgorithmMa
 		elevationCorrector:  elevationCorrectionAlgorithm,
 	}
 
-	return algorithmManager
+	return algorithmManager, nil
 }
 
 func (am *StandardAlgorithmManager) GetElevationCorrectionAlgorithm() converters.ElevationCorrector {
 	return am.elevationCorrector
 }
 
-func (am *StandardAlgorithmManager) GetTreeAlgorithm() *grid_tree.GridTree {
+func (am *StandardAlgorithmManager) GetTreeAlgorithm() octree.ITree {
 	return evaluateTreeAlgorithm(am.options, am.coordinateConverter, am.elevationCorrector)
 }
 
@@ -46,38 +73,78 @@ func (am *StandardAlgorithmManager) GetCoordinateConverterAlgorithm() converters
 	return am.coordinateConverter
 }
 
+func evaluateCoordinateConverterAlgorithm(options *tiler.TilerOptions) (converters.CoordinateConverter, error) {
+	if options.CoordinateConverter == tiler.CoordinateConverterNative {
+		return native_coordinate_converter.NewNativeCoordinateConverter()
+	}
+	return proj4_coordinate_converter.NewProj4CoordinateConverter()
+}
+
 func evaluateElevationCorrectionAlgorithm(
 	options *tiler.TilerOptions,
 	ellipsoidToGeoidOffsetCalculator converters.EllipsoidToGeoidOffsetCalculator,
 	converter converters.CoordinateConverter,
-) converters.ElevationCorrector {
+) (converters.ElevationCorrector, error) {
 
 	var elevationCorrectors []converters.ElevationCorrector
 	elevationCorrectors = append(elevationCorrectors,
 		offset_elevation_corrector.NewOffsetElevationCorrector(options.ZOffset))
 
-	if options.EnableGeoidZCorrection {
+	if options.EnableGeoidZCorrection && options.GeoidGrid != "" {
+		offsetCalculator, err := evaluateGeoidGridOffsetCalculator(options, converter)
+		if err != nil {
+			return nil, err
+		}
+		elevationCorrectors = append(elevationCorrectors,
+			geoid_elevation_corrector.NewBufferedGeoidElevationCorrector(geoidGridCacheCellSize, offsetCalculator))
+	} else if options.EnableGeoidZCorrection {
 		elevationCorrectors = append(elevationCorrectors,
 			geoid_elevation_corrector.NewGeoidElevationCorrector(options.Srid, ellipsoidToGeoidOffsetCalculator))
 	}
 
-	return pipeline_elevation_corrector.NewPipelineElevationCorrector(elevationCorrectors)
+	return pipeline_elevation_corrector.NewPipelineElevationCorrector(elevationCorrectors), nil
+}
+
+func evaluateGeoidGridOffsetCalculator(
+	options *tiler.TilerOptions,
+	converter converters.CoordinateConverter,
+) (converters.EllipsoidToGeoidOffsetCalculator, error) {
+	interpolation := grid_offset_calculator.Bilinear
+	if options.GeoidInterpolation == tiler.GeoidInterpolationBiquadratic {
+		interpolation = grid_offset_calculator.Biquadratic
+	}
+
+	offsetCalculator, err := grid_offset_calculator.NewEllipsoidToGeoidGridOffsetCalculator(options.GeoidGrid, interpolation, converter)
+	if err != nil {
+		return nil, fmt.Errorf("error loading geoid grid: %v", err)
+	}
+	glog.Infof("loaded geoid grid [%s]", options.GeoidGrid)
+	return offsetCalculator, nil
 }
 
 func evaluateTreeAlgorithm(
 	options *tiler.TilerOptions,
 	converter converters.CoordinateConverter,
 	elevationCorrection converters.ElevationCorrector,
-) *grid_tree.GridTree {
+) octree.ITree {
 	switch options.Algorithm {
 	case tiler.Grid:
-		return grid_tree.NewGridTree(converter, elevationCorrection, options.CellMaxSize, options.CellMinSize)
-		// case tiler.RandomBox:
-		// 	return random_trees.NewBoxedRandomTree(options, converter, elevationCorrection)
-		// case tiler.Random:
-		// 	return random_trees.NewRandomTree(options, converter, elevationCorrection)
+		return grid_tree.NewGridTree(converter, elevationCorrection, options.CellMaxSize, options.CellMinSize, options.Sampling)
+	case tiler.RandomBox:
+		return random_trees.NewBoxedRandomTree(options, converter, elevationCorrection)
+	case tiler.Random:
+		return random_trees.NewRandomTree(options, converter, elevationCorrection)
 	}
 
-	glog.Fatal("Unrecognized strategy")
+	// the algorithm is checked by NewAlgorithmManager
 	return nil
 }
+
+// Returns true if the given tree algorithm is one of the ones evaluateTreeAlgorithm instances
+func isTreeAlgorithmSupported(algorithm tiler.Algorithm) bool {
+	switch algorithm {
+	case tiler.Grid, tiler.RandomBox, tiler.Random:
+		return true
+	}
+	return false
+}
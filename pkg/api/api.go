@@ -0,0 +1,301 @@
+// Package api exposes the tiler as a Go library. Errors are returned to the caller instead of terminating the process
+// and the tiling stops as soon as the given context is canceled.
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"reflect"
+	"time"
+
+	"github.com/ecopia-map/cesium_tiler/internal/progress"
+	"github.com/ecopia-map/cesium_tiler/internal/tiler"
+	"github.com/ecopia-map/cesium_tiler/pkg"
+	"github.com/ecopia-map/cesium_tiler/pkg/algorithm_manager/std_algorithm_manager"
+	"github.com/ecopia-map/cesium_tiler/tools"
+)
+
+// Options of a tiling run, Srid 0 means that the srid is read from the input files
+type Options = tiler.TilerOptions
+type IndexOptions = tiler.TilerIndexOptions
+type MergeOptions = tiler.TilerMergeOptions
+type FilterOptions = tiler.TilerFilterOptions
+type OutlierOptions = tiler.TilerOutlierOptions
+
+type Algorithm = tiler.Algorithm
+type RefineMode = tiler.RefineMode
+type SamplingStrategy = tiler.SamplingStrategy
+type CoordinateConverterType = tiler.CoordinateConverterType
+type OutputFormat = tiler.OutputFormat
+type DracoMethod = tiler.DracoMethod
+type BoundingVolumeType = tiler.BoundingVolumeType
+type ReturnFilter = tiler.ReturnFilter
+type FlagFilter = tiler.FlagFilter
+type BatchAttribute = tiler.BatchAttribute
+type GeoidInterpolation = tiler.GeoidInterpolation
+type ProgressMode = tiler.ProgressMode
+
+// Receives the progress events of the stages of a run when set as the ProgressReporter of the options
+type ProgressReporter = progress.Reporter
+type ProgressEvent = progress.Event
+
+const (
+	Grid      Algorithm = tiler.Grid
+	Random    Algorithm = tiler.Random
+	RandomBox Algorithm = tiler.RandomBox
+
+	RefineModeAdd     RefineMode = tiler.RefineModeAdd
+	RefineModeReplace RefineMode = tiler.RefineModeReplace
+
+	SamplingClosest     SamplingStrategy = tiler.SamplingClosest
+	SamplingCentroid    SamplingStrategy = tiler.SamplingCentroid
+	SamplingRandom      SamplingStrategy = tiler.SamplingRandom
+	SamplingHighest     SamplingStrategy = tiler.SamplingHighest
+	SamplingLowest      SamplingStrategy = tiler.SamplingLowest
+	SamplingMinDistance SamplingStrategy = tiler.SamplingMinDistance
+
+	CoordinateConverterProj4  CoordinateConverterType = tiler.CoordinateConverterProj4
+	CoordinateConverterNative CoordinateConverterType = tiler.CoordinateConverterNative
+
+	OutputFormatPnts OutputFormat = tiler.OutputFormatPnts
+	OutputFormatGlb  OutputFormat = tiler.OutputFormatGlb
+
+	DracoMethodSequential DracoMethod = tiler.DracoMethodSequential
+	DracoMethodKdTree     DracoMethod = tiler.DracoMethodKdTree
+
+	BoundingVolumeRegion BoundingVolumeType = tiler.BoundingVolumeRegion
+	BoundingVolumeBox    BoundingVolumeType = tiler.BoundingVolumeBox
+	BoundingVolumeSphere BoundingVolumeType = tiler.BoundingVolumeSphere
+
+	ReturnFilterAll   ReturnFilter = tiler.ReturnFilterAll
+	ReturnFilterFirst ReturnFilter = tiler.ReturnFilterFirst
+	ReturnFilterLast  ReturnFilter = tiler.ReturnFilterLast
+
+	FlagFilterKeep FlagFilter = tiler.FlagFilterKeep
+	FlagFilterDrop FlagFilter = tiler.FlagFilterDrop
+	FlagFilterOnly FlagFilter = tiler.FlagFilterOnly
+
+	BatchAttributeIntensity       BatchAttribute = tiler.BatchAttributeIntensity
+	BatchAttributeClassification  BatchAttribute = tiler.BatchAttributeClassification
+	BatchAttributeGpsTime         BatchAttribute = tiler.BatchAttributeGpsTime
+	BatchAttributeReturnNumber    BatchAttribute = tiler.BatchAttributeReturnNumber
+	BatchAttributeNumberOfReturns BatchAttribute = tiler.BatchAttributeNumberOfReturns
+	BatchAttributePointSourceId   BatchAttribute = tiler.BatchAttributePointSourceId
+	BatchAttributeScanAngle       BatchAttribute = tiler.BatchAttributeScanAngle
+	BatchAttributeUserData        BatchAttribute = tiler.BatchAttributeUserData
+	BatchAttributeNir             BatchAttribute = tiler.BatchAttributeNir
+
+	GeoidInterpolationBilinear    GeoidInterpolation = tiler.GeoidInterpolationBilinear
+	GeoidInterpolationBiquadratic GeoidInterpolation = tiler.GeoidInterpolationBiquadratic
+
+	ProgressModeLog  ProgressMode = tiler.ProgressModeLog
+	ProgressModeBar  ProgressMode = tiler.ProgressModeBar
+	ProgressModeJson ProgressMode = tiler.ProgressModeJson
+)
+
+// Returns the batch attribute reading the extra bytes attribute of the las files with the given name
+var ExtraBytesBatchAttribute = tiler.ExtraBytesBatchAttribute
+
+const (
+	CommandMergeTree     = tools.CommandMergeTree
+	CommandMergeChildren = tools.CommandMergeChildren
+)
+
+// Assets loaded by the coordinate converter and by the geoid offset calculator, relative to the root folder
+var requiredAssets = []string{
+	path.Join("assets", "epsg_projections.txt"),
+	path.Join("assets", "egm180.nor"),
+}
+
+// Returns the batch attributes written by default, as the command line tool
+func defaultBatchAttributes() []tiler.BatchAttribute {
+	return []tiler.BatchAttribute{tiler.BatchAttributeIntensity, tiler.BatchAttributeClassification}
+}
+
+// Returns true if the batch attributes of the given options differ from the default ones
+func isBatchAttributesSet(opts *Options) bool {
+	return !reflect.DeepEqual(opts.BatchAttributes, defaultBatchAttributes())
+}
+
+// Outcome of a tiling run
+type Result struct {
+	Files   []string      // Las files read by the run
+	Srid    int           // Srid of the input points
+	Elapsed time.Duration // Duration of the run
+}
+
+// Returns the options of the index command with the same defaults as the command line tool
+func DefaultIndexOptions(input string, output string) *Options {
+	return &Options{
+		Input:                 input,
+		MaxNumPointsPerNode:   160000,
+		MinNumPointsPerNode:   10000,
+		Algorithm:             tiler.Grid,
+		GeoidInterpolation:    tiler.GeoidInterpolationBilinear,
+		CellMaxSize:           5.0,
+		CellMinSize:           0.15,
+		RefineMode:            tiler.RefineModeAdd,
+		Sampling:              tiler.SamplingClosest,
+		CoordinateConverter:   tiler.CoordinateConverterProj4,
+		DracoMethod:           tiler.DracoMethodKdTree,
+		DracoQuantizationBits: 11,
+		OutputFormat:          tiler.OutputFormatPnts,
+		BoundingVolume:        tiler.BoundingVolumeRegion,
+		BatchAttributes:       defaultBatchAttributes(),
+		Progress:              tiler.ProgressModeLog,
+		Command:               tools.CommandIndex,
+		TilerIndexOptions: &tiler.TilerIndexOptions{
+			Output:        output,
+			SubtreeLevels: 5,
+			Jobs:          1,
+			Filter: tiler.TilerFilterOptions{
+				Returns:   tiler.ReturnFilterAll,
+				Withheld:  tiler.FlagFilterKeep,
+				Synthetic: tiler.FlagFilterKeep,
+			},
+			Outliers: tiler.TilerOutlierOptions{
+				SorSigma:  2,
+				SorRadius: 1,
+				RorRadius: 1,
+			},
+		},
+	}
+}
+
+// Returns the options of the merge-tree command with the same defaults as the command line tool
+func DefaultMergeOptions(input string) *Options {
+	return &Options{
+		Input:                 input,
+		MaxNumPointsPerNode:   160000,
+		MinNumPointsPerNode:   10000,
+		Algorithm:             tiler.Grid,
+		GeoidInterpolation:    tiler.GeoidInterpolationBilinear,
+		CellMaxSize:           10.0,
+		CellMinSize:           5.0,
+		RefineMode:            tiler.RefineModeAdd,
+		Sampling:              tiler.SamplingClosest,
+		CoordinateConverter:   tiler.CoordinateConverterProj4,
+		DracoMethod:           tiler.DracoMethodKdTree,
+		DracoQuantizationBits: 11,
+		OutputFormat:          tiler.OutputFormatPnts,
+		BoundingVolume:        tiler.BoundingVolumeRegion,
+		BatchAttributes:       defaultBatchAttributes(),
+		Progress:              tiler.ProgressModeLog,
+		Command:               tools.CommandMergeTree,
+		TilerMergeOptions:     &tiler.TilerMergeOptions{},
+	}
+}
+
+// Sets the options to the values of the given yaml or json configuration file, as the config flag of the command line
+// tool. Keys of the sections of other commands are checked but ignored
+func ApplyConfigFile(options *Options, configPath string) error {
+	_, err := tools.ApplyConfigFile(options, configPath, nil)
+	return err
+}
+
+// Converts the las files of the input into a chunk tileset each, written in the output folder.
+// The given options are not modified
+func Index(ctx context.Context, options *Options) (Result, error) {
+	start := time.Now()
+
+	opts := options.Copy()
+	opts.Command = tools.CommandIndex
+
+	pkg.DropDracoEncoderBatchAttributes(opts, isBatchAttributesSet(opts))
+	if err := pkg.ValidateIndexOptions(opts); err != nil {
+		return Result{}, err
+	}
+	if err := checkAssets(); err != nil {
+		return Result{}, err
+	}
+
+	fileFinder := tools.NewStandardFileFinder()
+	lasFiles, err := fileFinder.GetLasFilesToProcess(opts)
+	if err != nil {
+		return Result{}, err
+	}
+
+	srid, err := pkg.DetectSrid(lasFiles, opts.Srid, opts.Srid != 0)
+	if err != nil {
+		return Result{}, err
+	}
+	opts.Srid = srid
+
+	algorithmManager, err := std_algorithm_manager.NewAlgorithmManager(opts)
+	if err != nil {
+		return Result{}, err
+	}
+	err = pkg.NewTiler(fileFinder, algorithmManager).RunTiler(ctx, opts)
+
+	return Result{
+		Files:   lasFiles,
+		Srid:    srid,
+		Elapsed: time.Since(start),
+	}, err
+}
+
+// Merges the chunk tilesets written by Index in the input folder into a single tileset. Command selects between
+// CommandMergeTree, the default, and CommandMergeChildren. The given options are not modified
+func Merge(ctx context.Context, options *Options) (Result, error) {
+	start := time.Now()
+
+	opts := options.Copy()
+	if opts.Command == "" {
+		opts.Command = tools.CommandMergeTree
+	}
+	if opts.Command != tools.CommandMergeTree && opts.Command != tools.CommandMergeChildren {
+		return Result{}, fmt.Errorf("unrecognized merge command [%s]", opts.Command)
+	}
+
+	pkg.DropDracoEncoderBatchAttributes(opts, isBatchAttributesSet(opts))
+	if err := pkg.ValidateMergeOptions(opts); err != nil {
+		return Result{}, err
+	}
+	if err := checkAssets(); err != nil {
+		return Result{}, err
+	}
+
+	fileFinder := tools.NewStandardFileFinder()
+	lasFiles, err := fileFinder.GetLasFilesToMerge(opts)
+	if err != nil {
+		return Result{}, err
+	}
+
+	srid, err := pkg.DetectSrid(lasFiles, opts.Srid, opts.Srid != 0)
+	if err != nil {
+		return Result{}, err
+	}
+	opts.Srid = srid
+
+	algorithmManager, err := std_algorithm_manager.NewAlgorithmManager(opts)
+	if err != nil {
+		return Result{}, err
+	}
+	err = pkg.NewTilerMerge(fileFinder, algorithmManager).RunTiler(ctx, opts)
+
+	return Result{
+		Files:   lasFiles,
+		Srid:    srid,
+		Elapsed: time.Since(start),
+	}, err
+}
+
+// Checks that the assets loaded when the algorithms are instanced exist, reporting the variable setting their folder
+func checkAssets() error {
+	rootFolder, err := tools.GetRootFolder()
+	if err != nil {
+		return err
+	}
+	for _, asset := range requiredAssets {
+		assetPath := path.Join(rootFolder, asset)
+		if _, err := os.Stat(assetPath); errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("asset [%s] not found, set CESIUM_TILER_WORKDIR to the folder containing the assets", assetPath)
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
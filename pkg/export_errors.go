@@ -0,0 +1,59 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/golang/glog"
+)
+
+// Collects the errors submitted by the consumers of an export. The first error cancels the context of the export so
+// that the producers stop submitting work
+type exportErrors struct {
+	channel chan error
+	cancel  context.CancelFunc
+	errs    []error
+	done    chan struct{}
+}
+
+// Instances the collector of the errors of the given number of consumers, each of which submits at most one error
+func newExportErrors(numConsumers int, cancel context.CancelFunc) *exportErrors {
+	exportErrors := &exportErrors{
+		channel: make(chan error, numConsumers),
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	go exportErrors.collect()
+
+	return exportErrors
+}
+
+func (e *exportErrors) collect() {
+	for err := range e.channel {
+		glog.Infoln(err)
+		e.errs = append(e.errs, err)
+		e.cancel()
+	}
+	close(e.done)
+}
+
+// Closes the error channel, once all the consumers are done, and returns the errors collected as a single error
+func (e *exportErrors) wait() error {
+	close(e.channel)
+	<-e.done
+
+	return joinErrors(e.errs)
+}
+
+// Returns nil if the list is empty, the error itself if there is only one, otherwise the first error and the number of
+// the others
+func joinErrors(errs []error) error {
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	default:
+		return fmt.Errorf("%v (and %d more errors, check console output for details)", errs[0], len(errs)-1)
+	}
+}
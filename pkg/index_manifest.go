@@ -0,0 +1,273 @@
+package pkg
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// Name of the manifest written by the index command in the output folder
+const IndexManifestFileName = "index-manifest.json"
+
+type ChunkStatus string
+
+const (
+	// The chunk tileset of the file is being written, its folder may contain partial output
+	ChunkStatusInProgress ChunkStatus = "in_progress"
+
+	// The chunk tileset of the file has been completely written
+	ChunkStatusCompleted ChunkStatus = "completed"
+)
+
+// Records the state of an input las file and of the chunk tileset written for it
+type IndexManifestEntry struct {
+	Path      string      `json:"path"`
+	Size      int64       `json:"size"`
+	ModTime   time.Time   `json:"mtime"`
+	Hash      string      `json:"sha256"`
+	Subfolder string      `json:"subfolder"`
+	Status    ChunkStatus `json:"status"`
+}
+
+// Checkpoint of an index run, stored in the output folder and updated as each input file is processed.
+// Safe for concurrent use
+type IndexManifest struct {
+	Files []*IndexManifestEntry `json:"files"`
+
+	filePath string
+	sync.Mutex
+}
+
+// Instances a new empty manifest stored in the given output folder
+func NewIndexManifest(outputFolder string) *IndexManifest {
+	return &IndexManifest{
+		Files:    make([]*IndexManifestEntry, 0),
+		filePath: path.Join(outputFolder, IndexManifestFileName),
+	}
+}
+
+// Reads the manifest stored in the given output folder, an empty manifest is returned if there is none. The relative
+// paths of the manifests written by older versions are made absolute, see resolveManifestEntryPath
+func LoadIndexManifest(outputFolder string) (*IndexManifest, error) {
+	manifest := NewIndexManifest(outputFolder)
+
+	jsonData, err := ioutil.ReadFile(manifest.filePath)
+	if os.IsNotExist(err) {
+		return manifest, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(jsonData, manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest [%s]: %v", manifest.filePath, err)
+	}
+
+	for _, entry := range manifest.Files {
+		if entry.Path, err = resolveManifestEntryPath(outputFolder, entry.Path); err != nil {
+			return nil, err
+		}
+	}
+
+	return manifest, nil
+}
+
+// Returns the absolute path of a las file recorded in the manifest of the given output folder. Relative paths are the
+// ones given to the index run, they are resolved from the current folder, as the run did, or from the output folder
+// if the file is not found there
+func resolveManifestEntryPath(outputFolder string, filePath string) (string, error) {
+	if filepath.IsAbs(filePath) {
+		return filePath, nil
+	}
+
+	absFilePath, err := filepath.Abs(filePath)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(absFilePath); err == nil {
+		return absFilePath, nil
+	}
+
+	absOutputFolder, err := filepath.Abs(outputFolder)
+	if err != nil {
+		return "", err
+	}
+	if outputFilePath := filepath.Join(absOutputFolder, filePath); outputFilePath != absFilePath {
+		if _, err := os.Stat(outputFilePath); err == nil {
+			return outputFilePath, nil
+		}
+	}
+
+	// the file is missing, reported with the path resolved from the current folder
+	return absFilePath, nil
+}
+
+// Writes the manifest to disk. The file is replaced atomically so that it is never left half written
+func (manifest *IndexManifest) Save() error {
+	manifest.Lock()
+	defer manifest.Unlock()
+
+	return manifest.save()
+}
+
+func (manifest *IndexManifest) save() error {
+	jsonData, err := json.MarshalIndent(manifest, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	tmpFilePath := manifest.filePath + ".tmp"
+	if err := ioutil.WriteFile(tmpFilePath, jsonData, 0666); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpFilePath, manifest.filePath)
+}
+
+// Returns the entry of the given input file, nil if the file is not in the manifest. The path must be absolute, as
+// recorded by NewIndexManifestEntry
+func (manifest *IndexManifest) GetEntry(filePath string) *IndexManifestEntry {
+	manifest.Lock()
+	defer manifest.Unlock()
+
+	return manifest.getEntry(filePath)
+}
+
+func (manifest *IndexManifest) getEntry(filePath string) *IndexManifestEntry {
+	for _, entry := range manifest.Files {
+		if entry.Path == filePath {
+			return entry
+		}
+	}
+	return nil
+}
+
+// Adds the given entry to the manifest, replacing the one of the same input file if present
+func (manifest *IndexManifest) SetEntry(newEntry *IndexManifestEntry) {
+	manifest.Lock()
+	defer manifest.Unlock()
+
+	for i, entry := range manifest.Files {
+		if entry.Path == newEntry.Path {
+			manifest.Files[i] = newEntry
+			return
+		}
+	}
+	manifest.Files = append(manifest.Files, newEntry)
+}
+
+// Sets the status of the entry of the given input file and writes the manifest to disk
+func (manifest *IndexManifest) SetStatus(filePath string, status ChunkStatus) error {
+	manifest.Lock()
+	defer manifest.Unlock()
+
+	entry := manifest.getEntry(filePath)
+	if entry == nil {
+		return fmt.Errorf("las_file [%s] not found in manifest", filePath)
+	}
+	entry.Status = status
+
+	return manifest.save()
+}
+
+// Builds the manifest entry of the given input file reading its size and modification time. The path is stored as
+// absolute, so that the manifest does not depend on the working directory of the run. The file is not hashed, see
+// IsCompletedFor and SetHash
+func NewIndexManifestEntry(filePath string, subfolder string) (*IndexManifestEntry, error) {
+	absFilePath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(absFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &IndexManifestEntry{
+		Path:      absFilePath,
+		Size:      info.Size(),
+		ModTime:   info.ModTime().UTC(),
+		Subfolder: subfolder,
+		Status:    ChunkStatusInProgress,
+	}, nil
+}
+
+// Hashes the input file of the entry, if it has not been hashed yet
+func (entry *IndexManifestEntry) SetHash() error {
+	if entry.Hash != "" {
+		return nil
+	}
+
+	hash, err := hashFile(entry.Path)
+	if err != nil {
+		return err
+	}
+	entry.Hash = hash
+
+	return nil
+}
+
+// Returns true if the chunk tileset of the entry is complete and has been written from the same input file as the
+// given entry. The input file is hashed only if its size and modification time match the ones of the entry
+func (entry *IndexManifestEntry) IsCompletedFor(current *IndexManifestEntry) (bool, error) {
+	if entry.Status != ChunkStatusCompleted ||
+		entry.Subfolder != current.Subfolder ||
+		entry.Size != current.Size ||
+		!entry.ModTime.Equal(current.ModTime) {
+		return false, nil
+	}
+
+	if err := current.SetHash(); err != nil {
+		return false, err
+	}
+
+	return entry.Hash == current.Hash, nil
+}
+
+// Removes the chunk tileset folder of the given subfolder and the spill folders left by an interrupted run
+func removePartialChunk(outputFolder string, subfolder string) error {
+	chunkFolder := path.Join(outputFolder, subfolder)
+	if _, err := os.Stat(chunkFolder); err == nil {
+		glog.Infof("removing partial chunk tileset [%s]", chunkFolder)
+		if err := os.RemoveAll(chunkFolder); err != nil {
+			return err
+		}
+	}
+
+	spillFolders, err := filepath.Glob(path.Join(outputFolder, "."+subfolder+"-spill-*"))
+	if err != nil {
+		return err
+	}
+	for _, spillFolder := range spillFolders {
+		if err := os.RemoveAll(spillFolder); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func hashFile(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,337 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/ecopia-map/cesium_tiler/internal/data"
+	"github.com/ecopia-map/cesium_tiler/internal/draco"
+	"github.com/ecopia-map/cesium_tiler/internal/point_filter"
+	"github.com/ecopia-map/cesium_tiler/internal/tiler"
+	"github.com/golang/glog"
+)
+
+// Validates the options of the index command checking that input and output folders/files exist
+func ValidateIndexOptions(opts *tiler.TilerOptions) error {
+	if opts.TilerIndexOptions == nil {
+		return errors.New("index options not set")
+	}
+
+	if _, err := os.Stat(opts.Input); os.IsNotExist(err) {
+		return errors.New("Input file/folder not found")
+	}
+	if _, err := os.Stat(opts.TilerIndexOptions.Output); os.IsNotExist(err) {
+		return errors.New("Output folder not found")
+	}
+
+	return validateIndexProcessingOptions(opts)
+}
+
+// Validates the options of the update command, the changed las files are indexed with its index options
+func ValidateUpdateOptions(opts *tiler.TilerOptions) error {
+	if opts.TilerIndexOptions == nil || opts.TilerUpdateOptions == nil {
+		return errors.New("update options not set")
+	}
+
+	if info, err := os.Stat(opts.Input); os.IsNotExist(err) {
+		return errors.New("Input folder not found")
+	} else if err == nil && !info.IsDir() {
+		return errors.New("Input must be the root folder of a merged tileset")
+	}
+
+	if opts.TilerUpdateOptions.MergeGridMaxSize <= 0 {
+		return errors.New("merge-grid-max-size must be greater than 0")
+	}
+
+	if err := ValidateWorkDir(opts); err != nil {
+		return err
+	}
+
+	return validateIndexProcessingOptions(opts)
+}
+
+// Validates the options of the index command controlling how the las files are processed
+func validateIndexProcessingOptions(opts *tiler.TilerOptions) error {
+	if err := validateTreeOptions(opts); err != nil {
+		return err
+	}
+
+	if err := validateOutputFormatOptions(opts); err != nil {
+		return err
+	}
+
+	if err := validateProgressOptions(opts); err != nil {
+		return err
+	}
+
+	if opts.TilerIndexOptions.ImplicitTiling && opts.TilerIndexOptions.SubtreeLevels < 1 {
+		return errors.New("subtree-levels must be greater than 0")
+	}
+
+	if opts.TilerIndexOptions.MemoryBudget < 0 {
+		return errors.New("memory-budget cannot be negative")
+	}
+
+	if opts.TilerIndexOptions.Jobs < 1 {
+		return errors.New("jobs must be greater than 0")
+	}
+
+	if opts.TilerIndexOptions.Workers < 0 {
+		return errors.New("workers cannot be negative")
+	}
+
+	if opts.TilerIndexOptions.MemoryBudget > 0 && opts.Algorithm != tiler.Grid {
+		return errors.New("memory-budget requires the grid algorithm")
+	}
+
+	if opts.TilerIndexOptions.MemoryBudget > 0 && opts.TilerIndexOptions.ImplicitTiling {
+		return errors.New("memory-budget cannot be used with implicit tiling")
+	}
+
+	if opts.BoundingVolume == tiler.BoundingVolumeSphere && opts.TilerIndexOptions.ImplicitTiling {
+		return errors.New("sphere bounding volume cannot be used with implicit tiling")
+	}
+
+	if err := validateOutlierOptions(&opts.TilerIndexOptions.Outliers); err != nil {
+		return err
+	}
+
+	if point_filter.IsOutlierFilterEnabled(&opts.TilerIndexOptions.Outliers) && opts.TilerIndexOptions.MemoryBudget > 0 {
+		return errors.New("outlier removal cannot be used with memory-budget, it needs all the points of a file in memory")
+	}
+
+	return validateFilterOptions(&opts.TilerIndexOptions.Filter)
+}
+
+// Validates the options of the removal of the noise points
+func validateOutlierOptions(outliers *tiler.TilerOutlierOptions) error {
+	if outliers.SorNeighbors < 0 || outliers.RorNeighbors < 0 {
+		return errors.New("sor-neighbors and ror-neighbors cannot be negative")
+	}
+
+	if outliers.SorNeighbors > 0 {
+		if outliers.SorRadius <= 0 {
+			return errors.New("sor-radius must be greater than 0")
+		}
+		if outliers.SorSigma < 0 {
+			return errors.New("sor-sigma cannot be negative")
+		}
+	}
+
+	if outliers.RorNeighbors > 0 && outliers.RorRadius <= 0 {
+		return errors.New("ror-radius must be greater than 0")
+	}
+
+	return nil
+}
+
+// Validates the filters applied to the points of the las files
+func validateFilterOptions(filter *tiler.TilerFilterOptions) error {
+	if filter.Returns == "" {
+		return errors.New("filter-returns should be either all, first or last")
+	}
+
+	if filter.Withheld == "" || filter.Synthetic == "" {
+		return errors.New("filter-withheld and filter-synthetic should be either keep, drop or only")
+	}
+
+	if filter.ZMin != nil && filter.ZMax != nil && *filter.ZMin > *filter.ZMax {
+		return errors.New("filter-z-range min cannot be greater than max")
+	}
+
+	if len(filter.ClipBox) > 0 {
+		if len(filter.ClipBox) != 4 {
+			return errors.New("clip-box should be xmin,ymin,xmax,ymax")
+		}
+		if filter.ClipBox[0] > filter.ClipBox[2] || filter.ClipBox[1] > filter.ClipBox[3] {
+			return errors.New("clip-box min values cannot be greater than max values")
+		}
+	}
+
+	if filter.ClipPolygon != "" {
+		if _, err := os.Stat(filter.ClipPolygon); os.IsNotExist(err) {
+			return errors.New("clip-polygon file not found")
+		}
+	}
+
+	if filter.ClipSrid < 0 {
+		return errors.New("clip-srid cannot be negative")
+	}
+
+	return nil
+}
+
+// Validates the options of the merge commands checking that the input folder exists
+func ValidateMergeOptions(opts *tiler.TilerOptions) error {
+	if opts.TilerMergeOptions == nil {
+		return errors.New("merge options not set")
+	}
+
+	if _, err := os.Stat(opts.Input); os.IsNotExist(err) {
+		return errors.New("Input file/folder not found")
+	}
+
+	if err := validateTreeOptions(opts); err != nil {
+		return err
+	}
+
+	if opts.Algorithm != tiler.Grid {
+		return errors.New("the merge commands only support the grid algorithm")
+	}
+
+	if err := validateOutputFormatOptions(opts); err != nil {
+		return err
+	}
+
+	if err := ValidateWorkDir(opts); err != nil {
+		return err
+	}
+
+	return validateProgressOptions(opts)
+}
+
+// Validates the folder of the temporary files of the merge, if given it must be an existing folder
+func ValidateWorkDir(opts *tiler.TilerOptions) error {
+	if opts.WorkDir == "" {
+		return nil
+	}
+
+	if info, err := os.Stat(opts.WorkDir); os.IsNotExist(err) {
+		return errors.New("workdir folder not found")
+	} else if err == nil && !info.IsDir() {
+		return errors.New("workdir must be a folder")
+	}
+
+	return nil
+}
+
+// Validates the options that control how the points are sampled into the tree
+func validateTreeOptions(opts *tiler.TilerOptions) error {
+	if opts.Algorithm == "" {
+		return errors.New("algorithm should be either grid, random or randombox")
+	}
+
+	if opts.CellMinSize > opts.CellMaxSize {
+		return errors.New("grid-max-size parameter cannot be lower than grid-min-size parameter")
+	}
+
+	if opts.RefineMode == "" {
+		return errors.New("refine-mode should be either ADD or REPLACE")
+	}
+
+	if opts.Sampling == "" {
+		return errors.New("sampling should be either closest, centroid, random, highest, lowest or min-distance")
+	}
+
+	if opts.CoordinateConverter == "" {
+		return errors.New("coordinate-converter should be either proj4 or native")
+	}
+
+	if opts.BoundingVolume == "" {
+		return errors.New("bounding-volume should be either region, box or sphere")
+	}
+
+	return ValidateGeoidOptions(opts)
+}
+
+// Validates the options of the geoid correction checking that the geoid grid file exists
+func ValidateGeoidOptions(opts *tiler.TilerOptions) error {
+	if opts.GeoidGrid == "" {
+		return nil
+	}
+
+	if !opts.EnableGeoidZCorrection {
+		return errors.New("geoid-grid requires the geoid correction to be enabled")
+	}
+
+	if _, err := os.Stat(opts.GeoidGrid); os.IsNotExist(err) {
+		return errors.New("geoid-grid file not found")
+	}
+
+	if opts.GeoidInterpolation == "" {
+		return errors.New("geoid-interpolation should be either bilinear or biquadratic")
+	}
+
+	return nil
+}
+
+// Validates the options that control how the tile content is encoded
+func validateOutputFormatOptions(opts *tiler.TilerOptions) error {
+	if opts.OutputFormat == "" {
+		return errors.New("output-format should be either pnts or glb")
+	}
+
+	// extra bytes names are turned into property names, different names may give the same property
+	propertyNames := map[string]bool{}
+	for _, attribute := range opts.BatchAttributes {
+		if attribute == "" {
+			return errors.New("batch-attributes should be a list of intensity, classification, gps-time, return-number, number-of-returns, point-source-id, scan-angle, user-data, nir or extra:<name>")
+		}
+		if propertyNames[attribute.PropertyName()] {
+			return fmt.Errorf("batch-attributes contains %s more than once", attribute.PropertyName())
+		}
+		propertyNames[attribute.PropertyName()] = true
+	}
+	if len(tiler.ExtraBytesNames(opts.BatchAttributes)) > data.MaxPointRecordExtraBytes {
+		return fmt.Errorf("batch-attributes should contain at most %d extra bytes attributes", data.MaxPointRecordExtraBytes)
+	}
+
+	if opts.Draco && opts.DracoMethod == "" {
+		return errors.New("draco-method should be either kd-tree or sequential")
+	}
+
+	if opts.Draco && (opts.DracoQuantizationBits < 1 || opts.DracoQuantizationBits > draco.MaxQuantizationBits) {
+		return fmt.Errorf("draco-quantization-bits should be between 1 and %d", draco.MaxQuantizationBits)
+	}
+
+	if opts.Meshopt && opts.OutputFormat != tiler.OutputFormatGlb {
+		return errors.New("meshopt requires output-format glb")
+	}
+
+	if opts.Meshopt && opts.Draco {
+		return errors.New("meshopt and draco cannot be used together")
+	}
+
+	// the points are reordered by the external encoder, which does not compress the batch attributes with them. The
+	// default attributes are dropped by DropDracoEncoderBatchAttributes, only the ones explicitly set get here
+	if usesExternalDracoEncoder(opts) && len(opts.BatchAttributes) > 0 {
+		return errors.New("draco-encoder-path cannot be used with batch-attributes, set an empty list of batch-attributes or use the in-process draco encoder")
+	}
+
+	return nil
+}
+
+// Drops the batch attributes of the pnts tiles compressed by the external draco encoder, which cannot compress them,
+// unless they have been explicitly set. Explicitly set attributes are kept and rejected by the validation
+func DropDracoEncoderBatchAttributes(opts *tiler.TilerOptions, isBatchAttributesSet bool) {
+	if !usesExternalDracoEncoder(opts) || len(opts.BatchAttributes) == 0 || isBatchAttributesSet {
+		return
+	}
+
+	glog.Warningf("batch-attributes %v are not written, the external draco encoder cannot compress them", opts.BatchAttributes)
+	opts.BatchAttributes = nil
+}
+
+// Returns true if the pnts tiles are compressed by the external draco encoder
+func usesExternalDracoEncoder(opts *tiler.TilerOptions) bool {
+	return opts.Draco && opts.DracoEncoderPath != "" && opts.OutputFormat == tiler.OutputFormatPnts
+}
+
+// Validates the options that control how the progress of the stages is reported. The mode is ignored when a custom
+// reporter is given
+func validateProgressOptions(opts *tiler.TilerOptions) error {
+	if opts.ProgressReporter != nil {
+		return nil
+	}
+
+	if opts.Progress == "" {
+		return errors.New("progress should be either log, bar or json")
+	}
+
+	if opts.ProgressFile != "" && opts.Progress != tiler.ProgressModeJson {
+		return errors.New("progress-file requires progress json")
+	}
+
+	return nil
+}
@@ -0,0 +1,66 @@
+package pkg
+
+import (
+	"os"
+	"path"
+
+	"github.com/ecopia-map/cesium_tiler/internal/progress"
+	"github.com/ecopia-map/cesium_tiler/internal/tiler"
+	"github.com/golang/glog"
+)
+
+// Name of the file of the output folder where the statistics of the run are written
+const SummaryFileName = "summary.json"
+
+// Returns the tracker of the stages of a run writing its summary in the given output folder, and the function closing
+// the progress file eventually opened. The reporter of the options is used if set, otherwise one is built according
+// to the progress mode
+func newProgressTracker(opts *tiler.TilerOptions, output string) (*progress.Tracker, func(), error) {
+	summary := progress.NewSummary(opts.Command, opts.Input, output)
+	closer := func() {}
+
+	reporter := opts.ProgressReporter
+	if reporter == nil {
+		switch opts.Progress {
+		case tiler.ProgressModeBar:
+			// the bar is drawn on the standard error so that it is not mixed with the json output of other tools
+			reporter = progress.NewBarReporter(os.Stderr)
+		case tiler.ProgressModeJson:
+			if opts.ProgressFile == "" {
+				reporter = progress.NewJsonReporter(os.Stdout)
+				break
+			}
+			file, err := os.Create(opts.ProgressFile)
+			if err != nil {
+				return nil, nil, err
+			}
+			reporter = progress.NewJsonReporter(file)
+			closer = func() { _ = file.Close() }
+		default:
+			reporter = progress.NewLogReporter()
+		}
+	}
+
+	return progress.NewTracker(reporter, summary), closer, nil
+}
+
+// Records the end of the run in the summary and writes it in the given output folder
+func writeSummary(tracker *progress.Tracker, output string, err error) {
+	summary := tracker.Summary()
+	summary.Finish(err)
+
+	summaryPath := path.Join(output, SummaryFileName)
+	if writeErr := summary.Write(summaryPath); writeErr != nil {
+		glog.Infof("unable to write summary [%s]: %v", summaryPath, writeErr)
+		return
+	}
+	glog.Infoln("Write summary success.", summaryPath)
+}
+
+// Runs a stage of the processing of a file whose progress is not measured, recording its duration
+func runStage(tracker *progress.Tracker, filePath string, stage progress.Stage, run func() error) error {
+	task := tracker.Start(filePath, stage, 0)
+	err := run()
+	task.Finish(err)
+	return err
+}
@@ -0,0 +1,68 @@
+package pkg
+
+import (
+	"fmt"
+
+	lidario "github.com/ecopia-map/cesium_tiler/third_party/lasread"
+	"github.com/golang/glog"
+)
+
+// Srid used when it is neither given nor defined by the input files
+const DefaultSrid = 4326
+
+// Returns the srid of the input points reading the coordinate reference system declared by the given las files.
+// If isSridSet is true the given srid is returned, failing if a file declares a different one. Otherwise the srid
+// shared by all the files is returned, falling back to DefaultSrid if no file declares it.
+func DetectSrid(lasFiles []string, srid int, isSridSet bool) (int, error) {
+	detectedSrid := 0
+	detectedFile := ""
+
+	for _, filePath := range lasFiles {
+		fileSrid, err := readLasFileSrid(filePath)
+		if err != nil {
+			if isSridSet {
+				glog.Infof("unable to read srid from las_file [%s], using srid:[%d]. %v", filePath, srid, err)
+				continue
+			}
+			return 0, fmt.Errorf("%v. Set the srid of the input points", err)
+		}
+		if fileSrid == 0 {
+			glog.Infof("no coordinate reference system defined in las_file [%s]", filePath)
+			continue
+		}
+
+		glog.Infof("las_file [%s] srid:[%d]", filePath, fileSrid)
+
+		if isSridSet && fileSrid != srid {
+			return 0, fmt.Errorf("srid %d differs from srid %d declared by las_file [%s]", srid, fileSrid, filePath)
+		}
+		if detectedSrid != 0 && fileSrid != detectedSrid {
+			return 0, fmt.Errorf("las_file [%s] srid %d differs from las_file [%s] srid %d",
+				filePath, fileSrid, detectedFile, detectedSrid)
+		}
+		detectedSrid = fileSrid
+		detectedFile = filePath
+	}
+
+	if isSridSet {
+		return srid, nil
+	}
+
+	if detectedSrid == 0 {
+		glog.Infof("no srid set nor declared by the input files, using srid:[%d]", DefaultSrid)
+		return DefaultSrid, nil
+	}
+
+	glog.Infof("using srid:[%d] declared by the input files", detectedSrid)
+	return detectedSrid, nil
+}
+
+func readLasFileSrid(filePath string) (int, error) {
+	lf, err := lidario.NewLasFile(filePath, "rh")
+	if err != nil {
+		return 0, err
+	}
+	defer lf.Close()
+
+	return lf.Srid()
+}
@@ -1,17 +1,21 @@
 package pkg
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
-	"runtime"
 	"strconv"
 	"sync"
 
 	"github.com/ecopia-map/cesium_tiler/internal/io"
+	"github.com/ecopia-map/cesium_tiler/internal/octree"
 	"github.com/ecopia-map/cesium_tiler/internal/octree/grid_tree"
+	"github.com/ecopia-map/cesium_tiler/internal/point_filter"
+	"github.com/ecopia-map/cesium_tiler/internal/progress"
 	"github.com/ecopia-map/cesium_tiler/internal/tiler"
 	"github.com/ecopia-map/cesium_tiler/pkg/algorithm_manager"
 	lidario "github.com/ecopia-map/cesium_tiler/third_party/lasread"
@@ -31,36 +35,208 @@ func NewTiler(fileFinder tools.FileFinder, algorithmManager algorithm_manager.Al
 	}
 }
 
-// Starts the tiling process
-func (tilerIndex *TilerIndex) RunTiler(opts *tiler.TilerOptions) error {
+// Starts the tiling process. Files not yet started when the context is canceled are skipped, the ones being processed
+// stop as soon as possible, and the context error is returned. The statistics of the run are written in the summary
+// file of the output folder, even if the run fails
+func (tilerIndex *TilerIndex) RunTiler(ctx context.Context, opts *tiler.TilerOptions) error {
+	tracker, closeProgress, err := newProgressTracker(opts, opts.TilerIndexOptions.Output)
+	if err != nil {
+		return err
+	}
+	defer closeProgress()
+
+	err = tilerIndex.runTiler(ctx, opts, tracker)
+	writeSummary(tracker, opts.TilerIndexOptions.Output, err)
+
+	return err
+}
+
+func (tilerIndex *TilerIndex) runTiler(ctx context.Context, opts *tiler.TilerOptions, tracker *progress.Tracker) error {
 	glog.Infoln("Preparing list of files to process...")
 
 	// Prepare list of files to process
-	lasFiles := tilerIndex.fileFinder.GetLasFilesToProcess(opts)
+	lasFiles, err := tilerIndex.fileFinder.GetLasFilesToProcess(opts)
+	if err != nil {
+		return err
+	}
 	glog.Infoln("las_file list", lasFiles)
 	for i, filePath := range lasFiles {
 		glog.Infof("las_file path %d [%s]", i+1, filePath)
 	}
 
-	// load las points in octree buffer
+	// The manifest records the files whose chunk tileset is complete, so that an interrupted run can be resumed
+	manifest := NewIndexManifest(opts.TilerIndexOptions.Output)
+	if opts.TilerIndexOptions.Resume {
+		var err error
+		if manifest, err = LoadIndexManifest(opts.TilerIndexOptions.Output); err != nil {
+			return err
+		}
+	}
+
+	// Files are processed concurrently by a pool of jobs sharing the workers and the memory budget
+	numJobs := opts.TilerIndexOptions.Jobs
+	if numJobs > len(lasFiles) {
+		numJobs = len(lasFiles)
+	}
+	if numJobs < 1 {
+		numJobs = 1
+	}
+	if numJobs > 1 {
+		// concurrent files writing the same chunk tileset would overwrite each other
+		subfolders := make(map[string]string)
+		for _, filePath := range lasFiles {
+			subfolder := getChunkSubfolder(filePath)
+			if other, ok := subfolders[subfolder]; ok {
+				return fmt.Errorf("las_files [%s] and [%s] are both written to chunk [%s], they cannot be processed concurrently", other, filePath, subfolder)
+			}
+			subfolders[subfolder] = filePath
+		}
+	}
+	numWorkers, memoryBudget := opts.TilerIndexOptions.JobResources(numJobs)
+
+	// The filter is shared by the jobs, the clip area is reprojected once to the srid of the input points
+	pointFilter, err := point_filter.NewPointFilter(&opts.TilerIndexOptions.Filter, opts.Srid, tilerIndex.algorithmManager.GetCoordinateConverterAlgorithm())
+	if err != nil {
+		return err
+	}
+	outlierFilter := point_filter.NewOutlierFilter(&opts.TilerIndexOptions.Outliers)
+	glog.Infof("index jobs:[%d] workers per job:[%d] memory budget per job:[%d MB]", numJobs, numWorkers, memoryBudget)
+
+	jobChannel := make(chan *indexJob, len(lasFiles))
 	for i, filePath := range lasFiles {
-		// Define point_loader strategy
-		var tree = tilerIndex.algorithmManager.GetTreeAlgorithm()
-		glog.Infoln("Processing file " + strconv.Itoa(i+1) + "/" + strconv.Itoa(len(lasFiles)))
-		tilerIndex.processLasFile(filePath, opts, tree)
+		jobChannel <- &indexJob{
+			number:        i + 1,
+			filePath:      filePath,
+			subfolder:     getChunkSubfolder(filePath),
+			numWorkers:    numWorkers,
+			memoryBudget:  memoryBudget,
+			pointFilter:   pointFilter,
+			outlierFilter: outlierFilter,
+			tracker:       tracker,
+		}
+	}
+	close(jobChannel)
 
-		// tree.Clear()
+	errorChannel := make(chan error, len(lasFiles))
+
+	var waitGroup sync.WaitGroup
+	for i := 0; i < numJobs; i++ {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			for job := range jobChannel {
+				if ctx.Err() != nil {
+					continue
+				}
+				glog.Infoln("Processing file " + strconv.Itoa(job.number) + "/" + strconv.Itoa(len(lasFiles)))
+				if err := tilerIndex.runIndexJob(ctx, job, opts, manifest); err != nil {
+					errorChannel <- fmt.Errorf("las_file [%s]: %v", job.filePath, err)
+				}
+			}
+		}()
 	}
+	waitGroup.Wait()
+	close(errorChannel)
+
 	tilerIndex.algorithmManager.GetCoordinateConverterAlgorithm().Cleanup()
 
-	return nil
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var errs []error
+	for err := range errorChannel {
+		glog.Infoln(err)
+		errs = append(errs, err)
+	}
+
+	return joinErrors(errs)
+}
+
+// A las file processed by the index command, with the resources assigned to its pipeline
+type indexJob struct {
+	number       int
+	filePath     string
+	subfolder    string
+	numWorkers   int                       // number of goroutines used to read, load and export the points
+	memoryBudget int                       // max memory in MB used to hold points, 0 keeps all the points in memory
+	pointFilter  *point_filter.PointFilter // filter of the points read from the file, nil keeps all the points
+	// filter of the noise points of the file, nil keeps all the points
+	outlierFilter *point_filter.OutlierFilter
+	tracker       *progress.Tracker // tracker of the stages of the run, shared by the jobs
+}
+
+// Writes the chunk tileset of the las file of the given job, recording its progress in the manifest
+func (tilerIndex *TilerIndex) runIndexJob(ctx context.Context, job *indexJob, opts *tiler.TilerOptions, manifest *IndexManifest) error {
+	entry, err := NewIndexManifestEntry(job.filePath, job.subfolder)
+	if err != nil {
+		return err
+	}
+
+	if opts.TilerIndexOptions.Resume {
+		if previous := manifest.GetEntry(entry.Path); previous != nil {
+			completed, err := previous.IsCompletedFor(entry)
+			if err != nil {
+				return err
+			}
+			if completed {
+				glog.Infof("> skipping completed chunk [%s] of las_file [%s]", job.subfolder, job.filePath)
+				job.tracker.Summary().AddSkippedFile()
+				return nil
+			}
+		}
+		if err := removePartialChunk(opts.TilerIndexOptions.Output, job.subfolder); err != nil {
+			return err
+		}
+	}
+
+	// the hash recorded lets a later run tell apart a file rewritten with the same size and modification time
+	if err := entry.SetHash(); err != nil {
+		return err
+	}
+	manifest.SetEntry(entry)
+	if err := manifest.Save(); err != nil {
+		return err
+	}
+
+	// Define point_loader strategy
+	var tree = tilerIndex.algorithmManager.GetTreeAlgorithm()
+	tree.SetNumWorkers(job.numWorkers)
+	if err := tilerIndex.processLasFile(ctx, job, opts, tree); err != nil {
+		return err
+	}
+
+	// tree.Clear()
+
+	return manifest.SetStatus(entry.Path, ChunkStatusCompleted)
 }
 
-func (tilerIndex *TilerIndex) processLasFile(filePath string, opts *tiler.TilerOptions, tree *grid_tree.GridTree) {
+func (tilerIndex *TilerIndex) processLasFile(ctx context.Context, job *indexJob, opts *tiler.TilerOptions, tree octree.ITree) error {
+	filePath, subfolder := job.filePath, job.subfolder
+
+	// Points are spilled to disk when a memory budget is set, which only the grid tree supports
+	gridTree, isGridTree := tree.(*grid_tree.GridTree)
+	if job.memoryBudget > 0 {
+		if !isGridTree {
+			return errors.New("memory-budget requires the grid algorithm")
+		}
+		spillFolder, err := ioutil.TempDir(opts.TilerIndexOptions.Output, "."+subfolder+"-spill-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(spillFolder)
+
+		if err := gridTree.EnableOutOfCore(spillFolder, int64(job.memoryBudget)*1024*1024); err != nil {
+			return err
+		}
+	}
+
 	// Create empty octree
-	lasFileLoader, err := tilerIndex.readLasData(filePath, opts, tree)
+	readTask := job.tracker.Start(filePath, progress.StageRead, getLasFileNumberOfPoints(filePath))
+	lasFileLoader, err := tilerIndex.readLasData(ctx, filePath, job.numWorkers, job.pointFilter, job.outlierFilter, opts, tree, readTask)
+	readTask.Finish(err)
 	if err != nil {
-		glog.Fatal(err)
+		return err
 	}
 	defer func() {
 		_ = lasFileLoader.LasFile.Clear()
@@ -68,26 +244,58 @@ func (tilerIndex *TilerIndex) processLasFile(filePath string, opts *tiler.TilerO
 		// lasFileLoader.LasFile = nil
 		// lasFileLoader.Tree = nil
 	}()
+	numLoadedPoints := int64(lasFileLoader.LasFile.Header.NumberPoints - lasFileLoader.NumFilteredPoints() - lasFileLoader.NumOutlierPoints())
+	job.tracker.Summary().AddFile(int64(lasFileLoader.LasFile.Header.NumberPoints), int64(lasFileLoader.NumFilteredPoints()))
+	job.tracker.Summary().AddOutliers(lasFileLoader.OutlierPointsByClass())
+
+	// files falling outside the clip area are common when a project is clipped, no chunk is written for them
+	if (job.pointFilter != nil || job.outlierFilter != nil) && numLoadedPoints == 0 {
+		glog.Infof("> skipping las_file [%s], all its points are filtered out or outliers", filepath.Base(filePath))
+		return nil
+	}
 
-	tilerIndex.prepareDataStructure(tree, opts)
-
-	subfolder := fmt.Sprintf("%s%s", tools.ChunkTilesetFilePrefix, getFilenameWithoutExtension(filePath))
-	tilerIndex.exportToCesiumTileset(tree, opts, subfolder)
+	if isOutOfCore(tree) {
+		if err := tilerIndex.buildAndExportOutOfCore(ctx, gridTree, opts, job, numLoadedPoints); err != nil {
+			return err
+		}
+	} else {
+		if err := tilerIndex.prepareDataStructure(ctx, tree, opts, job); err != nil {
+			return err
+		}
+		exportTask := job.tracker.Start(filePath, progress.StageExport, tree.GetRootNode().TotalNumberOfPoints())
+		err := tilerIndex.exportToCesiumTileset(ctx, tree, opts, subfolder, job.numWorkers, exportTask)
+		exportTask.Finish(err)
+		if err != nil {
+			return err
+		}
+	}
 
-	tilerIndex.exportRootNodeLas(tree, opts, subfolder, lasFileLoader.LasFile)
+	lasExportTask := job.tracker.Start(filePath, progress.StageLasExport, int64(tree.GetRootNode().NumberOfPoints()))
+	err = tilerIndex.exportRootNodeLas(tree, opts, subfolder, lasFileLoader.LasFile, lasExportTask)
+	lasExportTask.Finish(err)
+	if err != nil {
+		return err
+	}
 
 	glog.Infoln("> done processing", filepath.Base(filePath))
+
+	return nil
 }
 
-func (tilerIndex *TilerIndex) readLasData(filePath string, opts *tiler.TilerOptions, tree *grid_tree.GridTree) (*lidario.LasFileLoader, error) {
+func (tilerIndex *TilerIndex) readLasData(ctx context.Context, filePath string, numWorkers int, pointFilter *point_filter.PointFilter, outlierFilter *point_filter.OutlierFilter, opts *tiler.TilerOptions, tree octree.ITree, task *progress.Task) (*lidario.LasFileLoader, error) {
 	// Reading files
 	glog.Infoln("> reading data from las file...", filepath.Base(filePath))
-	lasFileLoader, err := readLas(filePath, opts, tree)
+	lasFileLoader, err := readLas(ctx, filePath, numWorkers, pointFilter, outlierFilter, opts, tree, task)
 	if err != nil {
-		glog.Fatal(err)
 		return nil, err
 	}
 
+	// the geometric error of the grid tree can be computed from the extent of the las file
+	gridTree, isGridTree := tree.(*grid_tree.GridTree)
+	if !isGridTree {
+		return lasFileLoader, nil
+	}
+
 	lasFile := lasFileLoader.LasFile
 
 	edgeX := lasFile.Header.MaxX - lasFile.Header.MinX
@@ -95,47 +303,139 @@ func (tilerIndex *TilerIndex) readLasData(filePath string, opts *tiler.TilerOpti
 	edgeZ := lasFile.Header.MaxZ - lasFile.Header.MinZ
 	useEdgeCalculateGeometricError := opts.TilerIndexOptions.UseEdgeCalculateGeometricError
 
-	tree.UpdateExtendChunkEdge(edgeX, edgeY, edgeZ, useEdgeCalculateGeometricError)
+	gridTree.UpdateExtendChunkEdge(edgeX, edgeY, edgeZ, useEdgeCalculateGeometricError)
 
 	return lasFileLoader, nil
 }
 
-func (tilerIndex *TilerIndex) prepareDataStructure(octree *grid_tree.GridTree, opts *tiler.TilerOptions) {
+func (tilerIndex *TilerIndex) prepareDataStructure(ctx context.Context, tree octree.ITree, opts *tiler.TilerOptions, job *indexJob) error {
 	// Build tree hierarchical structure
 	glog.Infoln("> building data structure...")
 
-	if err := octree.Build(); err != nil {
-		glog.Fatal(err)
+	// only the nodes of the grid tree are split and merged to fit the min and max number of points
+	octree, isGridTree := tree.(*grid_tree.GridTree)
+	if isGridTree && opts.MaxNumPointsPerNode < 8*opts.MinNumPointsPerNode {
+		return fmt.Errorf("MaxNumPoints shoud be greater than 8 * MinNumPoints")
 	}
 
-	if opts.MaxNumPointsPerNode < 8*opts.MinNumPointsPerNode {
-		err := fmt.Errorf("MaxNumPoints shoud be greater than 8 * MinNumPoints")
-		glog.Fatal(err)
+	if err := runStage(job.tracker, job.filePath, progress.StageBuild, tree.Build); err != nil {
+		return err
+	}
+
+	if !isGridTree {
+		rootNode := tree.GetRootNode()
+		glog.Infoln("las_file root_node num_of_points:", rootNode.NumberOfPoints(), ", points.len:", len(rootNode.GetPoints()))
+		return nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
 	}
 
 	glog.Infoln("split-big-node for tree...")
-	if err := octree.SplitBigNode(opts.MaxNumPointsPerNode); err != nil {
-		glog.Fatal(err)
+	err := runStage(job.tracker, job.filePath, progress.StageSplit, func() error {
+		return octree.SplitBigNode(opts.MaxNumPointsPerNode)
+	})
+	if err != nil {
+		return err
 	}
 	glog.Infoln("split-big-node for tree finished")
 
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	glog.Infoln("merge-small-node for tree...")
-	if err := octree.MergeSmallNode(opts.MinNumPointsPerNode); err != nil {
-		glog.Fatal(err)
+	err = runStage(job.tracker, job.filePath, progress.StageMerge, func() error {
+		return octree.MergeSmallNode(opts.MinNumPointsPerNode)
+	})
+	if err != nil {
+		return err
 	}
 	glog.Infoln("merge-small-node for tree finished")
 
 	rootNode := octree.GetRootNode()
 	glog.Infoln("las_file root_node num_of_points:", rootNode.NumberOfPoints(), ", points.len:", len(rootNode.GetPoints()))
 
+	return nil
 }
 
-func (tilerIndex *TilerIndex) exportToCesiumTileset(octree *grid_tree.GridTree, opts *tiler.TilerOptions, subfolder string) {
+func (tilerIndex *TilerIndex) exportToCesiumTileset(ctx context.Context, octree octree.ITree, opts *tiler.TilerOptions, subfolder string, numWorkers int, task *progress.Task) error {
 	glog.Infoln("> exporting data...")
-	err := tilerIndex.exportTreeAsTileset(opts, octree, subfolder)
+	return tilerIndex.exportTreeAsTileset(ctx, opts, octree, subfolder, numWorkers, task)
+}
+
+// Builds the tree out of core, exporting the nodes as soon as their subtree is complete. The build and export stages
+// run at the same time, the number of points to export is the number of points loaded in the tree
+func (tilerIndex *TilerIndex) buildAndExportOutOfCore(ctx context.Context, octree *grid_tree.GridTree, opts *tiler.TilerOptions, job *indexJob, numPoints int64) error {
+	glog.Infoln("> building data structure out of core and exporting data...")
+
+	if opts.MaxNumPointsPerNode < 8*opts.MinNumPointsPerNode {
+		return fmt.Errorf("MaxNumPoints shoud be greater than 8 * MinNumPoints")
+	}
+
+	subfolder, numWorkers := job.subfolder, job.numWorkers
+	buildTask := job.tracker.Start(job.filePath, progress.StageBuild, 0)
+	exportTask := job.tracker.Start(job.filePath, progress.StageExport, numPoints)
+	err := tilerIndex.buildAndExportNodes(ctx, octree, opts, subfolder, numWorkers, buildTask, exportTask)
+	exportTask.Finish(err)
 	if err != nil {
-		glog.Fatal(err)
+		return err
 	}
+
+	rootNode := octree.GetRootNode()
+	glog.Infoln("las_file root_node num_of_points:", rootNode.NumberOfPoints(), ", points.len:", len(rootNode.GetPoints()))
+
+	return nil
+}
+
+func (tilerIndex *TilerIndex) buildAndExportNodes(ctx context.Context, octree *grid_tree.GridTree, opts *tiler.TilerOptions, subfolder string, numWorkers int, buildTask *progress.Task, exportTask *progress.Task) error {
+	// the export is canceled by the first error raised by a consumer
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// a consumer goroutine per worker
+	numConsumers := numWorkers
+
+	// init channel where to submit work with a buffer 5 times greater than the number of consumer
+	workChannel := make(chan *io.WorkUnit, numConsumers*5)
+
+	// init collector where consumers can eventually submit errors that prevented them to finish the job
+	exportErrors := newExportErrors(numConsumers, cancel)
+
+	var waitGroup sync.WaitGroup
+
+	// add consumers to waitgroup and launch them
+	for i := 0; i < numConsumers; i++ {
+		waitGroup.Add(1)
+		consumer := io.NewStandardConsumer(tilerIndex.algorithmManager.GetCoordinateConverterAlgorithm(), opts.RefineMode, opts.Draco, opts.DracoEncoderPath, opts.DracoMethod, opts.DracoQuantizationBits, opts.OutputFormat, opts.Meshopt, opts.BoundingVolume, opts.BatchAttributes)
+		consumer.SetProgress(exportTask)
+		go consumer.Consume(ctx, workChannel, exportErrors.channel, &waitGroup)
+	}
+
+	// nodes are submitted as soon as they are complete, the tree releases them once written
+	producer := io.NewStandardStreamProducer(opts.TilerIndexOptions.Output, subfolder, opts)
+	err := octree.BuildOutOfCore(opts.MaxNumPointsPerNode, opts.MinNumPointsPerNode, func(nodes []*grid_tree.GridNode) error {
+		if err := producer.Produce(ctx, workChannel, grid_tree.ToINodeList(nodes)); err != nil {
+			return err
+		}
+		return ctx.Err()
+	})
+	buildTask.Finish(err)
+
+	close(workChannel)
+	waitGroup.Wait()
+
+	// the errors of the consumers are the cause of the cancellation of the build, if any
+	if consumerErr := exportErrors.wait(); consumerErr != nil {
+		return consumerErr
+	}
+	return err
+}
+
+// Returns the name of the folder of the output folder where the chunk tileset of the given las file is written
+func getChunkSubfolder(filePath string) string {
+	return fmt.Sprintf("%s%s", tools.ChunkTilesetFilePrefix, getFilenameWithoutExtension(filePath))
 }
 
 func getFilenameWithoutExtension(filePath string) string {
@@ -144,11 +444,31 @@ func getFilenameWithoutExtension(filePath string) string {
 	return nameWext[0 : len(nameWext)-len(extension)]
 }
 
-// Reads the given las file and preloads data in a list of Point
-func readLas(filePath string, opts *tiler.TilerOptions, tree *grid_tree.GridTree) (*lidario.LasFileLoader, error) {
+// Returns the number of points declared by the header of the given las file, 0 if it cannot be read
+func getLasFileNumberOfPoints(filePath string) int64 {
+	lf, err := lidario.NewLasFile(filePath, "rh")
+	if err != nil {
+		return 0
+	}
+	defer lf.Close()
+
+	return int64(lf.Header.NumberPoints)
+}
+
+// Reads the given las file and preloads data in a list of Point. If pointFilter is not nil only the points it accepts
+// are loaded, if outlierFilter is not nil the outliers it finds are not loaded
+func readLas(ctx context.Context, filePath string, numWorkers int, pointFilter *point_filter.PointFilter, outlierFilter *point_filter.OutlierFilter, opts *tiler.TilerOptions, tree octree.ITree, task *progress.Task) (*lidario.LasFileLoader, error) {
 	var lasFileLoader = lidario.NewLasFileLoader(tree)
-	_, err := lasFileLoader.LoadLasFile(filePath, opts.Srid, opts.EightBitColors)
+	lasFileLoader.NumWorkers = numWorkers
+	lasFileLoader.OnPointsRead = func(numPoints int) { task.Add(int64(numPoints)) }
+	lasFileLoader.Filter = pointFilter
+	lasFileLoader.Outliers = outlierFilter
+	lasFileLoader.StreamPoints = isOutOfCore(tree)
+	lasFileLoader.ExtraBytes = tiler.ExtraBytesNames(opts.BatchAttributes)
+	lasFile, err := lasFileLoader.LoadLasFile(ctx, filePath, opts.Srid, opts.EightBitColors)
 	if err != nil {
+		_ = lasFile.Clear()
+		_ = lasFile.Close()
 		return nil, err
 	}
 	// defer func() { _ = lf.Close() }()
@@ -156,83 +476,98 @@ func readLas(filePath string, opts *tiler.TilerOptions, tree *grid_tree.GridTree
 	return lasFileLoader, nil
 }
 
+// Returns true if the given tree is a grid tree built out of core, whose points are streamed to the tree
+func isOutOfCore(tree octree.ITree) bool {
+	gridTree, isGridTree := tree.(*grid_tree.GridTree)
+	return isGridTree && gridTree.IsOutOfCore()
+}
+
 // Exports the data cloud represented by the given built octree into 3D tiles data structure according to the options
-// specified in the TilerOptions instance
-func (tilerIndex *TilerIndex) exportTreeAsTileset(opts *tiler.TilerOptions, octree *grid_tree.GridTree, subfolder string) error {
+// specified in the TilerOptions instance. The producer stops submitting work when the context is canceled or a
+// consumer raises an error
+func (tilerIndex *TilerIndex) exportTreeAsTileset(ctx context.Context, opts *tiler.TilerOptions, octree octree.ITree, subfolder string, numWorkers int, task *progress.Task) error {
 	// if octree is not built, exit
 	if !octree.IsBuilt() {
 		return errors.New("octree not built, data structure not initialized")
 	}
 
-	// a consumer goroutine per CPU
-	numConsumers := runtime.NumCPU()
+	// the export is canceled by the first error raised by a consumer
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// a consumer goroutine per worker
+	numConsumers := numWorkers
 
 	// init channel where to submit work with a buffer 5 times greater than the number of consumer
 	workChannel := make(chan *io.WorkUnit, numConsumers*5)
 
-	// init channel where consumers can eventually submit errors that prevented them to finish the job
-	errorChannel := make(chan error)
+	// init collector where consumers can eventually submit errors that prevented them to finish the job
+	exportErrors := newExportErrors(numConsumers, cancel)
 
 	var waitGroup sync.WaitGroup
 
 	// add producer to waitgroup and launch producer goroutine
 	waitGroup.Add(1)
 
-	producer := io.NewStandardProducer(opts.TilerIndexOptions.Output, subfolder, opts)
-	go producer.Produce(workChannel, &waitGroup, octree.GetRootNode())
+	var implicitRoot *io.ImplicitTile
+	if opts.TilerIndexOptions.ImplicitTiling {
+		implicitRoot = io.NewImplicitTileTree(octree.GetRootNode())
+		producer := io.NewImplicitProducer(opts.TilerIndexOptions.Output, subfolder, opts)
+		go producer.Produce(ctx, workChannel, &waitGroup, implicitRoot)
+	} else {
+		producer := io.NewStandardProducer(opts.TilerIndexOptions.Output, subfolder, opts)
+		go producer.Produce(ctx, workChannel, &waitGroup, octree.GetRootNode())
+	}
 
 	// add consumers to waitgroup and launch them
 	for i := 0; i < numConsumers; i++ {
 		waitGroup.Add(1)
-		consumer := io.NewStandardConsumer(tilerIndex.algorithmManager.GetCoordinateConverterAlgorithm(), opts.RefineMode, opts.Draco, opts.DracoEncoderPath)
-		go consumer.Consume(workChannel, errorChannel, &waitGroup)
+		consumer := io.NewStandardConsumer(tilerIndex.algorithmManager.GetCoordinateConverterAlgorithm(), opts.RefineMode, opts.Draco, opts.DracoEncoderPath, opts.DracoMethod, opts.DracoQuantizationBits, opts.OutputFormat, opts.Meshopt, opts.BoundingVolume, opts.BatchAttributes)
+		consumer.SetProgress(task)
+		go consumer.Consume(ctx, workChannel, exportErrors.channel, &waitGroup)
 	}
 
 	// wait for producers and consumers to finish
 	waitGroup.Wait()
 
-	// close error chan
-	close(errorChannel)
-
-	// find if there are errors in the error channel buffer
-	withErrors := false
-	for err := range errorChannel {
-		glog.Infoln(err)
-		withErrors = true
+	// find if there are errors raised by the consumers
+	if err := exportErrors.wait(); err != nil {
+		return err
 	}
-	if withErrors {
-		return errors.New("errors raised during execution. Check console output for details")
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if implicitRoot != nil {
+		// the implicit tileset and its subtrees are written once all the tile contents are exported
+		writer := io.NewImplicitTilesetWriter(tilerIndex.algorithmManager.GetCoordinateConverterAlgorithm(), opts.RefineMode, opts.OutputFormat, opts.TilerIndexOptions.SubtreeLevels, opts.BoundingVolume)
+		if err := writer.Write(path.Join(opts.TilerIndexOptions.Output, subfolder), implicitRoot); err != nil {
+			return err
+		}
+		task.AddBytes(writer.BytesWritten())
 	}
 
 	return nil
 }
 
-func (tilerIndex *TilerIndex) exportRootNodeLas(octree *grid_tree.GridTree, opts *tiler.TilerOptions, subfolder string, lasFile *lidario.LasFile) error {
-	parentFolder := path.Join(opts.TilerIndexOptions.Output, subfolder)
-
-	var err error
+// Number of points written to the root node las file between two updates of the progress
+const lasExportProgressPoints = 1000
 
-	// var lf *lidario.LasFile
-	// lf, err = lidario.NewLasFile(filePath, "r")
-	// if err != nil {
-	// 	glog.Infoln(err)
-	// 	glog.Fatal(err)
-	// }
-	// defer lf.Close()
+func (tilerIndex *TilerIndex) exportRootNodeLas(octree octree.ITree, opts *tiler.TilerOptions, subfolder string, lasFile *lidario.LasFile, task *progress.Task) error {
+	parentFolder := path.Join(opts.TilerIndexOptions.Output, subfolder)
 
 	newFileName := path.Join(parentFolder, "content.las")
 	if _, err := os.Stat(newFileName); err == nil {
 		if err := os.Remove(newFileName); err != nil {
-			glog.Fatal(err)
+			return err
 		}
 	} else if !errors.Is(err, os.ErrNotExist) {
-		glog.Fatal(err)
+		return err
 	}
 
 	newLf, err := lidario.InitializeUsingFile(newFileName, lasFile)
 	if err != nil {
-		glog.Infoln(err)
-		glog.Fatal(err)
+		return err
 	}
 	defer func() {
 		if newLf != nil {
@@ -242,13 +577,9 @@ func (tilerIndex *TilerIndex) exportRootNodeLas(octree *grid_tree.GridTree, opts
 	}()
 
 	if err := newLf.CopyHeaderXYZ(lasFile.Header); err != nil {
-		glog.Infoln(err)
-		glog.Fatal(err)
+		return err
 	}
 
-	progress := 0
-	oldProgress := -1
-
 	rootNode := octree.GetRootNode()
 	numberOfPoints := rootNode.NumberOfPoints()
 	points := rootNode.GetPoints()
@@ -260,44 +591,35 @@ func (tilerIndex *TilerIndex) exportRootNodeLas(octree *grid_tree.GridTree, opts
 
 		pointLas, err := lasFile.LasPoint(point.PointExtend.LasPointIndex)
 		if err != nil {
-			glog.Infoln(err)
-			glog.Fatal(err)
 			return err
 		}
 
 		X, Y, Z := pointLas.PointData().X, pointLas.PointData().Y, pointLas.PointData().Z
 		if !lasFile.CheckPointXYZInvalid(X, Y, Z) {
-			glog.Infof(" nonono invalid point_pos:[%d] X:[%f] Y:[%f] Z:[%f]", i, X, Y, Z)
-			glog.Fatal("invalid point X/Y/Z")
-			continue
+			return fmt.Errorf("invalid point X/Y/Z. point_pos:[%d] X:[%f] Y:[%f] Z:[%f]", i, X, Y, Z)
 		}
 
-		newLf.AddLasPoint(pointLas)
+		if err := newLf.AddLasPoint(pointLas); err != nil {
+			return err
+		}
 
-		// print export-progress
-		progress = int(100.0 * float64(i+1) / float64(numberOfPoints))
-		if progress != oldProgress {
-			oldProgress = progress
-			if progress%50 == 0 {
-				glog.Infof("export root-node progress: %v\n", progress)
-			}
+		if (i+1)%lasExportProgressPoints == 0 {
+			task.Add(lasExportProgressPoints)
 		}
 	}
+	task.Add(int64(numberOfPoints) % lasExportProgressPoints)
 
-	newLf.Close()
+	err = newLf.Close()
 	newLf = nil
+	if err != nil {
+		return err
+	}
 
-	glog.Infoln("Write las file success.", newFileName)
+	if fileInfo, err := os.Stat(newFileName); err == nil {
+		task.AddBytes(fileInfo.Size())
+	}
 
-	// // Check
-	// glog.Infof("check las_file %s", newFileName)
-	// mergedLf, err := lidario.NewLasFile(newFileName, "r")
-	// if err != nil {
-	// 	glog.Infoln(err)
-	// 	glog.Fatal(err)
-	// 	return err
-	// }
-	// defer mergedLf.Close()
+	glog.Infoln("Write las file success.", newFileName)
 
 	return nil
 }
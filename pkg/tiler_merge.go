@@ -1,6 +1,7 @@
 package pkg
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -17,6 +18,7 @@ import (
 	"github.com/ecopia-map/cesium_tiler/internal/geometry"
 	"github.com/ecopia-map/cesium_tiler/internal/io"
 	"github.com/ecopia-map/cesium_tiler/internal/octree/grid_tree"
+	"github.com/ecopia-map/cesium_tiler/internal/progress"
 	"github.com/ecopia-map/cesium_tiler/internal/tiler"
 	"github.com/ecopia-map/cesium_tiler/pkg/algorithm_manager"
 	"github.com/ecopia-map/cesium_tiler/pkg/algorithm_manager/std_algorithm_manager"
@@ -28,52 +30,78 @@ import (
 type TilerMerge struct {
 	fileFinder       tools.FileFinder
 	algorithmManager algorithm_manager.AlgorithmManager
+	tracker          *progress.Tracker // tracker of the stages of the current run
 }
 
 func NewTilerMerge(fileFinder tools.FileFinder, algorithmManager algorithm_manager.AlgorithmManager) tiler.ITiler {
 	return &TilerMerge{
 		fileFinder:       fileFinder,
 		algorithmManager: algorithmManager,
+		// replaced by RunTiler, logs the stages of the merge methods called directly
+		tracker: progress.NewTracker(progress.NewLogReporter(), progress.NewSummary("", "", "")),
 	}
 }
 
-func (tilerMerge *TilerMerge) RunTiler(opts *tiler.TilerOptions) error {
+// Merges the chunk tilesets of the input folder. The statistics of the run are written in the summary file of the
+// input folder, even if the run fails
+func (tilerMerge *TilerMerge) RunTiler(ctx context.Context, opts *tiler.TilerOptions) error {
+	tracker, closeProgress, err := newProgressTracker(opts, opts.Input)
+	if err != nil {
+		return err
+	}
+	defer closeProgress()
+
+	tilerMerge.tracker = tracker
+	err = tilerMerge.runTiler(ctx, opts)
+	writeSummary(tracker, opts.Input, err)
+
+	return err
+}
+
+func (tilerMerge *TilerMerge) runTiler(ctx context.Context, opts *tiler.TilerOptions) error {
 	if opts.Command == tools.CommandMergeChildren {
-		if err := tilerMerge.RunTilerMergeChildren(opts); err != nil {
-			glog.Infoln(err)
-			return nil
-		}
+		return tilerMerge.RunTilerMergeChildren(ctx, opts)
 	} else if opts.Command == tools.CommandMergeTree {
-		if err := tilerMerge.RunTilerMergeTree(opts); err != nil {
-			glog.Infoln(err)
-			return nil
-		}
+		return tilerMerge.RunTilerMergeTree(ctx, opts)
 	}
 
-	return nil
+	return fmt.Errorf("unrecognized merge command [%s]", opts.Command)
 }
 
-func (tilerMerge *TilerMerge) RunTilerMergeChildren(opts *tiler.TilerOptions) error {
+func (tilerMerge *TilerMerge) RunTilerMergeChildren(ctx context.Context, opts *tiler.TilerOptions) error {
 	glog.Infoln("Preparing list of files to process...")
 
 	// Prepare list of files to process
-	lasFilePathList := tilerMerge.fileFinder.GetLasFilesToMerge(opts)
+	lasFilePathList, err := tilerMerge.fileFinder.GetLasFilesToMerge(opts)
+	if err != nil {
+		return err
+	}
 	glog.Infoln("las_file list", lasFilePathList)
 
 	if len(lasFilePathList) == 0 {
-		err := fmt.Errorf("no children las-file found. input:[%s]", opts.Input)
-		glog.Fatal(err.Error() + ". " + tools.FmtJSONString(opts))
-		return err
+		return fmt.Errorf("no children las-file found. input:[%s]", opts.Input)
 	}
 
 	for i, filePath := range lasFilePathList {
 		glog.Infof("las_file path %d [%s]", i+1, filePath)
 	}
 
-	tree := tilerMerge.algorithmManager.GetTreeAlgorithm()
-	lasFile, err := tilerMerge.mergeLasFileListToSingleTree(lasFilePathList, opts, tree)
+	defer tilerMerge.algorithmManager.GetCoordinateConverterAlgorithm().Cleanup()
+
+	// the merged las file is written in a folder of its own, so that concurrent merges do not overwrite each other,
+	// removed once the merged file is closed
+	workDir, err := newMergeWorkDir(opts)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workDir)
+
+	tree, err := tilerMerge.newGridTree()
+	if err != nil {
+		return err
+	}
+	lasFile, err := tilerMerge.mergeLasFileListToSingleTree(ctx, lasFilePathList, workDir, opts, tree)
 	if err != nil {
-		glog.Fatal(err)
 		return err
 	}
 	defer func() {
@@ -81,26 +109,74 @@ func (tilerMerge *TilerMerge) RunTilerMergeChildren(opts *tiler.TilerOptions) er
 		_ = lasFile.Close()
 	}()
 
-	tilerMerge.exportTreeRootTileset(tree, opts)
+	exportTask := tilerMerge.tracker.Start(opts.Input, progress.StageExport, int64(tree.GetRootNode().NumberOfPoints()))
+	err = tilerMerge.exportTreeRootTileset(ctx, tree, opts, exportTask)
+	exportTask.Finish(err)
+	if err != nil {
+		return err
+	}
 
 	if err := tilerMerge.repairTilesetMetadata(opts, lasFilePathList); err != nil {
-		glog.Fatal(err)
 		return err
 	}
 
-	tilerMerge.exportRootNodeLas(tree, opts, lasFile)
-
-	tilerMerge.algorithmManager.GetCoordinateConverterAlgorithm().Cleanup()
+	lasExportTask := tilerMerge.tracker.Start(opts.Input, progress.StageLasExport, int64(tree.GetRootNode().NumberOfPoints()))
+	err = tilerMerge.exportRootNodeLas(tree, opts, lasFile, lasExportTask)
+	lasExportTask.Finish(err)
+	if err != nil {
+		return err
+	}
 
 	glog.Infoln("> done merging-children", opts.Input)
 
 	return nil
 }
 
-func (tilerMerge *TilerMerge) RunTilerMergeTree(opts *tiler.TilerOptions) error {
+func (tilerMerge *TilerMerge) RunTilerMergeTree(ctx context.Context, opts *tiler.TilerOptions) error {
 	glog.Infoln("Preparing list of files to process...")
 
-	rootDir := strings.TrimSuffix(filepath.Join(opts.Input, ""), "/")
+	levelDirsMap, err := getMergeTreeLevels(opts.Input)
+	if err != nil {
+		return err
+	}
+
+	glog.Infoln("merge level-tree. level-num:", len(levelDirsMap))
+
+	for level, dirList := range levelDirsMap {
+		glog.Infoln("level-folder", level, tools.FmtJSONString(dirList))
+		glog.Infoln("level-folder", level, len(dirList))
+	}
+
+	glog.Infoln("opts", tools.FmtJSONString(opts))
+
+	maxLevel := len(levelDirsMap) - 1
+
+	// levelDirsList := make([][]string, 0)
+	// for i := 0; i <= maxLevel; i++ {
+	// 	levelDirsList = append(levelDirsList, levelDirsMap[i])
+	// }
+
+	for i := maxLevel; i >= 0; i-- {
+		for _, dir := range levelDirsMap[i] {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			if err := tilerMerge.mergeTreeFolder(ctx, opts, dir, i, maxLevel); err != nil {
+				return err
+			}
+		}
+	}
+
+	glog.Infoln("> done merging-tree", opts.Input)
+
+	return nil
+}
+
+// Returns the folders of the merge hierarchy under the given root folder by depth, the root folder being at depth 0.
+// The chunk tilesets written by the index command are not part of the hierarchy
+func getMergeTreeLevels(input string) (map[int][]string, error) {
+	rootDir := strings.TrimSuffix(filepath.Join(input, ""), "/")
 
 	levelDirsMap := make(map[int][]string)
 
@@ -108,6 +184,10 @@ func (tilerMerge *TilerMerge) RunTilerMergeTree(opts *tiler.TilerOptions) error
 	err := filepath.Walk(
 		rootDir,
 		func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
 			pathDepth := strings.Count(strings.TrimPrefix(path, rootDir), string("/"))
 			// glog.Infoln("walk_path:", path, ", pathDepth:", pathDepth)
 
@@ -139,90 +219,98 @@ func (tilerMerge *TilerMerge) RunTilerMergeTree(opts *tiler.TilerOptions) error
 	)
 
 	if err != nil {
-		glog.Fatal(err)
-	}
-
-	glog.Infoln("merge level-tree. level-num:", len(levelDirsMap))
-
-	for level, dirList := range levelDirsMap {
-		glog.Infoln("level-folder", level, tools.FmtJSONString(dirList))
-		glog.Infoln("level-folder", level, len(dirList))
+		return nil, err
 	}
 
-	glog.Infoln("opts", tools.FmtJSONString(opts))
-
-	maxLevel := len(levelDirsMap) - 1
+	return levelDirsMap, nil
+}
 
-	// levelDirsList := make([][]string, 0)
-	// for i := 0; i <= maxLevel; i++ {
-	// 	levelDirsList = append(levelDirsList, levelDirsMap[i])
-	// }
+// Merges the children of the given folder of the merge hierarchy. The cell size of the grid doubles at every level
+// from the deepest one up to the root, whose geometric error is scaled as the one of the level below it
+func (tilerMerge *TilerMerge) mergeTreeFolder(ctx context.Context, opts *tiler.TilerOptions, dir string, level int, maxLevel int) error {
+	cellSize := opts.CellMaxSize * math.Pow(2, float64(maxLevel-level))
 
-	cellSize := opts.CellMaxSize
-	for i := maxLevel; i >= 0; i-- {
-		for _, dir := range levelDirsMap[i] {
-			dirOpts := opts.Copy()
-			dirOpts.Input = dir
-			dirOpts.CellMaxSize = cellSize * 2
-			dirOpts.CellMinSize = cellSize
+	dirOpts := opts.Copy()
+	dirOpts.Input = dir
+	dirOpts.CellMaxSize = cellSize * 2
+	dirOpts.CellMinSize = cellSize
 
-			glog.Infoln("dirOpts", tools.FmtJSONString(dirOpts))
-			tilerMerge.algorithmManager.GetCoordinateConverterAlgorithm().Cleanup()
-			tilerMerge.algorithmManager = std_algorithm_manager.NewAlgorithmManager(dirOpts)
+	glog.Infoln("dirOpts", tools.FmtJSONString(dirOpts))
+	tilerMerge.algorithmManager.GetCoordinateConverterAlgorithm().Cleanup()
+	algorithmManager, err := std_algorithm_manager.NewAlgorithmManager(dirOpts)
+	if err != nil {
+		return err
+	}
+	tilerMerge.algorithmManager = algorithmManager
 
-			tilerMerge.RunTilerMergeChildren(dirOpts)
+	if err := tilerMerge.RunTilerMergeChildren(ctx, dirOpts); err != nil {
+		return fmt.Errorf("merging folder [%s]: %v", dir, err)
+	}
 
-			if i == 1 {
-				scale := 2
-				if err := tilerMerge.AdjustRootGeometricError(dirOpts, scale); err != nil {
-					glog.Fatal(err)
-				}
-			} else if i == 0 {
-				scale := 4
-				if err := tilerMerge.AdjustRootGeometricError(dirOpts, scale); err != nil {
-					glog.Fatal(err)
-				}
-			}
+	if level == 1 {
+		scale := 2
+		if err := tilerMerge.AdjustRootGeometricError(dirOpts, scale); err != nil {
+			return err
+		}
+	} else if level == 0 {
+		scale := 4
+		if err := tilerMerge.AdjustRootGeometricError(dirOpts, scale); err != nil {
+			return err
 		}
-		cellSize *= 2
-
 	}
 
-	glog.Infoln("> done merging-tree", opts.Input)
-
 	return nil
 }
 
 func (tilerMerge *TilerMerge) mergeLasFileListToSingleTree(
-	lasFilePathList []string, opts *tiler.TilerOptions, tree *grid_tree.GridTree,
+	ctx context.Context, lasFilePathList []string, workDir string, opts *tiler.TilerOptions, tree *grid_tree.GridTree,
 ) (lasFile *lidario.LasFile, _err error) {
 
 	// merge multi sub-folder las to single-las
-	mergedLasFilePath, err := tilerMerge.mergeLasFileList(lasFilePathList)
+	var numPoints int64
+	for _, filePath := range lasFilePathList {
+		numPoints += getLasFileNumberOfPoints(filePath)
+	}
+	mergeTask := tilerMerge.tracker.Start(opts.Input, progress.StageMerge, numPoints)
+	mergedLasFilePath, err := tilerMerge.mergeLasFileList(lasFilePathList, workDir, mergeTask)
+	mergeTask.Finish(err)
 	if err != nil {
-		glog.Fatal(err)
 		return nil, err
 	}
 	glog.Infoln("mergedLasFilePath", mergedLasFilePath)
 
 	// load merged single-las
 	glog.Infoln("Processing file " + mergedLasFilePath)
-	lasFileLoader, err := tilerMerge.readLasData(mergedLasFilePath, opts, tree)
+	readTask := tilerMerge.tracker.Start(mergedLasFilePath, progress.StageRead, numPoints)
+	lasFileLoader, err := tilerMerge.readLasData(ctx, mergedLasFilePath, opts, tree, readTask)
+	readTask.Finish(err)
 	if err != nil {
-		glog.Fatal(err)
 		return nil, err
 	}
 
-	tilerMerge.prepareDataStructure(tree)
+	if err := tilerMerge.prepareDataStructure(tree, opts.Input); err != nil {
+		_ = lasFileLoader.LasFile.Clear()
+		_ = lasFileLoader.LasFile.Close()
+		return nil, err
+	}
 	glog.Infoln(tree.GetRootNode().NumberOfPoints(), tree.GetRootNode().TotalNumberOfPoints())
 
 	// load sub-folder las points in octree buffer
 	lasTreeList := make([]*grid_tree.GridTree, 0)
 	for i, filePath := range lasFilePathList {
 		// Define point_loader strategy
-		lasTree := tilerMerge.algorithmManager.GetTreeAlgorithm()
+		lasTree, err := tilerMerge.newGridTree()
+		if err != nil {
+			_ = lasFileLoader.LasFile.Clear()
+			_ = lasFileLoader.LasFile.Close()
+			return nil, err
+		}
 		glog.Infoln("Processing file " + strconv.Itoa(i+1) + "/" + strconv.Itoa(len(lasFilePathList)) + ", " + filePath)
-		tilerMerge.loadLasFileIntoTree(filePath, opts, lasTree)
+		if err := tilerMerge.loadLasFileIntoTree(ctx, filePath, opts, lasTree); err != nil {
+			_ = lasFileLoader.LasFile.Clear()
+			_ = lasFileLoader.LasFile.Close()
+			return nil, err
+		}
 
 		lasTreeList = append(lasTreeList, lasTree)
 	}
@@ -246,49 +334,61 @@ func (tilerMerge *TilerMerge) mergeLasFileListToSingleTree(
 		tilerMerge.prepareDataStructure(parentTree)
 	*/
 
-	if err := tilerMerge.RepairParentTree(tree, lasTreeList); err != nil {
-		glog.Fatal(err)
+	err = runStage(tilerMerge.tracker, opts.Input, progress.StageBuild, func() error {
+		return tilerMerge.RepairParentTree(tree, lasTreeList)
+	})
+	if err != nil {
+		_ = lasFileLoader.LasFile.Clear()
+		_ = lasFileLoader.LasFile.Close()
 		return nil, err
 	}
 
 	return lasFileLoader.LasFile, nil
 }
 
-func (tilerMerge *TilerMerge) loadLasFileIntoTree(filePath string, opts *tiler.TilerOptions, tree *grid_tree.GridTree) {
+// Loads the points of a chunk las file into the given tree. Only the points of the chunk files are counted as read in
+// the summary, as the merged las file holds the same points
+func (tilerMerge *TilerMerge) loadLasFileIntoTree(ctx context.Context, filePath string, opts *tiler.TilerOptions, tree *grid_tree.GridTree) error {
 	// Create octree from las
-	lasFileLoader, err := tilerMerge.readLasData(filePath, opts, tree)
+	readTask := tilerMerge.tracker.Start(filePath, progress.StageRead, getLasFileNumberOfPoints(filePath))
+	lasFileLoader, err := tilerMerge.readLasData(ctx, filePath, opts, tree, readTask)
+	readTask.Finish(err)
 	if err != nil {
-		glog.Fatal(err)
+		return err
 	}
 	defer func() {
 		_ = lasFileLoader.LasFile.Clear()
 		_ = lasFileLoader.LasFile.Close()
 	}()
+	tilerMerge.tracker.Summary().AddFile(int64(lasFileLoader.LasFile.Header.NumberPoints), 0)
 
-	tilerMerge.prepareDataStructure(tree)
+	if err := tilerMerge.prepareDataStructure(tree, filePath); err != nil {
+		return err
+	}
 	glog.Infoln(tree.GetRootNode().NumberOfPoints(), tree.GetRootNode().TotalNumberOfPoints())
 
 	glog.Infoln("> done processing", filepath.Base(filePath))
+
+	return nil
 }
 
-func (tilerMerge *TilerMerge) readLasData(filePath string, opts *tiler.TilerOptions, tree *grid_tree.GridTree) (*lidario.LasFileLoader, error) {
+func (tilerMerge *TilerMerge) readLasData(ctx context.Context, filePath string, opts *tiler.TilerOptions, tree *grid_tree.GridTree, task *progress.Task) (*lidario.LasFileLoader, error) {
 	// Reading files
 	glog.Infoln("> reading data from las file...", filepath.Base(filePath))
-	lasFileLoader, err := readLas(filePath, opts, tree)
+	// the merge processes a file at a time, reading it with a goroutine per CPU
+	lasFileLoader, err := readLas(ctx, filePath, 0, nil, nil, opts, tree, task)
 	if err != nil {
-		glog.Fatal(err)
 		return nil, err
 	}
 
 	return lasFileLoader, nil
 }
 
-func (tilerMerge *TilerMerge) prepareDataStructure(octree *grid_tree.GridTree) error {
+func (tilerMerge *TilerMerge) prepareDataStructure(octree *grid_tree.GridTree, filePath string) error {
 	// Build tree hierarchical structure
 	glog.Infoln("> building data structure...")
 
-	if err := octree.Build(); err != nil {
-		glog.Fatal(err)
+	if err := runStage(tilerMerge.tracker, filePath, progress.StageBuild, octree.Build); err != nil {
 		return err
 	}
 
@@ -297,14 +397,14 @@ func (tilerMerge *TilerMerge) prepareDataStructure(octree *grid_tree.GridTree) e
 
 	return nil
 }
-func (tilerMerge *TilerMerge) mergeLasFileList(lasFilePathList []string) (_mergeLasFilePath string, _err error) {
-	mergedLasFilePath := "/tmp/merged.las"
+
+// Concatenates the given las files into a single las file written in the given folder
+func (tilerMerge *TilerMerge) mergeLasFileList(lasFilePathList []string, workDir string, task *progress.Task) (_mergeLasFilePath string, _err error) {
+	mergedLasFilePath := path.Join(workDir, MergedLasFileName)
 
 	filePath := lasFilePathList[0]
 	lf0, err := lidario.NewLasFile(filePath, "r")
 	if err != nil {
-		glog.Infoln(err)
-		glog.Fatal(err)
 		return "", err
 	}
 	defer func() {
@@ -316,15 +416,13 @@ func (tilerMerge *TilerMerge) mergeLasFileList(lasFilePathList []string) (_merge
 
 	if _, err := os.Stat(mergedLasFilePath); err == nil {
 		if err := os.Remove(mergedLasFilePath); err != nil {
-			glog.Fatal(err)
+			return "", err
 		}
 	} else if !errors.Is(err, os.ErrNotExist) {
-		glog.Fatal(err)
+		return "", err
 	}
 	newLf, err := lidario.InitializeUsingFile(mergedLasFilePath, lf0)
 	if err != nil {
-		glog.Infoln(err)
-		glog.Fatal(err)
 		return "", err
 	}
 	defer func() {
@@ -335,8 +433,6 @@ func (tilerMerge *TilerMerge) mergeLasFileList(lasFilePathList []string) (_merge
 	}()
 
 	if err := newLf.CopyHeaderXYZ(lf0.Header); err != nil {
-		glog.Infoln(err)
-		glog.Fatal(err)
 		return "", err
 	}
 
@@ -347,15 +443,11 @@ func (tilerMerge *TilerMerge) mergeLasFileList(lasFilePathList []string) (_merge
 		glog.Infof("mergeLasFileList %d/%d %s", i+1, len(lasFilePathList), filePath)
 		lf, err := lidario.NewLasFile(filePath, "r")
 		if err != nil {
-			glog.Infoln(err)
-			glog.Fatal(err)
 			return "", err
 		}
 		defer lf.Close()
 
 		if err := newLf.MergeHeaderXYZ(lf.Header); err != nil {
-			glog.Infoln(err)
-			glog.Fatal(err)
 			return "", err
 		}
 
@@ -365,25 +457,30 @@ func (tilerMerge *TilerMerge) mergeLasFileList(lasFilePathList []string) (_merge
 			// }
 			p, err := lf.LasPoint(i)
 			if err != nil {
-				glog.Infoln(err)
-				glog.Fatal(err)
 				return "", err
 			}
-			newLf.AddLasPoint(p)
+			if err := newLf.AddLasPoint(p); err != nil {
+				return "", err
+			}
+			if (i+1)%lasExportProgressPoints == 0 {
+				task.Add(lasExportProgressPoints)
+			}
 		}
+		task.Add(int64(lf.Header.NumberPoints) % lasExportProgressPoints)
 
 		lf.Close()
 	}
 
-	newLf.Close()
+	err = newLf.Close()
 	newLf = nil
+	if err != nil {
+		return "", err
+	}
 
 	// Check
 	glog.Infof("mergedLasFilePath %s", mergedLasFilePath)
 	mergedLf, err := lidario.NewLasFile(mergedLasFilePath, "r")
 	if err != nil {
-		glog.Infoln(err)
-		glog.Fatal(err)
 		return "", err
 	}
 	defer mergedLf.Close()
@@ -391,6 +488,30 @@ func (tilerMerge *TilerMerge) mergeLasFileList(lasFilePathList []string) (_merge
 	return mergedLasFilePath, nil
 }
 
+// Name of the las file concatenating the chunk las files of a merge, written in its work folder
+const MergedLasFileName = "merged.las"
+
+// Creates the folder of the temporary files of a merge in the work folder of the options, or in the system temporary
+// folder if not set
+func newMergeWorkDir(opts *tiler.TilerOptions) (string, error) {
+	workDir, err := ioutil.TempDir(opts.WorkDir, "cesium-tiler-merge-")
+	if err != nil {
+		return "", err
+	}
+	glog.Infoln("merge workdir", workDir)
+
+	return workDir, nil
+}
+
+// Returns a new tree of the algorithm manager, which must be a grid tree as only grid trees can be merged
+func (tilerMerge *TilerMerge) newGridTree() (*grid_tree.GridTree, error) {
+	tree, isGridTree := tilerMerge.algorithmManager.GetTreeAlgorithm().(*grid_tree.GridTree)
+	if !isGridTree {
+		return nil, errors.New("the merge commands only support the grid algorithm")
+	}
+	return tree, nil
+}
+
 func (tilerMerge *TilerMerge) RepairParentTree(octree *grid_tree.GridTree, treeList []*grid_tree.GridTree) error {
 	// Build tree hierarchical structure
 	glog.Infoln("> building parent tree structure...")
@@ -399,12 +520,12 @@ func (tilerMerge *TilerMerge) RepairParentTree(octree *grid_tree.GridTree, treeL
 	bboxList := make([]*geometry.BoundingBox, 0)
 	nodeList := make([]*grid_tree.GridNode, 0)
 	for _, tree := range treeList {
-		rootNode := tree.GetRootNode()
+		rootNode := tree.GetRootGridNode()
 		bboxList = append(bboxList, rootNode.GetBoundingBox())
 		nodeList = append(nodeList, rootNode)
 	}
 
-	rootNode := octree.GetRootNode()
+	rootNode := octree.GetRootGridNode()
 	rootNode.SetSpartialBoundingBoxByMergeBbox(bboxList)
 	rootNode.SetChildren(nodeList)
 
@@ -441,11 +562,9 @@ func (tilerMerge *TilerMerge) repairTilesetMetadata(opts *tiler.TilerOptions, la
 	rootTileset := io.Tileset{}
 	rootFile, err := ioutil.ReadFile(rootMetadataPath)
 	if err != nil {
-		glog.Fatal(err)
 		return err
 	}
 	if err := json.Unmarshal([]byte(rootFile), &rootTileset); err != nil {
-		glog.Fatal(err)
 		return err
 	}
 
@@ -460,11 +579,9 @@ func (tilerMerge *TilerMerge) repairTilesetMetadata(opts *tiler.TilerOptions, la
 		childTileset := io.Tileset{}
 		childFile, err := ioutil.ReadFile(filePath)
 		if err != nil {
-			glog.Fatal(err)
 			return err
 		}
 		if err := json.Unmarshal([]byte(childFile), &childTileset); err != nil {
-			glog.Fatal(err)
 			return err
 		}
 		childTilesetList = append(childTilesetList, &childTileset)
@@ -495,30 +612,21 @@ func (tilerMerge *TilerMerge) repairTilesetMetadata(opts *tiler.TilerOptions, la
 	rootTileset.Root.GeometricError = 2 * childGeometricError
 
 	// merge tileset .boundingVolume
-	region := rootTileset.Root.BoundingVolume.Region
+	childBoundingVolumes := make([]io.BoundingVolume, 0, len(childTilesetList))
 	for _, childTileset := range childTilesetList {
-		childRegion := childTileset.Root.BoundingVolume.Region
-
-		region[0] = math.Min(float64(childRegion[0]), region[0])
-		region[1] = math.Min(float64(childRegion[1]), region[1])
-		region[2] = math.Max(float64(childRegion[2]), region[2])
-		region[3] = math.Max(float64(childRegion[3]), region[3])
-		region[4] = math.Min(float64(childRegion[4]), region[4])
-		region[5] = math.Max(float64(childRegion[5]), region[5])
+		childBoundingVolumes = append(childBoundingVolumes, childTileset.Root.BoundingVolume)
 	}
-	rootTileset.Root.BoundingVolume.Region = region
+	rootTileset.Root.BoundingVolume = io.EncloseBoundingVolumes(rootTileset.Root.BoundingVolume, childBoundingVolumes)
 
 	// write root tilset.json
 	// Outputting a formatted json file
 	rootTilesetJSON, err := json.MarshalIndent(rootTileset, "", "\t")
 	if err != nil {
-		glog.Fatal(err)
 		return err
 	}
 
 	// Writes the tileset.json binary content to the given file
 	if err = ioutil.WriteFile(rootMetadataPath, rootTilesetJSON, 0666); err != nil {
-		glog.Fatal(err)
 		return err
 	}
 
@@ -541,11 +649,9 @@ func (tilerMerge *TilerMerge) AdjustRootGeometricError(opts *tiler.TilerOptions,
 	rootTileset := io.Tileset{}
 	rootFile, err := ioutil.ReadFile(rootMetadataPath)
 	if err != nil {
-		glog.Fatal(err)
 		return err
 	}
 	if err := json.Unmarshal([]byte(rootFile), &rootTileset); err != nil {
-		glog.Fatal(err)
 		return err
 	}
 
@@ -555,43 +661,43 @@ func (tilerMerge *TilerMerge) AdjustRootGeometricError(opts *tiler.TilerOptions,
 	// Outputting a formatted json file
 	rootTilesetJSON, err := json.MarshalIndent(rootTileset, "", "\t")
 	if err != nil {
-		glog.Fatal(err)
 		return err
 	}
 
 	// Writes the tileset.json binary content to the given file
 	if err = ioutil.WriteFile(rootMetadataPath, rootTilesetJSON, 0666); err != nil {
-		glog.Fatal(err)
 		return err
 	}
 
 	return nil
 }
 
-func (tilerMerge *TilerMerge) exportTreeRootTileset(octree *grid_tree.GridTree, opts *tiler.TilerOptions) {
+func (tilerMerge *TilerMerge) exportTreeRootTileset(ctx context.Context, octree *grid_tree.GridTree, opts *tiler.TilerOptions, task *progress.Task) error {
 	glog.Infoln("> exporting data...")
-	err := tilerMerge.exportRootNodeTileset(opts, octree)
-	if err != nil {
-		glog.Fatal(err)
-	}
+	return tilerMerge.exportRootNodeTileset(ctx, opts, octree, task)
 }
 
 // Exports the data cloud represented by the given built octree into 3D tiles data structure according to the options
-// specified in the TilerOptions instance
-func (tilerMerge *TilerMerge) exportRootNodeTileset(opts *tiler.TilerOptions, tree *grid_tree.GridTree) error {
+// specified in the TilerOptions instance. The producer stops submitting work when the context is canceled or a
+// consumer raises an error
+func (tilerMerge *TilerMerge) exportRootNodeTileset(ctx context.Context, opts *tiler.TilerOptions, tree *grid_tree.GridTree, task *progress.Task) error {
 	// if octree is not built, exit
 	if !tree.IsBuilt() {
 		return errors.New("octree not built, data structure not initialized")
 	}
 
+	// the export is canceled by the first error raised by a consumer
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
 	// a consumer goroutine per CPU
 	numConsumers := runtime.NumCPU()
 
 	// init channel where to submit work with a buffer 5 times greater than the number of consumer
 	workChannel := make(chan *io.WorkUnit, numConsumers*5)
 
-	// init channel where consumers can eventually submit errors that prevented them to finish the job
-	errorChannel := make(chan error)
+	// init collector where consumers can eventually submit errors that prevented them to finish the job
+	exportErrors := newExportErrors(numConsumers, cancel)
 
 	var waitGroup sync.WaitGroup
 
@@ -602,35 +708,28 @@ func (tilerMerge *TilerMerge) exportRootNodeTileset(opts *tiler.TilerOptions, tr
 	subfolder := ""
 	producer := io.NewStandardMergeProducer(outputDir, subfolder, opts)
 	rootNode := tree.GetRootNode()
-	go producer.Produce(workChannel, &waitGroup, rootNode)
+	go producer.Produce(ctx, workChannel, &waitGroup, rootNode)
 
 	// add consumers to waitgroup and launch them
 	for i := 0; i < numConsumers; i++ {
 		waitGroup.Add(1)
-		consumer := io.NewStandardConsumer(tilerMerge.algorithmManager.GetCoordinateConverterAlgorithm(), opts.RefineMode, opts.Draco, opts.DracoEncoderPath)
-		go consumer.Consume(workChannel, errorChannel, &waitGroup)
+		consumer := io.NewStandardConsumer(tilerMerge.algorithmManager.GetCoordinateConverterAlgorithm(), opts.RefineMode, opts.Draco, opts.DracoEncoderPath, opts.DracoMethod, opts.DracoQuantizationBits, opts.OutputFormat, opts.Meshopt, opts.BoundingVolume, opts.BatchAttributes)
+		consumer.SetProgress(task)
+		go consumer.Consume(ctx, workChannel, exportErrors.channel, &waitGroup)
 	}
 
 	// wait for producers and consumers to finish
 	waitGroup.Wait()
 
-	// close error chan
-	close(errorChannel)
-
-	// find if there are errors in the error channel buffer
-	withErrors := false
-	for err := range errorChannel {
-		glog.Infoln(err)
-		withErrors = true
-	}
-	if withErrors {
-		return errors.New("errors raised during execution. Check console output for details")
+	// find if there are errors raised by the consumers
+	if err := exportErrors.wait(); err != nil {
+		return err
 	}
 
-	return nil
+	return ctx.Err()
 }
 
-func (tilerMerge *TilerMerge) exportRootNodeLas(octree *grid_tree.GridTree, opts *tiler.TilerOptions, lasFile *lidario.LasFile) error {
+func (tilerMerge *TilerMerge) exportRootNodeLas(octree *grid_tree.GridTree, opts *tiler.TilerOptions, lasFile *lidario.LasFile, task *progress.Task) error {
 	parentFolder := opts.Input
 
 	var err error
@@ -646,8 +745,7 @@ func (tilerMerge *TilerMerge) exportRootNodeLas(octree *grid_tree.GridTree, opts
 	newFileName := path.Join(parentFolder, "content.las")
 	newLf, err := lidario.InitializeUsingFile(newFileName, lasFile)
 	if err != nil {
-		glog.Infoln(err)
-		glog.Fatal(err)
+		return err
 	}
 	defer func() {
 		if newLf != nil {
@@ -657,13 +755,9 @@ func (tilerMerge *TilerMerge) exportRootNodeLas(octree *grid_tree.GridTree, opts
 	}()
 
 	if err := newLf.CopyHeaderXYZ(lasFile.Header); err != nil {
-		glog.Infoln(err)
-		glog.Fatal(err)
+		return err
 	}
 
-	progress := 0
-	oldProgress := -1
-
 	rootNode := octree.GetRootNode()
 	numberOfPoints := rootNode.NumberOfPoints()
 	points := rootNode.GetPoints()
@@ -675,25 +769,28 @@ func (tilerMerge *TilerMerge) exportRootNodeLas(octree *grid_tree.GridTree, opts
 
 		pointLas, err := lasFile.LasPoint(point.PointExtend.LasPointIndex)
 		if err != nil {
-			glog.Infoln(err)
-			glog.Fatal(err)
 			return err
 		}
 
-		newLf.AddLasPoint(pointLas)
+		if err := newLf.AddLasPoint(pointLas); err != nil {
+			return err
+		}
 
-		// print export-progress
-		progress = int(100.0 * float64(i+1) / float64(numberOfPoints))
-		if progress != oldProgress {
-			oldProgress = progress
-			if progress%50 == 0 {
-				glog.Infof("export root-node progress: %v\n", progress)
-			}
+		if (i+1)%lasExportProgressPoints == 0 {
+			task.Add(lasExportProgressPoints)
 		}
 	}
+	task.Add(int64(numberOfPoints) % lasExportProgressPoints)
 
-	newLf.Close()
+	err = newLf.Close()
 	newLf = nil
+	if err != nil {
+		return err
+	}
+
+	if fileInfo, err := os.Stat(newFileName); err == nil {
+		task.AddBytes(fileInfo.Size())
+	}
 
 	return nil
 }
@@ -0,0 +1,292 @@
+package pkg
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ecopia-map/cesium_tiler/internal/tiler"
+	"github.com/golang/glog"
+)
+
+// Content types of the files of a tileset, by file extension
+var tilesetContentTypes = map[string]string{
+	".json":    "application/json",
+	".pnts":    "application/octet-stream",
+	".b3dm":    "application/octet-stream",
+	".subtree": "application/octet-stream",
+	".glb":     "model/gltf-binary",
+	".gltf":    "model/gltf+json",
+	".las":     "application/octet-stream",
+	".html":    "text/html; charset=utf-8",
+}
+
+// Extensions of the files compressed with gzip when the client accepts it
+var gzipExtensions = map[string]bool{
+	".json":    true,
+	".pnts":    true,
+	".subtree": true,
+	".glb":     true,
+	".gltf":    true,
+	".html":    true,
+}
+
+type TilerServe struct{}
+
+func NewTilerServe() tiler.ITiler {
+	return &TilerServe{}
+}
+
+// Serves the tileset folder given as input over HTTP until the context is canceled
+func (tilerServe *TilerServe) RunTiler(ctx context.Context, opts *tiler.TilerOptions) error {
+	server := &http.Server{
+		Addr:    opts.TilerServeOptions.Address,
+		Handler: NewTilesetHandler(opts.Input, opts.TilerServeOptions.Viewer),
+	}
+
+	glog.Infof("serving tileset folder [%s] on [%s]", opts.Input, opts.TilerServeOptions.Address)
+	if opts.TilerServeOptions.Viewer {
+		glog.Infof("viewer available at http://%s/", displayAddress(opts.TilerServeOptions.Address))
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		return err
+	case <-ctx.Done():
+		if err := server.Shutdown(context.Background()); err != nil {
+			return err
+		}
+		return ctx.Err()
+	}
+}
+
+// Serves the files of a tileset folder with CORS, gzip, range and ETag support
+type TilesetHandler struct {
+	folder string
+	viewer bool
+}
+
+// Instances a new handler serving the given tileset folder. If viewer is true the root path serves a Cesium page
+// loading the tilesets of the folder
+func NewTilesetHandler(folder string, viewer bool) http.Handler {
+	return &TilesetHandler{
+		folder: folder,
+		viewer: viewer,
+	}
+}
+
+func (h *TilesetHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	setCorsHeaders(w)
+
+	switch r.Method {
+	case http.MethodOptions:
+		w.WriteHeader(http.StatusNoContent)
+		return
+	case http.MethodGet, http.MethodHead:
+	default:
+		w.Header().Set("Allow", "GET, HEAD, OPTIONS")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	urlPath := path.Clean("/" + r.URL.Path)
+	if urlPath == "/" && h.viewer {
+		h.serveViewer(w, r)
+		return
+	}
+
+	filePath := filepath.Join(h.folder, filepath.FromSlash(urlPath))
+	file, err := os.Open(filePath)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil || info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+
+	ext := strings.ToLower(filepath.Ext(info.Name()))
+	if contentType, ok := tilesetContentTypes[ext]; ok {
+		w.Header().Set("Content-Type", contentType)
+	}
+
+	etag := fmt.Sprintf("\"%x-%x\"", info.ModTime().UnixNano(), info.Size())
+
+	if gzipExtensions[ext] {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		// range requests address the bytes of the file as stored, so they are never compressed
+		if r.Header.Get("Range") == "" && acceptsGzip(r) {
+			serveGzip(w, r, file, strings.TrimSuffix(etag, "\"")+"-gzip\"")
+			return
+		}
+	}
+
+	// ServeContent handles range and conditional requests using the ETag header
+	w.Header().Set("ETag", etag)
+	http.ServeContent(w, r, info.Name(), info.ModTime(), file)
+}
+
+// Writes the gzip compressed content of the given file
+func serveGzip(w http.ResponseWriter, r *http.Request, file io.Reader, etag string) {
+	w.Header().Set("ETag", etag)
+	if matchesETag(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Encoding", "gzip")
+	w.Header().Del("Content-Length")
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	gzipWriter := gzip.NewWriter(w)
+	defer gzipWriter.Close()
+
+	if _, err := io.Copy(gzipWriter, file); err != nil {
+		glog.Infoln(err)
+	}
+}
+
+// Writes the viewer page loading the root tileset of the folder, or the tilesets of its chunks if there is none
+func (h *TilesetHandler) serveViewer(w http.ResponseWriter, r *http.Request) {
+	tilesetURLs, err := findRootTilesets(h.folder)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	tilesetURLsJSON, err := json.Marshal(tilesetURLs)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", tilesetContentTypes[".html"])
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	if err := viewerTemplate.Execute(w, template.JS(tilesetURLsJSON)); err != nil {
+		glog.Infoln(err)
+	}
+}
+
+// Returns the urls of the tilesets to load in the viewer: the tileset.json of the folder if present, otherwise the
+// tileset.json of each of its subfolders
+func findRootTilesets(folder string) ([]string, error) {
+	if _, err := os.Stat(filepath.Join(folder, "tileset.json")); err == nil {
+		return []string{"tileset.json"}, nil
+	}
+
+	matches, err := filepath.Glob(filepath.Join(folder, "*", "tileset.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(matches)
+
+	tilesetURLs := make([]string, 0, len(matches))
+	for _, match := range matches {
+		relativePath, err := filepath.Rel(folder, match)
+		if err != nil {
+			return nil, err
+		}
+		tilesetURLs = append(tilesetURLs, filepath.ToSlash(relativePath))
+	}
+
+	return tilesetURLs, nil
+}
+
+func setCorsHeaders(w http.ResponseWriter) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, HEAD, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Range, If-None-Match, If-Modified-Since, Accept-Encoding")
+	w.Header().Set("Access-Control-Expose-Headers", "Content-Length, Content-Range, Content-Encoding, ETag, Accept-Ranges")
+}
+
+func acceptsGzip(r *http.Request) bool {
+	for _, encoding := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		encoding = strings.TrimSpace(strings.SplitN(encoding, ";", 2)[0])
+		if encoding == "gzip" {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesETag(ifNoneMatch string, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the address to display to reach the server, listening on all the interfaces is shown as localhost
+func displayAddress(address string) string {
+	if strings.HasPrefix(address, ":") {
+		return "localhost" + address
+	}
+	return address
+}
+
+var viewerTemplate = template.Must(template.New("viewer").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+	<meta charset="utf-8">
+	<title>cesium_tiler viewer</title>
+	<script src="https://cesium.com/downloads/cesiumjs/releases/1.110/Build/Cesium/Cesium.js"></script>
+	<link href="https://cesium.com/downloads/cesiumjs/releases/1.110/Build/Cesium/Widgets/widgets.css" rel="stylesheet">
+	<style>
+		html, body, #cesiumContainer { width: 100%; height: 100%; margin: 0; padding: 0; overflow: hidden; }
+	</style>
+</head>
+<body>
+	<div id="cesiumContainer"></div>
+	<script>
+		const tilesetUrls = {{.}};
+		const viewer = new Cesium.Viewer("cesiumContainer", {
+			baseLayerPicker: false,
+			geocoder: false,
+			timeline: false,
+			animation: false
+		});
+		viewer.scene.globe.depthTestAgainstTerrain = false;
+
+		Promise.all(tilesetUrls.map(function (url) {
+			return Cesium.Cesium3DTileset.fromUrl(url, { maximumScreenSpaceError: 4 }).then(function (tileset) {
+				viewer.scene.primitives.add(tileset);
+				return tileset;
+			});
+		})).then(function (tilesets) {
+			if (tilesets.length > 0) {
+				viewer.zoomTo(tilesets[0]);
+			}
+		}).catch(function (error) {
+			console.error(error);
+		});
+	</script>
+</body>
+</html>
+`))
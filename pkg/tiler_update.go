@@ -0,0 +1,217 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/ecopia-map/cesium_tiler/internal/progress"
+	"github.com/ecopia-map/cesium_tiler/internal/tiler"
+	"github.com/ecopia-map/cesium_tiler/pkg/algorithm_manager/std_algorithm_manager"
+	"github.com/ecopia-map/cesium_tiler/tools"
+	"github.com/golang/glog"
+)
+
+// Updates a tileset written by the index and merge-tree commands after some of its las files changed. The changed
+// files are found comparing them with the index manifests of the tileset, their chunk tilesets are written again and
+// only the folders containing them, up to the root, are merged again
+type TilerUpdate struct {
+	fileFinder tools.FileFinder
+}
+
+func NewTilerUpdate(fileFinder tools.FileFinder) tiler.ITiler {
+	return &TilerUpdate{
+		fileFinder: fileFinder,
+	}
+}
+
+// A las file recorded in an index manifest of the tileset whose chunk tileset is out of date
+type changedLasFile struct {
+	filePath string
+	folder   string // folder of the manifest, where the chunk tileset of the file is written
+}
+
+// Starts the update of the tileset of the input folder. The statistics of the run are written in the summary file of
+// the input folder, even if the run fails
+func (tilerUpdate *TilerUpdate) RunTiler(ctx context.Context, opts *tiler.TilerOptions) error {
+	tracker, closeProgress, err := newProgressTracker(opts, opts.Input)
+	if err != nil {
+		return err
+	}
+	defer closeProgress()
+
+	err = tilerUpdate.runTiler(ctx, opts, tracker)
+	writeSummary(tracker, opts.Input, err)
+
+	return err
+}
+
+func (tilerUpdate *TilerUpdate) runTiler(ctx context.Context, opts *tiler.TilerOptions, tracker *progress.Tracker) error {
+	rootDir := strings.TrimSuffix(filepath.Join(opts.Input, ""), "/")
+
+	levelDirsMap, err := getMergeTreeLevels(rootDir)
+	if err != nil {
+		return err
+	}
+	maxLevel := len(levelDirsMap) - 1
+
+	changedFiles, err := findChangedLasFiles(levelDirsMap)
+	if err != nil {
+		return err
+	}
+	if len(changedFiles) == 0 {
+		glog.Infoln("> no changed las_file found, tileset is up to date", rootDir)
+		return nil
+	}
+
+	// the folders containing the changed files are merged again, up to the root
+	mergeDirs := make(map[string]bool)
+	for _, changedFile := range changedFiles {
+		glog.Infof("changed las_file [%s] of folder [%s]", changedFile.filePath, changedFile.folder)
+		for dir := changedFile.folder; ; dir = filepath.Dir(dir) {
+			mergeDirs[dir] = true
+			if dir == rootDir || !strings.HasPrefix(dir, rootDir) {
+				break
+			}
+		}
+	}
+	glog.Infoln("folders to merge", tools.FmtJSONString(mergeDirs))
+
+	if opts.TilerUpdateOptions.DryRun {
+		glog.Infoln("> dry-run, tileset not updated", rootDir)
+		return nil
+	}
+
+	// all the changed files must share the srid of the tileset
+	lasFiles := make([]string, 0, len(changedFiles))
+	for _, changedFile := range changedFiles {
+		lasFiles = append(lasFiles, changedFile.filePath)
+	}
+	srid, err := DetectSrid(lasFiles, opts.Srid, opts.Srid != 0)
+	if err != nil {
+		return err
+	}
+
+	for _, changedFile := range changedFiles {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := tilerUpdate.indexLasFile(ctx, opts, changedFile, srid, tracker); err != nil {
+			return fmt.Errorf("indexing las_file [%s]: %v", changedFile.filePath, err)
+		}
+	}
+
+	mergeOpts := getUpdateMergeOptions(opts, srid)
+	mergeAlgorithmManager, err := std_algorithm_manager.NewAlgorithmManager(mergeOpts)
+	if err != nil {
+		return err
+	}
+	tilerMerge := &TilerMerge{
+		fileFinder:       tilerUpdate.fileFinder,
+		algorithmManager: mergeAlgorithmManager,
+		tracker:          tracker,
+	}
+	for i := maxLevel; i >= 0; i-- {
+		for _, dir := range levelDirsMap[i] {
+			if !mergeDirs[dir] {
+				continue
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			if err := tilerMerge.mergeTreeFolder(ctx, mergeOpts, dir, i, maxLevel); err != nil {
+				return err
+			}
+		}
+	}
+
+	glog.Infoln("> done updating", rootDir)
+
+	return nil
+}
+
+// Returns the las files recorded in the index manifests of the given folders whose chunk tileset is incomplete or has
+// been written from a different version of the file
+func findChangedLasFiles(levelDirsMap map[int][]string) ([]changedLasFile, error) {
+	changedFiles := make([]changedLasFile, 0)
+
+	for level := 0; level < len(levelDirsMap); level++ {
+		for _, dir := range levelDirsMap[level] {
+			if _, err := os.Stat(path.Join(dir, IndexManifestFileName)); os.IsNotExist(err) {
+				continue
+			}
+
+			manifest, err := LoadIndexManifest(dir)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, entry := range manifest.Files {
+				current, err := NewIndexManifestEntry(entry.Path, entry.Subfolder)
+				if os.IsNotExist(err) {
+					return nil, fmt.Errorf("las_file [%s] of manifest [%s] not found", entry.Path, path.Join(dir, IndexManifestFileName))
+				} else if err != nil {
+					return nil, fmt.Errorf("las_file of manifest [%s]: %v", path.Join(dir, IndexManifestFileName), err)
+				}
+				completed, err := entry.IsCompletedFor(current)
+				if err != nil {
+					return nil, fmt.Errorf("las_file of manifest [%s]: %v", path.Join(dir, IndexManifestFileName), err)
+				}
+				if !completed {
+					changedFiles = append(changedFiles, changedLasFile{filePath: entry.Path, folder: dir})
+				}
+			}
+		}
+	}
+
+	return changedFiles, nil
+}
+
+// Writes again the chunk tileset of the given changed file in the folder of its manifest, which keeps the entries of
+// the other files
+func (tilerUpdate *TilerUpdate) indexLasFile(ctx context.Context, opts *tiler.TilerOptions, changedFile changedLasFile, srid int, tracker *progress.Tracker) error {
+	indexOpts := opts.Copy()
+	indexOpts.Command = tools.CommandIndex
+	indexOpts.Input = changedFile.filePath
+	indexOpts.Srid = srid
+	indexOpts.FolderProcessing = false
+	indexOpts.Recursive = false
+	indexOpts.TilerUpdateOptions = nil
+	indexOpts.TilerIndexOptions.Output = changedFile.folder
+	indexOpts.TilerIndexOptions.Resume = true
+
+	algorithmManager, err := std_algorithm_manager.NewAlgorithmManager(indexOpts)
+	if err != nil {
+		return err
+	}
+	tilerIndex := &TilerIndex{
+		fileFinder:       tilerUpdate.fileFinder,
+		algorithmManager: algorithmManager,
+	}
+
+	return tilerIndex.runTiler(ctx, indexOpts, tracker)
+}
+
+// Returns the options merging the folders of the tileset, as given to the merge-tree command
+func getUpdateMergeOptions(opts *tiler.TilerOptions, srid int) *tiler.TilerOptions {
+	mergeOpts := opts.Copy()
+	mergeOpts.Command = tools.CommandMergeTree
+	mergeOpts.Srid = srid
+	mergeOpts.Algorithm = tiler.Grid
+	mergeOpts.FolderProcessing = true
+	mergeOpts.CellMaxSize = opts.TilerUpdateOptions.MergeGridMaxSize
+	mergeOpts.CellMinSize = opts.TilerUpdateOptions.MergeGridMaxSize / 2
+	mergeOpts.MinNumPointsPerNode = opts.TilerUpdateOptions.MergeMinNumPointsPerNode
+	mergeOpts.MaxNumPointsPerNode = opts.TilerUpdateOptions.MergeMaxNumPointsPerNode
+	mergeOpts.TilerIndexOptions = nil
+	mergeOpts.TilerUpdateOptions = nil
+	mergeOpts.TilerMergeOptions = &tiler.TilerMergeOptions{
+		Output: "",
+	}
+
+	return mergeOpts
+}
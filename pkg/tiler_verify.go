@@ -1,11 +1,13 @@
 package pkg
 
 import (
+	"context"
 	"errors"
 	"os"
+	"path"
 	"path/filepath"
 
-	"github.com/ecopia-map/cesium_tiler/internal/octree/grid_tree"
+	"github.com/ecopia-map/cesium_tiler/internal/octree"
 	"github.com/ecopia-map/cesium_tiler/internal/tiler"
 	"github.com/ecopia-map/cesium_tiler/pkg/algorithm_manager"
 	lidario "github.com/ecopia-map/cesium_tiler/third_party/lasread"
@@ -25,30 +27,29 @@ func NewTilerVerify(fileFinder tools.FileFinder, algorithmManager algorithm_mana
 	}
 }
 
-func (tilerVerify *TilerVerify) RunTiler(opts *tiler.TilerOptions) error {
+func (tilerVerify *TilerVerify) RunTiler(ctx context.Context, opts *tiler.TilerOptions) error {
 	if opts.Command == tools.CommandVerifyLas {
 
-		if err := tilerVerify.RunTilerVerifyLas(opts); err != nil {
-			glog.Infoln(err)
-			return nil
-		}
+		return tilerVerify.RunTilerVerifyLas(ctx, opts)
 	} else if opts.Command == tools.CommandVerifyLasMerge {
-		lasFilePathList := []string{
-			// "./las/10009-6-29-20-1.las",
-			// "./las/10009-7-58-40-0.las",
-			// "./las/10009-8-117-87-2.las",
-			"./las/10009-7-57-41-1.las",
-			// "tileset-las1/chunk-tileset-10009-6-29-20-1/content.las",
-			// "tileset-las1/chunk-tileset-10009-7-58-40-0/content.las",
-			// "tileset-las1/chunk-tileset-10009-8-117-87-2/content.las",
-			// "tileset-las1/chunk-tileset-10009-7-57-41-1/content.las",
-			// "./0/7/7-57-41-1.las",
-			// "tileset/0/chunk-tileset-7-57-41-1/content.las",
+		// the content.las of the chunk tilesets found in the input folder
+		lasFilePathList, err := tilerVerify.fileFinder.GetLasFilesToMerge(opts)
+		if err != nil {
+			return err
 		}
-		mergedLasFilePath, err := tilerVerify.mergeLasFileListCheck(lasFilePathList)
+		if len(lasFilePathList) == 0 {
+			return errors.New("no chunk las file found in " + opts.Input)
+		}
+		// the merged las file is the output of the command, its folder is only removed when the merge fails
+		workDir, err := newMergeWorkDir(opts)
 		if err != nil {
-			glog.Fatal(err)
-			return nil
+			return err
+		}
+
+		mergedLasFilePath, err := tilerVerify.mergeLasFileListCheck(lasFilePathList, workDir)
+		if err != nil {
+			_ = os.RemoveAll(workDir)
+			return err
 		}
 		glog.Infoln("mergedLasFilePath", mergedLasFilePath)
 		return nil
@@ -57,14 +58,14 @@ func (tilerVerify *TilerVerify) RunTiler(opts *tiler.TilerOptions) error {
 	return nil
 }
 
-func (tilerVerify *TilerVerify) RunTilerVerifyLas(opts *tiler.TilerOptions) error {
+func (tilerVerify *TilerVerify) RunTilerVerifyLas(ctx context.Context, opts *tiler.TilerOptions) error {
 	filePath := opts.Input
 
 	// Create empty octree
 	tree := tilerVerify.algorithmManager.GetTreeAlgorithm()
-	lasFileLoader, err := tilerVerify.readLasData(filePath, opts, tree)
+	lasFileLoader, err := tilerVerify.readLasData(ctx, filePath, opts, tree)
 	if err != nil {
-		glog.Fatal(err)
+		return err
 	}
 	defer func() {
 		_ = lasFileLoader.LasFile.Clear()
@@ -73,40 +74,46 @@ func (tilerVerify *TilerVerify) RunTilerVerifyLas(opts *tiler.TilerOptions) erro
 		// lasFileLoader.Tree = nil
 	}()
 
-	tilerVerify.VerifyLasLoader(opts)
+	if err := tilerVerify.VerifyLasLoader(opts); err != nil {
+		return err
+	}
 
-	tilerVerify.prepareDataStructure(tree)
+	if err := tilerVerify.prepareDataStructure(tree); err != nil {
+		return err
+	}
 
-	tilerVerify.VerifyLas(lasFileLoader.LasFile, opts)
+	if err := tilerVerify.VerifyLas(lasFileLoader.LasFile, opts); err != nil {
+		return err
+	}
 
 	glog.Infoln("> done processing", filepath.Base(filePath))
 
 	return nil
 }
 
-func (tilerVerify *TilerVerify) readLasData(filePath string, opts *tiler.TilerOptions, tree *grid_tree.GridTree) (*lidario.LasFileLoader, error) {
+func (tilerVerify *TilerVerify) readLasData(ctx context.Context, filePath string, opts *tiler.TilerOptions, tree octree.ITree) (*lidario.LasFileLoader, error) {
 	// Reading files
 	glog.Infoln("> reading data from las file...", filepath.Base(filePath))
-	lasFileLoader, err := readLas(filePath, opts, tree)
+	lasFileLoader, err := readLas(ctx, filePath, 0, nil, nil, opts, tree, nil)
 	if err != nil {
-		glog.Fatal(err)
 		return nil, err
 	}
 
 	return lasFileLoader, nil
 }
 
-func (tilerVerify *TilerVerify) prepareDataStructure(octree *grid_tree.GridTree) {
+func (tilerVerify *TilerVerify) prepareDataStructure(octree octree.ITree) error {
 	// Build tree hierarchical structure
 	glog.Infoln("> building data structure...")
 
 	if err := octree.Build(); err != nil {
-		glog.Fatal(err)
+		return err
 	}
 
 	rootNode := octree.GetRootNode()
 	glog.Infoln("las_file root_node num_of_points:", rootNode.NumberOfPoints(), ", points.len:", len(rootNode.GetPoints()))
 
+	return nil
 }
 
 func (tilerVerify *TilerVerify) VerifyLasLoader(opts *tiler.TilerOptions) error {
@@ -122,8 +129,6 @@ func (tilerVerify *TilerVerify) VerifyLas(lasFile *lidario.LasFile, opts *tiler.
 
 		pointLas, err := lasFile.LasPoint(i)
 		if err != nil {
-			glog.Infoln(err)
-			glog.Fatal(err)
 			return err
 		}
 
@@ -144,14 +149,12 @@ func (tilerVerify *TilerVerify) VerifyLas(lasFile *lidario.LasFile, opts *tiler.
 	return nil
 }
 
-func (tilerVerify *TilerVerify) mergeLasFileListCheck(lasFilePathList []string) (_mergeLasFilePath string, _err error) {
-	mergedLasFilePath := "/tmp/merged.las"
+func (tilerVerify *TilerVerify) mergeLasFileListCheck(lasFilePathList []string, workDir string) (_mergeLasFilePath string, _err error) {
+	mergedLasFilePath := path.Join(workDir, MergedLasFileName)
 
 	filePath := lasFilePathList[0]
 	lf0, err := lidario.NewLasFile(filePath, "r")
 	if err != nil {
-		glog.Infoln(err)
-		glog.Fatal(err)
 		return "", err
 	}
 	defer func() {
@@ -163,15 +166,13 @@ func (tilerVerify *TilerVerify) mergeLasFileListCheck(lasFilePathList []string)
 
 	if _, err := os.Stat(mergedLasFilePath); err == nil {
 		if err := os.Remove(mergedLasFilePath); err != nil {
-			glog.Fatal(err)
+			return "", err
 		}
 	} else if !errors.Is(err, os.ErrNotExist) {
-		glog.Fatal(err)
+		return "", err
 	}
 	newLf, err := lidario.InitializeUsingFile(mergedLasFilePath, lf0)
 	if err != nil {
-		glog.Infoln(err)
-		glog.Fatal(err)
 		return "", err
 	}
 	defer func() {
@@ -182,8 +183,6 @@ func (tilerVerify *TilerVerify) mergeLasFileListCheck(lasFilePathList []string)
 	}()
 
 	if err := newLf.CopyHeaderXYZ(lf0.Header); err != nil {
-		glog.Infoln(err)
-		glog.Fatal(err)
 		return "", err
 	}
 
@@ -194,15 +193,11 @@ func (tilerVerify *TilerVerify) mergeLasFileListCheck(lasFilePathList []string)
 		glog.Infof("mergeLasFileList %d/%d %s", i+1, len(lasFilePathList), filePath)
 		lf, err := lidario.NewLasFile(filePath, "r")
 		if err != nil {
-			glog.Infoln(err)
-			glog.Fatal(err)
 			return "", err
 		}
 		defer lf.Close()
 
 		if err := newLf.MergeHeaderXYZ(lf.Header); err != nil {
-			glog.Infoln(err)
-			glog.Fatal(err)
 			return "", err
 		}
 
@@ -212,11 +207,11 @@ func (tilerVerify *TilerVerify) mergeLasFileListCheck(lasFilePathList []string)
 			// }
 			p, err := lf.LasPoint(i)
 			if err != nil {
-				glog.Infoln(err)
-				glog.Fatal(err)
 				return "", err
 			}
-			newLf.AddLasPoint(p)
+			if err := newLf.AddLasPoint(p); err != nil {
+				return "", err
+			}
 		}
 
 		lf.Close()
@@ -229,8 +224,6 @@ func (tilerVerify *TilerVerify) mergeLasFileListCheck(lasFilePathList []string)
 	glog.Infof("mergedLasFilePath %s", mergedLasFilePath)
 	mergedLf, err := lidario.NewLasFile(mergedLasFilePath, "r")
 	if err != nil {
-		glog.Infoln(err)
-		glog.Fatal(err)
 		return "", err
 	}
 	defer mergedLf.Close()
@@ -0,0 +1,51 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/ecopia-map/cesium_tiler/internal/tiler"
+	"github.com/golang/glog"
+)
+
+type TilerVerifyTileset struct{}
+
+func NewTilerVerifyTileset() tiler.ITiler {
+	return &TilerVerifyTileset{}
+}
+
+// Verifies the tileset given as input and writes the json report of the problems found. Returns an error if the
+// tileset is not valid
+func (tilerVerifyTileset *TilerVerifyTileset) RunTiler(ctx context.Context, opts *tiler.TilerOptions) error {
+	glog.Infoln("> verifying tileset", opts.Input)
+
+	report, err := VerifyTileset(opts.Input)
+	if err != nil {
+		return err
+	}
+
+	jsonData, err := json.MarshalIndent(report, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	if opts.TilerVerifyTilesetOptions.Report == "" {
+		if _, err := os.Stdout.Write(append(jsonData, '\n')); err != nil {
+			return err
+		}
+	} else if err := ioutil.WriteFile(opts.TilerVerifyTilesetOptions.Report, jsonData, 0666); err != nil {
+		return err
+	}
+
+	glog.Infof("verified tilesets:[%d] tiles:[%d] contents:[%d] problems:[%d]",
+		report.Tilesets, report.Tiles, report.Contents, len(report.Problems))
+
+	if !report.Valid {
+		return fmt.Errorf("%d problems found in tileset %s", len(report.Problems), report.Tileset)
+	}
+
+	return nil
+}
@@ -0,0 +1,620 @@
+package pkg
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/ecopia-map/cesium_tiler/internal/geometry"
+	"github.com/golang/glog"
+)
+
+// Tolerances used when checking that a bounding region is contained in the one of its parent
+const (
+	regionAngleTolerance  = 1e-9 // radians
+	regionHeightTolerance = 1e-3 // meters
+)
+
+// Tolerance in meters used when checking that a box or sphere is contained in the bounding volume of its parent
+const boundingVolumeTolerance = 1e-3
+
+// Size in bytes of the header of a pnts file
+const pntsHeaderLength = 28
+
+// Size in bytes of the header of a glb file
+const glbHeaderLength = 12
+
+// Kinds of problems reported when verifying a tileset
+const (
+	CheckTilesetJson    = "tileset_json"
+	CheckBoundingVolume = "bounding_volume"
+	CheckContent        = "content"
+	CheckContainment    = "bounding_volume_containment"
+	CheckGeometricError = "geometric_error"
+)
+
+// A problem found in a tileset
+type TilesetProblem struct {
+	File    string `json:"file"`           // path of the file where the problem was found
+	Tile    string `json:"tile,omitempty"` // json path of the tile within the tileset.json, if any
+	Check   string `json:"check"`          // kind of check that failed
+	Message string `json:"message"`
+}
+
+// Machine readable result of the verification of a tileset
+type TilesetReport struct {
+	Tileset  string           `json:"tileset"`
+	Valid    bool             `json:"valid"`
+	Tilesets int              `json:"tilesets"`
+	Tiles    int              `json:"tiles"`
+	Contents int              `json:"contents"`
+	Problems []TilesetProblem `json:"problems"`
+}
+
+// Subset of the 3D Tiles tileset.json schema decoded for verification. Pointers are used to tell missing properties
+// from zero values
+type verifyTileset struct {
+	Asset *struct {
+		Version string `json:"version"`
+	} `json:"asset"`
+	GeometricError *float64    `json:"geometricError"`
+	Root           *verifyTile `json:"root"`
+}
+
+type verifyTile struct {
+	BoundingVolume *verifyBoundingVolume `json:"boundingVolume"`
+	GeometricError *float64              `json:"geometricError"`
+	Refine         string                `json:"refine"`
+	Content        *struct {
+		Uri string `json:"uri"`
+		Url string `json:"url"` // 3D Tiles 1.0 pre-release name of uri
+	} `json:"content"`
+	Children       *[]verifyTile    `json:"children"`
+	ImplicitTiling *json.RawMessage `json:"implicitTiling"`
+}
+
+type verifyBoundingVolume struct {
+	Region []float64 `json:"region"`
+	Box    []float64 `json:"box"`
+	Sphere []float64 `json:"sphere"`
+}
+
+// Walks the tileset rooted at the given tileset.json, or at the tileset.json of the given folder, following the
+// external tilesets it references and returns all the problems found
+func VerifyTileset(tilesetPath string) (*TilesetReport, error) {
+	if info, err := os.Stat(tilesetPath); err != nil {
+		return nil, err
+	} else if info.IsDir() {
+		tilesetPath = path.Join(tilesetPath, "tileset.json")
+	}
+
+	verifier := &tilesetVerifier{
+		report: &TilesetReport{
+			Tileset:  tilesetPath,
+			Problems: make([]TilesetProblem, 0),
+		},
+	}
+	verifier.verifyTilesetFile(tilesetPath, nil, "")
+	verifier.report.Valid = len(verifier.report.Problems) == 0
+
+	return verifier.report, nil
+}
+
+type tilesetVerifier struct {
+	report *TilesetReport
+}
+
+func (v *tilesetVerifier) addProblem(file string, tile string, check string, format string, args ...interface{}) {
+	problem := TilesetProblem{
+		File:    file,
+		Tile:    tile,
+		Check:   check,
+		Message: fmt.Sprintf(format, args...),
+	}
+	glog.Infof("tileset problem. file:[%s] tile:[%s] check:[%s] %s", problem.File, problem.Tile, problem.Check, problem.Message)
+	v.report.Problems = append(v.report.Problems, problem)
+}
+
+// Verifies a tileset.json file. The given parent is the tile referencing it, nil for the root tileset
+func (v *tilesetVerifier) verifyTilesetFile(filePath string, parent *verifyTile, parentFile string) {
+	jsonData, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		v.addProblem(parentFile, "", CheckContent, "external tileset [%s] cannot be read: %v", filePath, err)
+		return
+	}
+	v.report.Tilesets++
+
+	var tileset verifyTileset
+	if err := json.Unmarshal(jsonData, &tileset); err != nil {
+		v.addProblem(filePath, "", CheckTilesetJson, "invalid json: %v", err)
+		return
+	}
+
+	if tileset.Asset == nil {
+		v.addProblem(filePath, "", CheckTilesetJson, "missing required property asset")
+	} else if tileset.Asset.Version != "1.0" && tileset.Asset.Version != "1.1" {
+		v.addProblem(filePath, "", CheckTilesetJson, "unsupported asset.version [%s]", tileset.Asset.Version)
+	}
+
+	if tileset.GeometricError == nil {
+		v.addProblem(filePath, "", CheckTilesetJson, "missing required property geometricError")
+	} else if *tileset.GeometricError < 0 {
+		v.addProblem(filePath, "", CheckGeometricError, "geometricError %f is negative", *tileset.GeometricError)
+	}
+
+	if tileset.Root == nil {
+		v.addProblem(filePath, "", CheckTilesetJson, "missing required property root")
+		return
+	}
+
+	root := tileset.Root
+	if root.Refine == "" {
+		v.addProblem(filePath, "root", CheckTilesetJson, "missing required property refine of the root tile")
+	}
+	if tileset.GeometricError != nil && root.GeometricError != nil && *root.GeometricError > *tileset.GeometricError {
+		v.addProblem(filePath, "root", CheckGeometricError, "root geometricError %f greater than tileset geometricError %f",
+			*root.GeometricError, *tileset.GeometricError)
+	}
+
+	v.verifyTile(filePath, "root", root, parent)
+}
+
+// Verifies the given tile and its children against the schema and against its parent, nil for a root tile
+func (v *tilesetVerifier) verifyTile(filePath string, tilePath string, tile *verifyTile, parent *verifyTile) {
+	v.report.Tiles++
+
+	v.verifyTileProperties(filePath, tilePath, tile)
+
+	if parent != nil {
+		if tile.GeometricError != nil && parent.GeometricError != nil && *tile.GeometricError > *parent.GeometricError {
+			v.addProblem(filePath, tilePath, CheckGeometricError, "geometricError %f greater than parent geometricError %f",
+				*tile.GeometricError, *parent.GeometricError)
+		}
+		if tile.BoundingVolume != nil && parent.BoundingVolume != nil {
+			if msg, ok := boundingVolumeContained(tile.BoundingVolume, parent.BoundingVolume); !ok {
+				v.addProblem(filePath, tilePath, CheckContainment, "bounding volume not contained in parent: %s", msg)
+			}
+		}
+	}
+
+	if tile.ImplicitTiling != nil {
+		v.verifyImplicitRoot(filePath, tilePath, tile)
+	} else if tile.Content != nil {
+		v.verifyContent(filePath, tilePath, tile)
+	}
+
+	if tile.Children != nil {
+		for i := range *tile.Children {
+			v.verifyTile(filePath, fmt.Sprintf("%s.children[%d]", tilePath, i), &(*tile.Children)[i], tile)
+		}
+	}
+}
+
+func (v *tilesetVerifier) verifyTileProperties(filePath string, tilePath string, tile *verifyTile) {
+	if tile.GeometricError == nil {
+		v.addProblem(filePath, tilePath, CheckTilesetJson, "missing required property geometricError")
+	} else if *tile.GeometricError < 0 {
+		v.addProblem(filePath, tilePath, CheckGeometricError, "geometricError %f is negative", *tile.GeometricError)
+	}
+
+	if tile.Refine != "" && tile.Refine != "ADD" && tile.Refine != "REPLACE" {
+		v.addProblem(filePath, tilePath, CheckTilesetJson, "refine should be either ADD or REPLACE, found [%s]", tile.Refine)
+	}
+
+	if tile.Children != nil && len(*tile.Children) == 0 {
+		v.addProblem(filePath, tilePath, CheckTilesetJson, "children must contain at least one tile when defined")
+	}
+
+	if tile.Content != nil && tile.Content.Uri == "" && tile.Content.Url == "" {
+		v.addProblem(filePath, tilePath, CheckTilesetJson, "missing required property content.uri")
+	}
+
+	if tile.BoundingVolume == nil {
+		v.addProblem(filePath, tilePath, CheckTilesetJson, "missing required property boundingVolume")
+	} else if msg, ok := validateBoundingVolume(tile.BoundingVolume); !ok {
+		v.addProblem(filePath, tilePath, CheckBoundingVolume, "%s", msg)
+	}
+}
+
+// Verifies the content referenced by the tile: external tilesets are walked, pnts and glb headers are checked
+func (v *tilesetVerifier) verifyContent(filePath string, tilePath string, tile *verifyTile) {
+	uri := tile.Content.Uri
+	if uri == "" {
+		uri = tile.Content.Url
+	}
+	if uri == "" {
+		return
+	}
+
+	contentPath := path.Join(path.Dir(filePath), filepath.FromSlash(uri))
+	switch strings.ToLower(path.Ext(uri)) {
+	case ".json":
+		v.verifyTilesetFile(contentPath, tile, filePath)
+	case ".pnts":
+		v.report.Contents++
+		if err := verifyPntsFile(contentPath); err != nil {
+			v.addProblem(filePath, tilePath, CheckContent, "content [%s]: %v", uri, err)
+		}
+	case ".glb":
+		v.report.Contents++
+		if err := verifyGlbFile(contentPath); err != nil {
+			v.addProblem(filePath, tilePath, CheckContent, "content [%s]: %v", uri, err)
+		}
+	default:
+		v.report.Contents++
+		if _, err := os.Stat(contentPath); err != nil {
+			v.addProblem(filePath, tilePath, CheckContent, "content [%s]: %v", uri, err)
+		}
+	}
+}
+
+// Verifies that the root subtree and the root content of an implicit tileset exist
+func (v *tilesetVerifier) verifyImplicitRoot(filePath string, tilePath string, tile *verifyTile) {
+	var implicitTiling struct {
+		Subtrees struct {
+			Uri string `json:"uri"`
+		} `json:"subtrees"`
+	}
+	if err := json.Unmarshal(*tile.ImplicitTiling, &implicitTiling); err != nil || implicitTiling.Subtrees.Uri == "" {
+		v.addProblem(filePath, tilePath, CheckTilesetJson, "missing required property implicitTiling.subtrees.uri")
+		return
+	}
+
+	subtreePath := path.Join(path.Dir(filePath), filepath.FromSlash(expandRootTemplate(implicitTiling.Subtrees.Uri)))
+	if _, err := os.Stat(subtreePath); err != nil {
+		v.addProblem(filePath, tilePath, CheckContent, "root subtree: %v", err)
+	}
+
+	if tile.Content != nil && tile.Content.Uri != "" {
+		rootContent := *tile
+		rootContent.Content.Uri = expandRootTemplate(tile.Content.Uri)
+		v.verifyContent(filePath, tilePath, &rootContent)
+	}
+}
+
+// Replaces the implicit tiling template variables with the coordinates of the root tile
+func expandRootTemplate(uri string) string {
+	return strings.NewReplacer("{level}", "0", "{x}", "0", "{y}", "0", "{z}", "0").Replace(uri)
+}
+
+// Checks that the bounding volume defines exactly one valid region, box or sphere
+func validateBoundingVolume(bv *verifyBoundingVolume) (string, bool) {
+	defined := 0
+	if bv.Region != nil {
+		defined++
+		if len(bv.Region) != 6 {
+			return fmt.Sprintf("region must have 6 elements, found %d", len(bv.Region)), false
+		}
+		west, south, east, north, minHeight, maxHeight := bv.Region[0], bv.Region[1], bv.Region[2], bv.Region[3], bv.Region[4], bv.Region[5]
+		if west < -math.Pi || east > math.Pi || west > math.Pi || east < -math.Pi {
+			return fmt.Sprintf("region longitude [%f, %f] out of range [-pi, pi]", west, east), false
+		}
+		if south < -math.Pi/2 || north > math.Pi/2 || south > north {
+			return fmt.Sprintf("region latitude [%f, %f] invalid", south, north), false
+		}
+		if minHeight > maxHeight {
+			return fmt.Sprintf("region minimum height %f greater than maximum height %f", minHeight, maxHeight), false
+		}
+	}
+	if bv.Box != nil {
+		defined++
+		if len(bv.Box) != 12 {
+			return fmt.Sprintf("box must have 12 elements, found %d", len(bv.Box)), false
+		}
+	}
+	if bv.Sphere != nil {
+		defined++
+		if len(bv.Sphere) != 4 {
+			return fmt.Sprintf("sphere must have 4 elements, found %d", len(bv.Sphere)), false
+		}
+		if bv.Sphere[3] < 0 {
+			return fmt.Sprintf("sphere radius %f is negative", bv.Sphere[3]), false
+		}
+	}
+
+	if defined == 0 {
+		return "boundingVolume must define a region, a box or a sphere", false
+	}
+
+	return "", true
+}
+
+// Checks that the child bounding volume is contained in the parent one. Regions are compared on their bounds, boxes
+// and spheres are compared in ECEF coordinates
+func boundingVolumeContained(child *verifyBoundingVolume, parent *verifyBoundingVolume) (string, bool) {
+	if len(child.Region) == 6 && len(parent.Region) == 6 {
+		return regionContained(child.Region, parent.Region)
+	}
+
+	if len(child.Sphere) == 4 {
+		sphere := &geometry.BoundingSphere{
+			Center: geometry.Coordinate{X: child.Sphere[0], Y: child.Sphere[1], Z: child.Sphere[2]},
+			Radius: child.Sphere[3],
+		}
+		if len(parent.Sphere) == 4 {
+			parentSphere := &geometry.BoundingSphere{
+				Center: geometry.Coordinate{X: parent.Sphere[0], Y: parent.Sphere[1], Z: parent.Sphere[2]},
+				Radius: parent.Sphere[3],
+			}
+			if parentSphere.Enclose(sphere).Radius > parentSphere.Radius+boundingVolumeTolerance {
+				return fmt.Sprintf("sphere %v outside parent sphere %v", child.Sphere, parent.Sphere), false
+			}
+			return "", true
+		}
+	}
+
+	var corners []geometry.Coordinate
+	if len(child.Box) == 12 {
+		corners = newVerifyBox(child.Box).GetCorners()
+	} else if len(child.Sphere) == 4 {
+		// corners of the cube circumscribed to the sphere, as used when enclosing spheres in other volumes
+		center := geometry.Coordinate{X: child.Sphere[0], Y: child.Sphere[1], Z: child.Sphere[2]}
+		r := child.Sphere[3]
+		corners = (&geometry.OrientedBoundingBox{
+			Center:   center,
+			HalfAxes: [3]geometry.Coordinate{{X: r}, {Y: r}, {Z: r}},
+		}).GetCorners()
+	} else if len(child.Region) == 6 {
+		corners = geometry.GetRegionCorners(child.Region)
+	} else {
+		return "", true
+	}
+
+	for _, corner := range corners {
+		contained := true
+		if len(parent.Box) == 12 {
+			contained = newVerifyBox(parent.Box).Contains(corner, boundingVolumeTolerance)
+		} else if len(parent.Sphere) == 4 {
+			parentSphere := &geometry.BoundingSphere{
+				Center: geometry.Coordinate{X: parent.Sphere[0], Y: parent.Sphere[1], Z: parent.Sphere[2]},
+				Radius: parent.Sphere[3],
+			}
+			contained = parentSphere.Contains(corner, boundingVolumeTolerance)
+		} else if len(parent.Region) == 6 {
+			lon, lat, height := geometry.EcefToGeodetic(corner)
+			_, contained = regionContained([]float64{lon, lat, lon, lat, height, height}, parent.Region)
+		}
+		if !contained {
+			return fmt.Sprintf("corner [%f, %f, %f] outside parent", corner.X, corner.Y, corner.Z), false
+		}
+	}
+
+	return "", true
+}
+
+// Checks that the child region is contained in the parent one
+func regionContained(c []float64, p []float64) (string, bool) {
+	// regions crossing the antimeridian have west greater than east and are not compared on longitude
+	if c[0] <= c[2] && p[0] <= p[2] {
+		if c[0] < p[0]-regionAngleTolerance || c[2] > p[2]+regionAngleTolerance {
+			return fmt.Sprintf("longitude [%f, %f] outside parent [%f, %f]", c[0], c[2], p[0], p[2]), false
+		}
+	}
+	if c[1] < p[1]-regionAngleTolerance || c[3] > p[3]+regionAngleTolerance {
+		return fmt.Sprintf("latitude [%f, %f] outside parent [%f, %f]", c[1], c[3], p[1], p[3]), false
+	}
+	if c[4] < p[4]-regionHeightTolerance || c[5] > p[5]+regionHeightTolerance {
+		return fmt.Sprintf("height [%f, %f] outside parent [%f, %f]", c[4], c[5], p[4], p[5]), false
+	}
+
+	return "", true
+}
+
+// Returns the box of a 12 elements 3D Tiles boundingVolume.box
+func newVerifyBox(box []float64) *geometry.OrientedBoundingBox {
+	return &geometry.OrientedBoundingBox{
+		Center: geometry.Coordinate{X: box[0], Y: box[1], Z: box[2]},
+		HalfAxes: [3]geometry.Coordinate{
+			{X: box[3], Y: box[4], Z: box[5]},
+			{X: box[6], Y: box[7], Z: box[8]},
+			{X: box[9], Y: box[10], Z: box[11]},
+		},
+	}
+}
+
+// Checks the header of a pnts file against its size and the feature and batch tables it contains
+func verifyPntsFile(filePath string) error {
+	content, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+	if len(content) < pntsHeaderLength {
+		return fmt.Errorf("file length %d shorter than the pnts header", len(content))
+	}
+	if string(content[0:4]) != "pnts" {
+		return fmt.Errorf("invalid magic [%s]", string(content[0:4]))
+	}
+	if version := binary.LittleEndian.Uint32(content[4:8]); version != 1 {
+		return fmt.Errorf("unsupported version %d", version)
+	}
+
+	byteLength := int(binary.LittleEndian.Uint32(content[8:12]))
+	featureTableJsonLength := int(binary.LittleEndian.Uint32(content[12:16]))
+	featureTableBinaryLength := int(binary.LittleEndian.Uint32(content[16:20]))
+	batchTableJsonLength := int(binary.LittleEndian.Uint32(content[20:24]))
+	batchTableBinaryLength := int(binary.LittleEndian.Uint32(content[24:28]))
+
+	if byteLength != len(content) {
+		return fmt.Errorf("header byteLength %d differs from file length %d", byteLength, len(content))
+	}
+	if sum := pntsHeaderLength + featureTableJsonLength + featureTableBinaryLength + batchTableJsonLength + batchTableBinaryLength; sum != byteLength {
+		return fmt.Errorf("header byteLength %d differs from the sum of the header and table lengths %d", byteLength, sum)
+	}
+
+	offset := pntsHeaderLength
+	featureTableJson := content[offset : offset+featureTableJsonLength]
+	offset += featureTableJsonLength + featureTableBinaryLength
+	batchTableJson := content[offset : offset+batchTableJsonLength]
+
+	var featureTable map[string]json.RawMessage
+	if err := json.Unmarshal(featureTableJson, &featureTable); err != nil {
+		return fmt.Errorf("invalid feature table json: %v", err)
+	}
+
+	var pointsLength int
+	if raw, ok := featureTable["POINTS_LENGTH"]; !ok {
+		return fmt.Errorf("feature table misses POINTS_LENGTH")
+	} else if err := json.Unmarshal(raw, &pointsLength); err != nil {
+		return fmt.Errorf("invalid POINTS_LENGTH: %v", err)
+	}
+
+	if raw, ok := featureTable["extensions"]; ok && strings.Contains(string(raw), "3DTILES_draco_point_compression") {
+		var extensions struct {
+			Draco struct {
+				ByteOffset int `json:"byteOffset"`
+				ByteLength int `json:"byteLength"`
+			} `json:"3DTILES_draco_point_compression"`
+		}
+		if err := json.Unmarshal(raw, &extensions); err != nil {
+			return fmt.Errorf("invalid draco extension: %v", err)
+		}
+		if end := extensions.Draco.ByteOffset + extensions.Draco.ByteLength; end > featureTableBinaryLength {
+			return fmt.Errorf("draco buffer ends at %d beyond feature table binary length %d", end, featureTableBinaryLength)
+		}
+	} else {
+		if _, ok := featureTable["POSITION"]; !ok {
+			if _, ok := featureTable["POSITION_QUANTIZED"]; !ok {
+				return fmt.Errorf("feature table misses POSITION")
+			}
+		}
+		if err := verifyTableBinaryRanges(featureTable, pntsSemanticSizes, pointsLength, featureTableBinaryLength); err != nil {
+			return fmt.Errorf("feature table: %v", err)
+		}
+	}
+
+	if batchTableJsonLength > 0 {
+		var batchTable map[string]json.RawMessage
+		if err := json.Unmarshal(batchTableJson, &batchTable); err != nil {
+			return fmt.Errorf("invalid batch table json: %v", err)
+		}
+		// the properties compressed with draco are not stored in the binary body
+		if raw, ok := batchTable["extensions"]; ok {
+			var extensions struct {
+				Draco struct {
+					Properties map[string]int `json:"properties"`
+				} `json:"3DTILES_draco_point_compression"`
+			}
+			if err := json.Unmarshal(raw, &extensions); err != nil {
+				return fmt.Errorf("invalid batch table extensions: %v", err)
+			}
+			for name := range extensions.Draco.Properties {
+				delete(batchTable, name)
+			}
+		}
+		if err := verifyTableBinaryRanges(batchTable, nil, pointsLength, batchTableBinaryLength); err != nil {
+			return fmt.Errorf("batch table: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// Size in bytes of a point of the pnts feature table semantics stored in the binary body
+var pntsSemanticSizes = map[string]int{
+	"POSITION":           12,
+	"POSITION_QUANTIZED": 6,
+	"RGBA":               4,
+	"RGB":                3,
+	"RGB565":             2,
+	"NORMAL":             12,
+	"NORMAL_OCT16P":      2,
+	"BATCH_ID":           2,
+}
+
+var componentTypeSizes = map[string]int{
+	"BYTE":           1,
+	"UNSIGNED_BYTE":  1,
+	"SHORT":          2,
+	"UNSIGNED_SHORT": 2,
+	"INT":            4,
+	"UNSIGNED_INT":   4,
+	"FLOAT":          4,
+	"DOUBLE":         8,
+}
+
+var typeComponents = map[string]int{
+	"SCALAR": 1,
+	"VEC2":   2,
+	"VEC3":   3,
+	"VEC4":   4,
+}
+
+// Checks that every property of the table stored in the binary body fits in it. The size of a property is taken
+// from the given semantic sizes or from its componentType and type
+func verifyTableBinaryRanges(table map[string]json.RawMessage, semanticSizes map[string]int, count int, binaryLength int) error {
+	for name, raw := range table {
+		var reference struct {
+			ByteOffset    *int   `json:"byteOffset"`
+			ComponentType string `json:"componentType"`
+			Type          string `json:"type"`
+		}
+		if err := json.Unmarshal(raw, &reference); err != nil || reference.ByteOffset == nil {
+			// global properties and values stored in the json
+			continue
+		}
+
+		size, ok := semanticSizes[name]
+		if !ok {
+			componentSize, okComponent := componentTypeSizes[reference.ComponentType]
+			components, okType := typeComponents[reference.Type]
+			if !okComponent || !okType {
+				if semanticSizes == nil {
+					return fmt.Errorf("property %s has invalid componentType [%s] or type [%s]", name, reference.ComponentType, reference.Type)
+				}
+				continue
+			}
+			// typed arrays of the viewer cannot be created at an offset which is not a multiple of their element size
+			if *reference.ByteOffset%componentSize != 0 {
+				return fmt.Errorf("property %s byteOffset %d is not aligned to its componentType %s", name, *reference.ByteOffset, reference.ComponentType)
+			}
+			size = componentSize * components
+		}
+
+		if end := *reference.ByteOffset + count*size; end > binaryLength {
+			return fmt.Errorf("property %s ends at %d beyond binary length %d", name, end, binaryLength)
+		}
+	}
+
+	return nil
+}
+
+// Checks the header of a glb file against its size
+func verifyGlbFile(filePath string) error {
+	content, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+	if len(content) < glbHeaderLength {
+		return fmt.Errorf("file length %d shorter than the glb header", len(content))
+	}
+	if string(content[0:4]) != "glTF" {
+		return fmt.Errorf("invalid magic [%s]", string(content[0:4]))
+	}
+	if version := binary.LittleEndian.Uint32(content[4:8]); version != 2 {
+		return fmt.Errorf("unsupported version %d", version)
+	}
+	if length := int(binary.LittleEndian.Uint32(content[8:12])); length != len(content) {
+		return fmt.Errorf("header length %d differs from file length %d", length, len(content))
+	}
+
+	// chunks must cover exactly the rest of the file
+	offset := glbHeaderLength
+	for offset < len(content) {
+		if offset+8 > len(content) {
+			return fmt.Errorf("truncated chunk header at %d", offset)
+		}
+		chunkLength := int(binary.LittleEndian.Uint32(content[offset : offset+4]))
+		offset += 8 + chunkLength
+	}
+	if offset != len(content) {
+		return fmt.Errorf("chunks end at %d beyond file length %d", offset, len(content))
+	}
+
+	return nil
+}
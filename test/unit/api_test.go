@@ -0,0 +1,169 @@
+package unit_test
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ecopia-map/cesium_tiler/pkg"
+	"github.com/ecopia-map/cesium_tiler/pkg/api"
+	"github.com/ecopia-map/cesium_tiler/tools"
+)
+
+// cancelingReporter is an api.ProgressReporter canceling the run as soon as the given stage starts
+type cancelingReporter struct {
+	sync.Mutex
+	stage    api.ProgressEvent
+	cancel   context.CancelFunc
+	canceled bool
+}
+
+func (r *cancelingReporter) Report(event api.ProgressEvent) {
+	r.Lock()
+	defer r.Unlock()
+	if event.Type == r.stage.Type && event.Stage == r.stage.Stage {
+		r.canceled = true
+		r.cancel()
+	}
+}
+
+// Returns the options indexing the given LAZ fixture in the given output folder
+func apiTestIndexOptions(fileName string, output string) *api.Options {
+	opts := api.DefaultIndexOptions(filepath.Join(lazTestDataFolder, fileName), output)
+	opts.Srid = 32633
+	opts.CoordinateConverter = api.CoordinateConverterNative
+	return opts
+}
+
+func TestApiIndex(t *testing.T) {
+	output := t.TempDir()
+	options := apiTestIndexOptions("format3.las", output)
+	expected := options.Copy()
+
+	result, err := api.Index(context.Background(), options)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(result.Files) != 1 || result.Srid != 32633 {
+		t.Errorf("Unexpected result %+v", result)
+	}
+	if _, err := os.Stat(filepath.Join(output, tools.ChunkTilesetFilePrefix+"format3", "tileset.json")); err != nil {
+		t.Errorf("Expected the chunk tileset to be written: %s", err)
+	}
+
+	// the command and the srid are set on a copy of the options
+	if !reflect.DeepEqual(options, expected) {
+		t.Errorf("Expected the options not to be modified, got %+v", options)
+	}
+}
+
+func TestApiIndexReturnsErrors(t *testing.T) {
+	invalid := apiTestIndexOptions("format3.las", t.TempDir())
+	invalid.MaxNumPointsPerNode = 0
+	if _, err := api.Index(context.Background(), invalid); err == nil {
+		t.Errorf("Expected an error indexing with invalid options")
+	}
+
+	missingFile := apiTestIndexOptions("missing.las", t.TempDir())
+	if _, err := api.Index(context.Background(), missingFile); err == nil {
+		t.Errorf("Expected an error indexing a missing file")
+	}
+
+	layered := apiTestIndexOptions("format6_layered.laz", t.TempDir())
+	if _, err := api.Index(context.Background(), layered); err == nil || !strings.Contains(err.Error(), "point formats 6-10") {
+		t.Errorf("Expected the error of the unsupported laz file, got: %v", err)
+	}
+
+	merge := api.DefaultMergeOptions(t.TempDir())
+	merge.Command = "merge-everything"
+	if _, err := api.Merge(context.Background(), merge); err == nil {
+		t.Errorf("Expected an error merging with an unknown command")
+	}
+}
+
+func TestApiIndexCanceledDuringExport(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	reporter := &cancelingReporter{
+		stage:  api.ProgressEvent{Type: "start", Stage: "export"},
+		cancel: cancel,
+	}
+	options := apiTestIndexOptions("format3.las", t.TempDir())
+	options.ProgressReporter = reporter
+
+	_, err := api.Index(ctx, options)
+	if !reporter.canceled {
+		t.Fatalf("Expected the export to start")
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the run to be canceled, got: %v", err)
+	}
+}
+
+func TestApiIndexDracoPntsWithBatchAttributes(t *testing.T) {
+	output := t.TempDir()
+	options := apiTestIndexOptions("format3.las", output)
+	options.Draco = true
+
+	if _, err := api.Index(context.Background(), options); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	chunkFolder := filepath.Join(output, tools.ChunkTilesetFilePrefix+"format3")
+	report, err := pkg.VerifyTileset(chunkFolder)
+	if err != nil {
+		t.Fatalf("Unexpected error verifying the tileset: %s", err)
+	}
+	if !report.Valid {
+		t.Errorf("Expected a valid tileset, got %+v", report.Problems)
+	}
+
+	// the batch attributes are compressed with draco, following positions and colors
+	content, err := ioutil.ReadFile(filepath.Join(chunkFolder, "content.pnts"))
+	if err != nil {
+		t.Fatalf("Unexpected error reading the root content: %s", err)
+	}
+	featureTableLength := binary.LittleEndian.Uint32(content[12:16])
+	featureTableBinaryLength := binary.LittleEndian.Uint32(content[16:20])
+	batchTableLength := binary.LittleEndian.Uint32(content[20:24])
+	batchTableStart := 28 + featureTableLength + featureTableBinaryLength
+	var batchTable struct {
+		Intensity      map[string]interface{} `json:"INTENSITY"`
+		Classification map[string]interface{} `json:"CLASSIFICATION"`
+		Extensions     struct {
+			Draco struct {
+				Properties map[string]int `json:"properties"`
+			} `json:"3DTILES_draco_point_compression"`
+		} `json:"extensions"`
+	}
+	if err := json.Unmarshal(content[batchTableStart:batchTableStart+batchTableLength], &batchTable); err != nil {
+		t.Fatalf("Unexpected error decoding the batch table: %s", err)
+	}
+	if batchTable.Intensity == nil || batchTable.Classification == nil {
+		t.Errorf("Expected the batch attributes in the batch table, got %s", content[batchTableStart:batchTableStart+batchTableLength])
+	}
+	if properties := batchTable.Extensions.Draco.Properties; properties["INTENSITY"] != 2 || properties["CLASSIFICATION"] != 3 {
+		t.Errorf("Expected the draco attribute ids of the batch attributes, got %v", properties)
+	}
+	if binary.LittleEndian.Uint32(content[24:28]) != 0 {
+		t.Errorf("Expected no batch table binary")
+	}
+
+	// the external encoder only compresses positions and colors, explicitly set batch attributes are rejected
+	external := apiTestIndexOptions("format3.las", t.TempDir())
+	external.Draco = true
+	external.DracoEncoderPath = "draco_encoder"
+	external.BatchAttributes = []api.BatchAttribute{api.BatchAttributeGpsTime}
+	if _, err := api.Index(context.Background(), external); err == nil || !strings.Contains(err.Error(), "batch-attributes") {
+		t.Errorf("Expected the external draco encoder to be rejected with batch attributes, got: %v", err)
+	}
+}
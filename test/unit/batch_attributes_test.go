@@ -0,0 +1,101 @@
+package unit_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ecopia-map/cesium_tiler/internal/data"
+	"github.com/ecopia-map/cesium_tiler/internal/tiler"
+)
+
+func TestParseBatchAttributeList(t *testing.T) {
+	attributes := tiler.ParseBatchAttributeList("intensity, gps-time,POINT_SOURCE_ID")
+	expected := []tiler.BatchAttribute{tiler.BatchAttributeIntensity, tiler.BatchAttributeGpsTime, tiler.BatchAttributePointSourceId}
+	if !reflect.DeepEqual(attributes, expected) {
+		t.Errorf("Expected %v, got %v", expected, attributes)
+	}
+
+	if attributes := tiler.ParseBatchAttributeList(""); len(attributes) != 0 {
+		t.Errorf("Expected no attributes, got %v", attributes)
+	}
+
+	if attributes := tiler.ParseBatchAttributeList("intensity,rgb"); attributes[1] != "" {
+		t.Errorf("Expected invalid attribute to be parsed as empty value, got %v", attributes[1])
+	}
+
+	if name := tiler.BatchAttributeNumberOfReturns.String(); name != "number-of-returns" {
+		t.Errorf("Expected number-of-returns, got %s", name)
+	}
+}
+
+func TestParseExtraBytesBatchAttribute(t *testing.T) {
+	attributes := tiler.ParseBatchAttributeList("nir,EXTRA:Pulse width,extra:")
+	if attributes[0] != tiler.BatchAttributeNir {
+		t.Errorf("Expected %v, got %v", tiler.BatchAttributeNir, attributes[0])
+	}
+	if name, ok := attributes[1].ExtraBytesName(); !ok || name != "Pulse width" {
+		t.Errorf("Expected extra bytes attribute Pulse width, got %v", attributes[1])
+	}
+	if attributes[2] != "" {
+		t.Errorf("Expected extra bytes attribute without name to be parsed as empty value, got %v", attributes[2])
+	}
+
+	if name := attributes[1].PropertyName(); name != "EXTRA_Pulse_width" {
+		t.Errorf("Expected EXTRA_Pulse_width, got %s", name)
+	}
+	if name := attributes[1].String(); name != "extra:Pulse width" {
+		t.Errorf("Expected extra:Pulse width, got %s", name)
+	}
+	if names := tiler.ExtraBytesNames(attributes[:2]); !reflect.DeepEqual(names, []string{"Pulse width"}) {
+		t.Errorf("Expected [Pulse width], got %v", names)
+	}
+}
+
+func TestPointRecordRoundTrip(t *testing.T) {
+	point := data.NewPoint(1.5, 2.5, 3.5, 10, 20, 30, 65000, 6, &data.PointExtend{
+		LasPointIndex:   42,
+		GpsTime:         301234567.123456,
+		ScanAngle:       -12.5,
+		PointSourceID:   1234,
+		ReturnNumber:    2,
+		NumberOfReturns: 3,
+		UserData:        7,
+	})
+
+	buffer := make([]byte, data.PointRecordSize)
+	data.EncodePointRecord(buffer, point)
+	decoded := data.DecodePointRecord(buffer)
+
+	if !reflect.DeepEqual(decoded, point) {
+		t.Errorf("Expected %+v %+v, got %+v %+v", point, point.PointExtend, decoded, decoded.PointExtend)
+	}
+
+	// points without extend are decoded without extend
+	point.PointExtend = nil
+	data.EncodePointRecord(buffer, point)
+	if decoded := data.DecodePointRecord(buffer); decoded.PointExtend != nil || decoded.Intensity != 65000 {
+		t.Errorf("Expected point without extend and intensity 65000, got %+v", decoded)
+	}
+}
+
+func TestPointRecordExtraBytesRoundTrip(t *testing.T) {
+	point := data.NewPoint(1, 2, 3, 0, 0, 0, 100, 2, &data.PointExtend{
+		Nir:        1000,
+		ExtraBytes: []float64{12.5, -0.25},
+	})
+
+	length := data.PointRecordLength(point)
+	if length != data.PointRecordSize+16 {
+		t.Fatalf("Expected record length %d, got %d", data.PointRecordSize+16, length)
+	}
+	buffer := make([]byte, length)
+	data.EncodePointRecord(buffer, point)
+	if extraLength := data.PointRecordExtraBytesLength(buffer); extraLength != 16 {
+		t.Errorf("Expected 16 bytes of extra values, got %d", extraLength)
+	}
+
+	decoded := data.DecodePointRecord(buffer)
+	if !reflect.DeepEqual(decoded, point) {
+		t.Errorf("Expected %+v %+v, got %+v %+v", point, point.PointExtend, decoded, decoded.PointExtend)
+	}
+}
@@ -0,0 +1,93 @@
+package unit_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/ecopia-map/cesium_tiler/internal/geometry"
+)
+
+func TestGeodeticToEcefRoundTrip(t *testing.T) {
+	lon, lat, height := 12*math.Pi/180, 42*math.Pi/180, 250.0
+
+	coord := geometry.GeodeticToEcef(lon, lat, height)
+	gotLon, gotLat, gotHeight := geometry.EcefToGeodetic(coord)
+
+	if math.Abs(gotLon-lon) > 1e-12 || math.Abs(gotLat-lat) > 1e-12 {
+		t.Errorf("Expected lon/lat:%f/%f, got lon/lat:%f/%f", lon, lat, gotLon, gotLat)
+	}
+	if math.Abs(gotHeight-height) > 1e-6 {
+		t.Errorf("Expected height:%f, got height:%f", height, gotHeight)
+	}
+}
+
+func TestOrientedBoundingBoxFromPoints(t *testing.T) {
+	// points along a line rotated 45 degrees from the east direction
+	lon, lat := 12*math.Pi/180, 42*math.Pi/180
+	origin := geometry.GeodeticToEcef(lon, lat, 100)
+	east := geometry.Coordinate{X: -math.Sin(lon), Y: math.Cos(lon), Z: 0}
+	north := geometry.Coordinate{X: -math.Sin(lat) * math.Cos(lon), Y: -math.Sin(lat) * math.Sin(lon), Z: math.Cos(lat)}
+
+	var points []geometry.Coordinate
+	for i := -50; i <= 50; i++ {
+		d := float64(i) / math.Sqrt2
+		points = append(points, geometry.Coordinate{
+			X: origin.X + d*east.X + d*north.X,
+			Y: origin.Y + d*east.Y + d*north.Y,
+			Z: origin.Z + d*east.Z + d*north.Z,
+		})
+	}
+
+	box := geometry.NewOrientedBoundingBoxFromPoints(points)
+	if box == nil {
+		t.Fatalf("Expected a box, got nil")
+	}
+
+	for _, point := range points {
+		if !box.Contains(point, 1e-6) {
+			t.Errorf("Expected point %v to be contained in the box", point)
+		}
+	}
+
+	// the box is aligned to the line so its volume is much smaller than the one of the axis aligned box
+	volume := float64(8)
+	for _, halfAxis := range box.HalfAxes {
+		volume *= math.Sqrt(halfAxis.X*halfAxis.X + halfAxis.Y*halfAxis.Y + halfAxis.Z*halfAxis.Z)
+	}
+	if volume > 1 {
+		t.Errorf("Expected a tight box with volume lower than 1, got volume:%f", volume)
+	}
+
+	if len(box.GetAsArray()) != 12 {
+		t.Errorf("Expected 12 elements, got %d", len(box.GetAsArray()))
+	}
+	if len(box.GetCorners()) != 8 {
+		t.Errorf("Expected 8 corners, got %d", len(box.GetCorners()))
+	}
+}
+
+func TestOrientedBoundingBoxFromNoPoints(t *testing.T) {
+	if box := geometry.NewOrientedBoundingBoxFromPoints(nil); box != nil {
+		t.Errorf("Expected nil box, got %v", box)
+	}
+}
+
+func TestBoundingSphereEnclose(t *testing.T) {
+	center := geometry.Coordinate{X: 0, Y: 0, Z: 0}
+	sphere := geometry.NewBoundingSphereFromPoints(center, []geometry.Coordinate{{X: 3, Y: 4, Z: 0}, {X: 1, Y: 0, Z: 0}})
+	if sphere.Radius != 5 {
+		t.Errorf("Expected radius:%f, got radius:%f", 5.0, sphere.Radius)
+	}
+
+	other := &geometry.BoundingSphere{Center: geometry.Coordinate{X: 10, Y: 0, Z: 0}, Radius: 2}
+	enclosing := sphere.Enclose(other)
+	if enclosing.Radius != 12 {
+		t.Errorf("Expected radius:%f, got radius:%f", 12.0, enclosing.Radius)
+	}
+	if !enclosing.Contains(geometry.Coordinate{X: 12, Y: 0, Z: 0}, 0) {
+		t.Errorf("Expected the enclosing sphere to contain the other sphere")
+	}
+	if enclosing.Contains(geometry.Coordinate{X: 12.5, Y: 0, Z: 0}, 0) {
+		t.Errorf("Expected point outside of the enclosing sphere")
+	}
+}
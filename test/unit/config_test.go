@@ -0,0 +1,138 @@
+package unit_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ecopia-map/cesium_tiler/internal/tiler"
+	"github.com/ecopia-map/cesium_tiler/tools"
+)
+
+// Writes the given configuration to a file with the given name in a temporary folder
+func writeTestConfigFile(t *testing.T, name string, content string) string {
+	configPath := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return configPath
+}
+
+func TestReadConfigFileYaml(t *testing.T) {
+	content := `
+# comment
+input: "./las/a b.las"  # trailing comment
+srid: 32633
+geoid: true
+batch_attributes: [intensity, 'gps-time']
+index:
+  output: ./out
+  filter:
+    exclude_classes:
+    - 7
+    - 18
+    z_min: ~
+`
+	expected := map[string]interface{}{
+		"input":            "./las/a b.las",
+		"srid":             32633,
+		"geoid":            true,
+		"batch_attributes": []interface{}{"intensity", "gps-time"},
+		"index": map[string]interface{}{
+			"output": "./out",
+			"filter": map[string]interface{}{
+				"exclude_classes": []interface{}{7, 18},
+				"z_min":           nil,
+			},
+		},
+	}
+
+	output, err := tools.ReadConfigFile(writeTestConfigFile(t, "config.yaml", content))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(expected, output) {
+		t.Errorf("Expected %v, got %v", expected, output)
+	}
+
+	if _, err := tools.ReadConfigFile(writeTestConfigFile(t, "config.yml", "index:\n  output: ./out\n jobs: 2\n")); err == nil || !strings.Contains(err.Error(), "yaml: line") {
+		t.Errorf("Expected the error of the misplaced key, got %v", err)
+	}
+
+	if _, err := tools.ReadConfigFile(writeTestConfigFile(t, "config.yaml", "- input\n- srid\n")); err == nil {
+		t.Errorf("Expected error for a configuration which is not a mapping")
+	}
+
+	output, err = tools.ReadConfigFile(writeTestConfigFile(t, "config.yaml", "# no options\n"))
+	if err != nil || len(output) != 0 {
+		t.Errorf("Expected an empty configuration, got %v %v", output, err)
+	}
+}
+
+func TestApplyConfigYamlFile(t *testing.T) {
+	configPath := writeTestConfigFile(t, "config.yaml", "srid: 32633\ngrid_max_size: 8\nindex:\n  jobs: 4\n")
+	opts := &tiler.TilerOptions{TilerIndexOptions: &tiler.TilerIndexOptions{Jobs: 1}}
+
+	if _, err := tools.ApplyConfigFile(opts, configPath, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if opts.Srid != 32633 || opts.CellMaxSize != 8 || opts.TilerIndexOptions.Jobs != 4 {
+		t.Errorf("Expected srid 32633, grid_max_size 8 and 4 jobs, got %d, %f and %d", opts.Srid, opts.CellMaxSize, opts.TilerIndexOptions.Jobs)
+	}
+
+	configPath = writeTestConfigFile(t, "config.yaml", "index:\n  job: 4\n")
+	if _, err := tools.ApplyConfigFile(opts, configPath, nil); err == nil || !strings.Contains(err.Error(), "index.job") {
+		t.Errorf("Expected the error of the invalid key, got %v", err)
+	}
+}
+
+func TestApplyConfigFlagsOverrideFile(t *testing.T) {
+	flags := tools.ParseFlagsForCommandIndex([]string{"-x", "6", "-o", "./cli"})
+	opts := &tiler.TilerOptions{
+		CellMaxSize:       *flags.GridCellMaxSize,
+		RefineMode:        tiler.RefineModeAdd,
+		TilerIndexOptions: &tiler.TilerIndexOptions{Output: *flags.Output, Jobs: 1},
+	}
+	config := map[string]interface{}{
+		"grid_max_size": float64(8),
+		"refine_mode":   "replace",
+		"index":         map[string]interface{}{"output": "./file", "jobs": float64(4)},
+		"merge":         map[string]interface{}{"output": "./merged"},
+	}
+
+	applied, err := tools.ApplyConfig(opts, config, tools.GetFlagConfigKeys(flags.FlagCommand))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if opts.CellMaxSize != 6 || opts.TilerIndexOptions.Output != "./cli" {
+		t.Errorf("Expected the flags to override the file, got grid_max_size %f output %s", opts.CellMaxSize, opts.TilerIndexOptions.Output)
+	}
+	if opts.RefineMode != tiler.RefineModeReplace || opts.TilerIndexOptions.Jobs != 4 {
+		t.Errorf("Expected refine mode REPLACE and 4 jobs, got %s and %d", opts.RefineMode, opts.TilerIndexOptions.Jobs)
+	}
+	if opts.TilerMergeOptions != nil {
+		t.Errorf("Expected the merge section to be ignored")
+	}
+	if !applied["index.jobs"] || applied["grid_max_size"] {
+		t.Errorf("Unexpected applied keys %v", applied)
+	}
+}
+
+func TestApplyConfigNamesInvalidKeys(t *testing.T) {
+	configs := map[string]map[string]interface{}{
+		"index.filter.z_maxx":  {"index": map[string]interface{}{"filter": map[string]interface{}{"z_maxx": float64(3)}}},
+		"grid_max_size":        {"grid_max_size": "big"},
+		"bounding_volume":      {"bounding_volume": "cube"},
+		"index.filter.classes": {"index": map[string]interface{}{"filter": map[string]interface{}{"classes": []interface{}{float64(300)}}}},
+	}
+
+	for key, config := range configs {
+		opts := &tiler.TilerOptions{TilerIndexOptions: &tiler.TilerIndexOptions{}}
+		_, err := tools.ApplyConfig(opts, config, nil)
+		if err == nil || !strings.Contains(err.Error(), "["+key+"]") {
+			t.Errorf("Expected error naming key %s, got %v", key, err)
+		}
+	}
+}
@@ -0,0 +1,965 @@
+package unit_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/ecopia-map/cesium_tiler/internal/draco"
+)
+
+// dracoTestCloud holds the attributes of a point cloud used to test the encoder, every point has a position, an
+// rgb color and an intensity
+type dracoTestCloud struct {
+	numPoints int
+	positions []float32
+	colors    []uint8
+	intensity []uint16
+}
+
+func newDracoTestCloud(numPoints int, seed int64) *dracoTestCloud {
+	rnd := rand.New(rand.NewSource(seed))
+	cloud := &dracoTestCloud{
+		numPoints: numPoints,
+		positions: make([]float32, numPoints*3),
+		colors:    make([]uint8, numPoints*3),
+		intensity: make([]uint16, numPoints),
+	}
+	for i := 0; i < numPoints; i++ {
+		cloud.positions[i*3] = float32(-20 + 40*rnd.Float64())
+		cloud.positions[i*3+1] = float32(10 + 15*rnd.Float64())
+		cloud.positions[i*3+2] = float32(-3 + 6*rnd.Float64())
+		cloud.colors[i*3] = uint8(rnd.Intn(256))
+		cloud.colors[i*3+1] = uint8(rnd.Intn(32))
+		cloud.colors[i*3+2] = 200
+		cloud.intensity[i] = uint16(rnd.Intn(4096))
+	}
+	return cloud
+}
+
+func (c *dracoTestCloud) pointCloud(t *testing.T) *draco.PointCloud {
+	pc := draco.NewPointCloud(c.numPoints)
+	if _, err := pc.AddFloat32Attribute(draco.AttributePosition, 3, c.positions); err != nil {
+		t.Fatalf("Unexpected error occurred: %s", err.Error())
+	}
+	if _, err := pc.AddUint8Attribute(draco.AttributeColor, 3, true, c.colors); err != nil {
+		t.Fatalf("Unexpected error occurred: %s", err.Error())
+	}
+	if _, err := pc.AddUint16Attribute(draco.AttributeGeneric, 1, false, c.intensity); err != nil {
+		t.Fatalf("Unexpected error occurred: %s", err.Error())
+	}
+	return pc
+}
+
+// Returns the values of every point of the decoded point cloud, positions are quantized with the decoded
+// transform and they are checked to be within half quantization step from the original ones
+func (c *dracoTestCloud) checkDecoded(t *testing.T, decoded *dracoDecodedPointCloud, quantizationBits int) []string {
+	if decoded.numPoints != c.numPoints {
+		t.Fatalf("Expected %d points, got %d", c.numPoints, decoded.numPoints)
+	}
+	if len(decoded.attributes) != 3 {
+		t.Fatalf("Expected 3 attributes, got %d", len(decoded.attributes))
+	}
+	position, color, intensity := decoded.attributes[0], decoded.attributes[1], decoded.attributes[2]
+	if position.attributeType != uint8(draco.AttributePosition) || position.numComponents != 3 || position.transform == nil {
+		t.Fatalf("Unexpected position attribute %+v", *position)
+	}
+	if color.attributeType != uint8(draco.AttributeColor) || color.numComponents != 3 || !color.normalized {
+		t.Fatalf("Unexpected color attribute %+v", *color)
+	}
+	if intensity.attributeType != uint8(draco.AttributeGeneric) || intensity.numComponents != 1 {
+		t.Fatalf("Unexpected intensity attribute %+v", *intensity)
+	}
+	if position.transform.quantizationBits != quantizationBits {
+		t.Fatalf("Expected %d quantization bits, got %d", quantizationBits, position.transform.quantizationBits)
+	}
+
+	// the quantization grid starts at the minimum of each component and it spans the largest range
+	var valueRange float32
+	for comp := 0; comp < 3; comp++ {
+		min, max := float32(math.MaxFloat32), float32(-math.MaxFloat32)
+		for i := 0; i < c.numPoints; i++ {
+			v := c.positions[i*3+comp]
+			if v < min {
+				min = v
+			}
+			if v > max {
+				max = v
+			}
+		}
+		if position.transform.minValues[comp] != min {
+			t.Errorf("Expected minimum %f of component %d, got %f", min, comp, position.transform.minValues[comp])
+		}
+		if max-min > valueRange {
+			valueRange = max - min
+		}
+	}
+	if position.transform.valueRange != valueRange {
+		t.Errorf("Expected range %f, got %f", valueRange, position.transform.valueRange)
+	}
+
+	maxQuantizedValue := uint32(1)<<uint(quantizationBits) - 1
+	tolerance := float64(valueRange)/float64(maxQuantizedValue)/2 + 1E-5
+	points := make([]string, c.numPoints)
+	for i := 0; i < c.numPoints; i++ {
+		for comp := 0; comp < 3; comp++ {
+			q := position.values[i*3+comp]
+			if q > maxQuantizedValue {
+				t.Fatalf("Point %d: quantized value %d exceeds %d", i, q, maxQuantizedValue)
+			}
+		}
+		points[i] = fmt.Sprint(position.values[i*3:i*3+3], color.values[i*3:i*3+3], intensity.values[i])
+	}
+
+	// every original point must match a decoded one, positions are compared after the dequantization
+	expected := make([]string, c.numPoints)
+	for i := 0; i < c.numPoints; i++ {
+		var q [3]uint32
+		for comp := 0; comp < 3; comp++ {
+			v := c.positions[i*3+comp]
+			q[comp] = quantize(v, position.transform.minValues[comp], valueRange, maxQuantizedValue)
+			if dequantized := position.transform.dequantize(q[comp], comp); math.Abs(float64(dequantized-v)) > tolerance {
+				t.Fatalf("Point %d: value %f dequantized to %f, tolerance %f", i, v, dequantized, tolerance)
+			}
+		}
+		expected[i] = fmt.Sprint(q[:], []uint32{uint32(c.colors[i*3]), uint32(c.colors[i*3+1]), uint32(c.colors[i*3+2])}, uint32(c.intensity[i]))
+	}
+	return append(expected, points...)
+}
+
+func TestDracoSequentialEncodingRoundTrip(t *testing.T) {
+	cloud := newDracoTestCloud(500, 1)
+	for _, quantizationBits := range []int{1, 8, draco.DefaultQuantizationBits, 14, 20, draco.MaxQuantizationBits} {
+		encoded, err := draco.EncodePointCloud(cloud.pointCloud(t), draco.EncoderOptions{Method: draco.SequentialEncoding, QuantizationBits: quantizationBits})
+		if err != nil {
+			t.Fatalf("Unexpected error occurred: %s", err.Error())
+		}
+		decoded, err := decodeDracoPointCloud(encoded)
+		if err != nil {
+			t.Fatalf("Unexpected error decoding %d bits quantization: %s", quantizationBits, err.Error())
+		}
+		if decoded.method != uint8(draco.SequentialEncoding) {
+			t.Fatalf("Expected sequential encoding method, got %d", decoded.method)
+		}
+
+		// the sequential method keeps the point order
+		points := cloud.checkDecoded(t, decoded, quantizationBits)
+		for i := 0; i < cloud.numPoints; i++ {
+			if points[i] != points[cloud.numPoints+i] {
+				t.Fatalf("Quantization bits %d, point %d: expected %s, got %s", quantizationBits, i, points[i], points[cloud.numPoints+i])
+			}
+		}
+	}
+}
+
+func TestDracoKdTreeEncodingRoundTrip(t *testing.T) {
+	cloud := newDracoTestCloud(500, 2)
+	for _, quantizationBits := range []int{1, 8, draco.DefaultQuantizationBits, 14, 20, draco.MaxQuantizationBits} {
+		encoded, err := draco.EncodePointCloud(cloud.pointCloud(t), draco.EncoderOptions{Method: draco.KdTreeEncoding, QuantizationBits: quantizationBits})
+		if err != nil {
+			t.Fatalf("Unexpected error occurred: %s", err.Error())
+		}
+		decoded, err := decodeDracoPointCloud(encoded)
+		if err != nil {
+			t.Fatalf("Unexpected error decoding %d bits quantization: %s", quantizationBits, err.Error())
+		}
+		if decoded.method != uint8(draco.KdTreeEncoding) {
+			t.Fatalf("Expected kd-tree encoding method, got %d", decoded.method)
+		}
+
+		// the kd-tree method reorders the points
+		points := cloud.checkDecoded(t, decoded, quantizationBits)
+		expected, actual := points[:cloud.numPoints], points[cloud.numPoints:]
+		sort.Strings(expected)
+		sort.Strings(actual)
+		for i := range expected {
+			if expected[i] != actual[i] {
+				t.Fatalf("Quantization bits %d: expected point %s, got %s", quantizationBits, expected[i], actual[i])
+			}
+		}
+	}
+}
+
+func TestDracoKdTreeEncodingDuplicatePoints(t *testing.T) {
+	// duplicated points are not separated by any split, as well as points differing only in the lowest bits
+	values := []uint16{7, 7, 7, 7, 7, 7, 6, 6, 6, 65535, 65535, 65535, 65534, 0, 0}
+	pc := draco.NewPointCloud(len(values))
+	if _, err := pc.AddUint16Attribute(draco.AttributeGeneric, 1, false, values); err != nil {
+		t.Fatalf("Unexpected error occurred: %s", err.Error())
+	}
+	encoded, err := draco.EncodePointCloud(pc, draco.EncoderOptions{Method: draco.KdTreeEncoding, QuantizationBits: draco.DefaultQuantizationBits})
+	if err != nil {
+		t.Fatalf("Unexpected error occurred: %s", err.Error())
+	}
+	decoded, err := decodeDracoPointCloud(encoded)
+	if err != nil {
+		t.Fatalf("Unexpected error occurred: %s", err.Error())
+	}
+
+	checkSameMultiset(t, toUint32(values), decoded.attributes[0].values)
+}
+
+func TestDracoRAnsSymbolCoding(t *testing.T) {
+	rnd := rand.New(rand.NewSource(3))
+	testData := []struct {
+		name   string
+		values func(i int) uint16
+	}{
+		// a single symbol, its probability is the full precision of the coder
+		{"single symbol", func(i int) uint16 { return 42 }},
+		// symbols far apart, the zero probabilities between them are stored as runs
+		{"sparse symbols", func(i int) uint16 { return uint16(i%3) * 5000 }},
+		// a dominant symbol with many rare ones, the rare symbols get the minimum probability
+		{"skewed distribution", func(i int) uint16 {
+			if i%10 != 0 {
+				return 0
+			}
+			return uint16(rnd.Intn(2000))
+		}},
+		// hundreds of unique symbols, which increase the precision of the coder
+		{"many unique symbols", func(i int) uint16 { return uint16(rnd.Intn(1000)) }},
+		{"geometric distribution", func(i int) uint16 { return uint16(math.Min(rnd.ExpFloat64()*20, 65535)) }},
+	}
+
+	for _, data := range testData {
+		values := make([]uint16, 4000)
+		for i := range values {
+			values[i] = data.values(i)
+		}
+		pc := draco.NewPointCloud(len(values))
+		if _, err := pc.AddUint16Attribute(draco.AttributeGeneric, 1, false, values); err != nil {
+			t.Fatalf("Unexpected error occurred: %s", err.Error())
+		}
+		encoded, err := draco.EncodePointCloud(pc, draco.EncoderOptions{Method: draco.SequentialEncoding, QuantizationBits: draco.DefaultQuantizationBits})
+		if err != nil {
+			t.Fatalf("Unexpected error occurred: %s", err.Error())
+		}
+		decoded, err := decodeDracoPointCloud(encoded)
+		if err != nil {
+			t.Fatalf("%s: unexpected error occurred: %s", data.name, err.Error())
+		}
+
+		attribute := decoded.attributes[0]
+		if !attribute.entropyCoded {
+			t.Errorf("%s: expected values coded with rANS", data.name)
+		}
+		for i, v := range values {
+			if attribute.values[i] != uint32(v) {
+				t.Fatalf("%s: expected value %d at %d, got %d", data.name, v, i, attribute.values[i])
+			}
+		}
+	}
+}
+
+func TestDracoSmallInputIsNotEntropyCoded(t *testing.T) {
+	// the probability table of a few distinct values is larger than the values themselves
+	values := []uint16{1, 300, 65535}
+	pc := draco.NewPointCloud(len(values))
+	if _, err := pc.AddUint16Attribute(draco.AttributeGeneric, 1, false, values); err != nil {
+		t.Fatalf("Unexpected error occurred: %s", err.Error())
+	}
+	encoded, err := draco.EncodePointCloud(pc, draco.EncoderOptions{Method: draco.SequentialEncoding, QuantizationBits: draco.DefaultQuantizationBits})
+	if err != nil {
+		t.Fatalf("Unexpected error occurred: %s", err.Error())
+	}
+	decoded, err := decodeDracoPointCloud(encoded)
+	if err != nil {
+		t.Fatalf("Unexpected error occurred: %s", err.Error())
+	}
+
+	attribute := decoded.attributes[0]
+	if attribute.entropyCoded {
+		t.Errorf("Expected values stored without entropy coding")
+	}
+	for i, v := range values {
+		if attribute.values[i] != uint32(v) {
+			t.Errorf("Expected value %d at %d, got %d", v, i, attribute.values[i])
+		}
+	}
+}
+
+func TestDracoDirectBitEncoding(t *testing.T) {
+	// with two points every component is stored in the remaining bits stream, with lengths crossing the 32 bits
+	// words the bits are stored in
+	positions := []float32{0, 0.3, 1, 1, 0, 0.5}
+	intensity := []uint16{0xA5A5, 0x5A5A}
+	for _, quantizationBits := range []int{5, 13, 27, draco.MaxQuantizationBits} {
+		pc := draco.NewPointCloud(2)
+		if _, err := pc.AddFloat32Attribute(draco.AttributePosition, 3, positions); err != nil {
+			t.Fatalf("Unexpected error occurred: %s", err.Error())
+		}
+		if _, err := pc.AddUint16Attribute(draco.AttributeGeneric, 1, false, intensity); err != nil {
+			t.Fatalf("Unexpected error occurred: %s", err.Error())
+		}
+		encoded, err := draco.EncodePointCloud(pc, draco.EncoderOptions{Method: draco.KdTreeEncoding, QuantizationBits: quantizationBits})
+		if err != nil {
+			t.Fatalf("Unexpected error occurred: %s", err.Error())
+		}
+		decoded, err := decodeDracoPointCloud(encoded)
+		if err != nil {
+			t.Fatalf("Unexpected error decoding %d bits quantization: %s", quantizationBits, err.Error())
+		}
+
+		maxQuantizedValue := uint32(1)<<uint(quantizationBits) - 1
+		expected := []string{
+			fmt.Sprint([]uint32{0, quantize(0.3, 0, 1, maxQuantizedValue), quantize(1, 0.5, 1, maxQuantizedValue)}, 0xA5A5),
+			fmt.Sprint([]uint32{maxQuantizedValue, 0, 0}, 0x5A5A),
+		}
+		actual := make([]string, 2)
+		for i := range actual {
+			actual[i] = fmt.Sprint(decoded.attributes[0].values[i*3:i*3+3], decoded.attributes[1].values[i])
+		}
+		sort.Strings(expected)
+		sort.Strings(actual)
+		for i := range expected {
+			if expected[i] != actual[i] {
+				t.Errorf("Quantization bits %d: expected point %s, got %s", quantizationBits, expected[i], actual[i])
+			}
+		}
+	}
+}
+
+func TestDracoEncoderInvalidInput(t *testing.T) {
+	pc := draco.NewPointCloud(2)
+	if _, err := pc.AddFloat32Attribute(draco.AttributePosition, 3, []float32{1, 2, 3}); err == nil {
+		t.Errorf("Error was expected adding an attribute with missing values but none was returned")
+	}
+	if _, err := draco.EncodePointCloud(pc, draco.EncoderOptions{Method: draco.KdTreeEncoding, QuantizationBits: 11}); err == nil {
+		t.Errorf("Error was expected encoding a point cloud without attributes but none was returned")
+	}
+	if _, err := pc.AddFloat32Attribute(draco.AttributePosition, 3, []float32{1, 2, 3, 4, 5, 6}); err != nil {
+		t.Fatalf("Unexpected error occurred: %s", err.Error())
+	}
+	for _, quantizationBits := range []int{0, draco.MaxQuantizationBits + 1} {
+		if _, err := draco.EncodePointCloud(pc, draco.EncoderOptions{Method: draco.KdTreeEncoding, QuantizationBits: quantizationBits}); err == nil {
+			t.Errorf("Error was expected encoding with %d quantization bits but none was returned", quantizationBits)
+		}
+	}
+}
+
+// Quantizes the value as the encoder does, clamping the rounding errors to the largest quantized value
+func quantize(value float32, min float32, valueRange float32, maxQuantizedValue uint32) uint32 {
+	q := math.Floor(float64((value-min)*(float32(maxQuantizedValue)/valueRange)) + 0.5)
+	if q > float64(maxQuantizedValue) {
+		return maxQuantizedValue
+	}
+	return uint32(q)
+}
+
+func toUint32(values []uint16) []uint32 {
+	converted := make([]uint32, len(values))
+	for i, v := range values {
+		converted[i] = uint32(v)
+	}
+	return converted
+}
+
+func checkSameMultiset(t *testing.T, expected []uint32, actual []uint32) {
+	expected = append([]uint32{}, expected...)
+	actual = append([]uint32{}, actual...)
+	sort.Slice(expected, func(i, j int) bool { return expected[i] < expected[j] })
+	sort.Slice(actual, func(i, j int) bool { return actual[i] < actual[j] })
+	if fmt.Sprint(expected) != fmt.Sprint(actual) {
+		t.Errorf("Expected values %v, got %v", expected, actual)
+	}
+}
+
+// The following is a port of the parts of the reference Draco decoder needed to decode the point clouds written by
+// the encoder: the sequential attributes decoder without prediction, the raw rANS symbol decoder and the level 0
+// dynamic integer points kd-tree decoder. Values of float attributes are kept quantized
+
+type dracoQuantizationTransform struct {
+	minValues        []float32
+	valueRange       float32
+	quantizationBits int
+}
+
+func (t *dracoQuantizationTransform) dequantize(value uint32, component int) float32 {
+	maxQuantizedValue := uint32(1)<<uint(t.quantizationBits) - 1
+	delta := t.valueRange / float32(maxQuantizedValue)
+	return float32(value)*delta + t.minValues[component]
+}
+
+type dracoDecodedAttribute struct {
+	attributeType uint8
+	dataType      uint8
+	numComponents int
+	normalized    bool
+	uniqueID      uint64
+	values        []uint32
+	transform     *dracoQuantizationTransform
+	entropyCoded  bool
+}
+
+type dracoDecodedPointCloud struct {
+	method     uint8
+	numPoints  int
+	attributes []*dracoDecodedAttribute
+}
+
+type dracoDecoderBuffer struct {
+	data []byte
+	pos  int
+}
+
+func (b *dracoDecoderBuffer) bytes(n int) ([]byte, error) {
+	if n < 0 || b.pos+n > len(b.data) {
+		return nil, errors.New("unexpected end of buffer")
+	}
+	data := b.data[b.pos : b.pos+n]
+	b.pos += n
+	return data, nil
+}
+
+func (b *dracoDecoderBuffer) uint8() (uint8, error) {
+	data, err := b.bytes(1)
+	if err != nil {
+		return 0, err
+	}
+	return data[0], nil
+}
+
+func (b *dracoDecoderBuffer) uint32() (uint32, error) {
+	data, err := b.bytes(4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(data), nil
+}
+
+func (b *dracoDecoderBuffer) varint() (uint64, error) {
+	var v uint64
+	for shift := uint(0); shift < 64; shift += 7 {
+		c, err := b.uint8()
+		if err != nil {
+			return 0, err
+		}
+		v |= uint64(c&0x7F) << shift
+		if c&0x80 == 0 {
+			return v, nil
+		}
+	}
+	return 0, errors.New("invalid varint")
+}
+
+func decodeDracoPointCloud(data []byte) (*dracoDecodedPointCloud, error) {
+	buffer := &dracoDecoderBuffer{data: data}
+	header, err := buffer.bytes(11)
+	if err != nil {
+		return nil, err
+	}
+	if string(header[0:5]) != "DRACO" || header[5] != 2 || header[6] != 3 || header[7] != 0 {
+		return nil, fmt.Errorf("unexpected header %v", header)
+	}
+	if flags := binary.LittleEndian.Uint16(header[9:11]); flags != 0 {
+		return nil, fmt.Errorf("unexpected flags %d", flags)
+	}
+	pc := &dracoDecodedPointCloud{method: header[8]}
+
+	numPoints, err := buffer.uint32()
+	if err != nil {
+		return nil, err
+	}
+	pc.numPoints = int(int32(numPoints))
+
+	numDecoders, err := buffer.uint8()
+	if err != nil {
+		return nil, err
+	}
+	if numDecoders != 1 {
+		return nil, fmt.Errorf("expected a single attributes decoder, got %d", numDecoders)
+	}
+
+	numAttributes, err := buffer.varint()
+	if err != nil {
+		return nil, err
+	}
+	for i := uint64(0); i < numAttributes; i++ {
+		descriptor, err := buffer.bytes(4)
+		if err != nil {
+			return nil, err
+		}
+		att := &dracoDecodedAttribute{
+			attributeType: descriptor[0],
+			dataType:      descriptor[1],
+			numComponents: int(descriptor[2]),
+			normalized:    descriptor[3] != 0,
+		}
+		if att.uniqueID, err = buffer.varint(); err != nil {
+			return nil, err
+		}
+		if att.uniqueID != i {
+			return nil, fmt.Errorf("attribute %d has unique id %d", i, att.uniqueID)
+		}
+		pc.attributes = append(pc.attributes, att)
+	}
+
+	switch pc.method {
+	case 0:
+		err = decodeDracoSequentialAttributes(buffer, pc)
+	case 1:
+		err = decodeDracoKdTreeAttributes(buffer, pc)
+	default:
+		err = fmt.Errorf("unknown encoding method %d", pc.method)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// parameters of the quantization transforms, in the order of the attributes
+	for _, att := range pc.attributes {
+		if att.dataType != 9 {
+			continue
+		}
+		att.transform = &dracoQuantizationTransform{minValues: make([]float32, att.numComponents)}
+		for c := 0; c < att.numComponents; c++ {
+			v, err := buffer.uint32()
+			if err != nil {
+				return nil, err
+			}
+			att.transform.minValues[c] = math.Float32frombits(v)
+		}
+		v, err := buffer.uint32()
+		if err != nil {
+			return nil, err
+		}
+		att.transform.valueRange = math.Float32frombits(v)
+		bits, err := buffer.uint8()
+		if err != nil {
+			return nil, err
+		}
+		if bits > 31 {
+			return nil, fmt.Errorf("invalid quantization bits %d", bits)
+		}
+		att.transform.quantizationBits = int(bits)
+	}
+
+	if buffer.pos != len(buffer.data) {
+		return nil, fmt.Errorf("%d bytes left after decoding", len(buffer.data)-buffer.pos)
+	}
+	return pc, nil
+}
+
+func decodeDracoSequentialAttributes(buffer *dracoDecoderBuffer, pc *dracoDecodedPointCloud) error {
+	for _, att := range pc.attributes {
+		decoderType, err := buffer.uint8()
+		if err != nil {
+			return err
+		}
+		// quantization decoder for float attributes, integer decoder for the others
+		if (att.dataType == 9 && decoderType != 2) || (att.dataType != 9 && decoderType != 1) {
+			return fmt.Errorf("unexpected decoder type %d for data type %d", decoderType, att.dataType)
+		}
+	}
+
+	for _, att := range pc.attributes {
+		predictionMethod, err := buffer.uint8()
+		if err != nil {
+			return err
+		}
+		if int8(predictionMethod) != -2 {
+			return fmt.Errorf("unsupported prediction method %d", int8(predictionMethod))
+		}
+
+		numValues := pc.numPoints * att.numComponents
+		compressed, err := buffer.uint8()
+		if err != nil {
+			return err
+		}
+		var symbols []uint32
+		if compressed > 0 {
+			att.entropyCoded = true
+			if symbols, err = decodeDracoSymbols(buffer, numValues); err != nil {
+				return err
+			}
+		} else {
+			numBytes, err := buffer.uint8()
+			if err != nil {
+				return err
+			}
+			if numBytes == 0 || numBytes > 4 {
+				return fmt.Errorf("invalid number of bytes %d", numBytes)
+			}
+			symbols = make([]uint32, numValues)
+			for i := range symbols {
+				data, err := buffer.bytes(int(numBytes))
+				if err != nil {
+					return err
+				}
+				for b := range data {
+					symbols[i] |= uint32(data[b]) << uint(8*b)
+				}
+			}
+		}
+
+		// symbols of non negative values
+		att.values = make([]uint32, numValues)
+		for i, s := range symbols {
+			if s&1 != 0 {
+				return fmt.Errorf("negative value at %d", i)
+			}
+			att.values[i] = s >> 1
+		}
+	}
+	return nil
+}
+
+// Decodes the symbols with the raw scheme of the rANS coder
+func decodeDracoSymbols(buffer *dracoDecoderBuffer, numValues int) ([]uint32, error) {
+	scheme, err := buffer.uint8()
+	if err != nil {
+		return nil, err
+	}
+	if scheme != 1 {
+		return nil, fmt.Errorf("unsupported symbol coding scheme %d", scheme)
+	}
+	maxBitLength, err := buffer.uint8()
+	if err != nil {
+		return nil, err
+	}
+	if maxBitLength < 1 || maxBitLength > 18 {
+		return nil, fmt.Errorf("invalid unique symbols bit length %d", maxBitLength)
+	}
+	precisionBits := (3 * int(maxBitLength)) / 2
+	if precisionBits < 12 {
+		precisionBits = 12
+	} else if precisionBits > 20 {
+		precisionBits = 20
+	}
+	precision := uint32(1) << uint(precisionBits)
+
+	// probability table
+	numSymbols, err := buffer.varint()
+	if err != nil {
+		return nil, err
+	}
+	if numSymbols == 0 {
+		return nil, errors.New("empty probability table")
+	}
+	probabilities := make([]uint32, numSymbols)
+	for i := uint64(0); i < numSymbols; i++ {
+		probData, err := buffer.uint8()
+		if err != nil {
+			return nil, err
+		}
+		token := probData & 3
+		if token == 3 {
+			offset := uint64(probData >> 2)
+			if i+offset >= numSymbols {
+				return nil, errors.New("zero probabilities run exceeds the table")
+			}
+			i += offset
+			continue
+		}
+		prob := uint32(probData >> 2)
+		for b := 0; b < int(token); b++ {
+			extra, err := buffer.uint8()
+			if err != nil {
+				return nil, err
+			}
+			prob |= uint32(extra) << uint(8*(b+1)-2)
+		}
+		probabilities[i] = prob
+	}
+	lut := make([]uint32, 0, precision)
+	cumProbabilities := make([]uint32, numSymbols)
+	var cumProb uint32
+	for i, prob := range probabilities {
+		cumProbabilities[i] = cumProb
+		cumProb += prob
+		if cumProb > precision {
+			return nil, errors.New("probabilities exceed the precision")
+		}
+		for len(lut) < int(cumProb) {
+			lut = append(lut, uint32(i))
+		}
+	}
+	if cumProb != precision {
+		return nil, fmt.Errorf("probabilities sum to %d, expected %d", cumProb, precision)
+	}
+
+	bytesEncoded, err := buffer.varint()
+	if err != nil {
+		return nil, err
+	}
+	data, err := buffer.bytes(int(bytesEncoded))
+	if err != nil {
+		return nil, err
+	}
+
+	// the last bytes store the final state of the encoder
+	lRansBase := precision * 4
+	if len(data) < 1 {
+		return nil, errors.New("empty rans data")
+	}
+	offset := len(data)
+	var state uint32
+	switch data[offset-1] >> 6 {
+	case 0:
+		offset--
+		state = uint32(data[offset]) & 0x3F
+	case 1:
+		if offset < 2 {
+			return nil, errors.New("truncated rans state")
+		}
+		offset -= 2
+		state = uint32(binary.LittleEndian.Uint16(data[offset:])) & 0x3FFF
+	case 2:
+		if offset < 3 {
+			return nil, errors.New("truncated rans state")
+		}
+		offset -= 3
+		state = (uint32(data[offset]) | uint32(data[offset+1])<<8 | uint32(data[offset+2])<<16) & 0x3FFFFF
+	case 3:
+		if offset < 4 {
+			return nil, errors.New("truncated rans state")
+		}
+		offset -= 4
+		state = binary.LittleEndian.Uint32(data[offset:]) & 0x3FFFFFFF
+	}
+	state += lRansBase
+	if uint64(state) >= uint64(lRansBase)*256 {
+		return nil, errors.New("invalid rans state")
+	}
+
+	symbols := make([]uint32, numValues)
+	for i := range symbols {
+		for state < lRansBase && offset > 0 {
+			offset--
+			state = state*256 + uint32(data[offset])
+		}
+		quo := state / precision
+		rem := state % precision
+		symbol := lut[rem]
+		state = quo*probabilities[symbol] + rem - cumProbabilities[symbol]
+		symbols[i] = symbol
+	}
+	// the first byte written by the encoder is only read back when renormalizing after the last symbol
+	for state < lRansBase && offset > 0 {
+		offset--
+		state = state*256 + uint32(data[offset])
+	}
+	if offset != 0 || state != lRansBase {
+		return nil, fmt.Errorf("rans decoding ended at offset %d with state %d", offset, state)
+	}
+	return symbols, nil
+}
+
+// Bits packed in 32 bits words starting from the most significant bit, mirrors draco::DirectBitDecoder
+type dracoDirectBitDecoder struct {
+	words    []uint32
+	pos      int
+	usedBits uint32
+}
+
+func newDracoDirectBitDecoder(buffer *dracoDecoderBuffer) (*dracoDirectBitDecoder, error) {
+	size, err := buffer.uint32()
+	if err != nil {
+		return nil, err
+	}
+	if size == 0 || size&3 != 0 {
+		return nil, fmt.Errorf("invalid direct bits size %d", size)
+	}
+	data, err := buffer.bytes(int(size))
+	if err != nil {
+		return nil, err
+	}
+	d := &dracoDirectBitDecoder{words: make([]uint32, size/4)}
+	for i := range d.words {
+		d.words[i] = binary.LittleEndian.Uint32(data[i*4:])
+	}
+	return d, nil
+}
+
+func (d *dracoDirectBitDecoder) decodeNextBit() (bool, error) {
+	if d.pos == len(d.words) {
+		return false, errors.New("no more bits")
+	}
+	bit := d.words[d.pos]&(1<<(31-d.usedBits)) != 0
+	d.usedBits++
+	if d.usedBits == 32 {
+		d.pos++
+		d.usedBits = 0
+	}
+	return bit, nil
+}
+
+func (d *dracoDirectBitDecoder) decodeLeastSignificantBits32(nbits uint32) (uint32, error) {
+	remaining := 32 - d.usedBits
+	if nbits <= remaining {
+		if d.pos == len(d.words) {
+			return 0, errors.New("no more bits")
+		}
+		value := (d.words[d.pos] << d.usedBits) >> (32 - nbits)
+		d.usedBits += nbits
+		if d.usedBits == 32 {
+			d.pos++
+			d.usedBits = 0
+		}
+		return value, nil
+	}
+	if d.pos+1 >= len(d.words) {
+		return 0, errors.New("no more bits")
+	}
+	valueL := d.words[d.pos] << d.usedBits
+	d.usedBits = nbits - remaining
+	d.pos++
+	valueR := d.words[d.pos] >> (32 - d.usedBits)
+	return (valueL >> (32 - d.usedBits - remaining)) | valueR, nil
+}
+
+// Decodes the points of a level 0 kd-tree, mirrors draco::DynamicIntegerPointsKdTreeDecoder
+func decodeDracoKdTreeAttributes(buffer *dracoDecoderBuffer, pc *dracoDecodedPointCloud) error {
+	compressionLevel, err := buffer.uint8()
+	if err != nil {
+		return err
+	}
+	if compressionLevel != 0 {
+		return fmt.Errorf("unsupported compression level %d", compressionLevel)
+	}
+
+	dimension := uint32(0)
+	for _, att := range pc.attributes {
+		dimension += uint32(att.numComponents)
+		att.values = make([]uint32, 0, pc.numPoints*att.numComponents)
+	}
+
+	bitLength, err := buffer.uint32()
+	if err != nil {
+		return err
+	}
+	if bitLength > 32 {
+		return fmt.Errorf("invalid bit length %d", bitLength)
+	}
+	numPoints, err := buffer.uint32()
+	if err != nil {
+		return err
+	}
+	if int(numPoints) != pc.numPoints {
+		return fmt.Errorf("kd-tree has %d points, expected %d", numPoints, pc.numPoints)
+	}
+
+	numbersDecoder, err := newDracoDirectBitDecoder(buffer)
+	if err != nil {
+		return err
+	}
+	remainingBitsDecoder, err := newDracoDirectBitDecoder(buffer)
+	if err != nil {
+		return err
+	}
+	if _, err := newDracoDirectBitDecoder(buffer); err != nil {
+		return err
+	}
+	halfDecoder, err := newDracoDirectBitDecoder(buffer)
+	if err != nil {
+		return err
+	}
+
+	output := func(p []uint32) {
+		offset := 0
+		for _, att := range pc.attributes {
+			att.values = append(att.values, p[offset:offset+att.numComponents]...)
+			offset += att.numComponents
+		}
+	}
+	nextAxis := func(axis uint32) uint32 {
+		return (axis + 1) % dimension
+	}
+
+	type status struct {
+		numRemainingPoints uint32
+		lastAxis           uint32
+		stackPos           int
+	}
+	stackSize := int(bitLength*dimension) + 2
+	baseStack := make([][]uint32, stackSize)
+	levelsStack := make([][]uint32, stackSize)
+	for i := range baseStack {
+		baseStack[i] = make([]uint32, dimension)
+		levelsStack[i] = make([]uint32, dimension)
+	}
+
+	numDecodedPoints := uint32(0)
+	stack := []status{{numRemainingPoints: numPoints}}
+	for len(stack) > 0 {
+		s := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if s.stackPos+1 >= stackSize {
+			return errors.New("kd-tree is too deep")
+		}
+
+		oldBase := baseStack[s.stackPos]
+		levels := levelsStack[s.stackPos]
+		axis := nextAxis(s.lastAxis)
+		level := levels[axis]
+
+		if bitLength-level == 0 {
+			for i := uint32(0); i < s.numRemainingPoints; i++ {
+				output(oldBase)
+				numDecodedPoints++
+			}
+			continue
+		}
+
+		if s.numRemainingPoints <= 2 {
+			for i := uint32(0); i < s.numRemainingPoints; i++ {
+				p := make([]uint32, dimension)
+				currentAxis := axis
+				for j := uint32(0); j < dimension; j++ {
+					if numRemainingBits := bitLength - levels[currentAxis]; numRemainingBits > 0 {
+						if p[currentAxis], err = remainingBitsDecoder.decodeLeastSignificantBits32(numRemainingBits); err != nil {
+							return err
+						}
+					}
+					p[currentAxis] |= oldBase[currentAxis]
+					currentAxis = nextAxis(currentAxis)
+				}
+				output(p)
+				numDecodedPoints++
+			}
+			continue
+		}
+
+		numRemainingBits := bitLength - level
+		copy(baseStack[s.stackPos+1], oldBase)
+		baseStack[s.stackPos+1][axis] += 1 << (numRemainingBits - 1)
+
+		incomingBits := uint32(0)
+		for n := s.numRemainingPoints; n > 1; n >>= 1 {
+			incomingBits++
+		}
+		number, err := numbersDecoder.decodeLeastSignificantBits32(incomingBits)
+		if err != nil {
+			return err
+		}
+		firstHalf := s.numRemainingPoints/2 - number
+		secondHalf := s.numRemainingPoints - firstHalf
+		if firstHalf != secondHalf {
+			left, err := halfDecoder.decodeNextBit()
+			if err != nil {
+				return err
+			}
+			if !left {
+				firstHalf, secondHalf = secondHalf, firstHalf
+			}
+		}
+
+		levels[axis]++
+		copy(levelsStack[s.stackPos+1], levels)
+		if firstHalf > 0 {
+			stack = append(stack, status{numRemainingPoints: firstHalf, lastAxis: axis, stackPos: s.stackPos})
+		}
+		if secondHalf > 0 {
+			stack = append(stack, status{numRemainingPoints: secondHalf, lastAxis: axis, stackPos: s.stackPos + 1})
+		}
+	}
+
+	if numDecodedPoints != numPoints {
+		return fmt.Errorf("decoded %d points, expected %d", numDecodedPoints, numPoints)
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package unit_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ecopia-map/cesium_tiler/internal/draco"
+)
+
+// Folder of the Draco files written by the encoder for the cloud of newDracoGoldenCloud. Changes to the bitstream
+// must be checked with the reference draco_decoder, see TestDracoReferenceDecoderReadsGoldenFiles, before
+// updating them
+const dracoTestDataFolder = "testdata/draco"
+
+var dracoGoldenFiles = []struct {
+	fileName string
+	method   draco.EncodingMethod
+}{
+	{"points_sequential.drc", draco.SequentialEncoding},
+	{"points_kd_tree.drc", draco.KdTreeEncoding},
+}
+
+func newDracoGoldenCloud() *dracoTestCloud {
+	return newDracoTestCloud(64, 4)
+}
+
+func TestDracoEncodingMatchesGoldenFiles(t *testing.T) {
+	cloud := newDracoGoldenCloud()
+	for _, golden := range dracoGoldenFiles {
+		expected, err := ioutil.ReadFile(filepath.Join(dracoTestDataFolder, golden.fileName))
+		if err != nil {
+			t.Fatalf("Unable to read %s: %s", golden.fileName, err)
+		}
+		encoded, err := draco.EncodePointCloud(cloud.pointCloud(t), draco.EncoderOptions{Method: golden.method, QuantizationBits: draco.DefaultQuantizationBits})
+		if err != nil {
+			t.Fatalf("Unexpected error occurred: %s", err.Error())
+		}
+		if !bytes.Equal(encoded, expected) {
+			t.Errorf("Encoded bitstream differs from %s, check it with the reference decoder before updating the file", golden.fileName)
+		}
+	}
+}
+
+// Decodes the golden files with the reference draco_decoder, set by DRACO_DECODER_PATH or found in the PATH, and
+// checks that it returns the positions and colors decoded by the port of the decoder of draco_encoder_test.go
+func TestDracoReferenceDecoderReadsGoldenFiles(t *testing.T) {
+	decoderPath := os.Getenv("DRACO_DECODER_PATH")
+	if decoderPath == "" {
+		var err error
+		if decoderPath, err = exec.LookPath("draco_decoder"); err != nil {
+			t.Skip("reference draco_decoder not found, set DRACO_DECODER_PATH to run the test")
+		}
+	}
+
+	for _, golden := range dracoGoldenFiles {
+		inputPath := filepath.Join(dracoTestDataFolder, golden.fileName)
+		outputPath := filepath.Join(t.TempDir(), "points.ply")
+		if output, err := exec.Command(decoderPath, "-i", inputPath, "-o", outputPath).CombinedOutput(); err != nil {
+			t.Fatalf("Reference decoder failed on %s: %s\n%s", golden.fileName, err, output)
+		}
+		reference, err := readPlyVertices(outputPath)
+		if err != nil {
+			t.Fatalf("Unable to read the points decoded from %s: %s", golden.fileName, err)
+		}
+
+		data, err := ioutil.ReadFile(inputPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := decodeDracoPointCloud(data)
+		if err != nil {
+			t.Fatalf("Unexpected error decoding %s: %s", golden.fileName, err)
+		}
+		position, color := decoded.attributes[0], decoded.attributes[1]
+
+		if len(reference["x"]) != decoded.numPoints {
+			t.Fatalf("%s: expected %d points, the reference decoder returned %d", golden.fileName, decoded.numPoints, len(reference["x"]))
+		}
+		tolerance := float64(position.transform.valueRange) * 1e-6
+		for i := 0; i < decoded.numPoints; i++ {
+			for comp, name := range []string{"x", "y", "z"} {
+				expected := position.transform.dequantize(position.values[i*3+comp], comp)
+				if math.Abs(float64(expected)-reference[name][i]) > tolerance {
+					t.Fatalf("%s, point %d: expected %s %f, the reference decoder returned %f", golden.fileName, i, name, expected, reference[name][i])
+				}
+			}
+			for comp, name := range []string{"red", "green", "blue"} {
+				if expected := float64(color.values[i*3+comp]); reference[name][i] != expected {
+					t.Fatalf("%s, point %d: expected %s %v, the reference decoder returned %v", golden.fileName, i, name, expected, reference[name][i])
+				}
+			}
+		}
+	}
+}
+
+// Reads the scalar properties of the vertices of a binary little endian or ascii PLY file, by property name.
+// Other elements, as the faces, must be empty
+func readPlyVertices(filePath string) (map[string][]float64, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	reader := bufio.NewReader(file)
+
+	type plyProperty struct {
+		name     string
+		dataType string
+	}
+	var format string
+	var numVertices int
+	var properties []plyProperty
+	element := ""
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("invalid ply header: %v", err)
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if fields[0] == "end_header" {
+			break
+		}
+		switch {
+		case fields[0] == "format" && len(fields) > 1:
+			format = fields[1]
+		case fields[0] == "element" && len(fields) == 3:
+			element = fields[1]
+			count, err := strconv.Atoi(fields[2])
+			if err != nil {
+				return nil, fmt.Errorf("invalid ply element [%s]", strings.TrimSpace(line))
+			}
+			if element == "vertex" {
+				numVertices = count
+			} else if count != 0 {
+				return nil, fmt.Errorf("unexpected ply element [%s]", strings.TrimSpace(line))
+			}
+		case fields[0] == "property" && element == "vertex":
+			if len(fields) != 3 {
+				return nil, fmt.Errorf("unsupported ply property [%s]", strings.TrimSpace(line))
+			}
+			properties = append(properties, plyProperty{name: fields[2], dataType: fields[1]})
+		}
+	}
+
+	vertices := make(map[string][]float64)
+	for i := 0; i < numVertices; i++ {
+		var fields []string
+		if format == "ascii" {
+			line, err := reader.ReadString('\n')
+			if err != nil && !(err == io.EOF && line != "") {
+				return nil, err
+			}
+			if fields = strings.Fields(line); len(fields) != len(properties) {
+				return nil, fmt.Errorf("invalid ply vertex [%s]", strings.TrimSpace(line))
+			}
+		} else if format != "binary_little_endian" {
+			return nil, fmt.Errorf("unsupported ply format [%s]", format)
+		}
+
+		for p, property := range properties {
+			var value float64
+			if fields != nil {
+				if value, err = strconv.ParseFloat(fields[p], 64); err != nil {
+					return nil, err
+				}
+			} else if value, err = readPlyBinaryValue(reader, property.dataType); err != nil {
+				return nil, err
+			}
+			vertices[property.name] = append(vertices[property.name], value)
+		}
+	}
+
+	return vertices, nil
+}
+
+func readPlyBinaryValue(reader io.Reader, dataType string) (float64, error) {
+	var err error
+	switch dataType {
+	case "char", "int8":
+		var v int8
+		err = binary.Read(reader, binary.LittleEndian, &v)
+		return float64(v), err
+	case "uchar", "uint8":
+		var v uint8
+		err = binary.Read(reader, binary.LittleEndian, &v)
+		return float64(v), err
+	case "short", "int16":
+		var v int16
+		err = binary.Read(reader, binary.LittleEndian, &v)
+		return float64(v), err
+	case "ushort", "uint16":
+		var v uint16
+		err = binary.Read(reader, binary.LittleEndian, &v)
+		return float64(v), err
+	case "int", "int32":
+		var v int32
+		err = binary.Read(reader, binary.LittleEndian, &v)
+		return float64(v), err
+	case "uint", "uint32":
+		var v uint32
+		err = binary.Read(reader, binary.LittleEndian, &v)
+		return float64(v), err
+	case "float", "float32":
+		var v float32
+		err = binary.Read(reader, binary.LittleEndian, &v)
+		return float64(v), err
+	case "double", "float64":
+		var v float64
+		err = binary.Read(reader, binary.LittleEndian, &v)
+		return v, err
+	}
+	return 0, fmt.Errorf("unsupported ply property type [%s]", dataType)
+}
@@ -1,16 +1,14 @@
 package unit
 
 import (
-	"github.com/ecopia-map/cesium_tiler/internal/converters/coordinate/proj4_coordinate_converter"
 	"github.com/ecopia-map/cesium_tiler/internal/converters/geoid_offset"
-	"github.com/ecopia-map/cesium_tiler/internal/converters/geoid_offset/gh_offset_calculator"
 	"math"
 	"testing"
 )
 
 func TestSinglePointElevationConverter(t *testing.T) {
 	var bufferedElevationConverter = geoid_offset.NewEllipsoidToGeoidSinglePointCalculator(
-		gh_offset_calculator.NewEllipsoidToGeoidGHOffsetCalculator(proj4_coordinate_converter.NewProj4CoordinateConverter()),
+		newTestGHOffsetCalculator(t),
 	)
 	expected := 48.95
 	output, err := bufferedElevationConverter.GetEllipsoidToGeoidOffset(491880.85, 4576930.54, 32633)
@@ -0,0 +1,40 @@
+package unit_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ecopia-map/cesium_tiler/internal/tiler"
+	"github.com/ecopia-map/cesium_tiler/tools"
+)
+
+func TestFileFinderReturnsWalkErrors(t *testing.T) {
+	opts := &tiler.TilerOptions{
+		Input:            filepath.Join(t.TempDir(), "missing"),
+		FolderProcessing: true,
+	}
+
+	fileFinder := tools.NewStandardFileFinder()
+	if _, err := fileFinder.GetLasFilesToProcess(opts); !os.IsNotExist(err) {
+		t.Errorf("Expected a not exist error listing the files to process, got: %v", err)
+	}
+	if _, err := fileFinder.GetLasFilesToMerge(opts); !os.IsNotExist(err) {
+		t.Errorf("Expected a not exist error listing the files to merge, got: %v", err)
+	}
+}
+
+func TestFileFinderListsLasAndLazFiles(t *testing.T) {
+	opts := &tiler.TilerOptions{
+		Input:            lazTestDataFolder,
+		FolderProcessing: true,
+	}
+
+	files, err := tools.NewStandardFileFinder().GetLasFilesToProcess(opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(files) != 13 {
+		t.Errorf("Expected the 13 las and laz fixtures, got %v", files)
+	}
+}
@@ -1,18 +1,15 @@
 package unit_test
 
 import (
-	"flag"
-	"github.com/ecopia-map/cesium_tiler/tools"
-	"os"
 	"strconv"
 	"testing"
+
+	"github.com/ecopia-map/cesium_tiler/tools"
 )
 
 func TestInputFlagIsParsed(t *testing.T) {
 	expected := "/home/user/file.las"
-	os.Args = []string{"cesium_tiler", "-input=" + expected}
-	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
-	flags := tools.ParseFlags()
+	flags := tools.ParseFlagsForCommandIndex([]string{"-input=" + expected})
 	if *flags.Input != expected {
 		t.Errorf("Expected Input = %s, got %s", expected, *flags.Input)
 	}
@@ -20,9 +17,7 @@ func TestInputFlagIsParsed(t *testing.T) {
 
 func TestIFlagIsParsed(t *testing.T) {
 	expected := "/home/user/file.las"
-	os.Args = []string{"cesium_tiler", "-i=" + expected}
-	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
-	flags := tools.ParseFlags()
+	flags := tools.ParseFlagsForCommandIndex([]string{"-i=" + expected})
 	if *flags.Input != expected {
 		t.Errorf("Expected Input = %s, got %s", expected, *flags.Input)
 	}
@@ -30,9 +25,7 @@ func TestIFlagIsParsed(t *testing.T) {
 
 func TestOutputFlagIsParsed(t *testing.T) {
 	expected := "/home/user/output"
-	os.Args = []string{"cesium_tiler", "-output=" + expected}
-	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
-	flags := tools.ParseFlags()
+	flags := tools.ParseFlagsForCommandIndex([]string{"-output=" + expected})
 	if *flags.Output != expected {
 		t.Errorf("Expected Output = %s, got %s", expected, *flags.Output)
 	}
@@ -40,9 +33,7 @@ func TestOutputFlagIsParsed(t *testing.T) {
 
 func TestOFlagIsParsed(t *testing.T) {
 	expected := "/home/user/output"
-	os.Args = []string{"cesium_tiler", "-o=" + expected}
-	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
-	flags := tools.ParseFlags()
+	flags := tools.ParseFlagsForCommandIndex([]string{"-o=" + expected})
 	if *flags.Output != expected {
 		t.Errorf("Expected Output = %s, got %s", expected, *flags.Output)
 	}
@@ -50,18 +41,14 @@ func TestOFlagIsParsed(t *testing.T) {
 
 func TestSridFlagIsParsed(t *testing.T) {
 	expected := 32633
-	os.Args = []string{"cesium_tiler", "-srid=" + strconv.Itoa(expected)}
-	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
-	flags := tools.ParseFlags()
+	flags := tools.ParseFlagsForCommandIndex([]string{"-srid=" + strconv.Itoa(expected)})
 	if *flags.Srid != expected {
 		t.Errorf("Expected Srid = %d, got %d", expected, *flags.Srid)
 	}
 }
 func TestEFlagIsParsed(t *testing.T) {
 	expected := 32633
-	os.Args = []string{"cesium_tiler", "-e=" + strconv.Itoa(expected)}
-	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
-	flags := tools.ParseFlags()
+	flags := tools.ParseFlagsForCommandIndex([]string{"-e=" + strconv.Itoa(expected)})
 	if *flags.Srid != expected {
 		t.Errorf("Expected Srid = %d, got %d", expected, *flags.Srid)
 	}
@@ -69,9 +56,7 @@ func TestEFlagIsParsed(t *testing.T) {
 
 func TestSridFlagDefaultIs4326(t *testing.T) {
 	expected := 4326
-	os.Args = []string{"cesium_tiler"}
-	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
-	flags := tools.ParseFlags()
+	flags := tools.ParseFlagsForCommandIndex([]string{})
 	if *flags.Srid != expected {
 		t.Errorf("Expected Srid = %d, got %d", expected, *flags.Srid)
 	}
@@ -79,9 +64,7 @@ func TestSridFlagDefaultIs4326(t *testing.T) {
 
 func TestZOffsetFlagIsParsed(t *testing.T) {
 	expected := 10.0
-	os.Args = []string{"cesium_tiler", "-zoffset=10"}
-	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
-	flags := tools.ParseFlags()
+	flags := tools.ParseFlagsForCommandIndex([]string{"-zoffset=10"})
 	if *flags.ZOffset != expected {
 		t.Errorf("Expected ZOffset = %f, got %f", expected, *flags.ZOffset)
 	}
@@ -89,9 +72,7 @@ func TestZOffsetFlagIsParsed(t *testing.T) {
 
 func TestZFlagIsParsed(t *testing.T) {
 	expected := 10.0
-	os.Args = []string{"cesium_tiler", "-z=10"}
-	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
-	flags := tools.ParseFlags()
+	flags := tools.ParseFlagsForCommandIndex([]string{"-z=10"})
 	if *flags.ZOffset != expected {
 		t.Errorf("Expected ZOffset = %f, got %f", expected, *flags.ZOffset)
 	}
@@ -99,9 +80,7 @@ func TestZFlagIsParsed(t *testing.T) {
 
 func TestZOffsetFlagDefaultIsZero(t *testing.T) {
 	expected := 0.0
-	os.Args = []string{"cesium_tiler"}
-	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
-	flags := tools.ParseFlags()
+	flags := tools.ParseFlagsForCommandIndex([]string{})
 	if *flags.ZOffset != expected {
 		t.Errorf("Expected ZOffset = %f, got %f", expected, *flags.ZOffset)
 	}
@@ -109,28 +88,22 @@ func TestZOffsetFlagDefaultIsZero(t *testing.T) {
 
 func TestMaxPtsFlagIsParsed(t *testing.T) {
 	expected := 2000
-	os.Args = []string{"cesium_tiler", "-maxpts=2000"}
-	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
-	flags := tools.ParseFlags()
+	flags := tools.ParseFlagsForCommandIndex([]string{"-points-max-num=2000"})
 	if *flags.MaxNumPoints != expected {
 		t.Errorf("Expected MaxNumPoints = %d, got %d", expected, *flags.MaxNumPoints)
 	}
 }
-func TestMFlagIsParsed(t *testing.T) {
+func TestYFlagIsParsed(t *testing.T) {
 	expected := 2000
-	os.Args = []string{"cesium_tiler", "-m=2000"}
-	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
-	flags := tools.ParseFlags()
+	flags := tools.ParseFlagsForCommandIndex([]string{"-y=2000"})
 	if *flags.MaxNumPoints != expected {
 		t.Errorf("Expected MaxNumPoints = %d, got %d", expected, *flags.MaxNumPoints)
 	}
 }
 
-func TestMaxPtsFlagDefaultIs50000(t *testing.T) {
-	expected := 50000
-	os.Args = []string{"cesium_tiler"}
-	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
-	flags := tools.ParseFlags()
+func TestMaxPtsFlagDefaultIs160000(t *testing.T) {
+	expected := 160000
+	flags := tools.ParseFlagsForCommandIndex([]string{})
 	if *flags.MaxNumPoints != expected {
 		t.Errorf("Expected MaxNumPoints = %d, got %d", expected, *flags.MaxNumPoints)
 	}
@@ -138,9 +111,7 @@ func TestMaxPtsFlagDefaultIs50000(t *testing.T) {
 
 func TestGeoidFlagIsParsed(t *testing.T) {
 	expected := true
-	os.Args = []string{"cesium_tiler", "-geoid"}
-	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
-	flags := tools.ParseFlags()
+	flags := tools.ParseFlagsForCommandIndex([]string{"-geoid"})
 	if !*flags.ZGeoidCorrection {
 		t.Errorf("Expected ZGeoidCorrection = %t, got %t", expected, *flags.ZGeoidCorrection)
 	}
@@ -148,9 +119,7 @@ func TestGeoidFlagIsParsed(t *testing.T) {
 
 func TestGFlagIsParsed(t *testing.T) {
 	expected := true
-	os.Args = []string{"cesium_tiler", "-g"}
-	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
-	flags := tools.ParseFlags()
+	flags := tools.ParseFlagsForCommandIndex([]string{"-g"})
 	if !*flags.ZGeoidCorrection {
 		t.Errorf("Expected ZGeoidCorrection = %t, got %t", expected, *flags.ZGeoidCorrection)
 	}
@@ -158,9 +127,7 @@ func TestGFlagIsParsed(t *testing.T) {
 
 func TestGeoidFlagDefaultIsFalse(t *testing.T) {
 	expected := false
-	os.Args = []string{"cesium_tiler"}
-	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
-	flags := tools.ParseFlags()
+	flags := tools.ParseFlagsForCommandIndex([]string{})
 	if *flags.ZGeoidCorrection {
 		t.Errorf("Expected ZGeoidCorrection = %t, got %t", expected, *flags.ZGeoidCorrection)
 	}
@@ -168,9 +135,7 @@ func TestGeoidFlagDefaultIsFalse(t *testing.T) {
 
 func TestFolderProcessingFlagIsParsed(t *testing.T) {
 	expected := true
-	os.Args = []string{"cesium_tiler", "-folder"}
-	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
-	flags := tools.ParseFlags()
+	flags := tools.ParseFlagsForCommandIndex([]string{"-folder"})
 	if !*flags.FolderProcessing {
 		t.Errorf("Expected FolderProcessing = %t, got %t", expected, *flags.FolderProcessing)
 	}
@@ -178,9 +143,7 @@ func TestFolderProcessingFlagIsParsed(t *testing.T) {
 
 func TestFFlagIsParsed(t *testing.T) {
 	expected := true
-	os.Args = []string{"cesium_tiler", "-f"}
-	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
-	flags := tools.ParseFlags()
+	flags := tools.ParseFlagsForCommandIndex([]string{"-f"})
 	if !*flags.FolderProcessing {
 		t.Errorf("Expected FolderProcessing = %t, got %t", expected, *flags.FolderProcessing)
 	}
@@ -188,9 +151,7 @@ func TestFFlagIsParsed(t *testing.T) {
 
 func TestFolderProcessingDefaultIsFalse(t *testing.T) {
 	expected := false
-	os.Args = []string{"cesium_tiler"}
-	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
-	flags := tools.ParseFlags()
+	flags := tools.ParseFlagsForCommandIndex([]string{})
 	if *flags.FolderProcessing {
 		t.Errorf("Expected FolderProcessing = %t, got %t", expected, *flags.FolderProcessing)
 	}
@@ -198,9 +159,7 @@ func TestFolderProcessingDefaultIsFalse(t *testing.T) {
 
 func TestRecursiveFolderProcessingFlagIsParsed(t *testing.T) {
 	expected := true
-	os.Args = []string{"cesium_tiler", "-recursive"}
-	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
-	flags := tools.ParseFlags()
+	flags := tools.ParseFlagsForCommandIndex([]string{"-recursive"})
 	if !*flags.RecursiveFolderProcessing {
 		t.Errorf("Expected RecursiveFolderProcessing = %t, got %t", expected, *flags.RecursiveFolderProcessing)
 	}
@@ -208,9 +167,7 @@ func TestRecursiveFolderProcessingFlagIsParsed(t *testing.T) {
 
 func TestRFlagIsParsed(t *testing.T) {
 	expected := true
-	os.Args = []string{"cesium_tiler", "-r"}
-	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
-	flags := tools.ParseFlags()
+	flags := tools.ParseFlagsForCommandIndex([]string{"-r"})
 	if !*flags.RecursiveFolderProcessing {
 		t.Errorf("Expected RecursiveFolderProcessing = %t, got %t", expected, *flags.RecursiveFolderProcessing)
 	}
@@ -218,9 +175,7 @@ func TestRFlagIsParsed(t *testing.T) {
 
 func TestRecursiveFolderProcessingDefaultIsFalse(t *testing.T) {
 	expected := false
-	os.Args = []string{"cesium_tiler"}
-	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
-	flags := tools.ParseFlags()
+	flags := tools.ParseFlagsForCommandIndex([]string{})
 	if *flags.RecursiveFolderProcessing {
 		t.Errorf("Expected RecursiveFolderProcessing = %t, got %t", expected, *flags.RecursiveFolderProcessing)
 	}
@@ -228,9 +183,7 @@ func TestRecursiveFolderProcessingDefaultIsFalse(t *testing.T) {
 
 func TestSilentFlagIsParsed(t *testing.T) {
 	expected := true
-	os.Args = []string{"cesium_tiler", "-silent"}
-	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
-	flags := tools.ParseFlags()
+	flags := tools.ParseFlagsForCommandIndex([]string{"-silent"})
 	if !*flags.Silent {
 		t.Errorf("Expected Silent = %t, got %t", expected, *flags.Silent)
 	}
@@ -238,9 +191,7 @@ func TestSilentFlagIsParsed(t *testing.T) {
 
 func TestSFlagIsParsed(t *testing.T) {
 	expected := true
-	os.Args = []string{"cesium_tiler", "-silent"}
-	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
-	flags := tools.ParseFlags()
+	flags := tools.ParseFlagsForCommandIndex([]string{"-s"})
 	if !*flags.Silent {
 		t.Errorf("Expected Silent = %t, got %t", expected, *flags.Silent)
 	}
@@ -248,9 +199,7 @@ func TestSFlagIsParsed(t *testing.T) {
 
 func TestSilentFlagDefaultIsFalse(t *testing.T) {
 	expected := false
-	os.Args = []string{"cesium_tiler"}
-	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
-	flags := tools.ParseFlags()
+	flags := tools.ParseFlagsForCommandIndex([]string{})
 	if *flags.Silent {
 		t.Errorf("Expected Silent = %t, got %t", expected, *flags.Silent)
 	}
@@ -258,9 +207,7 @@ func TestSilentFlagDefaultIsFalse(t *testing.T) {
 
 func TestLogTimestampFlagIsParsed(t *testing.T) {
 	expected := true
-	os.Args = []string{"cesium_tiler", "-timestamp"}
-	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
-	flags := tools.ParseFlags()
+	flags := tools.ParseFlagsForCommandIndex([]string{"-timestamp"})
 	if !*flags.LogTimestamp {
 		t.Errorf("Expected LogTimestamp = %t, got %t", expected, *flags.LogTimestamp)
 	}
@@ -268,9 +215,7 @@ func TestLogTimestampFlagIsParsed(t *testing.T) {
 
 func TestTFlagIsParsed(t *testing.T) {
 	expected := true
-	os.Args = []string{"cesium_tiler", "-timestamp"}
-	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
-	flags := tools.ParseFlags()
+	flags := tools.ParseFlagsForCommandIndex([]string{"-t"})
 	if !*flags.LogTimestamp {
 		t.Errorf("Expected LogTimestamp = %t, got %t", expected, *flags.LogTimestamp)
 	}
@@ -278,9 +223,7 @@ func TestTFlagIsParsed(t *testing.T) {
 
 func TestLogTimestampFlagDefaultIsFalse(t *testing.T) {
 	expected := false
-	os.Args = []string{"cesium_tiler"}
-	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
-	flags := tools.ParseFlags()
+	flags := tools.ParseFlagsForCommandIndex([]string{})
 	if *flags.LogTimestamp {
 		t.Errorf("Expected LogTimestamp = %t, got %t", expected, *flags.LogTimestamp)
 	}
@@ -288,9 +231,7 @@ func TestLogTimestampFlagDefaultIsFalse(t *testing.T) {
 
 func TestAlgorithmFlagIsParsed(t *testing.T) {
 	expected := "random"
-	os.Args = []string{"cesium_tiler", "-algorithm=random"}
-	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
-	flags := tools.ParseFlags()
+	flags := tools.ParseFlagsForCommandIndex([]string{"-algorithm=random"})
 	if *flags.Algorithm != expected {
 		t.Errorf("Expected Algorithm = %s, got %s", expected, *flags.Algorithm)
 	}
@@ -298,9 +239,7 @@ func TestAlgorithmFlagIsParsed(t *testing.T) {
 
 func TestAlgorithmDefaultIsGrid(t *testing.T) {
 	expected := "grid"
-	os.Args = []string{"cesium_tiler"}
-	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
-	flags := tools.ParseFlags()
+	flags := tools.ParseFlagsForCommandIndex([]string{})
 	if *flags.Algorithm != "grid" {
 		t.Errorf("Expected Algorithm = %s, got %s", expected, *flags.Algorithm)
 	}
@@ -308,9 +247,7 @@ func TestAlgorithmDefaultIsGrid(t *testing.T) {
 
 func TestGridMaxSizeFlagIsParsed(t *testing.T) {
 	expected := 2.35
-	os.Args = []string{"cesium_tiler", "-grid-max-size=2.35"}
-	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
-	flags := tools.ParseFlags()
+	flags := tools.ParseFlagsForCommandIndex([]string{"-grid-max-size=2.35"})
 	if *flags.GridCellMaxSize != expected {
 		t.Errorf("Expected Algorithm = %f, got %f", expected, *flags.GridCellMaxSize)
 	}
@@ -318,9 +255,7 @@ func TestGridMaxSizeFlagIsParsed(t *testing.T) {
 
 func TestGridMaxSizeFlagDefaultIs5m(t *testing.T) {
 	expected := 5.0
-	os.Args = []string{"cesium_tiler"}
-	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
-	flags := tools.ParseFlags()
+	flags := tools.ParseFlagsForCommandIndex([]string{})
 	if *flags.GridCellMaxSize != expected {
 		t.Errorf("Expected Algorithm = %f, got %f", expected, *flags.GridCellMaxSize)
 	}
@@ -328,9 +263,7 @@ func TestGridMaxSizeFlagDefaultIs5m(t *testing.T) {
 
 func TestGridMinSizeFlagIsParsed(t *testing.T) {
 	expected := 0.04
-	os.Args = []string{"cesium_tiler", "-grid-min-size=0.04"}
-	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
-	flags := tools.ParseFlags()
+	flags := tools.ParseFlagsForCommandIndex([]string{"-grid-min-size=0.04"})
 	if *flags.GridCellMinSize != expected {
 		t.Errorf("Expected Algorithm = %f, got %f", expected, *flags.GridCellMinSize)
 	}
@@ -338,9 +271,7 @@ func TestGridMinSizeFlagIsParsed(t *testing.T) {
 
 func TestGridMinSizeFlagDefaultIs15cm(t *testing.T) {
 	expected := 0.15
-	os.Args = []string{"cesium_tiler"}
-	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
-	flags := tools.ParseFlags()
+	flags := tools.ParseFlagsForCommandIndex([]string{})
 	if *flags.GridCellMinSize != expected {
 		t.Errorf("Expected Algorithm = %f, got %f", expected, *flags.GridCellMinSize)
 	}
@@ -348,9 +279,7 @@ func TestGridMinSizeFlagDefaultIs15cm(t *testing.T) {
 
 func TestHelpFlagIsParsed(t *testing.T) {
 	expected := true
-	os.Args = []string{"cesium_tiler", "-help"}
-	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
-	flags := tools.ParseFlags()
+	flags := tools.ParseFlagsForCommandIndex([]string{"-help"})
 	if !*flags.Help {
 		t.Errorf("Expected Help = %t, got %t", expected, *flags.Help)
 	}
@@ -358,9 +287,7 @@ func TestHelpFlagIsParsed(t *testing.T) {
 
 func TestHFlagIsParsed(t *testing.T) {
 	expected := true
-	os.Args = []string{"cesium_tiler", "-h"}
-	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
-	flags := tools.ParseFlags()
+	flags := tools.ParseFlagsForCommandIndex([]string{"-h"})
 	if !*flags.Help {
 		t.Errorf("Expected Help = %t, got %t", expected, *flags.Help)
 	}
@@ -368,9 +295,7 @@ func TestHFlagIsParsed(t *testing.T) {
 
 func TestHelpDefaultIsFalse(t *testing.T) {
 	expected := false
-	os.Args = []string{"cesium_tiler"}
-	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
-	flags := tools.ParseFlags()
+	flags := tools.ParseFlagsForCommandIndex([]string{})
 	if *flags.Help {
 		t.Errorf("Expected Help = %t, got %t", expected, *flags.Help)
 	}
@@ -378,9 +303,7 @@ func TestHelpDefaultIsFalse(t *testing.T) {
 
 func TestRefineModeFlagIsParsed(t *testing.T) {
 	expected := "REPLACE"
-	os.Args = []string{"cesium_tiler", "-refine-mode=" + expected}
-	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
-	flags := tools.ParseFlags()
+	flags := tools.ParseFlagsForCommandIndex([]string{"-refine-mode=" + expected})
 	if *flags.RefineMode != expected {
 		t.Errorf("Expected Output = %s, got %s", expected, *flags.RefineMode)
 	}
@@ -388,10 +311,8 @@ func TestRefineModeFlagIsParsed(t *testing.T) {
 
 func TestRefineModeFlagDefaultIsAdd(t *testing.T) {
 	expected := "ADD"
-	os.Args = []string{"cesium_tiler"}
-	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
-	flags := tools.ParseFlags()
+	flags := tools.ParseFlagsForCommandIndex([]string{})
 	if *flags.RefineMode != expected {
 		t.Errorf("Expected Output = %s, got %s", expected, *flags.RefineMode)
 	}
-}
\ No newline at end of file
+}
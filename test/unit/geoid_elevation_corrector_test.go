@@ -1,6 +1,7 @@
 package unit
 
 import (
+	"errors"
 	"github.com/ecopia-map/cesium_tiler/internal/converters/elevation/geoid_elevation_corrector"
 	"math"
 	"testing"
@@ -19,7 +20,10 @@ func TestGeoidElevationCorrector(t *testing.T) {
 	)
 
 	expected := 58.95
-	output := bufferedElevationConverter.CorrectElevation(491880.85, 4576930.54, 10.0)
+	output, err := bufferedElevationConverter.CorrectElevation(491880.85, 4576930.54, 10.0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
 
 	if math.Abs(expected-output) > 1E-3 {
 		t.Errorf(
@@ -29,3 +33,17 @@ func TestGeoidElevationCorrector(t *testing.T) {
 		)
 	}
 }
+
+type failingOffsetCalculator struct{}
+
+func (failingOffsetCalculator *failingOffsetCalculator) GetEllipsoidToGeoidOffset(lat, lon float64, sourceSrid int) (float64, error) {
+	return 0, errors.New("point outside of the geoid grid")
+}
+
+func TestGeoidElevationCorrectorReturnsOffsetErrors(t *testing.T) {
+	var elevationCorrector = geoid_elevation_corrector.NewGeoidElevationCorrector(32633, &failingOffsetCalculator{})
+
+	if _, err := elevationCorrector.CorrectElevation(491880.85, 4576930.54, 10.0); err == nil {
+		t.Errorf("Expected the error of the offset calculator")
+	}
+}
@@ -1,15 +1,23 @@
 package unit
 
 import (
-	"github.com/ecopia-map/cesium_tiler/internal/converters/coordinate/proj4_coordinate_converter"
+	"github.com/ecopia-map/cesium_tiler/internal/converters"
 	"github.com/ecopia-map/cesium_tiler/internal/converters/geoid_offset/gh_offset_calculator"
 	"math"
 	"testing"
 )
 
-var offsetCalculator = gh_offset_calculator.NewEllipsoidToGeoidGHOffsetCalculator(proj4_coordinate_converter.NewProj4CoordinateConverter())
+// Returns the calculator of the geoid height of the gravitational model of the assets folder
+func newTestGHOffsetCalculator(t *testing.T) converters.EllipsoidToGeoidOffsetCalculator {
+	offsetCalculator, err := gh_offset_calculator.NewEllipsoidToGeoidGHOffsetCalculator(newTestProj4CoordinateConverter(t))
+	if err != nil {
+		t.Fatalf("Unexpected error loading the gravitational model: %s", err)
+	}
+	return offsetCalculator
+}
 
 func TestGetEllipsoidToGeoidZOffsetFrom32633Correct(t *testing.T) {
+	offsetCalculator := newTestGHOffsetCalculator(t)
 	expected := 48.95
 	output, err := offsetCalculator.GetEllipsoidToGeoidOffset(4576930.54, 491880.85, 32633)
 
@@ -27,6 +35,7 @@ func TestGetEllipsoidToGeoidZOffsetFrom32633Correct(t *testing.T) {
 }
 
 func TestGetEllipsoidToGeoidZOffsetFrom4326Correct(t *testing.T) {
+	offsetCalculator := newTestGHOffsetCalculator(t)
 	expected := 48.95
 	output, err := offsetCalculator.GetEllipsoidToGeoidOffset(41.343825, 14.902954, 4326)
 
@@ -1,26 +1,33 @@
 package unit
 
 import (
+	"math"
+	"testing"
+
 	"github.com/ecopia-map/cesium_tiler/internal/data"
 	"github.com/ecopia-map/cesium_tiler/internal/geometry"
 	"github.com/ecopia-map/cesium_tiler/internal/octree/grid_tree"
-	"math"
-	"testing"
+	"github.com/ecopia-map/cesium_tiler/internal/tiler"
 )
 
+// Returns a node of a tree with the closest point sampling, which the node needs to compute its geometric error
+func newTestGridNode(boundingBox *geometry.BoundingBox, maxCellSize float64, minCellSize float64, root bool) *grid_tree.GridNode {
+	tree := grid_tree.NewGridTree(&mockCoordinateConverter{}, &mockElevationCorrector{}, maxCellSize, minCellSize, tiler.SamplingClosest)
+	return grid_tree.NewGridNode("r", tree, nil, boundingBox, maxCellSize, minCellSize, root)
+}
+
 func TestGridNodeAddDataPointSinglePoint(t *testing.T) {
-	node := grid_tree.NewGridNode(
-		nil,
+	node := newTestGridNode(
 		geometry.NewBoundingBox(14, 15, 41, 42, 1, 2),
 		5.0,
 		1.0,
 		true,
 	)
 
-	point := data.NewPoint(14, 41, 1, 2, 3, 4, 5, 6)
-	node.AddDataPoint(point)
+	point := data.NewPoint(14, 41, 1, 2, 3, 4, 5, 6, nil)
+	node.AddDataPoint(point, true)
 
-	node.(*grid_tree.GridNode).BuildPoints()
+	node.BuildPoints()
 
 	if len(node.GetPoints()) != 1 {
 		t.Fatalf("One point expected, %d returned", len(node.GetPoints()))
@@ -40,23 +47,22 @@ func TestGridNodeAddDataPointSinglePoint(t *testing.T) {
 }
 
 func TestGridNodeAddDataPointMultiplePoints(t *testing.T) {
-	node := grid_tree.NewGridNode(
-		nil,
+	node := newTestGridNode(
 		geometry.NewBoundingBox(14, 15, 41, 42, 1, 2),
 		10.0,
 		1.0,
 		true,
 	)
 
-	point := data.NewPoint(11, 11, 1, 2, 3, 4, 5, 6)
-	point2 := data.NewPoint(13, 13, 1, 2, 3, 4, 5, 6)
-	point3 := data.NewPoint(12, 12, 1, 2, 3, 4, 5, 6)
+	point := data.NewPoint(11, 11, 1, 2, 3, 4, 5, 6, nil)
+	point2 := data.NewPoint(13, 13, 1, 2, 3, 4, 5, 6, nil)
+	point3 := data.NewPoint(12, 12, 1, 2, 3, 4, 5, 6, nil)
 
-	node.AddDataPoint(point)
-	node.AddDataPoint(point2)
-	node.AddDataPoint(point3)
+	node.AddDataPoint(point, true)
+	node.AddDataPoint(point2, true)
+	node.AddDataPoint(point3, true)
 
-	node.(*grid_tree.GridNode).BuildPoints()
+	node.BuildPoints()
 
 	if len(node.GetPoints()) != 1 {
 		t.Fatalf("One point expected, %d returned", len(node.GetPoints()))
@@ -76,8 +82,7 @@ func TestGridNodeAddDataPointMultiplePoints(t *testing.T) {
 }
 
 func TestGridNodeGetInternalSrid(t *testing.T) {
-	node := grid_tree.NewGridNode(
-		nil,
+	node := newTestGridNode(
 		geometry.NewBoundingBox(14, 15, 41, 42, 1, 2),
 		5.0,
 		1.0,
@@ -90,8 +95,7 @@ func TestGridNodeGetInternalSrid(t *testing.T) {
 }
 
 func TestGridNodeGetIsRootTrue(t *testing.T) {
-	node := grid_tree.NewGridNode(
-		nil,
+	node := newTestGridNode(
 		geometry.NewBoundingBox(14, 15, 41, 42, 1, 2),
 		5.0,
 		1.0,
@@ -104,8 +108,7 @@ func TestGridNodeGetIsRootTrue(t *testing.T) {
 }
 
 func TestGridNodeGetIsRootFalse(t *testing.T) {
-	node := grid_tree.NewGridNode(
-		nil,
+	node := newTestGridNode(
 		geometry.NewBoundingBox(14, 15, 41, 42, 1, 2),
 		5.0,
 		1.0,
@@ -119,8 +122,7 @@ func TestGridNodeGetIsRootFalse(t *testing.T) {
 
 func TestGridNodeGetBoundingBoxRegion(t *testing.T) {
 	inputRegion := geometry.NewBoundingBox(14, 15, 41, 42, 1, 2)
-	node := grid_tree.NewGridNode(
-		nil,
+	node := newTestGridNode(
 		inputRegion,
 		5.0,
 		1.0,
@@ -135,22 +137,21 @@ func TestGridNodeGetBoundingBoxRegion(t *testing.T) {
 }
 
 func TestGridNodeGetChildren(t *testing.T) {
-	node := grid_tree.NewGridNode(
-		nil,
+	node := newTestGridNode(
 		geometry.NewBoundingBox(14, 15, 41, 42, 1, 2),
 		5.0,
 		1.0,
 		true,
 	)
 
-	point := data.NewPoint(14, 41, 1, 2, 3, 4, 5, 6)
-	node.AddDataPoint(point)
-	point = data.NewPoint(15, 42, 2, 2, 3, 4, 5, 6)
-	node.AddDataPoint(point)
-	point = data.NewPoint(15, 42, 2, 2, 3, 4, 5, 6)
-	node.AddDataPoint(point)
+	point := data.NewPoint(14, 41, 1, 2, 3, 4, 5, 6, nil)
+	node.AddDataPoint(point, true)
+	point = data.NewPoint(15, 42, 2, 2, 3, 4, 5, 6, nil)
+	node.AddDataPoint(point, true)
+	point = data.NewPoint(15, 42, 2, 2, 3, 4, 5, 6, nil)
+	node.AddDataPoint(point, true)
 
-	node.(*grid_tree.GridNode).BuildPoints()
+	node.BuildPoints()
 
 	children := node.GetChildren()
 	if len(children[0].GetPoints()) != 0 {
@@ -180,24 +181,23 @@ func TestGridNodeGetChildren(t *testing.T) {
 }
 
 func TestGridNodeGetPoints(t *testing.T) {
-	node := grid_tree.NewGridNode(
-		nil,
+	node := newTestGridNode(
 		geometry.NewBoundingBox(14, 15, 41, 42, 1, 2),
 		1.0,
 		1.0,
 		true,
 	)
 
-	point := data.NewPoint(14.1, 41, 1, 2, 3, 4, 5, 6)
-	node.AddDataPoint(point)
+	point := data.NewPoint(14.1, 41, 1, 2, 3, 4, 5, 6, nil)
+	node.AddDataPoint(point, true)
 
-	point = data.NewPoint(14.3, 41, 1, 2, 3, 4, 5, 6)
-	node.AddDataPoint(point)
+	point = data.NewPoint(14.3, 41, 1, 2, 3, 4, 5, 6, nil)
+	node.AddDataPoint(point, true)
 
-	point = data.NewPoint(14.2, 41, 1, 2, 3, 4, 5, 6)
-	node.AddDataPoint(point)
+	point = data.NewPoint(14.2, 41, 1, 2, 3, 4, 5, 6, nil)
+	node.AddDataPoint(point, true)
 
-	node.(*grid_tree.GridNode).BuildPoints()
+	node.BuildPoints()
 
 	children := node.GetChildren()
 
@@ -213,24 +213,23 @@ func TestGridNodeGetPoints(t *testing.T) {
 }
 
 func TestGridNodeGetTotalNumberOfPoints(t *testing.T) {
-	node := grid_tree.NewGridNode(
-		nil,
+	node := newTestGridNode(
 		geometry.NewBoundingBox(14, 15, 41, 42, 1, 2),
 		1.0,
 		1.0,
 		true,
 	)
 
-	point := data.NewPoint(14.1, 41, 1, 2, 3, 4, 5, 6)
-	node.AddDataPoint(point)
+	point := data.NewPoint(14.1, 41, 1, 2, 3, 4, 5, 6, nil)
+	node.AddDataPoint(point, true)
 
-	point = data.NewPoint(14.3, 41, 1, 2, 3, 4, 5, 6)
-	node.AddDataPoint(point)
+	point = data.NewPoint(14.3, 41, 1, 2, 3, 4, 5, 6, nil)
+	node.AddDataPoint(point, true)
 
-	point = data.NewPoint(14.2, 41, 1, 2, 3, 4, 5, 6)
-	node.AddDataPoint(point)
+	point = data.NewPoint(14.2, 41, 1, 2, 3, 4, 5, 6, nil)
+	node.AddDataPoint(point, true)
 
-	node.(*grid_tree.GridNode).BuildPoints()
+	node.BuildPoints()
 
 	if node.TotalNumberOfPoints() != 3 {
 		t.Errorf("Expected node to have TotalNumberOfPoints equal to %d but got %d", 3, node.TotalNumberOfPoints())
@@ -238,24 +237,23 @@ func TestGridNodeGetTotalNumberOfPoints(t *testing.T) {
 }
 
 func TestGridNodeGetNumberOfPoints(t *testing.T) {
-	node := grid_tree.NewGridNode(
-		nil,
+	node := newTestGridNode(
 		geometry.NewBoundingBox(14, 15, 41, 42, 1, 2),
 		1.0,
 		0.5,
 		true,
 	)
 
-	point := data.NewPoint(14.1, 41, 1, 2, 3, 4, 5, 6)
-	node.AddDataPoint(point)
+	point := data.NewPoint(14.1, 41, 1, 2, 3, 4, 5, 6, nil)
+	node.AddDataPoint(point, true)
 
-	point = data.NewPoint(14.3, 41, 1, 2, 3, 4, 5, 6)
-	node.AddDataPoint(point)
+	point = data.NewPoint(14.3, 41, 1, 2, 3, 4, 5, 6, nil)
+	node.AddDataPoint(point, true)
 
-	point = data.NewPoint(14.2, 41, 1, 2, 3, 4, 5, 6)
-	node.AddDataPoint(point)
+	point = data.NewPoint(14.2, 41, 1, 2, 3, 4, 5, 6, nil)
+	node.AddDataPoint(point, true)
 
-	node.(*grid_tree.GridNode).BuildPoints()
+	node.BuildPoints()
 
 	if node.NumberOfPoints() != 1 {
 		t.Errorf("Expected node to have NumberOfPoints equal to %d but got %d", 1, node.NumberOfPoints())
@@ -267,21 +265,20 @@ func TestGridNodeGetNumberOfPoints(t *testing.T) {
 }
 
 func TestGridNodeIsLeaf(t *testing.T) {
-	node := grid_tree.NewGridNode(
-		nil,
+	node := newTestGridNode(
 		geometry.NewBoundingBox(14, 15, 41, 42, 1, 2),
 		1.0,
 		0.5,
 		true,
 	)
 
-	point := data.NewPoint(14.1, 41, 1, 2, 3, 4, 5, 6)
-	node.AddDataPoint(point)
+	point := data.NewPoint(14.1, 41, 1, 2, 3, 4, 5, 6, nil)
+	node.AddDataPoint(point, true)
 
-	point = data.NewPoint(14.3, 41, 1, 2, 3, 4, 5, 6)
-	node.AddDataPoint(point)
+	point = data.NewPoint(14.3, 41, 1, 2, 3, 4, 5, 6, nil)
+	node.AddDataPoint(point, true)
 
-	node.(*grid_tree.GridNode).BuildPoints()
+	node.BuildPoints()
 
 	if node.IsLeaf() {
 		t.Errorf("Expected node to be non leaf")
@@ -293,29 +290,27 @@ func TestGridNodeIsLeaf(t *testing.T) {
 }
 
 func TestGridNodeIsInitialized(t *testing.T) {
-	node := grid_tree.NewGridNode(
-		nil,
+	node := newTestGridNode(
 		geometry.NewBoundingBox(14, 15, 41, 42, 1, 2),
 		1.0,
 		0.5,
 		true,
 	)
 
-	if node.IsInitialized() {
+	if node.IsChildrenInitialized() {
 		t.Errorf("Expected node to be not initialized")
 	}
 
-	point := data.NewPoint(14.1, 41, 1, 2, 3, 4, 5, 6)
-	node.AddDataPoint(point)
+	point := data.NewPoint(14.1, 41, 1, 2, 3, 4, 5, 6, nil)
+	node.AddDataPoint(point, true)
 
-	if !node.IsInitialized() {
+	if !node.IsChildrenInitialized() {
 		t.Errorf("Expected node to be initialized")
 	}
 }
 
 func TestGridNodeComputeGeometricError(t *testing.T) {
-	node := grid_tree.NewGridNode(
-		nil,
+	node := newTestGridNode(
 		geometry.NewBoundingBox(14, 15, 41, 42, 1, 2),
 		1.0,
 		0.5,
@@ -329,36 +324,34 @@ func TestGridNodeComputeGeometricError(t *testing.T) {
 }
 
 func TestRootGridNodeComputeGeometricError(t *testing.T) {
-	node := grid_tree.NewGridNode(
-		nil,
+	node := newTestGridNode(
 		geometry.NewBoundingBox(14, 16, 41, 42, 1, 2),
 		1.0,
 		0.5,
 		true,
 	)
 
-	expectedError := 1.0 * math.Sqrt(4 + 1 + 1)
+	expectedError := 1.0 * math.Sqrt(4+1+1)
 	if node.ComputeGeometricError() != expectedError {
 		t.Errorf("Expected ComputeGeometricError %f, got %f", expectedError, node.ComputeGeometricError())
 	}
 }
 
 func TestGridNodeGetParent(t *testing.T) {
-	node := grid_tree.NewGridNode(
-		nil,
+	node := newTestGridNode(
 		geometry.NewBoundingBox(14, 15, 41, 42, 1, 2),
 		1.0,
 		0.5,
 		true,
 	)
 
-	point := data.NewPoint(14.1, 41, 1, 2, 3, 4, 5, 6)
-	node.AddDataPoint(point)
+	point := data.NewPoint(14.1, 41, 1, 2, 3, 4, 5, 6, nil)
+	node.AddDataPoint(point, true)
 
-	point = data.NewPoint(14.3, 41, 1, 2, 3, 4, 5, 6)
-	node.AddDataPoint(point)
+	point = data.NewPoint(14.3, 41, 1, 2, 3, 4, 5, 6, nil)
+	node.AddDataPoint(point, true)
 
-	node.(*grid_tree.GridNode).BuildPoints()
+	node.BuildPoints()
 
 	if node.GetParent() != nil {
 		t.Errorf("Unexpected parent node")
@@ -0,0 +1,258 @@
+package unit_test
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/ecopia-map/cesium_tiler/internal/converters/geoid_offset/grid_offset_calculator"
+	"github.com/ecopia-map/cesium_tiler/internal/geometry"
+)
+
+// Coordinate converter returning the coordinates unchanged, the test grids are in EPSG:4326
+type identityCoordinateConverter struct{}
+
+func (c *identityCoordinateConverter) ConvertCoordinateSrid(sourceSrid int, targetSrid int, coord geometry.Coordinate) (geometry.Coordinate, error) {
+	return coord, nil
+}
+
+func (c *identityCoordinateConverter) ConvertCoordinatesSrid(sourceSrid int, targetSrid int, coords []geometry.Coordinate) error {
+	return nil
+}
+
+func (c *identityCoordinateConverter) Convert2DBoundingboxToWGS84Region(bbox *geometry.BoundingBox, srid int) (*geometry.BoundingBox, error) {
+	return bbox, nil
+}
+
+func (c *identityCoordinateConverter) ConvertToWGS84Cartesian(coord geometry.Coordinate, sourceSrid int) (geometry.Coordinate, error) {
+	return coord, nil
+}
+
+func (c *identityCoordinateConverter) ConvertToWGS84CartesianBatch(coords []geometry.Coordinate, sourceSrid int) error {
+	return nil
+}
+
+func (c *identityCoordinateConverter) Cleanup() {}
+
+// Writes a 5x5 .gtx grid with nodes every degree from lat 40 lon 10, whose heights are given by the function
+func writeTestGtxGrid(t *testing.T, height func(lat, lon float64) float64) string {
+	folder, err := ioutil.TempDir("", "geoid_grid")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(folder) })
+
+	content := make([]byte, 40+25*4)
+	for i, value := range []float64{40, 10, 1, 1} {
+		binary.BigEndian.PutUint64(content[i*8:], math.Float64bits(value))
+	}
+	binary.BigEndian.PutUint32(content[32:], 5)
+	binary.BigEndian.PutUint32(content[36:], 5)
+	for row := 0; row < 5; row++ {
+		for col := 0; col < 5; col++ {
+			value := float32(height(40+float64(row), 10+float64(col)))
+			binary.BigEndian.PutUint32(content[40+(row*5+col)*4:], math.Float32bits(value))
+		}
+	}
+
+	gridPath := path.Join(folder, "geoid.gtx")
+	if err := ioutil.WriteFile(gridPath, content, 0644); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return gridPath
+}
+
+// Writes a 5x5 NOAA .bin grid in the given byte order, with nodes every degree from lat 40 lon 10, whose heights are
+// given by the function
+func writeTestNoaaBinGrid(t *testing.T, order binary.ByteOrder, height func(lat, lon float64) float64) string {
+	content := make([]byte, 44+25*4)
+	for i, value := range []float64{40, 10, 1, 1} {
+		order.PutUint64(content[i*8:], math.Float64bits(value))
+	}
+	order.PutUint32(content[32:], 5)
+	order.PutUint32(content[36:], 5)
+	order.PutUint32(content[40:], 1)
+	for row := 0; row < 5; row++ {
+		for col := 0; col < 5; col++ {
+			value := float32(height(40+float64(row), 10+float64(col)))
+			order.PutUint32(content[44+(row*5+col)*4:], math.Float32bits(value))
+		}
+	}
+
+	gridPath := path.Join(t.TempDir(), "geoid.bin")
+	if err := ioutil.WriteFile(gridPath, content, 0644); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return gridPath
+}
+
+// Writes a 5x5 little endian GeoTIFF grid of float32 points with nodes every degree from lat 40 lon 10, whose heights
+// are given by the function. The single strip of the image is deflate compressed if deflate is true
+func writeTestGeoTiffGrid(t *testing.T, deflate bool, height func(lat, lon float64) float64) string {
+	order := binary.LittleEndian
+
+	// tiff rows start from the north
+	strip := make([]byte, 25*4)
+	for row := 0; row < 5; row++ {
+		for col := 0; col < 5; col++ {
+			value := float32(height(44-float64(row), 10+float64(col)))
+			order.PutUint32(strip[(row*5+col)*4:], math.Float32bits(value))
+		}
+	}
+	compression := uint32(1)
+	if deflate {
+		var buffer bytes.Buffer
+		writer := zlib.NewWriter(&buffer)
+		_, _ = writer.Write(strip)
+		_ = writer.Close()
+		strip = buffer.Bytes()
+		compression = 8
+	}
+
+	// values that don't fit in the directory entries follow it: pixel scale, tiepoint, geo keys and the strip
+	const numEntries = 12
+	dataOffset := uint32(8 + 2 + numEntries*12 + 4)
+	var data bytes.Buffer
+	_ = binary.Write(&data, order, []float64{1, 1, 0, 0, 0, 0, 10, 44, 0})
+	// geographic model with pixels as points, the tiepoint refers to the north west node
+	_ = binary.Write(&data, order, []uint16{1, 1, 0, 2, 1024, 0, 1, 2, 1025, 0, 1, 2})
+	stripOffset := dataOffset + uint32(data.Len())
+	data.Write(strip)
+
+	entries := []struct {
+		tag       uint16
+		fieldType uint16
+		count     uint32
+		value     uint32
+	}{
+		{256, 3, 1, 5},
+		{257, 3, 1, 5},
+		{258, 3, 1, 32},
+		{259, 3, 1, compression},
+		{273, 4, 1, stripOffset},
+		{277, 3, 1, 1},
+		{278, 3, 1, 5},
+		{279, 4, 1, uint32(len(strip))},
+		{339, 3, 1, 3},
+		{33550, 12, 3, dataOffset},
+		{33922, 12, 6, dataOffset + 24},
+		{34735, 3, 12, dataOffset + 72},
+	}
+	var content bytes.Buffer
+	content.WriteString("II")
+	_ = binary.Write(&content, order, []uint16{42})
+	_ = binary.Write(&content, order, []uint32{8})
+	_ = binary.Write(&content, order, []uint16{numEntries})
+	for _, entry := range entries {
+		_ = binary.Write(&content, order, []uint16{entry.tag, entry.fieldType})
+		_ = binary.Write(&content, order, []uint32{entry.count})
+		if entry.fieldType == 3 && entry.count == 1 {
+			_ = binary.Write(&content, order, []uint16{uint16(entry.value), 0})
+		} else {
+			_ = binary.Write(&content, order, []uint32{entry.value})
+		}
+	}
+	_ = binary.Write(&content, order, []uint32{0})
+	content.Write(data.Bytes())
+
+	gridPath := path.Join(t.TempDir(), "geoid.tif")
+	if err := ioutil.WriteFile(gridPath, content.Bytes(), 0644); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return gridPath
+}
+
+// Checks the height interpolated by a bilinear calculator loading the given grid, whose heights are linear
+func assertLinearGridHeight(t *testing.T, gridPath string, height func(lat, lon float64) float64) {
+	calculator, err := grid_offset_calculator.NewEllipsoidToGeoidGridOffsetCalculator(gridPath, grid_offset_calculator.Bilinear, &identityCoordinateConverter{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, point := range [][2]float64{{41.3, 12.6}, {40, 10}, {44, 14}} {
+		output, err := calculator.GetEllipsoidToGeoidOffset(point[0], point[1], 4326)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if expected := height(point[0], point[1]); math.Abs(expected-output) > 1e-4 {
+			t.Errorf("Lat %f lon %f: expected %.4f, got %.4f", point[0], point[1], expected, output)
+		}
+	}
+}
+
+func TestGridOffsetCalculatorNoaaBinGrid(t *testing.T) {
+	height := func(lat, lon float64) float64 { return 30 + 0.5*lat - 0.25*lon }
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		t.Run(order.String(), func(t *testing.T) {
+			assertLinearGridHeight(t, writeTestNoaaBinGrid(t, order, height), height)
+		})
+	}
+
+	// only float32 values are supported
+	gridPath := writeTestNoaaBinGrid(t, binary.LittleEndian, height)
+	content, _ := ioutil.ReadFile(gridPath)
+	binary.LittleEndian.PutUint32(content[40:], 2)
+	_ = ioutil.WriteFile(gridPath, content, 0644)
+	if _, err := grid_offset_calculator.NewEllipsoidToGeoidGridOffsetCalculator(gridPath, grid_offset_calculator.Bilinear, &identityCoordinateConverter{}); err == nil {
+		t.Errorf("Expected error for an unsupported kind of values")
+	}
+}
+
+func TestGridOffsetCalculatorGeoTiffGrid(t *testing.T) {
+	height := func(lat, lon float64) float64 { return 30 + 0.5*lat - 0.25*lon }
+	for _, deflate := range []bool{false, true} {
+		assertLinearGridHeight(t, writeTestGeoTiffGrid(t, deflate, height), height)
+	}
+
+	// truncated files are rejected
+	gridPath := writeTestGeoTiffGrid(t, false, height)
+	content, _ := ioutil.ReadFile(gridPath)
+	_ = ioutil.WriteFile(gridPath, content[:len(content)-10], 0644)
+	if _, err := grid_offset_calculator.NewEllipsoidToGeoidGridOffsetCalculator(gridPath, grid_offset_calculator.Bilinear, &identityCoordinateConverter{}); err == nil {
+		t.Errorf("Expected error for a truncated grid")
+	}
+}
+
+func TestGridOffsetCalculatorBilinear(t *testing.T) {
+	gridPath := writeTestGtxGrid(t, func(lat, lon float64) float64 { return 30 + 0.5*lat - 0.25*lon })
+	calculator, err := grid_offset_calculator.NewEllipsoidToGeoidGridOffsetCalculator(gridPath, grid_offset_calculator.Bilinear, &identityCoordinateConverter{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := 30 + 0.5*41.3 - 0.25*12.6
+	output, err := calculator.GetEllipsoidToGeoidOffset(41.3, 12.6, 4326)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if math.Abs(expected-output) > 1e-4 {
+		t.Errorf("Expected %.4f, got %.4f", expected, output)
+	}
+
+	if _, err := calculator.GetEllipsoidToGeoidOffset(45.5, 12, 4326); err == nil {
+		t.Errorf("Expected error for point outside of the grid")
+	}
+}
+
+func TestGridOffsetCalculatorBiquadratic(t *testing.T) {
+	gridPath := writeTestGtxGrid(t, func(lat, lon float64) float64 { return (lat - 40) * (lon - 10) * (lon - 10) })
+	calculator, err := grid_offset_calculator.NewEllipsoidToGeoidGridOffsetCalculator(gridPath, grid_offset_calculator.Biquadratic, &identityCoordinateConverter{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// quadratic heights are interpolated exactly
+	expected := 1.3 * 2.6 * 2.6
+	output, err := calculator.GetEllipsoidToGeoidOffset(41.3, 12.6, 4326)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if math.Abs(expected-output) > 1e-4 {
+		t.Errorf("Expected %.4f, got %.4f", expected, output)
+	}
+}
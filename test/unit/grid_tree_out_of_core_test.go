@@ -0,0 +1,157 @@
+package unit
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"testing"
+
+	"github.com/ecopia-map/cesium_tiler/internal/geometry"
+	"github.com/ecopia-map/cesium_tiler/internal/octree"
+	"github.com/ecopia-map/cesium_tiler/internal/octree/grid_tree"
+	"github.com/ecopia-map/cesium_tiler/internal/tiler"
+)
+
+const (
+	outOfCoreTestMaxPointsNum = 400
+	outOfCoreTestMinPointsNum = 50
+)
+
+// Builds a tree with a single loading goroutine, for the points to be sampled in the same order by the in memory
+// and the out of core builds, holding a fixed cloud of random points. The out of core build is enabled with the
+// given spill folder and memory budget if the budget is positive
+func newOutOfCoreTestTree(t *testing.T, spillFolder string, memoryBudget int64) *grid_tree.GridTree {
+	tree := grid_tree.NewGridTree(
+		&mockCoordinateConverter{},
+		&mockElevationCorrector{},
+		20.0,
+		1.0,
+		tiler.SamplingClosest,
+	)
+	tree.SetNumWorkers(1)
+	if memoryBudget > 0 {
+		if err := tree.EnableOutOfCore(spillFolder, memoryBudget); err != nil {
+			t.Fatalf("Unexpected error enabling the out of core build: %s", err)
+		}
+	}
+
+	random := rand.New(rand.NewSource(42))
+	for i := 0; i < 5000; i++ {
+		coord := &geometry.Coordinate{
+			X: random.Float64() * 200,
+			Y: random.Float64() * 200,
+			Z: random.Float64() * 20,
+		}
+		if err := tree.AddPoint(coord, 1, 2, 3, 4, 5, 4326, nil); err != nil {
+			t.Fatalf("Unexpected error adding point: %v", err)
+		}
+	}
+
+	return tree
+}
+
+// Returns the path of the node from the root, made of the children paths of its ancestors
+func outOfCoreTestNodePath(t *testing.T, node octree.INode) string {
+	parent := node.GetParent()
+	if parent == nil {
+		return "root"
+	}
+	children := parent.GetChildren()
+	for i := range children {
+		if children[i] == node {
+			return fmt.Sprintf("%s/%s", outOfCoreTestNodePath(t, parent), parent.GetChildrenPath()[i])
+		}
+	}
+	t.Fatalf("Node not found among the children of its parent")
+	return ""
+}
+
+// Collects the number of points of the given node and of its descendants holding points, by node path
+func collectNodePoints(t *testing.T, node octree.INode, pointsByPath map[string]int32) {
+	if node.NumberOfPoints() > 0 {
+		pointsByPath[outOfCoreTestNodePath(t, node)] = node.NumberOfPoints()
+	}
+	for _, child := range node.GetChildren() {
+		if child != nil {
+			collectNodePoints(t, child, pointsByPath)
+		}
+	}
+}
+
+func TestTreeBuildOutOfCoreMatchesInMemoryBuild(t *testing.T) {
+	inMemoryTree := newOutOfCoreTestTree(t, "", 0)
+	if err := inMemoryTree.Build(); err != nil {
+		t.Fatalf("Unexpected error occurred while building the tree: %s", err)
+	}
+	if err := inMemoryTree.SplitBigNode(outOfCoreTestMaxPointsNum); err != nil {
+		t.Fatalf("Unexpected error occurred while splitting the tree: %s", err)
+	}
+	if err := inMemoryTree.MergeSmallNode(outOfCoreTestMinPointsNum); err != nil {
+		t.Fatalf("Unexpected error occurred while merging the tree: %s", err)
+	}
+	expected := make(map[string]int32)
+	collectNodePoints(t, inMemoryTree.GetRootNode(), expected)
+
+	// a budget of 300 points forces several levels of the tree to be spilled to disk
+	outOfCoreTree := newOutOfCoreTestTree(t, t.TempDir(), 300*160)
+	actual := make(map[string]int32)
+	var numExported int64
+	err := outOfCoreTree.BuildOutOfCore(outOfCoreTestMaxPointsNum, outOfCoreTestMinPointsNum, func(nodes []*grid_tree.GridNode) error {
+		for _, node := range nodes {
+			path := outOfCoreTestNodePath(t, node)
+			if _, ok := actual[path]; ok {
+				t.Errorf("Node %s exported twice", path)
+			}
+			if len(node.GetPoints()) != int(node.NumberOfPoints()) {
+				t.Errorf("Node %s exported with %d points, expected %d", path, len(node.GetPoints()), node.NumberOfPoints())
+			}
+			actual[path] = node.NumberOfPoints()
+			numExported += int64(node.NumberOfPoints())
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error occurred while building the tree out of core: %s", err)
+	}
+
+	if len(expected) < 10 {
+		t.Fatalf("Expected the test cloud to produce a deep tree, got %d nodes", len(expected))
+	}
+	if len(actual) != len(expected) {
+		t.Errorf("Expected %d nodes, got %d", len(expected), len(actual))
+	}
+	for path, numPoints := range expected {
+		if actual[path] != numPoints {
+			t.Errorf("Node %s: expected %d points, got %d", path, numPoints, actual[path])
+		}
+	}
+	if numExported != 5000 {
+		t.Errorf("Expected 5000 exported points, got %d", numExported)
+	}
+	if outOfCoreTree.GetRootNode().TotalNumberOfPoints() != 5000 {
+		t.Errorf("Expected 5000 points in the tree, got %d", outOfCoreTree.GetRootNode().TotalNumberOfPoints())
+	}
+}
+
+func TestTreeBuildOutOfCoreErrorRemovesSpillFiles(t *testing.T) {
+	spillFolder := t.TempDir()
+	tree := newOutOfCoreTestTree(t, spillFolder, 300*160)
+
+	// the first subtree exported fails while the buckets of its ancestors still hold the points of its siblings
+	exportErr := errors.New("export failed")
+	err := tree.BuildOutOfCore(outOfCoreTestMaxPointsNum, outOfCoreTestMinPointsNum, func(nodes []*grid_tree.GridNode) error {
+		return exportErr
+	})
+	if err != exportErr {
+		t.Fatalf("Expected the export error, got %v", err)
+	}
+
+	files, err := ioutil.ReadDir(spillFolder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		t.Errorf("Spill file %s not removed", file.Name())
+	}
+}
@@ -1,8 +1,11 @@
 package unit
 
 import (
+	"github.com/ecopia-map/cesium_tiler/internal/data"
 	"github.com/ecopia-map/cesium_tiler/internal/geometry"
 	"github.com/ecopia-map/cesium_tiler/internal/octree/grid_tree"
+	"github.com/ecopia-map/cesium_tiler/internal/tiler"
+	"math"
 	"testing"
 )
 
@@ -12,6 +15,10 @@ func (m *mockCoordinateConverter) ConvertCoordinateSrid(sourceSrid int, targetSr
 	return coord, nil
 }
 
+func (m *mockCoordinateConverter) ConvertCoordinatesSrid(sourceSrid int, targetSrid int, coords []geometry.Coordinate) error {
+	return nil
+}
+
 func (m *mockCoordinateConverter) Convert2DBoundingboxToWGS84Region(bbox *geometry.BoundingBox, srid int) (*geometry.BoundingBox, error) {
 	return bbox, nil
 }
@@ -20,6 +27,10 @@ func (m *mockCoordinateConverter) ConvertToWGS84Cartesian(coord geometry.Coordin
 	return coord, nil
 }
 
+func (m *mockCoordinateConverter) ConvertToWGS84CartesianBatch(coords []geometry.Coordinate, sourceSrid int) error {
+	return nil
+}
+
 func (m *mockCoordinateConverter) Cleanup() {}
 
 func TestTreeAddPointSuccess(t *testing.T) {
@@ -28,6 +39,7 @@ func TestTreeAddPointSuccess(t *testing.T) {
 		&mockElevationCorrector{},
 		5.0,
 		0.1,
+		tiler.SamplingClosest,
 	)
 
 	x := 14.0
@@ -36,7 +48,7 @@ func TestTreeAddPointSuccess(t *testing.T) {
 	r := uint8(4)
 	g := uint8(5)
 	b := uint8(6)
-	i := uint8(7)
+	i := uint16(7)
 	c := uint8(8)
 
 	coord := &geometry.Coordinate{
@@ -45,9 +57,9 @@ func TestTreeAddPointSuccess(t *testing.T) {
 		Z: z,
 	}
 
-	tree.AddPoint(coord, r, g, b, i, c, 4326)
+	tree.AddPoint(coord, r, g, b, i, c, 4326, nil)
 
-	point, hasMore := tree.(*grid_tree.GridTree).Loader.GetNext()
+	point, hasMore := tree.Loader.GetNext()
 
 	if hasMore == true {
 		t.Errorf("Only one point loaded, GetNext should return false")
@@ -66,6 +78,7 @@ func TestTreeBuildSuccess(t *testing.T) {
 		&mockElevationCorrector{},
 		5.0,
 		0.1,
+		tiler.SamplingClosest,
 	)
 
 	x := 14.0
@@ -74,7 +87,7 @@ func TestTreeBuildSuccess(t *testing.T) {
 	r := uint8(4)
 	g := uint8(5)
 	b := uint8(6)
-	i := uint8(7)
+	i := uint16(7)
 	c := uint8(8)
 
 	coord := &geometry.Coordinate{
@@ -83,7 +96,7 @@ func TestTreeBuildSuccess(t *testing.T) {
 		Z: z,
 	}
 
-	tree.AddPoint(coord, r, g, b, i, c, 4326)
+	tree.AddPoint(coord, r, g, b, i, c, 4326, nil)
 
 	err := tree.Build()
 
@@ -106,6 +119,7 @@ func TestGetRootNode(t *testing.T) {
 		&mockElevationCorrector{},
 		5.0,
 		0.1,
+		tiler.SamplingClosest,
 	)
 
 	x := 14.0
@@ -114,7 +128,7 @@ func TestGetRootNode(t *testing.T) {
 	r := uint8(4)
 	g := uint8(5)
 	b := uint8(6)
-	i := uint8(7)
+	i := uint16(7)
 	c := uint8(8)
 
 	coord := &geometry.Coordinate{
@@ -123,7 +137,7 @@ func TestGetRootNode(t *testing.T) {
 		Z: z,
 	}
 
-	tree.AddPoint(coord, r, g, b, i, c, 4326)
+	tree.AddPoint(coord, r, g, b, i, c, 4326, nil)
 
 	err := tree.Build()
 
@@ -142,5 +156,164 @@ func TestGetRootNode(t *testing.T) {
 	}
 }
 
+// Builds a tree with a single loading goroutine, for the points to be sampled in the order they are given, and
+// returns it. The points fall in the same root cell of 10 meters, the mock elevation corrector doubling their Z
+func buildSamplingTestTree(t *testing.T, sampling tiler.SamplingStrategy, coords []geometry.Coordinate) *grid_tree.GridTree {
+	tree := grid_tree.NewGridTree(
+		&mockCoordinateConverter{},
+		&mockElevationCorrector{},
+		10.0,
+		1.0,
+		sampling,
+	)
+	tree.SetNumWorkers(1)
+
+	for i := range coords {
+		value := uint8(10 * (i + 1))
+		if err := tree.AddPoint(&coords[i], value, value, value, uint16(100*(i+1)), 2, 4326, nil); err != nil {
+			t.Fatalf("Unexpected error adding point: %v", err)
+		}
+	}
+
+	if err := tree.Build(); err != nil {
+		t.Fatalf("Unexpected error occurred while building the tree: %s", err)
+	}
+
+	if tree.GetRootNode().TotalNumberOfPoints() != int64(len(coords)) {
+		t.Errorf("Expected %d points in the tree, got %d", len(coords), tree.GetRootNode().TotalNumberOfPoints())
+	}
+
+	return tree
+}
+
+var samplingTestCoords = []geometry.Coordinate{
+	{X: 1, Y: 1, Z: 1},
+	{X: 5, Y: 5, Z: 2.5},
+	{X: 9, Y: 9, Z: 4},
+}
+
+func assertSingleRootPoint(t *testing.T, tree *grid_tree.GridTree, x float64, y float64, z float64) *data.Point {
+	points := tree.GetRootNode().GetPoints()
+	if len(points) != 1 {
+		t.Fatalf("Expected one point in the root node, got %d", len(points))
+	}
+
+	if points[0].X != x || points[0].Y != y || points[0].Z != z {
+		t.Errorf("Expected root point at %f %f %f, got %f %f %f", x, y, z, points[0].X, points[0].Y, points[0].Z)
+	}
+
+	return points[0]
+}
+
+func TestTreeSamplingClosest(t *testing.T) {
+	tree := buildSamplingTestTree(t, tiler.SamplingClosest, samplingTestCoords)
+	assertSingleRootPoint(t, tree, 5, 5, 5)
+}
+
+func TestTreeSamplingHighest(t *testing.T) {
+	tree := buildSamplingTestTree(t, tiler.SamplingHighest, samplingTestCoords)
+	assertSingleRootPoint(t, tree, 9, 9, 8)
+}
+
+func TestTreeSamplingLowest(t *testing.T) {
+	tree := buildSamplingTestTree(t, tiler.SamplingLowest, samplingTestCoords)
+	assertSingleRootPoint(t, tree, 1, 1, 2)
+}
+
+func TestTreeSamplingCentroid(t *testing.T) {
+	coords := []geometry.Coordinate{
+		{X: 1, Y: 2, Z: 1},
+		{X: 5, Y: 5, Z: 2.5},
+		{X: 6, Y: 8, Z: 0.5},
+	}
+	tree := buildSamplingTestTree(t, tiler.SamplingCentroid, coords)
+
+	point := assertSingleRootPoint(t, tree, 4, 5, 8.0/3)
+	if point.R != 20 || point.G != 20 || point.B != 20 || point.Intensity != 200 {
+		t.Errorf("Expected mean color 20 and intensity 200, got %d %d %d and %d", point.R, point.G, point.B, point.Intensity)
+	}
+
+	// the centroid is a copy of the point closest to the cell center, the points pushed to the children keep their coordinates
+	for _, child := range tree.GetRootNode().GetChildren() {
+		if child == nil {
+			continue
+		}
+		for _, childPoint := range child.GetPoints() {
+			if childPoint.X == 4 {
+				t.Errorf("Unexpected centroid point in the children")
+			}
+		}
+	}
+}
+
+func TestTreeSamplingRandom(t *testing.T) {
+	tree := buildSamplingTestTree(t, tiler.SamplingRandom, samplingTestCoords)
+
+	points := tree.GetRootNode().GetPoints()
+	if len(points) != 1 {
+		t.Fatalf("Expected one point in the root node, got %d", len(points))
+	}
+
+	found := false
+	for _, coord := range samplingTestCoords {
+		if points[0].X == coord.X && points[0].Y == coord.Y && points[0].Z == coord.Z*2 {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Root point %f %f %f is not one of the points added", points[0].X, points[0].Y, points[0].Z)
+	}
+}
+
+func TestTreeSamplingMinDistance(t *testing.T) {
+	coords := []geometry.Coordinate{
+		{X: 1, Y: 1, Z: 1},
+		{X: 5, Y: 1, Z: 1},
+		{X: 12, Y: 1, Z: 1},
+		{X: 21, Y: 1, Z: 1},
+	}
+	tree := buildSamplingTestTree(t, tiler.SamplingMinDistance, coords)
+
+	// the second and the last points are closer than the cell size to the points kept before them
+	points := tree.GetRootNode().GetPoints()
+	if len(points) != 2 {
+		t.Fatalf("Expected two points in the root node, got %d", len(points))
+	}
+	if math.Abs(points[0].X-points[1].X) != 11 {
+		t.Errorf("Expected the root points at X 1 and 12, got %f and %f", points[0].X, points[1].X)
+	}
+}
+
 // TODO add test to evaluate safety against race conditions while adding points,
 //  especially check against gridCell being correctly write locked when points slice is edited
+
+func TestTreeAddPointsConvertsPointsInPlace(t *testing.T) {
+	tree := grid_tree.NewGridTree(
+		&mockCoordinateConverter{},
+		&mockElevationCorrector{},
+		10.0,
+		1.0,
+		tiler.SamplingClosest,
+	)
+
+	points := []*data.Point{
+		data.NewPoint(1, 1, 1, 10, 10, 10, 100, 2, nil),
+		data.NewPoint(9, 9, 4, 20, 20, 20, 200, 2, nil),
+	}
+	if err := tree.AddPoints(points, 4326); err != nil {
+		t.Fatalf("Unexpected error adding points: %v", err)
+	}
+
+	// the mock elevation corrector doubles Z
+	if points[0].Z != 2 || points[1].Z != 8 {
+		t.Errorf("Expected the Z of the points to be corrected to 2 and 8, got %f and %f", points[0].Z, points[1].Z)
+	}
+
+	if err := tree.Build(); err != nil {
+		t.Fatalf("Unexpected error occurred while building the tree: %s", err)
+	}
+
+	if tree.GetRootNode().TotalNumberOfPoints() != int64(len(points)) {
+		t.Errorf("Expected %d points in the tree, got %d", len(points), tree.GetRootNode().TotalNumberOfPoints())
+	}
+}
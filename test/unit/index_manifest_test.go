@@ -0,0 +1,142 @@
+package unit_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ecopia-map/cesium_tiler/pkg"
+)
+
+// Writes the given content to the given file, setting its modification time
+func writeManifestTestFile(t *testing.T, filePath string, content string, modTime time.Time) {
+	if err := ioutil.WriteFile(filePath, []byte(content), 0666); err != nil {
+		t.Fatalf("Unexpected error writing %s: %s", filePath, err)
+	}
+	if err := os.Chtimes(filePath, modTime, modTime); err != nil {
+		t.Fatalf("Unexpected error setting the modification time of %s: %s", filePath, err)
+	}
+}
+
+// Returns the manifest entry of the given file, as recorded once its chunk tileset is complete
+func completedManifestEntry(t *testing.T, filePath string) *pkg.IndexManifestEntry {
+	entry, err := pkg.NewIndexManifestEntry(filePath, "chunk")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if err := entry.SetHash(); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	entry.Status = pkg.ChunkStatusCompleted
+	return entry
+}
+
+func TestIndexManifestEntryStoresAbsolutePath(t *testing.T) {
+	folder := t.TempDir()
+	writeManifestTestFile(t, filepath.Join(folder, "a.las"), "points", time.Now())
+
+	workingDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	defer os.Chdir(workingDir)
+	if err := os.Chdir(folder); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	entry, err := pkg.NewIndexManifestEntry("a.las", "chunk")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !filepath.IsAbs(entry.Path) || filepath.Base(entry.Path) != "a.las" {
+		t.Errorf("Expected an absolute path, got %s", entry.Path)
+	}
+	if entry.Hash != "" {
+		t.Errorf("Expected the file not to be hashed")
+	}
+}
+
+func TestIndexManifestEntryIsCompletedFor(t *testing.T) {
+	folder := t.TempDir()
+	filePath := filepath.Join(folder, "a.las")
+	modTime := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	writeManifestTestFile(t, filePath, "points", modTime)
+	previous := completedManifestEntry(t, filePath)
+
+	current, err := pkg.NewIndexManifestEntry(filePath, "chunk")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if completed, err := previous.IsCompletedFor(current); err != nil || !completed {
+		t.Errorf("Expected the unchanged file to be completed, got %t %v", completed, err)
+	}
+
+	// same size and modification time, only the hash tells the content apart
+	writeManifestTestFile(t, filePath, "pOints", modTime)
+	current, _ = pkg.NewIndexManifestEntry(filePath, "chunk")
+	if completed, err := previous.IsCompletedFor(current); err != nil || completed {
+		t.Errorf("Expected the rewritten file not to be completed, got %t %v", completed, err)
+	}
+
+	// a different size is enough, the file is not hashed
+	writeManifestTestFile(t, filePath, "more points", modTime)
+	current, _ = pkg.NewIndexManifestEntry(filePath, "chunk")
+	if completed, err := previous.IsCompletedFor(current); err != nil || completed {
+		t.Errorf("Expected the grown file not to be completed, got %t %v", completed, err)
+	}
+	if current.Hash != "" {
+		t.Errorf("Expected the grown file not to be hashed")
+	}
+
+	previous.Status = pkg.ChunkStatusInProgress
+	writeManifestTestFile(t, filePath, "points", modTime)
+	current, _ = pkg.NewIndexManifestEntry(filePath, "chunk")
+	if completed, _ := previous.IsCompletedFor(current); completed {
+		t.Errorf("Expected an entry in progress not to be completed")
+	}
+}
+
+func TestLoadIndexManifestResolvesRelativePaths(t *testing.T) {
+	workingFolder := t.TempDir()
+	outputFolder := filepath.Join(workingFolder, "tileset")
+	if err := os.MkdirAll(filepath.Join(outputFolder, "las"), 0777); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	writeManifestTestFile(t, filepath.Join(workingFolder, "a.las"), "points", time.Now())
+	writeManifestTestFile(t, filepath.Join(outputFolder, "las", "b.las"), "points", time.Now())
+
+	// manifests written by older versions store the paths given to the index run
+	manifest := `{"files":[{"path":"a.las","subfolder":"a"},{"path":"las/b.las","subfolder":"b"},{"path":"c.las","subfolder":"c"}]}`
+	if err := ioutil.WriteFile(filepath.Join(outputFolder, pkg.IndexManifestFileName), []byte(manifest), 0666); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	workingDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	defer os.Chdir(workingDir)
+	if err := os.Chdir(workingFolder); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	loaded, err := pkg.LoadIndexManifest(outputFolder)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	// resolved from the current folder, then from the output folder, a missing file from the current folder
+	for i, expected := range []string{
+		filepath.Join(workingFolder, "a.las"),
+		filepath.Join(outputFolder, "las", "b.las"),
+		filepath.Join(workingFolder, "c.las"),
+	} {
+		// the current folder may be returned with its symbolic links resolved
+		actualFolder, _ := filepath.EvalSymlinks(filepath.Dir(loaded.Files[i].Path))
+		expectedFolder, _ := filepath.EvalSymlinks(filepath.Dir(expected))
+		if actualFolder != expectedFolder || filepath.Base(loaded.Files[i].Path) != filepath.Base(expected) {
+			t.Errorf("Expected the path %s, got %s", expected, loaded.Files[i].Path)
+		}
+	}
+}
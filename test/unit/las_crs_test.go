@@ -0,0 +1,98 @@
+package unit_test
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"path"
+	"testing"
+
+	lidario "github.com/ecopia-map/cesium_tiler/third_party/lasread"
+)
+
+const (
+	lasTestHeaderSize  = 375
+	lasTestWktEncoding = 16
+	lasTestUtm33nWkt   = `PROJCS["WGS 84 / UTM zone 33N",GEOGCS["WGS 84",DATUM["WGS_1984",SPHEROID["WGS 84",6378137,298.257223563]],PRIMEM["Greenwich",0],UNIT["degree",0.0174532925199433]],PROJECTION["Transverse_Mercator"],UNIT["metre",1],AUTHORITY["EPSG","32633"]]`
+)
+
+func TestSridIsReadFromWktVlr(t *testing.T) {
+	las := openTestLasWithWkt(t, lasTestUtm33nWkt, false)
+	defer las.Close()
+
+	srid, err := las.Srid()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if srid != 32633 {
+		t.Errorf("Expected srid 32633, got %d", srid)
+	}
+}
+
+func TestSridIsReadFromWktEvlr(t *testing.T) {
+	las := openTestLasWithWkt(t, lasTestUtm33nWkt, true)
+	defer las.Close()
+
+	if len(las.VlrData) != 0 || len(las.EvlrData) != 1 {
+		t.Fatalf("Expected the WKT in the only EVLR, got %d VLRs and %d EVLRs", len(las.VlrData), len(las.EvlrData))
+	}
+	srid, err := las.Srid()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if srid != 32633 {
+		t.Errorf("Expected srid 32633, got %d", srid)
+	}
+}
+
+func TestSridWithoutEpsgCodeInWktEvlrReturnsError(t *testing.T) {
+	las := openTestLasWithWkt(t, `LOCAL_CS["site grid",UNIT["metre",1]]`, true)
+	defer las.Close()
+
+	if _, err := las.Srid(); err == nil {
+		t.Errorf("Expected error for a WKT without EPSG code")
+	}
+}
+
+// openTestLasWithWkt writes a LAS 1.4 file without points whose coordinate reference system is the given WKT,
+// stored either in a VLR or in an EVLR, and opens its header
+func openTestLasWithWkt(t *testing.T, wkt string, inEvlr bool) *lidario.LasFile {
+	t.Helper()
+	record := append([]byte(wkt), 0)
+	header := make([]byte, lasTestHeaderSize)
+	copy(header[0:4], "LASF")
+	binary.LittleEndian.PutUint16(header[6:8], lasTestWktEncoding)
+	header[24], header[25] = 1, 4
+	binary.LittleEndian.PutUint16(header[94:96], lasTestHeaderSize)
+	header[104] = 6
+	binary.LittleEndian.PutUint16(header[105:107], 30)
+	for i := 0; i < 3; i++ {
+		binary.LittleEndian.PutUint64(header[131+8*i:], 0x3f847ae147ae147b) // 0.01 scale factors
+	}
+
+	var vlrs []byte
+	if inEvlr {
+		binary.LittleEndian.PutUint32(header[96:100], lasTestHeaderSize)
+		binary.LittleEndian.PutUint64(header[235:243], lasTestHeaderSize)
+		binary.LittleEndian.PutUint32(header[243:247], 1)
+		vlrs = make([]byte, 60)
+		binary.LittleEndian.PutUint64(vlrs[20:28], uint64(len(record)))
+	} else {
+		binary.LittleEndian.PutUint32(header[96:100], uint32(lasTestHeaderSize+54+len(record)))
+		binary.LittleEndian.PutUint32(header[100:104], 1)
+		vlrs = make([]byte, 54)
+		binary.LittleEndian.PutUint16(vlrs[20:22], uint16(len(record)))
+	}
+	copy(vlrs[2:18], "LASF_Projection")
+	binary.LittleEndian.PutUint16(vlrs[18:20], 2112)
+	vlrs = append(vlrs, record...)
+
+	fileName := path.Join(t.TempDir(), "wkt.las")
+	if err := ioutil.WriteFile(fileName, append(header, vlrs...), 0644); err != nil {
+		t.Fatal(err)
+	}
+	las, err := lidario.NewLasFile(fileName, "rh")
+	if err != nil {
+		t.Fatalf("Unable to open %s: %v", fileName, err)
+	}
+	return las
+}
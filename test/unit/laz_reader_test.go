@@ -0,0 +1,181 @@
+package unit_test
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ecopia-map/cesium_tiler/internal/data"
+	"github.com/ecopia-map/cesium_tiler/internal/geometry"
+	"github.com/ecopia-map/cesium_tiler/internal/octree"
+	lidario "github.com/ecopia-map/cesium_tiler/third_party/lasread"
+)
+
+// The LAZ fixtures are compressed with the LASzip v2 pointwise compressors, each one has an uncompressed LAS twin
+// holding the same point records
+const lazTestDataFolder = "testdata/laz"
+
+// pointRecorderTree is an octree.ITree that only stores the points added to it
+type pointRecorderTree struct {
+	sync.Mutex
+	points []*data.Point
+}
+
+func (t *pointRecorderTree) Build() error                 { return nil }
+func (t *pointRecorderTree) GetRootNode() octree.INode    { return nil }
+func (t *pointRecorderTree) IsBuilt() bool                { return false }
+func (t *pointRecorderTree) Clear() bool                  { return true }
+func (t *pointRecorderTree) SetNumWorkers(numWorkers int) {}
+
+func (t *pointRecorderTree) AddPoint(coordinate *geometry.Coordinate, r uint8, g uint8, b uint8, intensity uint16, classification uint8, srid int, pointExtend *data.PointExtend) error {
+	return t.AddPoints([]*data.Point{data.NewPoint(coordinate.X, coordinate.Y, coordinate.Z, r, g, b, intensity, classification, pointExtend)}, srid)
+}
+
+func (t *pointRecorderTree) AddPoints(points []*data.Point, srid int) error {
+	t.Lock()
+	defer t.Unlock()
+	for _, p := range points {
+		copied := *p
+		t.points = append(t.points, &copied)
+	}
+	return nil
+}
+
+func TestLazFileMatchesLasTwin(t *testing.T) {
+	for _, name := range []string{
+		// pointwise chunked compressor with a single chunk
+		"format1",
+		// pointwise compressor without chunk table
+		"format2",
+		"format3",
+		// chunks of 50 points, the last one partially filled
+		"format3_chunks",
+		// chunks of variable size, one of them with a single point
+		"format3_variable_chunks",
+		// extra bytes compressed with the BYTE item
+		"format0_extra_bytes",
+	} {
+		t.Run(name, func(t *testing.T) {
+			laz, err := lidario.NewLasFile(filepath.Join(lazTestDataFolder, name+".laz"), "r")
+			if err != nil {
+				t.Fatalf("Unexpected error occurred reading laz file: %s", err.Error())
+			}
+			defer laz.Close()
+			las, err := lidario.NewLasFile(filepath.Join(lazTestDataFolder, name+".las"), "r")
+			if err != nil {
+				t.Fatalf("Unexpected error occurred reading las file: %s", err.Error())
+			}
+			defer las.Close()
+
+			if laz.Header.NumberPoints != las.Header.NumberPoints || laz.Header.NumberPoints == 0 {
+				t.Fatalf("Expected %d points, got %d", las.Header.NumberPoints, laz.Header.NumberPoints)
+			}
+			if laz.Header.PointFormatID != las.Header.PointFormatID {
+				t.Fatalf("Expected point format %d, got %d", las.Header.PointFormatID, laz.Header.PointFormatID)
+			}
+
+			for i := 0; i < las.Header.NumberPoints; i++ {
+				expected, err := las.LasPoint(i)
+				if err != nil {
+					t.Fatalf("Unexpected error occurred: %s", err.Error())
+				}
+				actual, err := laz.LasPoint(i)
+				if err != nil {
+					t.Fatalf("Unexpected error occurred: %s", err.Error())
+				}
+				if *actual.PointData() != *expected.PointData() {
+					t.Fatalf("Point %d: expected %+v, got %+v", i, *expected.PointData(), *actual.PointData())
+				}
+				if actual.GpsTimeData() != expected.GpsTimeData() {
+					t.Fatalf("Point %d: expected gps time %f, got %f", i, expected.GpsTimeData(), actual.GpsTimeData())
+				}
+				if expected.RgbData() != nil && *actual.RgbData() != *expected.RgbData() {
+					t.Fatalf("Point %d: expected rgb %+v, got %+v", i, *expected.RgbData(), *actual.RgbData())
+				}
+			}
+		})
+	}
+}
+
+func TestLazFileExtraBytesMatchLasTwin(t *testing.T) {
+	attributes := []string{"quality", "height", "deviation"}
+	load := func(fileName string) []*data.Point {
+		tree := &pointRecorderTree{}
+		loader := lidario.NewLasFileLoader(tree)
+		loader.ExtraBytes = attributes
+		if _, err := loader.LoadLasFile(context.Background(), filepath.Join(lazTestDataFolder, fileName), 32633, false); err != nil {
+			t.Fatalf("Unexpected error occurred loading %s: %s", fileName, err.Error())
+		}
+		byIndex := make([]*data.Point, len(tree.points))
+		for _, p := range tree.points {
+			byIndex[p.PointExtend.LasPointIndex] = p
+		}
+		return byIndex
+	}
+
+	expected := load("format0_extra_bytes.las")
+	actual := load("format0_extra_bytes.laz")
+	if len(actual) != len(expected) {
+		t.Fatalf("Expected %d points, got %d", len(expected), len(actual))
+	}
+
+	for i := range expected {
+		for j := range attributes {
+			if actual[i].PointExtend.ExtraBytes[j] != expected[i].PointExtend.ExtraBytes[j] {
+				t.Fatalf(
+					"Point %d: expected %s %f, got %f",
+					i,
+					attributes[j],
+					expected[i].PointExtend.ExtraBytes[j],
+					actual[i].PointExtend.ExtraBytes[j],
+				)
+			}
+		}
+	}
+}
+
+func TestLazFileStreamedMatchesLasTwin(t *testing.T) {
+	load := func(fileName string, streamPoints bool) []*data.Point {
+		tree := &pointRecorderTree{}
+		loader := lidario.NewLasFileLoader(tree)
+		loader.StreamPoints = streamPoints
+		if _, err := loader.LoadLasFile(context.Background(), filepath.Join(lazTestDataFolder, fileName), 32633, false); err != nil {
+			t.Fatalf("Unexpected error occurred loading %s: %s", fileName, err.Error())
+		}
+		byIndex := make([]*data.Point, len(tree.points))
+		for _, p := range tree.points {
+			byIndex[p.PointExtend.LasPointIndex] = p
+		}
+		return byIndex
+	}
+
+	for _, name := range []string{"format2", "format3_chunks", "format3_variable_chunks"} {
+		t.Run(name, func(t *testing.T) {
+			expected := load(name+".las", false)
+			actual := load(name+".laz", true)
+			if len(actual) != len(expected) {
+				t.Fatalf("Expected %d points, got %d", len(expected), len(actual))
+			}
+			for i := range expected {
+				if !reflect.DeepEqual(actual[i], expected[i]) {
+					t.Fatalf("Point %d: expected %+v, got %+v", i, expected[i], actual[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLazLayeredCompressionIsRejected(t *testing.T) {
+	_, err := lidario.NewLasFile(filepath.Join(lazTestDataFolder, "format6_layered.laz"), "r")
+	if err == nil {
+		t.Fatal("Error was expected reading a laz file with point format 6 but none was returned")
+	}
+	for _, expected := range []string{"point format 6", "point formats 6-10", "laszip"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected layered compression error containing %q, got: %s", expected, err.Error())
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/ecopia-map/cesium_tiler/internal/geometry"
 	"github.com/ecopia-map/cesium_tiler/internal/octree"
 	"github.com/ecopia-map/cesium_tiler/internal/tiler"
+	"strconv"
 	"sync"
 )
 
@@ -27,8 +28,6 @@ type mockNode struct {
 	sync.RWMutex
 }
 
-func (mockNode *mockNode) AddDataPoint(element *data.Point) {}
-
 func (mockNode *mockNode) IsRoot() bool {
 	return mockNode.parent == nil
 }
@@ -51,6 +50,18 @@ func (mockNode *mockNode) GetChildren() [8]octree.INode {
 	return mockNode.children
 }
 
+func (mockNode *mockNode) GetChildrenPath() [8]string {
+	var paths [8]string
+	for i := range paths {
+		paths[i] = strconv.Itoa(i)
+	}
+	return paths
+}
+
+func (mockNode *mockNode) GetOctreeChildren() [8]octree.INode {
+	return mockNode.children
+}
+
 func (mockNode *mockNode) GetPoints() []*data.Point {
 	return mockNode.points
 }
@@ -75,7 +86,15 @@ func (mockNode *mockNode) IsLeaf() bool {
 	return mockNode.leaf
 }
 
-func (mockNode *mockNode) IsInitialized() bool {
+func (mockNode *mockNode) AppendParentPoints(points []*data.Point) []*data.Point {
+	return octree.AppendParentPoints(mockNode, points)
+}
+
+func (mockNode *mockNode) GetTightBoundingVolume(converter converters.CoordinateConverter, includeParentPoints bool) (*octree.NodeBoundingVolume, error) {
+	return octree.ComputeTightBoundingVolume(mockNode, converter, includeParentPoints)
+}
+
+func (mockNode *mockNode) IsChildrenInitialized() bool {
 	return mockNode.initialized
 }
 
@@ -0,0 +1,221 @@
+package unit
+
+import (
+	"github.com/ecopia-map/cesium_tiler/internal/converters"
+	"github.com/ecopia-map/cesium_tiler/internal/converters/coordinate/native_coordinate_converter"
+	"github.com/ecopia-map/cesium_tiler/internal/geometry"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Returns the native converter of the EPSG codes of the assets folder
+func newTestNativeCoordinateConverter(t *testing.T) converters.CoordinateConverter {
+	converter, err := native_coordinate_converter.NewNativeCoordinateConverter()
+	if err != nil {
+		t.Fatalf("Unexpected error loading the native converter: %s", err)
+	}
+	return converter
+}
+
+func TestNativeConverterMatchesProj4(t *testing.T) {
+	nativeCoordinateConverter := newTestNativeCoordinateConverter(t)
+	proj4Converter := newTestProj4CoordinateConverter(t)
+	defer proj4Converter.Cleanup()
+
+	var testData = []struct {
+		X           float64
+		Y           float64
+		Z           float64
+		inEpsgCode  int
+		outEpsgCode int
+		tolerance   float64
+	}{
+		// utm
+		{491880.85, 4576930.54, 10.0, 32633, 4326, 1E-9},
+		{491880.85, 4576930.54, 10.0, 32633, 4978, 1E-6},
+		{14.902954, 41.343825, 10.0, 4326, 32733, 1E-6},
+		// lambert conformal conic on GRS80
+		{652469.02, 6862035.26, 35.0, 2154, 4326, 1E-9},
+		// lambert conformal conic in us survey feet
+		{987654.32, 210987.65, 100.0, 2263, 4326, 1E-9},
+		// world mercator and web mercator
+		{1658911.0, 5035621.0, 0.0, 3395, 4326, 1E-9},
+		{1658911.0, 5035621.0, 0.0, 3857, 4326, 1E-9},
+		{15.309277, 41.363327, 0.0, 4326, 3857, 1E-6},
+		// transverse mercator with a Helmert datum shift
+		{530000.0, 180000.0, 50.0, 27700, 4326, 1E-9},
+		{530000.0, 180000.0, 50.0, 27700, 4978, 1E-6},
+		{3500000.0, 5400000.0, 100.0, 31467, 4326, 1E-9},
+		// geocentric to geographic
+		{4623905.13, 1265762.04, 4192791.72, 4978, 4326, 1E-9},
+		{4623905.13, 1265762.04, 4192791.72, 4978, 32633, 1E-6},
+	}
+
+	for _, data := range testData {
+		coord := geometry.Coordinate{X: data.X, Y: data.Y, Z: data.Z}
+
+		expected, err := proj4Converter.ConvertCoordinateSrid(data.inEpsgCode, data.outEpsgCode, coord)
+		if err != nil {
+			t.Fatalf("Unexpected proj4 error occurred: %s", err.Error())
+		}
+
+		output, err := nativeCoordinateConverter.ConvertCoordinateSrid(data.inEpsgCode, data.outEpsgCode, coord)
+		if err != nil {
+			t.Errorf("Unexpected error occurred converting from %d to %d: %s", data.inEpsgCode, data.outEpsgCode, err.Error())
+			continue
+		}
+
+		if math.Abs(output.X-expected.X) > data.tolerance ||
+			math.Abs(output.Y-expected.Y) > data.tolerance ||
+			math.Abs(output.Z-expected.Z) > 1E-6 {
+			t.Errorf(
+				"Converting from %d to %d expected %.10f %.10f %.10f, got %.10f %.10f %.10f",
+				data.inEpsgCode,
+				data.outEpsgCode,
+				expected.X,
+				expected.Y,
+				expected.Z,
+				output.X,
+				output.Y,
+				output.Z,
+			)
+		}
+	}
+}
+
+func TestNativeConverterRoundTrip(t *testing.T) {
+	nativeCoordinateConverter := newTestNativeCoordinateConverter(t)
+	var testData = []struct {
+		X        float64
+		Y        float64
+		Z        float64
+		epsgCode int
+	}{
+		{491880.85, 4576930.54, 10.0, 32633},
+		{652469.02, 6862035.26, 35.0, 2154},
+		{1658911.0, 5035621.0, 0.0, 3395},
+		{530000.0, 180000.0, 50.0, 27700},
+	}
+
+	for _, data := range testData {
+		coord := geometry.Coordinate{X: data.X, Y: data.Y, Z: data.Z}
+
+		cartesian, err := nativeCoordinateConverter.ConvertToWGS84Cartesian(coord, data.epsgCode)
+		if err != nil {
+			t.Fatalf("Unexpected error occurred: %s", err.Error())
+		}
+
+		output, err := nativeCoordinateConverter.ConvertCoordinateSrid(4978, data.epsgCode, cartesian)
+		if err != nil {
+			t.Fatalf("Unexpected error occurred: %s", err.Error())
+		}
+
+		// as in proj4 the Helmert transformation is inverted to the first order and the latitude is found iteratively
+		if math.Abs(output.X-data.X) > 1E-4 || math.Abs(output.Y-data.Y) > 1E-4 || math.Abs(output.Z-data.Z) > 1E-4 {
+			t.Errorf(
+				"Expected %.6f %.6f %.6f back from EPSG:%d, got %.6f %.6f %.6f",
+				data.X,
+				data.Y,
+				data.Z,
+				data.epsgCode,
+				output.X,
+				output.Y,
+				output.Z,
+			)
+		}
+	}
+}
+
+func TestNativeConverterConvert2DBoundingboxToWGS84Region(t *testing.T) {
+	nativeCoordinateConverter := newTestNativeCoordinateConverter(t)
+	bbox := geometry.NewBoundingBox(430936.93, 430946.93, 4978549.23, 4978559.23, 0.0, 10.0)
+
+	boundingBoxOutput, err := nativeCoordinateConverter.Convert2DBoundingboxToWGS84Region(bbox, 32632)
+	if err != nil {
+		t.Fatalf("Unexpected error occurred: %s", err.Error())
+	}
+
+	output := boundingBoxOutput.GetAsArray()
+	expected := []float64{0.14179735, 0.78464809, 0.14179954, 0.78464968, 0.0, 10.0}
+	for i := range expected {
+		if math.Abs(output[i]-expected[i]) > 1E-8 {
+			t.Errorf("Expected bound %d: %.8f, got %.8f", i, expected[i], output[i])
+		}
+	}
+}
+
+func TestNativeConverterUnsupportedSridReturnsError(t *testing.T) {
+	nativeCoordinateConverter := newTestNativeCoordinateConverter(t)
+	coord := geometry.Coordinate{X: 491880.85, Y: 4576930.54, Z: 10.0}
+
+	// unknown code, lambert azimuthal equal area projection and NAD27 grid shift
+	for _, epsgCode := range []int{-1, 3035, 4267} {
+		if _, err := nativeCoordinateConverter.ConvertCoordinateSrid(epsgCode, 4326, coord); err == nil {
+			t.Errorf("Error was expected converting from EPSG:%d but none was returned", epsgCode)
+		}
+	}
+}
+
+func TestNativeConverterConvertsCoordinatesBatchAsSingleCoordinates(t *testing.T) {
+	nativeCoordinateConverter := newTestNativeCoordinateConverter(t)
+	coords := []geometry.Coordinate{
+		{X: 530000.0, Y: 180000.0, Z: 50.0},
+		{X: 450000.0, Y: 350000.0, Z: 120.0},
+	}
+
+	batch := append([]geometry.Coordinate{}, coords...)
+	if err := nativeCoordinateConverter.ConvertToWGS84CartesianBatch(batch, 27700); err != nil {
+		t.Fatalf("Unexpected error occurred: %s", err.Error())
+	}
+
+	for i, coord := range coords {
+		expected, err := nativeCoordinateConverter.ConvertToWGS84Cartesian(coord, 27700)
+		if err != nil {
+			t.Fatalf("Unexpected error occurred: %s", err.Error())
+		}
+		if batch[i] != expected {
+			t.Errorf("Expected coordinate %d converted to %v, got %v", i, expected, batch[i])
+		}
+	}
+
+	if err := nativeCoordinateConverter.ConvertCoordinatesSrid(3035, 4326, coords); err == nil {
+		t.Errorf("Error was expected but none was returned")
+	}
+}
+
+// Sets the root folder of the assets to the given folder, returns the function restoring the previous one
+func setTestRootFolder(folder string) func() {
+	previous, isSet := os.LookupEnv("CESIUM_TILER_WORKDIR")
+	_ = os.Setenv("CESIUM_TILER_WORKDIR", folder)
+	return func() {
+		if isSet {
+			_ = os.Setenv("CESIUM_TILER_WORKDIR", previous)
+		} else {
+			_ = os.Unsetenv("CESIUM_TILER_WORKDIR")
+		}
+	}
+}
+
+func TestNativeConverterReturnsDatabaseErrors(t *testing.T) {
+	rootFolder := t.TempDir()
+	defer setTestRootFolder(rootFolder)()
+
+	if _, err := native_coordinate_converter.NewNativeCoordinateConverter(); err == nil {
+		t.Errorf("Error was expected loading a missing database but none was returned")
+	}
+
+	if err := os.MkdirAll(filepath.Join(rootFolder, "assets"), 0777); err != nil {
+		t.Fatalf("Unexpected error occurred: %s", err.Error())
+	}
+	database := "EPSG:4326\tWGS 84\t+proj=longlat +datum=WGS84 +no_defs\nEPSG:x\tinvalid\t+proj=longlat\n"
+	if err := ioutil.WriteFile(filepath.Join(rootFolder, "assets", "epsg_projections.txt"), []byte(database), 0666); err != nil {
+		t.Fatalf("Unexpected error occurred: %s", err.Error())
+	}
+	if _, err := native_coordinate_converter.NewNativeCoordinateConverter(); err == nil || !strings.Contains(err.Error(), "EPSG:x") {
+		t.Errorf("Expected the error of the invalid record, got: %v", err)
+	}
+}
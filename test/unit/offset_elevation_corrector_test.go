@@ -8,7 +8,7 @@ import (
 func TestElevationIsAdded(t *testing.T) {
 	expected := 10.68
 	offsetElevationCorrector := offset_elevation_corrector.NewOffsetElevationCorrector(7.57)
-	actual := offsetElevationCorrector.CorrectElevation(0, 0, 3.11)
+	actual, _ := offsetElevationCorrector.CorrectElevation(0, 0, 3.11)
 	if actual != expected {
 		t.Errorf("Expected Elevation = %f, got %f", expected, actual)
 	}
@@ -17,7 +17,7 @@ func TestElevationIsAdded(t *testing.T) {
 func TestElevationIsSubtracted(t *testing.T) {
 	expected := 3.0
 	offsetElevationCorrector := offset_elevation_corrector.NewOffsetElevationCorrector(-0.11)
-	actual := offsetElevationCorrector.CorrectElevation(0, 0, 3.11)
+	actual, _ := offsetElevationCorrector.CorrectElevation(0, 0, 3.11)
 	if actual != expected {
 		t.Errorf("Expected Elevation = %f, got %f", expected, actual)
 	}
@@ -26,7 +26,7 @@ func TestElevationIsSubtracted(t *testing.T) {
 func TestElevationIsLeftUnchanged(t *testing.T) {
 	expected := 3.11
 	offsetElevationCorrector := offset_elevation_corrector.NewOffsetElevationCorrector(0)
-	actual := offsetElevationCorrector.CorrectElevation(0, 0, 3.11)
+	actual, _ := offsetElevationCorrector.CorrectElevation(0, 0, 3.11)
 	if actual != expected {
 		t.Errorf("Expected Elevation = %f, got %f", expected, actual)
 	}
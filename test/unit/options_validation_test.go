@@ -0,0 +1,45 @@
+package unit_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ecopia-map/cesium_tiler/internal/tiler"
+	"github.com/ecopia-map/cesium_tiler/pkg"
+	"github.com/ecopia-map/cesium_tiler/pkg/api"
+)
+
+func TestDropDracoEncoderBatchAttributes(t *testing.T) {
+	testData := []struct {
+		name                 string
+		dracoEncoderPath     string
+		outputFormat         tiler.OutputFormat
+		isBatchAttributesSet bool
+		expected             []tiler.BatchAttribute
+		expectedValid        bool
+	}{
+		// the default attributes are not written with the external encoder
+		{"external encoder", "draco_encoder", tiler.OutputFormatPnts, false, nil, true},
+		{"explicit attributes", "draco_encoder", tiler.OutputFormatPnts, true, []tiler.BatchAttribute{tiler.BatchAttributeIntensity, tiler.BatchAttributeClassification}, false},
+		{"in-process encoder", "", tiler.OutputFormatPnts, false, []tiler.BatchAttribute{tiler.BatchAttributeIntensity, tiler.BatchAttributeClassification}, true},
+		// the external encoder only compresses pnts content
+		{"glb", "draco_encoder", tiler.OutputFormatGlb, false, []tiler.BatchAttribute{tiler.BatchAttributeIntensity, tiler.BatchAttributeClassification}, true},
+	}
+
+	for _, data := range testData {
+		t.Run(data.name, func(t *testing.T) {
+			opts := api.DefaultIndexOptions(lazTestDataFolder, t.TempDir())
+			opts.Draco = true
+			opts.DracoEncoderPath = data.dracoEncoderPath
+			opts.OutputFormat = data.outputFormat
+
+			pkg.DropDracoEncoderBatchAttributes(opts, data.isBatchAttributesSet)
+			if !reflect.DeepEqual(opts.BatchAttributes, data.expected) {
+				t.Errorf("Expected batch attributes %v, got %v", data.expected, opts.BatchAttributes)
+			}
+			if err := pkg.ValidateIndexOptions(opts); (err == nil) != data.expectedValid {
+				t.Errorf("Expected valid options %t, got: %v", data.expectedValid, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package unit_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ecopia-map/cesium_tiler/internal/point_filter"
+	"github.com/ecopia-map/cesium_tiler/internal/tiler"
+)
+
+// Returns a 10x10 grid of points 1 meter apart with a last point 20 meters above its center, and the brute force
+// search of the neighbours of the points within the given radius
+func newOutlierTestPoints(radius float64) ([][3]float64, point_filter.NeighbourSearch) {
+	points := make([][3]float64, 0)
+	for i := 0; i < 10; i++ {
+		for j := 0; j < 10; j++ {
+			points = append(points, [3]float64{float64(i), float64(j), 0})
+		}
+	}
+	points = append(points, [3]float64{4.5, 4.5, 20})
+
+	search := func(index int) []float64 {
+		squaredDistances := make([]float64, 0)
+		for i, p := range points {
+			dx, dy, dz := p[0]-points[index][0], p[1]-points[index][1], p[2]-points[index][2]
+			if squaredDistance := dx*dx + dy*dy + dz*dz; i != index && squaredDistance <= radius*radius {
+				squaredDistances = append(squaredDistances, squaredDistance)
+			}
+		}
+		return squaredDistances
+	}
+
+	return points, search
+}
+
+func TestOutlierFilterDisabled(t *testing.T) {
+	if filter := point_filter.NewOutlierFilter(&tiler.TilerOutlierOptions{SorSigma: 2, SorRadius: 1, RorRadius: 1}); filter != nil {
+		t.Errorf("Expected nil filter when no outlier removal is set")
+	}
+}
+
+func TestOutlierFilterRadius(t *testing.T) {
+	filter := point_filter.NewOutlierFilter(&tiler.TilerOutlierOptions{RorNeighbors: 2, RorRadius: 1})
+	if filter.SearchRadius() != 1 {
+		t.Errorf("Expected search radius 1, got %f", filter.SearchRadius())
+	}
+
+	points, search := newOutlierTestPoints(filter.SearchRadius())
+	outliers, err := filter.FindOutliers(context.Background(), len(points), 3, search)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for i := range points {
+		// every grid point has at least 2 neighbours at 1 meter, the corners included
+		if expected := i == len(points)-1; outliers[i] != expected {
+			t.Errorf("Point %d %v: expected outlier %v, got %v", i, points[i], expected, outliers[i])
+		}
+	}
+}
+
+func TestOutlierFilterStatistical(t *testing.T) {
+	filter := point_filter.NewOutlierFilter(&tiler.TilerOutlierOptions{SorNeighbors: 4, SorSigma: 3, SorRadius: 5})
+
+	points, search := newOutlierTestPoints(filter.SearchRadius())
+	outliers, err := filter.FindOutliers(context.Background(), len(points), 0, search)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for i := range points {
+		// the 4 nearest neighbours of the point above the grid are missing within 5 meters, counted at 5 meters
+		if expected := i == len(points)-1; outliers[i] != expected {
+			t.Errorf("Point %d %v: expected outlier %v, got %v", i, points[i], expected, outliers[i])
+		}
+	}
+}
+
+func TestOutlierFilterCanceled(t *testing.T) {
+	filter := point_filter.NewOutlierFilter(&tiler.TilerOutlierOptions{RorNeighbors: 2, RorRadius: 1})
+	points, search := newOutlierTestPoints(filter.SearchRadius())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := filter.FindOutliers(ctx, len(points), 2, search); err != context.Canceled {
+		t.Errorf("Expected context canceled error, got %v", err)
+	}
+}
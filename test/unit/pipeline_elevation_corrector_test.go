@@ -1,6 +1,7 @@
 package unit
 
 import (
+	"errors"
 	"github.com/ecopia-map/cesium_tiler/internal/converters"
 	"github.com/ecopia-map/cesium_tiler/internal/converters/elevation/pipeline_elevation_corrector"
 	"testing"
@@ -9,8 +10,15 @@ import (
 type mockElevationCorrector struct{
 }
 
-func (m *mockElevationCorrector) CorrectElevation(lon, lat, z float64) float64 {
-	return z * 2
+func (m *mockElevationCorrector) CorrectElevation(lon, lat, z float64) (float64, error) {
+	return z * 2, nil
+}
+
+type failingElevationCorrector struct{
+}
+
+func (m *failingElevationCorrector) CorrectElevation(lon, lat, z float64) (float64, error) {
+	return 0, errors.New("point outside of the geoid grid")
 }
 
 func TestElevationCorrectionsAreSummed(t *testing.T) {
@@ -22,9 +30,26 @@ func TestElevationCorrectionsAreSummed(t *testing.T) {
 
 	pipelineCorrector := pipeline_elevation_corrector.NewPipelineElevationCorrector(correctors)
 
-	actual := pipelineCorrector.CorrectElevation(14, 41, 1.2)
+	actual, err := pipelineCorrector.CorrectElevation(14, 41, 1.2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
 
 	if actual != expected {
 		t.Errorf("Expected Elevation = %f, got %f", expected, actual)
 	}
 }
+
+func TestElevationCorrectionErrorIsReturned(t *testing.T) {
+	var correctors = []converters.ElevationCorrector{
+		&mockElevationCorrector{},
+		&failingElevationCorrector{},
+		&mockElevationCorrector{},
+	}
+
+	pipelineCorrector := pipeline_elevation_corrector.NewPipelineElevationCorrector(correctors)
+
+	if _, err := pipelineCorrector.CorrectElevation(14, 41, 1.2); err == nil {
+		t.Errorf("Expected the error of the failing corrector")
+	}
+}
@@ -0,0 +1,139 @@
+package unit_test
+
+import (
+	"testing"
+
+	"github.com/ecopia-map/cesium_tiler/internal/point_filter"
+	"github.com/ecopia-map/cesium_tiler/internal/tiler"
+)
+
+func newTestFilterOptions() *tiler.TilerFilterOptions {
+	return &tiler.TilerFilterOptions{
+		Returns:   tiler.ReturnFilterAll,
+		Withheld:  tiler.FlagFilterKeep,
+		Synthetic: tiler.FlagFilterKeep,
+	}
+}
+
+func TestPointFilterDisabled(t *testing.T) {
+	filter, err := point_filter.NewPointFilter(newTestFilterOptions(), 4326, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if filter != nil {
+		t.Errorf("Expected nil filter when no filter is set")
+	}
+}
+
+func TestPointFilterAccept(t *testing.T) {
+	zMax := 50.0
+	opts := newTestFilterOptions()
+	opts.ExcludeClasses = []uint8{7, 18}
+	opts.Returns = tiler.ReturnFilterLast
+	opts.Withheld = tiler.FlagFilterDrop
+	opts.ZMax = &zMax
+	opts.ClipBox = []float64{0, 0, 10, 10}
+
+	filter, err := point_filter.NewPointFilter(opts, 32633, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	valid := point_filter.PointAttributes{X: 5, Y: 5, Z: 10, Classification: 2, ReturnNumber: 2, NumberOfReturns: 2}
+	testData := []struct {
+		name     string
+		edit     func(p *point_filter.PointAttributes)
+		expected bool
+	}{
+		{"valid", func(p *point_filter.PointAttributes) {}, true},
+		{"noise class", func(p *point_filter.PointAttributes) { p.Classification = 7 }, false},
+		{"high noise class", func(p *point_filter.PointAttributes) { p.Classification = 18 }, false},
+		{"not last return", func(p *point_filter.PointAttributes) { p.ReturnNumber = 1 }, false},
+		{"withheld", func(p *point_filter.PointAttributes) { p.Withheld = true }, false},
+		{"synthetic", func(p *point_filter.PointAttributes) { p.Synthetic = true }, true},
+		{"above z max", func(p *point_filter.PointAttributes) { p.Z = 51 }, false},
+		{"outside clip box", func(p *point_filter.PointAttributes) { p.X = 11 }, false},
+	}
+
+	for _, test := range testData {
+		point := valid
+		test.edit(&point)
+		if actual := filter.Accept(&point); actual != test.expected {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, actual)
+		}
+	}
+}
+
+func TestPointFilterIncludeClasses(t *testing.T) {
+	opts := newTestFilterOptions()
+	opts.IncludeClasses = []uint8{2, 6}
+	opts.Returns = tiler.ReturnFilterFirst
+
+	filter, err := point_filter.NewPointFilter(opts, 4326, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !filter.Accept(&point_filter.PointAttributes{Classification: 6, ReturnNumber: 1, NumberOfReturns: 3}) {
+		t.Errorf("Expected first return of class 6 to be accepted")
+	}
+	if filter.Accept(&point_filter.PointAttributes{Classification: 5, ReturnNumber: 1, NumberOfReturns: 3}) {
+		t.Errorf("Expected class 5 to be discarded")
+	}
+	if filter.Accept(&point_filter.PointAttributes{Classification: 2, ReturnNumber: 3, NumberOfReturns: 3}) {
+		t.Errorf("Expected last return to be discarded")
+	}
+}
+
+func TestWktClipAreaWithHole(t *testing.T) {
+	area, err := point_filter.ParseWktClipArea("POLYGON ((0 0, 10 0, 10 10, 0 10, 0 0), (4 4, 6 4, 6 6, 4 6, 4 4))")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !area.Contains(2, 2) {
+		t.Errorf("Expected point inside the outer ring to be contained")
+	}
+	if area.Contains(5, 5) {
+		t.Errorf("Expected point inside the hole not to be contained")
+	}
+	if area.Contains(11, 5) {
+		t.Errorf("Expected point outside the polygon not to be contained")
+	}
+}
+
+func TestWktMultiPolygonClipArea(t *testing.T) {
+	area, err := point_filter.ParseWktClipArea("MULTIPOLYGON (((0 0, 1 0, 1 1, 0 0)), ((5 5, 6 5, 6 6, 5 6, 5 5)))")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(area.Polygons) != 2 {
+		t.Fatalf("Expected 2 polygons, got %d", len(area.Polygons))
+	}
+	if !area.Contains(5.5, 5.5) {
+		t.Errorf("Expected point inside the second polygon to be contained")
+	}
+
+	if _, err := point_filter.ParseWktClipArea("LINESTRING (0 0, 1 1)"); err == nil {
+		t.Errorf("Expected error for unsupported geometry")
+	}
+}
+
+func TestGeoJsonClipArea(t *testing.T) {
+	geoJson := `{"type": "Feature", "geometry": {"type": "MultiPolygon", "coordinates": [[[[0, 0], [2, 0], [2, 2], [0, 2], [0, 0]]]]}}`
+	area, err := point_filter.ParseGeoJsonClipArea([]byte(geoJson))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !area.Contains(1, 1) {
+		t.Errorf("Expected point inside the polygon to be contained")
+	}
+	if area.Contains(3, 1) {
+		t.Errorf("Expected point outside the polygon not to be contained")
+	}
+
+	if _, err := point_filter.ParseGeoJsonClipArea([]byte(`{"type": "Point", "coordinates": [0, 0]}`)); err == nil {
+		t.Errorf("Expected error for unsupported geometry")
+	}
+}
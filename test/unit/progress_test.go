@@ -0,0 +1,102 @@
+package unit_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/ecopia-map/cesium_tiler/internal/progress"
+)
+
+type recordingReporter struct {
+	events []progress.Event
+}
+
+func (r *recordingReporter) Report(event progress.Event) {
+	r.events = append(r.events, event)
+}
+
+func TestTaskReportsProgress(t *testing.T) {
+	reporter := &recordingReporter{}
+	tracker := progress.NewTracker(reporter, progress.NewSummary("index", "in", "out"))
+
+	task := tracker.Start("a.las", progress.StageRead, 200)
+	task.Add(1)
+	task.Add(1)
+	task.Add(98)
+	task.Add(100)
+	task.Finish(errors.New("stopped"))
+
+	var percents []int
+	for _, event := range reporter.events {
+		if event.Type == progress.EventProgress {
+			percents = append(percents, event.Percent())
+		}
+	}
+	if len(percents) != 3 || percents[0] != 1 || percents[1] != 50 || percents[2] != 100 {
+		t.Errorf("Expected progress events at 1, 50 and 100 percent, got %v", percents)
+	}
+
+	first, last := reporter.events[0], reporter.events[len(reporter.events)-1]
+	if first.Type != progress.EventStart || first.File != "a.las" || first.Stage != progress.StageRead {
+		t.Errorf("Unexpected start event %+v", first)
+	}
+	if last.Type != progress.EventFinish || last.Done != 200 || last.Error != "stopped" {
+		t.Errorf("Unexpected finish event %+v", last)
+	}
+
+	// a nil task does nothing
+	var nilTask *progress.Task
+	nilTask.Add(10)
+	nilTask.AddTile(0, 10, 10)
+	nilTask.Finish(nil)
+}
+
+func TestSummaryJson(t *testing.T) {
+	summary := progress.NewSummary("index", "in", "out")
+	tracker := progress.NewTracker(nil, summary)
+
+	summary.AddFile(100, 10)
+	summary.AddSkippedFile()
+	task := tracker.Start("a.las", progress.StageExport, 90)
+	task.AddTile(1, 40, 1000)
+	task.AddTile(0, 30, 500)
+	task.AddTile(1, 20, 800)
+	task.AddBytes(200)
+	task.Finish(nil)
+	tracker.Start("a.las", progress.StageRead, 100).Finish(nil)
+	summary.Finish(nil)
+
+	jsonData, err := json.Marshal(summary)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var output struct {
+		Files          int   `json:"files"`
+		FilesSkipped   int   `json:"files_skipped"`
+		PointsRead     int64 `json:"points_read"`
+		PointsFiltered int64 `json:"points_filtered"`
+		PointsWritten  int64 `json:"points_written"`
+		Nodes          int64 `json:"nodes"`
+		BytesWritten   int64 `json:"bytes_written"`
+		Levels         []progress.LevelSummary
+		Stages         []progress.StageSummary
+	}
+	if err := json.Unmarshal(jsonData, &output); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if output.Files != 1 || output.FilesSkipped != 1 || output.PointsRead != 100 || output.PointsFiltered != 10 {
+		t.Errorf("Unexpected file counts in %s", jsonData)
+	}
+	if output.PointsWritten != 90 || output.Nodes != 3 || output.BytesWritten != 2500 {
+		t.Errorf("Unexpected written counts in %s", jsonData)
+	}
+	if len(output.Levels) != 2 || output.Levels[0] != (progress.LevelSummary{Level: 0, Nodes: 1, Points: 30}) ||
+		output.Levels[1] != (progress.LevelSummary{Level: 1, Nodes: 2, Points: 60}) {
+		t.Errorf("Unexpected levels %+v", output.Levels)
+	}
+	if len(output.Stages) != 2 || output.Stages[0].Stage != progress.StageRead || output.Stages[1].Stage != progress.StageExport {
+		t.Errorf("Expected the read and export stages in pipeline order, got %+v", output.Stages)
+	}
+}
@@ -1,15 +1,26 @@
 package unit
 
 import (
+	"github.com/ecopia-map/cesium_tiler/internal/converters"
 	"github.com/ecopia-map/cesium_tiler/internal/converters/coordinate/proj4_coordinate_converter"
 	"github.com/ecopia-map/cesium_tiler/internal/geometry"
+	"fmt"
 	"math"
+	"sync"
 	"testing"
 )
 
-var coordinateConverter = proj4_coordinate_converter.NewProj4CoordinateConverter()
+// Returns the proj4 converter of the EPSG codes of the assets folder
+func newTestProj4CoordinateConverter(t *testing.T) converters.CoordinateConverter {
+	converter, err := proj4_coordinate_converter.NewProj4CoordinateConverter()
+	if err != nil {
+		t.Fatalf("Unexpected error loading the proj4 converter: %s", err)
+	}
+	return converter
+}
 
 func TestConvertsCoordinate(t *testing.T) {
+	coordinateConverter := newTestProj4CoordinateConverter(t)
 	var testData = []struct {
 		X           float64
 		Y           float64
@@ -73,6 +84,7 @@ func TestConvertsCoordinate(t *testing.T) {
 }
 
 func TestConvertsFromUnknownSridReturnsError(t *testing.T) {
+	coordinateConverter := newTestProj4CoordinateConverter(t)
 	x := 491880.85
 	y := 4576930.54
 	z := 10.0
@@ -93,6 +105,7 @@ func TestConvertsFromUnknownSridReturnsError(t *testing.T) {
 }
 
 func TestConvertsToUnknownSridReturnsError(t *testing.T) {
+	coordinateConverter := newTestProj4CoordinateConverter(t)
 	x := 491880.85
 	y := 4576930.54
 	z := 10.0
@@ -113,6 +126,7 @@ func TestConvertsToUnknownSridReturnsError(t *testing.T) {
 }
 
 func TestConvertsFrom4326toWGS84Cartesian(t *testing.T) {
+	coordinateConverter := newTestProj4CoordinateConverter(t)
 	x := 15.309277
 	y := 41.363327
 	z := 0.0
@@ -160,6 +174,7 @@ func TestConvertsFrom4326toWGS84Cartesian(t *testing.T) {
 }
 
 func TestConvert326322DBoundingboxToWGS84Region(t *testing.T) {
+	coordinateConverter := newTestProj4CoordinateConverter(t)
 	bbox := geometry.NewBoundingBox(
 		430936.93,
 		430946.93,
@@ -235,3 +250,104 @@ func TestConvert326322DBoundingboxToWGS84Region(t *testing.T) {
 		)
 	}
 }
+
+func TestConvertsCoordinatesBatchAsSingleCoordinates(t *testing.T) {
+	coordinateConverter := newTestProj4CoordinateConverter(t)
+	coords := []geometry.Coordinate{
+		{X: 491880.85, Y: 4576930.54, Z: 10.0},
+		{X: 430936.93, Y: 4978549.23, Z: 0.0},
+		{X: 504544.56, Y: 4848085.02, Z: -20.0},
+	}
+
+	for _, targetSrid := range []int{4326, 3395} {
+		batch := append([]geometry.Coordinate{}, coords...)
+		if err := coordinateConverter.ConvertCoordinatesSrid(32633, targetSrid, batch); err != nil {
+			t.Fatalf("Unexpected error occurred: %s", err.Error())
+		}
+
+		for i, coord := range coords {
+			expected, err := coordinateConverter.ConvertCoordinateSrid(32633, targetSrid, coord)
+			if err != nil {
+				t.Fatalf("Unexpected error occurred: %s", err.Error())
+			}
+			if batch[i] != expected {
+				t.Errorf("Expected coordinate %d converted to %v, got %v", i, expected, batch[i])
+			}
+		}
+	}
+
+	batch := append([]geometry.Coordinate{}, coords...)
+	if err := coordinateConverter.ConvertToWGS84CartesianBatch(batch, 32633); err != nil {
+		t.Fatalf("Unexpected error occurred: %s", err.Error())
+	}
+	for i, coord := range coords {
+		expected, err := coordinateConverter.ConvertToWGS84Cartesian(coord, 32633)
+		if err != nil {
+			t.Fatalf("Unexpected error occurred: %s", err.Error())
+		}
+		if math.Abs(batch[i].X-expected.X) > 1E-6 || math.Abs(batch[i].Y-expected.Y) > 1E-6 || math.Abs(batch[i].Z-expected.Z) > 1E-6 {
+			t.Errorf("Expected coordinate %d converted to %v, got %v", i, expected, batch[i])
+		}
+	}
+}
+
+func TestConvertsCoordinatesBatchFromUnknownSridReturnsError(t *testing.T) {
+	coordinateConverter := newTestProj4CoordinateConverter(t)
+	coords := []geometry.Coordinate{{X: 491880.85, Y: 4576930.54, Z: 10.0}}
+
+	if err := coordinateConverter.ConvertCoordinatesSrid(-1, 4326, coords); err == nil {
+		t.Errorf("Error was expected but none was returned")
+	}
+}
+
+func TestConvertsCoordinatesConcurrently(t *testing.T) {
+	coordinateConverter := newTestProj4CoordinateConverter(t)
+	defer coordinateConverter.Cleanup()
+
+	// web mercator uses a null grid shift, listed when its projection is initialized
+	sources := []struct {
+		srid  int
+		coord geometry.Coordinate
+	}{
+		{32633, geometry.Coordinate{X: 491880.85, Y: 4576930.54, Z: 10.0}},
+		{3857, geometry.Coordinate{X: 1658911.0, Y: 5035621.0, Z: 0.0}},
+		{27700, geometry.Coordinate{X: 530000.0, Y: 180000.0, Z: 50.0}},
+	}
+	expected := make([]geometry.Coordinate, len(sources))
+	for i, source := range sources {
+		output, err := coordinateConverter.ConvertToWGS84Cartesian(source.coord, source.srid)
+		if err != nil {
+			t.Fatalf("Unexpected error occurred: %s", err.Error())
+		}
+		expected[i] = output
+	}
+
+	var waitGroup sync.WaitGroup
+	errs := make(chan error, 8)
+	for worker := 0; worker < 8; worker++ {
+		waitGroup.Add(1)
+		go func(worker int) {
+			defer waitGroup.Done()
+			for i := 0; i < 200; i++ {
+				source := sources[(worker+i)%len(sources)]
+				batch := []geometry.Coordinate{source.coord, source.coord}
+				if err := coordinateConverter.ConvertToWGS84CartesianBatch(batch, source.srid); err != nil {
+					errs <- err
+					return
+				}
+				for _, output := range batch {
+					if output != expected[(worker+i)%len(sources)] {
+						errs <- fmt.Errorf("EPSG:%d converted to %v, expected %v", source.srid, output, expected[(worker+i)%len(sources)])
+						return
+					}
+				}
+			}
+		}(worker)
+	}
+	waitGroup.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("Unexpected concurrent conversion: %s", err)
+	}
+}
@@ -0,0 +1,186 @@
+package unit_test
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/ecopia-map/cesium_tiler/internal/converters"
+	"github.com/ecopia-map/cesium_tiler/internal/converters/coordinate/native_coordinate_converter"
+	"github.com/ecopia-map/cesium_tiler/internal/converters/elevation/offset_elevation_corrector"
+	"github.com/ecopia-map/cesium_tiler/internal/geometry"
+	"github.com/ecopia-map/cesium_tiler/internal/io"
+	"github.com/ecopia-map/cesium_tiler/internal/octree"
+	"github.com/ecopia-map/cesium_tiler/internal/octree/random_trees"
+	"github.com/ecopia-map/cesium_tiler/internal/tiler"
+	"github.com/ecopia-map/cesium_tiler/pkg"
+)
+
+const randomTreeTestNumPoints = 400
+
+// Builds a random tree of the given algorithm holding two clusters of random points at opposite corners of their
+// bounding box, so that most of the octants of the root are empty
+func newRandomTilingTestTree(t *testing.T, opts *tiler.TilerOptions, converter converters.CoordinateConverter) octree.ITree {
+	var tree octree.ITree
+	if opts.Algorithm == tiler.RandomBox {
+		tree = random_trees.NewBoxedRandomTree(opts, converter, offset_elevation_corrector.NewOffsetElevationCorrector(0))
+	} else {
+		tree = random_trees.NewRandomTree(opts, converter, offset_elevation_corrector.NewOffsetElevationCorrector(0))
+	}
+
+	random := rand.New(rand.NewSource(42))
+	for i := 0; i < randomTreeTestNumPoints; i++ {
+		corner := float64(i % 2 * 1000)
+		coord := &geometry.Coordinate{
+			X: 500000 + corner + random.Float64()*20,
+			Y: 4000000 + corner + random.Float64()*20,
+			Z: corner/10 + random.Float64()*5,
+		}
+		if err := tree.AddPoint(coord, 1, 2, 3, 4, 5, 32633, nil); err != nil {
+			t.Fatalf("Unexpected error adding point: %v", err)
+		}
+	}
+
+	if err := tree.Build(); err != nil {
+		t.Fatalf("Unexpected error building the tree: %s", err)
+	}
+	return tree
+}
+
+// Writes the tileset of the given tree in the given folder with the standard producer and consumers
+func writeRandomTilingTestTileset(t *testing.T, opts *tiler.TilerOptions, converter converters.CoordinateConverter, tree octree.ITree, output string) {
+	ctx := context.Background()
+	workChannel := make(chan *io.WorkUnit, 10)
+	errorChannel := make(chan error, 2)
+	var waitGroup sync.WaitGroup
+
+	waitGroup.Add(1)
+	go io.NewStandardProducer(output, "", opts).Produce(ctx, workChannel, &waitGroup, tree.GetRootNode())
+	for i := 0; i < 2; i++ {
+		waitGroup.Add(1)
+		consumer := io.NewStandardConsumer(converter, opts.RefineMode, false, "", opts.DracoMethod, 0, opts.OutputFormat, false, opts.BoundingVolume, opts.BatchAttributes)
+		go consumer.Consume(ctx, workChannel, errorChannel, &waitGroup)
+	}
+	waitGroup.Wait()
+
+	close(errorChannel)
+	for err := range errorChannel {
+		t.Fatalf("Unexpected error writing the tileset: %s", err)
+	}
+}
+
+// Returns the number of points of the given pnts file
+func readRandomTilingTestPointsLength(t *testing.T, filePath string) int {
+	content, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		t.Fatalf("Unexpected error reading %s: %s", filePath, err)
+	}
+	featureTableLength := binary.LittleEndian.Uint32(content[12:16])
+	var featureTable struct {
+		PointsLength int `json:"POINTS_LENGTH"`
+	}
+	if err := json.Unmarshal(content[28:28+featureTableLength], &featureTable); err != nil {
+		t.Fatalf("Unexpected error decoding the feature table of %s: %s", filePath, err)
+	}
+	return featureTable.PointsLength
+}
+
+// Checks the files of the given node and of its descendants holding points written in the given folder: the
+// tileset.json of the root and of the nodes with children and the content of every node. Returns the number of points
+// written
+func checkRandomTilingTestNode(t *testing.T, node octree.INode, folder string) int {
+	// the contents of the leaves are referenced by the tileset of their parent
+	_, err := os.Stat(filepath.Join(folder, "tileset.json"))
+	if hasTileset := err == nil; hasTileset != (!node.IsLeaf() || node.IsRoot()) {
+		t.Errorf("Folder %s of a node with leaf %t: unexpected tileset.json %t", folder, node.IsLeaf(), hasTileset)
+	}
+
+	numPoints := 0
+	if node.NumberOfPoints() > 0 {
+		numPoints = readRandomTilingTestPointsLength(t, filepath.Join(folder, "content.pnts"))
+		if numPoints != int(node.NumberOfPoints()) {
+			t.Errorf("Expected %d points in %s, got %d", node.NumberOfPoints(), folder, numPoints)
+		}
+	}
+
+	for i, child := range node.GetChildren() {
+		childFolder := filepath.Join(folder, node.GetChildrenPath()[i])
+		if child == nil || child.TotalNumberOfPoints() == 0 {
+			// empty octants are neither written nor referenced
+			if _, err := os.Stat(childFolder); err == nil {
+				t.Errorf("Expected the empty octant %s not to be written", childFolder)
+			}
+			continue
+		}
+		numPoints += checkRandomTilingTestNode(t, child, childFolder)
+	}
+	return numPoints
+}
+
+func TestRandomTreesTiling(t *testing.T) {
+	converter, err := native_coordinate_converter.NewNativeCoordinateConverter()
+	if err != nil {
+		t.Fatalf("Unexpected error loading the native converter: %s", err)
+	}
+
+	for _, algorithm := range []tiler.Algorithm{tiler.Random, tiler.RandomBox} {
+		t.Run(string(algorithm), func(t *testing.T) {
+			opts := &tiler.TilerOptions{
+				Algorithm:           algorithm,
+				MaxNumPointsPerNode: 50,
+				RefineMode:          tiler.RefineModeAdd,
+				DracoMethod:         tiler.DracoMethodKdTree,
+				OutputFormat:        tiler.OutputFormatPnts,
+				BoundingVolume:      tiler.BoundingVolumeRegion,
+			}
+			tree := newRandomTilingTestTree(t, opts, converter)
+			output := t.TempDir()
+			writeRandomTilingTestTileset(t, opts, converter, tree, output)
+
+			report, err := pkg.VerifyTileset(output)
+			if err != nil {
+				t.Fatalf("Unexpected error verifying the tileset: %s", err)
+			}
+			if !report.Valid {
+				t.Errorf("Expected a valid tileset, got %+v", report.Problems)
+			}
+
+			// the root holds the points of the two clusters, in opposite octants
+			var rootTileset struct {
+				Root struct {
+					Children []struct {
+						Content struct {
+							Url string `json:"uri"`
+						} `json:"content"`
+					} `json:"children"`
+				} `json:"root"`
+			}
+			content, err := ioutil.ReadFile(filepath.Join(output, "tileset.json"))
+			if err != nil {
+				t.Fatalf("Unexpected error reading the root tileset: %s", err)
+			}
+			if err := json.Unmarshal(content, &rootTileset); err != nil {
+				t.Fatalf("Unexpected error decoding the root tileset: %s", err)
+			}
+			if len(rootTileset.Root.Children) != 2 {
+				t.Fatalf("Expected the two octants holding points, got %+v", rootTileset.Root.Children)
+			}
+			for i, expected := range []string{"0/tileset.json", "7/tileset.json"} {
+				if url := rootTileset.Root.Children[i].Content.Url; url != expected {
+					t.Errorf("Expected child %s, got %s", expected, url)
+				}
+			}
+
+			// with additive refinement every point is written once
+			if numPoints := checkRandomTilingTestNode(t, tree.GetRootNode(), output); numPoints != randomTreeTestNumPoints {
+				t.Errorf("Expected %d points written, got %d", randomTreeTestNumPoints, numPoints)
+			}
+		})
+	}
+}
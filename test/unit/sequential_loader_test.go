@@ -9,7 +9,7 @@ import (
 
 func TestSequentialLoaderAddPoint(t *testing.T) {
 	loader := point_loader.NewSequentialLoader()
-	point := data.NewPoint(14.1, 41, 1, 2, 3, 4, 5, 6)
+	point := data.NewPoint(14.1, 41, 1, 2, 3, 4, 5, 6, nil)
 
 	loader.AddPoint(point)
 
@@ -30,8 +30,8 @@ func TestSequentialLoaderAddPoint(t *testing.T) {
 
 func TestSequentialLoaderGetNext(t *testing.T) {
 	loader := point_loader.NewSequentialLoader()
-	point := data.NewPoint(14.1, 41, 1, 2, 3, 4, 5, 6)
-	point2 := data.NewPoint(15, 45, 3, 2, 3, 4, 5, 6)
+	point := data.NewPoint(14.1, 41, 1, 2, 3, 4, 5, 6, nil)
+	point2 := data.NewPoint(15, 45, 3, 2, 3, 4, 5, 6, nil)
 
 	loader.AddPoint(point)
 	loader.AddPoint(point2)
@@ -67,9 +67,9 @@ func TestSequentialLoaderGetNext(t *testing.T) {
 
 func TestSequentialLoaderGetBounds(t *testing.T) {
 	loader := point_loader.NewSequentialLoader()
-	point := data.NewPoint(14.1, 41, 1, 2, 3, 4, 5, 6)
-	point2 := data.NewPoint(15, 45, 3, 2, 3, 4, 5, 6)
-	point3 := data.NewPoint(14.3, 47, 2, 2, 3, 4, 5, 6)
+	point := data.NewPoint(14.1, 41, 1, 2, 3, 4, 5, 6, nil)
+	point2 := data.NewPoint(15, 45, 3, 2, 3, 4, 5, 6, nil)
+	point3 := data.NewPoint(14.3, 47, 2, 2, 3, 4, 5, 6, nil)
 
 	loader.AddPoint(point)
 	loader.AddPoint(point2)
@@ -0,0 +1,77 @@
+package unit
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ecopia-map/cesium_tiler/internal/data"
+	"github.com/ecopia-map/cesium_tiler/internal/point_loader"
+)
+
+func TestSpillLoaderRoundTrip(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "points.bin")
+	loader, err := point_loader.NewSpillLoader(filePath)
+	if err != nil {
+		t.Fatalf("Unexpected error creating the spill loader: %s", err)
+	}
+
+	withoutExtend := data.NewPoint(14.1, 41, 1, 2, 3, 4, 5, 6, nil)
+	withExtend := data.NewPoint(15, 45, -3, 7, 8, 9, 10, 11, &data.PointExtend{
+		LasPointIndex:   12,
+		GpsTime:         1234.5,
+		ScanAngle:       -15.5,
+		PointSourceID:   13,
+		ReturnNumber:    1,
+		NumberOfReturns: 2,
+		UserData:        14,
+		Nir:             15,
+	})
+	withExtraBytes := data.NewPoint(-16, 17, 18.25, 19, 20, 21, 22, 23, &data.PointExtend{
+		LasPointIndex: 0,
+		ExtraBytes:    []float64{1.5, -2, 300000.125},
+	})
+	points := []*data.Point{withoutExtend, withExtend, withExtraBytes, withExtend}
+
+	for _, point := range points {
+		loader.AddPoint(point)
+	}
+	if loader.NumberOfPoints() != int64(len(points)) {
+		t.Errorf("Expected %d points, got %d", len(points), loader.NumberOfPoints())
+	}
+	if bounds := loader.GetBounds(); !reflect.DeepEqual(bounds, []float64{-16, 15, 17, 45, -3, 18.25}) {
+		t.Errorf("Unexpected bounds %v", bounds)
+	}
+
+	loader.InitializeLoader()
+	for i, expected := range points {
+		point, hasNext := loader.GetNext()
+		if point == nil {
+			t.Fatalf("Point %d: unexpected nil point returned", i)
+		}
+		if hasNext != (i < len(points)-1) {
+			t.Errorf("Point %d: unexpected hasNext %t", i, hasNext)
+		}
+		if !reflect.DeepEqual(point, expected) {
+			t.Errorf("Point %d: expected %+v, got %+v", i, expected, point)
+		}
+	}
+	if point, hasNext := loader.GetNext(); point != nil || hasNext {
+		t.Errorf("Expected no more points to return")
+	}
+	if err := loader.Err(); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+
+	loader.ClearLoader()
+	if _, err := os.Stat(filePath); !os.IsNotExist(err) {
+		t.Errorf("Expected the spill file to be removed")
+	}
+}
+
+func TestSpillLoaderReportsErrors(t *testing.T) {
+	if _, err := point_loader.NewSpillLoader(filepath.Join(t.TempDir(), "missing", "points.bin")); err == nil {
+		t.Errorf("Expected an error creating a spill file in a missing folder")
+	}
+}
@@ -5,17 +5,23 @@ import (
 	"github.com/ecopia-map/cesium_tiler/internal/converters/elevation/offset_elevation_corrector"
 	"github.com/ecopia-map/cesium_tiler/internal/tiler"
 	"github.com/ecopia-map/cesium_tiler/pkg/algorithm_manager/std_algorithm_manager"
+	"io/ioutil"
+	"path"
 	"reflect"
+	"strings"
 	"testing"
 )
 
 func TestAlgorithmManagerReturnsGridTree(t *testing.T) {
 	expected := "GridTree"
-	algorithmManager := std_algorithm_manager.NewAlgorithmManager(
+	algorithmManager, err := std_algorithm_manager.NewAlgorithmManager(
 		&tiler.TilerOptions{
 			Algorithm: tiler.Grid,
 		},
 	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
 
 	treeType := reflect.ValueOf(algorithmManager.GetTreeAlgorithm()).Elem().Type().Name()
 	if treeType != expected {
@@ -26,11 +32,14 @@ func TestAlgorithmManagerReturnsGridTree(t *testing.T) {
 func TestAlgorithmManagerReturnsRandomTree(t *testing.T) {
 	expectedTree := "RandomTree"
 	expectedLoader := "RandomLoader"
-	algorithmManager := std_algorithm_manager.NewAlgorithmManager(
+	algorithmManager, err := std_algorithm_manager.NewAlgorithmManager(
 		&tiler.TilerOptions{
 			Algorithm: tiler.Random,
 		},
 	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
 
 	value := reflect.ValueOf(algorithmManager.GetTreeAlgorithm())
 	treeType := value.Elem().Type().Name()
@@ -48,11 +57,14 @@ func TestAlgorithmManagerReturnsRandomTree(t *testing.T) {
 func TestAlgorithmManagerReturnsRandomBoxTree(t *testing.T) {
 	expectedTree := "RandomTree"
 	expectedLoader := "RandomBoxLoader"
-	algorithmManager := std_algorithm_manager.NewAlgorithmManager(
+	algorithmManager, err := std_algorithm_manager.NewAlgorithmManager(
 		&tiler.TilerOptions{
 			Algorithm: tiler.RandomBox,
 		},
 	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
 
 	value := reflect.ValueOf(algorithmManager.GetTreeAlgorithm())
 	treeType := value.Elem().Type().Name()
@@ -69,11 +81,14 @@ func TestAlgorithmManagerReturnsRandomBoxTree(t *testing.T) {
 
 func TestAlgorithmManagerReturnsProj4CoordinateConverter(t *testing.T) {
 	expected := "proj4CoordinateConverter"
-	algorithmManager := std_algorithm_manager.NewAlgorithmManager(
+	algorithmManager, err := std_algorithm_manager.NewAlgorithmManager(
 		&tiler.TilerOptions{
 			Algorithm: tiler.Grid,
 		},
 	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
 
 	coordinateConverterType := reflect.ValueOf(algorithmManager.GetCoordinateConverterAlgorithm()).Elem().Type().Name()
 	if coordinateConverterType != expected {
@@ -85,13 +100,16 @@ func TestAlgorithmManagerReturnsOffsetElevationCorrector(t *testing.T) {
 	expectedWrapper := "PipelineElevationCorrector"
 	expectedNestedCorrector := "OffsetElevationCorrector"
 	expectedOffset := 10.3
-	algorithmManager := std_algorithm_manager.NewAlgorithmManager(
+	algorithmManager, err := std_algorithm_manager.NewAlgorithmManager(
 		&tiler.TilerOptions{
 			Algorithm:              tiler.Grid,
 			ZOffset:                expectedOffset,
 			EnableGeoidZCorrection: false,
 		},
 	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
 
 	elevationCorrectionType := reflect.ValueOf(algorithmManager.GetElevationCorrectionAlgorithm()).Elem()
 	treeType := elevationCorrectionType.Type().Name()
@@ -121,13 +139,16 @@ func TestAlgorithmManagerReturnsGeoidElevationCorrector(t *testing.T) {
 	expectedNestedCorrectorOne := "OffsetElevationCorrector"
 	expectedNestedCorrectorTwo := "GeoidElevationCorrector"
 	expectedOffset := 10.3
-	algorithmManager := std_algorithm_manager.NewAlgorithmManager(
+	algorithmManager, err := std_algorithm_manager.NewAlgorithmManager(
 		&tiler.TilerOptions{
 			Algorithm:              tiler.Grid,
 			ZOffset:                expectedOffset,
 			EnableGeoidZCorrection: true,
 		},
 	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
 
 	elevationCorrectionType := reflect.ValueOf(algorithmManager.GetElevationCorrectionAlgorithm()).Elem()
 	treeType := elevationCorrectionType.Type().Name()
@@ -157,3 +178,50 @@ func TestAlgorithmManagerReturnsGeoidElevationCorrector(t *testing.T) {
 	}
 
 }
+
+func TestAlgorithmManagerReturnsGeoidGridErrors(t *testing.T) {
+	gridPath := path.Join(t.TempDir(), "geoid.gtx")
+	if err := ioutil.WriteFile(gridPath, []byte("not a grid"), 0644); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	for _, grid := range []string{gridPath, path.Join(t.TempDir(), "missing.gtx")} {
+		_, err := std_algorithm_manager.NewAlgorithmManager(
+			&tiler.TilerOptions{
+				Algorithm:              tiler.Grid,
+				EnableGeoidZCorrection: true,
+				GeoidGrid:              grid,
+			},
+		)
+		if err == nil || !strings.Contains(err.Error(), "geoid grid") {
+			t.Errorf("Expected the error loading the geoid grid %s, got: %v", grid, err)
+		}
+	}
+}
+
+func TestAlgorithmManagerReturnsUnknownAlgorithmError(t *testing.T) {
+	_, err := std_algorithm_manager.NewAlgorithmManager(
+		&tiler.TilerOptions{
+			Algorithm: tiler.Algorithm("octree"),
+		},
+	)
+	if err == nil || !strings.Contains(err.Error(), "unrecognized algorithm [octree]") {
+		t.Errorf("Expected the error of the unknown algorithm, got: %v", err)
+	}
+}
+
+func TestAlgorithmManagerReturnsAssetErrors(t *testing.T) {
+	defer setTestRootFolder(t.TempDir())()
+
+	for _, converter := range []tiler.CoordinateConverterType{tiler.CoordinateConverterProj4, tiler.CoordinateConverterNative} {
+		_, err := std_algorithm_manager.NewAlgorithmManager(
+			&tiler.TilerOptions{
+				Algorithm:           tiler.Grid,
+				CoordinateConverter: converter,
+			},
+		)
+		if err == nil || !strings.Contains(err.Error(), "epsg projection file") {
+			t.Errorf("Expected the error loading the epsg database with the %s converter, got: %v", converter, err)
+		}
+	}
+}
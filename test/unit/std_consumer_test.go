@@ -1,30 +1,36 @@
 package unit
 
 import (
+	"context"
 	"encoding/binary"
 	"encoding/json"
 	"io/ioutil"
 	"math"
 	"os"
 	"path"
+	"strings"
 	"sync"
 	"testing"
 
-	"github.com/ecopia-map/cesium_tiler/internal/converters/coordinate/proj4_coordinate_converter"
 	"github.com/ecopia-map/cesium_tiler/internal/data"
 	"github.com/ecopia-map/cesium_tiler/internal/geometry"
 	"github.com/ecopia-map/cesium_tiler/internal/io"
 	"github.com/ecopia-map/cesium_tiler/internal/octree"
 	"github.com/ecopia-map/cesium_tiler/internal/tiler"
-	"github.com/ecopia-map/cesium_tiler/tools"
 )
 
+// Returns a consumer writing pnts tiles with region bounding volumes and the default batch attributes, without
+// compression
+func newTestStandardConsumer(t *testing.T, refineMode tiler.RefineMode) *io.StandardConsumer {
+	return io.NewStandardConsumer(newTestProj4CoordinateConverter(t), refineMode, false, "", tiler.DracoMethodKdTree, 0, tiler.OutputFormatPnts, false, tiler.BoundingVolumeRegion, []tiler.BatchAttribute{tiler.BatchAttributeIntensity, tiler.BatchAttributeClassification})
+}
+
 func TestConsumerSinglePointNoChildrenEPSG4326(t *testing.T) {
 	// generate mock node with one point and no children
 	node := &mockNode{
 		boundingBox: geometry.NewBoundingBox(13.7995147, 13.7995147, 42.3306312, 42.3306312, 0, 1),
 		points: []*data.Point{
-			data.NewPoint(13.7995147, 42.3306312, 1, 1, 2, 3, 4, 5),
+			data.NewPoint(13.7995147, 42.3306312, 1, 1, 2, 3, 4, 5, nil),
 		},
 		depth:               1,
 		internalSrid:        4326,
@@ -35,9 +41,8 @@ func TestConsumerSinglePointNoChildrenEPSG4326(t *testing.T) {
 		},
 	}
 
-	// generate a temp dir and defer its deletion
-	tempdir, _ := ioutil.TempDir(tools.GetRootFolder(), "temp*")
-	defer func() { _ = os.RemoveAll(tempdir) }()
+	// generate a temp dir deleted when the test ends
+	tempdir := t.TempDir()
 
 	// generate a mock workunit
 	workUnit := io.WorkUnit{
@@ -55,8 +60,8 @@ func TestConsumerSinglePointNoChildrenEPSG4326(t *testing.T) {
 	waitGroup.Add(1)
 
 	// start consumer
-	consumer := io.NewStandardConsumer(proj4_coordinate_converter.NewProj4CoordinateConverter(), tiler.RefineModeAdd, false)
-	go consumer.Consume(workChannel, errorChannel, &waitGroup)
+	consumer := newTestStandardConsumer(t, tiler.RefineModeAdd)
+	go consumer.Consume(context.Background(), workChannel, errorChannel, &waitGroup)
 
 	// inject work unit in channel
 	workChannel <- &workUnit
@@ -154,8 +159,8 @@ func TestConsumerSinglePointNoChildrenEPSG4326(t *testing.T) {
 
 	_, err = pntsFile.Read(buffer)
 	var length = binary.LittleEndian.Uint32(buffer)
-	if length != 175 {
-		t.Errorf("Expected len value: %d, got: %d", 175, length)
+	if length != 347 {
+		t.Errorf("Expected len value: %d, got: %d", 347, length)
 	}
 
 	_, err = pntsFile.Read(buffer)
@@ -172,14 +177,14 @@ func TestConsumerSinglePointNoChildrenEPSG4326(t *testing.T) {
 
 	_, err = pntsFile.Read(buffer)
 	var batchTableLen = binary.LittleEndian.Uint32(buffer)
-	if batchTableLen != 164 {
-		t.Errorf("Expected batch table length: %d, got: %d", 164, batchTableLen)
+	if batchTableLen != 169 {
+		t.Errorf("Expected batch table length: %d, got: %d", 169, batchTableLen)
 	}
 
 	_, err = pntsFile.Read(buffer)
 	var intensityAndClassificationLen = binary.LittleEndian.Uint32(buffer)
-	if intensityAndClassificationLen != 2 {
-		t.Errorf("Expected intensity and classification sections length: %d, got: %d", 2, intensityAndClassificationLen)
+	if intensityAndClassificationLen != 3 {
+		t.Errorf("Expected intensity and classification sections length: %d, got: %d", 3, intensityAndClassificationLen)
 	}
 
 	buffer = make([]byte, 132)
@@ -232,26 +237,26 @@ func TestConsumerSinglePointNoChildrenEPSG4326(t *testing.T) {
 		t.Errorf("Expected blue: %d, got: %d", 3, blue)
 	}
 
-	buffer = make([]byte, 164)
+	buffer = make([]byte, batchTableLen)
 	_, err = pntsFile.Read(buffer)
-	var batchTable = string(buffer)
-	var expectedBatchTable = "{\"INTENSITY\":{\"byteOffset\":0, \"componentType\":\"UNSIGNED_BYTE\", \"type\":\"SCALAR\"},\"CLASSIFICATION\":{\"byteOffset\":1, \"componentType\":\"UNSIGNED_BYTE\", \"type\":\"SCALAR\"}}"
+	var batchTable = strings.TrimRight(string(buffer), " ")
+	var expectedBatchTable = "{\"INTENSITY\":{\"byteOffset\":0, \"componentType\":\"UNSIGNED_SHORT\", \"type\":\"SCALAR\"},\"CLASSIFICATION\":{\"byteOffset\":2, \"componentType\":\"UNSIGNED_BYTE\", \"type\":\"SCALAR\"}}"
 	if batchTable != expectedBatchTable {
 		t.Errorf("Expected batch table: \r\n %s \r\n Got: %s", expectedBatchTable, batchTable)
 	}
 
-	buffer = make([]byte, 1)
+	buffer = make([]byte, 2)
 	_, err = pntsFile.Read(buffer)
-	var intensity = buffer[0]
+	var intensity = binary.LittleEndian.Uint16(buffer)
 	if intensity != 4 {
-		t.Errorf("Expected blue: %d, got: %d", 4, intensity)
+		t.Errorf("Expected intensity: %d, got: %d", 4, intensity)
 	}
 
 	buffer = make([]byte, 1)
 	_, err = pntsFile.Read(buffer)
 	var classification = buffer[0]
 	if classification != 5 {
-		t.Errorf("Expected blue: %d, got: %d", 5, intensity)
+		t.Errorf("Expected classification: %d, got: %d", 5, classification)
 	}
 }
 
@@ -260,7 +265,7 @@ func TestConsumerSinglePointNoChildrenEPSG32633(t *testing.T) {
 	node := &mockNode{
 		boundingBox: geometry.NewBoundingBox(401094.30, 401094.30, 4687184.70, 4687184.70, 0, 1),
 		points: []*data.Point{
-			data.NewPoint(401094.30, 4687184.70, 1, 1, 2, 3, 4, 5),
+			data.NewPoint(401094.30, 4687184.70, 1, 1, 2, 3, 4, 5, nil),
 		},
 		depth:               1,
 		internalSrid:        32633,
@@ -271,9 +276,8 @@ func TestConsumerSinglePointNoChildrenEPSG32633(t *testing.T) {
 		},
 	}
 
-	// generate a temp dir and defer its deletion
-	tempdir, _ := ioutil.TempDir(tools.GetRootFolder(), "temp*")
-	defer func() { _ = os.RemoveAll(tempdir) }()
+	// generate a temp dir deleted when the test ends
+	tempdir := t.TempDir()
 
 	// generate a mock workunit
 	workUnit := io.WorkUnit{
@@ -291,8 +295,8 @@ func TestConsumerSinglePointNoChildrenEPSG32633(t *testing.T) {
 	waitGroup.Add(1)
 
 	// start consumer
-	consumer := io.NewStandardConsumer(proj4_coordinate_converter.NewProj4CoordinateConverter(), tiler.RefineModeAdd, false)
-	go consumer.Consume(workChannel, errorChannel, &waitGroup)
+	consumer := newTestStandardConsumer(t, tiler.RefineModeAdd)
+	go consumer.Consume(context.Background(), workChannel, errorChannel, &waitGroup)
 
 	// inject work unit in channel
 	workChannel <- &workUnit
@@ -390,8 +394,8 @@ func TestConsumerSinglePointNoChildrenEPSG32633(t *testing.T) {
 
 	_, err = pntsFile.Read(buffer)
 	var length = binary.LittleEndian.Uint32(buffer)
-	if length != 175 {
-		t.Errorf("Expected len value: %d, got: %d", 175, length)
+	if length != 347 {
+		t.Errorf("Expected len value: %d, got: %d", 347, length)
 	}
 
 	_, err = pntsFile.Read(buffer)
@@ -408,14 +412,14 @@ func TestConsumerSinglePointNoChildrenEPSG32633(t *testing.T) {
 
 	_, err = pntsFile.Read(buffer)
 	var batchTableLen = binary.LittleEndian.Uint32(buffer)
-	if batchTableLen != 164 {
-		t.Errorf("Expected batch table length: %d, got: %d", 164, batchTableLen)
+	if batchTableLen != 169 {
+		t.Errorf("Expected batch table length: %d, got: %d", 169, batchTableLen)
 	}
 
 	_, err = pntsFile.Read(buffer)
 	var intensityAndClassificationLen = binary.LittleEndian.Uint32(buffer)
-	if intensityAndClassificationLen != 2 {
-		t.Errorf("Expected intensity and classification sections length: %d, got: %d", 2, intensityAndClassificationLen)
+	if intensityAndClassificationLen != 3 {
+		t.Errorf("Expected intensity and classification sections length: %d, got: %d", 3, intensityAndClassificationLen)
 	}
 
 	buffer = make([]byte, 132)
@@ -468,26 +472,26 @@ func TestConsumerSinglePointNoChildrenEPSG32633(t *testing.T) {
 		t.Errorf("Expected blue: %d, got: %d", 3, blue)
 	}
 
-	buffer = make([]byte, 164)
+	buffer = make([]byte, batchTableLen)
 	_, err = pntsFile.Read(buffer)
-	var batchTable = string(buffer)
-	var expectedBatchTable = "{\"INTENSITY\":{\"byteOffset\":0, \"componentType\":\"UNSIGNED_BYTE\", \"type\":\"SCALAR\"},\"CLASSIFICATION\":{\"byteOffset\":1, \"componentType\":\"UNSIGNED_BYTE\", \"type\":\"SCALAR\"}}"
+	var batchTable = strings.TrimRight(string(buffer), " ")
+	var expectedBatchTable = "{\"INTENSITY\":{\"byteOffset\":0, \"componentType\":\"UNSIGNED_SHORT\", \"type\":\"SCALAR\"},\"CLASSIFICATION\":{\"byteOffset\":2, \"componentType\":\"UNSIGNED_BYTE\", \"type\":\"SCALAR\"}}"
 	if batchTable != expectedBatchTable {
 		t.Errorf("Expected batch table: \r\n %s \r\n Got: %s", expectedBatchTable, batchTable)
 	}
 
-	buffer = make([]byte, 1)
+	buffer = make([]byte, 2)
 	_, err = pntsFile.Read(buffer)
-	var intensity = buffer[0]
+	var intensity = binary.LittleEndian.Uint16(buffer)
 	if intensity != 4 {
-		t.Errorf("Expected blue: %d, got: %d", 4, intensity)
+		t.Errorf("Expected intensity: %d, got: %d", 4, intensity)
 	}
 
 	buffer = make([]byte, 1)
 	_, err = pntsFile.Read(buffer)
 	var classification = buffer[0]
 	if classification != 5 {
-		t.Errorf("Expected blue: %d, got: %d", 5, intensity)
+		t.Errorf("Expected classification: %d, got: %d", 5, classification)
 	}
 }
 
@@ -496,7 +500,7 @@ func TestConsumerOneChild(t *testing.T) {
 	node := &mockNode{
 		boundingBox: geometry.NewBoundingBox(13.7995147, 13.7995147, 42.3306312, 42.3306312, 0, 1),
 		points: []*data.Point{
-			data.NewPoint(13.7995147, 42.3306312, 1, 1, 2, 3, 4, 5),
+			data.NewPoint(13.7995147, 42.3306312, 1, 1, 2, 3, 4, 5, nil),
 		},
 		depth:               1,
 		globalChildrenCount: 2,
@@ -509,7 +513,7 @@ func TestConsumerOneChild(t *testing.T) {
 			&mockNode{
 				boundingBox: geometry.NewBoundingBox(13.7995147, 13.7995147, 42.3306312, 42.3306312, 0.5, 1),
 				points: []*data.Point{
-					data.NewPoint(13.7995147, 42.3306312, 1, 4, 5, 6, 4, 5),
+					data.NewPoint(13.7995147, 42.3306312, 1, 4, 5, 6, 4, 5, nil),
 				},
 				depth:               1,
 				internalSrid:        4326,
@@ -522,9 +526,8 @@ func TestConsumerOneChild(t *testing.T) {
 		},
 	}
 
-	// generate a temp dir and defer its deletion
-	tempdir, _ := ioutil.TempDir(tools.GetRootFolder(), "temp*")
-	defer func() { _ = os.RemoveAll(tempdir) }()
+	// generate a temp dir deleted when the test ends
+	tempdir := t.TempDir()
 
 	// generate a mock workunit
 	workUnit := io.WorkUnit{
@@ -542,8 +545,8 @@ func TestConsumerOneChild(t *testing.T) {
 	waitGroup.Add(1)
 
 	// start consumer
-	consumer := io.NewStandardConsumer(proj4_coordinate_converter.NewProj4CoordinateConverter(), tiler.RefineModeAdd, false)
-	go consumer.Consume(workChannel, errorChannel, &waitGroup)
+	consumer := newTestStandardConsumer(t, tiler.RefineModeAdd)
+	go consumer.Consume(context.Background(), workChannel, errorChannel, &waitGroup)
 
 	// inject work unit in channel
 	workChannel <- &workUnit
@@ -668,8 +671,8 @@ func TestConsumerOneChild(t *testing.T) {
 
 	_, err = pntsFile.Read(buffer)
 	var length = binary.LittleEndian.Uint32(buffer)
-	if length != 175 {
-		t.Errorf("Expected len value: %d, got: %d", 175, length)
+	if length != 347 {
+		t.Errorf("Expected len value: %d, got: %d", 347, length)
 	}
 
 	_, err = pntsFile.Read(buffer)
@@ -686,14 +689,14 @@ func TestConsumerOneChild(t *testing.T) {
 
 	_, err = pntsFile.Read(buffer)
 	var batchTableLen = binary.LittleEndian.Uint32(buffer)
-	if batchTableLen != 164 {
-		t.Errorf("Expected batch table length: %d, got: %d", 164, batchTableLen)
+	if batchTableLen != 169 {
+		t.Errorf("Expected batch table length: %d, got: %d", 169, batchTableLen)
 	}
 
 	_, err = pntsFile.Read(buffer)
 	var intensityAndClassificationLen = binary.LittleEndian.Uint32(buffer)
-	if intensityAndClassificationLen != 2 {
-		t.Errorf("Expected intensity and classification sections length: %d, got: %d", 2, intensityAndClassificationLen)
+	if intensityAndClassificationLen != 3 {
+		t.Errorf("Expected intensity and classification sections length: %d, got: %d", 3, intensityAndClassificationLen)
 	}
 
 	buffer = make([]byte, 132)
@@ -746,26 +749,26 @@ func TestConsumerOneChild(t *testing.T) {
 		t.Errorf("Expected blue: %d, got: %d", 3, blue)
 	}
 
-	buffer = make([]byte, 164)
+	buffer = make([]byte, batchTableLen)
 	_, err = pntsFile.Read(buffer)
-	var batchTable = string(buffer)
-	var expectedBatchTable = "{\"INTENSITY\":{\"byteOffset\":0, \"componentType\":\"UNSIGNED_BYTE\", \"type\":\"SCALAR\"},\"CLASSIFICATION\":{\"byteOffset\":1, \"componentType\":\"UNSIGNED_BYTE\", \"type\":\"SCALAR\"}}"
+	var batchTable = strings.TrimRight(string(buffer), " ")
+	var expectedBatchTable = "{\"INTENSITY\":{\"byteOffset\":0, \"componentType\":\"UNSIGNED_SHORT\", \"type\":\"SCALAR\"},\"CLASSIFICATION\":{\"byteOffset\":2, \"componentType\":\"UNSIGNED_BYTE\", \"type\":\"SCALAR\"}}"
 	if batchTable != expectedBatchTable {
 		t.Errorf("Expected batch table: \r\n %s \r\n Got: %s", expectedBatchTable, batchTable)
 	}
 
-	buffer = make([]byte, 1)
+	buffer = make([]byte, 2)
 	_, err = pntsFile.Read(buffer)
-	var intensity = buffer[0]
+	var intensity = binary.LittleEndian.Uint16(buffer)
 	if intensity != 4 {
-		t.Errorf("Expected blue: %d, got: %d", 4, intensity)
+		t.Errorf("Expected intensity: %d, got: %d", 4, intensity)
 	}
 
 	buffer = make([]byte, 1)
 	_, err = pntsFile.Read(buffer)
 	var classification = buffer[0]
 	if classification != 5 {
-		t.Errorf("Expected blue: %d, got: %d", 5, intensity)
+		t.Errorf("Expected classification: %d, got: %d", 5, classification)
 	}
 }
 
@@ -774,7 +777,7 @@ func TestConsumerOneChildRefineModeReplace(t *testing.T) {
 	node := &mockNode{
 		boundingBox: geometry.NewBoundingBox(13.6, 13.7995147, 42.3, 42.3306312, 0, 1),
 		points: []*data.Point{
-			data.NewPoint(13.7995147, 42.3306312, 1, 1, 2, 3, 4, 5),
+			data.NewPoint(13.7995147, 42.3306312, 1, 1, 2, 3, 4, 5, nil),
 		},
 		depth:               1,
 		globalChildrenCount: 2,
@@ -790,7 +793,7 @@ func TestConsumerOneChildRefineModeReplace(t *testing.T) {
 			parent:      node,
 			boundingBox: geometry.NewBoundingBox(13.6, 13.7995147, 42.3, 42.3306312, 0.5, 1),
 			points: []*data.Point{
-				data.NewPoint(13.6, 42.3, 1, 7, 8, 9, 10, 11),
+				data.NewPoint(13.6, 42.3, 1, 7, 8, 9, 10, 11, nil),
 			},
 			depth:               1,
 			internalSrid:        4326,
@@ -801,9 +804,8 @@ func TestConsumerOneChildRefineModeReplace(t *testing.T) {
 			},
 		}
 
-	// generate a temp dir and defer its deletion
-	tempdir, _ := ioutil.TempDir(tools.GetRootFolder(), "temp*")
-	defer func() { _ = os.RemoveAll(tempdir) }()
+	// generate a temp dir deleted when the test ends
+	tempdir := t.TempDir()
 
 	// generate a mock workunit
 	workUnit := io.WorkUnit{
@@ -826,8 +828,8 @@ func TestConsumerOneChildRefineModeReplace(t *testing.T) {
 	waitGroup.Add(1)
 
 	// start consumer
-	consumer := io.NewStandardConsumer(proj4_coordinate_converter.NewProj4CoordinateConverter(), tiler.RefineModeReplace, false)
-	go consumer.Consume(workChannel, errorChannel, &waitGroup)
+	consumer := newTestStandardConsumer(t, tiler.RefineModeReplace)
+	go consumer.Consume(context.Background(), workChannel, errorChannel, &waitGroup)
 
 	// inject work unit in channel
 	workChannel <- &workUnit
@@ -953,8 +955,8 @@ func TestConsumerOneChildRefineModeReplace(t *testing.T) {
 
 	_, err = pntsFile.Read(buffer)
 	var length = binary.LittleEndian.Uint32(buffer)
-	if length != 175 {
-		t.Errorf("Expected len value: %d, got: %d", 175, length)
+	if length != 347 {
+		t.Errorf("Expected len value: %d, got: %d", 347, length)
 	}
 
 	_, err = pntsFile.Read(buffer)
@@ -971,14 +973,14 @@ func TestConsumerOneChildRefineModeReplace(t *testing.T) {
 
 	_, err = pntsFile.Read(buffer)
 	var batchTableLen = binary.LittleEndian.Uint32(buffer)
-	if batchTableLen != 164 {
-		t.Errorf("Expected batch table length: %d, got: %d", 164, batchTableLen)
+	if batchTableLen != 169 {
+		t.Errorf("Expected batch table length: %d, got: %d", 169, batchTableLen)
 	}
 
 	_, err = pntsFile.Read(buffer)
 	var intensityAndClassificationLen = binary.LittleEndian.Uint32(buffer)
-	if intensityAndClassificationLen != 2 {
-		t.Errorf("Expected intensity and classification sections length: %d, got: %d", 2, intensityAndClassificationLen)
+	if intensityAndClassificationLen != 3 {
+		t.Errorf("Expected intensity and classification sections length: %d, got: %d", 3, intensityAndClassificationLen)
 	}
 
 	buffer = make([]byte, 132)
@@ -1031,17 +1033,17 @@ func TestConsumerOneChildRefineModeReplace(t *testing.T) {
 		t.Errorf("Expected blue: %d, got: %d", 3, blue)
 	}
 
-	buffer = make([]byte, 164)
+	buffer = make([]byte, batchTableLen)
 	_, err = pntsFile.Read(buffer)
-	var batchTable = string(buffer)
-	var expectedBatchTable = "{\"INTENSITY\":{\"byteOffset\":0, \"componentType\":\"UNSIGNED_BYTE\", \"type\":\"SCALAR\"},\"CLASSIFICATION\":{\"byteOffset\":1, \"componentType\":\"UNSIGNED_BYTE\", \"type\":\"SCALAR\"}}"
+	var batchTable = strings.TrimRight(string(buffer), " ")
+	var expectedBatchTable = "{\"INTENSITY\":{\"byteOffset\":0, \"componentType\":\"UNSIGNED_SHORT\", \"type\":\"SCALAR\"},\"CLASSIFICATION\":{\"byteOffset\":2, \"componentType\":\"UNSIGNED_BYTE\", \"type\":\"SCALAR\"}}"
 	if batchTable != expectedBatchTable {
 		t.Errorf("Expected batch table: \r\n %s \r\n Got: %s", expectedBatchTable, batchTable)
 	}
 
-	buffer = make([]byte, 1)
+	buffer = make([]byte, 2)
 	_, err = pntsFile.Read(buffer)
-	var intensity = buffer[0]
+	var intensity = binary.LittleEndian.Uint16(buffer)
 	if intensity != 4 {
 		t.Errorf("Expected intensity: %d, got: %d", 4, intensity)
 	}
@@ -1079,8 +1081,8 @@ func TestConsumerOneChildRefineModeReplace(t *testing.T) {
 
 	_, err = pntsFile2.Read(buffer)
 	length = binary.LittleEndian.Uint32(buffer)
-	if length != 190 {
-		t.Errorf("Expected len value: %d, got: %d", 190, length)
+	if length != 366 {
+		t.Errorf("Expected len value: %d, got: %d", 366, length)
 	}
 
 	_, err = pntsFile2.Read(buffer)
@@ -1097,14 +1099,14 @@ func TestConsumerOneChildRefineModeReplace(t *testing.T) {
 
 	_, err = pntsFile2.Read(buffer)
 	batchTableLen = binary.LittleEndian.Uint32(buffer)
-	if batchTableLen != 164 {
-		t.Errorf("Expected batch table length: %d, got: %d", 164, batchTableLen)
+	if batchTableLen != 170 {
+		t.Errorf("Expected batch table length: %d, got: %d", 170, batchTableLen)
 	}
 
 	_, err = pntsFile2.Read(buffer)
 	intensityAndClassificationLen = binary.LittleEndian.Uint32(buffer)
-	if intensityAndClassificationLen != 4 {
-		t.Errorf("Expected intensity and classification sections length: %d, got: %d", 4, intensityAndClassificationLen)
+	if intensityAndClassificationLen != 6 {
+		t.Errorf("Expected intensity and classification sections length: %d, got: %d", 6, intensityAndClassificationLen)
 	}
 
 	buffer = make([]byte, 132)
@@ -1199,24 +1201,24 @@ func TestConsumerOneChildRefineModeReplace(t *testing.T) {
 		t.Errorf("Expected blue: %d, got: %d", 3, blue)
 	}
 
-	buffer = make([]byte, 164)
+	buffer = make([]byte, batchTableLen)
 	_, err = pntsFile2.Read(buffer)
-	batchTable = string(buffer)
-	expectedBatchTable = "{\"INTENSITY\":{\"byteOffset\":0, \"componentType\":\"UNSIGNED_BYTE\", \"type\":\"SCALAR\"},\"CLASSIFICATION\":{\"byteOffset\":2, \"componentType\":\"UNSIGNED_BYTE\", \"type\":\"SCALAR\"}}"
+	batchTable = strings.TrimRight(string(buffer), " ")
+	expectedBatchTable = "{\"INTENSITY\":{\"byteOffset\":0, \"componentType\":\"UNSIGNED_SHORT\", \"type\":\"SCALAR\"},\"CLASSIFICATION\":{\"byteOffset\":4, \"componentType\":\"UNSIGNED_BYTE\", \"type\":\"SCALAR\"}}"
 	if batchTable != expectedBatchTable {
 		t.Errorf("Expected batch table: \r\n %s \r\n Got: %s", expectedBatchTable, batchTable)
 	}
 
-	buffer = make([]byte, 1)
+	buffer = make([]byte, 2)
 	_, err = pntsFile2.Read(buffer)
-	intensity = buffer[0]
+	intensity = binary.LittleEndian.Uint16(buffer)
 	if intensity != 10 {
 		t.Errorf("Expected intensity: %d, got: %d", 10, intensity)
 	}
 
-	buffer = make([]byte, 1)
+	buffer = make([]byte, 2)
 	_, err = pntsFile2.Read(buffer)
-	intensity = buffer[0]
+	intensity = binary.LittleEndian.Uint16(buffer)
 	if intensity != 4 {
 		t.Errorf("Expected intensity: %d, got: %d", 4, intensity)
 	}
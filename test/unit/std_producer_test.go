@@ -1,14 +1,16 @@
 package unit
 
 import (
+	"context"
+	"path"
+	"sync"
+	"testing"
+
 	"github.com/ecopia-map/cesium_tiler/internal/data"
 	"github.com/ecopia-map/cesium_tiler/internal/geometry"
 	"github.com/ecopia-map/cesium_tiler/internal/io"
 	"github.com/ecopia-map/cesium_tiler/internal/octree"
 	"github.com/ecopia-map/cesium_tiler/internal/tiler"
-	"path"
-	"sync"
-	"testing"
 )
 
 func TestProducerInjectsWorkUnits(t *testing.T) {
@@ -19,7 +21,7 @@ func TestProducerInjectsWorkUnits(t *testing.T) {
 	rootNode := &mockNode{
 		boundingBox: geometry.NewBoundingBox(13.7995147, 13.7995147, 42.3306312, 42.3306312, 0, 1),
 		points: []*data.Point{
-			data.NewPoint(13.7995147, 42.3306312, 1, 1, 2, 3, 4, 5),
+			data.NewPoint(13.7995147, 42.3306312, 1, 1, 2, 3, 4, 5, nil),
 		},
 		depth:               1,
 		globalChildrenCount: 2,
@@ -30,7 +32,7 @@ func TestProducerInjectsWorkUnits(t *testing.T) {
 			&mockNode{
 				boundingBox: geometry.NewBoundingBox(13.7995147, 13.7995147, 42.3306312, 42.3306312, 0.5, 1),
 				points: []*data.Point{
-					data.NewPoint(13.7995147, 42.3306312, 1, 4, 5, 6, 4, 5),
+					data.NewPoint(13.7995147, 42.3306312, 1, 4, 5, 6, 4, 5, nil),
 				},
 				depth:               1,
 				globalChildrenCount: 1,
@@ -45,7 +47,7 @@ func TestProducerInjectsWorkUnits(t *testing.T) {
 	var waitGroup sync.WaitGroup
 	waitGroup.Add(1)
 	producer := io.NewStandardProducer("basepath", "", &opts)
-	producer.Produce(workChannel, &waitGroup, rootNode)
+	producer.Produce(context.Background(), workChannel, &waitGroup, rootNode)
 	waitGroup.Wait() // if the test waits here indefinitely then producer is not deregistering itself from the waitgroup with waitGroup.Done()
 
 	if len(workChannel) != 2 {
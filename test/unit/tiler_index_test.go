@@ -0,0 +1,129 @@
+package unit_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/ecopia-map/cesium_tiler/internal/tiler"
+	"github.com/ecopia-map/cesium_tiler/pkg"
+	"github.com/ecopia-map/cesium_tiler/pkg/algorithm_manager/std_algorithm_manager"
+	"github.com/ecopia-map/cesium_tiler/pkg/api"
+)
+
+// indexTestFileFinder is a tools.FileFinder returning a fixed list of las files
+type indexTestFileFinder struct {
+	files []string
+}
+
+func (f *indexTestFileFinder) GetLasFilesToProcess(opts *tiler.TilerOptions) ([]string, error) {
+	return f.files, nil
+}
+
+func (f *indexTestFileFinder) GetLasFilesToMerge(opts *tiler.TilerOptions) ([]string, error) {
+	return f.files, nil
+}
+
+// Returns the options indexing the LAZ fixtures in the given output folder with the given number of jobs
+func indexTestOptions(output string, jobs int) *tiler.TilerOptions {
+	opts := api.DefaultIndexOptions("", output)
+	opts.Srid = 32633
+	opts.CoordinateConverter = tiler.CoordinateConverterNative
+	opts.BatchAttributes = nil
+	opts.TilerIndexOptions.Jobs = jobs
+	opts.TilerIndexOptions.Workers = 2
+	return opts
+}
+
+// Returns the index tiler processing the given files with the given options
+func newIndexTestTiler(t *testing.T, files []string, opts *tiler.TilerOptions) tiler.ITiler {
+	algorithmManager, err := std_algorithm_manager.NewAlgorithmManager(opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	return pkg.NewTiler(&indexTestFileFinder{files: files}, algorithmManager)
+}
+
+func TestIndexJobResources(t *testing.T) {
+	testData := []struct {
+		workers              int
+		memoryBudget         int
+		numJobs              int
+		expectedWorkers      int
+		expectedMemoryBudget int
+	}{
+		{workers: 8, memoryBudget: 1000, numJobs: 1, expectedWorkers: 8, expectedMemoryBudget: 1000},
+		{workers: 8, memoryBudget: 1000, numJobs: 4, expectedWorkers: 2, expectedMemoryBudget: 250},
+		{workers: 8, memoryBudget: 1000, numJobs: 3, expectedWorkers: 2, expectedMemoryBudget: 333},
+		// every job gets at least a worker and 1 MB
+		{workers: 2, memoryBudget: 3, numJobs: 5, expectedWorkers: 1, expectedMemoryBudget: 1},
+		// no budget keeps all the points in memory
+		{workers: 4, memoryBudget: 0, numJobs: 2, expectedWorkers: 2, expectedMemoryBudget: 0},
+		// no workers uses one per CPU
+		{workers: 0, memoryBudget: 0, numJobs: 1, expectedWorkers: runtime.NumCPU(), expectedMemoryBudget: 0},
+	}
+
+	for _, data := range testData {
+		opts := &tiler.TilerIndexOptions{Workers: data.workers, MemoryBudget: data.memoryBudget}
+		numWorkers, memoryBudget := opts.JobResources(data.numJobs)
+		if numWorkers != data.expectedWorkers || memoryBudget != data.expectedMemoryBudget {
+			t.Errorf(
+				"Workers %d and budget %d split among %d jobs: expected %d workers and %d MB, got %d workers and %d MB",
+				data.workers, data.memoryBudget, data.numJobs, data.expectedWorkers, data.expectedMemoryBudget, numWorkers, memoryBudget,
+			)
+		}
+	}
+}
+
+func TestIndexRejectsConcurrentFilesWritingTheSameChunk(t *testing.T) {
+	files := []string{filepath.Join("a", "points.las"), filepath.Join("b", "points.las")}
+
+	opts := indexTestOptions(t.TempDir(), 2)
+	err := newIndexTestTiler(t, files, opts).RunTiler(context.Background(), opts)
+	if err == nil || !strings.Contains(err.Error(), "cannot be processed concurrently") {
+		t.Fatalf("Expected the run to be rejected, got: %v", err)
+	}
+
+	// a single job writes the chunks one after the other, the files are then read and found missing
+	opts = indexTestOptions(t.TempDir(), 1)
+	err = newIndexTestTiler(t, files, opts).RunTiler(context.Background(), opts)
+	if err == nil || strings.Contains(err.Error(), "cannot be processed concurrently") {
+		t.Fatalf("Expected the run to fail reading the files, got: %v", err)
+	}
+}
+
+func TestIndexJobErrorDoesNotStopOtherJobs(t *testing.T) {
+	for _, jobs := range []int{1, 2} {
+		output := t.TempDir()
+		files := []string{
+			filepath.Join(t.TempDir(), "missing.las"),
+			filepath.Join(lazTestDataFolder, "format3.las"),
+			filepath.Join(lazTestDataFolder, "format1.laz"),
+		}
+
+		opts := indexTestOptions(output, jobs)
+		err := newIndexTestTiler(t, files, opts).RunTiler(context.Background(), opts)
+		if err == nil || !strings.Contains(err.Error(), "missing.las") {
+			t.Fatalf("Jobs %d: expected the error of the missing file, got: %v", jobs, err)
+		}
+
+		manifest, err := pkg.LoadIndexManifest(output)
+		if err != nil {
+			t.Fatalf("Jobs %d: unexpected error reading the manifest: %s", jobs, err)
+		}
+		for _, file := range files[1:] {
+			absFilePath, _ := filepath.Abs(file)
+			entry := manifest.GetEntry(absFilePath)
+			if entry == nil || entry.Status != pkg.ChunkStatusCompleted {
+				t.Errorf("Jobs %d: expected the chunk of %s to be completed, got %+v", jobs, file, entry)
+				continue
+			}
+			if _, err := os.Stat(filepath.Join(output, entry.Subfolder, "tileset.json")); err != nil {
+				t.Errorf("Jobs %d: expected the tileset of %s to be written: %s", jobs, file, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package unit_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ecopia-map/cesium_tiler/internal/tiler"
+	"github.com/ecopia-map/cesium_tiler/pkg"
+	"github.com/ecopia-map/cesium_tiler/pkg/api"
+	"github.com/ecopia-map/cesium_tiler/tools"
+)
+
+// Copies the given LAZ fixture to the given file
+func copyUpdateTestLasFile(t *testing.T, fileName string, filePath string) {
+	content, err := ioutil.ReadFile(filepath.Join(lazTestDataFolder, fileName))
+	if err != nil {
+		t.Fatalf("Unexpected error reading %s: %s", fileName, err)
+	}
+	if err := ioutil.WriteFile(filePath, content, 0666); err != nil {
+		t.Fatalf("Unexpected error writing %s: %s", filePath, err)
+	}
+}
+
+// Sets the modification time of all the files of the given folder, returning it by file path
+func setUpdateTestModTimes(t *testing.T, folder string, modTime time.Time) map[string]time.Time {
+	modTimes := make(map[string]time.Time)
+	err := filepath.Walk(folder, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		modTimes[filePath] = modTime
+		return os.Chtimes(filePath, modTime, modTime)
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error setting the modification times: %s", err)
+	}
+	return modTimes
+}
+
+// Returns true if the given file has been written after the given modification time
+func isUpdateTestFileRewritten(t *testing.T, filePath string, modTime time.Time) bool {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	return !info.ModTime().Equal(modTime)
+}
+
+func TestUpdateMergesOnlyTheAncestorsOfTheChangedFile(t *testing.T) {
+	lasFolder := t.TempDir()
+	root := t.TempDir()
+	changedFile := filepath.Join(lasFolder, "a.las")
+	copyUpdateTestLasFile(t, "format3.las", changedFile)
+	copyUpdateTestLasFile(t, "format3_chunks.laz", filepath.Join(lasFolder, "b.laz"))
+
+	// root/a and root/b hold the chunk tileset of a file each and are merged up to the root
+	for folder, fileName := range map[string]string{"a": "a.las", "b": "b.laz"} {
+		if err := os.Mkdir(filepath.Join(root, folder), 0777); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		opts := apiTestIndexOptions(fileName, filepath.Join(root, folder))
+		opts.Input = filepath.Join(lasFolder, fileName)
+		if _, err := api.Index(context.Background(), opts); err != nil {
+			t.Fatalf("Unexpected error indexing %s: %s", fileName, err)
+		}
+	}
+	mergeOpts := api.DefaultMergeOptions(root)
+	mergeOpts.Srid = 32633
+	mergeOpts.CoordinateConverter = api.CoordinateConverterNative
+	if _, err := api.Merge(context.Background(), mergeOpts); err != nil {
+		t.Fatalf("Unexpected error merging: %s", err)
+	}
+
+	modTime := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	modTimes := setUpdateTestModTimes(t, root, modTime)
+	touched := time.Now().Add(time.Hour)
+	if err := os.Chtimes(changedFile, touched, touched); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	updateOpts := apiTestIndexOptions("", "")
+	updateOpts.Input = root
+	updateOpts.Command = tools.CommandUpdate
+	updateOpts.TilerUpdateOptions = &tiler.TilerUpdateOptions{
+		MergeGridMaxSize:         mergeOpts.CellMaxSize,
+		MergeMinNumPointsPerNode: mergeOpts.MinNumPointsPerNode,
+		MergeMaxNumPointsPerNode: mergeOpts.MaxNumPointsPerNode,
+	}
+	if err := pkg.NewTilerUpdate(tools.NewStandardFileFinder()).RunTiler(context.Background(), updateOpts); err != nil {
+		t.Fatalf("Unexpected error updating: %s", err)
+	}
+
+	// the chunk of the changed file and its ancestor folders are written again
+	for _, filePath := range []string{
+		filepath.Join(root, "a", tools.ChunkTilesetFilePrefix+"a", "tileset.json"),
+		filepath.Join(root, "a", "tileset.json"),
+		filepath.Join(root, "tileset.json"),
+	} {
+		if !isUpdateTestFileRewritten(t, filePath, modTime) {
+			t.Errorf("Expected %s to be written again", filePath)
+		}
+	}
+
+	// the sibling folder is left untouched
+	numSiblingFiles := 0
+	for filePath := range modTimes {
+		if rel, _ := filepath.Rel(root, filePath); strings.HasPrefix(rel, "b"+string(filepath.Separator)) {
+			numSiblingFiles++
+			if isUpdateTestFileRewritten(t, filePath, modTime) {
+				t.Errorf("Expected %s not to be written again", filePath)
+			}
+		}
+	}
+	if numSiblingFiles == 0 {
+		t.Fatalf("Expected the sibling folder to hold files")
+	}
+
+	manifest, err := pkg.LoadIndexManifest(filepath.Join(root, "a"))
+	if err != nil {
+		t.Fatalf("Unexpected error reading the manifest: %s", err)
+	}
+	info, err := os.Stat(changedFile)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if entry := manifest.GetEntry(changedFile); entry == nil || entry.Status != pkg.ChunkStatusCompleted || !entry.ModTime.Equal(info.ModTime()) {
+		t.Errorf("Expected the manifest entry of the changed file to be updated, got %+v", entry)
+	}
+}
@@ -0,0 +1,92 @@
+package unit_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ecopia-map/cesium_tiler/internal/tiler"
+	"github.com/ecopia-map/cesium_tiler/pkg"
+	"github.com/ecopia-map/cesium_tiler/pkg/algorithm_manager/std_algorithm_manager"
+	lidario "github.com/ecopia-map/cesium_tiler/third_party/lasread"
+	"github.com/ecopia-map/cesium_tiler/tools"
+)
+
+// Returns the options verifying the given las file or folder with the given verify command
+func verifyTestOptions(command string, input string) *tiler.TilerOptions {
+	opts := indexTestOptions("", 1)
+	opts.Command = command
+	opts.Input = input
+	opts.TilerIndexOptions = nil
+	opts.TilerVerifyOptions = &tiler.TilerVerifyOptions{OffsetEnd: -1}
+	return opts
+}
+
+// Runs the verify command of the given options
+func runVerifyTest(t *testing.T, opts *tiler.TilerOptions) error {
+	algorithmManager, err := std_algorithm_manager.NewAlgorithmManager(opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	defer algorithmManager.GetCoordinateConverterAlgorithm().Cleanup()
+	return pkg.NewTilerVerify(tools.NewStandardFileFinder(), algorithmManager).RunTiler(context.Background(), opts)
+}
+
+func TestVerifyLasReturnsErrors(t *testing.T) {
+	if err := runVerifyTest(t, verifyTestOptions(tools.CommandVerifyLas, filepath.Join(lazTestDataFolder, "format3.las"))); err != nil {
+		t.Errorf("Unexpected error verifying a valid las file: %s", err)
+	}
+
+	if err := runVerifyTest(t, verifyTestOptions(tools.CommandVerifyLas, filepath.Join(t.TempDir(), "missing.las"))); err == nil {
+		t.Errorf("Expected an error verifying a missing las file")
+	}
+
+	if err := runVerifyTest(t, verifyTestOptions(tools.CommandVerifyLasMerge, t.TempDir())); err == nil {
+		t.Errorf("Expected an error merging a folder without chunk las files")
+	}
+}
+
+func TestVerifyLasMergeKeepsMergedLasFile(t *testing.T) {
+	input := t.TempDir()
+	numPoints := 0
+	for _, name := range []string{"format1.las", "format3.las"} {
+		content, err := ioutil.ReadFile(filepath.Join(lazTestDataFolder, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		chunkFolder := filepath.Join(input, name[:len(name)-len(filepath.Ext(name))])
+		if err := os.Mkdir(chunkFolder, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(chunkFolder, "content.las"), content, 0644); err != nil {
+			t.Fatal(err)
+		}
+		lf, err := lidario.NewLasFile(filepath.Join(chunkFolder, "content.las"), "rh")
+		if err != nil {
+			t.Fatal(err)
+		}
+		numPoints += lf.Header.NumberPoints
+		lf.Close()
+	}
+
+	opts := verifyTestOptions(tools.CommandVerifyLasMerge, input)
+	opts.WorkDir = t.TempDir()
+	if err := runVerifyTest(t, opts); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	mergedLasFilePaths, err := filepath.Glob(filepath.Join(opts.WorkDir, "*", pkg.MergedLasFileName))
+	if err != nil || len(mergedLasFilePaths) != 1 {
+		t.Fatalf("Expected the merged las file to be kept in the work folder, found %v", mergedLasFilePaths)
+	}
+	mergedLf, err := lidario.NewLasFile(mergedLasFilePaths[0], "rh")
+	if err != nil {
+		t.Fatalf("Unable to open the merged las file: %s", err)
+	}
+	defer mergedLf.Close()
+	if mergedLf.Header.NumberPoints != numPoints {
+		t.Errorf("Expected %d merged points, got %d", numPoints, mergedLf.Header.NumberPoints)
+	}
+}
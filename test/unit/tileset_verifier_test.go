@@ -0,0 +1,249 @@
+package unit_test
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ecopia-map/cesium_tiler/pkg"
+)
+
+// Tileset with a single root tile whose content uri is replaced by the one of the test
+const verifierTestTileset = `{
+	"asset": {"version": "1.0"},
+	"geometricError": 10,
+	"root": {
+		"boundingVolume": {"region": [0.1, 0.2, 0.3, 0.4, 0, 10]},
+		"geometricError": 5,
+		"refine": "ADD",
+		"content": {"uri": "CONTENT_URI"}
+	}
+}`
+
+// Implicit tileset whose root subtree and content are written by the test
+const verifierTestImplicitTileset = `{
+	"asset": {"version": "1.1"},
+	"geometricError": 10,
+	"root": {
+		"boundingVolume": {"region": [0.1, 0.2, 0.3, 0.4, 0, 10]},
+		"geometricError": 5,
+		"refine": "ADD",
+		"content": {"uri": "content/{level}/{x}/{y}/{z}.glb"},
+		"implicitTiling": {
+			"subdivisionScheme": "OCTREE",
+			"subtreeLevels": 2,
+			"availableLevels": 2,
+			"subtrees": {"uri": "subtrees/{level}/{x}/{y}/{z}.subtree"}
+		}
+	}
+}`
+
+// Returns a pnts file made of the given feature and batch tables
+func buildVerifierTestPnts(featureTableJson string, featureTableBinary []byte, batchTableJson string, batchTableBinary []byte) []byte {
+	byteLength := 28 + len(featureTableJson) + len(featureTableBinary) + len(batchTableJson) + len(batchTableBinary)
+	content := make([]byte, 28, byteLength)
+	copy(content[0:4], "pnts")
+	binary.LittleEndian.PutUint32(content[4:8], 1)
+	binary.LittleEndian.PutUint32(content[8:12], uint32(byteLength))
+	binary.LittleEndian.PutUint32(content[12:16], uint32(len(featureTableJson)))
+	binary.LittleEndian.PutUint32(content[16:20], uint32(len(featureTableBinary)))
+	binary.LittleEndian.PutUint32(content[20:24], uint32(len(batchTableJson)))
+	binary.LittleEndian.PutUint32(content[24:28], uint32(len(batchTableBinary)))
+	content = append(content, featureTableJson...)
+	content = append(content, featureTableBinary...)
+	content = append(content, batchTableJson...)
+	return append(content, batchTableBinary...)
+}
+
+// Returns a pnts file holding two points with their position
+func buildValidVerifierTestPnts() []byte {
+	return buildVerifierTestPnts(`{"POINTS_LENGTH":2,"POSITION":{"byteOffset":0}}`, make([]byte, 24), "", nil)
+}
+
+// Returns a glb file made of a json chunk and a binary chunk
+func buildVerifierTestGlb() []byte {
+	jsonChunk := []byte(`{"asset":{"version":"2.0"}}`)
+	binaryChunk := make([]byte, 8)
+	content := make([]byte, 12)
+	copy(content[0:4], "glTF")
+	binary.LittleEndian.PutUint32(content[4:8], 2)
+	for _, chunk := range []struct {
+		chunkType string
+		data      []byte
+	}{{"JSON", jsonChunk}, {"BIN\x00", binaryChunk}} {
+		header := make([]byte, 8)
+		binary.LittleEndian.PutUint32(header[0:4], uint32(len(chunk.data)))
+		copy(header[4:8], chunk.chunkType)
+		content = append(content, header...)
+		content = append(content, chunk.data...)
+	}
+	binary.LittleEndian.PutUint32(content[8:12], uint32(len(content)))
+	return content
+}
+
+// Writes the given files to a temporary folder, creating their parent folders, and verifies its tileset
+func verifyTestTileset(t *testing.T, files map[string][]byte) *pkg.TilesetReport {
+	folder := t.TempDir()
+	for name, content := range files {
+		filePath := filepath.Join(folder, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filePath), 0777); err != nil {
+			t.Fatalf("Unexpected error creating the folder of %s: %s", name, err)
+		}
+		if err := ioutil.WriteFile(filePath, content, 0666); err != nil {
+			t.Fatalf("Unexpected error writing %s: %s", name, err)
+		}
+	}
+
+	report, err := pkg.VerifyTileset(folder)
+	if err != nil {
+		t.Fatalf("Unexpected error verifying the tileset: %s", err)
+	}
+	return report
+}
+
+// Returns the tileset of a single tile with the given content
+func verifierTestTilesetWithContent(uri string) []byte {
+	return []byte(strings.Replace(verifierTestTileset, "CONTENT_URI", uri, 1))
+}
+
+// Checks that the report contains a single problem of the given kind, whose message contains the given text
+func assertSingleTilesetProblem(t *testing.T, report *pkg.TilesetReport, check string, message string) {
+	if report.Valid {
+		t.Fatalf("Expected the tileset to be invalid")
+	}
+	if len(report.Problems) != 1 {
+		t.Fatalf("Expected one problem, got %+v", report.Problems)
+	}
+	if problem := report.Problems[0]; problem.Check != check || !strings.Contains(problem.Message, message) {
+		t.Errorf("Expected a %s problem containing [%s], got %+v", check, message, problem)
+	}
+}
+
+func TestVerifyTilesetWellFormedContents(t *testing.T) {
+	for uri, content := range map[string][]byte{
+		"content.pnts": buildValidVerifierTestPnts(),
+		"content.glb":  buildVerifierTestGlb(),
+	} {
+		report := verifyTestTileset(t, map[string][]byte{
+			"tileset.json": verifierTestTilesetWithContent(uri),
+			uri:            content,
+		})
+		if !report.Valid || len(report.Problems) != 0 {
+			t.Errorf("Content %s: expected a valid tileset, got %+v", uri, report.Problems)
+		}
+		if report.Tilesets != 1 || report.Tiles != 1 || report.Contents != 1 {
+			t.Errorf("Content %s: expected 1 tileset, tile and content, got %d, %d and %d", uri, report.Tilesets, report.Tiles, report.Contents)
+		}
+	}
+}
+
+func TestVerifyTilesetCorruptedPnts(t *testing.T) {
+	wrongMagic := buildValidVerifierTestPnts()
+	copy(wrongMagic[0:4], "pntz")
+
+	wrongByteLength := buildValidVerifierTestPnts()
+	binary.LittleEndian.PutUint32(wrongByteLength[8:12], uint32(len(wrongByteLength)+4))
+
+	wrongTableLength := buildValidVerifierTestPnts()
+	binary.LittleEndian.PutUint32(wrongTableLength[16:20], 20)
+
+	testData := []struct {
+		name    string
+		content []byte
+		message string
+	}{
+		{"truncated", buildValidVerifierTestPnts()[:20], "shorter than the pnts header"},
+		{"magic", wrongMagic, "invalid magic"},
+		{"byte length", wrongByteLength, "differs from file length"},
+		{"table length", wrongTableLength, "differs from the sum of the header and table lengths"},
+		{"missing position", buildVerifierTestPnts(`{"POINTS_LENGTH":2}`, nil, "", nil), "misses POSITION"},
+		{"short position", buildVerifierTestPnts(`{"POINTS_LENGTH":3,"POSITION":{"byteOffset":0}}`, make([]byte, 24), "", nil), "POSITION ends at 36"},
+	}
+
+	for _, data := range testData {
+		t.Run(data.name, func(t *testing.T) {
+			report := verifyTestTileset(t, map[string][]byte{
+				"tileset.json": verifierTestTilesetWithContent("content.pnts"),
+				"content.pnts": data.content,
+			})
+			assertSingleTilesetProblem(t, report, pkg.CheckContent, data.message)
+		})
+	}
+}
+
+func TestVerifyTilesetCorruptedGlb(t *testing.T) {
+	wrongVersion := buildVerifierTestGlb()
+	binary.LittleEndian.PutUint32(wrongVersion[4:8], 1)
+
+	wrongLength := buildVerifierTestGlb()
+	binary.LittleEndian.PutUint32(wrongLength[8:12], uint32(len(wrongLength)-1))
+
+	// the length of the binary chunk exceeds the file
+	wrongChunkLength := buildVerifierTestGlb()
+	binary.LittleEndian.PutUint32(wrongChunkLength[len(wrongChunkLength)-16:], 12)
+
+	testData := []struct {
+		name    string
+		content []byte
+		message string
+	}{
+		{"truncated", buildVerifierTestGlb()[:8], "shorter than the glb header"},
+		{"version", wrongVersion, "unsupported version 1"},
+		{"length", wrongLength, "differs from file length"},
+		{"chunk length", wrongChunkLength, "beyond file length"},
+	}
+
+	for _, data := range testData {
+		t.Run(data.name, func(t *testing.T) {
+			report := verifyTestTileset(t, map[string][]byte{
+				"tileset.json": verifierTestTilesetWithContent("content.glb"),
+				"content.glb":  data.content,
+			})
+			assertSingleTilesetProblem(t, report, pkg.CheckContent, data.message)
+		})
+	}
+}
+
+func TestVerifyTilesetBatchTableAlignment(t *testing.T) {
+	featureTableJson := `{"POINTS_LENGTH":2,"POSITION":{"byteOffset":0}}`
+
+	aligned := buildVerifierTestPnts(featureTableJson, make([]byte, 24),
+		`{"Intensity":{"byteOffset":0,"componentType":"UNSIGNED_SHORT","type":"SCALAR"},"GpsTime":{"byteOffset":8,"componentType":"DOUBLE","type":"SCALAR"}}`,
+		make([]byte, 24))
+	report := verifyTestTileset(t, map[string][]byte{
+		"tileset.json": verifierTestTilesetWithContent("content.pnts"),
+		"content.pnts": aligned,
+	})
+	if !report.Valid {
+		t.Errorf("Expected a valid tileset, got %+v", report.Problems)
+	}
+
+	// the doubles follow the two shorts without padding
+	unaligned := buildVerifierTestPnts(featureTableJson, make([]byte, 24),
+		`{"Intensity":{"byteOffset":0,"componentType":"UNSIGNED_SHORT","type":"SCALAR"},"GpsTime":{"byteOffset":4,"componentType":"DOUBLE","type":"SCALAR"}}`,
+		make([]byte, 20))
+	report = verifyTestTileset(t, map[string][]byte{
+		"tileset.json": verifierTestTilesetWithContent("content.pnts"),
+		"content.pnts": unaligned,
+	})
+	assertSingleTilesetProblem(t, report, pkg.CheckContent, "GpsTime byteOffset 4 is not aligned to its componentType DOUBLE")
+}
+
+func TestVerifyTilesetImplicitSubtree(t *testing.T) {
+	files := map[string][]byte{
+		"tileset.json":             []byte(verifierTestImplicitTileset),
+		"content/0/0/0/0.glb":      buildVerifierTestGlb(),
+		"subtrees/0/0/0/0.subtree": []byte("subt"),
+	}
+	report := verifyTestTileset(t, files)
+	if !report.Valid {
+		t.Errorf("Expected a valid tileset, got %+v", report.Problems)
+	}
+
+	delete(files, "subtrees/0/0/0/0.subtree")
+	report = verifyTestTileset(t, files)
+	assertSingleTilesetProblem(t, report, pkg.CheckContent, "root subtree")
+}
@@ -0,0 +1,176 @@
+// This file contains the detection of the coordinate reference system of a LAS file, either from its GeoTIFF
+// keys or from its OGC WKT VLR or EVLR.
+
+package lidario
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+const (
+	// GeoKey ids of the EPSG codes of the coordinate reference system
+	geographicTypeGeoKey  = 2048
+	projectedCSTypeGeoKey = 3072
+
+	// GeoKey value used for user defined coordinate reference systems, which have no EPSG code
+	userDefinedGeoKeyValue = 32767
+
+	// Record id of the VLR storing the coordinate reference system as OGC WKT
+	ogcWktRecordID   = 2112
+	projectionUserID = "LASF_Projection"
+)
+
+// Srid returns the EPSG code of the coordinate reference system of the file, 0 if the file does not define one.
+// The WKT VLR or EVLR is used when present, the GeoTIFF keys otherwise. Returns an error if the file defines a
+// coordinate reference system that can't be mapped to an EPSG code.
+func (las *LasFile) Srid() (int, error) {
+	// LAS 1.4 files can store the WKT in an EVLR as well
+	for _, vlrs := range [][]VLR{las.VlrData, las.EvlrData} {
+		for _, vlr := range vlrs {
+			if vlr.UserID != projectionUserID || vlr.RecordID != ogcWktRecordID {
+				continue
+			}
+			wkt := strings.TrimRight(string(vlr.BinaryData), "\x00 ")
+			if srid := wktEpsgCode(wkt); srid > 0 {
+				return srid, nil
+			}
+			return 0, errors.New("no EPSG code found in the WKT coordinate reference system of " + las.fileName)
+		}
+	}
+
+	if las.Header.GlobalEncoding.CoordinateReferenceSystemMethod() == WellKnownText {
+		// GeoTIFF keys must be ignored when the WKT flag is set
+		return 0, nil
+	}
+
+	return las.geokeys.epsgCode(las.fileName)
+}
+
+// epsgCode returns the EPSG code stored in the projected or geographic type keys, 0 if the keys are not defined.
+// The projected type is preferred as a projected coordinate reference system also defines a geographic one.
+func (gk *GeoKeys) epsgCode(fileName string) (int, error) {
+	if len(gk.GeoKeyDirectory) < 4 {
+		return 0, nil
+	}
+
+	var projected, geographic uint16
+	numKeys := int(gk.GeoKeyDirectory[3])
+	for i := 0; i < numKeys; i++ {
+		offset := 4 * (i + 1)
+		if offset+3 >= len(gk.GeoKeyDirectory) {
+			break
+		}
+
+		keyID := gk.GeoKeyDirectory[offset]
+		tiffTagLocation := gk.GeoKeyDirectory[offset+1]
+		value := gk.GeoKeyDirectory[offset+3]
+		if tiffTagLocation != 0 {
+			// EPSG codes are always stored as short values within the directory
+			continue
+		}
+
+		switch keyID {
+		case projectedCSTypeGeoKey:
+			projected = value
+		case geographicTypeGeoKey:
+			geographic = value
+		}
+	}
+
+	if projected == userDefinedGeoKeyValue || (projected == 0 && geographic == userDefinedGeoKeyValue) {
+		return 0, errors.New("user defined coordinate reference system without EPSG code in " + fileName)
+	}
+	if projected != 0 {
+		return int(projected), nil
+	}
+	return int(geographic), nil
+}
+
+// wktEpsgCode returns the EPSG code of the coordinate reference system described by the given WKT, either WKT1 or
+// WKT2, 0 if it has no EPSG authority. For compound systems the code of the horizontal component is returned.
+func wktEpsgCode(wkt string) int {
+	keyword, args, ok := parseWktNode(wkt)
+	if !ok {
+		return 0
+	}
+
+	switch strings.ToUpper(keyword) {
+	case "COMPD_CS", "COMPOUNDCRS":
+		for _, arg := range args {
+			childKeyword, _, ok := parseWktNode(arg)
+			if ok && isHorizontalWktKeyword(childKeyword) {
+				return wktEpsgCode(arg)
+			}
+		}
+		return 0
+	}
+
+	for _, arg := range args {
+		childKeyword, childArgs, ok := parseWktNode(arg)
+		if !ok || len(childArgs) < 2 {
+			continue
+		}
+		childKeyword = strings.ToUpper(childKeyword)
+		if childKeyword != "AUTHORITY" && childKeyword != "ID" {
+			continue
+		}
+		if strings.ToUpper(unquoteWkt(childArgs[0])) != "EPSG" {
+			continue
+		}
+		if code, err := strconv.Atoi(unquoteWkt(childArgs[1])); err == nil {
+			return code
+		}
+	}
+
+	return 0
+}
+
+func isHorizontalWktKeyword(keyword string) bool {
+	switch strings.ToUpper(keyword) {
+	case "PROJCS", "GEOGCS", "PROJCRS", "PROJECTEDCRS", "GEOGCRS", "GEOGRAPHICCRS", "GEODCRS", "GEODETICCRS":
+		return true
+	}
+	return false
+}
+
+// parseWktNode splits a WKT node as KEYWORD[arg1,arg2,...] in its keyword and its top level arguments
+func parseWktNode(node string) (string, []string, bool) {
+	node = strings.TrimSpace(node)
+	open := strings.IndexAny(node, "[(")
+	if open <= 0 || len(node) < 2 {
+		return "", nil, false
+	}
+	closing := node[len(node)-1]
+	if closing != ']' && closing != ')' {
+		return "", nil, false
+	}
+
+	var args []string
+	depth := 0
+	quoted := false
+	start := open + 1
+	for i := open + 1; i < len(node)-1; i++ {
+		switch c := node[i]; {
+		case c == '"':
+			// quotes inside strings are escaped by doubling them, which toggles the state twice
+			quoted = !quoted
+		case quoted:
+		case c == '[' || c == '(':
+			depth++
+		case c == ']' || c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			args = append(args, strings.TrimSpace(node[start:i]))
+			start = i + 1
+		}
+	}
+	args = append(args, strings.TrimSpace(node[start:len(node)-1]))
+
+	return strings.TrimSpace(node[:open]), args, true
+}
+
+func unquoteWkt(value string) string {
+	return strings.Trim(strings.TrimSpace(value), "\"")
+}
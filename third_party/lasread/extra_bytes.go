@@ -0,0 +1,217 @@
+// This file contains the parsing of the Extra Bytes VLR of LAS 1.4 files, which describes the attributes stored
+// after the standard fields of each point record
+
+package lidario
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+)
+
+const (
+	extraBytesVLRRecordID = 4
+
+	// size in bytes of an extra bytes descriptor
+	extraBytesDescriptorSize = 192
+
+	// options bits of an extra bytes descriptor
+	extraBytesOptionNoData = 1 << 0
+	extraBytesOptionScale  = 1 << 3
+	extraBytesOptionOffset = 1 << 4
+)
+
+// size in bytes of the scalar data types 1 to 10 of the extra bytes, 0 is the undocumented extra bytes type
+var extraBytesDataTypeSizes = [11]int{0, 1, 1, 2, 2, 4, 4, 8, 8, 4, 8}
+
+// ExtraBytesAttribute describes an attribute stored in the extra bytes of the point records
+type ExtraBytesAttribute struct {
+	Name          string
+	Description   string
+	DataType      uint8 // data type of the descriptor, 0 for undocumented extra bytes
+	Options       uint8
+	NumComponents int        // 1 for the scalar types, 2 or 3 for the deprecated array types
+	Size          int        // size in bytes of the attribute in the point record
+	Offset        int        // offset of the attribute from the start of the point record
+	NoData        [3]float64 // no data value of each component, only meaningful if HasNoData is true
+	Scale         [3]float64
+	ValueOffset   [3]float64
+}
+
+// isExtraBytesVLR returns true if the given VLR holds the extra bytes descriptors
+func isExtraBytesVLR(vlr VLR) bool {
+	return vlr.UserID == lasSpecUserID && vlr.RecordID == extraBytesVLRRecordID
+}
+
+// parseExtraBytesVLR parses the descriptors of the extra bytes VLR. The extra bytes are stored at the end of point
+// records of the given length
+func parseExtraBytesVLR(data []byte, recordLength int) ([]ExtraBytesAttribute, error) {
+	if len(data)%extraBytesDescriptorSize != 0 {
+		return nil, fmt.Errorf("extra bytes vlr length %d is not a multiple of %d", len(data), extraBytesDescriptorSize)
+	}
+
+	attributes := make([]ExtraBytesAttribute, 0, len(data)/extraBytesDescriptorSize)
+	totalSize := 0
+	for offset := 0; offset < len(data); offset += extraBytesDescriptorSize {
+		d := data[offset : offset+extraBytesDescriptorSize]
+		attribute := ExtraBytesAttribute{
+			Name:          strings.Trim(bytesToString(d[4:36], 32), " \x00"),
+			Description:   strings.Trim(bytesToString(d[160:192], 32), " \x00"),
+			DataType:      d[2],
+			Options:       d[3],
+			NumComponents: 1,
+		}
+
+		switch {
+		case attribute.DataType == 0:
+			// the options hold the number of undocumented extra bytes
+			attribute.Size = int(attribute.Options)
+		case attribute.DataType <= 10:
+			attribute.Size = extraBytesDataTypeSizes[attribute.DataType]
+		case attribute.DataType <= 30:
+			// deprecated arrays of 2 and 3 values of the scalar types
+			attribute.NumComponents = 2 + int(attribute.DataType-11)/10
+			attribute.Size = attribute.NumComponents * extraBytesDataTypeSizes[attribute.scalarType()]
+		default:
+			return nil, fmt.Errorf("extra bytes attribute [%s] has unsupported data type %d", attribute.Name, attribute.DataType)
+		}
+
+		for i := 0; i < 3; i++ {
+			attribute.NoData[i] = attribute.decodeAnyType(d[40+i*8 : 48+i*8])
+			attribute.Scale[i] = 1
+			if attribute.Options&extraBytesOptionScale != 0 {
+				attribute.Scale[i] = math.Float64frombits(binary.LittleEndian.Uint64(d[112+i*8 : 120+i*8]))
+			}
+			if attribute.Options&extraBytesOptionOffset != 0 {
+				attribute.ValueOffset[i] = math.Float64frombits(binary.LittleEndian.Uint64(d[136+i*8 : 144+i*8]))
+			}
+		}
+
+		attribute.Offset = totalSize
+		totalSize += attribute.Size
+		attributes = append(attributes, attribute)
+	}
+
+	// the extra bytes are the last bytes of the point record
+	start := recordLength - totalSize
+	if start < 0 {
+		return nil, fmt.Errorf("extra bytes size %d exceeds the point record length %d", totalSize, recordLength)
+	}
+	for i := range attributes {
+		attributes[i].Offset += start
+	}
+
+	return attributes, nil
+}
+
+// HasNoData returns true if the descriptor defines a no data value
+func (a *ExtraBytesAttribute) HasNoData() bool {
+	return a.DataType != 0 && a.Options&extraBytesOptionNoData != 0
+}
+
+// Value returns the scaled value of the given component of the attribute in the point record starting at the given
+// offset. Undocumented extra bytes have no value and return 0
+func (a *ExtraBytesAttribute) Value(record []byte, offset int, component int) float64 {
+	if a.DataType == 0 || component >= a.NumComponents {
+		return 0
+	}
+	size := a.Size / a.NumComponents
+	start := offset + a.Offset + component*size
+	raw := a.decodeRaw(record[start : start+size])
+	return raw*a.Scale[component] + a.ValueOffset[component]
+}
+
+// IsNoData returns true if the raw value of the given component of the attribute in the point record starting at
+// the given offset is the no data value of the descriptor
+func (a *ExtraBytesAttribute) IsNoData(record []byte, offset int, component int) bool {
+	if !a.HasNoData() || component >= a.NumComponents {
+		return false
+	}
+	size := a.Size / a.NumComponents
+	start := offset + a.Offset + component*size
+	return a.decodeRaw(record[start:start+size]) == a.NoData[component]
+}
+
+// Returns the scalar data type of the components of the attribute
+func (a *ExtraBytesAttribute) scalarType() uint8 {
+	if a.DataType <= 10 {
+		return a.DataType
+	}
+	return (a.DataType-11)%10 + 1
+}
+
+// Decodes a raw value of the scalar type of the attribute
+func (a *ExtraBytesAttribute) decodeRaw(b []byte) float64 {
+	switch a.scalarType() {
+	case 1:
+		return float64(b[0])
+	case 2:
+		return float64(int8(b[0]))
+	case 3:
+		return float64(binary.LittleEndian.Uint16(b))
+	case 4:
+		return float64(int16(binary.LittleEndian.Uint16(b)))
+	case 5:
+		return float64(binary.LittleEndian.Uint32(b))
+	case 6:
+		return float64(int32(binary.LittleEndian.Uint32(b)))
+	case 7:
+		return float64(binary.LittleEndian.Uint64(b))
+	case 8:
+		return float64(int64(binary.LittleEndian.Uint64(b)))
+	case 9:
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+	case 10:
+		return math.Float64frombits(binary.LittleEndian.Uint64(b))
+	}
+	return 0
+}
+
+// Decodes the 8 bytes "anytype" fields of the descriptor, which store integers as 64 bits integers and floats as
+// doubles
+func (a *ExtraBytesAttribute) decodeAnyType(b []byte) float64 {
+	switch a.scalarType() {
+	case 1, 3, 5, 7:
+		return float64(binary.LittleEndian.Uint64(b))
+	case 2, 4, 6, 8:
+		return float64(int64(binary.LittleEndian.Uint64(b)))
+	case 9, 10:
+		return math.Float64frombits(binary.LittleEndian.Uint64(b))
+	}
+	return 0
+}
+
+// ExtraBytesAttributes returns the attributes described by the extra bytes VLR, if any
+func (las *LasFile) ExtraBytesAttributes() []ExtraBytesAttribute {
+	return las.extraBytes
+}
+
+// ExtraBytesAttribute returns the extra bytes attribute with the given name
+func (las *LasFile) ExtraBytesAttribute(name string) (*ExtraBytesAttribute, bool) {
+	for i := range las.extraBytes {
+		if las.extraBytes[i].Name == name && las.extraBytes[i].DataType != 0 {
+			return &las.extraBytes[i], true
+		}
+	}
+	return nil, false
+}
+
+// Parses the extra bytes descriptors from the VLRs or the EVLRs of the file
+func (las *LasFile) readExtraBytesDescriptors() error {
+	las.extraBytes = nil
+	for _, vlrs := range [][]VLR{las.VlrData, las.EvlrData} {
+		for _, vlr := range vlrs {
+			if !isExtraBytesVLR(vlr) {
+				continue
+			}
+			attributes, err := parseExtraBytesVLR(vlr.BinaryData, las.Header.PointRecordLength)
+			if err != nil {
+				return err
+			}
+			las.extraBytes = attributes
+			return nil
+		}
+	}
+	return nil
+}
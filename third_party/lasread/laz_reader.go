@@ -0,0 +1,1192 @@
+// This file contains a native decoder for LASzip compressed point data (LAZ files).
+// The arithmetic decoder and the item decompressors are a port of the reference
+// LASzip implementation. Only the pointwise compressors are supported, which cover
+// the POINT10, GPSTIME11, RGB12 and BYTE items used by the point formats 0 to 3.
+
+package lidario
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+
+	"github.com/golang/glog"
+)
+
+const (
+	lazVLRUserID   = "laszip encoded"
+	lazVLRRecordID = 22204
+
+	lazCompressorNone             = 0
+	lazCompressorPointwise        = 1
+	lazCompressorPointwiseChunked = 2
+	lazCompressorLayeredChunked   = 3
+
+	lazItemByte      = 0
+	lazItemPoint10   = 6
+	lazItemGpsTime11 = 7
+	lazItemRGB12     = 8
+
+	lazVariableChunkSize = math.MaxUint32
+)
+
+// lazItem describes one of the items that compose a compressed point record
+type lazItem struct {
+	Type    uint16
+	Size    uint16
+	Version uint16
+}
+
+// lazInfo contains the content of the laszip VLR
+type lazInfo struct {
+	Compressor      uint16
+	Coder           uint16
+	VersionMajor    uint8
+	VersionMinor    uint8
+	VersionRevision uint16
+	Options         uint32
+	ChunkSize       uint32
+	Items           []lazItem
+}
+
+// isLazVLR returns true if the given VLR is the one describing the laszip compression
+func isLazVLR(vlr VLR) bool {
+	return vlr.UserID == lazVLRUserID && vlr.RecordID == lazVLRRecordID
+}
+
+func parseLazVLR(data []byte) (*lazInfo, error) {
+	if len(data) < 34 {
+		return nil, errors.New("laszip vlr is too short")
+	}
+	info := &lazInfo{
+		Compressor:      binary.LittleEndian.Uint16(data[0:2]),
+		Coder:           binary.LittleEndian.Uint16(data[2:4]),
+		VersionMajor:    data[4],
+		VersionMinor:    data[5],
+		VersionRevision: binary.LittleEndian.Uint16(data[6:8]),
+		Options:         binary.LittleEndian.Uint32(data[8:12]),
+		ChunkSize:       binary.LittleEndian.Uint32(data[12:16]),
+	}
+	// bytes 16:32 contain the number and the offset of the special EVLRs, not used here
+	numItems := int(binary.LittleEndian.Uint16(data[32:34]))
+	if len(data) < 34+numItems*6 {
+		return nil, errors.New("laszip vlr is too short for the declared items")
+	}
+	offset := 34
+	for i := 0; i < numItems; i++ {
+		info.Items = append(info.Items, lazItem{
+			Type:    binary.LittleEndian.Uint16(data[offset : offset+2]),
+			Size:    binary.LittleEndian.Uint16(data[offset+2 : offset+4]),
+			Version: binary.LittleEndian.Uint16(data[offset+4 : offset+6]),
+		})
+		offset += 6
+	}
+	return info, nil
+}
+
+// validate checks that the compressed point layout of the given point format is supported and consistent with the
+// given record length
+func (info *lazInfo) validate(pointFormatID byte, recordLength int) error {
+	if info.Coder != 0 {
+		return fmt.Errorf("laz coder %d is not supported", info.Coder)
+	}
+	switch info.Compressor {
+	case lazCompressorPointwise, lazCompressorPointwiseChunked:
+	case lazCompressorLayeredChunked:
+		return fmt.Errorf("laz point format %d is not supported: the layered chunked compression of point formats 6-10 "+
+			"can't be read, convert the file to las with laszip -i file.laz -o file.las", pointFormatID)
+	default:
+		return fmt.Errorf("laz compressor %d is not supported", info.Compressor)
+	}
+
+	size := 0
+	for _, item := range info.Items {
+		switch item.Type {
+		case lazItemByte, lazItemPoint10, lazItemGpsTime11, lazItemRGB12:
+			if item.Version != 2 {
+				return fmt.Errorf("laz item type %d version %d is not supported", item.Type, item.Version)
+			}
+		default:
+			return fmt.Errorf("laz item type %d is not supported", item.Type)
+		}
+		size += int(item.Size)
+	}
+	if size != recordLength {
+		return fmt.Errorf("laz items size %d does not match point record length %d", size, recordLength)
+	}
+	return nil
+}
+
+// readCompressedPointRecords decompresses all the point records of a LAZ file, returning them in the same
+// binary layout used by uncompressed LAS files
+func (las *LasFile) readCompressedPointRecords() ([]byte, error) {
+	recordLength := las.Header.PointRecordLength
+	chunkStarts, chunkPoints, err := las.lazChunks()
+	if err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, las.Header.NumberPoints*recordLength)
+
+	numCPUs := las.workers()
+	glog.Infof("parallel decompress numCPUs:[%d] numChunks:[%d] lasFilePath:[%s]", numCPUs, len(chunkPoints), las.fileName)
+
+	var wg sync.WaitGroup
+	var errOnce sync.Once
+	var decompressErr error
+	sem := make(chan struct{}, numCPUs)
+	firstPoint := 0
+	for i := range chunkPoints {
+		numPoints := chunkPoints[i]
+		if firstPoint+numPoints > las.Header.NumberPoints {
+			numPoints = las.Header.NumberPoints - firstPoint
+		}
+		if numPoints <= 0 {
+			break
+		}
+		out := b[firstPoint*recordLength : (firstPoint+numPoints)*recordLength]
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(start, end int64, numPoints int, out []byte) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			chunk := make([]byte, end-start)
+			if _, err := las.f.ReadAt(chunk, start); err != nil && err != io.EOF {
+				errOnce.Do(func() { decompressErr = err })
+				return
+			}
+			if err := decompressLazChunk(chunk, las.lazInfo.Items, numPoints, recordLength, out); err != nil {
+				errOnce.Do(func() { decompressErr = err })
+			}
+		}(chunkStarts[i], chunkStarts[i+1], numPoints, out)
+		firstPoint += numPoints
+	}
+	wg.Wait()
+
+	if decompressErr != nil {
+		return nil, decompressErr
+	}
+	if firstPoint != las.Header.NumberPoints {
+		return nil, fmt.Errorf("laz chunks contain %d points, header declares %d", firstPoint, las.Header.NumberPoints)
+	}
+	return b, nil
+}
+
+// lazChunks validates the compressed point layout and returns the chunks of the file as readLazChunkTable does
+func (las *LasFile) lazChunks() ([]int64, []int, error) {
+	if las.lazInfo == nil {
+		return nil, nil, errors.New("compressed point format but laszip vlr not found")
+	}
+	if err := las.lazInfo.validate(las.Header.PointFormatID, las.Header.PointRecordLength); err != nil {
+		return nil, nil, err
+	}
+	return las.readLazChunkTable()
+}
+
+// readLazChunkTable returns the file offsets of the compressed chunks (with an additional trailing
+// element marking the end of the last chunk) and the number of points contained in each chunk
+func (las *LasFile) readLazChunkTable() ([]int64, []int, error) {
+	fileInfo, err := las.f.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	fileSize := fileInfo.Size()
+	pointsStart := int64(las.Header.OffsetToPoints)
+
+	if las.lazInfo.Compressor == lazCompressorPointwise {
+		// a single chunk containing all the points, without any chunk table
+		return []int64{pointsStart, fileSize}, []int{las.Header.NumberPoints}, nil
+	}
+
+	b8 := make([]byte, 8)
+	if _, err := las.f.ReadAt(b8, pointsStart); err != nil {
+		return nil, nil, err
+	}
+	chunkTableStart := int64(binary.LittleEndian.Uint64(b8))
+	if chunkTableStart <= pointsStart || chunkTableStart >= fileSize {
+		return nil, nil, fmt.Errorf("invalid laz chunk table offset %d", chunkTableStart)
+	}
+
+	table := make([]byte, fileSize-chunkTableStart)
+	if _, err := las.f.ReadAt(table, chunkTableStart); err != nil && err != io.EOF {
+		return nil, nil, err
+	}
+	if len(table) < 8 {
+		return nil, nil, errors.New("laz chunk table is truncated")
+	}
+	if version := binary.LittleEndian.Uint32(table[0:4]); version != 0 {
+		return nil, nil, fmt.Errorf("laz chunk table version %d is not supported", version)
+	}
+	numChunks := int(binary.LittleEndian.Uint32(table[4:8]))
+
+	variableChunks := las.lazInfo.ChunkSize == lazVariableChunkSize
+	chunkStarts := make([]int64, numChunks+1)
+	chunkPoints := make([]int, numChunks)
+	chunkStarts[0] = pointsStart + 8
+
+	if numChunks > 0 {
+		dec := &arithmeticDecoder{}
+		dec.init(table[8:])
+		ic := newIntegerCompressor(dec, 32, 2)
+		var lastSize, lastCount int32
+		for i := 0; i < numChunks; i++ {
+			if variableChunks {
+				lastCount = ic.decompress(lastCount, 0)
+				chunkPoints[i] = int(uint32(lastCount))
+			} else {
+				chunkPoints[i] = int(las.lazInfo.ChunkSize)
+			}
+			lastSize = ic.decompress(lastSize, 1)
+			chunkStarts[i+1] = chunkStarts[i] + int64(uint32(lastSize))
+			if chunkStarts[i+1] <= chunkStarts[i] || chunkStarts[i+1] > fileSize {
+				return nil, nil, fmt.Errorf("invalid laz chunk table entry %d", i)
+			}
+		}
+	}
+	return chunkStarts, chunkPoints, nil
+}
+
+// decompressLazChunk decompresses numPoints records from the given chunk data into out
+func decompressLazChunk(data []byte, items []lazItem, numPoints int, recordLength int, out []byte) error {
+	reader, err := newLazChunkReader(data, items, recordLength)
+	if err != nil {
+		return err
+	}
+	reader.readRecords(out, numPoints)
+	return reader.finish()
+}
+
+// lazChunkReader decompresses the records of a chunk sequentially, so that they can be read in batches into a
+// reused buffer. The first point of each chunk is stored raw and it is used to initialize the item decompressors
+type lazChunkReader struct {
+	data         []byte
+	items        []lazItem
+	recordLength int
+	dec          *arithmeticDecoder
+	readers      []lazItemReader
+	itemOffsets  []int
+	numRead      int
+}
+
+func newLazChunkReader(data []byte, items []lazItem, recordLength int) (*lazChunkReader, error) {
+	if len(data) < recordLength {
+		return nil, errors.New("laz chunk is truncated")
+	}
+	return &lazChunkReader{data: data, items: items, recordLength: recordLength}, nil
+}
+
+// readRecords decompresses the next numPoints records of the chunk into out
+func (c *lazChunkReader) readRecords(out []byte, numPoints int) {
+	for p := 0; p < numPoints; p++ {
+		record := out[p*c.recordLength : (p+1)*c.recordLength]
+		if c.numRead == 0 {
+			copy(record, c.data[0:c.recordLength])
+			c.init(record)
+		} else {
+			for i, item := range c.items {
+				c.readers[i].read(record[c.itemOffsets[i] : c.itemOffsets[i]+int(item.Size)])
+			}
+		}
+		c.numRead++
+	}
+}
+
+func (c *lazChunkReader) init(first []byte) {
+	c.dec = &arithmeticDecoder{}
+	c.dec.init(c.data[c.recordLength:])
+
+	c.readers = make([]lazItemReader, len(c.items))
+	c.itemOffsets = make([]int, len(c.items))
+	offset := 0
+	for i, item := range c.items {
+		c.itemOffsets[i] = offset
+		c.readers[i] = newLazItemReader(c.dec, item)
+		c.readers[i].init(first[offset : offset+int(item.Size)])
+		offset += int(item.Size)
+	}
+}
+
+// finish returns an error if the chunk data ended before all the read records were decompressed
+func (c *lazChunkReader) finish() error {
+	if c.numRead > 1 && c.dec.overflow() {
+		return errors.New("laz chunk data ended before all points were decompressed")
+	}
+	return nil
+}
+
+// lazItemReader decompresses a single item of a point record
+type lazItemReader interface {
+	init(item []byte)
+	read(item []byte)
+}
+
+func newLazItemReader(dec *arithmeticDecoder, item lazItem) lazItemReader {
+	switch item.Type {
+	case lazItemPoint10:
+		return newLazPoint10Reader(dec)
+	case lazItemGpsTime11:
+		return newLazGpsTime11Reader(dec)
+	case lazItemRGB12:
+		return newLazRGB12Reader(dec)
+	default:
+		return newLazByteReader(dec, int(item.Size))
+	}
+}
+
+// ---- arithmetic decoder ----
+
+const (
+	acMinLength   = 0x01000000
+	acMaxLength   = 0xFFFFFFFF
+	bmLengthShift = 13
+	bmMaxCount    = 1 << bmLengthShift
+	dmLengthShift = 15
+	dmMaxCount    = 1 << dmLengthShift
+)
+
+type arithmeticDecoder struct {
+	data   []byte
+	pos    int
+	value  uint32
+	length uint32
+}
+
+func (d *arithmeticDecoder) init(data []byte) {
+	d.data = data
+	d.pos = 0
+	d.length = acMaxLength
+	d.value = uint32(d.getByte())<<24 | uint32(d.getByte())<<16 | uint32(d.getByte())<<8 | uint32(d.getByte())
+}
+
+func (d *arithmeticDecoder) getByte() byte {
+	if d.pos >= len(d.data) {
+		d.pos++
+		return 0
+	}
+	b := d.data[d.pos]
+	d.pos++
+	return b
+}
+
+// overflow returns true if the decoder had to read past the end of its data
+func (d *arithmeticDecoder) overflow() bool {
+	return d.pos > len(d.data)+4
+}
+
+func (d *arithmeticDecoder) renormDecInterval() {
+	for {
+		d.value = d.value<<8 | uint32(d.getByte())
+		d.length <<= 8
+		if d.length >= acMinLength {
+			break
+		}
+	}
+}
+
+func (d *arithmeticDecoder) decodeBit(m *arithmeticBitModel) uint32 {
+	x := m.bit0Prob * (d.length >> bmLengthShift)
+	var sym uint32
+	if d.value >= x {
+		sym = 1
+		d.value -= x
+		d.length -= x
+	} else {
+		d.length = x
+		m.bit0Count++
+	}
+	if d.length < acMinLength {
+		d.renormDecInterval()
+	}
+	m.bitsUntilUpdate--
+	if m.bitsUntilUpdate == 0 {
+		m.update()
+	}
+	return sym
+}
+
+func (d *arithmeticDecoder) decodeSymbol(m *arithmeticModel) uint32 {
+	var n, sym, x uint32
+	y := d.length
+
+	if m.decoderTable != nil {
+		d.length >>= dmLengthShift
+		dv := d.value / d.length
+		t := dv >> m.tableShift
+		sym = m.decoderTable[t]
+		n = m.decoderTable[t+1] + 1
+		for n > sym+1 {
+			k := (sym + n) >> 1
+			if m.distribution[k] > dv {
+				n = k
+			} else {
+				sym = k
+			}
+		}
+		x = m.distribution[sym] * d.length
+		if sym != m.lastSymbol {
+			y = m.distribution[sym+1] * d.length
+		}
+	} else {
+		d.length >>= dmLengthShift
+		n = m.symbols
+		k := n >> 1
+		for {
+			z := d.length * m.distribution[k]
+			if z > d.value {
+				n = k
+				y = z
+			} else {
+				sym = k
+				x = z
+			}
+			k = (sym + n) >> 1
+			if k == sym {
+				break
+			}
+		}
+	}
+
+	d.value -= x
+	d.length = y - x
+	if d.length < acMinLength {
+		d.renormDecInterval()
+	}
+
+	m.symbolCount[sym]++
+	m.symbolsUntilUpdate--
+	if m.symbolsUntilUpdate == 0 {
+		m.update()
+	}
+	return sym
+}
+
+func (d *arithmeticDecoder) readBits(bits uint32) uint32 {
+	if bits > 19 {
+		lower := uint32(d.readShort())
+		upper := d.readBits(bits - 16)
+		return upper<<16 | lower
+	}
+	d.length >>= bits
+	sym := d.value / d.length
+	d.value -= d.length * sym
+	if d.length < acMinLength {
+		d.renormDecInterval()
+	}
+	return sym
+}
+
+func (d *arithmeticDecoder) readShort() uint16 {
+	d.length >>= 16
+	sym := d.value / d.length
+	d.value -= d.length * sym
+	if d.length < acMinLength {
+		d.renormDecInterval()
+	}
+	return uint16(sym)
+}
+
+func (d *arithmeticDecoder) readInt() uint32 {
+	lower := uint32(d.readShort())
+	upper := uint32(d.readShort())
+	return upper<<16 | lower
+}
+
+// arithmeticModel is an adaptive model for symbols in the range [0, symbols)
+type arithmeticModel struct {
+	symbols            uint32
+	distribution       []uint32
+	symbolCount        []uint32
+	decoderTable       []uint32
+	totalCount         uint32
+	updateCycle        uint32
+	symbolsUntilUpdate uint32
+	lastSymbol         uint32
+	tableSize          uint32
+	tableShift         uint32
+}
+
+func newArithmeticModel(symbols uint32) *arithmeticModel {
+	m := &arithmeticModel{
+		symbols:      symbols,
+		lastSymbol:   symbols - 1,
+		distribution: make([]uint32, symbols),
+		symbolCount:  make([]uint32, symbols),
+	}
+	if symbols > 16 {
+		tableBits := uint32(3)
+		for symbols > 1<<(tableBits+2) {
+			tableBits++
+		}
+		m.tableSize = 1 << tableBits
+		m.tableShift = dmLengthShift - tableBits
+		m.decoderTable = make([]uint32, m.tableSize+2)
+	}
+
+	m.totalCount = 0
+	m.updateCycle = symbols
+	for k := range m.symbolCount {
+		m.symbolCount[k] = 1
+	}
+	m.update()
+	m.updateCycle = (symbols + 6) >> 1
+	m.symbolsUntilUpdate = m.updateCycle
+	return m
+}
+
+func (m *arithmeticModel) update() {
+	// halve counts when a threshold is reached
+	m.totalCount += m.updateCycle
+	if m.totalCount > dmMaxCount {
+		m.totalCount = 0
+		for n := range m.symbolCount {
+			m.symbolCount[n] = (m.symbolCount[n] + 1) >> 1
+			m.totalCount += m.symbolCount[n]
+		}
+	}
+
+	// compute cumulative distribution and decoder table
+	var sum, s uint32
+	scale := uint32(0x80000000) / m.totalCount
+	if m.tableSize == 0 {
+		for k := uint32(0); k < m.symbols; k++ {
+			m.distribution[k] = (scale * sum) >> (31 - dmLengthShift)
+			sum += m.symbolCount[k]
+		}
+	} else {
+		for k := uint32(0); k < m.symbols; k++ {
+			m.distribution[k] = (scale * sum) >> (31 - dmLengthShift)
+			sum += m.symbolCount[k]
+			w := m.distribution[k] >> m.tableShift
+			for s < w {
+				s++
+				m.decoderTable[s] = k - 1
+			}
+		}
+		m.decoderTable[0] = 0
+		for s <= m.tableSize {
+			s++
+			m.decoderTable[s] = m.symbols - 1
+		}
+	}
+
+	// set frequency of model updates
+	m.updateCycle = (5 * m.updateCycle) >> 2
+	maxCycle := (m.symbols + 6) << 3
+	if m.updateCycle > maxCycle {
+		m.updateCycle = maxCycle
+	}
+	m.symbolsUntilUpdate = m.updateCycle
+}
+
+// arithmeticBitModel is an adaptive model for binary symbols
+type arithmeticBitModel struct {
+	bit0Count       uint32
+	bitCount        uint32
+	bit0Prob        uint32
+	bitsUntilUpdate uint32
+	updateCycle     uint32
+}
+
+func newArithmeticBitModel() *arithmeticBitModel {
+	return &arithmeticBitModel{
+		bit0Count:       1,
+		bitCount:        2,
+		bit0Prob:        1 << (bmLengthShift - 1),
+		bitsUntilUpdate: 4,
+		updateCycle:     4,
+	}
+}
+
+func (m *arithmeticBitModel) update() {
+	// halve counts when a threshold is reached
+	m.bitCount += m.updateCycle
+	if m.bitCount > bmMaxCount {
+		m.bitCount = (m.bitCount + 1) >> 1
+		m.bit0Count = (m.bit0Count + 1) >> 1
+		if m.bit0Count == m.bitCount {
+			m.bitCount++
+		}
+	}
+
+	// compute scaled bit 0 probability
+	scale := uint32(0x80000000) / m.bitCount
+	m.bit0Prob = (m.bit0Count * scale) >> (31 - bmLengthShift)
+
+	// set frequency of model updates
+	m.updateCycle = (5 * m.updateCycle) >> 2
+	if m.updateCycle > 64 {
+		m.updateCycle = 64
+	}
+	m.bitsUntilUpdate = m.updateCycle
+}
+
+// ---- integer compressor ----
+
+// integerCompressor decodes integers predicted by the item decompressors as a corrector relative to the prediction
+type integerCompressor struct {
+	dec         *arithmeticDecoder
+	bitsHigh    uint32
+	corrBits    uint32
+	corrRange   uint32
+	corrMin     int32
+	k           uint32
+	mBits       []*arithmeticModel
+	mCorrector0 *arithmeticBitModel
+	mCorrector  []*arithmeticModel
+}
+
+func newIntegerCompressor(dec *arithmeticDecoder, bits uint32, contexts uint32) *integerCompressor {
+	ic := &integerCompressor{
+		dec:      dec,
+		bitsHigh: 8,
+	}
+	if bits > 0 && bits < 32 {
+		ic.corrBits = bits
+		ic.corrRange = 1 << bits
+		ic.corrMin = -int32(ic.corrRange / 2)
+	} else {
+		ic.corrBits = 32
+		ic.corrRange = 0
+		ic.corrMin = math.MinInt32
+	}
+
+	ic.mBits = make([]*arithmeticModel, contexts)
+	for i := range ic.mBits {
+		ic.mBits[i] = newArithmeticModel(ic.corrBits + 1)
+	}
+	ic.mCorrector0 = newArithmeticBitModel()
+	ic.mCorrector = make([]*arithmeticModel, ic.corrBits+1)
+	for i := uint32(1); i <= ic.corrBits; i++ {
+		if i <= ic.bitsHigh {
+			ic.mCorrector[i] = newArithmeticModel(1 << i)
+		} else {
+			ic.mCorrector[i] = newArithmeticModel(1 << ic.bitsHigh)
+		}
+	}
+	return ic
+}
+
+func (ic *integerCompressor) decompress(pred int32, context uint32) int32 {
+	real := pred + ic.readCorrector(ic.mBits[context])
+	if real < 0 {
+		real += int32(ic.corrRange)
+	} else if uint32(real) >= ic.corrRange {
+		real -= int32(ic.corrRange)
+	}
+	return real
+}
+
+func (ic *integerCompressor) readCorrector(mBits *arithmeticModel) int32 {
+	var c int32
+	ic.k = ic.dec.decodeSymbol(mBits)
+	if ic.k == 0 {
+		return int32(ic.dec.decodeBit(ic.mCorrector0))
+	}
+	if ic.k >= 32 {
+		return ic.corrMin
+	}
+
+	if ic.k <= ic.bitsHigh {
+		c = int32(ic.dec.decodeSymbol(ic.mCorrector[ic.k]))
+	} else {
+		k1 := ic.k - ic.bitsHigh
+		c = int32(ic.dec.decodeSymbol(ic.mCorrector[ic.k]))
+		c1 := int32(ic.dec.readBits(k1))
+		c = c<<k1 | c1
+	}
+
+	// translate c back into its correct interval
+	if int64(c) >= int64(1)<<(ic.k-1) {
+		c++
+	} else {
+		c = int32(int64(c) - (int64(1)<<ic.k - 1))
+	}
+	return c
+}
+
+// ---- item decompressors ----
+
+var numberReturnMap = [8][8]uint32{
+	{15, 14, 13, 12, 11, 10, 9, 8},
+	{14, 0, 1, 3, 6, 10, 10, 9},
+	{13, 1, 2, 4, 7, 11, 11, 10},
+	{12, 3, 4, 5, 8, 12, 12, 11},
+	{11, 6, 7, 8, 9, 13, 13, 12},
+	{10, 10, 11, 12, 13, 14, 14, 13},
+	{9, 10, 11, 12, 13, 14, 15, 14},
+	{8, 9, 10, 11, 12, 13, 14, 15},
+}
+
+var numberReturnLevel = [8][8]uint32{
+	{0, 1, 2, 3, 4, 5, 6, 7},
+	{1, 0, 1, 2, 3, 4, 5, 6},
+	{2, 1, 0, 1, 2, 3, 4, 5},
+	{3, 2, 1, 0, 1, 2, 3, 4},
+	{4, 3, 2, 1, 0, 1, 2, 3},
+	{5, 4, 3, 2, 1, 0, 1, 2},
+	{6, 5, 4, 3, 2, 1, 0, 1},
+	{7, 6, 5, 4, 3, 2, 1, 0},
+}
+
+func u8Fold(n int32) byte {
+	if n < 0 {
+		return byte(n + 256)
+	}
+	if n > 255 {
+		return byte(n - 256)
+	}
+	return byte(n)
+}
+
+func u8Clamp(n int32) int32 {
+	if n < 0 {
+		return 0
+	}
+	if n > 255 {
+		return 255
+	}
+	return n
+}
+
+func boolToUint32(b bool) uint32 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// streamingMedian5 keeps track of the median of the last five values added
+type streamingMedian5 struct {
+	values [5]int32
+	high   bool
+}
+
+func newStreamingMedian5() streamingMedian5 {
+	return streamingMedian5{high: true}
+}
+
+func (s *streamingMedian5) add(v int32) {
+	if s.high {
+		if v < s.values[2] {
+			s.values[4] = s.values[3]
+			s.values[3] = s.values[2]
+			if v < s.values[0] {
+				s.values[2] = s.values[1]
+				s.values[1] = s.values[0]
+				s.values[0] = v
+			} else if v < s.values[1] {
+				s.values[2] = s.values[1]
+				s.values[1] = v
+			} else {
+				s.values[2] = v
+			}
+		} else {
+			if v < s.values[3] {
+				s.values[4] = s.values[3]
+				s.values[3] = v
+			} else {
+				s.values[4] = v
+			}
+			s.high = false
+		}
+	} else {
+		if s.values[2] < v {
+			s.values[0] = s.values[1]
+			s.values[1] = s.values[2]
+			if s.values[4] < v {
+				s.values[2] = s.values[3]
+				s.values[3] = s.values[4]
+				s.values[4] = v
+			} else if s.values[3] < v {
+				s.values[2] = s.values[3]
+				s.values[3] = v
+			} else {
+				s.values[2] = v
+			}
+		} else {
+			if s.values[1] < v {
+				s.values[0] = s.values[1]
+				s.values[1] = v
+			} else {
+				s.values[0] = v
+			}
+			s.high = true
+		}
+	}
+}
+
+func (s *streamingMedian5) get() int32 {
+	return s.values[2]
+}
+
+// lazPoint10Reader decompresses the 20 bytes core of the point formats 0 to 5
+type lazPoint10Reader struct {
+	dec              *arithmeticDecoder
+	lastItem         [20]byte
+	lastXDiffMedian5 [16]streamingMedian5
+	lastYDiffMedian5 [16]streamingMedian5
+	lastIntensity    [16]uint16
+	lastHeight       [8]int32
+	mChangedValues   *arithmeticModel
+	icIntensity      *integerCompressor
+	mScanAngleRank   [2]*arithmeticModel
+	icPointSourceID  *integerCompressor
+	mBitByte         [256]*arithmeticModel
+	mClassification  [256]*arithmeticModel
+	mUserData        [256]*arithmeticModel
+	icDX             *integerCompressor
+	icDY             *integerCompressor
+	icZ              *integerCompressor
+}
+
+func newLazPoint10Reader(dec *arithmeticDecoder) *lazPoint10Reader {
+	r := &lazPoint10Reader{
+		dec:             dec,
+		mChangedValues:  newArithmeticModel(64),
+		icIntensity:     newIntegerCompressor(dec, 16, 4),
+		mScanAngleRank:  [2]*arithmeticModel{newArithmeticModel(256), newArithmeticModel(256)},
+		icPointSourceID: newIntegerCompressor(dec, 16, 1),
+		icDX:            newIntegerCompressor(dec, 32, 2),
+		icDY:            newIntegerCompressor(dec, 32, 22),
+		icZ:             newIntegerCompressor(dec, 32, 20),
+	}
+	for i := 0; i < 16; i++ {
+		r.lastXDiffMedian5[i] = newStreamingMedian5()
+		r.lastYDiffMedian5[i] = newStreamingMedian5()
+	}
+	return r
+}
+
+func (r *lazPoint10Reader) init(item []byte) {
+	copy(r.lastItem[:], item)
+	// the intensity of the last item is not used as prediction
+	r.lastItem[12] = 0
+	r.lastItem[13] = 0
+}
+
+func (r *lazPoint10Reader) read(item []byte) {
+	last := r.lastItem[:]
+
+	changedValues := r.dec.decodeSymbol(r.mChangedValues)
+
+	if changedValues != 0 {
+		// edge_of_flight_line, scan_direction_flag, returns
+		if changedValues&32 != 0 {
+			if r.mBitByte[last[14]] == nil {
+				r.mBitByte[last[14]] = newArithmeticModel(256)
+			}
+			last[14] = byte(r.dec.decodeSymbol(r.mBitByte[last[14]]))
+		}
+	}
+
+	returnNumber := uint32(last[14] & 0x07)
+	numberOfReturns := uint32((last[14] >> 3) & 0x07)
+	m := numberReturnMap[numberOfReturns][returnNumber]
+	l := numberReturnLevel[numberOfReturns][returnNumber]
+
+	if changedValues != 0 {
+		// intensity
+		if changedValues&16 != 0 {
+			context := m
+			if context > 3 {
+				context = 3
+			}
+			r.lastIntensity[m] = uint16(r.icIntensity.decompress(int32(r.lastIntensity[m]), context))
+		}
+		binary.LittleEndian.PutUint16(last[12:14], r.lastIntensity[m])
+
+		// classification
+		if changedValues&8 != 0 {
+			if r.mClassification[last[15]] == nil {
+				r.mClassification[last[15]] = newArithmeticModel(256)
+			}
+			last[15] = byte(r.dec.decodeSymbol(r.mClassification[last[15]]))
+		}
+
+		// scan angle rank
+		if changedValues&4 != 0 {
+			val := int32(r.dec.decodeSymbol(r.mScanAngleRank[(last[14]>>6)&1]))
+			last[16] = u8Fold(val + int32(last[16]))
+		}
+
+		// user data
+		if changedValues&2 != 0 {
+			if r.mUserData[last[17]] == nil {
+				r.mUserData[last[17]] = newArithmeticModel(256)
+			}
+			last[17] = byte(r.dec.decodeSymbol(r.mUserData[last[17]]))
+		}
+
+		// point source id
+		if changedValues&1 != 0 {
+			pointSourceID := r.icPointSourceID.decompress(int32(binary.LittleEndian.Uint16(last[18:20])), 0)
+			binary.LittleEndian.PutUint16(last[18:20], uint16(pointSourceID))
+		}
+	} else {
+		binary.LittleEndian.PutUint16(last[12:14], r.lastIntensity[m])
+	}
+
+	singleReturn := boolToUint32(numberOfReturns == 1)
+
+	// x coordinate
+	median := r.lastXDiffMedian5[m].get()
+	diff := r.icDX.decompress(median, singleReturn)
+	binary.LittleEndian.PutUint32(last[0:4], uint32(int32(binary.LittleEndian.Uint32(last[0:4]))+diff))
+	r.lastXDiffMedian5[m].add(diff)
+
+	// y coordinate, the number k of corrector bits of x is used to switch contexts
+	kBits := r.icDX.k
+	context := singleReturn
+	if kBits < 20 {
+		context += kBits &^ 1
+	} else {
+		context += 20
+	}
+	median = r.lastYDiffMedian5[m].get()
+	diff = r.icDY.decompress(median, context)
+	binary.LittleEndian.PutUint32(last[4:8], uint32(int32(binary.LittleEndian.Uint32(last[4:8]))+diff))
+	r.lastYDiffMedian5[m].add(diff)
+
+	// z coordinate
+	kBits = (r.icDX.k + r.icDY.k) / 2
+	context = singleReturn
+	if kBits < 18 {
+		context += kBits &^ 1
+	} else {
+		context += 18
+	}
+	z := r.icZ.decompress(r.lastHeight[l], context)
+	binary.LittleEndian.PutUint32(last[8:12], uint32(z))
+	r.lastHeight[l] = z
+
+	copy(item, last)
+}
+
+const (
+	lazGpsTimeMulti          = 500
+	lazGpsTimeMultiMinus     = -10
+	lazGpsTimeMultiUnchanged = lazGpsTimeMulti - lazGpsTimeMultiMinus + 1
+	lazGpsTimeMultiCodeFull  = lazGpsTimeMulti - lazGpsTimeMultiMinus + 2
+	lazGpsTimeMultiTotal     = lazGpsTimeMulti - lazGpsTimeMultiMinus + 6
+)
+
+// lazGpsTime11Reader decompresses the 8 bytes gps time item
+type lazGpsTime11Reader struct {
+	dec                 *arithmeticDecoder
+	last                uint32
+	next                uint32
+	lastGpsTime         [4]uint64
+	lastGpsTimeDiff     [4]int32
+	multiExtremeCounter [4]int32
+	mGpsTimeMulti       *arithmeticModel
+	mGpsTime0Diff       *arithmeticModel
+	icGpsTime           *integerCompressor
+}
+
+func newLazGpsTime11Reader(dec *arithmeticDecoder) *lazGpsTime11Reader {
+	return &lazGpsTime11Reader{
+		dec:           dec,
+		mGpsTimeMulti: newArithmeticModel(lazGpsTimeMultiTotal),
+		mGpsTime0Diff: newArithmeticModel(6),
+		icGpsTime:     newIntegerCompressor(dec, 32, 9),
+	}
+}
+
+func (r *lazGpsTime11Reader) init(item []byte) {
+	r.lastGpsTime[0] = binary.LittleEndian.Uint64(item)
+}
+
+func (r *lazGpsTime11Reader) readFullGpsTime() {
+	r.next = (r.next + 1) & 3
+	upper := r.icGpsTime.decompress(int32(r.lastGpsTime[r.last]>>32), 8)
+	r.lastGpsTime[r.next] = uint64(uint32(upper))<<32 | uint64(r.dec.readInt())
+	r.last = r.next
+	r.lastGpsTimeDiff[r.last] = 0
+	r.multiExtremeCounter[r.last] = 0
+}
+
+func (r *lazGpsTime11Reader) countExtreme(gpsTimeDiff int32) {
+	r.multiExtremeCounter[r.last]++
+	if r.multiExtremeCounter[r.last] > 3 {
+		r.lastGpsTimeDiff[r.last] = gpsTimeDiff
+		r.multiExtremeCounter[r.last] = 0
+	}
+}
+
+func (r *lazGpsTime11Reader) read(item []byte) {
+	if r.lastGpsTimeDiff[r.last] == 0 {
+		// the last integer difference was zero
+		multi := r.dec.decodeSymbol(r.mGpsTime0Diff)
+		if multi == 1 {
+			// the difference can be represented with 32 bits
+			r.lastGpsTimeDiff[r.last] = r.icGpsTime.decompress(0, 0)
+			r.lastGpsTime[r.last] = uint64(int64(r.lastGpsTime[r.last]) + int64(r.lastGpsTimeDiff[r.last]))
+			r.multiExtremeCounter[r.last] = 0
+		} else if multi == 2 {
+			// the difference is huge
+			r.readFullGpsTime()
+		} else if multi > 2 {
+			// switch to another sequence
+			r.last = (r.last + multi - 2) & 3
+			r.read(item)
+			return
+		}
+	} else {
+		multi := int32(r.dec.decodeSymbol(r.mGpsTimeMulti))
+		lastDiff := r.lastGpsTimeDiff[r.last]
+		if multi == 1 {
+			// the difference is close to the last one, which is replaced by it
+			r.lastGpsTimeDiff[r.last] = r.icGpsTime.decompress(lastDiff, 1)
+			r.lastGpsTime[r.last] = uint64(int64(r.lastGpsTime[r.last]) + int64(r.lastGpsTimeDiff[r.last]))
+			r.multiExtremeCounter[r.last] = 0
+		} else if multi < lazGpsTimeMultiUnchanged {
+			var gpsTimeDiff int32
+			if multi == 0 {
+				gpsTimeDiff = r.icGpsTime.decompress(0, 7)
+				r.countExtreme(gpsTimeDiff)
+			} else if multi < lazGpsTimeMulti {
+				if multi < 10 {
+					gpsTimeDiff = r.icGpsTime.decompress(multi*lastDiff, 2)
+				} else {
+					gpsTimeDiff = r.icGpsTime.decompress(multi*lastDiff, 3)
+				}
+			} else if multi == lazGpsTimeMulti {
+				gpsTimeDiff = r.icGpsTime.decompress(lazGpsTimeMulti*lastDiff, 4)
+				r.countExtreme(gpsTimeDiff)
+			} else {
+				multi = lazGpsTimeMulti - multi
+				if multi > lazGpsTimeMultiMinus {
+					gpsTimeDiff = r.icGpsTime.decompress(multi*lastDiff, 5)
+				} else {
+					gpsTimeDiff = r.icGpsTime.decompress(lazGpsTimeMultiMinus*lastDiff, 6)
+					r.countExtreme(gpsTimeDiff)
+				}
+			}
+			r.lastGpsTime[r.last] = uint64(int64(r.lastGpsTime[r.last]) + int64(gpsTimeDiff))
+		} else if multi == lazGpsTimeMultiCodeFull {
+			r.readFullGpsTime()
+		} else if multi > lazGpsTimeMultiCodeFull {
+			r.last = (r.last + uint32(multi) - lazGpsTimeMultiCodeFull) & 3
+			r.read(item)
+			return
+		}
+	}
+	binary.LittleEndian.PutUint64(item, r.lastGpsTime[r.last])
+}
+
+// lazRGB12Reader decompresses the 6 bytes rgb item
+type lazRGB12Reader struct {
+	dec       *arithmeticDecoder
+	lastItem  [3]uint16
+	mByteUsed *arithmeticModel
+	mRGBDiff  [6]*arithmeticModel
+}
+
+func newLazRGB12Reader(dec *arithmeticDecoder) *lazRGB12Reader {
+	r := &lazRGB12Reader{
+		dec:       dec,
+		mByteUsed: newArithmeticModel(128),
+	}
+	for i := range r.mRGBDiff {
+		r.mRGBDiff[i] = newArithmeticModel(256)
+	}
+	return r
+}
+
+func (r *lazRGB12Reader) init(item []byte) {
+	for i := 0; i < 3; i++ {
+		r.lastItem[i] = binary.LittleEndian.Uint16(item[i*2 : i*2+2])
+	}
+}
+
+func (r *lazRGB12Reader) decodeCorrected(model int, prediction int32) uint16 {
+	corr := int32(r.dec.decodeSymbol(r.mRGBDiff[model]))
+	return uint16(u8Fold(corr + prediction))
+}
+
+func (r *lazRGB12Reader) read(item []byte) {
+	var rgb [3]uint16
+	last := r.lastItem
+
+	sym := r.dec.decodeSymbol(r.mByteUsed)
+	if sym&(1<<0) != 0 {
+		rgb[0] = r.decodeCorrected(0, int32(last[0]&0xFF))
+	} else {
+		rgb[0] = last[0] & 0xFF
+	}
+	if sym&(1<<1) != 0 {
+		rgb[0] |= r.decodeCorrected(1, int32(last[0]>>8)) << 8
+	} else {
+		rgb[0] |= last[0] & 0xFF00
+	}
+
+	if sym&(1<<6) != 0 {
+		diff := int32(rgb[0]&0x00FF) - int32(last[0]&0x00FF)
+		if sym&(1<<2) != 0 {
+			rgb[1] = r.decodeCorrected(2, u8Clamp(diff+int32(last[1]&0xFF)))
+		} else {
+			rgb[1] = last[1] & 0xFF
+		}
+		if sym&(1<<4) != 0 {
+			diff = (diff + (int32(rgb[1]&0x00FF) - int32(last[1]&0x00FF))) / 2
+			rgb[2] = r.decodeCorrected(4, u8Clamp(diff+int32(last[2]&0xFF)))
+		} else {
+			rgb[2] = last[2] & 0xFF
+		}
+
+		diff = int32(rgb[0]>>8) - int32(last[0]>>8)
+		if sym&(1<<3) != 0 {
+			rgb[1] |= r.decodeCorrected(3, u8Clamp(diff+int32(last[1]>>8))) << 8
+		} else {
+			rgb[1] |= last[1] & 0xFF00
+		}
+		if sym&(1<<5) != 0 {
+			diff = (diff + (int32(rgb[1]>>8) - int32(last[1]>>8))) / 2
+			rgb[2] |= r.decodeCorrected(5, u8Clamp(diff+int32(last[2]>>8))) << 8
+		} else {
+			rgb[2] |= last[2] & 0xFF00
+		}
+	} else {
+		rgb[1] = rgb[0]
+		rgb[2] = rgb[0]
+	}
+
+	for i := 0; i < 3; i++ {
+		binary.LittleEndian.PutUint16(item[i*2:i*2+2], rgb[i])
+	}
+	r.lastItem = rgb
+}
+
+// lazByteReader decompresses the extra bytes that follow the standard point record fields
+type lazByteReader struct {
+	dec      *arithmeticDecoder
+	lastItem []byte
+	mByte    []*arithmeticModel
+}
+
+func newLazByteReader(dec *arithmeticDecoder, size int) *lazByteReader {
+	r := &lazByteReader{
+		dec:      dec,
+		lastItem: make([]byte, size),
+		mByte:    make([]*arithmeticModel, size),
+	}
+	for i := range r.mByte {
+		r.mByte[i] = newArithmeticModel(256)
+	}
+	return r
+}
+
+func (r *lazByteReader) init(item []byte) {
+	copy(r.lastItem, item)
+}
+
+func (r *lazByteReader) read(item []byte) {
+	for i := range r.lastItem {
+		value := int32(r.lastItem[i]) + int32(r.dec.decodeSymbol(r.mByte[i]))
+		item[i] = u8Fold(value)
+	}
+	copy(r.lastItem, item)
+}
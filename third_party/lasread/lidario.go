@@ -21,6 +21,14 @@ import (
 // NoData value used when indexing data outside of allowable range.
 var NoData = math.Inf(-1)
 
+const (
+	// user id of the records defined by the LAS specification
+	lasSpecUserID = "LASF_Spec"
+
+	// record id of the EVLR holding the waveform data packets
+	waveformDataPacketsRecordID = 65535
+)
+
 // LasFile is a structure for manipulating LAS files.
 type LasFile struct {
 	fileName               string
@@ -28,10 +36,13 @@ type LasFile struct {
 	f                      *os.File
 	Header                 LasHeader
 	VlrData                []VLR
+	EvlrData               []VLR // extended VLRs of LAS 1.4 files, the waveform data packets are not loaded
 	geokeys                GeoKeys
+	extraBytes             []ExtraBytesAttribute
 	pointData              []PointRecord0
 	gpsData                []float64
 	rgbData                []RgbData
+	recordData             []byte // raw point records of the point formats 4 to 10, which are written as read
 	usePointIntensity      bool
 	usePointUserdata       bool
 	headerIsSet            bool
@@ -39,6 +50,10 @@ type LasFile struct {
 	frs2D                  *fixedRadiusSearch
 	fixedRadiusSearch3DSet bool
 	frs3D                  *fixedRadiusSearch
+	compressed             bool
+	lazInfo                *lazInfo
+	streamed               bool // point records are not kept in memory and are read from the file when requested
+	numWorkers             int  // number of goroutines used to read the point records, 0 uses one per CPU
 	sync.RWMutex
 }
 
@@ -89,20 +104,7 @@ func InitializeUsingFile(fileName string, other *LasFile) (*LasFile, error) {
 	las.AddHeader(other.Header)
 
 	// Set las.useXxx flag by other-las
-	recLengths := [4][4]int{{20, 18, 19, 17}, {28, 26, 27, 25}, {26, 24, 25, 23}, {34, 32, 33, 31}}
-	if las.Header.PointRecordLength == recLengths[las.Header.PointFormatID][0] {
-		las.usePointIntensity = true
-		las.usePointUserdata = true
-	} else if las.Header.PointRecordLength == recLengths[las.Header.PointFormatID][1] {
-		las.usePointIntensity = false
-		las.usePointUserdata = true
-	} else if las.Header.PointRecordLength == recLengths[las.Header.PointFormatID][2] {
-		las.usePointIntensity = true
-		las.usePointUserdata = false
-	} else if las.Header.PointRecordLength == recLengths[las.Header.PointFormatID][3] {
-		las.usePointIntensity = false
-		las.usePointUserdata = false
-	}
+	las.setOptionalFields()
 
 	glog.Infof("init las FileName:[%s] Major:[%d] Minor:[%d] PointFormatID:[%d] PointRecordLength:[%d] "+
 		"userIntensity:[%v] userUserData:[%v]",
@@ -110,8 +112,11 @@ func InitializeUsingFile(fileName string, other *LasFile) (*LasFile, error) {
 		las.Header.PointFormatID, las.Header.PointRecordLength,
 		las.usePointIntensity, las.usePointUserdata)
 
-	// Copy the VLRs
+	// Copy the VLRs, the laszip one is dropped as the new file is not compressed
 	for _, vlr := range other.VlrData {
+		if isLazVLR(vlr) {
+			continue
+		}
 		las.AddVLR(vlr)
 	}
 
@@ -268,10 +273,17 @@ func (las *LasFile) AddLasPoint(p LasPointer) error {
 	if !las.headerIsSet {
 		return errors.New("the header of a LAS file must be added before any points; Please see AddHeader()")
 	}
+	extended, err := las.extendedPoint(p)
+	if err != nil {
+		return err
+	}
 	las.Lock()
 	// defer las.Unlock()
 	pd := p.PointData()
 	las.pointData = append(las.pointData, *pd)
+	if extended != nil {
+		las.recordData = append(las.recordData, extended.Record...)
+	}
 
 	switch p.Format() {
 	case 1:
@@ -309,17 +321,45 @@ func (las *LasFile) AddLasPoint(p LasPointer) error {
 		las.Header.MaxZ = val
 	}
 
+	whichReturn := las.returnIndex(pd, extended)
+	las.Header.NumberPointsByReturn[whichReturn-1]++
+	las.Header.NumberPoints++
+	las.Unlock()
+	return nil
+}
+
+// extendedPoint returns the given point as extended record if the file has one of the point formats 4 to 10, whose
+// points are written copying their raw record. The point must have the same format and record length of the file
+func (las *LasFile) extendedPoint(p LasPointer) (*PointRecordExtended, error) {
+	if las.Header.PointFormatID < 4 {
+		return nil, nil
+	}
+	extended, ok := p.(*PointRecordExtended)
+	if !ok || extended.Format() != las.Header.PointFormatID || len(extended.Record) != las.Header.PointRecordLength {
+		return nil, fmt.Errorf("a point of format %d can not be added to a LAS file of point format %d and record length %d",
+			p.Format(), las.Header.PointFormatID, las.Header.PointRecordLength)
+	}
+	return extended, nil
+}
+
+// returnIndex returns the return number used to count the points by return, between 1 and 15 for the point formats
+// 6 to 10 and between 1 and 5 for the other ones
+func (las *LasFile) returnIndex(pd *PointRecord0, extended *PointRecordExtended) byte {
 	whichReturn := pd.BitField.ReturnNumber()
+	maxReturn := byte(5)
+	if extended != nil {
+		whichReturn = extended.ReturnNumber()
+		if extended.Format() >= 6 {
+			maxReturn = 15
+		}
+	}
 	if whichReturn == 0 {
 		whichReturn = 1
 	}
-	if whichReturn > 5 {
-		whichReturn = 5
+	if whichReturn > maxReturn {
+		whichReturn = maxReturn
 	}
-	las.Header.NumberPointsByReturn[whichReturn-1]++
-	las.Header.NumberPoints++
-	las.Unlock()
-	return nil
+	return whichReturn
 }
 
 func (las *LasFile) CheckPointXYZInvalid(x, y, z float64) bool {
@@ -345,14 +385,25 @@ func (las *LasFile) AddLasPoints(points []LasPointer) error {
 	if !las.headerIsSet {
 		return errors.New("the header of a LAS file must be added before any points; Please see AddHeader()")
 	}
+	extendedPoints := make([]*PointRecordExtended, len(points))
+	for i, p := range points {
+		extended, err := las.extendedPoint(p)
+		if err != nil {
+			return err
+		}
+		extendedPoints[i] = extended
+	}
 	las.Lock()
 	// defer las.Unlock()
 	var pd PointRecord0
 	var val float64
 	var whichReturn uint8
-	for _, p := range points {
+	for i, p := range points {
 		pd = *p.PointData()
 		las.pointData = append(las.pointData, pd)
+		if extendedPoints[i] != nil {
+			las.recordData = append(las.recordData, extendedPoints[i].Record...)
+		}
 
 		// if p.Format() == 1 || p.Format() == 3 {
 		// 	las.gpsData = append(las.gpsData, p.GpsTimeData())
@@ -398,13 +449,7 @@ func (las *LasFile) AddLasPoints(points []LasPointer) error {
 			las.Header.MaxZ = val
 		}
 
-		whichReturn = pd.BitField.ReturnNumber()
-		if whichReturn == 0 {
-			whichReturn = 1
-		}
-		if whichReturn > 5 {
-			whichReturn = 5
-		}
+		whichReturn = las.returnIndex(&pd, extendedPoints[i])
 		las.Header.NumberPointsByReturn[whichReturn-1]++
 		las.Header.NumberPoints++
 	}
@@ -418,19 +463,18 @@ func (las *LasFile) Close() error {
 		// do nothing
 		return errors.New("the LAS reader is nil")
 	}
+	var writeErr error
 	if las.fileMode == "w" {
-		if err := las.write(); err != nil {
-			glog.Fatal(err)
-		}
-	}
-
-	if err := las.f.Close(); err != nil {
-		glog.Fatal(err)
+		writeErr = las.write()
 	}
 
+	err := las.f.Close()
 	las.f = nil
 
-	return nil
+	if writeErr != nil {
+		return writeErr
+	}
+	return err
 }
 
 // Clear clear a LasFile
@@ -444,6 +488,7 @@ func (las *LasFile) Clear() error {
 	if len(las.rgbData) > 0 {
 		las.rgbData = make([]RgbData, 0)
 	}
+	las.recordData = nil
 	return nil
 }
 
@@ -463,6 +508,9 @@ func (las *LasFile) LasPoint(index int) (LasPointer, error) {
 	if las.fileMode == "rh" {
 		return &PointRecord0{}, errors.New("The file was opened in 'rh' (read header); data points were therefore not read from the file")
 	}
+	if las.streamed {
+		return las.readLasPoint(index)
+	}
 	// las.RLock()
 	// defer las.RUnlock()
 	switch las.Header.PointFormatID {
@@ -478,12 +526,45 @@ func (las *LasFile) LasPoint(index int) (LasPointer, error) {
 	case 3:
 		// las.RUnlock()
 		return &PointRecord3{PointRecord0: &las.pointData[index], GPSTime: las.gpsData[index], RGB: &las.rgbData[index]}, nil
+	case 4, 5, 6, 7, 8, 9, 10:
+		recordLength := las.Header.PointRecordLength
+		return las.newPointRecordExtended(las.recordData[index*recordLength : (index+1)*recordLength]), nil
 	default:
 		// las.RUnlock()
 		return &PointRecord0{}, errors.New("Unrecognized data format")
 	}
 }
 
+// readLasPoint reads the point with the given index from the file
+func (las *LasFile) readLasPoint(index int) (LasPointer, error) {
+	if las.compressed {
+		return &PointRecord0{}, errors.New("the points of a streamed laz file cannot be read by index")
+	}
+	b := make([]byte, las.Header.PointRecordLength)
+	offset := int64(las.Header.OffsetToPoints) + int64(index)*int64(las.Header.PointRecordLength)
+	if _, err := las.f.ReadAt(b, offset); err != nil && err != io.EOF {
+		return &PointRecord0{}, err
+	}
+
+	if las.Header.PointFormatID >= 4 {
+		return las.newPointRecordExtended(b), nil
+	}
+
+	p, gpsTime, rgb := las.decodePointRecord(b, 0)
+	switch las.Header.PointFormatID {
+	case 0:
+		return &p, nil
+	case 1:
+		return &PointRecord1{PointRecord0: &p, GPSTime: gpsTime}, nil
+	case 2:
+		return &PointRecord2{PointRecord0: &p, RGB: &rgb}, nil
+	case 3:
+		return &PointRecord3{PointRecord0: &p, GPSTime: gpsTime, RGB: &rgb}, nil
+	default:
+		return &PointRecord0{}, errors.New("Unrecognized data format")
+	}
+}
+
 func (las *LasFile) read() error {
 	var err error
 	if las.f, err = os.Open(las.fileName); err != nil {
@@ -498,21 +579,7 @@ func (las *LasFile) read() error {
 		return err
 	}
 	if las.fileMode != "rh" {
-		recLengths := [4][4]int{{20, 18, 19, 17}, {28, 26, 27, 25}, {26, 24, 25, 23}, {34, 32, 33, 31}}
-
-		if las.Header.PointRecordLength == recLengths[las.Header.PointFormatID][0] {
-			las.usePointIntensity = true
-			las.usePointUserdata = true
-		} else if las.Header.PointRecordLength == recLengths[las.Header.PointFormatID][1] {
-			las.usePointIntensity = false
-			las.usePointUserdata = true
-		} else if las.Header.PointRecordLength == recLengths[las.Header.PointFormatID][2] {
-			las.usePointIntensity = true
-			las.usePointUserdata = false
-		} else if las.Header.PointRecordLength == recLengths[las.Header.PointFormatID][3] {
-			las.usePointIntensity = false
-			las.usePointUserdata = false
-		}
+		las.setOptionalFields()
 
 		glog.Infof("read las FileName:[%s] Major:[%d] Minor:[%d] PointFormatID:[%d] PointRecordLength:[%d] "+
 			"userIntensity:[%v] userUserData:[%v]",
@@ -528,6 +595,27 @@ func (las *LasFile) read() error {
 	return nil
 }
 
+// setOptionalFields figures out if the optional intensity and user data fields are stored, the only way to do this is
+// to compare the point record length with the ones of the point format. Records longer than the ones of the point
+// format hold both fields followed by extra bytes
+func (las *LasFile) setOptionalFields() {
+	lengths := recLengths[las.Header.PointFormatID]
+	switch las.Header.PointRecordLength {
+	case lengths[1]:
+		las.usePointIntensity = false
+		las.usePointUserdata = true
+	case lengths[2]:
+		las.usePointIntensity = true
+		las.usePointUserdata = false
+	case lengths[3]:
+		las.usePointIntensity = false
+		las.usePointUserdata = false
+	default:
+		las.usePointIntensity = true
+		las.usePointUserdata = true
+	}
+}
+
 func (las *LasFile) readHeader() error {
 	las.Lock()
 	defer las.Unlock()
@@ -590,8 +678,13 @@ func (las *LasFile) readHeader() error {
 	offset += 4
 	las.Header.NumberOfVLRs = int(binary.LittleEndian.Uint32(b[offset : offset+4]))
 	offset += 4
-	las.Header.PointFormatID = b[104]
+	// The two high bits of the point format are set by laszip in compressed files
+	las.compressed = b[104]&0xC0 != 0
+	las.Header.PointFormatID = b[104] & 0x3F
 	offset++
+	if las.Header.PointFormatID > 10 {
+		return fmt.Errorf("unsupported point format %d", las.Header.PointFormatID)
+	}
 	las.Header.PointRecordLength = int(binary.LittleEndian.Uint16(b[offset : offset+2]))
 	offset += 2
 	// this might be zero, it's a legacy field in LAS 1.4
@@ -638,14 +731,15 @@ func (las *LasFile) readHeader() error {
 		offset += 8
 	}
 	if las.Header.VersionMajor == 1 && las.Header.VersionMinor == 4 {
-		// Skip start of first Extended Variable Length and number of EVLR
-		offset += 12
-		// For Las 1.4 get the number of points from the new fields
-
-		las.Header.NumberPoints = int(binary.LittleEndian.Uint32(b[offset : offset+8]))
+		las.Header.StartOfFirstEVLR = binary.LittleEndian.Uint64(b[offset : offset+8])
+		offset += 8
+		las.Header.NumberOfEVLRs = int(binary.LittleEndian.Uint32(b[offset : offset+4]))
+		offset += 4
+		// For Las 1.4 get the number of points from the new 64 bits fields
+		las.Header.NumberPoints = int(binary.LittleEndian.Uint64(b[offset : offset+8]))
 		offset += 8
 		for i := 0; i < 15; i++ {
-			las.Header.NumberPointsByReturn[i] = int(binary.LittleEndian.Uint32(b[offset : offset+8]))
+			las.Header.NumberPointsByReturn[i] = int(binary.LittleEndian.Uint64(b[offset : offset+8]))
 			offset += 8
 		}
 	}
@@ -715,15 +809,84 @@ func (las *LasFile) readVLRs() error {
 		} else if vlr.RecordID == 34737 {
 			// ASCII GeoKey parameters
 			las.geokeys.addASCIIParams(vlr.BinaryData)
+		} else if isLazVLR(vlr) {
+			// LASzip compression parameters
+			lazInfo, err := parseLazVLR(vlr.BinaryData)
+			if err != nil {
+				return err
+			}
+			las.lazInfo = lazInfo
 		}
 		las.VlrData[i] = vlr
 		// glog.Infoln(vlr.String())
 	}
 
+	if err := las.readEVLRs(); err != nil {
+		return err
+	}
+
+	return las.readExtraBytesDescriptors()
+}
+
+// readEVLRs reads the extended VLRs stored after the point records of LAS 1.4 files. The waveform data packets
+// record can be as large as the point records and its data is not loaded
+func (las *LasFile) readEVLRs() error {
+	las.EvlrData = make([]VLR, 0, las.Header.NumberOfEVLRs)
+
+	offset := int64(las.Header.StartOfFirstEVLR)
+	b := make([]byte, 60)
+	for i := 0; i < las.Header.NumberOfEVLRs; i++ {
+		if _, err := las.f.ReadAt(b, offset); err != nil {
+			return fmt.Errorf("unable to read evlr %d: %v", i, err)
+		}
+		vlr := VLR{}
+		vlr.Reserved = int(binary.LittleEndian.Uint16(b[0:2]))
+		vlr.UserID = strings.Trim(bytesToString(b[2:18], 16), " \x00")
+		vlr.RecordID = int(binary.LittleEndian.Uint16(b[18:20]))
+		recordLength := binary.LittleEndian.Uint64(b[20:28])
+		vlr.RecordLengthAfterHeader = int(recordLength)
+		vlr.Description = strings.Trim(bytesToString(b[28:60], 32), " \x00")
+		offset += 60
+
+		if !(vlr.UserID == lasSpecUserID && vlr.RecordID == waveformDataPacketsRecordID) {
+			vlr.BinaryData = make([]uint8, recordLength)
+			if _, err := las.f.ReadAt(vlr.BinaryData, offset); err != nil && err != io.EOF {
+				return fmt.Errorf("unable to read evlr %d: %v", i, err)
+			}
+		}
+		offset += int64(recordLength)
+
+		las.EvlrData = append(las.EvlrData, vlr)
+	}
+
 	return nil
 }
 
+// readPointRecords returns the raw point records of the file, decompressing them if the file is a LAZ
+func (las *LasFile) readPointRecords() ([]byte, error) {
+	if las.compressed {
+		return las.readCompressedPointRecords()
+	}
+
+	// Estimate how many bytes are used to store the points
+	pointsLength := las.Header.NumberPoints * las.Header.PointRecordLength
+	b := make([]byte, pointsLength)
+	if _, err := las.f.ReadAt(b, int64(las.Header.OffsetToPoints)); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return b, nil
+}
+
 func (las *LasFile) readPoints() error {
+	b, err := las.readPointRecords()
+	if err != nil {
+		return err
+	}
+	return las.parsePointRecords(b)
+}
+
+// parsePointRecords parses the given raw point records into the inner list of points
+func (las *LasFile) parsePointRecords(b []byte) error {
 	las.Lock()
 	defer las.Unlock()
 	las.pointData = make([]PointRecord0, las.Header.NumberPoints)
@@ -734,35 +897,16 @@ func (las *LasFile) readPoints() error {
 		las.rgbData = make([]RgbData, las.Header.NumberPoints)
 	}
 
-	// Estimate how many bytes are used to store the points
-	pointsLength := las.Header.NumberPoints * las.Header.PointRecordLength
-	b := make([]byte, pointsLength)
-	if _, err := las.f.ReadAt(b, int64(las.Header.OffsetToPoints)); err != nil && err != io.EOF {
-		return err
-	}
-
-	// Intensity and userdata are both optional. Figure out if they need to be read.
-	// The only way to do this is to compare the data record length by data format
-	recLengths := [4][4]int{{20, 18, 19, 17}, {28, 26, 27, 25}, {26, 24, 25, 23}, {34, 32, 33, 31}}
-
-	if las.Header.PointRecordLength == recLengths[las.Header.PointFormatID][0] {
-		las.usePointIntensity = true
-		las.usePointUserdata = true
-	} else if las.Header.PointRecordLength == recLengths[las.Header.PointFormatID][1] {
-		las.usePointIntensity = false
-		las.usePointUserdata = true
-	} else if las.Header.PointRecordLength == recLengths[las.Header.PointFormatID][2] {
-		las.usePointIntensity = true
-		las.usePointUserdata = false
-	} else if las.Header.PointRecordLength == recLengths[las.Header.PointFormatID][3] {
-		las.usePointIntensity = false
-		las.usePointUserdata = false
+	las.setOptionalFields()
+	if las.Header.PointFormatID >= 4 {
+		las.recordData = b
 	}
 
 	numCPUs := runtime.NumCPU()
 	glog.Infof("parallel read numCPUs:[%d] lasFilePath:[%s]", numCPUs, las.fileName)
 
 	var wg sync.WaitGroup
+	var pointErr firstError
 	blockSize := las.Header.NumberPoints / numCPUs
 	if blockSize == 0 {
 		blockSize = 1
@@ -782,68 +926,19 @@ func (las *LasFile) readPoints() error {
 			glog.Infof("cpu-thread read %d/%d pointsNum:[%d] pointSt:[%d] pointEnd:[%d] NumberPoints:[%d]",
 				threadNum, numCPUs, pointEnd-pointSt+1, pointSt, pointEnd, las.Header.NumberPoints)
 
-			var xVal, yVal, zVal int32
-			var offset int
-			var p PointRecord0
 			for i := pointSt; i <= pointEnd; i++ {
-				offset = i * las.Header.PointRecordLength
-
-				//p.X = float64(int32(binary.LittleEndian.Uint32(b[offset:offset+4])))*las.Header.XScaleFactor + las.Header.XOffset
-				xVal = int32(binary.LittleEndian.Uint32(b[offset : offset+4]))
-				p.X = float64(xVal)*las.Header.XScaleFactor + las.Header.XOffset
-				offset += 4
-
-				// p.Y = float64(int32(binary.LittleEndian.Uint32(b[offset:offset+4])))*las.Header.YScaleFactor + las.Header.YOffset
-				yVal = int32(binary.LittleEndian.Uint32(b[offset : offset+4]))
-				p.Y = float64(yVal)*las.Header.YScaleFactor + las.Header.YOffset
-				offset += 4
-
-				// p.Z = float64(int32(binary.LittleEndian.Uint32(b[offset:offset+4])))*las.Header.ZScaleFactor + las.Header.ZOffset
-				zVal = int32(binary.LittleEndian.Uint32(b[offset : offset+4]))
-				p.Z = float64(zVal)*las.Header.ZScaleFactor + las.Header.ZOffset
-				offset += 4
-
-				// glog.Infof(" << read point_pos:[%d] XRelative:[%d] YRelative:[%d] ZRelative:[%d]", i, xVal, yVal, zVal)
-
-				if las.usePointIntensity {
-					p.Intensity = binary.LittleEndian.Uint16(b[offset : offset+2])
-					offset += 2
-				}
-				p.BitField = PointBitField{Value: b[offset]}
-				offset++
-				p.ClassBitField = ClassificationBitField{Value: b[offset]}
-				offset++
-				p.ScanAngle = int8(b[offset])
-				offset++
-				if las.usePointUserdata {
-					p.UserData = b[offset]
-					offset++
-				}
-				p.PointSourceID = binary.LittleEndian.Uint16(b[offset : offset+2])
-				offset += 2
-
+				p, gpsTime, rgb := las.decodePointRecord(b, i*las.Header.PointRecordLength)
 				las.pointData[i] = p
-
 				if las.Header.PointFormatID == 1 || las.Header.PointFormatID == 3 {
-					las.gpsData[i] = math.Float64frombits(binary.LittleEndian.Uint64(b[offset : offset+8]))
-					offset += 8
+					las.gpsData[i] = gpsTime
 				}
 				if las.Header.PointFormatID == 2 || las.Header.PointFormatID == 3 {
-					rgb := RgbData{}
-					rgb.Red = binary.LittleEndian.Uint16(b[offset : offset+2])
-					offset += 2
-					rgb.Green = binary.LittleEndian.Uint16(b[offset : offset+2])
-					offset += 2
-					rgb.Blue = binary.LittleEndian.Uint16(b[offset : offset+2])
-					offset += 2
 					las.rgbData[i] = rgb
-					// glog.Infoln(tools.FmtJSONString(rgb))
 				}
 				// glog.Infoln(tools.FmtJSONString(p))
 				if !las.CheckPointXYZInvalid(p.X, p.Y, p.Z) {
-					glog.Infof(" nonono invalid point_pos:[%d] X:[%f] Y:[%f] Z:[%f]", i, p.X, p.Y, p.Z)
-					glog.Fatal("invalid point X/Y/Z")
-					continue
+					pointErr.set(fmt.Errorf("invalid point X/Y/Z. point_pos:[%d] X:[%f] Y:[%f] Z:[%f]", i, p.X, p.Y, p.Z))
+					return
 				}
 				// glog.Infof(" okokok valid point_pos:[%d] X:[%f] Y:[%f] Z:[%f]", i, p.X, p.Y, p.Z)
 				// glog.Infoln("verify deserialize success.")
@@ -855,7 +950,125 @@ func (las *LasFile) readPoints() error {
 
 	wg.Wait()
 
-	return nil
+	return pointErr.err
+}
+
+// decodePointRecord parses the raw point record starting at the given offset
+func (las *LasFile) decodePointRecord(b []byte, offset int) (PointRecord0, float64, RgbData) {
+	if las.Header.PointFormatID >= 4 {
+		return las.decodeExtendedPointRecord(b, offset)
+	}
+
+	var p PointRecord0
+	var gpsTime float64
+	var rgb RgbData
+
+	p.X = float64(int32(binary.LittleEndian.Uint32(b[offset:offset+4])))*las.Header.XScaleFactor + las.Header.XOffset
+	offset += 4
+	p.Y = float64(int32(binary.LittleEndian.Uint32(b[offset:offset+4])))*las.Header.YScaleFactor + las.Header.YOffset
+	offset += 4
+	p.Z = float64(int32(binary.LittleEndian.Uint32(b[offset:offset+4])))*las.Header.ZScaleFactor + las.Header.ZOffset
+	offset += 4
+
+	if las.usePointIntensity {
+		p.Intensity = binary.LittleEndian.Uint16(b[offset : offset+2])
+		offset += 2
+	}
+	p.BitField = PointBitField{Value: b[offset]}
+	offset++
+	p.ClassBitField = ClassificationBitField{Value: b[offset]}
+	offset++
+	p.ScanAngle = int8(b[offset])
+	offset++
+	if las.usePointUserdata {
+		p.UserData = b[offset]
+		offset++
+	}
+	p.PointSourceID = binary.LittleEndian.Uint16(b[offset : offset+2])
+	offset += 2
+
+	if las.Header.PointFormatID == 1 || las.Header.PointFormatID == 3 {
+		gpsTime = math.Float64frombits(binary.LittleEndian.Uint64(b[offset : offset+8]))
+		offset += 8
+	}
+	if las.Header.PointFormatID == 2 || las.Header.PointFormatID == 3 {
+		rgb.Red = binary.LittleEndian.Uint16(b[offset : offset+2])
+		offset += 2
+		rgb.Green = binary.LittleEndian.Uint16(b[offset : offset+2])
+		offset += 2
+		rgb.Blue = binary.LittleEndian.Uint16(b[offset : offset+2])
+	}
+
+	return p, gpsTime, rgb
+}
+
+// decodeExtendedPointRecord parses the raw record of the point formats 4 to 10 starting at the given offset. The
+// fields of the point formats 6 to 10 are converted to their legacy layout, the classes above 31 and the return
+// numbers above 7 can not be represented and are stored as 0 and 7
+func (las *LasFile) decodeExtendedPointRecord(b []byte, offset int) (PointRecord0, float64, RgbData) {
+	var p PointRecord0
+	var gpsTime float64
+	var rgb RgbData
+
+	format := las.Header.PointFormatID
+	p.X = float64(int32(binary.LittleEndian.Uint32(b[offset:offset+4])))*las.Header.XScaleFactor + las.Header.XOffset
+	p.Y = float64(int32(binary.LittleEndian.Uint32(b[offset+4:offset+8])))*las.Header.YScaleFactor + las.Header.YOffset
+	p.Z = float64(int32(binary.LittleEndian.Uint32(b[offset+8:offset+12])))*las.Header.ZScaleFactor + las.Header.ZOffset
+	p.Intensity = binary.LittleEndian.Uint16(b[offset+12 : offset+14])
+
+	if format >= 6 {
+		returnNumber, numberOfReturns := minByte(b[offset+14]&0x0F, 7), minByte(b[offset+14]>>4, 7)
+		flags := b[offset+15]
+		// the scan direction and edge of flight line flags are the 2 high bits of both layouts
+		p.BitField = PointBitField{Value: returnNumber | numberOfReturns<<3 | flags&0xC0}
+		classification := b[offset+16]
+		if classification > 31 {
+			classification = 0
+		}
+		// synthetic, key-point and withheld flags
+		p.ClassBitField = ClassificationBitField{Value: classification | (flags&0x07)<<5}
+		p.UserData = b[offset+17]
+		scanAngle := math.Round(float64(int16(binary.LittleEndian.Uint16(b[offset+18:offset+20]))) * 0.006)
+		p.ScanAngle = int8(math.Max(-128, math.Min(127, scanAngle)))
+		p.PointSourceID = binary.LittleEndian.Uint16(b[offset+20 : offset+22])
+	} else {
+		p.BitField = PointBitField{Value: b[offset+14]}
+		p.ClassBitField = ClassificationBitField{Value: b[offset+15]}
+		p.ScanAngle = int8(b[offset+16])
+		p.UserData = b[offset+17]
+		p.PointSourceID = binary.LittleEndian.Uint16(b[offset+18 : offset+20])
+	}
+
+	if gpsTimeOffset := gpsTimeOffets[format]; gpsTimeOffset >= 0 {
+		gpsTime = math.Float64frombits(binary.LittleEndian.Uint64(b[offset+gpsTimeOffset : offset+gpsTimeOffset+8]))
+	}
+	if rgbOffsets := rgbOffets[format]; rgbOffsets != nil {
+		rgb.Red = binary.LittleEndian.Uint16(b[offset+rgbOffsets[0] : offset+rgbOffsets[0]+2])
+		rgb.Green = binary.LittleEndian.Uint16(b[offset+rgbOffsets[1] : offset+rgbOffsets[1]+2])
+		rgb.Blue = binary.LittleEndian.Uint16(b[offset+rgbOffsets[2] : offset+rgbOffsets[2]+2])
+	}
+
+	return p, gpsTime, rgb
+}
+
+// newPointRecordExtended builds the point of the point formats 4 to 10 stored in the given raw record
+func (las *LasFile) newPointRecordExtended(record []byte) *PointRecordExtended {
+	p, gpsTime, rgb := las.decodeExtendedPointRecord(record, 0)
+	point := &PointRecordExtended{PointRecord0: &p, format: las.Header.PointFormatID, GPSTime: NoData, Record: record}
+	if gpsTimeOffets[point.format] >= 0 {
+		point.GPSTime = gpsTime
+	}
+	if rgbOffets[point.format] != nil {
+		point.RGB = &rgb
+	}
+	return point
+}
+
+func minByte(a byte, b byte) byte {
+	if a < b {
+		return a
+	}
+	return b
 }
 
 func (las *LasFile) write() error {
@@ -939,6 +1152,10 @@ func (las *LasFile) write() error {
 	las.Header.VersionMajor = 1
 	w.WriteByte(las.Header.VersionMajor)
 	las.Header.VersionMinor = 3
+	if las.Header.PointFormatID >= 6 {
+		// the point formats 6 to 10 require LAS 1.4
+		las.Header.VersionMinor = 4
+	}
 	w.WriteByte(las.Header.VersionMinor)
 
 	if len(las.Header.SystemID) == 0 {
@@ -965,6 +1182,8 @@ func (las *LasFile) write() error {
 			las.Header.HeaderSize = 227
 		} else if las.Header.VersionMinor == 3 {
 			las.Header.HeaderSize = 235
+		} else if las.Header.VersionMinor == 4 {
+			las.Header.HeaderSize = 375
 		}
 	}
 
@@ -986,10 +1205,11 @@ func (las *LasFile) write() error {
 	w.WriteByte(las.Header.PointFormatID)
 
 	// Intensity and userdata are both optional. Figure out if they need to be read.
-	// The only way to do this is to compare the data record length by data format
-	recLengths := [][]int{{20, 18, 19, 17}, {28, 26, 27, 25}, {26, 24, 25, 23}, {34, 32, 33, 31}}
-
-	if las.usePointIntensity && las.usePointUserdata {
+	// The only way to do this is to compare the data record length by data format.
+	// The records of the point formats 4 to 10 are written as read, keeping their length
+	if las.Header.PointFormatID >= 4 {
+		// keep the record length of the source file
+	} else if las.usePointIntensity && las.usePointUserdata {
 		las.Header.PointRecordLength = recLengths[las.Header.PointFormatID][0]
 	} else if !las.usePointIntensity && las.usePointUserdata {
 		las.Header.PointRecordLength = recLengths[las.Header.PointFormatID][1]
@@ -1002,11 +1222,19 @@ func (las *LasFile) write() error {
 	binary.LittleEndian.PutUint16(bytes2, uint16(las.Header.PointRecordLength))
 	w.Write(bytes2)
 
-	binary.LittleEndian.PutUint32(bytes4, uint32(las.Header.NumberPoints))
+	// the legacy point counts must be 0 for the point formats 6 to 10, which use the 64 bits LAS 1.4 counts
+	legacyCounts := las.Header.PointFormatID < 6
+	binary.LittleEndian.PutUint32(bytes4, 0)
+	if legacyCounts {
+		binary.LittleEndian.PutUint32(bytes4, uint32(las.Header.NumberPoints))
+	}
 	w.Write(bytes4)
 
 	for i := 0; i < 5; i++ {
-		binary.LittleEndian.PutUint32(bytes4, uint32(las.Header.NumberPointsByReturn[i]))
+		binary.LittleEndian.PutUint32(bytes4, 0)
+		if legacyCounts {
+			binary.LittleEndian.PutUint32(bytes4, uint32(las.Header.NumberPointsByReturn[i]))
+		}
 		w.Write(bytes4)
 	}
 
@@ -1063,6 +1291,23 @@ func (las *LasFile) write() error {
 		w.Write(bytes8)
 	}
 
+	if las.Header.VersionMajor == 1 && las.Header.VersionMinor == 4 {
+		// the EVLRs are not written
+		las.Header.StartOfFirstEVLR = 0
+		las.Header.NumberOfEVLRs = 0
+		binary.LittleEndian.PutUint64(bytes8, las.Header.StartOfFirstEVLR)
+		w.Write(bytes8)
+		binary.LittleEndian.PutUint32(bytes4, uint32(las.Header.NumberOfEVLRs))
+		w.Write(bytes4)
+
+		binary.LittleEndian.PutUint64(bytes8, uint64(las.Header.NumberPoints))
+		w.Write(bytes8)
+		for i := 0; i < 15; i++ {
+			binary.LittleEndian.PutUint64(bytes8, uint64(las.Header.NumberPointsByReturn[i]))
+			w.Write(bytes8)
+		}
+	}
+
 	////////////////////////////////
 	// Write the VLRs to the file //
 	////////////////////////////////
@@ -1093,6 +1338,7 @@ func (las *LasFile) write() error {
 	glog.Infof("parallel write numCPUs:[%d] lasFilePath:[%s]", numCPUs, las.fileName)
 
 	var wg sync.WaitGroup
+	var pointErr firstError
 	blockSize := las.Header.NumberPoints / numCPUs
 	if blockSize == 0 {
 		blockSize = 1
@@ -1367,9 +1613,8 @@ func (las *LasFile) write() error {
 					p = las.pointData[i]
 
 					if !las.CheckPointXYZInvalid(p.X, p.Y, p.Z) {
-						glog.Infof(" nonono invalid point_pos:[%d] X:[%f] Y:[%f] Z:[%f]", i, p.X, p.Y, p.Z)
-						glog.Fatal("invalid point X/Y/Z")
-						continue
+						pointErr.set(fmt.Errorf("invalid point X/Y/Z. point_pos:[%d] X:[%f] Y:[%f] Z:[%f]", i, p.X, p.Y, p.Z))
+						return
 					}
 					// glog.Infof(" okokok valid point_pos:[%d] X:[%f] Y:[%f] Z:[%f]", i, p.X, p.Y, p.Z)
 
@@ -1452,9 +1697,8 @@ func (las *LasFile) write() error {
 						pOffset += 4
 
 						if !las.CheckPointXYZInvalid(pX, pY, pZ) {
-							glog.Infof(" nonono invalid point_pos:[%d] X:[%f] Y:[%f] Z:[%f]", i, pX, pY, pZ)
-							glog.Fatal("invalid point X/Y/Z")
-							continue
+							pointErr.set(fmt.Errorf("invalid point X/Y/Z. point_pos:[%d] X:[%f] Y:[%f] Z:[%f]", i, pX, pY, pZ))
+							return
 						}
 						// glog.Infof(" okokok valid point_pos:[%d] X:[%f] Y:[%f] Z:[%f]", i, pX, pY, pZ)
 						// glog.Infoln("verify serialize success.")
@@ -1535,9 +1779,8 @@ func (las *LasFile) write() error {
 					offset = i * las.Header.PointRecordLength
 
 					if !las.CheckPointXYZInvalid(p.X, p.Y, p.Z) {
-						glog.Infof(" nonono invalid point_pos:[%d] X:[%f] Y:[%f] Z:[%f]", i, p.X, p.Y, p.Z)
-						glog.Fatal("invalid point X/Y/Z")
-						continue
+						pointErr.set(fmt.Errorf("invalid point X/Y/Z. point_pos:[%d] X:[%f] Y:[%f] Z:[%f]", i, p.X, p.Y, p.Z))
+						return
 					}
 					// glog.Infof(" okokok valid point_pos:[%d] X:[%f] Y:[%f] Z:[%f]", i, p.X, p.Y, p.Z)
 
@@ -1628,9 +1871,8 @@ func (las *LasFile) write() error {
 						pOffset += 4
 
 						if !las.CheckPointXYZInvalid(pX, pY, pZ) {
-							glog.Infof(" nonono invalid point_pos:[%d] X:[%f] Y:[%f] Z:[%f]", i, pX, pY, pZ)
-							glog.Fatal("invalid point X/Y/Z")
-							continue
+							pointErr.set(fmt.Errorf("invalid point X/Y/Z. point_pos:[%d] X:[%f] Y:[%f] Z:[%f]", i, pX, pY, pZ))
+							return
 						}
 						// glog.Infof(" okokok valid point_pos:[%d] X:[%f] Y:[%f] Z:[%f]", i, pX, pY, pZ)
 						// glog.Infoln("verify serialize success.")
@@ -1688,21 +1930,60 @@ func (las *LasFile) write() error {
 			startingPoint = endingPoint + 1
 			cpuThread = cpuThread + 1
 		}
+
+	default:
+		// the point formats 4 to 10 copy the raw records of the points, re-encoding their coordinates
+		for startingPoint < las.Header.NumberPoints {
+			endingPoint := startingPoint + blockSize
+			if endingPoint >= las.Header.NumberPoints {
+				endingPoint = las.Header.NumberPoints - 1
+			}
+			wg.Add(1)
+			go func(pointSt, pointEnd int, threadNum int) {
+				defer wg.Done()
+				glog.Infof("cpu-thread write %d/%d pointsNum:[%d] pointSt:[%d] pointEnd:[%d] NumberPoints:[%d]",
+					threadNum, numCPUs, pointEnd-pointSt+1, pointSt, pointEnd, las.Header.NumberPoints)
+
+				recordLength := las.Header.PointRecordLength
+				for i := pointSt; i <= pointEnd; i++ {
+					p := las.pointData[i]
+
+					if !las.CheckPointXYZInvalid(p.X, p.Y, p.Z) {
+						pointErr.set(fmt.Errorf("invalid point X/Y/Z. point_pos:[%d] X:[%f] Y:[%f] Z:[%f]", i, p.X, p.Y, p.Z))
+						return
+					}
+
+					offset := i * recordLength
+					copy(b[offset:offset+recordLength], las.recordData[offset:offset+recordLength])
+					binary.LittleEndian.PutUint32(b[offset:offset+4], uint32(encodeCoordinate(p.X, las.Header.XOffset, las.Header.XScaleFactor)))
+					binary.LittleEndian.PutUint32(b[offset+4:offset+8], uint32(encodeCoordinate(p.Y, las.Header.YOffset, las.Header.YScaleFactor)))
+					binary.LittleEndian.PutUint32(b[offset+8:offset+12], uint32(encodeCoordinate(p.Z, las.Header.ZOffset, las.Header.ZScaleFactor)))
+				}
+			}(startingPoint, endingPoint, cpuThread)
+			startingPoint = endingPoint + 1
+			cpuThread = cpuThread + 1
+		}
 	}
 
 	wg.Wait()
 
-	if nSize, err := w.Write(b); err != nil {
-		glog.Fatal(err)
-	} else {
-		glog.Infoln("write nSize:", nSize)
+	if pointErr.err != nil {
+		return pointErr.err
 	}
 
-	if err := w.Flush(); err != nil {
-		glog.Fatal(err)
+	nSize, err := w.Write(b)
+	if err != nil {
+		return err
 	}
+	glog.Infoln("write nSize:", nSize)
 
-	return nil
+	return w.Flush()
+}
+
+// encodeCoordinate returns the integer value stored in the point records for the given coordinate
+func encodeCoordinate(value float64, offset float64, scale float64) int32 {
+	relative := decimal.NewFromFloat(value).Sub(decimal.NewFromFloat(offset))
+	return int32(relative.Div(decimal.NewFromFloat(scale)).IntPart())
 }
 
 // FixedRadiusSearch2D performs a 2D fixed radius search
@@ -1773,6 +2054,8 @@ type LasHeader struct {
 	MaxZ                 float64
 	MinZ                 float64
 	WaveformDataStart    uint64
+	StartOfFirstEVLR     uint64 // LAS 1.4 only
+	NumberOfEVLRs        int    // LAS 1.4 only
 	projectIDUsed        bool
 }
 
@@ -1844,6 +2127,10 @@ func (h LasHeader) String() string {
 	buffer.WriteString(s)
 	s = fmt.Sprintf("Waveform Data Start: %v\n", h.WaveformDataStart)
 	buffer.WriteString(s)
+	s = fmt.Sprintf("Start Of First EVLR: %v\n", h.StartOfFirstEVLR)
+	buffer.WriteString(s)
+	s = fmt.Sprintf("Number Of EVLRs: %v\n", h.NumberOfEVLRs)
+	buffer.WriteString(s)
 
 	return buffer.String()
 }
@@ -2151,6 +2438,81 @@ func (p *PointRecord3) RgbData() *RgbData {
 	return p.RGB
 }
 
+// PointRecordExtended is a LAS data record of the point formats 4 to 10. The fields shared with the legacy point
+// formats are decoded in PointRecord0, the other ones are read from the raw record, which also holds the wave packet
+// and the extra bytes
+type PointRecordExtended struct {
+	*PointRecord0
+	format  uint8
+	GPSTime float64
+	RGB     *RgbData // nil for the point formats without colors
+	Record  []byte
+}
+
+// Format returns the data format number.
+func (p *PointRecordExtended) Format() uint8 {
+	return p.format
+}
+
+// GpsTimeData returns the GPS time data for the LAS data.
+func (p *PointRecordExtended) GpsTimeData() float64 {
+	return p.GPSTime
+}
+
+// RgbData returns the RGB colour data for the LAS data.
+func (p *PointRecordExtended) RgbData() *RgbData {
+	if p.RGB == nil {
+		return &RgbData{}
+	}
+	return p.RGB
+}
+
+// ReturnNumber returns the return number of the data, the point formats 6 to 10 support up to 15 returns
+func (p *PointRecordExtended) ReturnNumber() byte {
+	if p.format >= 6 {
+		return p.Record[14] & 0x0F
+	}
+	return p.Record[14] & 0x07
+}
+
+// NumberOfReturns returns the number of returns of the data
+func (p *PointRecordExtended) NumberOfReturns() byte {
+	if p.format >= 6 {
+		return p.Record[14] >> 4
+	}
+	return (p.Record[14] >> 3) & 0x07
+}
+
+// Classification returns the class of the data, the point formats 6 to 10 support classes up to 255
+func (p *PointRecordExtended) Classification() byte {
+	if p.format >= 6 {
+		return p.Record[classificationOffets[p.format]]
+	}
+	return p.Record[classificationOffets[p.format]] & 0x1F
+}
+
+// ScanAngleDegrees returns the scan angle of the data in degrees
+func (p *PointRecordExtended) ScanAngleDegrees() float64 {
+	if p.format >= 6 {
+		return float64(int16(binary.LittleEndian.Uint16(p.Record[18:20]))) * 0.006
+	}
+	return float64(int8(p.Record[16]))
+}
+
+// Nir returns the near infrared channel of the data, false for the point formats without it
+func (p *PointRecordExtended) Nir() (uint16, bool) {
+	nirOffset := nirOffets[p.format]
+	if nirOffset < 0 {
+		return 0, false
+	}
+	return binary.LittleEndian.Uint16(p.Record[nirOffset : nirOffset+2]), true
+}
+
+// ExtraBytesValue returns the scaled value of the given component of the extra bytes attribute of the data
+func (p *PointRecordExtended) ExtraBytesValue(attribute *ExtraBytesAttribute, component int) float64 {
+	return attribute.Value(p.Record, 0, component)
+}
+
 // PointBitField is a data record bit field
 type PointBitField struct {
 	Value byte
@@ -2324,3 +2686,11 @@ func bytesToString(bs []byte, maxLength int) string {
 	}
 	return string(bs)
 }
+
+// Returns the number of goroutines used to read the point records
+func (las *LasFile) workers() int {
+	if las.numWorkers > 0 {
+		return las.numWorkers
+	}
+	return runtime.NumCPU()
+}
@@ -6,15 +6,19 @@
 package lidario
 
 import (
+	"context"
 	"encoding/binary"
+	"fmt"
 	"io"
+	"math"
 	"os"
-	"runtime"
 	"sync"
+	"sync/atomic"
 
+	"github.com/ecopia-map/cesium_tiler/internal/converters"
 	"github.com/ecopia-map/cesium_tiler/internal/data"
-	"github.com/ecopia-map/cesium_tiler/internal/geometry"
-	"github.com/ecopia-map/cesium_tiler/internal/octree/grid_tree"
+	"github.com/ecopia-map/cesium_tiler/internal/octree"
+	"github.com/ecopia-map/cesium_tiler/internal/point_filter"
 	"github.com/golang/glog"
 )
 
@@ -76,12 +80,90 @@ var classificationOffets = [11]int{
 	16, // Point format 10
 }
 
+// -1 for the point formats without GPS time
+var gpsTimeOffets = [11]int{
+	-1, // Point format 0
+	20, // Point format 1
+	-1, // Point format 2
+	20, // Point format 3
+	20, // Point format 4
+	20, // Point format 5
+	22, // Point format 6
+	22, // Point format 7
+	22, // Point format 8
+	22, // Point format 9
+	22, // Point format 10
+}
+
+// -1 for the point formats without near infrared channel
+var nirOffets = [11]int{
+	-1, // Point format 0
+	-1, // Point format 1
+	-1, // Point format 2
+	-1, // Point format 3
+	-1, // Point format 4
+	-1, // Point format 5
+	-1, // Point format 6
+	-1, // Point format 7
+	36, // Point format 8
+	-1, // Point format 9
+	36, // Point format 10
+}
+
+// Number of point records read at once when the points are streamed from the file
+const streamChunkPoints = 1 << 20
+
+// Number of points read by a goroutine between two checks of the context cancellation
+const cancelCheckPoints = 1 << 16
+
+// Records the first error raised by the goroutines processing the points of a file
+type firstError struct {
+	sync.Once
+	err error
+}
+
+func (e *firstError) set(err error) {
+	e.Do(func() { e.err = err })
+}
+
 type LasFileLoader struct {
 	LasFile *LasFile
-	Tree    *grid_tree.GridTree
+	Tree    octree.ITree
+
+	// if true the point records are read in chunks and not kept in memory, LasPoint reads them back from the file.
+	// LAZ files are decompressed one chunk at a time and their points cannot be read back by index
+	StreamPoints bool
+
+	// number of goroutines used to read the points, 0 uses one per CPU
+	NumWorkers int
+
+	// if set only the points accepted by the filter are added to the tree
+	Filter *point_filter.PointFilter
+
+	// if set the outliers found among the points of the file are not added to the tree. Requires the points to be
+	// kept in memory, so it is not applied to streamed files
+	Outliers *point_filter.OutlierFilter
+
+	// flags of the outliers of the file, by point index
+	outliers []bool
+
+	// number of outliers not added to the tree, by classification
+	numOutlierPoints [256]int64
+
+	// names of the extra bytes attributes whose values are stored in the point extend, in the same order
+	ExtraBytes []string
+
+	// extra bytes attributes of the file matching ExtraBytes
+	extraBytesAttributes []*ExtraBytesAttribute
+
+	// if set it is called with the number of points read, filtered or not, as the goroutines progress
+	OnPointsRead func(numPoints int)
+
+	// number of points discarded by the filter
+	numFilteredPoints int64
 }
 
-func NewLasFileLoader(tree *grid_tree.GridTree) *LasFileLoader {
+func NewLasFileLoader(tree octree.ITree) *LasFileLoader {
 	return &LasFileLoader{
 		LasFile: nil,
 		Tree:    tree,
@@ -89,12 +171,13 @@ func NewLasFileLoader(tree *grid_tree.GridTree) *LasFileLoader {
 }
 
 // NewLasFile creates a new LasFile structure which stores the points data directly into Point instances
-// which can be retrieved by index using the GetPoint function
-func (lasFileLoader *LasFileLoader) LoadLasFile(fileName string, inSrid int, eightBitColor bool) (*LasFile, error) {
+// which can be retrieved by index using the GetPoint function. Reading stops with the context error if the given
+// context is canceled
+func (lasFileLoader *LasFileLoader) LoadLasFile(ctx context.Context, fileName string, inSrid int, eightBitColor bool) (*LasFile, error) {
 	// initialize the VLR array
 	vlrs := []VLR{}
-	las := LasFile{fileName: fileName, fileMode: "r", Header: LasHeader{}, VlrData: vlrs}
-	if err := lasFileLoader.readForOctree(inSrid, eightBitColor, &las); err != nil {
+	las := LasFile{fileName: fileName, fileMode: "r", Header: LasHeader{}, VlrData: vlrs, numWorkers: lasFileLoader.NumWorkers}
+	if err := lasFileLoader.readForOctree(ctx, inSrid, eightBitColor, &las); err != nil {
 		return &las, err
 	}
 
@@ -102,7 +185,7 @@ func (lasFileLoader *LasFileLoader) LoadLasFile(fileName string, inSrid int, eig
 }
 
 // Reads the las file and produces a LasFile struct instance loading points data into its inner list of Point
-func (lasFileLoader *LasFileLoader) readForOctree(inSrid int, eightBitColor bool, las *LasFile) error {
+func (lasFileLoader *LasFileLoader) readForOctree(ctx context.Context, inSrid int, eightBitColor bool, las *LasFile) error {
 	glog.Infoln("las_file path:", las.fileName)
 
 	var err error
@@ -120,38 +203,224 @@ func (lasFileLoader *LasFileLoader) readForOctree(inSrid int, eightBitColor bool
 
 	lasFileLoader.LasFile = las
 	glog.Infof("las_file [%s] open success. num_of_points:%d", las.fileName, las.Header.NumberPoints)
+
+	lasFileLoader.extraBytesAttributes = make([]*ExtraBytesAttribute, len(lasFileLoader.ExtraBytes))
+	for i, name := range lasFileLoader.ExtraBytes {
+		attribute, ok := las.ExtraBytesAttribute(name)
+		if !ok {
+			return fmt.Errorf("extra bytes attribute [%s] not found in las_file [%s]", name, las.fileName)
+		}
+		lasFileLoader.extraBytesAttributes[i] = attribute
+	}
 	// glog.Infoln("las_file header", las.Header.String())
 
 	if las.fileMode != "rh" {
 
-		if las.Header.PointRecordLength == recLengths[las.Header.PointFormatID][0] {
-			las.usePointIntensity = true
-			las.usePointUserdata = true
-		} else if las.Header.PointRecordLength == recLengths[las.Header.PointFormatID][1] {
-			las.usePointIntensity = false
-			las.usePointUserdata = true
-		} else if las.Header.PointRecordLength == recLengths[las.Header.PointFormatID][2] {
-			las.usePointIntensity = true
-			las.usePointUserdata = false
-		} else if las.Header.PointRecordLength == recLengths[las.Header.PointFormatID][3] {
-			las.usePointIntensity = false
-			las.usePointUserdata = false
+		las.setOptionalFields()
+
+		if lasFileLoader.StreamPoints && las.compressed {
+			if err := lasFileLoader.streamCompressedPointsOctElem(ctx, inSrid, eightBitColor, las); err != nil {
+				return err
+			}
+		} else if lasFileLoader.StreamPoints {
+			if err := lasFileLoader.streamPointsOctElem(ctx, inSrid, eightBitColor, las); err != nil {
+				return err
+			}
+		} else {
+			b, err := las.readPointRecords()
+			if err != nil {
+				return err
+			}
+
+			if err := las.parsePointRecords(b); err != nil {
+				return err
+			}
+
+			if lasFileLoader.Outliers != nil {
+				if err := lasFileLoader.findOutliers(ctx, las); err != nil {
+					return err
+				}
+			}
+
+			if err := lasFileLoader.readPointsOctElem(ctx, inSrid, eightBitColor, las, b, 0, las.Header.NumberPoints); err != nil {
+				return err
+			}
+		}
+
+		if lasFileLoader.Filter != nil {
+			glog.Infof("las_file [%s] filtered out %d/%d points", las.fileName, lasFileLoader.NumFilteredPoints(), las.Header.NumberPoints)
+		}
+		if lasFileLoader.Outliers != nil {
+			glog.Infof("las_file [%s] removed %d/%d outliers, by classification %v", las.fileName, lasFileLoader.NumOutlierPoints(), las.Header.NumberPoints, lasFileLoader.OutlierPointsByClass())
+		}
+	}
+	return nil
+}
+
+// Flags the outliers among the points of the given las file, searching the neighbours of every point with the fixed
+// radius search
+func (lasFileLoader *LasFileLoader) findOutliers(ctx context.Context, las *LasFile) error {
+	if err := las.SetFixedRadiusSearchDistance(lasFileLoader.Outliers.SearchRadius(), true); err != nil {
+		return err
+	}
+	// the search index holds a reference to every point, it is released once the outliers are found
+	defer func() {
+		las.frs3D = nil
+		las.fixedRadiusSearch3DSet = false
+	}()
+
+	outliers, err := lasFileLoader.Outliers.FindOutliers(ctx, len(las.pointData), las.workers(), func(index int) []float64 {
+		p := las.pointData[index]
+		neighbours := las.FixedRadiusSearch3D(p.X, p.Y, p.Z)
+		squaredDistances := make([]float64, 0, neighbours.Len())
+		for node := neighbours.First(); node != nil; node = node.Next() {
+			if node.Index != index {
+				squaredDistances = append(squaredDistances, node.SquaredDist)
+			}
 		}
+		return squaredDistances
+	})
+	if err != nil {
+		return err
+	}
+	lasFileLoader.outliers = outliers
 
-		if err := las.readPoints(); err != nil {
+	return nil
+}
+
+// Returns the number of points of the las file removed as outliers
+func (lasFileLoader *LasFileLoader) NumOutlierPoints() int {
+	var numPoints int64
+	for i := range lasFileLoader.numOutlierPoints {
+		numPoints += atomic.LoadInt64(&lasFileLoader.numOutlierPoints[i])
+	}
+	return int(numPoints)
+}
+
+// Returns the number of points of the las file removed as outliers by classification, only the classifications
+// with outliers are present
+func (lasFileLoader *LasFileLoader) OutlierPointsByClass() map[uint8]int {
+	byClass := make(map[uint8]int)
+	for i := range lasFileLoader.numOutlierPoints {
+		if numPoints := atomic.LoadInt64(&lasFileLoader.numOutlierPoints[i]); numPoints > 0 {
+			byClass[uint8(i)] = int(numPoints)
+		}
+	}
+	return byClass
+}
+
+// Returns the number of points of the las file discarded by the filter
+func (lasFileLoader *LasFileLoader) NumFilteredPoints() int {
+	return int(atomic.LoadInt64(&lasFileLoader.numFilteredPoints))
+}
+
+func (lasFileLoader *LasFileLoader) reportPointsRead(numPoints int) {
+	if lasFileLoader.OnPointsRead != nil && numPoints > 0 {
+		lasFileLoader.OnPointsRead(numPoints)
+	}
+}
+
+// Reads the point records of the given las file chunk by chunk, so that only one chunk of raw records is in memory
+// at any time
+func (lasFileLoader *LasFileLoader) streamPointsOctElem(ctx context.Context, inSrid int, eightBitColor bool, las *LasFile) error {
+	las.streamed = true
+
+	recordLength := las.Header.PointRecordLength
+	b := make([]byte, streamChunkPoints*recordLength)
+	for firstPoint := 0; firstPoint < las.Header.NumberPoints; firstPoint += streamChunkPoints {
+		numPoints := las.Header.NumberPoints - firstPoint
+		if numPoints > streamChunkPoints {
+			numPoints = streamChunkPoints
+		}
+
+		offset := int64(las.Header.OffsetToPoints) + int64(firstPoint)*int64(recordLength)
+		if _, err := las.f.ReadAt(b[:numPoints*recordLength], offset); err != nil && err != io.EOF {
+			return err
+		}
+
+		if err := lasFileLoader.readPointsOctElem(ctx, inSrid, eightBitColor, las, b, firstPoint, numPoints); err != nil {
 			return err
 		}
+	}
+
+	return nil
+}
 
-		if err := lasFileLoader.readPointsOctElem(inSrid, eightBitColor, las); err != nil {
+// Decompresses the point records of the given laz file chunk by chunk, so that only one laz chunk and
+// streamChunkPoints decompressed records are in memory at any time
+func (lasFileLoader *LasFileLoader) streamCompressedPointsOctElem(ctx context.Context, inSrid int, eightBitColor bool, las *LasFile) error {
+	las.streamed = true
+
+	chunkStarts, chunkPoints, err := las.lazChunks()
+	if err != nil {
+		return err
+	}
+
+	recordLength := las.Header.PointRecordLength
+	b := make([]byte, streamChunkPoints*recordLength)
+	firstPoint := 0
+	numBuffered := 0
+	flush := func() error {
+		if err := lasFileLoader.readPointsOctElem(ctx, inSrid, eightBitColor, las, b, firstPoint, numBuffered); err != nil {
 			return err
 		}
+		firstPoint += numBuffered
+		numBuffered = 0
+		return nil
+	}
+
+	numDecompressed := 0
+	for i := range chunkPoints {
+		numPoints := chunkPoints[i]
+		if numDecompressed+numPoints > las.Header.NumberPoints {
+			numPoints = las.Header.NumberPoints - numDecompressed
+		}
+		if numPoints <= 0 {
+			break
+		}
+
+		chunk := make([]byte, chunkStarts[i+1]-chunkStarts[i])
+		if _, err := las.f.ReadAt(chunk, chunkStarts[i]); err != nil && err != io.EOF {
+			return err
+		}
+		reader, err := newLazChunkReader(chunk, las.lazInfo.Items, recordLength)
+		if err != nil {
+			return err
+		}
+
+		for numRead := 0; numRead < numPoints; {
+			n := numPoints - numRead
+			if n > streamChunkPoints-numBuffered {
+				n = streamChunkPoints - numBuffered
+			}
+			reader.readRecords(b[numBuffered*recordLength:(numBuffered+n)*recordLength], n)
+			numRead += n
+			numBuffered += n
+			if numBuffered == streamChunkPoints {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+		}
+		if err := reader.finish(); err != nil {
+			return err
+		}
+		numDecompressed += numPoints
+	}
+
+	if numDecompressed != las.Header.NumberPoints {
+		return fmt.Errorf("laz chunks contain %d points, header declares %d", numDecompressed, las.Header.NumberPoints)
+	}
+	if numBuffered > 0 {
+		return flush()
 	}
 	return nil
 }
 
-// Reads all the points of the given las file and parses them into a Point data structure which is then stored
-// in the given LasFile instance
-func (lasFileLoader *LasFileLoader) readPointsOctElem(inSrid int, eightBitColor bool, las *LasFile) error {
+// Reads numPoints points of the given las file, starting from firstPoint, from their raw records and parses them into
+// a Point data structure which is then stored in the given LasFile instance. The first error raised by a goroutine
+// stops the others and is returned
+func (lasFileLoader *LasFileLoader) readPointsOctElem(ctx context.Context, inSrid int, eightBitColor bool, las *LasFile, b []byte, firstPoint int, numPoints int) error {
 	las.Lock()
 	defer las.Unlock()
 	// las.pointDataOctElement = make([]octree.OctElement, las.Header.NumberPoints)
@@ -162,14 +431,6 @@ func (lasFileLoader *LasFileLoader) readPointsOctElem(inSrid int, eightBitColor
 		// las.rgbData = make([]RgbData, las.Header.NumberPoints)
 	}
 
-	// Estimate how many bytes are used to store the points
-	pointsLength := las.Header.NumberPoints * las.Header.PointRecordLength
-	b := make([]byte, pointsLength)
-	if _, err := las.f.ReadAt(b, int64(las.Header.OffsetToPoints)); err != nil && err != io.EOF {
-		glog.Fatal(err)
-		return err
-	}
-
 	// The LAS Specifications state that:
 	// " Point data items that are not ‘Required’ must be set to
 	// the equivalent of zero for the data type (e.g. 0.0 for floating types, null for ASCII, 0 for integers)."
@@ -180,22 +441,26 @@ func (lasFileLoader *LasFileLoader) readPointsOctElem(inSrid int, eightBitColor
 	// las file format specifications rather than bugfixing the original las read library logic
 	// imported and used in this project.
 
-	numCPUs := runtime.NumCPU()
+	numCPUs := las.workers()
 	glog.Infof("parallel read numCPUs:[%d] lasFilePath:[%s]", numCPUs, lasFileLoader.LasFile.fileName)
 
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
 	var wg sync.WaitGroup
-	blockSize := las.Header.NumberPoints / numCPUs
+	var pointErr firstError
+	blockSize := numPoints / numCPUs
 	if blockSize == 0 {
 		blockSize = 1
 	}
 	// blockSize = 100000000
 
-	var startingPoint int
+	startingPoint := firstPoint
 	cpuThread := 1
-	for startingPoint < las.Header.NumberPoints {
+	for startingPoint < firstPoint+numPoints {
 		endingPoint := startingPoint + blockSize
-		if endingPoint >= las.Header.NumberPoints {
-			endingPoint = las.Header.NumberPoints - 1
+		if endingPoint >= firstPoint+numPoints {
+			endingPoint = firstPoint + numPoints - 1
 		}
 		wg.Add(1)
 		go func(pointSt, pointEnd int, threadNum int) {
@@ -204,43 +469,74 @@ func (lasFileLoader *LasFileLoader) readPointsOctElem(inSrid int, eightBitColor
 				threadNum, numCPUs, pointEnd-pointSt+1, pointSt, pointEnd, las.Header.NumberPoints)
 
 			var offset int
+			var numFilteredPoints int64
+			var numReadPoints int
+			// points are added to the tree in batches, so that their coordinates are converted together
+			batch := make([]*data.Point, 0, converters.CoordinateBatchSize)
+			defer func() {
+				atomic.AddInt64(&lasFileLoader.numFilteredPoints, numFilteredPoints)
+				lasFileLoader.reportPointsRead(numReadPoints)
+			}()
 			// var p PointRecord0
 			for i := pointSt; i <= pointEnd; i++ {
-				offset = i * las.Header.PointRecordLength
+				if (i-pointSt)%cancelCheckPoints == 0 {
+					lasFileLoader.reportPointsRead(numReadPoints)
+					numReadPoints = 0
+					if ctx.Err() != nil {
+						pointErr.set(ctx.Err())
+						return
+					}
+				}
+				numReadPoints++
+
+				offset = (i - firstPoint) * las.Header.PointRecordLength
 				X, Y, Z, R, G, B, Intensity, Classification := readPoint(&las.Header, b, offset, eightBitColor)
 				if !las.CheckPointXYZInvalid(X, Y, Z) {
-					glog.Infof(" nonono invalid point_pos:[%d] X:[%f] Y:[%f] Z:[%f]", i, X, Y, Z)
-					glog.Fatal("invalid point X/Y/Z")
+					pointErr.set(fmt.Errorf("invalid point X/Y/Z. point_pos:[%d] X:[%f] Y:[%f] Z:[%f]", i, X, Y, Z))
+					cancel()
+					return
+				}
+				if lasFileLoader.Filter != nil && !lasFileLoader.Filter.Accept(readPointFilterAttributes(&las.Header, b, offset, X, Y, Z)) {
+					numFilteredPoints++
 					continue
 				}
-				pointExtend := &data.PointExtend{
-					LasPointIndex: i,
+				if lasFileLoader.outliers != nil && lasFileLoader.outliers[i] {
+					atomic.AddInt64(&lasFileLoader.numOutlierPoints[Classification], 1)
+					continue
 				}
+				pointExtend := readPointExtend(&las.Header, b, offset, i, lasFileLoader.extraBytesAttributes)
 
 				// glog.Infof(" oooooo point_pos:[%d] X:[%f] Y:[%f] Z:[%f]", i, X, Y, Z)
 				// glog.Infoln(" oooooo las_file_reader point", X, Y, Z, R, G, B, Intensity, Classification)
-				lasFileLoader.Tree.AddPoint(
-					&geometry.Coordinate{X: X, Y: Y, Z: Z},
-					R, G, B,
-					Intensity, Classification, inSrid,
-					pointExtend,
-				)
+				batch = append(batch, data.NewPoint(X, Y, Z, R, G, B, Intensity, Classification, pointExtend))
+				if len(batch) == cap(batch) {
+					if err := lasFileLoader.Tree.AddPoints(batch, inSrid); err != nil {
+						pointErr.set(err)
+						cancel()
+						return
+					}
+					batch = batch[:0]
+				}
 				// las.pointDataOctElement[i] = elem
 			}
+			if err := lasFileLoader.Tree.AddPoints(batch, inSrid); err != nil {
+				pointErr.set(err)
+				cancel()
+			}
 		}(startingPoint, endingPoint, cpuThread)
 		startingPoint = endingPoint + 1
 		cpuThread = cpuThread + 1
 	}
 	wg.Wait()
-	return nil
+	return pointErr.err
 }
 
 func readPoint(header *LasHeader, data []byte, offset int, eightBitColor bool) (
-	float64, float64, float64, uint8, uint8, uint8, uint8, uint8,
+	float64, float64, float64, uint8, uint8, uint8, uint16, uint8,
 ) {
 	var x, y, z float64
 	var r, g, b uint8
-	var intensity uint8
+	var intensity uint16
 	var classification uint8
 	xyzOffsetValues := xyzOffets[header.PointFormatID]
 	xOffset := xyzOffsetValues[0] + offset
@@ -265,9 +561,80 @@ func readPoint(header *LasHeader, data []byte, offset int, eightBitColor bool) (
 		b = uint8(binary.LittleEndian.Uint16(data[bOffset:bOffset+2]) / conversionFactor)
 	}
 	intensityOffset := 12 + offset
-	intensity = uint8(binary.LittleEndian.Uint16(data[intensityOffset:intensityOffset+2]) / 256)
+	intensity = binary.LittleEndian.Uint16(data[intensityOffset : intensityOffset+2])
 	classificationOffset := classificationOffets[header.PointFormatID] + offset
 	classification = data[classificationOffset]
+	if header.PointFormatID < 6 {
+		// the 3 high bits of the legacy classification byte hold the synthetic, key-point and withheld flags
+		classification &= 0x1F
+	}
 
 	return x, y, z, r, g, b, intensity, classification
 }
+
+// Returns the attributes of the point record at the given offset which are written in the batch table, including the
+// values of the given extra bytes attributes
+func readPointExtend(header *LasHeader, b []byte, offset int, lasPointIndex int, extraBytesAttributes []*ExtraBytesAttribute) *data.PointExtend {
+	pointExtend := &data.PointExtend{
+		LasPointIndex: lasPointIndex,
+		UserData:      b[offset+17],
+	}
+	pointExtend.ReturnNumber, pointExtend.NumberOfReturns = readPointReturns(header, b, offset)
+
+	if header.PointFormatID >= 6 {
+		// scan angle is stored in increments of 0.006 degrees
+		pointExtend.ScanAngle = float32(int16(binary.LittleEndian.Uint16(b[offset+18:offset+20]))) * 0.006
+		pointExtend.PointSourceID = binary.LittleEndian.Uint16(b[offset+20 : offset+22])
+	} else {
+		pointExtend.ScanAngle = float32(int8(b[offset+16]))
+		pointExtend.PointSourceID = binary.LittleEndian.Uint16(b[offset+18 : offset+20])
+	}
+
+	if gpsTimeOffset := gpsTimeOffets[header.PointFormatID]; gpsTimeOffset >= 0 {
+		pointExtend.GpsTime = math.Float64frombits(binary.LittleEndian.Uint64(b[offset+gpsTimeOffset : offset+gpsTimeOffset+8]))
+	}
+
+	if nirOffset := nirOffets[header.PointFormatID]; nirOffset >= 0 {
+		pointExtend.Nir = binary.LittleEndian.Uint16(b[offset+nirOffset : offset+nirOffset+2])
+	}
+
+	if len(extraBytesAttributes) > 0 {
+		pointExtend.ExtraBytes = make([]float64, len(extraBytesAttributes))
+		for i, attribute := range extraBytesAttributes {
+			pointExtend.ExtraBytes[i] = attribute.Value(b, offset, 0)
+		}
+	}
+
+	return pointExtend
+}
+
+// Returns the return number and the number of returns of the point record at the given offset
+func readPointReturns(header *LasHeader, data []byte, offset int) (uint8, uint8) {
+	returnByte := data[offset+14]
+	if header.PointFormatID >= 6 {
+		// point formats 6 to 10 store 4 bits return numbers
+		return returnByte & 0x0F, returnByte >> 4
+	}
+	return returnByte & 0x07, (returnByte >> 3) & 0x07
+}
+
+// Returns the attributes of the point record at the given offset checked by the point filter
+func readPointFilterAttributes(header *LasHeader, data []byte, offset int, x float64, y float64, z float64) *point_filter.PointAttributes {
+	attributes := &point_filter.PointAttributes{X: x, Y: y, Z: z}
+
+	attributes.ReturnNumber, attributes.NumberOfReturns = readPointReturns(header, data, offset)
+	if header.PointFormatID >= 6 {
+		// point formats 6 to 10 store the flags byte and a full classification byte
+		flags := data[offset+15]
+		attributes.Synthetic = flags&0x01 != 0
+		attributes.Withheld = flags&0x04 != 0
+		attributes.Classification = data[offset+classificationOffets[header.PointFormatID]]
+	} else {
+		classByte := data[offset+classificationOffets[header.PointFormatID]]
+		attributes.Synthetic = classByte&0x20 != 0
+		attributes.Withheld = classByte&0x80 != 0
+		attributes.Classification = classByte & 0x1F
+	}
+
+	return attributes
+}
@@ -0,0 +1,334 @@
+package tools
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/ecopia-map/cesium_tiler/internal/tiler"
+	"gopkg.in/yaml.v3"
+)
+
+// Keys of the configuration file corresponding to the command line flags, nested keys are separated by dots. Flags
+// without key, as help, version and the logging flags, cannot be set from the configuration file
+var flagConfigKeys = map[string][]string{
+	"input":                   {"input"},
+	"srid":                    {"srid"},
+	"8bit":                    {"eight_bit_colors"},
+	"zoffset":                 {"z_offset"},
+	"geoid":                   {"geoid"},
+	"geoid-grid":              {"geoid_grid"},
+	"geoid-interpolation":     {"geoid_interpolation"},
+	"points-max-num":          {"max_num_points_per_node"},
+	"points-min-num":          {"min_num_points_per_node"},
+	"folder":                  {"folder"},
+	"recursive":               {"recursive"},
+	"grid-max-size":           {"grid_max_size"},
+	"grid-min-size":           {"grid_min_size"},
+	"refine-mode":             {"refine_mode"},
+	"algorithm":               {"algorithm"},
+	"sampling":                {"sampling"},
+	"draco":                   {"draco"},
+	"draco-encoder-path":      {"draco_encoder_path"},
+	"draco-method":            {"draco_method"},
+	"draco-quantization-bits": {"draco_quantization_bits"},
+	"output-format":           {"output_format"},
+	"meshopt":                 {"meshopt"},
+	"bounding-volume":         {"bounding_volume"},
+	"batch-attributes":        {"batch_attributes"},
+	"progress":                {"progress"},
+	"progress-file":           {"progress_file"},
+	"workdir":                 {"workdir"},
+	"coordinate-converter":    {"coordinate_converter"},
+	"output":                  {"index.output"},
+	"use-edge-calculate":      {"index.use_edge_calculate"},
+	"implicit":                {"index.implicit"},
+	"subtree-levels":          {"index.subtree_levels"},
+	"memory-budget":           {"index.memory_budget"},
+	"resume":                  {"index.resume"},
+	"jobs":                    {"index.jobs"},
+	"workers":                 {"index.workers"},
+	"filter-classes":          {"index.filter.classes"},
+	"filter-exclude-classes":  {"index.filter.exclude_classes"},
+	"filter-returns":          {"index.filter.returns"},
+	"filter-withheld":         {"index.filter.withheld"},
+	"filter-synthetic":        {"index.filter.synthetic"},
+	"filter-z-range":          {"index.filter.z_min", "index.filter.z_max"},
+	"clip-box":                {"index.filter.clip_box"},
+	"clip-polygon":            {"index.filter.clip_polygon"},
+	"clip-srid":               {"index.filter.clip_srid"},
+	"sor-neighbors":           {"index.outliers.sor_neighbors"},
+	"sor-sigma":               {"index.outliers.sor_sigma"},
+	"sor-radius":              {"index.outliers.sor_radius"},
+	"ror-neighbors":           {"index.outliers.ror_neighbors"},
+	"ror-radius":              {"index.outliers.ror_radius"},
+	"merge-grid-max-size":     {"update.merge_grid_max_size"},
+	"dry-run":                 {"update.dry_run"},
+	"address":                 {"serve.address"},
+	"viewer":                  {"serve.viewer"},
+	"report":                  {"verify_tileset.report"},
+}
+
+// Returns the configuration keys of the flags set on the command line, which override the values of the
+// configuration file. Shorthand flags share the value of their flag, which gives their name
+func GetFlagConfigKeys(flagCommand *flag.FlagSet) map[string]bool {
+	keys := map[string]bool{}
+	flagCommand.Visit(func(set *flag.Flag) {
+		flagCommand.VisitAll(func(f *flag.Flag) {
+			if f.Value != set.Value {
+				return
+			}
+			for _, key := range flagConfigKeys[f.Name] {
+				keys[key] = true
+			}
+		})
+	})
+	return keys
+}
+
+// Reads a yaml or json configuration file, the format is chosen according to the extension of the file
+func ReadConfigFile(configPath string) (map[string]interface{}, error) {
+	content, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var config map[string]interface{}
+	switch strings.ToLower(filepath.Ext(configPath)) {
+	case ".json":
+		if err := json.Unmarshal(content, &config); err != nil {
+			return nil, fmt.Errorf("invalid json configuration [%s]: %v", configPath, err)
+		}
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(content, &config); err != nil {
+			return nil, fmt.Errorf("invalid yaml configuration [%s]: %v", configPath, err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported configuration file [%s], expected .yaml, .yml or .json", configPath)
+	}
+
+	// an empty or null document sets no option
+	if config == nil {
+		config = map[string]interface{}{}
+	}
+	return config, nil
+}
+
+// Sets the options to the values of the given configuration file, except for the given overridden keys. Options of
+// other commands, whose sections are not set in the options, are ignored. Returns the keys set from the file
+func ApplyConfigFile(opts *tiler.TilerOptions, configPath string, overridden map[string]bool) (map[string]bool, error) {
+	config, err := ReadConfigFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+	return ApplyConfig(opts, config, overridden)
+}
+
+// Sets the options to the values of the given configuration, except for the given overridden keys. Returns the keys
+// set from the configuration
+func ApplyConfig(opts *tiler.TilerOptions, config map[string]interface{}, overridden map[string]bool) (map[string]bool, error) {
+	schema, err := toConfigMap(&tiler.TilerOptions{
+		TilerIndexOptions:         &tiler.TilerIndexOptions{},
+		TilerMergeOptions:         &tiler.TilerMergeOptions{},
+		TilerUpdateOptions:        &tiler.TilerUpdateOptions{},
+		TilerVerifyOptions:        &tiler.TilerVerifyOptions{},
+		TilerServeOptions:         &tiler.TilerServeOptions{},
+		TilerVerifyTilesetOptions: &tiler.TilerVerifyTilesetOptions{},
+	})
+	if err != nil {
+		return nil, err
+	}
+	current, err := toConfigMap(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := map[string]bool{}
+	if err := mergeConfig(config, schema, current, "", overridden, applied); err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(current)
+	if err != nil {
+		return nil, err
+	}
+	newOpts := tiler.TilerOptions{}
+	if err := json.Unmarshal(data, &newOpts); err != nil {
+		var typeError *json.UnmarshalTypeError
+		if errors.As(err, &typeError) {
+			return nil, fmt.Errorf("config key [%s] cannot be a %s, expected %s", typeError.Field, typeError.Value, typeError.Type)
+		}
+		if key := findInvalidConfigKey(config, ""); key != "" {
+			return nil, fmt.Errorf("config key [%s]: %v", key, err)
+		}
+		return nil, fmt.Errorf("invalid configuration: %v", err)
+	}
+	newOpts.Command = opts.Command
+	newOpts.ProgressReporter = opts.ProgressReporter
+
+	if err := normalizeConfigEnums(&newOpts, applied); err != nil {
+		return nil, err
+	}
+
+	*opts = newOpts
+	return applied, nil
+}
+
+// Converts the options to the generic map of their json representation
+func toConfigMap(opts *tiler.TilerOptions) (map[string]interface{}, error) {
+	data, err := json.Marshal(opts)
+	if err != nil {
+		return nil, err
+	}
+	var config map[string]interface{}
+	err = json.Unmarshal(data, &config)
+	return config, err
+}
+
+// Copies the values of the configuration into the current options map, checking the keys against the schema. The
+// current map is nil for sections of other commands, whose keys are only checked
+func mergeConfig(config, schema, current map[string]interface{}, prefix string, overridden, applied map[string]bool) error {
+	for key, value := range config {
+		path := prefix + key
+		schemaValue, ok := schema[key]
+		if !ok {
+			return fmt.Errorf("config key [%s] is not a valid option", path)
+		}
+
+		schemaSection, isSection := schemaValue.(map[string]interface{})
+		if !isSection {
+			if current != nil && !overridden[path] {
+				current[key] = value
+				applied[path] = true
+			}
+			continue
+		}
+
+		section, ok := value.(map[string]interface{})
+		if !ok {
+			if value == nil {
+				continue
+			}
+			return fmt.Errorf("config key [%s] should be a mapping", path)
+		}
+		var currentSection map[string]interface{}
+		if current != nil {
+			currentSection, _ = current[key].(map[string]interface{})
+		}
+		if err := mergeConfig(section, schemaSection, currentSection, path+".", overridden, applied); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns the key of the first configuration value which cannot be decoded on its own. Used to name the key of the
+// errors returned by the custom decoders of the options, which do not report it
+func findInvalidConfigKey(config map[string]interface{}, prefix string) string {
+	for key, value := range config {
+		if section, ok := value.(map[string]interface{}); ok {
+			if invalidKey := findInvalidConfigKey(section, prefix+key+"."); invalidKey != "" {
+				return invalidKey
+			}
+			continue
+		}
+
+		// wraps the value in its sections to decode it alone
+		var document interface{} = map[string]interface{}{key: value}
+		keys := strings.Split(prefix, ".")
+		for i := len(keys) - 2; i >= 0; i-- {
+			document = map[string]interface{}{keys[i]: document}
+		}
+		data, err := json.Marshal(document)
+		if err != nil {
+			return prefix + key
+		}
+		if err := json.Unmarshal(data, &tiler.TilerOptions{}); err != nil {
+			return prefix + key
+		}
+	}
+	return ""
+}
+
+// Normalizes the values of the enumerated options set from the configuration, returning an error naming the key of
+// the invalid ones
+func normalizeConfigEnums(opts *tiler.TilerOptions, applied map[string]bool) error {
+	if applied["algorithm"] {
+		if opts.Algorithm = tiler.ParseAlgorithm(string(opts.Algorithm)); opts.Algorithm == "" {
+			return errors.New("config key [algorithm] should be either grid, random or randombox")
+		}
+	}
+	if applied["refine_mode"] {
+		if opts.RefineMode = tiler.ParseRefineMode(string(opts.RefineMode)); opts.RefineMode == "" {
+			return errors.New("config key [refine_mode] should be either ADD or REPLACE")
+		}
+	}
+	if applied["coordinate_converter"] {
+		if opts.CoordinateConverter = tiler.ParseCoordinateConverterType(string(opts.CoordinateConverter)); opts.CoordinateConverter == "" {
+			return errors.New("config key [coordinate_converter] should be either proj4 or native")
+		}
+	}
+
+	if applied["sampling"] {
+		if opts.Sampling = tiler.ParseSamplingStrategy(string(opts.Sampling)); opts.Sampling == "" {
+			return errors.New("config key [sampling] should be either closest, centroid, random, highest, lowest or min-distance")
+		}
+	}
+	if applied["draco_method"] {
+		if opts.DracoMethod = tiler.ParseDracoMethod(string(opts.DracoMethod)); opts.DracoMethod == "" {
+			return errors.New("config key [draco_method] should be either kd-tree or sequential")
+		}
+	}
+	if applied["output_format"] {
+		if opts.OutputFormat = tiler.ParseOutputFormat(string(opts.OutputFormat)); opts.OutputFormat == "" {
+			return errors.New("config key [output_format] should be either pnts or glb")
+		}
+	}
+	if applied["bounding_volume"] {
+		if opts.BoundingVolume = tiler.ParseBoundingVolumeType(string(opts.BoundingVolume)); opts.BoundingVolume == "" {
+			return errors.New("config key [bounding_volume] should be either region, box or sphere")
+		}
+	}
+	if applied["geoid_interpolation"] {
+		if opts.GeoidInterpolation = tiler.ParseGeoidInterpolation(string(opts.GeoidInterpolation)); opts.GeoidInterpolation == "" {
+			return errors.New("config key [geoid_interpolation] should be either bilinear or biquadratic")
+		}
+	}
+	if applied["progress"] {
+		if opts.Progress = tiler.ParseProgressMode(string(opts.Progress)); opts.Progress == "" {
+			return errors.New("config key [progress] should be either log, bar or json")
+		}
+	}
+	if applied["batch_attributes"] {
+		for i, attribute := range opts.BatchAttributes {
+			if opts.BatchAttributes[i] = tiler.ParseBatchAttribute(string(attribute)); opts.BatchAttributes[i] == "" {
+				return fmt.Errorf("config key [batch_attributes] contains the invalid attribute [%s]", attribute)
+			}
+		}
+	}
+
+	if opts.TilerIndexOptions == nil {
+		return nil
+	}
+	filter := &opts.TilerIndexOptions.Filter
+	if applied["index.filter.returns"] {
+		if filter.Returns = tiler.ParseReturnFilter(string(filter.Returns)); filter.Returns == "" {
+			return errors.New("config key [index.filter.returns] should be either all, first or last")
+		}
+	}
+	if applied["index.filter.withheld"] {
+		if filter.Withheld = tiler.ParseFlagFilter(string(filter.Withheld)); filter.Withheld == "" {
+			return errors.New("config key [index.filter.withheld] should be either keep, drop or only")
+		}
+	}
+	if applied["index.filter.synthetic"] {
+		if filter.Synthetic = tiler.ParseFlagFilter(string(filter.Synthetic)); filter.Synthetic == "" {
+			return errors.New("config key [index.filter.synthetic] should be either keep, drop or only")
+		}
+	}
+	return nil
+}
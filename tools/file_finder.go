@@ -9,9 +9,10 @@ import (
 	"github.com/golang/glog"
 )
 
+// Finds the las files given as input to the commands. An error is returned if the input folder cannot be walked
 type FileFinder interface {
-	GetLasFilesToProcess(opts *tiler.TilerOptions) []string
-	GetLasFilesToMerge(opts *tiler.TilerOptions) []string
+	GetLasFilesToProcess(opts *tiler.TilerOptions) ([]string, error)
+	GetLasFilesToMerge(opts *tiler.TilerOptions) ([]string, error)
 }
 
 type StandardFileFinder struct{}
@@ -20,27 +21,30 @@ func NewStandardFileFinder() FileFinder {
 	return &StandardFileFinder{}
 }
 
-func (f *StandardFileFinder) GetLasFilesToProcess(opts *tiler.TilerOptions) []string {
+func (f *StandardFileFinder) GetLasFilesToProcess(opts *tiler.TilerOptions) ([]string, error) {
 	// If folder processing is not enabled then las file is given by -input flag, otherwise look for las in -input folder
 	// eventually excluding nested folders if Recursive flag is disabled
 	if !opts.FolderProcessing {
-		return []string{opts.Input}
+		return []string{opts.Input}, nil
 	}
 
 	return f.getLasFilesFromInputFolder(opts)
 }
 
-func (f *StandardFileFinder) getLasFilesFromInputFolder(opts *tiler.TilerOptions) []string {
+func (f *StandardFileFinder) getLasFilesFromInputFolder(opts *tiler.TilerOptions) ([]string, error) {
 	var lasFiles = make([]string, 0)
 
 	baseInfo, _ := os.Stat(opts.Input)
 	err := filepath.Walk(
 		opts.Input,
 		func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
 			if info.IsDir() && !opts.Recursive && !os.SameFile(info, baseInfo) {
 				return filepath.SkipDir
 			} else {
-				if strings.ToLower(filepath.Ext(info.Name())) == ".las" {
+				if isLasOrLazFile(info.Name()) {
 					lasFiles = append(lasFiles, path)
 				}
 			}
@@ -49,20 +53,20 @@ func (f *StandardFileFinder) getLasFilesFromInputFolder(opts *tiler.TilerOptions
 	)
 
 	if err != nil {
-		glog.Fatal(err)
+		return nil, err
 	}
 
-	return lasFiles
+	return lasFiles, nil
 }
 
-func (f *StandardFileFinder) GetLasFilesToMerge(opts *tiler.TilerOptions) []string {
+func (f *StandardFileFinder) GetLasFilesToMerge(opts *tiler.TilerOptions) ([]string, error) {
 	// If folder processing is not enabled then las file is given by -input flag, otherwise look for las in -input folder
 	// eventually excluding nested folders if Recursive flag is disabled
 
 	return f.getLasFilesFromInputSubFolder(opts)
 }
 
-func (f *StandardFileFinder) getLasFilesFromInputSubFolder(opts *tiler.TilerOptions) []string {
+func (f *StandardFileFinder) getLasFilesFromInputSubFolder(opts *tiler.TilerOptions) ([]string, error) {
 	var lasFiles = make([]string, 0)
 
 	rootDir := strings.TrimSuffix(filepath.Join(opts.Input, "/"), "/")
@@ -74,6 +78,9 @@ func (f *StandardFileFinder) getLasFilesFromInputSubFolder(opts *tiler.TilerOpti
 	err := filepath.Walk(
 		rootDir,
 		func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
 			if os.SameFile(info, baseInfo) {
 				return nil // walk into rootDir
 			}
@@ -95,8 +102,14 @@ func (f *StandardFileFinder) getLasFilesFromInputSubFolder(opts *tiler.TilerOpti
 	)
 
 	if err != nil {
-		glog.Fatal(err)
+		return nil, err
 	}
 
-	return lasFiles
+	return lasFiles, nil
+}
+
+// isLasOrLazFile returns true if the given file name has a las or a laz (compressed las) extension
+func isLasOrLazFile(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	return ext == ".las" || ext == ".laz"
 }
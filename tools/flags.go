@@ -2,6 +2,9 @@ package tools
 
 import (
 	"flag"
+	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/golang/glog"
 )
@@ -12,6 +15,10 @@ const (
 	CommandMergeTree      = "merge-tree"
 	CommandVerifyLas      = "verify-las"
 	CommandVerifyLasMerge = "verify-las-merge"
+	CommandServe          = "serve"
+	CommandVerifyTileset  = "verify-tileset"
+	CommandConfig         = "config"
+	CommandUpdate         = "update"
 )
 
 type FlagsGlobal struct {
@@ -20,6 +27,7 @@ type FlagsGlobal struct {
 }
 
 type TilerFlags struct {
+	Config                    *string `json:"config"`
 	Input                     *string `json:"input"`
 	Srid                      *int    `json:"srid"`
 	EightBitColors            *bool
@@ -27,14 +35,27 @@ type TilerFlags struct {
 	MaxNumPoints              *int
 	MinNumPoints              *int
 	ZGeoidCorrection          *bool
+	GeoidGrid                 *string `json:"geoid_grid"`
+	GeoidInterpolation        *string `json:"geoid_interpolation"`
 	FolderProcessing          *bool
 	RecursiveFolderProcessing *bool
 	Algorithm                 *string
 	GridCellMaxSize           *float64 `json:"grid_max_size"`
 	GridCellMinSize           *float64 `json:"grid_min_size"`
 	RefineMode                *string  `json:"refine_mode"`
+	Sampling                  *string  `json:"sampling"`
+	CoordinateConverter       *string  `json:"coordinate_converter"`
 	Draco                     *bool
 	DracoEncoderPath          *string
+	DracoMethod               *string
+	DracoQuantizationBits     *int
+	OutputFormat              *string `json:"output_format"`
+	Meshopt                   *bool
+	BoundingVolume            *string `json:"bounding_volume"`
+	BatchAttributes           *string `json:"batch_attributes"`
+	Progress                  *string `json:"progress"`
+	ProgressFile              *string `json:"progress_file"`
+	WorkDir                   *string `json:"workdir"`
 }
 
 type FlagsForCommandIndex struct {
@@ -45,8 +66,38 @@ type FlagsForCommandIndex struct {
 
 	Output                         *string
 	UseEdgeCalculateGeometricError *bool
+	ImplicitTiling                 *bool
+	SubtreeLevels                  *int
+	MemoryBudget                   *int
+	Resume                         *bool
+	Jobs                           *int
+	Workers                        *int
 	Silent                         *bool
 	LogTimestamp                   *bool
+
+	FilterClasses        *string
+	FilterExcludeClasses *string
+	FilterReturns        *string
+	FilterWithheld       *string
+	FilterSynthetic      *string
+	FilterZRange         *string
+	ClipBox              *string
+	ClipPolygon          *string
+	ClipSrid             *int
+	SorNeighbors         *int
+	SorSigma             *float64
+	SorRadius            *float64
+	RorNeighbors         *int
+	RorRadius            *float64
+}
+
+type FlagsForCommandUpdate struct {
+	FlagsForCommandIndex
+
+	MergeGridMaxSize         *float64
+	MergeMinNumPointsPerNode *int
+	MergeMaxNumPointsPerNode *int
+	DryRun                   *bool
 }
 
 type FlagsForCommandMerge struct {
@@ -67,6 +118,25 @@ type FlagsForCommandVerify struct {
 	OffsetEnd   *int
 }
 
+type FlagsForCommandServe struct {
+	FlagCommand *flag.FlagSet
+	Config      *string
+	Input       *string
+	Address     *string
+	Viewer      *bool
+	Help        *bool
+	Version     *bool
+}
+
+type FlagsForCommandVerifyTileset struct {
+	FlagCommand *flag.FlagSet
+	Config      *string
+	Input       *string
+	Report      *string
+	Help        *bool
+	Version     *bool
+}
+
 func ParseFlagsGlobal() FlagsGlobal {
 	help := defineBoolFlag("help", "h", false, "Displays this help.")
 	version := defineBoolFlag("version", "ver", false, "Displays the version of cesium_tiler.")
@@ -84,12 +154,57 @@ func ParseFlagsForCommandIndex(args []string) FlagsForCommandIndex {
 
 	flagCommand := flag.NewFlagSet("command-index", flag.ExitOnError)
 
-	input := defineStringFlagCommand(flagCommand, "input", "i", "", "Specifies the input las file/folder.")
+	input := defineStringFlagCommand(flagCommand, "input", "i", "", "Specifies the input las file/folder. LAZ files are read as well, except the ones with point formats 6-10 whose layered chunked compression is not supported: convert them to las with laszip -i file.laz -o file.las")
 	output := defineStringFlagCommand(flagCommand, "output", "o", "", "Specifies the output folder where to write the tileset data.")
-	srid := defineIntFlagCommand(flagCommand, "srid", "e", 4326, "EPSG srid code of input points.")
+	flags := defineIndexFlags(flagCommand)
+
+	flagCommand.Parse(args)
+
+	flags.Input = input
+	flags.Output = output
+	return flags
+}
+
+func ParseFlagsForCommandUpdate(args []string) FlagsForCommandUpdate {
+	glog.Infoln(FmtJSONString(args))
+
+	flagCommand := flag.NewFlagSet("command-update", flag.ExitOnError)
+
+	input := defineStringFlagCommand(flagCommand, "input", "i", "", "Specifies the root folder of the merged tileset to update.")
+	mergeGridMaxSize := defineFloat64FlagCommand(flagCommand, "merge-grid-max-size", "", 10.0, "Max cell size in meters of the grid algorithm merging the folders, the grid-max-size given to the merge-tree command which wrote the tileset. It doubles at every level from the deepest folders up to the root")
+	dryRun := defineBoolFlagCommand(flagCommand, "dry-run", "", false, "Lists the changed las files and the folders that would be merged again without writing anything")
+	workDir := defineStringFlagCommand(flagCommand, "workdir", "", "", "Folder where the merge writes its temporary files, a folder of its own is created in it for every merged folder and removed when the merge ends. If not set the system temporary folder is used")
+	flags := defineIndexFlags(flagCommand)
+
+	mergeMinNumPointsPerNode := 10000
+	mergeMaxNumPointsPerNode := 50000
+
+	flagCommand.Parse(args)
+
+	// the chunk tilesets are written in the folders of the index manifests recording their las files
+	output := ""
+	flags.Input = input
+	flags.Output = &output
+	flags.WorkDir = workDir
+	return FlagsForCommandUpdate{
+		FlagsForCommandIndex:     flags,
+		MergeGridMaxSize:         mergeGridMaxSize,
+		MergeMinNumPointsPerNode: &mergeMinNumPointsPerNode,
+		MergeMaxNumPointsPerNode: &mergeMaxNumPointsPerNode,
+		DryRun:                   dryRun,
+	}
+}
+
+// Defines the flags of the index command, except for the input and output flags, which differ among the commands
+// indexing las files
+func defineIndexFlags(flagCommand *flag.FlagSet) FlagsForCommandIndex {
+	config := defineStringFlagCommand(flagCommand, "config", "", "", "Path of a yaml or json configuration file setting the options of the command, whose keys are listed by the config dump command. Flags set on the command line override the values of the file.")
+	srid := defineIntFlagCommand(flagCommand, "srid", "e", 4326, "EPSG srid code of input points. If not set it is read from the input files, 4326 is used if they don't declare it.")
 	eightBit := defineBoolFlagCommand(flagCommand, "8bit", "b", false, "Assumes the input LAS has colors encoded in eight bit format. Default is false (LAS has 16 bit color depth)")
 	zOffset := defineFloat64FlagCommand(flagCommand, "zoffset", "z", 0, "Vertical offset to apply to points, in meters.")
 	zGeoidCorrection := defineBoolFlagCommand(flagCommand, "geoid", "g", false, "Enables Geoid to Ellipsoid elevation correction. Use this flag if your input LAS files have Z coordinates specified relative to the Earth geoid rather than to the standard ellipsoid.")
+	geoidGrid := defineStringFlagCommand(flagCommand, "geoid-grid", "", "", "Optional path of a geoid height grid file used by the geoid correction, either a VDatum .gtx, a NOAA .bin or a single band GeoTIFF grid in EPSG:4326 such as the EGM96 and EGM2008 grids. If not set the geoid height is computed from the EGM180 model for the first point and applied to all the points")
+	geoidInterpolation := defineStringFlagCommand(flagCommand, "geoid-interpolation", "", "bilinear", "Interpolation of the geoid grid heights, can be 'bilinear' or 'biquadratic'. 'biquadratic' follows the curvature of the geoid on coarse grids.")
 	maxNumPointsPerNode := defineIntFlagCommand(flagCommand, "points-max-num", "y", 160000, "Maximun allowed number of points per node for GridTree Algorithms.")
 	minNumPointsPerNode := defineIntFlagCommand(flagCommand, "points-min-num", "m", 10000, "Minimum allowed number of points per node for GridTree Algorithms.")
 	folderProcessing := defineBoolFlagCommand(flagCommand, "folder", "f", false, "Enables processing of all las files from input folder. Input must be a folder if specified")
@@ -97,41 +212,99 @@ func ParseFlagsForCommandIndex(args []string) FlagsForCommandIndex {
 	gridCellMaxSize := defineFloat64FlagCommand(flagCommand, "grid-max-size", "x", 5.0, "Max cell size in meters for the grid algorithm. It roughly represents the max spacing between any two samples. ")
 	gridCellMinSize := defineFloat64FlagCommand(flagCommand, "grid-min-size", "n", 0.15, "Min cell size in meters for the grid algorithm. It roughly represents the minimum possible size of a 3d tile. ")
 	refineMode := defineStringFlagCommand(flagCommand, "refine-mode", "", "ADD", "Type of refine mode, can be 'ADD' or 'REPLACE'. 'ADD' means that child tiles will not contain the parent tiles points. 'REPLACE' means that they will also contain the parent tiles points. ADD implies less disk space but more network overhead when fetching the data, REPLACE is the opposite.")
+	algorithm := defineStringFlagCommand(flagCommand, "algorithm", "", "grid", "Algorithm sampling the points into the tree, can be 'grid', 'random' or 'randombox'. 'grid' keeps a point per cell of a grid halving in size at every level, 'random' picks the points uniformly at random and 'randombox' picks them at random from small boxes, spacing them more evenly. memory-budget and the merge commands require 'grid'.")
+	sampling := defineStringFlagCommand(flagCommand, "sampling", "", "closest", "Strategy choosing the points kept by the grid cells of the coarse levels, can be 'closest', 'centroid', 'random', 'highest', 'lowest' or 'min-distance'. 'closest' keeps the point closest to the cell center, 'centroid' moves it to the centroid of the cell points with their mean color and intensity, 'highest' and 'lowest' keep the point with the highest or lowest Z and 'min-distance' keeps only the points farther than the cell size from each other.")
+	coordinateConverter := defineStringFlagCommand(flagCommand, "coordinate-converter", "", "proj4", "Library converting the coordinates, can be 'proj4' or 'native'. 'native' is a pure Go port of the proj4 projections, faster as it doesn't go through cgo, which supports Transverse Mercator, UTM, Lambert Conformal Conic, Mercator, geographic and geocentric systems with translation and Helmert datum shifts. Other srids, and the ones needing grid shift files as NAD27, require 'proj4'.")
 	draco := defineBoolFlagCommand(flagCommand, "draco", "", false, "Use Draco algorithm to compress xyz and color")
-	dracoEncoderPath := defineStringFlagCommand(flagCommand, "draco-encoder-path", "", "", "draco-encoder-path")
+	dracoEncoderPath := defineStringFlagCommand(flagCommand, "draco-encoder-path", "", "", "Optional path of an external draco_encoder binary used to compress pnts content. It does not compress batch attributes: the default ones are not written and explicitly set ones are rejected. If not set draco compression is performed in-process")
+	dracoMethod := defineStringFlagCommand(flagCommand, "draco-method", "", "kd-tree", "Draco encoding method, can be 'kd-tree' or 'sequential'. 'kd-tree' reorders the points and produces smaller tiles, 'sequential' is faster and keeps the point order.")
+	dracoQuantizationBits := defineIntFlagCommand(flagCommand, "draco-quantization-bits", "", 11, "Number of bits used by Draco to quantize point positions, between 1 and 30.")
+	outputFormat := defineStringFlagCommand(flagCommand, "output-format", "", "pnts", "Format of the tile content, can be 'pnts' or 'glb'. 'pnts' writes 3D Tiles 1.0 point cloud tiles, 'glb' writes 3D Tiles 1.1 glTF tiles with POINTS primitives.")
+	meshopt := defineBoolFlagCommand(flagCommand, "meshopt", "", false, "Use EXT_meshopt_compression to compress glb vertex attributes. Requires output-format glb")
+	boundingVolume := defineStringFlagCommand(flagCommand, "bounding-volume", "", "region", "Type of the tile bounding volumes, can be 'region', 'box' or 'sphere'. 'region' writes longitude/latitude regions, 'box' and 'sphere' write oriented boxes and spheres fitted to the points of the tiles, which cull better in the viewer.")
+	batchAttributes := defineStringFlagCommand(flagCommand, "batch-attributes", "", "intensity,classification", "Comma separated list of the point attributes written in the batch table of pnts tiles or as vertex attributes of glb tiles, among 'intensity', 'classification', 'gps-time', 'return-number', 'number-of-returns', 'point-source-id', 'scan-angle', 'user-data', 'nir' and 'extra:<name>' for the LAS 1.4 extra bytes attribute with the given name. An empty list writes no attribute")
+	progress := defineStringFlagCommand(flagCommand, "progress", "", "log", "How the progress of the processing stages is reported, can be 'log', 'bar' or 'json'. 'bar' draws a progress bar on the terminal, 'json' writes a stream of json events, one per line. A summary.json with the statistics of the run is always written in the output folder")
+	progressFile := defineStringFlagCommand(flagCommand, "progress-file", "", "", "Path of the file receiving the json progress events. If not set they are written to the standard output")
 
 	useEdgeCalculateGeometricError := defineBoolFlagCommand(flagCommand, "use-edge-calculate", "d", true, "Assumes use chunk-edge x/y/z to calculate tileset geometricError")
+	implicitTiling := defineBoolFlagCommand(flagCommand, "implicit", "", false, "Writes the tileset as a 3D Tiles 1.1 implicit octree with subtree files instead of nested tileset.json files")
+	subtreeLevels := defineIntFlagCommand(flagCommand, "subtree-levels", "", 5, "Number of octree levels stored in each subtree file when implicit is enabled")
+	memoryBudget := defineIntFlagCommand(flagCommand, "memory-budget", "", 0, "Max memory in MB used to hold points while building the tree. When the input exceeds it points are spilled to disk and tiles are written as soon as their subtree is complete. 0 keeps all the points in memory")
+	resume := defineBoolFlagCommand(flagCommand, "resume", "", false, "Resumes an interrupted run using the manifest of the output folder. Files whose chunk tileset is complete and unchanged are skipped, partially written chunks are removed and processed again")
+	jobs := defineIntFlagCommand(flagCommand, "jobs", "j", 1, "Number of las files processed concurrently. The workers and the memory budget are split among the concurrent files")
+	workers := defineIntFlagCommand(flagCommand, "workers", "", 0, "Total number of goroutines used to read, build and export the points of the concurrent files. 0 uses one per CPU")
+	filterClasses := defineStringFlagCommand(flagCommand, "filter-classes", "", "", "Comma separated list of the classifications of the points to keep, e.g. '2,6'. If not set all the classifications are kept")
+	filterExcludeClasses := defineStringFlagCommand(flagCommand, "filter-exclude-classes", "", "", "Comma separated list of the classifications of the points to discard, e.g. '7,18' to remove noise")
+	filterReturns := defineStringFlagCommand(flagCommand, "filter-returns", "", "all", "Returns to keep, can be 'all', 'first' or 'last'")
+	filterWithheld := defineStringFlagCommand(flagCommand, "filter-withheld", "", "keep", "How points flagged as withheld are handled, can be 'keep', 'drop' or 'only'")
+	filterSynthetic := defineStringFlagCommand(flagCommand, "filter-synthetic", "", "keep", "How points flagged as synthetic are handled, can be 'keep', 'drop' or 'only'")
+	filterZRange := defineStringFlagCommand(flagCommand, "filter-z-range", "", "", "Range 'min,max' of the Z of the points to keep, in the units of the input points. Either bound can be left empty, e.g. '0,' keeps the points above 0")
+	clipBox := defineStringFlagCommand(flagCommand, "clip-box", "", "", "Box 'xmin,ymin,xmax,ymax' outside of which points are discarded, in the clip-srid coordinates")
+	clipPolygon := defineStringFlagCommand(flagCommand, "clip-polygon", "", "", "Path of a GeoJSON or WKT file with the polygons outside of which points are discarded, in the clip-srid coordinates")
+	clipSrid := defineIntFlagCommand(flagCommand, "clip-srid", "", 0, "EPSG srid code of the clip-box and clip-polygon coordinates. 0 uses the srid of the input points")
+	sorNeighbors := defineIntFlagCommand(flagCommand, "sor-neighbors", "", 0, "Number of nearest neighbours of the statistical outlier removal, which removes the points whose mean distance to them is greater than the one of all the points by more than sor-sigma standard deviations. 0 disables it")
+	sorSigma := defineFloat64FlagCommand(flagCommand, "sor-sigma", "", 2.0, "Number of standard deviations of the mean neighbour distance above which a point is removed by the statistical outlier removal")
+	sorRadius := defineFloat64FlagCommand(flagCommand, "sor-radius", "", 1.0, "Distance the neighbours of the statistical outlier removal are searched within, in the units of the input points. The neighbours not found are counted at this distance")
+	rorNeighbors := defineIntFlagCommand(flagCommand, "ror-neighbors", "", 0, "Min number of neighbours within ror-radius a point must have not to be removed by the radius outlier removal. 0 disables it")
+	rorRadius := defineFloat64FlagCommand(flagCommand, "ror-radius", "", 1.0, "Radius of the radius outlier removal, in the units of the input points")
 	silent := defineBoolFlagCommand(flagCommand, "silent", "s", false, "Use to suppress all the non-error messages.")
 	logTimestamp := defineBoolFlagCommand(flagCommand, "timestamp", "t", false, "Adds timestamp to log messages.")
 	help := defineBoolFlagCommand(flagCommand, "help", "h", false, "Displays this help.")
 	version := defineBoolFlagCommand(flagCommand, "version", "v", false, "Displays the version of cesium_tiler.")
 
-	algorithm := "grid"
-
-	flagCommand.Parse(args)
-
 	return FlagsForCommandIndex{
 		FlagCommand: flagCommand,
 		TilerFlags: TilerFlags{
-			Input:                     input,
+			Config:                    config,
 			Srid:                      srid,
 			EightBitColors:            eightBit,
 			ZOffset:                   zOffset,
 			MaxNumPoints:              maxNumPointsPerNode,
 			MinNumPoints:              minNumPointsPerNode,
 			ZGeoidCorrection:          zGeoidCorrection,
+			GeoidGrid:                 geoidGrid,
+			GeoidInterpolation:        geoidInterpolation,
 			FolderProcessing:          folderProcessing,
 			RecursiveFolderProcessing: recursiveFolderProcessing,
-			Algorithm:                 &algorithm,
+			Algorithm:                 algorithm,
 			GridCellMaxSize:           gridCellMaxSize,
 			GridCellMinSize:           gridCellMinSize,
 			RefineMode:                refineMode,
+			Sampling:                  sampling,
+			CoordinateConverter:       coordinateConverter,
 			Draco:                     draco,
 			DracoEncoderPath:          dracoEncoderPath,
+			DracoMethod:               dracoMethod,
+			DracoQuantizationBits:     dracoQuantizationBits,
+			OutputFormat:              outputFormat,
+			Meshopt:                   meshopt,
+			BoundingVolume:            boundingVolume,
+			BatchAttributes:           batchAttributes,
+			Progress:                  progress,
+			ProgressFile:              progressFile,
 		},
-		Output:                         output,
 		UseEdgeCalculateGeometricError: useEdgeCalculateGeometricError,
+		ImplicitTiling:                 implicitTiling,
+		SubtreeLevels:                  subtreeLevels,
+		MemoryBudget:                   memoryBudget,
+		Resume:                         resume,
+		Jobs:                           jobs,
+		Workers:                        workers,
 		Silent:                         silent,
+		FilterClasses:                  filterClasses,
+		FilterExcludeClasses:           filterExcludeClasses,
+		FilterReturns:                  filterReturns,
+		FilterWithheld:                 filterWithheld,
+		FilterSynthetic:                filterSynthetic,
+		FilterZRange:                   filterZRange,
+		ClipBox:                        clipBox,
+		ClipPolygon:                    clipPolygon,
+		ClipSrid:                       clipSrid,
+		SorNeighbors:                   sorNeighbors,
+		SorSigma:                       sorSigma,
+		SorRadius:                      sorRadius,
+		RorNeighbors:                   rorNeighbors,
+		RorRadius:                      rorRadius,
 		LogTimestamp:                   logTimestamp,
 		Help:                           help,
 		Version:                        version,
@@ -143,17 +316,31 @@ func ParseFlagsForCommandMerge(args []string) FlagsForCommandMerge {
 
 	flagCommand := flag.NewFlagSet("command-merge", flag.ExitOnError)
 
+	config := defineStringFlagCommand(flagCommand, "config", "", "", "Path of a yaml or json configuration file setting the options of the command, whose keys are listed by the config dump command. Flags set on the command line override the values of the file.")
 	input := defineStringFlagCommand(flagCommand, "input", "i", "", "Specifies the input tileset parent folder.")
-	srid := defineIntFlagCommand(flagCommand, "srid", "e", 4326, "EPSG srid code of input points.")
+	srid := defineIntFlagCommand(flagCommand, "srid", "e", 4326, "EPSG srid code of input points. If not set it is read from the input files, 4326 is used if they don't declare it.")
 	eightBit := defineBoolFlagCommand(flagCommand, "8bit", "b", false, "Assumes the input LAS has colors encoded in eight bit format. Default is false (LAS has 16 bit color depth)")
 	zOffset := defineFloat64FlagCommand(flagCommand, "zoffset", "z", 0, "Vertical offset to apply to points, in meters.")
 	zGeoidCorrection := defineBoolFlagCommand(flagCommand, "geoid", "g", false, "Enables Geoid to Ellipsoid elevation correction. Use this flag if your input LAS files have Z coordinates specified relative to the Earth geoid rather than to the standard ellipsoid.")
+	geoidGrid := defineStringFlagCommand(flagCommand, "geoid-grid", "", "", "Optional path of a geoid height grid file used by the geoid correction, either a VDatum .gtx, a NOAA .bin or a single band GeoTIFF grid in EPSG:4326 such as the EGM96 and EGM2008 grids. If not set the geoid height is computed from the EGM180 model for the first point and applied to all the points")
+	geoidInterpolation := defineStringFlagCommand(flagCommand, "geoid-interpolation", "", "bilinear", "Interpolation of the geoid grid heights, can be 'bilinear' or 'biquadratic'. 'biquadratic' follows the curvature of the geoid on coarse grids.")
 	recursiveFolderProcessing := defineBoolFlagCommand(flagCommand, "recursive", "r", false, "Enables recursive lookup for all .las files inside the subfolders")
 	gridCellMaxSize := defineFloat64FlagCommand(flagCommand, "grid-max-size", "x", 10.0, "Max cell size in meters for the grid algorithm. It roughly represents the max spacing between any two samples. ")
 	gridCellMinSize := defineFloat64FlagCommand(flagCommand, "grid-min-size", "n", 5.0, "Min cell size in meters for the grid algorithm. It roughly represents the minimum possible size of a 3d tile. ")
 	refineMode := defineStringFlagCommand(flagCommand, "refine-mode", "", "ADD", "Type of refine mode, can be 'ADD' or 'REPLACE'. 'ADD' means that child tiles will not contain the parent tiles points. 'REPLACE' means that they will also contain the parent tiles points. ADD implies less disk space but more network overhead when fetching the data, REPLACE is the opposite.")
+	sampling := defineStringFlagCommand(flagCommand, "sampling", "", "closest", "Strategy choosing the points kept by the grid cells of the coarse levels, can be 'closest', 'centroid', 'random', 'highest', 'lowest' or 'min-distance'. 'closest' keeps the point closest to the cell center, 'centroid' moves it to the centroid of the cell points with their mean color and intensity, 'highest' and 'lowest' keep the point with the highest or lowest Z and 'min-distance' keeps only the points farther than the cell size from each other.")
+	coordinateConverter := defineStringFlagCommand(flagCommand, "coordinate-converter", "", "proj4", "Library converting the coordinates, can be 'proj4' or 'native'. 'native' is a pure Go port of the proj4 projections, faster as it doesn't go through cgo, which supports Transverse Mercator, UTM, Lambert Conformal Conic, Mercator, geographic and geocentric systems with translation and Helmert datum shifts. Other srids, and the ones needing grid shift files as NAD27, require 'proj4'.")
 	draco := defineBoolFlagCommand(flagCommand, "draco", "", false, "Use Draco algorithm to compress xyz and color")
-	dracoEncoderPath := defineStringFlagCommand(flagCommand, "draco-encoder-path", "", "", "draco-encoder-path")
+	dracoEncoderPath := defineStringFlagCommand(flagCommand, "draco-encoder-path", "", "", "Optional path of an external draco_encoder binary used to compress pnts content. It does not compress batch attributes: the default ones are not written and explicitly set ones are rejected. If not set draco compression is performed in-process")
+	dracoMethod := defineStringFlagCommand(flagCommand, "draco-method", "", "kd-tree", "Draco encoding method, can be 'kd-tree' or 'sequential'. 'kd-tree' reorders the points and produces smaller tiles, 'sequential' is faster and keeps the point order.")
+	dracoQuantizationBits := defineIntFlagCommand(flagCommand, "draco-quantization-bits", "", 11, "Number of bits used by Draco to quantize point positions, between 1 and 30.")
+	outputFormat := defineStringFlagCommand(flagCommand, "output-format", "", "pnts", "Format of the tile content, can be 'pnts' or 'glb'. 'pnts' writes 3D Tiles 1.0 point cloud tiles, 'glb' writes 3D Tiles 1.1 glTF tiles with POINTS primitives.")
+	meshopt := defineBoolFlagCommand(flagCommand, "meshopt", "", false, "Use EXT_meshopt_compression to compress glb vertex attributes. Requires output-format glb")
+	boundingVolume := defineStringFlagCommand(flagCommand, "bounding-volume", "", "region", "Type of the tile bounding volumes, can be 'region', 'box' or 'sphere'. 'region' writes longitude/latitude regions, 'box' and 'sphere' write oriented boxes and spheres fitted to the points of the tiles, which cull better in the viewer.")
+	batchAttributes := defineStringFlagCommand(flagCommand, "batch-attributes", "", "intensity,classification", "Comma separated list of the point attributes written in the batch table of pnts tiles or as vertex attributes of glb tiles, among 'intensity', 'classification', 'gps-time', 'return-number', 'number-of-returns', 'point-source-id', 'scan-angle', 'user-data', 'nir' and 'extra:<name>' for the LAS 1.4 extra bytes attribute with the given name. An empty list writes no attribute")
+	progress := defineStringFlagCommand(flagCommand, "progress", "", "log", "How the progress of the processing stages is reported, can be 'log', 'bar' or 'json'. 'bar' draws a progress bar on the terminal, 'json' writes a stream of json events, one per line. A summary.json with the statistics of the run is always written in the output folder")
+	progressFile := defineStringFlagCommand(flagCommand, "progress-file", "", "", "Path of the file receiving the json progress events. If not set they are written to the standard output")
+	workDir := defineStringFlagCommand(flagCommand, "workdir", "", "", "Folder where the merge writes its temporary files, a folder of its own is created in it for every merged folder and removed when the merge ends. If not set the system temporary folder is used")
 
 	help := defineBoolFlagCommand(flagCommand, "help", "h", false, "Displays this help.")
 	version := defineBoolFlagCommand(flagCommand, "version", "v", false, "Displays the version of cesium_tiler.")
@@ -169,6 +356,7 @@ func ParseFlagsForCommandMerge(args []string) FlagsForCommandMerge {
 	return FlagsForCommandMerge{
 		FlagCommand: flagCommand,
 		TilerFlags: TilerFlags{
+			Config:                    config,
 			Input:                     input,
 			Srid:                      srid,
 			EightBitColors:            eightBit,
@@ -176,14 +364,27 @@ func ParseFlagsForCommandMerge(args []string) FlagsForCommandMerge {
 			MaxNumPoints:              &maxNumPointsPerNode,
 			MinNumPoints:              &minNumPointsPerNode,
 			ZGeoidCorrection:          zGeoidCorrection,
+			GeoidGrid:                 geoidGrid,
+			GeoidInterpolation:        geoidInterpolation,
 			FolderProcessing:          &folderProcessing,
 			RecursiveFolderProcessing: recursiveFolderProcessing,
 			Algorithm:                 &algorithm,
 			GridCellMaxSize:           gridCellMaxSize,
 			GridCellMinSize:           gridCellMinSize,
 			RefineMode:                refineMode,
+			Sampling:                  sampling,
+			CoordinateConverter:       coordinateConverter,
 			Draco:                     draco,
 			DracoEncoderPath:          dracoEncoderPath,
+			DracoMethod:               dracoMethod,
+			DracoQuantizationBits:     dracoQuantizationBits,
+			OutputFormat:              outputFormat,
+			Meshopt:                   meshopt,
+			BoundingVolume:            boundingVolume,
+			BatchAttributes:           batchAttributes,
+			Progress:                  progress,
+			ProgressFile:              progressFile,
+			WorkDir:                   workDir,
 		},
 		Help:    help,
 		Version: version,
@@ -195,15 +396,20 @@ func ParseFlagsForCommandVerify(args []string) FlagsForCommandVerify {
 
 	flagCommand := flag.NewFlagSet("command-verify", flag.ExitOnError)
 
+	config := defineStringFlagCommand(flagCommand, "config", "", "", "Path of a yaml or json configuration file setting the options of the command, whose keys are listed by the config dump command. Flags set on the command line override the values of the file.")
 	input := defineStringFlagCommand(flagCommand, "input", "i", "", "Specifies the input tileset parent folder.")
 	output := defineStringFlagCommand(flagCommand, "output", "o", "", "Specifies the output folder where to write the tileset data.")
-	srid := defineIntFlagCommand(flagCommand, "srid", "e", 4326, "EPSG srid code of input points.")
+	srid := defineIntFlagCommand(flagCommand, "srid", "e", 4326, "EPSG srid code of input points. If not set it is read from the input files, 4326 is used if they don't declare it.")
 	eightBit := defineBoolFlagCommand(flagCommand, "8bit", "b", false, "Assumes the input LAS has colors encoded in eight bit format. Default is false (LAS has 16 bit color depth)")
 	zOffset := defineFloat64FlagCommand(flagCommand, "zoffset", "z", 0, "Vertical offset to apply to points, in meters.")
 	zGeoidCorrection := defineBoolFlagCommand(flagCommand, "geoid", "g", false, "Enables Geoid to Ellipsoid elevation correction. Use this flag if your input LAS files have Z coordinates specified relative to the Earth geoid rather than to the standard ellipsoid.")
+	geoidGrid := defineStringFlagCommand(flagCommand, "geoid-grid", "", "", "Optional path of a geoid height grid file used by the geoid correction, either a VDatum .gtx, a NOAA .bin or a single band GeoTIFF grid in EPSG:4326 such as the EGM96 and EGM2008 grids. If not set the geoid height is computed from the EGM180 model for the first point and applied to all the points")
+	geoidInterpolation := defineStringFlagCommand(flagCommand, "geoid-interpolation", "", "bilinear", "Interpolation of the geoid grid heights, can be 'bilinear' or 'biquadratic'. 'biquadratic' follows the curvature of the geoid on coarse grids.")
 	recursiveFolderProcessing := defineBoolFlagCommand(flagCommand, "recursive", "r", false, "Enables recursive lookup for all .las files inside the subfolders")
 	gridCellMaxSize := defineFloat64FlagCommand(flagCommand, "grid-max-size", "x", 10.0, "Max cell size in meters for the grid algorithm. It roughly represents the max spacing between any two samples. ")
 	gridCellMinSize := defineFloat64FlagCommand(flagCommand, "grid-min-size", "n", 5.0, "Min cell size in meters for the grid algorithm. It roughly represents the minimum possible size of a 3d tile. ")
+	workDir := defineStringFlagCommand(flagCommand, "workdir", "", "", "Folder where verify-las-merge writes the merged las file, in a folder of its own created in it and kept when the merge succeeds. If not set the system temporary folder is used")
+	coordinateConverter := defineStringFlagCommand(flagCommand, "coordinate-converter", "", "proj4", "Library converting the coordinates, can be 'proj4' or 'native'. 'native' is a pure Go port of the proj4 projections, faster as it doesn't go through cgo, which supports Transverse Mercator, UTM, Lambert Conformal Conic, Mercator, geographic and geocentric systems with translation and Helmert datum shifts. Other srids, and the ones needing grid shift files as NAD27, require 'proj4'.")
 
 	help := defineBoolFlagCommand(flagCommand, "help", "h", false, "Displays this help.")
 	version := defineBoolFlagCommand(flagCommand, "version", "v", false, "Displays the version of cesium_tiler.")
@@ -217,12 +423,14 @@ func ParseFlagsForCommandVerify(args []string) FlagsForCommandVerify {
 	maxNumPointsPerNode := 50000
 	algorithm := "grid"
 	refineMode := "REPLACE"
+	sampling := "closest"
 
 	flagCommand.Parse(args)
 
 	return FlagsForCommandVerify{
 		FlagCommand: flagCommand,
 		TilerFlags: TilerFlags{
+			Config:                    config,
 			Input:                     input,
 			Srid:                      srid,
 			EightBitColors:            eightBit,
@@ -230,12 +438,17 @@ func ParseFlagsForCommandVerify(args []string) FlagsForCommandVerify {
 			MaxNumPoints:              &maxNumPointsPerNode,
 			MinNumPoints:              &minNumPointsPerNode,
 			ZGeoidCorrection:          zGeoidCorrection,
+			GeoidGrid:                 geoidGrid,
+			GeoidInterpolation:        geoidInterpolation,
 			FolderProcessing:          &folderProcessing,
 			RecursiveFolderProcessing: recursiveFolderProcessing,
 			Algorithm:                 &algorithm,
 			GridCellMaxSize:           gridCellMaxSize,
 			GridCellMinSize:           gridCellMinSize,
 			RefineMode:                &refineMode,
+			Sampling:                  &sampling,
+			WorkDir:                   workDir,
+			CoordinateConverter:       coordinateConverter,
 		},
 		Help:        help,
 		Version:     version,
@@ -245,6 +458,99 @@ func ParseFlagsForCommandVerify(args []string) FlagsForCommandVerify {
 	}
 }
 
+func ParseFlagsForCommandServe(args []string) FlagsForCommandServe {
+	glog.Infoln(FmtJSONString(args))
+
+	flagCommand := flag.NewFlagSet("command-serve", flag.ExitOnError)
+
+	config := defineStringFlagCommand(flagCommand, "config", "", "", "Path of a yaml or json configuration file setting the options of the command, whose keys are listed by the config dump command. Flags set on the command line override the values of the file.")
+	input := defineStringFlagCommand(flagCommand, "input", "i", "", "Specifies the tileset folder to serve.")
+	address := defineStringFlagCommand(flagCommand, "address", "a", ":8080", "Address the HTTP server listens on.")
+	viewer := defineBoolFlagCommand(flagCommand, "viewer", "", true, "Serves at the root path a Cesium viewer page loading the root tileset.json of the folder, or the ones of its chunk tilesets.")
+	help := defineBoolFlagCommand(flagCommand, "help", "h", false, "Displays this help.")
+	version := defineBoolFlagCommand(flagCommand, "version", "v", false, "Displays the version of cesium_tiler.")
+
+	flagCommand.Parse(args)
+
+	return FlagsForCommandServe{
+		FlagCommand: flagCommand,
+		Config:      config,
+		Input:       input,
+		Address:     address,
+		Viewer:      viewer,
+		Help:        help,
+		Version:     version,
+	}
+}
+
+func ParseFlagsForCommandVerifyTileset(args []string) FlagsForCommandVerifyTileset {
+	glog.Infoln(FmtJSONString(args))
+
+	flagCommand := flag.NewFlagSet("command-verify-tileset", flag.ExitOnError)
+
+	config := defineStringFlagCommand(flagCommand, "config", "", "", "Path of a yaml or json configuration file setting the options of the command, whose keys are listed by the config dump command. Flags set on the command line override the values of the file.")
+	input := defineStringFlagCommand(flagCommand, "input", "i", "", "Specifies the tileset folder or tileset.json file to verify.")
+	report := defineStringFlagCommand(flagCommand, "report", "", "", "Path of the json report listing the problems found. If not set the report is written to the standard output.")
+	help := defineBoolFlagCommand(flagCommand, "help", "h", false, "Displays this help.")
+	version := defineBoolFlagCommand(flagCommand, "version", "v", false, "Displays the version of cesium_tiler.")
+
+	flagCommand.Parse(args)
+
+	return FlagsForCommandVerifyTileset{
+		FlagCommand: flagCommand,
+		Config:      config,
+		Input:       input,
+		Report:      report,
+		Help:        help,
+		Version:     version,
+	}
+}
+
+// Parses a comma separated list of point classifications, an empty string returns an empty list
+func ParseClassificationList(value string) ([]uint8, error) {
+	var classes []uint8
+	for _, token := range strings.Split(value, ",") {
+		token = strings.TrimSpace(token)
+		if token == "" {
+			continue
+		}
+		class, err := strconv.ParseUint(token, 10, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid classification [%s], must be between 0 and 255", token)
+		}
+		classes = append(classes, uint8(class))
+	}
+	return classes, nil
+}
+
+// Parses a comma separated list of size numbers, an empty string returns an empty list. An empty element is
+// returned as nil, if allowEmpty is true, so that optional values can be left unset
+func ParseFloatList(value string, size int, allowEmpty bool) ([]*float64, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	tokens := strings.Split(value, ",")
+	if len(tokens) != size {
+		return nil, fmt.Errorf("[%s] must have %d comma separated values", value, size)
+	}
+
+	values := make([]*float64, 0, size)
+	for _, token := range tokens {
+		token = strings.TrimSpace(token)
+		if token == "" && allowEmpty {
+			values = append(values, nil)
+			continue
+		}
+		number, err := strconv.ParseFloat(token, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number [%s] in [%s]", token, value)
+		}
+		values = append(values, &number)
+	}
+	return values, nil
+}
+
 func defineStringFlag(name string, shortHand string, defaultValue string, usage string) *string {
 	var output string
 	flag.StringVar(&output, name, defaultValue, usage)
@@ -258,7 +564,7 @@ func defineStringFlag(name string, shortHand string, defaultValue string, usage
 func defineIntFlag(name string, shortHand string, defaultValue int, usage string) *int {
 	var output int
 	flag.IntVar(&output, name, defaultValue, usage)
-	if shortHand != name {
+	if shortHand != name && shortHand != "" {
 		flag.IntVar(&output, shortHand, defaultValue, usage+" (shorthand for "+name+")")
 	}
 
@@ -268,7 +574,7 @@ func defineIntFlag(name string, shortHand string, defaultValue int, usage string
 func defineFloat64Flag(name string, shortHand string, defaultValue float64, usage string) *float64 {
 	var output float64
 	flag.Float64Var(&output, name, defaultValue, usage)
-	if shortHand != name {
+	if shortHand != name && shortHand != "" {
 		flag.Float64Var(&output, shortHand, defaultValue, usage+" (shorthand for "+name+")")
 	}
 	return &output
@@ -277,7 +583,7 @@ func defineFloat64Flag(name string, shortHand string, defaultValue float64, usag
 func defineBoolFlag(name string, shortHand string, defaultValue bool, usage string) *bool {
 	var output bool
 	flag.BoolVar(&output, name, defaultValue, usage)
-	if shortHand != name {
+	if shortHand != name && shortHand != "" {
 		flag.BoolVar(&output, shortHand, defaultValue, usage+" (shorthand for "+name+")")
 	}
 	return &output
@@ -296,7 +602,7 @@ func defineStringFlagCommand(flagCommand *flag.FlagSet, name string, shortHand s
 func defineIntFlagCommand(flagCommand *flag.FlagSet, name string, shortHand string, defaultValue int, usage string) *int {
 	var output int
 	flagCommand.IntVar(&output, name, defaultValue, usage)
-	if shortHand != name {
+	if shortHand != name && shortHand != "" {
 		flagCommand.IntVar(&output, shortHand, defaultValue, usage+" (shorthand for "+name+")")
 	}
 
@@ -306,7 +612,7 @@ func defineIntFlagCommand(flagCommand *flag.FlagSet, name string, shortHand stri
 func defineFloat64FlagCommand(flagCommand *flag.FlagSet, name string, shortHand string, defaultValue float64, usage string) *float64 {
 	var output float64
 	flagCommand.Float64Var(&output, name, defaultValue, usage)
-	if shortHand != name {
+	if shortHand != name && shortHand != "" {
 		flagCommand.Float64Var(&output, shortHand, defaultValue, usage+" (shorthand for "+name+")")
 	}
 	return &output
@@ -315,8 +621,21 @@ func defineFloat64FlagCommand(flagCommand *flag.FlagSet, name string, shortHand
 func defineBoolFlagCommand(flagCommand *flag.FlagSet, name string, shortHand string, defaultValue bool, usage string) *bool {
 	var output bool
 	flagCommand.BoolVar(&output, name, defaultValue, usage)
-	if shortHand != name {
+	if shortHand != name && shortHand != "" {
 		flagCommand.BoolVar(&output, shortHand, defaultValue, usage+" (shorthand for "+name+")")
 	}
 	return &output
 }
+
+// Returns true if any of the given flags has been explicitly set on the command line
+func IsFlagSet(flagCommand *flag.FlagSet, names ...string) bool {
+	isSet := false
+	flagCommand.Visit(func(f *flag.Flag) {
+		for _, name := range names {
+			if f.Name == name {
+				isSet = true
+			}
+		}
+	})
+	return isSet
+}
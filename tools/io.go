@@ -1,36 +1,28 @@
 package tools
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
-
-	"github.com/golang/glog"
 )
 
-func OpenFileOrFail(filePath string) *os.File {
-	file, err := os.Open(filePath)
-	if err != nil {
-		glog.Fatal(err)
-	}
-
-	return file
-}
-
-func GetRootFolder() string {
+// Returns the root folder of the assets: the CESIUM_TILER_WORKDIR environment variable if set, the root of the module
+// when running the tests, otherwise the folder of the executable
+func GetRootFolder() (string, error) {
 	assetsFromEnv := os.Getenv("CESIUM_TILER_WORKDIR")
 	if assetsFromEnv != "" {
-		return assetsFromEnv
+		return assetsFromEnv, nil
 	} else if strings.HasSuffix(os.Args[0], ".test") || strings.HasSuffix(os.Args[0], ".test.exe") {
 		_, b, _, _ := runtime.Caller(0)
-		return filepath.Dir(filepath.Dir(b))
+		return filepath.Dir(filepath.Dir(b)), nil
 	} else {
 		ex, err := os.Executable()
 		if err != nil {
-			glog.Fatal("cannot retrieve executable directory", err)
+			return "", fmt.Errorf("cannot retrieve executable directory: %v", err)
 		}
-		return filepath.Dir(ex)
+		return filepath.Dir(ex), nil
 	}
 }
 